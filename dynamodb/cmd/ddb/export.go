@@ -0,0 +1,274 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/acksell/bezos/dynamodb/ddbexport"
+	"github.com/acksell/bezos/dynamodb/ddbiface"
+	"github.com/acksell/bezos/dynamodb/ddbstore"
+	"github.com/acksell/bezos/dynamodb/ddbui"
+	"github.com/acksell/bezos/dynamodb/schema"
+	"github.com/acksell/bezos/dynamodb/table"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+func runExport() error {
+	if len(os.Args) < 2 || os.Args[1] == "--help" || os.Args[1] == "-h" || os.Args[1] == "help" {
+		printExportUsage()
+		return nil
+	}
+	tableName := os.Args[1]
+
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	connFlags := RegisterConnectionFlags(fs)
+	formatName := fs.String("format", "dynamodb_json", "export format: dynamodb_json or ion")
+	out := fs.String("out", "", "file to write, - for stdout (default <table>.json.gz or <table>.ion.gz)")
+	if err := fs.Parse(os.Args[2:]); err != nil {
+		return err
+	}
+	format, err := ddbexport.ParseFormat(*formatName)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	client, cleanup, err := connectForTable(ctx, connFlags, tableName)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	path := *out
+	if path == "" {
+		path = ddbexport.FileName(tableName, format)
+	}
+	var w io.Writer = os.Stdout
+	if path != "-" {
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	ew, err := ddbexport.NewWriter(w, format)
+	if err != nil {
+		return err
+	}
+	n, err := ddbexport.Export(ctx, client, tableName, ew)
+	if err != nil {
+		return err
+	}
+	if err := ew.Close(); err != nil {
+		return err
+	}
+	if path != "-" {
+		fmt.Fprintf(os.Stderr, "ddb export: wrote %d items of %s to %s\n", n, tableName, path)
+	}
+	return nil
+}
+
+func runImport() error {
+	if len(os.Args) < 2 || os.Args[1] == "--help" || os.Args[1] == "-h" || os.Args[1] == "help" {
+		printImportUsage()
+		return nil
+	}
+	tableName := os.Args[1]
+
+	// Paths come before the flags.
+	rest := os.Args[2:]
+	var paths []string
+	for len(rest) > 0 && !strings.HasPrefix(rest[0], "-") {
+		paths = append(paths, rest[0])
+		rest = rest[1:]
+	}
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	connFlags := RegisterConnectionFlags(fs)
+	formatName := fs.String("format", "", "format of the files: dynamodb_json or ion (default by file extension)")
+	if err := fs.Parse(rest); err != nil {
+		return err
+	}
+	if len(paths) == 0 {
+		printImportUsage()
+		return fmt.Errorf("missing file or export directory")
+	}
+	var format ddbexport.Format
+	if *formatName != "" {
+		f, err := ddbexport.ParseFormat(*formatName)
+		if err != nil {
+			return err
+		}
+		format = f
+	}
+
+	var files []string
+	for _, p := range paths {
+		info, err := os.Stat(p)
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			files = append(files, p)
+			continue
+		}
+		data, err := ddbexport.DataFiles(p)
+		if err != nil {
+			return err
+		}
+		if len(data) == 0 {
+			return fmt.Errorf("no .json(.gz) or .ion(.gz) data files in %s", p)
+		}
+		files = append(files, data...)
+	}
+
+	ctx := context.Background()
+	client, cleanup, err := connectForTable(ctx, connFlags, tableName)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	def, err := tableDefinition(ctx, client, tableName)
+	if err != nil {
+		return err
+	}
+
+	total := 0
+	for _, path := range files {
+		f := format
+		if f == "" {
+			var ok bool
+			if f, ok = ddbexport.FormatFromPath(path); !ok {
+				return fmt.Errorf("can't tell the format of %s by its extension, use --format", path)
+			}
+		}
+		n, err := importFile(ctx, client, def, path, f)
+		total += n
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	fmt.Fprintf(os.Stderr, "ddb import: wrote %d items from %d files to %s\n", total, len(files), tableName)
+	return nil
+}
+
+func importFile(ctx context.Context, client ddbiface.ReadWriteClient, def table.TableDefinition, path string, format ddbexport.Format) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	r, err := ddbexport.NewReader(file, format)
+	if err != nil {
+		return 0, err
+	}
+	return ddbexport.Import(ctx, client, def, r)
+}
+
+// tableDefinition returns the definition of a table, from the local store or by
+// describing the AWS table.
+func tableDefinition(ctx context.Context, client ddbiface.ReadWriteClient, tableName string) (table.TableDefinition, error) {
+	switch c := client.(type) {
+	case *ddbstore.Store:
+		for _, def := range c.TableDefinitions() {
+			if def.Name == tableName {
+				return def, nil
+			}
+		}
+		return table.TableDefinition{}, fmt.Errorf("table %q not found in the local store", tableName)
+	case *dynamodb.Client:
+		desc, err := c.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: &tableName})
+		if err != nil {
+			return table.TableDefinition{}, fmt.Errorf("describing table %s: %w", tableName, err)
+		}
+		t := describeTableToSchema(desc.Table)
+		return ddbui.TableDefinitionsFromSchemas(schema.Schema{Tables: []schema.Table{t}})[0], nil
+	}
+	return table.TableDefinition{}, fmt.Errorf("can't look up the definition of table %q with a %T", tableName, client)
+}
+
+// connectForTable connects to DynamoDB. Local stores only hold the tables of the
+// schema files, so for them the table must be defined in one.
+func connectForTable(ctx context.Context, connFlags *ConnectionFlags, tableName string) (ddbiface.ReadWriteClient, func(), error) {
+	var schemas []schema.Schema
+	if connFlags.IsLocal() {
+		var err error
+		if schemas, err = loadSchemas(); err != nil {
+			return nil, nil, err
+		}
+		if !schemaHasTable(schemas, tableName) {
+			return nil, nil, fmt.Errorf("table %q is not defined in any schema file, local stores only hold those tables\n\nRun 'ddb schema tables' to see available tables", tableName)
+		}
+	}
+	return connFlags.Connect(ctx, schemas)
+}
+
+func schemaHasTable(schemas []schema.Schema, name string) bool {
+	for _, s := range schemas {
+		for _, t := range s.Tables {
+			if t.Name == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func printExportUsage() {
+	fmt.Println(`ddb export - Export the items of a table
+
+Usage:
+  ddb export <table> [flags]
+
+Scans the table and writes its items gzipped, one per line, in the format of
+DynamoDB exports to S3: DynamoDB JSON or Amazon Ion.
+
+Flags:
+  --format FORMAT   dynamodb_json (default) or ion
+  --out PATH        File to write, - for stdout (default <table>.json.gz)
+  --aws             Connect to AWS DynamoDB (default)
+  --region STRING   AWS region
+  --profile STRING  AWS profile name
+  --endpoint URL    Custom DynamoDB endpoint
+  --db PATH         Path to local database directory
+  --memory          Use in-memory database
+
+Examples:
+  ddb export orders --profile prod
+  ddb export orders --format ion --out orders.ion.gz
+  ddb export orders --db ./data --out - | gunzip | head`)
+}
+
+func printImportUsage() {
+	fmt.Println(`ddb import - Import items into a table
+
+Usage:
+  ddb import <table> <file or directory>... [flags]
+
+Writes the items of DynamoDB JSON or Amazon Ion files, gzipped or not, to the
+table, overwriting items with the same key. A directory is searched for data
+files, so an export to S3 can be imported after downloading it, e.g. with
+aws s3 sync. The format is taken from the file extension unless --format is set.
+
+Flags:
+  --format FORMAT   dynamodb_json or ion
+  --aws             Connect to AWS DynamoDB (default)
+  --region STRING   AWS region
+  --profile STRING  AWS profile name
+  --endpoint URL    Custom DynamoDB endpoint
+  --db PATH         Path to local database directory
+  --memory          Use in-memory database
+
+Examples:
+  # Debug production data locally:
+  aws s3 sync s3://exports/AWSDynamoDB/01234-abcd ./export
+  ddb import orders ./export --db ./data
+  ddb ui --db ./data
+
+  ddb import orders orders.json.gz --db ./data`)
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"bufio"
 	"bytes"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"os"
@@ -10,6 +11,10 @@ import (
 	"path/filepath"
 	"strings"
 	"text/template"
+
+	"github.com/acksell/bezos/dynamodb/dbt"
+	"github.com/acksell/bezos/dynamodb/ddbgen"
+	"github.com/acksell/bezos/dynamodb/infra"
 )
 
 const genMainHeader = "// Code generated by ddbgen. DO NOT EDIT."
@@ -40,12 +45,19 @@ func main() {
 
 func runGen() error {
 	fs := flag.NewFlagSet("gen", flag.ExitOnError)
+	check := fs.Bool("check", false, "Compare the generated schema with the committed one instead of writing files, fail on breaking changes")
+	ref := fs.String("ref", "", "With --check, compare against schema_dynamodb.yaml at this git ref")
+	infraFormats := fs.String("infra", "", "Comma-separated formats to write table definitions in to schema/infra/: cloudformation, cloudformation-json, terraform, cdk")
+	dbtDialect := fs.String("dbt", "", "Also write dbt sources and staging models to schema/dbt/, in this SQL dialect: snowflake, bigquery, duckdb")
+	mutations := fs.Bool("mutations", false, "Report the functions that write each entity instead of generating code")
+	jsonOut := fs.Bool("json", false, "With --mutations, print the report as JSON")
 
 	fs.Usage = func() {
 		fmt.Println(`ddb gen - Generate type-safe key constructors and schema files
 
 Usage:
   ddb gen [flags]
+  ddb gen --mutations [--json] [packages]
 
 When invoked via go:generate (i.e. //go:generate ddb gen):
   - Creates gen/main.go if it doesn't exist (bootstraps the generator)
@@ -55,6 +67,25 @@ When invoked from the CLI:
   - Discovers all gen/main.go files created by ddbgen in the repo
   - Runs each one to regenerate code
 
+Flags:
+  --check      Don't write any files. Compare the generated schema with the
+               committed schema/schema_dynamodb.yaml and exit non-zero on
+               breaking changes not listed in schema/breaking_changes_allowed.txt
+  --ref REF    With --check, compare against the schema at a git ref instead
+  --infra LIST Also write the definition of every table to schema/infra/, in
+               the comma-separated formats cloudformation, cloudformation-json,
+               terraform and cdk. Check deployed templates against the Go
+               definitions with ddb schema verify-infra.
+  --dbt DIALECT
+               Also write a dbt source per table and a staging model per
+               entity to schema/dbt/, in the SQL dialect snowflake, bigquery
+               or duckdb. The models read DynamoDB exports to S3.
+  --mutations  Don't generate anything. Analyze the packages (default ./...)
+               and report which functions put, update or delete each entity
+               through the generated builders or ddbsdk, along with the
+               ddbsdk.WithCause labels they pass.
+  --json       With --mutations, print the report as JSON
+
 Examples:
   # Add to your indexes.go:
   //go:generate ddb gen
@@ -63,13 +94,57 @@ Examples:
   go generate ./...
 
   # Or run directly:
-  ddb gen`)
+  ddb gen
+
+  # Fail CI on breaking schema changes since main:
+  ddb gen --check --ref origin/main
+
+  # Generate Terraform and CDK definitions of the tables:
+  ddb gen --infra terraform,cdk
+
+  # Generate dbt models for Snowflake:
+  ddb gen --dbt snowflake
+
+  # Which functions write which entities:
+  ddb gen --mutations ./...`)
 	}
 
 	if err := fs.Parse(os.Args[1:]); err != nil {
 		return err
 	}
 
+	if *mutations {
+		return runMutations(fs.Args(), *jsonOut)
+	}
+	if *jsonOut {
+		return fmt.Errorf("--json requires --mutations")
+	}
+
+	// The generators read these, see ddbgen.GenerateOptions.
+	if *check {
+		os.Setenv("DDBGEN_CHECK", "1")
+	}
+	if *ref != "" {
+		if !*check {
+			return fmt.Errorf("--ref requires --check")
+		}
+		os.Setenv("DDBGEN_CHECK_REF", *ref)
+	}
+	if *infraFormats != "" {
+		for _, name := range strings.Split(*infraFormats, ",") {
+			if _, err := infra.ParseFormat(strings.TrimSpace(name)); err != nil {
+				return err
+			}
+		}
+		os.Setenv("DDBGEN_INFRA", *infraFormats)
+	}
+	if *dbtDialect != "" {
+		if _, err := dbt.ParseDialect(*dbtDialect); err != nil {
+			return err
+		}
+		os.Setenv("DDBGEN_DBT", *dbtDialect)
+	}
+
 	// Detect if we're running inside go:generate by checking env vars.
 	goPackage := os.Getenv("GOPACKAGE")
 	if goPackage != "" {
@@ -79,6 +154,24 @@ Examples:
 	return runGenFromCLI()
 }
 
+// runMutations prints the mutation report of the packages matching patterns.
+func runMutations(patterns []string, jsonOut bool) error {
+	report, err := ddbgen.AnalyzeMutations(".", patterns...)
+	if err != nil {
+		return err
+	}
+	if jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+	if len(report.Functions) == 0 {
+		fmt.Println("No entity writes found.")
+		return nil
+	}
+	return report.WriteText(os.Stdout)
+}
+
 // runGenFromGoGenerate is called when `ddb gen` is invoked via //go:generate.
 // It bootstraps gen/main.go if needed, then runs it.
 func runGenFromGoGenerate(pkgName string) error {
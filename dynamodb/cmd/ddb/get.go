@@ -25,15 +25,25 @@ func runGet() error {
 		return nil
 	}
 
-	// Separate key=value args from flags
-	kvArgs, flagArgs := splitKVAndFlags(os.Args[2:])
-
 	fs := flag.NewFlagSet("get", flag.ContinueOnError)
 	connFlags := RegisterConnectionFlags(fs)
 	consistent := fs.Bool("consistent", false, "use strongly consistent read")
+	explainGSI := fs.Bool("explain-gsi", false, "report which GSIs the item is in, and why not")
+	fields := fs.String("fields", "", "comma-separated attributes to return")
+	outOpts := registerOutputFlags(fs)
+
+	// Separate key=value args from flags
+	kvArgs, flagArgs := splitKVAndFlags(fs, os.Args[2:])
 	if err := fs.Parse(flagArgs); err != nil {
 		return err
 	}
+	format, err := outOpts.format()
+	if err != nil {
+		return err
+	}
+	if *explainGSI && (format != ddbcli.OutputJSON || *fields != "") {
+		return fmt.Errorf("--explain-gsi needs the whole item as JSON, it can't be combined with --output or --fields")
+	}
 
 	// Parse key=value pairs
 	values, err := parseKVArgs(kvArgs)
@@ -79,6 +89,14 @@ func runGet() error {
 	if *consistent {
 		input.ConsistentRead = consistent
 	}
+	expr, err := readExpression(match.Entity, match.Table, *fields, nil)
+	if err != nil {
+		return err
+	}
+	if expr != nil {
+		input.ProjectionExpression = expr.Projection()
+		input.ExpressionAttributeNames = expr.Names()
+	}
 
 	out, err := client.GetItem(ctx, input)
 	if err != nil {
@@ -90,6 +108,18 @@ func runGet() error {
 		os.Exit(1)
 	}
 
+	if *explainGSI {
+		return writeJSONStdout(struct {
+			Item map[string]any         `json:"item"`
+			GSIs []ddbcli.GSIMembership `json:"gsis"`
+		}{
+			Item: ddbcli.ItemToJSON(out.Item),
+			GSIs: ddbcli.ExplainGSIMembership(match.Entity, match.Table, out.Item),
+		})
+	}
+	if format != ddbcli.OutputJSON {
+		return outOpts.writeItems([]map[string]types.AttributeValue{out.Item}, format, *fields)
+	}
 	return writeJSONStdout(ddbcli.ItemToJSON(out.Item))
 }
 
@@ -134,19 +164,37 @@ func makeAttributeValue(value string, kind string) types.AttributeValue {
 	}
 }
 
-// splitKVAndFlags separates key=value positional args from flag args (starting with -).
-func splitKVAndFlags(args []string) (kvArgs, flagArgs []string) {
-	for _, arg := range args {
-		if strings.HasPrefix(arg, "-") {
-			flagArgs = append(flagArgs, arg)
-		} else if strings.Contains(arg, "=") {
-			kvArgs = append(kvArgs, arg)
-		} else {
-			// Unknown positional arg — treat as flag arg for error reporting
-			flagArgs = append(flagArgs, arg)
+// splitKVAndFlags separates key=value positional args, and key conditions like
+// orderID>=A, from the flags of fs. Flags that take a value keep it even if it
+// contains "=", like --where status=open.
+func splitKVAndFlags(fs *flag.FlagSet, args []string) (kvArgs, flagArgs []string) {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "-") {
+			if strings.ContainsAny(arg, "=<>") {
+				kvArgs = append(kvArgs, arg)
+			} else {
+				// Unknown positional arg — treat as flag arg for error reporting
+				flagArgs = append(flagArgs, arg)
+			}
+			continue
+		}
+		flagArgs = append(flagArgs, arg)
+		name := strings.TrimLeft(arg, "-")
+		if strings.Contains(name, "=") || i+1 == len(args) {
+			continue
+		}
+		f := fs.Lookup(name)
+		if f == nil {
+			continue
+		}
+		if b, ok := f.Value.(interface{ IsBoolFlag() bool }); ok && b.IsBoolFlag() {
+			continue
 		}
+		i++
+		flagArgs = append(flagArgs, args[i])
 	}
-	return
+	return kvArgs, flagArgs
 }
 
 // parseKVArgs parses key=value strings into a map.
@@ -187,6 +235,10 @@ Usage:
 
 Flags:
   --consistent      Use strongly consistent read
+  --explain-gsi     Report which GSIs the item is in (sparse GSIs only hold some items)
+  --fields LIST     Comma-separated attributes to fetch (a ProjectionExpression)
+  --output FORMAT   Output format: table, json (default), jsonl, csv, yaml or dynamodb-json
+  --columns LIST    Comma-separated columns for table and csv (dotted paths allowed)
   --aws             Connect to AWS DynamoDB (default)
   --region STRING   AWS region
   --profile STRING  AWS profile name
@@ -198,5 +250,7 @@ Examples:
   ddb get User id=abc123
   ddb get Order tenantID=tenant-42 orderID=order-1
   ddb get User id=abc123 --consistent
+  ddb get Order tenantID=tenant-42 orderID=order-1 --explain-gsi
+  ddb get Order tenantID=tenant-42 orderID=order-1 --fields orderID,amount --output table
   ddb get User id=abc123 --memory`)
 }
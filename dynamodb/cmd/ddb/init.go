@@ -0,0 +1,273 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/acksell/bezos/dynamodb/infra"
+	"github.com/acksell/bezos/dynamodb/scaffold"
+	"github.com/acksell/bezos/dynamodb/schema"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// scaffoldFlags are the output flags of the init-from commands.
+type scaffoldFlags struct {
+	Out     *string
+	Package *string
+}
+
+func registerScaffoldFlags(fs *flag.FlagSet) *scaffoldFlags {
+	return &scaffoldFlags{
+		Out:     fs.String("out", "", "write the Go source to this file instead of stdout"),
+		Package: fs.String("package", "", "package name (default: the name of the output directory)"),
+	}
+}
+
+// write renders the tables and writes them to --out, which mustn't exist yet,
+// or stdout.
+func (sf *scaffoldFlags) write(origin string, tables []scaffold.Table) error {
+	pkg := *sf.Package
+	if pkg == "" {
+		dir := "."
+		if *sf.Out != "" {
+			dir = filepath.Dir(*sf.Out)
+		}
+		pkg = packageName(dir)
+	}
+	src, err := scaffold.Source(pkg, origin, tables)
+	if err != nil {
+		return err
+	}
+	if *sf.Out == "" {
+		_, err := os.Stdout.Write(src)
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(*sf.Out), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(*sf.Out, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(src); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	entities := 0
+	for _, t := range tables {
+		entities += len(t.Entities)
+	}
+	fmt.Fprintf(os.Stderr, "wrote %s (%d tables, %d entities), review it and run go generate\n", *sf.Out, len(tables), entities)
+	return nil
+}
+
+// packageName returns a package name for the Go files in dir, after the directory.
+func packageName(dir string) string {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "tables"
+	}
+	var b strings.Builder
+	for _, r := range strings.ToLower(filepath.Base(abs)) {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			b.WriteRune(r)
+		}
+	}
+	name := b.String()
+	if name == "" || unicode.IsDigit(rune(name[0])) {
+		return "tables"
+	}
+	return name
+}
+
+func runInitFromAWS() error {
+	fs := flag.NewFlagSet("init-from-aws", flag.ContinueOnError)
+	tableNames := fs.String("table", "", "comma-separated names of the tables to import (required)")
+	sample := fs.Int("sample", 200, "number of items to scan per table to infer entities and key patterns, 0 to skip")
+	region := fs.String("region", "", "AWS region")
+	profile := fs.String("profile", "", "AWS profile name")
+	endpoint := fs.String("endpoint", "", "custom DynamoDB endpoint URL")
+	out := registerScaffoldFlags(fs)
+	fs.Usage = printInitFromAWSUsage
+	if err := fs.Parse(os.Args[1:]); err != nil {
+		return err
+	}
+	if *tableNames == "" {
+		printInitFromAWSUsage()
+		return fmt.Errorf("--table is required")
+	}
+
+	ctx := context.Background()
+	client, err := createAWSClient(ctx, AWSOptions{Region: *region, Profile: *profile, Endpoint: *endpoint})
+	if err != nil {
+		return err
+	}
+
+	var tables []scaffold.Table
+	for _, name := range strings.Split(*tableNames, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		def, err := describeTableWithTTL(ctx, client, name)
+		if err != nil {
+			return err
+		}
+		items, err := sampleItems(ctx, client, name, *sample)
+		if err != nil {
+			return err
+		}
+		tables = append(tables, scaffold.Infer(def, items))
+	}
+	return out.write("ddb init-from-aws", tables)
+}
+
+// describeTableWithTTL describes a table, including its TTL attribute if enabled.
+func describeTableWithTTL(ctx context.Context, client *dynamodb.Client, name string) (schema.Table, error) {
+	desc, err := client.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: &name})
+	if err != nil {
+		return schema.Table{}, fmt.Errorf("describing table %s: %w", name, err)
+	}
+	t := describeTableToSchema(desc.Table)
+
+	ttl, err := client.DescribeTimeToLive(ctx, &dynamodb.DescribeTimeToLiveInput{TableName: &name})
+	if err != nil {
+		return schema.Table{}, fmt.Errorf("describing TTL of table %s: %w", name, err)
+	}
+	if d := ttl.TimeToLiveDescription; d != nil && d.AttributeName != nil &&
+		(d.TimeToLiveStatus == types.TimeToLiveStatusEnabled || d.TimeToLiveStatus == types.TimeToLiveStatusEnabling) {
+		t.TimeToLiveKey = *d.AttributeName
+	}
+	return t, nil
+}
+
+// sampleItems scans up to n items of a table.
+func sampleItems(ctx context.Context, client *dynamodb.Client, name string, n int) ([]map[string]types.AttributeValue, error) {
+	var items []map[string]types.AttributeValue
+	var start map[string]types.AttributeValue
+	for len(items) < n {
+		out, err := client.Scan(ctx, &dynamodb.ScanInput{
+			TableName:         &name,
+			Limit:             aws.Int32(int32(n - len(items))),
+			ExclusiveStartKey: start,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("scanning table %s: %w", name, err)
+		}
+		items = append(items, out.Items...)
+		if len(out.LastEvaluatedKey) == 0 {
+			break
+		}
+		start = out.LastEvaluatedKey
+	}
+	return items, nil
+}
+
+func printInitFromAWSUsage() {
+	fmt.Println(`ddb init-from-aws - Scaffold Go definitions of existing AWS tables
+
+Usage:
+  ddb init-from-aws --table NAME[,NAME...] [flags]
+
+Describes each table and scans a sample of its items to write Go source with a
+table.TableDefinition per table, and a placeholder entity struct and
+indices.Add registration per kind of item. Items are grouped by the literal
+prefixes of their keys (split at #), e.g. TENANT#…/ORDER#… becomes an Order
+entity with the key patterns TENANT#{tenantID} and ORDER#{orderID}. Review the
+names, field types and patterns, then run go generate.
+
+Flags:
+  --table LIST      Comma-separated names of the tables to import (required)
+  --sample N        Number of items to scan per table (default 200, 0 to skip)
+  --out FILE        Write to FILE instead of stdout, it mustn't exist yet
+  --package NAME    Package name (default: the name of the output directory)
+  --region STRING   AWS region
+  --profile STRING  AWS profile name
+  --endpoint URL    Custom DynamoDB endpoint
+
+Examples:
+  ddb init-from-aws --table orders --out internal/store/tables.go
+  ddb init-from-aws --table users,orders --sample 1000 --profile prod`)
+}
+
+func runInitFromCFN() error {
+	fs := flag.NewFlagSet("init-from-cfn", flag.ContinueOnError)
+	tableFilter := fs.String("table", "", "comma-separated names or logical IDs of the tables to import (default: all)")
+	out := registerScaffoldFlags(fs)
+	fs.Usage = printInitFromCFNUsage
+	if err := fs.Parse(os.Args[1:]); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		printInitFromCFNUsage()
+		return fmt.Errorf("expected one or more templates")
+	}
+
+	want := make(map[string]bool)
+	for _, name := range strings.Split(*tableFilter, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			want[name] = true
+		}
+	}
+
+	var tables []scaffold.Table
+	for _, path := range fs.Args() {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		templateTables, err := infra.ParseTemplate(data)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		for _, tt := range templateTables {
+			if len(want) > 0 && !want[tt.Table.Name] && !want[tt.Resource] {
+				continue
+			}
+			def := tt.Table
+			if def.Name == "" {
+				// The template computes the name, e.g. with !Sub.
+				def.Name = tt.Resource
+				fmt.Fprintf(os.Stderr, "%s: %s has a computed table name, named it %q\n", path, tt.Resource, def.Name)
+			}
+			tables = append(tables, scaffold.Infer(def, nil))
+		}
+	}
+	if len(tables) == 0 {
+		return fmt.Errorf("no DynamoDB tables found in the templates")
+	}
+	return out.write("ddb init-from-cfn", tables)
+}
+
+func printInitFromCFNUsage() {
+	fmt.Println(`ddb init-from-cfn - Scaffold Go definitions of the tables of templates
+
+Usage:
+  ddb init-from-cfn [flags] <template>...
+
+Reads the AWS::DynamoDB::Table resources of CloudFormation templates (YAML or
+JSON, e.g. as written by cdk synth) or Terraform aws_dynamodb_table resources,
+and writes Go source with a table.TableDefinition per table and a placeholder
+entity for each. Templates have no items to infer entities from: replace the
+placeholders, or use ddb init-from-aws on a deployed table instead.
+
+Flags:
+  --table LIST      Only import these tables, by name or logical ID
+  --out FILE        Write to FILE instead of stdout, it mustn't exist yet
+  --package NAME    Package name (default: the name of the output directory)
+
+Examples:
+  ddb init-from-cfn template.yaml
+  ddb init-from-cfn --table OrdersTable --out store/tables.go cdk.out/App.template.json`)
+}
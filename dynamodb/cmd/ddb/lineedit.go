@@ -0,0 +1,268 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// maxHistory is the number of lines the history file keeps.
+const maxHistory = 1000
+
+// completeFunc returns the candidates for the word ending at the end of line,
+// and where in line that word starts.
+type completeFunc func(line string) (start int, candidates []string)
+
+// lineEditor reads lines from the terminal with cursor movement, history and
+// tab completion. When stdin is not a terminal it reads plain lines.
+type lineEditor struct {
+	in          *bufio.Reader
+	out         io.Writer
+	fd          int
+	terminal    bool
+	complete    completeFunc
+	history     []string
+	historyPath string
+}
+
+func newLineEditor(historyPath string, complete completeFunc) *lineEditor {
+	e := &lineEditor{
+		in:          bufio.NewReader(os.Stdin),
+		out:         os.Stdout,
+		fd:          int(os.Stdin.Fd()),
+		complete:    complete,
+		historyPath: historyPath,
+	}
+	e.terminal = isTerminal(e.fd)
+	if data, err := os.ReadFile(historyPath); err == nil {
+		e.history = strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	}
+	return e
+}
+
+// addHistory appends a line to the history, and to its file when reading from a terminal.
+// It skips repeats of the last line.
+func (e *lineEditor) addHistory(line string) {
+	if line == "" || (len(e.history) > 0 && e.history[len(e.history)-1] == line) {
+		return
+	}
+	e.history = append(e.history, line)
+	if len(e.history) > maxHistory {
+		e.history = e.history[len(e.history)-maxHistory:]
+	}
+	if e.terminal && e.historyPath != "" {
+		os.WriteFile(e.historyPath, []byte(strings.Join(e.history, "\n")+"\n"), 0o600)
+	}
+}
+
+// readLine reads a line, returning io.EOF on Ctrl-D at an empty line or the end of input.
+// Ctrl-C discards the line and returns "".
+func (e *lineEditor) readLine(prompt string) (string, error) {
+	if !e.terminal {
+		line, err := e.in.ReadString('\n')
+		if err != nil && line == "" {
+			return "", err
+		}
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+	restore, err := makeRaw(e.fd)
+	if err != nil {
+		e.terminal = false
+		return e.readLine(prompt)
+	}
+	defer restore()
+
+	var buf []rune
+	pos := 0
+	histIdx, draft := len(e.history), ""
+	refresh := func() {
+		fmt.Fprintf(e.out, "\r%s%s\x1b[K", prompt, string(buf))
+		if n := len(buf) - pos; n > 0 {
+			fmt.Fprintf(e.out, "\x1b[%dD", n)
+		}
+	}
+	setLine := func(s string) {
+		buf = []rune(s)
+		pos = len(buf)
+		refresh()
+	}
+	insert := func(s string) {
+		r := []rune(s)
+		buf = append(buf[:pos], append(r, buf[pos:]...)...)
+		pos += len(r)
+		refresh()
+	}
+	refresh()
+
+	for {
+		r, _, err := e.in.ReadRune()
+		if err != nil {
+			return "", err
+		}
+		switch r {
+		case '\r', '\n':
+			fmt.Fprint(e.out, "\n")
+			return string(buf), nil
+		case 3: // Ctrl-C
+			fmt.Fprint(e.out, "^C\n")
+			return "", nil
+		case 4: // Ctrl-D
+			if len(buf) == 0 {
+				fmt.Fprint(e.out, "\n")
+				return "", io.EOF
+			}
+			if pos < len(buf) {
+				buf = append(buf[:pos], buf[pos+1:]...)
+				refresh()
+			}
+		case 127, 8: // Backspace
+			if pos > 0 {
+				buf = append(buf[:pos-1], buf[pos:]...)
+				pos--
+				refresh()
+			}
+		case 1: // Ctrl-A
+			pos = 0
+			refresh()
+		case 5: // Ctrl-E
+			pos = len(buf)
+			refresh()
+		case 21: // Ctrl-U
+			buf = buf[pos:]
+			pos = 0
+			refresh()
+		case 23: // Ctrl-W
+			start := pos
+			for start > 0 && unicode.IsSpace(buf[start-1]) {
+				start--
+			}
+			for start > 0 && !unicode.IsSpace(buf[start-1]) {
+				start--
+			}
+			buf = append(buf[:start], buf[pos:]...)
+			pos = start
+			refresh()
+		case '\t':
+			e.completeAt(&buf, &pos)
+			refresh()
+		case 27: // Escape sequences: arrows, Home, End and Delete
+			key, err := e.readEscape()
+			if err != nil {
+				return "", err
+			}
+			switch key {
+			case "A", "B": // Up, Down
+				if histIdx == len(e.history) {
+					draft = string(buf)
+				}
+				if key == "A" && histIdx > 0 {
+					histIdx--
+					setLine(e.history[histIdx])
+				} else if key == "B" && histIdx < len(e.history) {
+					histIdx++
+					if histIdx == len(e.history) {
+						setLine(draft)
+					} else {
+						setLine(e.history[histIdx])
+					}
+				}
+			case "C": // Right
+				if pos < len(buf) {
+					pos++
+					refresh()
+				}
+			case "D": // Left
+				if pos > 0 {
+					pos--
+					refresh()
+				}
+			case "H", "1~": // Home
+				pos = 0
+				refresh()
+			case "F", "4~": // End
+				pos = len(buf)
+				refresh()
+			case "3~": // Delete
+				if pos < len(buf) {
+					buf = append(buf[:pos], buf[pos+1:]...)
+					refresh()
+				}
+			}
+		default:
+			if unicode.IsPrint(r) {
+				insert(string(r))
+			}
+		}
+	}
+}
+
+// readEscape reads the rest of an escape sequence like ESC [ A or ESC [ 3 ~,
+// and returns the part after the bracket.
+func (e *lineEditor) readEscape() (string, error) {
+	b, err := e.in.ReadByte()
+	if err != nil {
+		return "", err
+	}
+	if b != '[' && b != 'O' {
+		return "", nil
+	}
+	var seq []byte
+	for {
+		c, err := e.in.ReadByte()
+		if err != nil {
+			return "", err
+		}
+		seq = append(seq, c)
+		if c >= 0x40 && c <= 0x7e {
+			return string(seq), nil
+		}
+		if len(seq) > 8 {
+			return "", errors.New("unterminated escape sequence")
+		}
+	}
+}
+
+// completeAt completes the word before the cursor. A single candidate replaces
+// the word; several are completed to their common prefix, or listed if that
+// adds nothing.
+func (e *lineEditor) completeAt(buf *[]rune, pos *int) {
+	if e.complete == nil {
+		return
+	}
+	line := string((*buf)[:*pos])
+	start, candidates := e.complete(line)
+	if len(candidates) == 0 {
+		return
+	}
+	word := line[start:]
+	replacement := candidates[0]
+	if len(candidates) == 1 {
+		if !strings.HasSuffix(replacement, "=") {
+			replacement += " "
+		}
+	} else {
+		replacement = commonPrefix(candidates)
+		if len(replacement) <= len(word) {
+			fmt.Fprintf(e.out, "\n%s\n", strings.Join(candidates, "  "))
+			return
+		}
+	}
+	rest := (*buf)[*pos:]
+	head := []rune(line[:start] + replacement)
+	*buf = append(head, rest...)
+	*pos = len(head)
+}
+
+func commonPrefix(words []string) string {
+	prefix := words[0]
+	for _, w := range words[1:] {
+		for !strings.HasPrefix(w, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	return prefix
+}
@@ -6,8 +6,18 @@
 //
 // # Commands
 //
-//	ddb gen    Generate type-safe key constructors and schema files
-//	ddb ui     Start the local debugging UI
+//	ddb gen      Generate type-safe key constructors and schema files
+//	ddb ui       Start the local debugging UI
+//	ddb schema   Inspect, diff and verify schema definitions
+//	ddb migrate  Run data migrations
+//	ddb shell    Interactive shell with completion, paging and pipes
+//	ddb put      Create or replace an item, built from its fields
+//	ddb update   Change attributes of an existing item
+//	ddb delete   Delete an item by entity type and key fields
+//	ddb export   Export the items of a table in DynamoDB JSON or Ion
+//	ddb import   Import items from DynamoDB JSON or Ion files
+//	ddb init-from-aws  Scaffold Go definitions of existing AWS tables
+//	ddb init-from-cfn  Scaffold Go definitions of the tables of templates
 //
 // # Quick Start
 //
@@ -62,6 +72,24 @@ func main() {
 		err = runQuery()
 	case "scan":
 		err = runScan()
+	case "migrate":
+		err = runMigrate()
+	case "shell":
+		err = runShell()
+	case "put":
+		err = runPut()
+	case "update":
+		err = runUpdate()
+	case "delete":
+		err = runDelete()
+	case "export":
+		err = runExport()
+	case "import":
+		err = runImport()
+	case "init-from-aws":
+		err = runInitFromAWS()
+	case "init-from-cfn":
+		err = runInitFromCFN()
 	case "help", "-h", "--help":
 		printUsage()
 		return
@@ -89,10 +117,19 @@ Usage:
 Commands:
   gen     Generate type-safe key constructors and schema files
   ui      Start the DynamoDB debug UI
-  schema  Inspect schema definitions (tables, entities, keys)
+  schema  Inspect, diff and verify schema definitions (tables, entities, keys)
   get     Get an item by entity type and key fields
   query   Query items by entity type and key conditions
   scan    Scan items by entity type
+  migrate Run data migrations (up, status, dry-run)
+  shell   Interactive shell for get, query and scan with tab completion
+  put     Create or replace an item, with conditions and a dry run
+  update  Change attributes of an existing item
+  delete  Delete an item by entity type and key fields
+  export  Export the items of a table in DynamoDB JSON or Ion (S3 export formats)
+  import  Import items from DynamoDB JSON or Ion files, e.g. an export from S3
+  init-from-aws  Scaffold Go definitions of existing AWS tables from sampled items
+  init-from-cfn  Scaffold Go definitions of the tables of CloudFormation or Terraform templates
 
 Examples:
   # Code generation:
@@ -107,6 +144,17 @@ Examples:
   ddb query Order tenantID=tenant-42
   ddb query User --gsi GSI1 email=foo@bar.com
   ddb scan User --limit 10
+  ddb scan Order --where status=pending --output table
+  ddb shell --profile prod
+  ddb update Order tenantID=tenant-42 orderID=order-1 status=shipped --dry-run
+
+  # Copy production data into a local database:
+  ddb export orders --profile prod
+  ddb import orders orders.json.gz --db ./data
+
+  # Onboard existing tables:
+  ddb init-from-aws --table orders --out store/tables.go
+  ddb init-from-cfn template.yaml
 
   # Start UI:
   ddb ui --db ./data
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+func runMigrate() error {
+	if len(os.Args) < 2 {
+		printMigrateUsage()
+		return fmt.Errorf("missing subcommand")
+	}
+
+	subcmd := os.Args[1]
+	switch subcmd {
+	case "up", "status", "dry-run":
+	case "help", "-h", "--help":
+		printMigrateUsage()
+		return nil
+	default:
+		printMigrateUsage()
+		return fmt.Errorf("unknown subcommand %q", subcmd)
+	}
+
+	// --pkg is ours, everything else is passed on to migrate.RunCommand.
+	pkg := "./migrations"
+	var args []string
+	rest := os.Args[2:]
+	for i := 0; i < len(rest); i++ {
+		switch arg := rest[i]; {
+		case arg == "--pkg" && i+1 < len(rest):
+			pkg = rest[i+1]
+			i++
+		case strings.HasPrefix(arg, "--pkg="):
+			pkg = strings.TrimPrefix(arg, "--pkg=")
+		default:
+			args = append(args, arg)
+		}
+	}
+
+	cmd := exec.Command("go", append([]string{"run", pkg, subcmd}, args...)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("running %s: %w", pkg, err)
+	}
+	return nil
+}
+
+func printMigrateUsage() {
+	fmt.Println(`ddb migrate - Run data migrations
+
+Usage:
+  ddb migrate <subcommand> [--pkg DIR] [flags]
+
+Migrations are registered with migrate.Register in your code. ddb migrate runs
+the main package in DIR (default ./migrations), which imports them, connects to
+DynamoDB and calls migrate.RunCommand:
+
+  func main() {
+      client := dynamodb.NewFromConfig(cfg, func(o *dynamodb.Options) {
+          o.Retryer = aws.NopRetryer{} // migrations retry with their own policy
+      })
+      if err := migrate.RunCommand(ctx, client, os.Args[1:], os.Stdout); err != nil {
+          fmt.Fprintln(os.Stderr, err)
+          os.Exit(1)
+      }
+  }
+
+Progress is recorded in the bezos_migrations table, so applied migrations are
+skipped and interrupted ones resume where they stopped.
+
+Subcommands:
+  up        Apply pending migrations in ID order
+  status    Show the state and progress of every migration
+  dry-run   Report what pending migrations would change, without writing.
+            Actions are applied to copies of the items in an in-memory store.
+
+Flags:
+  --pkg DIR        Main package running the migrations (default ./migrations)
+  --segments N     Parallel scan segments of new migrations (default 4)
+  --page-size N    Items per scanned page, i.e. per checkpoint
+  --table NAME     Table recording migration progress (default bezos_migrations)
+  --samples N      Changed items to show per migration in a dry run (default 5)
+  --json           Output JSON
+
+Examples:
+  ddb migrate status
+  ddb migrate dry-run --samples 10
+  ddb migrate up --segments 16
+  ddb migrate up --pkg ./cmd/migrate`)
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/acksell/bezos/dynamodb/ddbcli"
+	"github.com/acksell/bezos/dynamodb/schema"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// outputOptions holds the flags choosing how get, query and scan print items.
+type outputOptions struct {
+	Output  string
+	Columns string
+}
+
+// registerOutputFlags adds the output flags to a FlagSet.
+func registerOutputFlags(fs *flag.FlagSet) *outputOptions {
+	opts := &outputOptions{}
+	fs.StringVar(&opts.Output, "output", "json", "output format: table, json, jsonl, csv, yaml or dynamodb-json")
+	fs.StringVar(&opts.Columns, "columns", "", "comma-separated attributes to show as columns, for table and csv")
+	return opts
+}
+
+// format parses the output format, and checks the columns are only given for
+// formats that have them.
+func (o *outputOptions) format() (ddbcli.OutputFormat, error) {
+	format, err := ddbcli.ParseOutputFormat(o.Output)
+	if err != nil {
+		return "", err
+	}
+	if o.Columns != "" && format != ddbcli.OutputTable && format != ddbcli.OutputCSV {
+		return "", fmt.Errorf("--columns only applies to --output table or csv")
+	}
+	return format, nil
+}
+
+// writeItems prints the items in a format other than the default JSON. The
+// columns default to the projected fields, if any.
+func (o *outputOptions) writeItems(items []map[string]types.AttributeValue, format ddbcli.OutputFormat, fields string) error {
+	columns := splitList(o.Columns)
+	if len(columns) == 0 {
+		columns = splitList(fields)
+	}
+	return ddbcli.WriteItems(os.Stdout, items, format, columns)
+}
+
+// whereFlag collects the values of a repeatable --where flag.
+type whereFlag []string
+
+func (w *whereFlag) String() string { return strings.Join(*w, " and ") }
+
+func (w *whereFlag) Set(s string) error {
+	*w = append(*w, s)
+	return nil
+}
+
+// readExpression compiles --fields to a projection and the --where filters to a
+// condition that all of them have to match. It returns nil if there are neither.
+func readExpression(entity schema.Entity, table schema.Table, fields string, where []string) (*expression.Expression, error) {
+	builder := expression.NewBuilder()
+	empty := true
+	if names := splitList(fields); len(names) > 0 {
+		proj := expression.NamesList(expression.Name(names[0]))
+		for _, name := range names[1:] {
+			proj = proj.AddNames(expression.Name(name))
+		}
+		builder = builder.WithProjection(proj)
+		empty = false
+	}
+
+	var conds []expression.ConditionBuilder
+	for _, w := range where {
+		segments, err := splitShellLine(w)
+		if err != nil {
+			return nil, fmt.Errorf("--where %q: %w", w, err)
+		}
+		if len(segments) > 1 {
+			return nil, fmt.Errorf("--where %q: unexpected |", w)
+		}
+		filter, err := ddbcli.ParseWhere(segments[0])
+		if err != nil {
+			return nil, fmt.Errorf("--where %q: %w", w, err)
+		}
+		cond, err := filter.Expression(entity, table)
+		if err != nil {
+			return nil, fmt.Errorf("--where %q: %w", w, err)
+		}
+		conds = append(conds, cond)
+	}
+	if len(conds) > 0 {
+		cond := conds[0]
+		if len(conds) > 1 {
+			cond = expression.And(conds[0], conds[1], conds[2:]...)
+		}
+		builder = builder.WithFilter(cond)
+		empty = false
+	}
+	if empty {
+		return nil, nil
+	}
+	expr, err := builder.Build()
+	if err != nil {
+		return nil, err
+	}
+	return &expr, nil
+}
+
+// mergeExpressionAttributes adds the names and values of expr to those of a request.
+// The expression builder names them #0, :0 and so on, which don't collide with the
+// #pk and :pk style names of key conditions.
+func mergeExpressionAttributes(names map[string]string, values map[string]types.AttributeValue, expr *expression.Expression) (map[string]string, map[string]types.AttributeValue) {
+	for k, v := range expr.Names() {
+		if names == nil {
+			names = make(map[string]string)
+		}
+		names[k] = v
+	}
+	for k, v := range expr.Values() {
+		if values == nil {
+			values = make(map[string]types.AttributeValue)
+		}
+		values[k] = v
+	}
+	return names, values
+}
+
+// splitList splits a comma-separated list, dropping empty elements.
+func splitList(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
@@ -52,18 +52,20 @@ func runQuery() error {
 		return nil
 	}
 
-	// Separate key=value args from flags
-	kvArgs, flagArgs := splitKVAndFlags(os.Args[2:])
-
 	fs := flag.NewFlagSet("query", flag.ContinueOnError)
 	connFlags := RegisterConnectionFlags(fs)
-	gsiName := fs.String("gsi", "", "query a Global Secondary Index")
-	limit := fs.Int("limit", 0, "maximum number of items to return")
-	reverse := fs.Bool("reverse", false, "scan in reverse (descending sort key order)")
-	consistent := fs.Bool("consistent", false, "use strongly consistent read (not supported on GSIs)")
+	opts := registerQueryFlags(fs)
+	outOpts := registerOutputFlags(fs)
+
+	// Separate key=value args from flags
+	kvArgs, flagArgs := splitKVAndFlags(fs, os.Args[2:])
 	if err := fs.Parse(flagArgs); err != nil {
 		return err
 	}
+	format, err := outOpts.format()
+	if err != nil {
+		return err
+	}
 
 	// Load schemas and find entity
 	schemas, err := loadSchemas()
@@ -76,6 +78,70 @@ func runQuery() error {
 		return fmt.Errorf("entity %q not found in schema\n\nRun 'ddb schema entities' to see available entity types", entityName)
 	}
 
+	input, err := buildQueryInput(match, kvArgs, *opts)
+	if err != nil {
+		return err
+	}
+
+	// Connect and execute
+	ctx := context.Background()
+	client, cleanup, err := connFlags.Connect(ctx, schemas)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	out, err := client.Query(ctx, input)
+	if err != nil {
+		return fmt.Errorf("Query: %w", err)
+	}
+
+	if note := ddbcli.SparseGSINote(match.Entity, opts.GSI); note != "" {
+		fmt.Fprintf(os.Stderr, "note: %s\n", note)
+	}
+
+	if format != ddbcli.OutputJSON {
+		return outOpts.writeItems(out.Items, format, opts.Fields)
+	}
+	result := struct {
+		Count        int32            `json:"count"`
+		ScannedCount int32            `json:"scannedCount"`
+		Items        []map[string]any `json:"items"`
+	}{
+		Count:        out.Count,
+		ScannedCount: out.ScannedCount,
+		Items:        ddbcli.ItemsToJSON(out.Items),
+	}
+
+	return writeJSONStdout(result)
+}
+
+// queryOptions holds the query flags that are not about the connection.
+type queryOptions struct {
+	GSI        string
+	Limit      int
+	Reverse    bool
+	Consistent bool
+	Fields     string
+	Where      whereFlag
+}
+
+// registerQueryFlags adds the query flags to a FlagSet.
+func registerQueryFlags(fs *flag.FlagSet) *queryOptions {
+	opts := &queryOptions{}
+	fs.StringVar(&opts.GSI, "gsi", "", "query a Global Secondary Index")
+	fs.IntVar(&opts.Limit, "limit", 0, "maximum number of items to return")
+	fs.BoolVar(&opts.Reverse, "reverse", false, "scan in reverse (descending sort key order)")
+	fs.BoolVar(&opts.Consistent, "consistent", false, "use strongly consistent read (not supported on GSIs)")
+	fs.StringVar(&opts.Fields, "fields", "", "comma-separated attributes to return")
+	fs.Var(&opts.Where, "where", "only return items matching the filter, e.g. 'status=open and total>=100' (repeatable)")
+	return opts
+}
+
+// buildQueryInput builds the query of an entity from key=value and sort key condition args.
+func buildQueryInput(match ddbcli.EntityMatch, kvArgs []string, opts queryOptions) (*dynamodb.QueryInput, error) {
+	var err error
+
 	// Determine which index we're querying
 	var pkParams, skParams []ddbcli.Param
 	var pkAttrName, skAttrName string
@@ -83,17 +149,17 @@ func runQuery() error {
 	var skPattern string
 	indexName := "" // empty = primary index
 
-	if *gsiName != "" {
-		indexName = *gsiName
-		pkParams, skParams, err = ddbcli.GSIParams(match.Entity, *gsiName)
+	if opts.GSI != "" {
+		indexName = opts.GSI
+		pkParams, skParams, err = ddbcli.GSIParams(match.Entity, opts.GSI)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		// Find GSI definition for attribute names and types
-		gsi := findGSI(match.Table, *gsiName)
+		gsi := findGSI(match.Table, opts.GSI)
 		if gsi == nil {
-			return fmt.Errorf("table %q has no GSI %q", match.Table.Name, *gsiName)
+			return nil, fmt.Errorf("table %q has no GSI %q", match.Table.Name, opts.GSI)
 		}
 		pkAttrName = gsi.PartitionKey.Name
 		pkKind = gsi.PartitionKey.Kind
@@ -104,7 +170,7 @@ func runQuery() error {
 
 		// Find GSI mapping for sort key pattern
 		for _, m := range match.Entity.GSIMappings {
-			if strings.EqualFold(m.GSI, *gsiName) {
+			if strings.EqualFold(m.GSI, opts.GSI) {
 				skPattern = m.SortPattern
 				break
 			}
@@ -138,7 +204,7 @@ func runQuery() error {
 	for _, arg := range kvArgs {
 		field, cond, isPlain := parseSkCondition(arg)
 		if cond == nil {
-			return fmt.Errorf("invalid argument %q", arg)
+			return nil, fmt.Errorf("invalid argument %q", arg)
 		}
 		if pkFieldNames[field] && isPlain {
 			pkValues[field] = cond.value
@@ -147,7 +213,7 @@ func runQuery() error {
 		} else {
 			// Could be a PK field with non-equality op (error) or unknown field
 			if pkFieldNames[field] {
-				return fmt.Errorf("partition key field %q only supports equality (=)", field)
+				return nil, fmt.Errorf("partition key field %q only supports equality (=)", field)
 			}
 			// Unknown field — maybe it's still a PK field not in the pattern,
 			// or user made a typo. Try to be helpful.
@@ -157,14 +223,14 @@ func runQuery() error {
 
 	// Validate PK params
 	if err := validateParams(pkParams, pkValues); err != nil {
-		return fmt.Errorf("partition key: %w", err)
+		return nil, fmt.Errorf("partition key: %w", err)
 	}
 
 	// Build the partition key value
 	var pkPattern string
-	if *gsiName != "" {
+	if opts.GSI != "" {
 		for _, m := range match.Entity.GSIMappings {
-			if strings.EqualFold(m.GSI, *gsiName) {
+			if strings.EqualFold(m.GSI, opts.GSI) {
 				pkPattern = m.PartitionPattern
 				break
 			}
@@ -175,7 +241,7 @@ func runQuery() error {
 
 	pkValue, err := ddbcli.BuildKeyFromEntity(pkPattern, match.Entity, pkValues)
 	if err != nil {
-		return fmt.Errorf("building partition key: %w", err)
+		return nil, fmt.Errorf("building partition key: %w", err)
 	}
 
 	// Build the KeyConditionExpression
@@ -187,7 +253,7 @@ func runQuery() error {
 		exprNames["#sk"] = skAttrName
 		skExpr, err := buildSKExpression(skConditions, skPattern, skKind, match.Entity, exprValues)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		keyCondExpr += " AND " + skExpr
 	} else if skAttrName != "" && len(skConditions) == 0 && skPattern != "" {
@@ -212,43 +278,30 @@ func runQuery() error {
 	if indexName != "" {
 		input.IndexName = &indexName
 	}
-	if *limit > 0 {
-		input.Limit = aws.Int32(int32(*limit))
+	if opts.Limit > 0 {
+		input.Limit = aws.Int32(int32(opts.Limit))
 	}
-	if *reverse {
+	if opts.Reverse {
 		input.ScanIndexForward = aws.Bool(false)
 	}
-	if *consistent {
+	if opts.Consistent {
 		if indexName != "" {
-			return fmt.Errorf("--consistent is not supported on GSI queries")
+			return nil, fmt.Errorf("--consistent is not supported on GSI queries")
 		}
-		input.ConsistentRead = consistent
+		input.ConsistentRead = aws.Bool(true)
 	}
 
-	// Connect and execute
-	ctx := context.Background()
-	client, cleanup, err := connFlags.Connect(ctx, schemas)
+	expr, err := readExpression(match.Entity, match.Table, opts.Fields, opts.Where)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer cleanup()
-
-	out, err := client.Query(ctx, input)
-	if err != nil {
-		return fmt.Errorf("Query: %w", err)
-	}
-
-	result := struct {
-		Count        int32            `json:"count"`
-		ScannedCount int32            `json:"scannedCount"`
-		Items        []map[string]any `json:"items"`
-	}{
-		Count:        out.Count,
-		ScannedCount: out.ScannedCount,
-		Items:        ddbcli.ItemsToJSON(out.Items),
+	if expr != nil {
+		input.FilterExpression = expr.Filter()
+		input.ProjectionExpression = expr.Projection()
+		input.ExpressionAttributeNames, input.ExpressionAttributeValues = mergeExpressionAttributes(input.ExpressionAttributeNames, input.ExpressionAttributeValues, expr)
 	}
 
-	return writeJSONStdout(result)
+	return input, nil
 }
 
 // buildSKExpression builds the sort key part of a KeyConditionExpression
@@ -375,11 +428,21 @@ Sort Key Operators:
   field<value       Less than
   Two conditions    BETWEEN (e.g. orderID>=A orderID<=Z)
 
+Filters (--where, repeatable; all must match):
+  field=value, !=, >, >=, <, <=   Compare (typed by the entity's fields)
+  field^=prefix, field~=text      Begins with, contains
+  exists(field), missing(field)   Attribute present or absent
+  and, or                         Combine conditions; and binds tighter
+
 Flags:
   --gsi NAME        Query a Global Secondary Index
   --limit N         Maximum number of items to return
   --reverse         Reverse sort key order (descending)
   --consistent      Use strongly consistent read (primary index only)
+  --fields LIST     Comma-separated attributes to fetch (a ProjectionExpression)
+  --where EXPR      Filter items server-side (a FilterExpression)
+  --output FORMAT   Output format: table, json (default), jsonl, csv, yaml or dynamodb-json
+  --columns LIST    Comma-separated columns for table and csv (dotted paths allowed)
   --aws             Connect to AWS DynamoDB (default)
   --region STRING   AWS region
   --profile STRING  AWS profile name
@@ -392,5 +455,7 @@ Examples:
   ddb query Order tenantID=tenant-42 orderID^=2024
   ddb query Order tenantID=tenant-42 orderID>=2024-01 orderID<=2024-12
   ddb query User --gsi GSI1 email=foo@bar.com
-  ddb query Order tenantID=t1 --limit 10 --reverse`)
+  ddb query Order tenantID=t1 --limit 10 --reverse
+  ddb query Order tenantID=t1 --where "amount>=100 or missing(status)" --output jsonl
+  ddb query Order tenantID=t1 --fields orderID,amount --output csv`)
 }
@@ -29,12 +29,15 @@ func runScan() error {
 
 	fs := flag.NewFlagSet("scan", flag.ContinueOnError)
 	connFlags := RegisterConnectionFlags(fs)
-	limit := fs.Int("limit", 0, "maximum number of items to return")
-	gsiName := fs.String("gsi", "", "scan a Global Secondary Index")
-	consistent := fs.Bool("consistent", false, "use strongly consistent read (not supported on GSIs)")
+	opts := registerScanFlags(fs)
+	outOpts := registerOutputFlags(fs)
 	if err := fs.Parse(flagArgsScan); err != nil {
 		return err
 	}
+	format, err := outOpts.format()
+	if err != nil {
+		return err
+	}
 
 	// Load schemas and find entity
 	schemas, err := loadSchemas()
@@ -47,6 +50,62 @@ func runScan() error {
 		return fmt.Errorf("entity %q not found in schema\n\nRun 'ddb schema entities' to see available entity types", entityName)
 	}
 
+	input, err := buildScanInput(match, *opts)
+	if err != nil {
+		return err
+	}
+
+	// Connect and execute
+	ctx := context.Background()
+	client, cleanup, err := connFlags.Connect(ctx, schemas)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	out, err := client.Scan(ctx, input)
+	if err != nil {
+		return fmt.Errorf("Scan: %w", err)
+	}
+
+	if format != ddbcli.OutputJSON {
+		return outOpts.writeItems(out.Items, format, opts.Fields)
+	}
+	result := struct {
+		Count        int32            `json:"count"`
+		ScannedCount int32            `json:"scannedCount"`
+		Items        []map[string]any `json:"items"`
+	}{
+		Count:        out.Count,
+		ScannedCount: out.ScannedCount,
+		Items:        ddbcli.ItemsToJSON(out.Items),
+	}
+
+	return writeJSONStdout(result)
+}
+
+// scanOptions holds the scan flags that are not about the connection.
+type scanOptions struct {
+	GSI        string
+	Limit      int
+	Consistent bool
+	Fields     string
+	Where      whereFlag
+}
+
+// registerScanFlags adds the scan flags to a FlagSet.
+func registerScanFlags(fs *flag.FlagSet) *scanOptions {
+	opts := &scanOptions{}
+	fs.IntVar(&opts.Limit, "limit", 0, "maximum number of items to return")
+	fs.StringVar(&opts.GSI, "gsi", "", "scan a Global Secondary Index")
+	fs.BoolVar(&opts.Consistent, "consistent", false, "use strongly consistent read (not supported on GSIs)")
+	fs.StringVar(&opts.Fields, "fields", "", "comma-separated attributes to return")
+	fs.Var(&opts.Where, "where", "only return items matching the filter, e.g. 'status=open and total>=100' (repeatable)")
+	return opts
+}
+
+// buildScanInput builds the scan of an entity's items.
+func buildScanInput(match ddbcli.EntityMatch, opts scanOptions) (*dynamodb.ScanInput, error) {
 	// Build a filter expression using the entity's PK prefix.
 	// This ensures we only return items belonging to this entity type.
 	pkPrefix := ddbcli.LiteralPrefix(match.Entity.PartitionKeyPattern)
@@ -72,49 +131,40 @@ func runScan() error {
 		input.ExpressionAttributeValues = exprValues
 	}
 
-	if *gsiName != "" {
-		gsi := findGSI(match.Table, *gsiName)
+	if opts.GSI != "" {
+		gsi := findGSI(match.Table, opts.GSI)
 		if gsi == nil {
-			return fmt.Errorf("table %q has no GSI %q", match.Table.Name, *gsiName)
+			return nil, fmt.Errorf("table %q has no GSI %q", match.Table.Name, opts.GSI)
 		}
-		input.IndexName = gsiName
+		input.IndexName = &opts.GSI
 	}
 
-	if *limit > 0 {
-		input.Limit = aws.Int32(int32(*limit))
+	if opts.Limit > 0 {
+		input.Limit = aws.Int32(int32(opts.Limit))
 	}
 
-	if *consistent {
-		if *gsiName != "" {
-			return fmt.Errorf("--consistent is not supported on GSI scans")
+	if opts.Consistent {
+		if opts.GSI != "" {
+			return nil, fmt.Errorf("--consistent is not supported on GSI scans")
 		}
-		input.ConsistentRead = consistent
-	}
-
-	// Connect and execute
-	ctx := context.Background()
-	client, cleanup, err := connFlags.Connect(ctx, schemas)
-	if err != nil {
-		return err
+		input.ConsistentRead = aws.Bool(true)
 	}
-	defer cleanup()
 
-	out, err := client.Scan(ctx, input)
+	expr, err := readExpression(match.Entity, match.Table, opts.Fields, opts.Where)
 	if err != nil {
-		return fmt.Errorf("Scan: %w", err)
+		return nil, err
 	}
-
-	result := struct {
-		Count        int32            `json:"count"`
-		ScannedCount int32            `json:"scannedCount"`
-		Items        []map[string]any `json:"items"`
-	}{
-		Count:        out.Count,
-		ScannedCount: out.ScannedCount,
-		Items:        ddbcli.ItemsToJSON(out.Items),
+	if expr != nil {
+		if f := expr.Filter(); f != nil && input.FilterExpression != nil {
+			input.FilterExpression = aws.String("(" + *input.FilterExpression + ") AND (" + *f + ")")
+		} else if f != nil {
+			input.FilterExpression = f
+		}
+		input.ProjectionExpression = expr.Projection()
+		input.ExpressionAttributeNames, input.ExpressionAttributeValues = mergeExpressionAttributes(input.ExpressionAttributeNames, input.ExpressionAttributeValues, expr)
 	}
 
-	return writeJSONStdout(result)
+	return input, nil
 }
 
 func printScanUsage() {
@@ -126,10 +176,20 @@ Usage:
 The scan automatically filters to only return items matching the entity's
 partition key prefix pattern.
 
+Filters (--where, repeatable; all must match):
+  field=value, !=, >, >=, <, <=   Compare (typed by the entity's fields)
+  field^=prefix, field~=text      Begins with, contains
+  exists(field), missing(field)   Attribute present or absent
+  and, or                         Combine conditions; and binds tighter
+
 Flags:
   --limit N         Maximum number of items to return
   --gsi NAME        Scan a Global Secondary Index
   --consistent      Use strongly consistent read (primary index only)
+  --fields LIST     Comma-separated attributes to fetch (a ProjectionExpression)
+  --where EXPR      Filter items server-side (a FilterExpression)
+  --output FORMAT   Output format: table, json (default), jsonl, csv, yaml or dynamodb-json
+  --columns LIST    Comma-separated columns for table and csv (dotted paths allowed)
   --aws             Connect to AWS DynamoDB (default)
   --region STRING   AWS region
   --profile STRING  AWS profile name
@@ -142,5 +202,7 @@ Examples:
   ddb scan User --limit 10
   ddb scan Order --limit 50
   ddb scan User --gsi GSI1 --limit 20
-  ddb scan User --memory`)
+  ddb scan User --memory
+  ddb scan Order --where status=pending --where "amount>100" --output table
+  ddb scan Order --output table --columns orderID,status,amount`)
 }
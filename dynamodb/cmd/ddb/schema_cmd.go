@@ -19,6 +19,14 @@ func runSchema() error {
 	subcmd := os.Args[1]
 	os.Args = append([]string{os.Args[0]}, os.Args[2:]...)
 
+	// diff reads the schemas it's given rather than the discovered ones.
+	switch subcmd {
+	case "diff":
+		return schemaDiff()
+	case "verify-infra":
+		return schemaVerifyInfra()
+	}
+
 	schemas, err := loadSchemas()
 	if err != nil {
 		return err
@@ -273,11 +281,30 @@ Subcommands:
   entities [--table NAME]   List all entities (optionally filtered by table)
   describe <EntityType>     Describe an entity type (fields, keys, GSIs)
   describe --table <NAME>   Describe a table (keys, GSIs, all entities)
+  diff <old> <new>          Compare two schema files, exit non-zero on breaking
+                            changes not in the allowlist. A file that doesn't
+                            exist is read from git as <ref>:<path>.
+      --allow FILE          Allowlist of acknowledged changes, one ID per line
+                            (default: breaking_changes_allowed.txt next to <new>)
+      --json                Output the changes as JSON
+  verify-infra <template>...
+                            Check CloudFormation (YAML or JSON, e.g. cdk synth
+                            output) or Terraform templates against the tables
+                            defined in Go, exit non-zero if keys, GSIs or TTL differ.
+                            Tables no template declares are skipped.
+      --schema FILE         Verify against this schema file instead of all
+                            discovered ones
+      --table NAME          Only verify this table
+      --require-all         Fail for tables defined in Go that no template declares
+      --json                Output the mismatches as JSON
 
 Examples:
   ddb schema tables
   ddb schema entities
   ddb schema entities --table users
   ddb schema describe User
-  ddb schema describe --table orders`)
+  ddb schema describe --table orders
+  ddb schema diff main:pkg/schema/schema_dynamodb.yaml pkg/schema/schema_dynamodb.yaml
+  ddb schema verify-infra infra/main.tf
+  ddb schema verify-infra --table orders cdk.out/AppStack.template.json`)
 }
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/acksell/bezos/dynamodb/schema"
+	"gopkg.in/yaml.v3"
+)
+
+// schemaDiff compares two schema_dynamodb.yaml files and fails on unacknowledged
+// breaking changes.
+func schemaDiff() error {
+	fs := flag.NewFlagSet("schema diff", flag.ContinueOnError)
+	allowPath := fs.String("allow", "", "allowlist of acknowledged breaking changes (default: breaking_changes_allowed.txt next to <new>)")
+	asJSON := fs.Bool("json", false, "output the changes as JSON")
+	if err := fs.Parse(os.Args[1:]); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		return fmt.Errorf("expected <old> and <new> schema files\n\nUsage:\n  ddb schema diff [--allow FILE] [--json] <old> <new>")
+	}
+	oldPath, newPath := fs.Arg(0), fs.Arg(1)
+
+	old, err := readSchemaArg(oldPath)
+	if err != nil {
+		return err
+	}
+	new, err := readSchemaArg(newPath)
+	if err != nil {
+		return err
+	}
+
+	allow := make(schema.Allowlist)
+	if *allowPath == "" {
+		if _, err := os.Stat(newPath); err == nil {
+			*allowPath = filepath.Join(filepath.Dir(newPath), "breaking_changes_allowed.txt")
+			if _, err := os.Stat(*allowPath); err != nil {
+				*allowPath = ""
+			}
+		}
+	}
+	if *allowPath != "" {
+		f, err := os.Open(*allowPath)
+		if err != nil {
+			return fmt.Errorf("opening allowlist: %w", err)
+		}
+		allow, err = schema.ParseAllowlist(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("reading allowlist %s: %w", *allowPath, err)
+		}
+	}
+
+	changes := schema.Diff(old, new)
+	broken := allow.Unacknowledged(changes)
+
+	if *asJSON {
+		type changeOutput struct {
+			schema.Change
+			ID           string `json:"id"`
+			Acknowledged bool   `json:"acknowledged,omitempty"`
+		}
+		out := make([]changeOutput, 0, len(changes))
+		for _, c := range changes {
+			out = append(out, changeOutput{Change: c, ID: c.ID(), Acknowledged: c.Breaking && allow[c.ID()]})
+		}
+		if err := writeJSONStdout(out); err != nil {
+			return err
+		}
+	} else {
+		for _, c := range changes {
+			marker := " "
+			switch {
+			case c.Breaking && allow[c.ID()]:
+				marker = "~"
+			case c.Breaking:
+				marker = "!"
+			}
+			fmt.Printf("%s %s\n", marker, c)
+		}
+		if len(changes) == 0 {
+			fmt.Println("no changes")
+		}
+	}
+
+	if len(broken) > 0 {
+		return fmt.Errorf("%d unacknowledged breaking change(s)", len(broken))
+	}
+	return nil
+}
+
+// readSchemaArg reads a schema from a file path, or from "<ref>:<path>" in git
+// if no such file exists.
+func readSchemaArg(arg string) (schema.Schema, error) {
+	var s schema.Schema
+	data, err := os.ReadFile(arg)
+	if errors.Is(err, os.ErrNotExist) && strings.Contains(arg, ":") {
+		data, err = exec.Command("git", "show", arg).Output()
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			err = fmt.Errorf("git show %s: %s", arg, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+	}
+	if err != nil {
+		return s, err
+	}
+	if err := yaml.Unmarshal(data, &s); err != nil {
+		return s, fmt.Errorf("parsing %s: %w", arg, err)
+	}
+	return s, nil
+}
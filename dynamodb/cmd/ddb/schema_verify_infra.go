@@ -0,0 +1,105 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/acksell/bezos/dynamodb/infra"
+	"github.com/acksell/bezos/dynamodb/schema"
+)
+
+// schemaVerifyInfra checks CloudFormation or Terraform templates against the tables
+// defined in Go, and fails if they disagree.
+func schemaVerifyInfra() error {
+	fs := flag.NewFlagSet("schema verify-infra", flag.ContinueOnError)
+	schemaPath := fs.String("schema", "", "schema_dynamodb.yaml to verify against (default: all discovered schema files)")
+	tableFilter := fs.String("table", "", "only verify this table")
+	asJSON := fs.Bool("json", false, "output the mismatches as JSON")
+	requireAll := fs.Bool("require-all", false, "fail for tables defined in Go that no template declares")
+	if err := fs.Parse(os.Args[1:]); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("expected one or more templates\n\nUsage:\n  ddb schema verify-infra [--schema FILE] [--table NAME] [--require-all] [--json] <template>...")
+	}
+
+	var schemas []schema.Schema
+	if *schemaPath != "" {
+		s, err := readSchemaArg(*schemaPath)
+		if err != nil {
+			return err
+		}
+		schemas = []schema.Schema{s}
+	} else {
+		var err error
+		if schemas, err = loadSchemas(); err != nil {
+			return err
+		}
+	}
+
+	// Packages sharing a table each have it in their schema.
+	var defined []schema.Table
+	seen := make(map[string]bool)
+	for _, s := range schemas {
+		for _, t := range s.Tables {
+			if seen[t.Name] || (*tableFilter != "" && !strings.EqualFold(t.Name, *tableFilter)) {
+				continue
+			}
+			seen[t.Name] = true
+			defined = append(defined, t)
+		}
+	}
+	if len(defined) == 0 {
+		return fmt.Errorf("no tables to verify")
+	}
+
+	var templates []infra.TemplateTable
+	declared := make(map[string]bool)
+	for _, path := range fs.Args() {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		tables, err := infra.ParseTemplate(data)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		for _, tt := range tables {
+			if tt.Table.Name == "" && !*asJSON {
+				fmt.Printf("  %s: %s has a computed table name, skipped\n", path, tt.Resource)
+			}
+			declared[tt.Table.Name] = true
+		}
+		templates = append(templates, tables...)
+	}
+
+	mismatches := infra.Verify(defined, templates, *requireAll)
+	if *asJSON {
+		if mismatches == nil {
+			mismatches = []infra.Mismatch{}
+		}
+		if err := writeJSONStdout(mismatches); err != nil {
+			return err
+		}
+	} else {
+		for _, m := range mismatches {
+			fmt.Printf("! %s\n", m)
+		}
+		if len(mismatches) == 0 {
+			checked := 0
+			for _, t := range defined {
+				if declared[t.Name] {
+					checked++
+				}
+			}
+			fmt.Printf("%d table(s) match\n", checked)
+		}
+	}
+
+	if len(mismatches) > 0 {
+		return fmt.Errorf("%d mismatch(es) between the templates and the Go definitions", len(mismatches))
+	}
+	return nil
+}
@@ -0,0 +1,578 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/acksell/bezos/dynamodb/ddbcli"
+	"github.com/acksell/bezos/dynamodb/ddbiface"
+	"github.com/acksell/bezos/dynamodb/schema"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// shellPageSize is the page size of queries and scans in the shell without --limit.
+const shellPageSize = 25
+
+var (
+	shellCommands = []string{"get", "query", "scan", "next", "entities", "describe", "format", "history", "help", "exit"}
+	pipeStages    = []string{"where", "fields", "count"}
+)
+
+// shell is the state of a ddb shell session.
+type shell struct {
+	ctx     context.Context
+	schemas []schema.Schema
+	client  ddbiface.ReadWriteClient
+	format  ddbcli.OutputFormat
+	editor  *lineEditor
+	out     io.Writer
+
+	// The last query or scan, its --fields and its pipeline, to fetch the next page of.
+	lastQuery  *dynamodb.QueryInput
+	lastScan   *dynamodb.ScanInput
+	lastFields []string
+	lastPipe   [][]string
+}
+
+func runShell() error {
+	if len(os.Args) > 1 && (os.Args[1] == "--help" || os.Args[1] == "-h" || os.Args[1] == "help") {
+		printShellUsage()
+		return nil
+	}
+	fs := flag.NewFlagSet("shell", flag.ContinueOnError)
+	connFlags := RegisterConnectionFlags(fs)
+	output := fs.String("output", "table", "output format: table, json, jsonl, csv, yaml or dynamodb-json")
+	if err := fs.Parse(os.Args[1:]); err != nil {
+		return err
+	}
+	format, err := ddbcli.ParseOutputFormat(*output)
+	if err != nil {
+		return err
+	}
+
+	schemas, err := loadSchemas()
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	client, cleanup, err := connFlags.Connect(ctx, schemas)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	sh := &shell{ctx: ctx, schemas: schemas, client: client, format: format, out: os.Stdout}
+	var historyPath string
+	if home, err := os.UserHomeDir(); err == nil {
+		historyPath = filepath.Join(home, ".ddb_history")
+	}
+	sh.editor = newLineEditor(historyPath, sh.complete)
+
+	if sh.editor.terminal {
+		fmt.Fprintf(sh.out, "ddb shell, connected to %s. Type 'help' for commands, Tab to complete.\n", connectionName(connFlags))
+	}
+	for {
+		line, err := sh.editor.readLine("ddb> ")
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		sh.editor.addHistory(line)
+		if line == "exit" || line == "quit" {
+			return nil
+		}
+		if err := sh.exec(line); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		}
+	}
+}
+
+func connectionName(cf *ConnectionFlags) string {
+	switch {
+	case *cf.Memory:
+		return "an in-memory store"
+	case *cf.DB != "":
+		return "the local store at " + *cf.DB
+	}
+	name := "AWS"
+	if *cf.Profile != "" {
+		name += " (profile " + *cf.Profile + ")"
+	}
+	if *cf.Region != "" {
+		name += " in " + *cf.Region
+	}
+	return name
+}
+
+// exec runs a command line: a command, optionally followed by | and pipe stages.
+func (sh *shell) exec(line string) error {
+	segments, err := splitShellLine(line)
+	if err != nil {
+		return err
+	}
+	args, pipe := segments[0], segments[1:]
+	if len(args) == 0 {
+		return fmt.Errorf("missing command before |")
+	}
+	for _, stage := range pipe {
+		if len(stage) == 0 {
+			return fmt.Errorf("empty pipe stage")
+		}
+	}
+
+	switch args[0] {
+	case "help":
+		printShellHelp(sh.out)
+		return nil
+	case "entities":
+		return sh.entities()
+	case "describe":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: describe <EntityType>")
+		}
+		return sh.describe(args[1])
+	case "format":
+		if len(args) == 1 {
+			fmt.Fprintln(sh.out, sh.format)
+			return nil
+		}
+		f, err := ddbcli.ParseOutputFormat(args[1])
+		if err != nil {
+			return err
+		}
+		sh.format = f
+		return nil
+	case "history":
+		for i, h := range sh.editor.history {
+			fmt.Fprintf(sh.out, "%5d  %s\n", i+1, h)
+		}
+		return nil
+	case "get":
+		return sh.get(args[1:], pipe)
+	case "query", "scan":
+		return sh.read(args[0], args[1:], pipe)
+	case "next":
+		return sh.next()
+	}
+	return fmt.Errorf("unknown command %q, type 'help' for commands", args[0])
+}
+
+func (sh *shell) findEntity(args []string) (ddbcli.EntityMatch, error) {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return ddbcli.EntityMatch{}, fmt.Errorf("missing entity type, type 'entities' to list them")
+	}
+	match, ok := ddbcli.FindEntity(sh.schemas, args[0])
+	if !ok {
+		return ddbcli.EntityMatch{}, fmt.Errorf("entity %q not found in schema, type 'entities' to list them", args[0])
+	}
+	return match, nil
+}
+
+func (sh *shell) get(args []string, pipe [][]string) error {
+	match, err := sh.findEntity(args)
+	if err != nil {
+		return err
+	}
+	fs := flag.NewFlagSet("get", flag.ContinueOnError)
+	consistent := fs.Bool("consistent", false, "use strongly consistent read")
+	kvArgs, flagArgs := splitKVAndFlags(fs, args[1:])
+	if err := fs.Parse(flagArgs); err != nil {
+		return err
+	}
+	values, err := parseKVArgs(kvArgs)
+	if err != nil {
+		return err
+	}
+	if err := validateParams(ddbcli.RequiredParams(match.Entity), values); err != nil {
+		return err
+	}
+	key, err := buildEntityKey(match, values)
+	if err != nil {
+		return err
+	}
+	out, err := sh.client.GetItem(sh.ctx, &dynamodb.GetItemInput{
+		TableName:      &match.Table.Name,
+		Key:            key,
+		ConsistentRead: aws.Bool(*consistent),
+	})
+	if err != nil {
+		return fmt.Errorf("GetItem: %w", err)
+	}
+	if out.Item == nil {
+		fmt.Fprintln(os.Stderr, "item not found")
+		return nil
+	}
+	return sh.print([]map[string]types.AttributeValue{out.Item}, nil, pipe)
+}
+
+// read runs a query or scan, and remembers it for 'next'.
+func (sh *shell) read(cmd string, args []string, pipe [][]string) error {
+	match, err := sh.findEntity(args)
+	if err != nil {
+		return err
+	}
+	fs := flag.NewFlagSet(cmd, flag.ContinueOnError)
+	sh.lastQuery, sh.lastScan, sh.lastFields, sh.lastPipe = nil, nil, nil, pipe
+	if cmd == "query" {
+		opts := registerQueryFlags(fs)
+		kvArgs, flagArgs := splitKVAndFlags(fs, args[1:])
+		if err := fs.Parse(flagArgs); err != nil {
+			return err
+		}
+		if opts.Limit == 0 {
+			opts.Limit = shellPageSize
+		}
+		if sh.lastQuery, err = buildQueryInput(match, kvArgs, *opts); err != nil {
+			return err
+		}
+		sh.lastFields = splitList(opts.Fields)
+		if note := ddbcli.SparseGSINote(match.Entity, opts.GSI); note != "" {
+			fmt.Fprintf(os.Stderr, "note: %s\n", note)
+		}
+	} else {
+		opts := registerScanFlags(fs)
+		kvArgs, flagArgs := splitKVAndFlags(fs, args[1:])
+		if err := fs.Parse(flagArgs); err != nil {
+			return err
+		}
+		if len(kvArgs) > 0 {
+			return fmt.Errorf("scan takes no key fields, filter with '| where %s'", kvArgs[0])
+		}
+		if opts.Limit == 0 {
+			opts.Limit = shellPageSize
+		}
+		if sh.lastScan, err = buildScanInput(match, *opts); err != nil {
+			return err
+		}
+		sh.lastFields = splitList(opts.Fields)
+	}
+	return sh.next()
+}
+
+// next fetches the next page of the last query or scan.
+func (sh *shell) next() error {
+	var items []map[string]types.AttributeValue
+	var lastKey map[string]types.AttributeValue
+	switch {
+	case sh.lastQuery != nil:
+		out, err := sh.client.Query(sh.ctx, sh.lastQuery)
+		if err != nil {
+			return fmt.Errorf("Query: %w", err)
+		}
+		items, lastKey = out.Items, out.LastEvaluatedKey
+		sh.lastQuery.ExclusiveStartKey = lastKey
+		if lastKey == nil {
+			sh.lastQuery = nil
+		}
+	case sh.lastScan != nil:
+		out, err := sh.client.Scan(sh.ctx, sh.lastScan)
+		if err != nil {
+			return fmt.Errorf("Scan: %w", err)
+		}
+		items, lastKey = out.Items, out.LastEvaluatedKey
+		sh.lastScan.ExclusiveStartKey = lastKey
+		if lastKey == nil {
+			sh.lastScan = nil
+		}
+	default:
+		return fmt.Errorf("no more pages, run a query or scan first")
+	}
+	if err := sh.print(items, sh.lastFields, sh.lastPipe); err != nil {
+		return err
+	}
+	if lastKey != nil {
+		fmt.Fprintln(os.Stderr, "(more items, type 'next' for the next page)")
+	}
+	return nil
+}
+
+// print runs the items through the pipe stages and prints them in the shell's format,
+// with the given columns unless a fields stage selects others.
+func (sh *shell) print(items []map[string]types.AttributeValue, columns []string, pipe [][]string) error {
+	for _, stage := range pipe {
+		switch stage[0] {
+		case "where":
+			filter, err := ddbcli.ParseWhere(stage[1:])
+			if err != nil {
+				return err
+			}
+			items = filter.Apply(items)
+		case "fields":
+			var names []string
+			for _, arg := range stage[1:] {
+				names = append(names, strings.Split(arg, ",")...)
+			}
+			projected := make([]map[string]types.AttributeValue, len(items))
+			for i, item := range items {
+				projected[i] = make(map[string]types.AttributeValue, len(names))
+				for _, name := range names {
+					if av, ok := item[name]; ok {
+						projected[i][name] = av
+					}
+				}
+			}
+			items, columns = projected, names
+		case "count":
+			fmt.Fprintln(sh.out, len(items))
+			return nil
+		default:
+			return fmt.Errorf("unknown pipe stage %q (want %s)", stage[0], strings.Join(pipeStages, ", "))
+		}
+	}
+	return ddbcli.WriteItems(sh.out, items, sh.format, columns)
+}
+
+func (sh *shell) entities() error {
+	tw := tabwriter.NewWriter(sh.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ENTITY\tTABLE\tPARTITION KEY\tSORT KEY")
+	for _, s := range sh.schemas {
+		for _, t := range s.Tables {
+			for _, e := range t.Entities {
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", e.Type, t.Name, e.PartitionKeyPattern, e.SortKeyPattern)
+			}
+		}
+	}
+	return tw.Flush()
+}
+
+func (sh *shell) describe(name string) error {
+	match, ok := ddbcli.FindEntity(sh.schemas, name)
+	if !ok {
+		return fmt.Errorf("entity %q not found in schema", name)
+	}
+	e := match.Entity
+	fmt.Fprintf(sh.out, "%s in table %s\n  key: %s", e.Type, match.Table.Name, e.PartitionKeyPattern)
+	if e.SortKeyPattern != "" {
+		fmt.Fprintf(sh.out, " / %s", e.SortKeyPattern)
+	}
+	fmt.Fprintln(sh.out)
+	tw := tabwriter.NewWriter(sh.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "  fields:")
+	for _, f := range e.Fields {
+		fmt.Fprintf(tw, "    %s\t%s\n", f.Tag, f.Type)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	for _, m := range e.GSIMappings {
+		fmt.Fprintf(sh.out, "  gsi %s: %s", m.GSI, m.PartitionPattern)
+		if m.SortPattern != "" {
+			fmt.Fprintf(sh.out, " / %s", m.SortPattern)
+		}
+		if note := ddbcli.SparseGSINote(e, m.GSI); note != "" {
+			fmt.Fprintf(sh.out, " (%s)", note)
+		}
+		fmt.Fprintln(sh.out)
+	}
+	return nil
+}
+
+// complete completes the word at the end of line from the commands, the
+// entity types, their key fields and GSIs, and the pipe stages.
+func (sh *shell) complete(line string) (int, []string) {
+	start := strings.LastIndexAny(line, " \t|") + 1
+	word := line[start:]
+
+	segments := strings.Split(line, "|")
+	cmdWords := strings.Fields(segments[0])
+	words := strings.Fields(segments[len(segments)-1])
+	if word == "" {
+		words = append(words, "")
+	}
+	var match *ddbcli.EntityMatch
+	if len(cmdWords) > 1 {
+		if m, ok := ddbcli.FindEntity(sh.schemas, cmdWords[1]); ok {
+			match = &m
+		}
+	}
+
+	var candidates []string
+	switch {
+	case len(segments) > 1 && len(words) == 1:
+		candidates = pipeStages
+	case len(segments) > 1:
+		if match != nil {
+			candidates = attributeNames(*match)
+		}
+	case len(words) == 1:
+		candidates = shellCommands
+	case len(words) == 2 && words[0] == "format":
+		for _, f := range ddbcli.OutputFormats {
+			candidates = append(candidates, string(f))
+		}
+	case len(words) == 2 && (words[0] == "get" || words[0] == "query" || words[0] == "scan" || words[0] == "describe"):
+		for _, s := range sh.schemas {
+			for _, t := range s.Tables {
+				for _, e := range t.Entities {
+					candidates = append(candidates, e.Type)
+				}
+			}
+		}
+	case match == nil:
+	case words[len(words)-2] == "--gsi":
+		for _, m := range match.Entity.GSIMappings {
+			candidates = append(candidates, m.GSI)
+		}
+	case strings.HasPrefix(word, "-"):
+		switch words[0] {
+		case "get":
+			candidates = []string{"--consistent"}
+		case "query":
+			candidates = []string{"--gsi", "--limit", "--reverse", "--consistent", "--fields", "--where"}
+		case "scan":
+			candidates = []string{"--gsi", "--limit", "--consistent", "--fields", "--where"}
+		}
+	case words[0] == "get" || words[0] == "query":
+		params := ddbcli.RequiredParams(match.Entity)
+		for i, w := range words {
+			if w == "--gsi" && i+1 < len(words) && words[0] == "query" {
+				pk, sk, _ := ddbcli.GSIParams(match.Entity, words[i+1])
+				params = append(pk, sk...)
+			}
+		}
+		for _, p := range params {
+			candidates = append(candidates, p.Name+"=")
+		}
+	}
+
+	var out []string
+	seen := make(map[string]bool)
+	for _, c := range candidates {
+		if !seen[c] && len(c) >= len(word) && strings.EqualFold(c[:len(word)], word) {
+			seen[c] = true
+			out = append(out, c)
+		}
+	}
+	sort.Strings(out)
+	return start, out
+}
+
+// attributeNames returns the names of an entity's attributes: its fields and key attributes.
+func attributeNames(match ddbcli.EntityMatch) []string {
+	names := []string{match.Table.PartitionKey.Name}
+	if match.Table.SortKey != nil {
+		names = append(names, match.Table.SortKey.Name)
+	}
+	if match.Table.EntityTypeKey != "" {
+		names = append(names, match.Table.EntityTypeKey)
+	}
+	for _, f := range match.Entity.Fields {
+		names = append(names, f.Tag)
+	}
+	return names
+}
+
+// splitShellLine splits a command line into words, and into segments at each |.
+// Single and double quotes group words, and a backslash escapes the next character.
+func splitShellLine(line string) ([][]string, error) {
+	segments := [][]string{nil}
+	var word strings.Builder
+	inWord := false
+	var quote rune
+	escaped := false
+	endWord := func() {
+		if inWord {
+			segments[len(segments)-1] = append(segments[len(segments)-1], word.String())
+			word.Reset()
+			inWord = false
+		}
+	}
+	for _, r := range line {
+		switch {
+		case escaped:
+			word.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped, inWord = true, true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote, inWord = r, true
+		case r == '|':
+			endWord()
+			segments = append(segments, nil)
+		case r == ' ' || r == '\t':
+			endWord()
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+	endWord()
+	return segments, nil
+}
+
+func printShellHelp(w io.Writer) {
+	fmt.Fprintln(w, `Commands:
+  get <Entity> <field=value>... [--consistent]     Get an item by its key fields
+  query <Entity> <field=value>... [flags]          Query, flags: --gsi --limit --reverse --consistent
+                                                   --fields --where
+  scan <Entity> [flags]                            Scan the items of an entity, flags: --gsi --limit
+                                                   --consistent --fields --where
+  next                                             Fetch the next page of the last query or scan
+  entities                                         List the entity types
+  describe <Entity>                                Show an entity's keys, fields and GSIs
+  format [FORMAT]                                  Show or set the output format: table, json,
+                                                   jsonl, csv, yaml or dynamodb-json
+  history                                          Show the command history
+  exit                                             Leave the shell (or Ctrl-D)
+
+Pipe results through stages:
+  ... | where status=open and total>=100 or ...    Keep matching items (operators = != > >= < <=
+                                                   ^= ~=, exists(field) and missing(field))
+  ... | fields orderID,status                      Keep only these attributes
+  ... | count                                      Print the number of items
+
+Queries and scans return 25 items per page unless --limit is given.`)
+}
+
+func printShellUsage() {
+	fmt.Println(`ddb shell - Interactive shell for reading items
+
+Usage:
+  ddb shell [flags]
+
+Connects once and reads commands like get, query and scan with tab completion
+of entity types, key fields and GSIs from the schema files. History is kept in
+~/.ddb_history.
+
+Flags:
+  --output FORMAT   Output format: table (default), json, jsonl, csv, yaml or dynamodb-json
+  --aws             Connect to AWS DynamoDB (default)
+  --region STRING   AWS region
+  --profile STRING  AWS profile name
+  --endpoint URL    Custom DynamoDB endpoint
+  --db PATH         Path to local database directory
+  --memory          Use in-memory database
+
+Examples:
+  ddb shell --profile prod
+  ddb shell --db ./data --output json
+
+  ddb> query Order tenantID=t1 | where status=pending | fields orderID,amount
+  ddb> next
+  ddb> format yaml`)
+}
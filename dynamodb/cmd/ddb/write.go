@@ -0,0 +1,618 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/acksell/bezos/dynamodb/ddbcli"
+	"github.com/acksell/bezos/dynamodb/ddbexport"
+	"github.com/acksell/bezos/dynamodb/ddbiface"
+	"github.com/acksell/bezos/dynamodb/schema"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// writeCommand holds the parsed arguments shared by put, update and delete.
+type writeCommand struct {
+	name        string
+	match       ddbcli.EntityMatch
+	connFlags   *ConnectionFlags
+	attrs       map[string]types.AttributeValue
+	key         map[string]types.AttributeValue
+	ifVersion   *int64
+	ifNotExists bool
+	versionAttr string
+	remove      []string
+	dryRun      bool
+	yes         bool
+}
+
+func runPut() error {
+	cmd, err := parseWriteCommand("put", printPutUsage)
+	if cmd == nil || err != nil {
+		return err
+	}
+	ctx := context.Background()
+	client, cleanup, err := cmd.connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	before, err := cmd.get(ctx, client)
+	if err != nil {
+		return err
+	}
+	after := make(map[string]types.AttributeValue, len(cmd.attrs))
+	for k, v := range cmd.attrs {
+		after[k] = v
+	}
+	if err := cmd.completeItem(after); err != nil {
+		return err
+	}
+
+	input := &dynamodb.PutItemInput{TableName: &cmd.match.Table.Name, Item: after}
+	var cond *expression.ConditionBuilder
+	if cmd.ifNotExists {
+		c := expression.AttributeNotExists(expression.Name(cmd.match.Table.PartitionKey.Name))
+		cond = &c
+	} else if cmd.ifVersion != nil {
+		c := expression.Equal(expression.Name(cmd.versionAttr), expression.Value(*cmd.ifVersion))
+		cond = &c
+	}
+	if cond != nil {
+		expr, err := expression.NewBuilder().WithCondition(*cond).Build()
+		if err != nil {
+			return err
+		}
+		input.ConditionExpression = expr.Condition()
+		input.ExpressionAttributeNames = expr.Names()
+		input.ExpressionAttributeValues = expr.Values()
+	}
+
+	if !cmd.printDiff(before, after) {
+		return nil
+	}
+	if cmd.dryRun {
+		return printRequest("PutItem", writeRequest{
+			TableName:                 *input.TableName,
+			Item:                      input.Item,
+			ConditionExpression:       input.ConditionExpression,
+			ExpressionAttributeNames:  input.ExpressionAttributeNames,
+			ExpressionAttributeValues: input.ExpressionAttributeValues,
+		})
+	}
+	if err := cmd.confirm(); err != nil {
+		return err
+	}
+	if _, err := client.PutItem(ctx, input); err != nil {
+		return cmd.writeError("PutItem", err)
+	}
+	return nil
+}
+
+func runUpdate() error {
+	cmd, err := parseWriteCommand("update", printUpdateUsage)
+	if cmd == nil || err != nil {
+		return err
+	}
+	ctx := context.Background()
+	client, cleanup, err := cmd.connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	before, err := cmd.get(ctx, client)
+	if err != nil {
+		return err
+	}
+	if before == nil {
+		return fmt.Errorf("item not found, use 'ddb put' to create it")
+	}
+	after := make(map[string]types.AttributeValue, len(before)+len(cmd.attrs))
+	for k, v := range before {
+		after[k] = v
+	}
+	for k, v := range cmd.attrs {
+		after[k] = v
+	}
+	for _, name := range cmd.remove {
+		if _, ok := cmd.key[name]; ok {
+			return fmt.Errorf("can't remove key attribute %q", name)
+		}
+		delete(after, name)
+	}
+	if err := cmd.completeItem(after); err != nil {
+		return err
+	}
+
+	changes := ddbcli.DiffItems(before, after)
+	if !cmd.printDiff(before, after) {
+		return nil
+	}
+
+	var update expression.UpdateBuilder
+	for _, c := range changes {
+		if c.After == nil {
+			update = update.Remove(expression.Name(c.Name))
+		} else {
+			update = update.Set(expression.Name(c.Name), expression.Value(c.After))
+		}
+	}
+	cond := expression.AttributeExists(expression.Name(cmd.match.Table.PartitionKey.Name))
+	if cmd.ifVersion != nil {
+		cond = cond.And(expression.Equal(expression.Name(cmd.versionAttr), expression.Value(*cmd.ifVersion)))
+	}
+	expr, err := expression.NewBuilder().WithUpdate(update).WithCondition(cond).Build()
+	if err != nil {
+		return err
+	}
+	input := &dynamodb.UpdateItemInput{
+		TableName:                 &cmd.match.Table.Name,
+		Key:                       cmd.key,
+		UpdateExpression:          expr.Update(),
+		ConditionExpression:       expr.Condition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	}
+
+	if cmd.dryRun {
+		return printRequest("UpdateItem", writeRequest{
+			TableName:                 *input.TableName,
+			Key:                       input.Key,
+			UpdateExpression:          input.UpdateExpression,
+			ConditionExpression:       input.ConditionExpression,
+			ExpressionAttributeNames:  input.ExpressionAttributeNames,
+			ExpressionAttributeValues: input.ExpressionAttributeValues,
+		})
+	}
+	if err := cmd.confirm(); err != nil {
+		return err
+	}
+	if _, err := client.UpdateItem(ctx, input); err != nil {
+		return cmd.writeError("UpdateItem", err)
+	}
+	return nil
+}
+
+func runDelete() error {
+	cmd, err := parseWriteCommand("delete", printDeleteUsage)
+	if cmd == nil || err != nil {
+		return err
+	}
+	ctx := context.Background()
+	client, cleanup, err := cmd.connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	before, err := cmd.get(ctx, client)
+	if err != nil {
+		return err
+	}
+	if before == nil {
+		fmt.Fprintln(os.Stderr, "item not found, nothing to delete")
+		return nil
+	}
+
+	input := &dynamodb.DeleteItemInput{TableName: &cmd.match.Table.Name, Key: cmd.key}
+	if cmd.ifVersion != nil {
+		expr, err := expression.NewBuilder().WithCondition(
+			expression.Equal(expression.Name(cmd.versionAttr), expression.Value(*cmd.ifVersion)),
+		).Build()
+		if err != nil {
+			return err
+		}
+		input.ConditionExpression = expr.Condition()
+		input.ExpressionAttributeNames = expr.Names()
+		input.ExpressionAttributeValues = expr.Values()
+	}
+
+	cmd.printDiff(before, nil)
+	if cmd.dryRun {
+		return printRequest("DeleteItem", writeRequest{
+			TableName:                 *input.TableName,
+			Key:                       input.Key,
+			ConditionExpression:       input.ConditionExpression,
+			ExpressionAttributeNames:  input.ExpressionAttributeNames,
+			ExpressionAttributeValues: input.ExpressionAttributeValues,
+		})
+	}
+	if err := cmd.confirm(); err != nil {
+		return err
+	}
+	if _, err := client.DeleteItem(ctx, input); err != nil {
+		return cmd.writeError("DeleteItem", err)
+	}
+	return nil
+}
+
+// parseWriteCommand parses the arguments of put, update and delete.
+// It returns a nil command after printing the usage for --help.
+func parseWriteCommand(name string, usage func()) (*writeCommand, error) {
+	if len(os.Args) < 2 || os.Args[1] == "--help" || os.Args[1] == "-h" || os.Args[1] == "help" {
+		usage()
+		return nil, nil
+	}
+	entityName := os.Args[1]
+
+	cmd := &writeCommand{name: name}
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	cmd.connFlags = RegisterConnectionFlags(fs)
+	fs.Func("if-version", "only write if the item's version attribute equals `N`, and set it to N+1", func(s string) error {
+		v, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return fmt.Errorf("not an integer: %q", s)
+		}
+		cmd.ifVersion = &v
+		return nil
+	})
+	fs.StringVar(&cmd.versionAttr, "version-attr", "version", "attribute holding the item's version, for --if-version")
+	fs.BoolVar(&cmd.dryRun, "dry-run", false, "print the request instead of sending it")
+	fs.BoolVar(&cmd.yes, "yes", false, "don't ask for confirmation before writing to AWS")
+	var jsonArg *string
+	if name != "delete" {
+		jsonArg = fs.String("json", "", "attributes as a JSON object, or @file to read it from a file")
+	}
+	if name == "put" {
+		fs.BoolVar(&cmd.ifNotExists, "if-not-exists", false, "only write if no item with the key exists")
+	}
+	if name == "update" {
+		fs.Func("remove", "remove the `attribute` from the item (repeatable)", func(s string) error {
+			cmd.remove = append(cmd.remove, s)
+			return nil
+		})
+	}
+
+	kvArgs, flagArgs := splitKVAndFlags(fs, os.Args[2:])
+	if err := fs.Parse(flagArgs); err != nil {
+		return nil, err
+	}
+	if cmd.ifNotExists && cmd.ifVersion != nil {
+		return nil, fmt.Errorf("--if-not-exists and --if-version are mutually exclusive")
+	}
+	values, err := parseKVArgs(kvArgs)
+	if err != nil {
+		return nil, err
+	}
+
+	schemas, err := loadSchemas()
+	if err != nil {
+		return nil, err
+	}
+	match, ok := ddbcli.FindEntity(schemas, entityName)
+	if !ok {
+		return nil, fmt.Errorf("entity %q not found in schema\n\nRun 'ddb schema entities' to see available entity types", entityName)
+	}
+	cmd.match = match
+
+	cmd.attrs = make(map[string]types.AttributeValue)
+	if jsonArg != nil && *jsonArg != "" {
+		data := []byte(*jsonArg)
+		if path, ok := strings.CutPrefix(*jsonArg, "@"); ok {
+			if data, err = os.ReadFile(path); err != nil {
+				return nil, err
+			}
+		}
+		if cmd.attrs, err = ddbcli.ParseJSONItem(data); err != nil {
+			return nil, err
+		}
+	}
+	if name == "delete" {
+		if err := validateParams(ddbcli.RequiredParams(match.Entity), values); err != nil {
+			return nil, err
+		}
+		cmd.key, err = buildEntityKey(match, values)
+		return cmd, err
+	}
+	for k, raw := range values {
+		attr, av, err := ddbcli.ParseAttribute(match.Entity, k, raw)
+		if err != nil {
+			return nil, err
+		}
+		cmd.attrs[attr] = av
+	}
+	keyValues := ddbcli.KeyValues(cmd.attrs)
+	if err := validateParams(ddbcli.RequiredParams(match.Entity), keyValues); err != nil {
+		return nil, err
+	}
+	if cmd.key, err = buildEntityKey(match, keyValues); err != nil {
+		return nil, err
+	}
+	return cmd, nil
+}
+
+func (cmd *writeCommand) connect(ctx context.Context) (ddbiface.ReadWriteClient, func(), error) {
+	schemas, err := loadSchemas()
+	if err != nil {
+		return nil, nil, err
+	}
+	return cmd.connFlags.Connect(ctx, schemas)
+}
+
+// get reads the current item, or returns nil if there is none.
+func (cmd *writeCommand) get(ctx context.Context, client ddbiface.ReadWriteClient) (map[string]types.AttributeValue, error) {
+	consistent := true
+	out, err := client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      &cmd.match.Table.Name,
+		Key:            cmd.key,
+		ConsistentRead: &consistent,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("GetItem: %w", err)
+	}
+	return out.Item, nil
+}
+
+// completeItem adds the attributes the schema derives from the item's fields:
+// the primary key, the entity type, the GSI keys and the next version.
+func (cmd *writeCommand) completeItem(item map[string]types.AttributeValue) error {
+	for k, v := range cmd.key {
+		item[k] = v
+	}
+	table, entity := cmd.match.Table, cmd.match.Entity
+	if table.EntityTypeKey != "" {
+		typ := entity.EntityTypeValue
+		if typ == "" {
+			typ = entity.Type
+		}
+		item[table.EntityTypeKey] = &types.AttributeValueMemberS{Value: typ}
+	}
+	if cmd.ifVersion != nil {
+		item[cmd.versionAttr] = &types.AttributeValueMemberN{Value: strconv.FormatInt(*cmd.ifVersion+1, 10)}
+	}
+
+	fields := make(map[string]bool, len(entity.Fields))
+	for _, f := range entity.Fields {
+		fields[f.Tag] = true
+	}
+	values := ddbcli.KeyValues(item)
+	for _, m := range entity.GSIMappings {
+		var gsi *schema.GSI
+		for i := range table.GSIs {
+			if strings.EqualFold(table.GSIs[i].Name, m.GSI) {
+				gsi = &table.GSIs[i]
+			}
+		}
+		if gsi == nil {
+			continue
+		}
+		included := true
+		if c := m.Condition; c != nil {
+			switch c.Kind {
+			case "present":
+				_, included = values[c.Field]
+			case "equals":
+				included = values[c.Field] == c.Value
+			default:
+				// Custom conditions are Go code, leave the keys as they are.
+				continue
+			}
+		}
+		if !included {
+			attrs := []string{gsi.PartitionKey.Name}
+			if gsi.SortKey != nil {
+				attrs = append(attrs, gsi.SortKey.Name)
+			}
+			for _, attr := range attrs {
+				if !fields[attr] {
+					delete(item, attr)
+				}
+			}
+			continue
+		}
+		pk, err := ddbcli.BuildKeyFromEntity(m.PartitionPattern, entity, values)
+		if err != nil {
+			return fmt.Errorf("building %s partition key: %w", m.GSI, err)
+		}
+		item[gsi.PartitionKey.Name] = makeAttributeValue(pk, gsi.PartitionKey.Kind)
+		if gsi.SortKey != nil && m.SortPattern != "" {
+			sk, err := ddbcli.BuildKeyFromEntity(m.SortPattern, entity, values)
+			if err != nil {
+				return fmt.Errorf("building %s sort key: %w", m.GSI, err)
+			}
+			item[gsi.SortKey.Name] = makeAttributeValue(sk, gsi.SortKey.Kind)
+		}
+	}
+	return nil
+}
+
+// printDiff prints the changes to the item to stderr, and reports whether there are any.
+func (cmd *writeCommand) printDiff(before, after map[string]types.AttributeValue) bool {
+	changes := ddbcli.DiffItems(before, after)
+	if len(changes) == 0 {
+		fmt.Fprintf(os.Stderr, "%s %s: no changes\n", cmd.match.Entity.Type, cmd.keyString())
+		return false
+	}
+	verb := "update"
+	switch {
+	case before == nil:
+		verb = "create"
+	case after == nil:
+		verb = "delete"
+	}
+	fmt.Fprintf(os.Stderr, "%s %s %s in %s:\n", verb, cmd.match.Entity.Type, cmd.keyString(), cmd.match.Table.Name)
+	for _, c := range changes {
+		fmt.Fprintln(os.Stderr, "  "+c.String())
+	}
+	return true
+}
+
+func (cmd *writeCommand) keyString() string {
+	values := ddbcli.KeyValues(cmd.key)
+	names := make([]string, 0, len(values))
+	for k := range values {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	parts := make([]string, len(names))
+	for i, k := range names {
+		parts[i] = k + "=" + values[k]
+	}
+	return strings.Join(parts, " ")
+}
+
+// confirm asks before writing to AWS, unless --yes was given.
+func (cmd *writeCommand) confirm() error {
+	if cmd.yes || cmd.connFlags.IsLocal() {
+		return nil
+	}
+	if info, err := os.Stdin.Stat(); err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return fmt.Errorf("refusing to %s in AWS without confirmation, pass --yes", cmd.name)
+	}
+	fmt.Fprintf(os.Stderr, "%s the item in %s? [y/N] ", cmd.name, cmd.match.Table.Name)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	if a := strings.ToLower(strings.TrimSpace(answer)); a != "y" && a != "yes" {
+		return fmt.Errorf("%s cancelled", cmd.name)
+	}
+	return nil
+}
+
+func (cmd *writeCommand) writeError(op string, err error) error {
+	var failed *types.ConditionalCheckFailedException
+	if !errors.As(err, &failed) {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	switch {
+	case cmd.ifNotExists:
+		return fmt.Errorf("%s: the item already exists", op)
+	case cmd.ifVersion != nil:
+		return fmt.Errorf("%s: the item's %s is no longer %d, it was changed concurrently", op, cmd.versionAttr, *cmd.ifVersion)
+	}
+	return fmt.Errorf("%s: the item no longer exists", op)
+}
+
+// writeRequest is a write request in the input JSON of the AWS CLI,
+// e.g. aws dynamodb put-item --cli-input-json file://request.json.
+type writeRequest struct {
+	TableName                 string
+	Item                      map[string]types.AttributeValue
+	Key                       map[string]types.AttributeValue
+	UpdateExpression          *string
+	ConditionExpression       *string
+	ExpressionAttributeNames  map[string]string
+	ExpressionAttributeValues map[string]types.AttributeValue
+}
+
+func printRequest(op string, req writeRequest) error {
+	out := map[string]any{"TableName": req.TableName}
+	for name, item := range map[string]map[string]types.AttributeValue{
+		"Item":                      req.Item,
+		"Key":                       req.Key,
+		"ExpressionAttributeValues": req.ExpressionAttributeValues,
+	} {
+		if len(item) == 0 {
+			continue
+		}
+		attrs, err := ddbexport.JSONAttributes(item)
+		if err != nil {
+			return err
+		}
+		out[name] = attrs
+	}
+	if req.UpdateExpression != nil {
+		out["UpdateExpression"] = *req.UpdateExpression
+	}
+	if req.ConditionExpression != nil {
+		out["ConditionExpression"] = *req.ConditionExpression
+	}
+	if len(req.ExpressionAttributeNames) > 0 {
+		out["ExpressionAttributeNames"] = req.ExpressionAttributeNames
+	}
+	fmt.Fprintf(os.Stderr, "dry run, not sending this %s request:\n", op)
+	return writeJSONStdout(out)
+}
+
+func printPutUsage() {
+	fmt.Println(`ddb put - Create or replace an item
+
+Usage:
+  ddb put <EntityType> <field=value> [...] [flags]
+
+Builds the item's primary key, entity type and GSI keys from its fields like
+'ddb get' does, prints how the item changes and writes it. Values are typed by
+the entity's fields; use field:=JSON for lists and maps, or --json for the
+whole item.
+
+Flags:
+  --json JSON         Attributes as a JSON object, or @file to read them from a file
+  --if-not-exists     Only write if no item with the key exists
+  --if-version N      Only write if the item's version is N, and set it to N+1
+  --version-attr STR  Attribute holding the version (default "version")
+  --dry-run           Print the PutItem request instead of sending it
+  --yes               Don't ask for confirmation before writing to AWS
+  --aws               Connect to AWS DynamoDB (default)
+  --region STRING     AWS region
+  --profile STRING    AWS profile name
+  --endpoint URL      Custom DynamoDB endpoint
+  --db PATH           Path to local database directory
+  --memory            Use in-memory database
+
+Examples:
+  ddb put User id=abc123 email=alice@example.com --if-not-exists
+  ddb put Order tenantID=t1 orderID=o1 status=pending 'items:=["sku-1","sku-2"]'
+  ddb put Order --json @order.json --dry-run`)
+}
+
+func printUpdateUsage() {
+	fmt.Println(`ddb update - Change attributes of an existing item
+
+Usage:
+  ddb update <EntityType> <key field=value> [...] <field=value> [...] [flags]
+
+Reads the item, sets the given fields, recomputes its GSI keys and prints how
+the item changes. Only the changed attributes are written, and only if the
+item still exists.
+
+Flags:
+  --json JSON         Attributes to set as a JSON object, or @file
+  --remove ATTR       Remove an attribute (repeatable)
+  --if-version N      Only write if the item's version is N, and set it to N+1
+  --version-attr STR  Attribute holding the version (default "version")
+  --dry-run           Print the UpdateItem request instead of sending it
+  --yes               Don't ask for confirmation before writing to AWS
+  --aws               Connect to AWS DynamoDB (default)
+  --region STRING     AWS region
+  --profile STRING    AWS profile name
+  --endpoint URL      Custom DynamoDB endpoint
+  --db PATH           Path to local database directory
+  --memory            Use in-memory database
+
+Examples:
+  ddb update Order tenantID=t1 orderID=o1 status=shipped
+  ddb update Order tenantID=t1 orderID=o1 --remove note --if-version 3
+  ddb update User id=abc123 email=bob@example.com --dry-run`)
+}
+
+func printDeleteUsage() {
+	fmt.Println(`ddb delete - Delete an item by entity type and key fields
+
+Usage:
+  ddb delete <EntityType> <field=value> [...] [flags]
+
+Flags:
+  --if-version N      Only delete if the item's version is N
+  --version-attr STR  Attribute holding the version (default "version")
+  --dry-run           Print the DeleteItem request instead of sending it
+  --yes               Don't ask for confirmation before deleting in AWS
+  --aws               Connect to AWS DynamoDB (default)
+  --region STRING     AWS region
+  --profile STRING    AWS profile name
+  --endpoint URL      Custom DynamoDB endpoint
+  --db PATH           Path to local database directory
+  --memory            Use in-memory database
+
+Examples:
+  ddb delete User id=abc123
+  ddb delete Order tenantID=t1 orderID=o1 --if-version 3 --yes`)
+}
@@ -0,0 +1,122 @@
+// Package dbt generates dbt sources and staging models of DynamoDB tables from their
+// schema, so that the analytics catalog follows the Go entities.
+//
+// The source tables hold DynamoDB exports to S3 in the DynamoDB JSON format, loaded
+// with one row per item and the item in a JSON column named item, as in the Item field
+// of an exported line:
+//
+//	{"Item": {"pk": {"S": "TENANT#1"}, "amount": {"N": "42"}}}
+//
+// [Generate] writes a source per table and a staging model per entity, which selects
+// the entity's items from the table, by the table's entity type attribute or else by
+// the literal prefixes of the entity's key patterns, and types a column per field. The
+// models are documented with the key patterns, GSIs and validation rules, and not_null
+// and accepted_values tests derived from the required and enum rules. ddbgen writes
+// them to schema/dbt/ with ddb gen --dbt.
+package dbt
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/acksell/bezos/dynamodb/schema"
+)
+
+// SourceName is the name of the dbt source of the DynamoDB tables.
+const SourceName = "dynamodb"
+
+// ItemColumn is the column of the source tables holding the item in DynamoDB JSON.
+const ItemColumn = "item"
+
+// Header starts every generated file.
+const Header = "Generated by ddbgen. DO NOT EDIT."
+
+// File is a generated file, Path is relative to the dbt directory.
+type File struct {
+	Path    string
+	Content []byte
+}
+
+// Generate renders the dbt sources and staging models of the tables of s in dialect d:
+// models/_dynamodb__sources.yml, models/_dynamodb__models.yml, and a
+// models/stg_<table>__<entity>.sql per entity. Event streams are not modelled.
+func Generate(s schema.Schema, d Dialect) ([]File, error) {
+	sql, err := d.sql()
+	if err != nil {
+		return nil, err
+	}
+	var files []File
+	var models []model
+	for _, t := range s.Tables {
+		for _, e := range t.Entities {
+			m := newModel(t, e)
+			models = append(models, m)
+			files = append(files, File{
+				Path:    path.Join("models", m.Name+".sql"),
+				Content: []byte(m.sql(sql)),
+			})
+		}
+	}
+	sources, err := sourcesYAML(s)
+	if err != nil {
+		return nil, err
+	}
+	docs, err := modelsYAML(models, sql)
+	if err != nil {
+		return nil, err
+	}
+	files = append([]File{
+		{Path: path.Join("models", "_"+SourceName+"__sources.yml"), Content: sources},
+		{Path: path.Join("models", "_"+SourceName+"__models.yml"), Content: docs},
+	}, files...)
+	return files, nil
+}
+
+// ModelName is the name of the staging model of entity e of table t,
+// e.g. "stg_orders__order".
+func ModelName(t schema.Table, e schema.Entity) string {
+	return "stg_" + snakeCase(t.Name) + "__" + snakeCase(e.Type)
+}
+
+// snakeCase converts an identifier or attribute name to snake case,
+// e.g. "tenantID" to "tenant_id" and "bezos-orders" to "bezos_orders".
+func snakeCase(s string) string {
+	var b strings.Builder
+	runes := []rune(s)
+	for i, r := range runes {
+		isUpper := r >= 'A' && r <= 'Z'
+		isAlnum := isUpper || r >= 'a' && r <= 'z' || r >= '0' && r <= '9'
+		if !isAlnum {
+			if b.Len() > 0 && !strings.HasSuffix(b.String(), "_") {
+				b.WriteByte('_')
+			}
+			continue
+		}
+		if isUpper && i > 0 {
+			prev := runes[i-1]
+			prevLowerOrDigit := prev >= 'a' && prev <= 'z' || prev >= '0' && prev <= '9'
+			nextLower := i+1 < len(runes) && runes[i+1] >= 'a' && runes[i+1] <= 'z'
+			prevUpper := prev >= 'A' && prev <= 'Z'
+			if (prevLowerOrDigit || prevUpper && nextLower) && !strings.HasSuffix(b.String(), "_") {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteString(strings.ToLower(string(r)))
+	}
+	return strings.Trim(b.String(), "_")
+}
+
+// literalPrefix returns the text of a key pattern before its first placeholder, and
+// whether the pattern has no placeholder at all.
+func literalPrefix(pattern string) (prefix string, constant bool) {
+	i := strings.IndexByte(pattern, '{')
+	if i < 0 {
+		return pattern, true
+	}
+	return pattern[:i], false
+}
+
+func keyKindDescription(k schema.KeyDef) string {
+	return fmt.Sprintf("%s (%s)", k.Name, k.Kind)
+}
@@ -0,0 +1,159 @@
+package dbt_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/acksell/bezos/dynamodb/dbt"
+	"github.com/acksell/bezos/dynamodb/schema"
+	"gopkg.in/yaml.v3"
+)
+
+var testSchema = schema.Schema{Tables: []schema.Table{
+	{
+		Name:          "orders",
+		PartitionKey:  schema.KeyDef{Name: "pk", Kind: "S"},
+		SortKey:       &schema.KeyDef{Name: "sk", Kind: "S"},
+		EntityTypeKey: "type",
+		GSIs:          []schema.GSI{{Name: "ByStatus", PartitionKey: schema.KeyDef{Name: "gsi1pk", Kind: "S"}}},
+		Entities: []schema.Entity{{
+			Type:                "Order",
+			PartitionKeyPattern: "TENANT#{tenantID}",
+			SortKeyPattern:      "ORDER#{orderID}",
+			Fields: []schema.Field{
+				{Name: "OrderID", Tag: "orderID", Type: "string", Rules: []schema.Rule{{Name: "required"}}},
+				{Name: "Amount", Tag: "amount", Type: "int"},
+				{Name: "Status", Tag: "status", Type: "string", Rules: []schema.Rule{{Name: "enum", Arg: "paid|shipped"}}},
+				{Name: "Tags", Tag: "tags", Type: "[]string"},
+			},
+			GSIMappings: []schema.GSIMapping{{GSI: "ByStatus", PartitionPattern: "STATUS#{status}"}},
+		}},
+	},
+	{
+		Name:          "users",
+		PartitionKey:  schema.KeyDef{Name: "pk", Kind: "S"},
+		SortKey:       &schema.KeyDef{Name: "sk", Kind: "S"},
+		TimeToLiveKey: "expiresAt",
+		Entities: []schema.Entity{{
+			Type:                "UserProfile",
+			PartitionKeyPattern: "USER#{userID}",
+			SortKeyPattern:      "PROFILE",
+			Fields: []schema.Field{
+				{Name: "CreatedAt", Tag: "createdAt", Type: "time.Time"},
+				{Name: "Admin", Tag: "admin", Type: "*bool"},
+			},
+		}},
+	},
+}}
+
+func generate(t *testing.T, d dbt.Dialect) map[string]string {
+	t.Helper()
+	files, err := dbt.Generate(testSchema, d)
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	out := map[string]string{}
+	for _, f := range files {
+		out[f.Path] = string(f.Content)
+	}
+	return out
+}
+
+func TestGenerate_Models(t *testing.T) {
+	files := generate(t, dbt.Snowflake)
+	order, ok := files["models/stg_orders__order.sql"]
+	if !ok {
+		t.Fatalf("no order model in %v", files)
+	}
+	for _, want := range []string{
+		"{{ source('dynamodb', 'orders') }}",
+		"item['orderID']['S']::varchar as order_id,",
+		"try_to_number(item['amount']['N']::varchar) as amount,",
+		"item['tags'] as tags",
+		"where item['type']['S']::varchar = 'Order'",
+	} {
+		if !strings.Contains(order, want) {
+			t.Errorf("order model is missing %q:\n%s", want, order)
+		}
+	}
+
+	// Without an entity type attribute, items are told apart by their key patterns.
+	profile := files["models/stg_users__user_profile.sql"]
+	for _, want := range []string{
+		"try_to_timestamp_tz(item['createdAt']['S']::varchar) as created_at,",
+		"try_to_boolean(item['admin']['BOOL']::varchar) as admin,",
+		"to_timestamp_tz(try_to_number(item['expiresAt']['N']::varchar)) as expires_at",
+		"where startswith(item['pk']['S']::varchar, 'USER#')",
+		"and item['sk']['S']::varchar = 'PROFILE'",
+	} {
+		if !strings.Contains(profile, want) {
+			t.Errorf("profile model is missing %q:\n%s", want, profile)
+		}
+	}
+}
+
+func TestGenerate_Dialects(t *testing.T) {
+	tests := map[dbt.Dialect]string{
+		dbt.BigQuery: `SAFE_CAST(JSON_VALUE(item, '$."amount".N') AS INT64) as amount`,
+		dbt.DuckDB:   `TRY_CAST(json_extract_string(item, '$."amount".N') AS BIGINT) as amount`,
+	}
+	for d, want := range tests {
+		if order := generate(t, d)["models/stg_orders__order.sql"]; !strings.Contains(order, want) {
+			t.Errorf("%s order model is missing %q:\n%s", d, want, order)
+		}
+	}
+	if _, err := dbt.ParseDialect("oracle"); err == nil {
+		t.Error("ParseDialect(oracle) succeeded")
+	}
+}
+
+func TestGenerate_Docs(t *testing.T) {
+	files := generate(t, dbt.DuckDB)
+
+	var sources struct {
+		Sources []struct {
+			Name   string
+			Tables []struct {
+				Name string
+				Meta struct {
+					GSIs []struct{ Name string }
+				}
+			}
+		}
+	}
+	if err := yaml.Unmarshal([]byte(files["models/_dynamodb__sources.yml"]), &sources); err != nil {
+		t.Fatalf("parsing sources: %v", err)
+	}
+	if len(sources.Sources) != 1 || len(sources.Sources[0].Tables) != 2 ||
+		len(sources.Sources[0].Tables[0].Meta.GSIs) != 1 {
+		t.Errorf("unexpected sources %+v", sources)
+	}
+
+	var models struct {
+		Models []struct {
+			Name    string
+			Columns []struct {
+				Name     string
+				DataType string `yaml:"data_type"`
+				Tests    []any
+			}
+		}
+	}
+	if err := yaml.Unmarshal([]byte(files["models/_dynamodb__models.yml"]), &models); err != nil {
+		t.Fatalf("parsing models: %v", err)
+	}
+	if len(models.Models) != 2 || models.Models[0].Name != "stg_orders__order" {
+		t.Fatalf("unexpected models %+v", models)
+	}
+	tests := map[string]int{}
+	for _, c := range models.Models[0].Columns {
+		tests[c.Name] = len(c.Tests)
+	}
+	// pk, sk and type are never null, order_id is required and status has an enum.
+	want := map[string]int{"pk": 1, "sk": 1, "type": 1, "order_id": 1, "amount": 0, "status": 1, "tags": 0}
+	for col, n := range want {
+		if tests[col] != n {
+			t.Errorf("column %s has %d tests, want %d", col, tests[col], n)
+		}
+	}
+}
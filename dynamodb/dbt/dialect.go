@@ -0,0 +1,233 @@
+package dbt
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Dialect is the SQL dialect of the warehouse the models run in.
+type Dialect string
+
+const (
+	Snowflake Dialect = "snowflake"
+	BigQuery  Dialect = "bigquery"
+	DuckDB    Dialect = "duckdb"
+)
+
+// Dialects lists the dialects [Generate] supports.
+func Dialects() []Dialect {
+	return []Dialect{Snowflake, BigQuery, DuckDB}
+}
+
+// ParseDialect returns the dialect named s.
+func ParseDialect(s string) (Dialect, error) {
+	for _, d := range Dialects() {
+		if string(d) == s {
+			return d, nil
+		}
+	}
+	return "", fmt.Errorf("unknown dbt dialect %q, expected one of %s", s, dialectList())
+}
+
+func dialectList() string {
+	names := make([]string, 0, len(Dialects()))
+	for _, d := range Dialects() {
+		names = append(names, string(d))
+	}
+	return strings.Join(names, ", ")
+}
+
+func (d Dialect) sql() (sqlDialect, error) {
+	switch d {
+	case Snowflake:
+		return snowflake{}, nil
+	case BigQuery:
+		return bigQuery{}, nil
+	case DuckDB:
+		return duckDB{}, nil
+	default:
+		return nil, fmt.Errorf("unknown dbt dialect %q, expected one of %s", d, dialectList())
+	}
+}
+
+// columnKind is the SQL type of a column.
+type columnKind int
+
+const (
+	kindString columnKind = iota
+	kindInteger
+	kindFloat
+	kindBoolean
+	kindTimestamp // an RFC 3339 string, as attributevalue marshals time.Time
+	kindEpoch     // a number of seconds since the epoch, as TTL attributes
+	kindJSON      // the attribute in DynamoDB JSON, for lists, maps and unknown types
+)
+
+// sqlDialect renders the expressions of a dialect. attr is a DynamoDB attribute name
+// and typ a DynamoDB JSON type descriptor such as "S" or "N".
+type sqlDialect interface {
+	// text extracts the value of attribute attr with descriptor typ as text.
+	text(attr, typ string) string
+	// json extracts attribute attr in DynamoDB JSON.
+	json(attr string) string
+	// cast converts text to kind, yielding null where it doesn't convert.
+	cast(text string, kind columnKind) string
+	dataType(kind columnKind) string
+	startsWith(expr, prefix string) string
+	literal(s string) string
+}
+
+// sqlLiteral quotes s by doubling single quotes.
+func sqlLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// jsonPath returns the JSONPath of attribute attr, followed by typ if set.
+func jsonPath(attr, typ string) string {
+	p := `$."` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(attr) + `"`
+	if typ != "" {
+		p += "." + typ
+	}
+	return p
+}
+
+type snowflake struct{}
+
+func (snowflake) text(attr, typ string) string {
+	return fmt.Sprintf("%s[%s][%s]::varchar", ItemColumn, sqlLiteral(attr), sqlLiteral(typ))
+}
+
+func (snowflake) json(attr string) string {
+	return fmt.Sprintf("%s[%s]", ItemColumn, sqlLiteral(attr))
+}
+
+func (snowflake) cast(text string, kind columnKind) string {
+	switch kind {
+	case kindInteger:
+		return "try_to_number(" + text + ")"
+	case kindFloat:
+		return "try_to_double(" + text + ")"
+	case kindBoolean:
+		return "try_to_boolean(" + text + ")"
+	case kindTimestamp:
+		return "try_to_timestamp_tz(" + text + ")"
+	case kindEpoch:
+		return "to_timestamp_tz(try_to_number(" + text + "))"
+	}
+	return text
+}
+
+func (snowflake) dataType(kind columnKind) string {
+	switch kind {
+	case kindInteger:
+		return "number"
+	case kindFloat:
+		return "float"
+	case kindBoolean:
+		return "boolean"
+	case kindTimestamp, kindEpoch:
+		return "timestamp_tz"
+	case kindJSON:
+		return "variant"
+	}
+	return "varchar"
+}
+
+func (snowflake) startsWith(expr, prefix string) string {
+	return fmt.Sprintf("startswith(%s, %s)", expr, sqlLiteral(prefix))
+}
+
+func (snowflake) literal(s string) string { return sqlLiteral(s) }
+
+type bigQuery struct{}
+
+func (q bigQuery) text(attr, typ string) string {
+	return fmt.Sprintf("JSON_VALUE(%s, %s)", ItemColumn, q.literal(jsonPath(attr, typ)))
+}
+
+func (q bigQuery) json(attr string) string {
+	return fmt.Sprintf("JSON_QUERY(%s, %s)", ItemColumn, q.literal(jsonPath(attr, "")))
+}
+
+func (bigQuery) cast(text string, kind columnKind) string {
+	switch kind {
+	case kindInteger:
+		return "SAFE_CAST(" + text + " AS INT64)"
+	case kindFloat:
+		return "SAFE_CAST(" + text + " AS FLOAT64)"
+	case kindBoolean:
+		return "SAFE_CAST(" + text + " AS BOOL)"
+	case kindTimestamp:
+		return "SAFE_CAST(" + text + " AS TIMESTAMP)"
+	case kindEpoch:
+		return "TIMESTAMP_SECONDS(SAFE_CAST(" + text + " AS INT64))"
+	}
+	return text
+}
+
+func (bigQuery) dataType(kind columnKind) string {
+	switch kind {
+	case kindInteger:
+		return "INT64"
+	case kindFloat:
+		return "FLOAT64"
+	case kindBoolean:
+		return "BOOL"
+	case kindTimestamp, kindEpoch:
+		return "TIMESTAMP"
+	case kindJSON:
+		return "JSON"
+	}
+	return "STRING"
+}
+
+func (q bigQuery) startsWith(expr, prefix string) string {
+	return fmt.Sprintf("STARTS_WITH(%s, %s)", expr, q.literal(prefix))
+}
+
+// literal quotes s with backslash escapes, BigQuery doesn't accept doubled quotes.
+func (bigQuery) literal(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
+
+type duckDB struct{}
+
+func (duckDB) text(attr, typ string) string {
+	return fmt.Sprintf("json_extract_string(%s, %s)", ItemColumn, sqlLiteral(jsonPath(attr, typ)))
+}
+
+func (duckDB) json(attr string) string {
+	return fmt.Sprintf("json_extract(%s, %s)", ItemColumn, sqlLiteral(jsonPath(attr, "")))
+}
+
+func (d duckDB) cast(text string, kind columnKind) string {
+	switch kind {
+	case kindEpoch:
+		return "to_timestamp(TRY_CAST(" + text + " AS BIGINT))"
+	case kindString, kindJSON:
+		return text
+	}
+	return "TRY_CAST(" + text + " AS " + d.dataType(kind) + ")"
+}
+
+func (duckDB) dataType(kind columnKind) string {
+	switch kind {
+	case kindInteger:
+		return "BIGINT"
+	case kindFloat:
+		return "DOUBLE"
+	case kindBoolean:
+		return "BOOLEAN"
+	case kindTimestamp, kindEpoch:
+		return "TIMESTAMPTZ"
+	case kindJSON:
+		return "JSON"
+	}
+	return "VARCHAR"
+}
+
+func (duckDB) startsWith(expr, prefix string) string {
+	return fmt.Sprintf("starts_with(%s, %s)", expr, sqlLiteral(prefix))
+}
+
+func (duckDB) literal(s string) string { return sqlLiteral(s) }
@@ -0,0 +1,184 @@
+package dbt
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/acksell/bezos/dynamodb/schema"
+)
+
+// model is the staging model of an entity.
+type model struct {
+	Name    string
+	Table   schema.Table
+	Entity  schema.Entity
+	Columns []column
+}
+
+// column is a column of a staging model, read from a DynamoDB attribute.
+type column struct {
+	Name        string
+	Attr        string
+	Typ         string // DynamoDB JSON type descriptor, empty for kindJSON
+	Kind        columnKind
+	Description string
+	Required    bool
+	Enum        []string
+}
+
+func newModel(t schema.Table, e schema.Entity) model {
+	m := model{Name: ModelName(t, e), Table: t, Entity: e}
+	seen := map[string]bool{}
+	add := func(c column) {
+		if seen[c.Attr] || seen["col:"+c.Name] {
+			return
+		}
+		seen[c.Attr], seen["col:"+c.Name] = true, true
+		m.Columns = append(m.Columns, c)
+	}
+
+	add(keyColumn(t.PartitionKey, "Partition key", e.PartitionKeyPattern))
+	if t.SortKey != nil {
+		add(keyColumn(*t.SortKey, "Sort key", e.SortKeyPattern))
+	}
+	if t.EntityTypeKey != "" {
+		add(column{
+			Name:        snakeCase(t.EntityTypeKey),
+			Attr:        t.EntityTypeKey,
+			Typ:         "S",
+			Description: "Entity type, always " + entityTypeValue(e) + ".",
+			Required:    true,
+		})
+	}
+	for _, f := range e.Fields {
+		add(fieldColumn(f))
+	}
+	if t.TimeToLiveKey != "" {
+		add(column{
+			Name:        snakeCase(t.TimeToLiveKey),
+			Attr:        t.TimeToLiveKey,
+			Typ:         "N",
+			Kind:        kindEpoch,
+			Description: "Time the item expires at, DynamoDB deletes it after.",
+		})
+	}
+	return m
+}
+
+func keyColumn(k schema.KeyDef, what, pattern string) column {
+	c := column{
+		Name:        snakeCase(k.Name),
+		Attr:        k.Name,
+		Typ:         k.Kind,
+		Description: what + ", " + pattern + ".",
+		Required:    true,
+	}
+	if k.Kind == "N" {
+		c.Kind = kindInteger
+	}
+	return c
+}
+
+func fieldColumn(f schema.Field) column {
+	c := column{
+		Name:        snakeCase(f.Tag),
+		Attr:        f.Tag,
+		Description: fmt.Sprintf("Field %s (%s).", f.Name, f.Type),
+	}
+	c.Typ, c.Kind = fieldKind(f.Type)
+	var rules []string
+	for _, r := range f.Rules {
+		switch r.Name {
+		case "required":
+			c.Required = true
+		case "enum":
+			c.Enum = strings.Split(r.Arg, "|")
+		}
+		if r.Arg != "" {
+			rules = append(rules, r.Name+"="+r.Arg)
+		} else {
+			rules = append(rules, r.Name)
+		}
+	}
+	if len(rules) > 0 {
+		c.Description += " Validated with " + strings.Join(rules, ", ") + "."
+	}
+	return c
+}
+
+// fieldKind maps the Go type of a field, as written to the schema, to the DynamoDB JSON
+// type descriptor attributevalue marshals it with and a column kind. Named types other
+// than time.Time can't be told apart from structs, so they are kept as JSON.
+func fieldKind(goType string) (typ string, kind columnKind) {
+	switch strings.TrimLeft(goType, "*") {
+	case "string":
+		return "S", kindString
+	case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64":
+		return "N", kindInteger
+	case "float32", "float64":
+		return "N", kindFloat
+	case "bool":
+		return "BOOL", kindBoolean
+	case "time.Time":
+		return "S", kindTimestamp
+	case "[]uint8":
+		return "B", kindString
+	}
+	return "", kindJSON
+}
+
+func entityTypeValue(e schema.Entity) string {
+	if e.EntityTypeValue != "" {
+		return e.EntityTypeValue
+	}
+	return e.Type
+}
+
+// filters returns the conditions that select the entity's items from the table.
+func (m model) filters(sql sqlDialect) []string {
+	if m.Table.EntityTypeKey != "" {
+		return []string{sql.text(m.Table.EntityTypeKey, "S") + " = " + sql.literal(entityTypeValue(m.Entity))}
+	}
+	var filters []string
+	keyFilter := func(k schema.KeyDef, pattern string) {
+		prefix, constant := literalPrefix(pattern)
+		switch {
+		case constant:
+			filters = append(filters, sql.text(k.Name, k.Kind)+" = "+sql.literal(prefix))
+		case prefix != "":
+			filters = append(filters, sql.startsWith(sql.text(k.Name, k.Kind), prefix))
+		}
+	}
+	keyFilter(m.Table.PartitionKey, m.Entity.PartitionKeyPattern)
+	if m.Table.SortKey != nil && m.Entity.SortKeyPattern != "" {
+		keyFilter(*m.Table.SortKey, m.Entity.SortKeyPattern)
+	}
+	return filters
+}
+
+func (m model) sql(sql sqlDialect) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "-- %s\n", Header)
+	fmt.Fprintf(&b, "-- %s entities of the DynamoDB table %s.\n\n", m.Entity.Type, m.Table.Name)
+	fmt.Fprintf(&b, "with source as (\n    select * from {{ source('%s', '%s') }}\n)\n\nselect\n", SourceName, m.Table.Name)
+	for i, c := range m.Columns {
+		expr := sql.json(c.Attr)
+		if c.Kind != kindJSON {
+			expr = sql.cast(sql.text(c.Attr, c.Typ), c.Kind)
+		}
+		sep := ","
+		if i == len(m.Columns)-1 {
+			sep = ""
+		}
+		fmt.Fprintf(&b, "    %s as %s%s\n", expr, c.Name, sep)
+	}
+	b.WriteString("from source\n")
+	for i, f := range m.filters(sql) {
+		kw := "where"
+		if i > 0 {
+			kw = "  and"
+		}
+		fmt.Fprintf(&b, "%s %s\n", kw, f)
+	}
+	return b.String()
+}
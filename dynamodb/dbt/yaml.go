@@ -0,0 +1,170 @@
+package dbt
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/acksell/bezos/dynamodb/schema"
+	"gopkg.in/yaml.v3"
+)
+
+type sourcesFile struct {
+	Version int         `yaml:"version"`
+	Sources []sourceDef `yaml:"sources"`
+}
+
+type sourceDef struct {
+	Name        string           `yaml:"name"`
+	Description string           `yaml:"description"`
+	Tables      []sourceTableDef `yaml:"tables"`
+}
+
+type sourceTableDef struct {
+	Name        string      `yaml:"name"`
+	Description string      `yaml:"description"`
+	Meta        tableMeta   `yaml:"meta"`
+	Columns     []columnDef `yaml:"columns"`
+}
+
+type tableMeta struct {
+	PartitionKey  string    `yaml:"partition_key"`
+	SortKey       string    `yaml:"sort_key,omitempty"`
+	EntityTypeKey string    `yaml:"entity_type_key,omitempty"`
+	TimeToLiveKey string    `yaml:"time_to_live_key,omitempty"`
+	GSIs          []gsiMeta `yaml:"gsis,omitempty"`
+	Entities      []string  `yaml:"entities,omitempty"`
+}
+
+type gsiMeta struct {
+	Name         string `yaml:"name"`
+	PartitionKey string `yaml:"partition_key"`
+	SortKey      string `yaml:"sort_key,omitempty"`
+}
+
+type modelsFile struct {
+	Version int        `yaml:"version"`
+	Models  []modelDef `yaml:"models"`
+}
+
+type modelDef struct {
+	Name        string      `yaml:"name"`
+	Description string      `yaml:"description"`
+	Meta        modelMeta   `yaml:"meta"`
+	Columns     []columnDef `yaml:"columns"`
+}
+
+type modelMeta struct {
+	DynamoDBTable       string           `yaml:"dynamodb_table"`
+	Entity              string           `yaml:"entity"`
+	PartitionKeyPattern string           `yaml:"partition_key_pattern"`
+	SortKeyPattern      string           `yaml:"sort_key_pattern,omitempty"`
+	GSIs                []gsiMappingMeta `yaml:"gsis,omitempty"`
+}
+
+type gsiMappingMeta struct {
+	Name             string `yaml:"name"`
+	PartitionPattern string `yaml:"partition_pattern"`
+	SortPattern      string `yaml:"sort_pattern,omitempty"`
+	Condition        string `yaml:"condition,omitempty"`
+}
+
+type columnDef struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+	DataType    string `yaml:"data_type,omitempty"`
+	Tests       []any  `yaml:"tests,omitempty"`
+}
+
+func sourcesYAML(s schema.Schema) ([]byte, error) {
+	src := sourceDef{
+		Name:        SourceName,
+		Description: "DynamoDB tables exported to S3 in DynamoDB JSON, one row per item.",
+	}
+	for _, t := range s.Tables {
+		meta := tableMeta{
+			PartitionKey:  keyKindDescription(t.PartitionKey),
+			EntityTypeKey: t.EntityTypeKey,
+			TimeToLiveKey: t.TimeToLiveKey,
+		}
+		if t.SortKey != nil {
+			meta.SortKey = keyKindDescription(*t.SortKey)
+		}
+		for _, g := range t.GSIs {
+			gm := gsiMeta{Name: g.Name, PartitionKey: keyKindDescription(g.PartitionKey)}
+			if g.SortKey != nil {
+				gm.SortKey = keyKindDescription(*g.SortKey)
+			}
+			meta.GSIs = append(meta.GSIs, gm)
+		}
+		for _, e := range t.Entities {
+			meta.Entities = append(meta.Entities, e.Type)
+		}
+		desc := fmt.Sprintf("DynamoDB table %s.", t.Name)
+		if len(meta.Entities) > 0 {
+			desc += " Holds " + strings.Join(meta.Entities, ", ") + "."
+		}
+		src.Tables = append(src.Tables, sourceTableDef{
+			Name:        t.Name,
+			Description: desc,
+			Meta:        meta,
+			Columns: []columnDef{{
+				Name:        ItemColumn,
+				Description: "The item in DynamoDB JSON, the Item field of a line of the export.",
+			}},
+		})
+	}
+	return marshalYAML(sourcesFile{Version: 2, Sources: []sourceDef{src}})
+}
+
+func modelsYAML(models []model, sql sqlDialect) ([]byte, error) {
+	f := modelsFile{Version: 2, Models: []modelDef{}}
+	for _, m := range models {
+		def := modelDef{
+			Name: m.Name,
+			Description: fmt.Sprintf("%s entities of the DynamoDB table %s, one row per item.",
+				m.Entity.Type, m.Table.Name),
+			Meta: modelMeta{
+				DynamoDBTable:       m.Table.Name,
+				Entity:              m.Entity.Type,
+				PartitionKeyPattern: m.Entity.PartitionKeyPattern,
+				SortKeyPattern:      m.Entity.SortKeyPattern,
+			},
+		}
+		for _, g := range m.Entity.GSIMappings {
+			gm := gsiMappingMeta{Name: g.GSI, PartitionPattern: g.PartitionPattern, SortPattern: g.SortPattern}
+			if g.Condition != nil {
+				gm.Condition = g.Condition.Description
+			}
+			def.Meta.GSIs = append(def.Meta.GSIs, gm)
+		}
+		for _, c := range m.Columns {
+			cd := columnDef{Name: c.Name, Description: c.Description, DataType: sql.dataType(c.Kind)}
+			if c.Required {
+				cd.Tests = append(cd.Tests, "not_null")
+			}
+			if len(c.Enum) > 0 {
+				cd.Tests = append(cd.Tests, map[string]any{
+					"accepted_values": map[string]any{"values": c.Enum},
+				})
+			}
+			def.Columns = append(def.Columns, cd)
+		}
+		f.Models = append(f.Models, def)
+	}
+	return marshalYAML(f)
+}
+
+func marshalYAML(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString("# " + Header + "\n\n")
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(v); err != nil {
+		return nil, fmt.Errorf("encoding yaml: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, fmt.Errorf("encoding yaml: %w", err)
+	}
+	return buf.Bytes(), nil
+}
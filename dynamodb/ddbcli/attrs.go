@@ -0,0 +1,182 @@
+package ddbcli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/acksell/bezos/dynamodb/schema"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// ParseAttribute converts a field value given as name=value to an attribute value,
+// typed by the Go type of the entity's field: numbers become N, bools BOOL, times
+// RFC 3339 strings and everything else S.
+//
+// A value given as name:=value is JSON instead, see [AttributeFromJSON], e.g.
+// tags:='["a","b"]'.
+func ParseAttribute(e schema.Entity, name, raw string) (string, types.AttributeValue, error) {
+	if field, ok := strings.CutSuffix(name, ":"); ok {
+		dec := json.NewDecoder(strings.NewReader(raw))
+		dec.UseNumber()
+		var v any
+		if err := dec.Decode(&v); err != nil {
+			return "", nil, fmt.Errorf("field %q: invalid JSON %q: %w", field, raw, err)
+		}
+		av, err := AttributeFromJSON(v)
+		if err != nil {
+			return "", nil, fmt.Errorf("field %q: %w", field, err)
+		}
+		return field, av, nil
+	}
+
+	fieldType := strings.TrimPrefix(fieldTypeMap(e)[name], "*")
+	switch {
+	case isIntegerType(fieldType), isUintType(fieldType), isFloatType(fieldType):
+		if _, err := strconv.ParseFloat(raw, 64); err != nil {
+			return "", nil, fmt.Errorf("field %q: %q is not a number", name, raw)
+		}
+		return name, &types.AttributeValueMemberN{Value: raw}, nil
+	case fieldType == "bool":
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return "", nil, fmt.Errorf("field %q: %q is not a bool", name, raw)
+		}
+		return name, &types.AttributeValueMemberBOOL{Value: b}, nil
+	case fieldType == "time.Time":
+		t, err := parseTimeInput(raw)
+		if err != nil {
+			return "", nil, fmt.Errorf("field %q: %w", name, err)
+		}
+		return name, &types.AttributeValueMemberS{Value: t.UTC().Format(time.RFC3339Nano)}, nil
+	}
+	return name, &types.AttributeValueMemberS{Value: raw}, nil
+}
+
+// ParseJSONItem parses a JSON object into an item, see [AttributeFromJSON].
+func ParseJSONItem(data []byte) (map[string]types.AttributeValue, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var obj map[string]any
+	if err := dec.Decode(&obj); err != nil {
+		return nil, fmt.Errorf("invalid JSON object: %w", err)
+	}
+	item := make(map[string]types.AttributeValue, len(obj))
+	for k, v := range obj {
+		av, err := AttributeFromJSON(v)
+		if err != nil {
+			return nil, fmt.Errorf("field %q: %w", k, err)
+		}
+		item[k] = av
+	}
+	return item, nil
+}
+
+// AttributeFromJSON converts a decoded JSON value to an attribute value: strings to S,
+// numbers to N, booleans to BOOL, null to NULL, arrays to L and objects to M.
+// It is the inverse of [ItemToJSON] for these types.
+func AttributeFromJSON(v any) (types.AttributeValue, error) {
+	switch v := v.(type) {
+	case string:
+		return &types.AttributeValueMemberS{Value: v}, nil
+	case json.Number:
+		return &types.AttributeValueMemberN{Value: v.String()}, nil
+	case float64:
+		return &types.AttributeValueMemberN{Value: strconv.FormatFloat(v, 'f', -1, 64)}, nil
+	case bool:
+		return &types.AttributeValueMemberBOOL{Value: v}, nil
+	case nil:
+		return &types.AttributeValueMemberNULL{Value: true}, nil
+	case []any:
+		l := make([]types.AttributeValue, len(v))
+		for i, e := range v {
+			av, err := AttributeFromJSON(e)
+			if err != nil {
+				return nil, err
+			}
+			l[i] = av
+		}
+		return &types.AttributeValueMemberL{Value: l}, nil
+	case map[string]any:
+		m := make(map[string]types.AttributeValue, len(v))
+		for k, e := range v {
+			av, err := AttributeFromJSON(e)
+			if err != nil {
+				return nil, err
+			}
+			m[k] = av
+		}
+		return &types.AttributeValueMemberM{Value: m}, nil
+	}
+	return nil, fmt.Errorf("unsupported JSON value %T", v)
+}
+
+// KeyValues returns the values of the S, N and BOOL attributes of item as strings,
+// to build keys from with [BuildKeyFromEntity].
+func KeyValues(item map[string]types.AttributeValue) map[string]string {
+	values := make(map[string]string, len(item))
+	for k, av := range item {
+		switch v := av.(type) {
+		case *types.AttributeValueMemberS:
+			values[k] = v.Value
+		case *types.AttributeValueMemberN:
+			values[k] = v.Value
+		case *types.AttributeValueMemberBOOL:
+			values[k] = strconv.FormatBool(v.Value)
+		}
+	}
+	return values
+}
+
+// AttributeChange is an attribute that differs between two versions of an item.
+// Before is nil for an added attribute, After is nil for a removed one.
+type AttributeChange struct {
+	Name   string
+	Before types.AttributeValue
+	After  types.AttributeValue
+}
+
+// String formats the change, e.g. `~ status: "open" -> "shipped"`, `+ note: "rush"`
+// or `- legacy: true`, with values in the JSON of [ItemToJSON].
+func (c AttributeChange) String() string {
+	switch {
+	case c.Before == nil:
+		return "+ " + c.Name + ": " + formatJSON(c.After)
+	case c.After == nil:
+		return "- " + c.Name + ": " + formatJSON(c.Before)
+	}
+	return "~ " + c.Name + ": " + formatJSON(c.Before) + " -> " + formatJSON(c.After)
+}
+
+func formatJSON(av types.AttributeValue) string {
+	b, err := json.Marshal(attributeValueToJSON(av))
+	if err != nil {
+		return fmt.Sprint(av)
+	}
+	return string(b)
+}
+
+// DiffItems returns the attributes that differ between before and after, sorted by name.
+// A nil item has no attributes, e.g. before an item is created.
+func DiffItems(before, after map[string]types.AttributeValue) []AttributeChange {
+	var changes []AttributeChange
+	for name, b := range before {
+		a, ok := after[name]
+		if !ok {
+			changes = append(changes, AttributeChange{Name: name, Before: b})
+		} else if formatJSON(a) != formatJSON(b) || fmt.Sprintf("%T", a) != fmt.Sprintf("%T", b) {
+			changes = append(changes, AttributeChange{Name: name, Before: b, After: a})
+		}
+	}
+	for name, a := range after {
+		if _, ok := before[name]; !ok {
+			changes = append(changes, AttributeChange{Name: name, After: a})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Name < changes[j].Name })
+	return changes
+}
@@ -0,0 +1,95 @@
+package ddbcli
+
+import (
+	"testing"
+
+	"github.com/acksell/bezos/dynamodb/schema"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var orderEntity = schema.Entity{
+	Type: "Order",
+	Fields: []schema.Field{
+		{Name: "ID", Tag: "id", Type: "string"},
+		{Name: "Total", Tag: "total", Type: "float64"},
+		{Name: "Paid", Tag: "paid", Type: "*bool"},
+		{Name: "CreatedAt", Tag: "createdAt", Type: "time.Time"},
+	},
+}
+
+func TestParseAttribute(t *testing.T) {
+	tests := []struct {
+		name, raw string
+		wantName  string
+		want      types.AttributeValue
+	}{
+		{"id", "42", "id", &types.AttributeValueMemberS{Value: "42"}},
+		{"total", "12.50", "total", &types.AttributeValueMemberN{Value: "12.50"}},
+		{"paid", "true", "paid", &types.AttributeValueMemberBOOL{Value: true}},
+		{"createdAt", "1714564800", "createdAt", &types.AttributeValueMemberS{Value: "2024-05-01T12:00:00Z"}},
+		{"note", "rush", "note", &types.AttributeValueMemberS{Value: "rush"}},
+		{"tags:", `["a",1]`, "tags", &types.AttributeValueMemberL{Value: []types.AttributeValue{
+			&types.AttributeValueMemberS{Value: "a"}, &types.AttributeValueMemberN{Value: "1"},
+		}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name, av, err := ParseAttribute(orderEntity, tt.name, tt.raw)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantName, name)
+			assert.Equal(t, tt.want, av)
+		})
+	}
+}
+
+func TestParseAttribute_Invalid(t *testing.T) {
+	for name, raw := range map[string]string{"total": "twelve", "paid": "maybe", "createdAt": "yesterday", "tags:": "[a"} {
+		_, _, err := ParseAttribute(orderEntity, name, raw)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestParseJSONItem(t *testing.T) {
+	item, err := ParseJSONItem([]byte(`{"id": "o1", "total": 12.5, "meta": {"gift": true, "note": null}}`))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]types.AttributeValue{
+		"id":    &types.AttributeValueMemberS{Value: "o1"},
+		"total": &types.AttributeValueMemberN{Value: "12.5"},
+		"meta": &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+			"gift": &types.AttributeValueMemberBOOL{Value: true},
+			"note": &types.AttributeValueMemberNULL{Value: true},
+		}},
+	}, item)
+	assert.Equal(t, map[string]string{"id": "o1", "total": "12.5"}, KeyValues(item))
+
+	_, err = ParseJSONItem([]byte(`["not", "an", "object"]`))
+	assert.Error(t, err)
+}
+
+func TestDiffItems(t *testing.T) {
+	before := map[string]types.AttributeValue{
+		"id":     &types.AttributeValueMemberS{Value: "o1"},
+		"status": &types.AttributeValueMemberS{Value: "open"},
+		"legacy": &types.AttributeValueMemberBOOL{Value: true},
+		"total":  &types.AttributeValueMemberN{Value: "10"},
+	}
+	after := map[string]types.AttributeValue{
+		"id":     &types.AttributeValueMemberS{Value: "o1"},
+		"status": &types.AttributeValueMemberS{Value: "shipped"},
+		"note":   &types.AttributeValueMemberS{Value: "rush"},
+		"total":  &types.AttributeValueMemberS{Value: "10"},
+	}
+	var got []string
+	for _, c := range DiffItems(before, after) {
+		got = append(got, c.String())
+	}
+	assert.Equal(t, []string{
+		`- legacy: true`,
+		`+ note: "rush"`,
+		`~ status: "open" -> "shipped"`,
+		`~ total: 10 -> "10"`,
+	}, got)
+	assert.Empty(t, DiffItems(before, before))
+}
@@ -0,0 +1,329 @@
+package ddbcli
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/acksell/bezos/dynamodb/schema"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Condition compares an attribute of an item to a value, e.g. status=open or
+// total>=100, or checks that it exists with exists(note) or missing(note).
+// Path is the attribute name, with dots for attributes of maps.
+type Condition struct {
+	Path  string
+	Op    string // "=", "!=", ">", ">=", "<", "<=", "^=" (begins with), "~=" (contains), "exists" or "missing"
+	Value string
+}
+
+// conditionOps are the operators of comparisons, longest first so that
+// status>=x isn't parsed as status> with value =x.
+var conditionOps = []string{"!=", ">=", "<=", "^=", "~=", "=", ">", "<"}
+
+// ParseCondition parses a condition like status=open, see [Condition].
+func ParseCondition(s string) (Condition, error) {
+	for _, fn := range []string{"exists", "missing"} {
+		if path, ok := strings.CutPrefix(s, fn+"("); ok && strings.HasSuffix(path, ")") && len(path) > 1 {
+			return Condition{Path: path[:len(path)-1], Op: fn}, nil
+		}
+	}
+	idx, op := -1, ""
+	for _, o := range conditionOps {
+		if i := strings.Index(s, o); i >= 0 && (idx < 0 || i < idx) {
+			idx, op = i, o
+		}
+	}
+	if idx <= 0 {
+		return Condition{}, fmt.Errorf("invalid condition %q (expected field<op>value with op one of %s, exists(field) or missing(field))", s, strings.Join(conditionOps, " "))
+	}
+	return Condition{Path: s[:idx], Op: op, Value: s[idx+len(op):]}, nil
+}
+
+// Where is a filter of conditions combined with "and" and "or", where "and"
+// binds tighter: each element is a group of conditions that all have to hold,
+// and an item matches if any group does.
+type Where [][]Condition
+
+// ParseWhere parses a filter from its words, e.g.
+//
+//	status=open and total>=100 or exists(priority)
+//
+// Adjacent conditions without "and" or "or" between them are combined with "and".
+func ParseWhere(words []string) (Where, error) {
+	if len(words) == 0 {
+		return nil, fmt.Errorf("empty filter")
+	}
+	w := Where{nil}
+	expectCond := true
+	for _, word := range words {
+		switch strings.ToLower(word) {
+		case "and", "or":
+			if expectCond {
+				return nil, fmt.Errorf("%q without a condition before it", word)
+			}
+			if strings.EqualFold(word, "or") {
+				w = append(w, nil)
+			}
+			expectCond = true
+			continue
+		}
+		c, err := ParseCondition(word)
+		if err != nil {
+			return nil, err
+		}
+		w[len(w)-1] = append(w[len(w)-1], c)
+		expectCond = false
+	}
+	if expectCond {
+		return nil, fmt.Errorf("filter ends with %q", words[len(words)-1])
+	}
+	return w, nil
+}
+
+// Apply returns the items that match the filter.
+func (w Where) Apply(items []map[string]types.AttributeValue) []map[string]types.AttributeValue {
+	var out []map[string]types.AttributeValue
+	for _, item := range items {
+		if w.Match(item) {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// Match reports whether the item matches the filter.
+func (w Where) Match(item map[string]types.AttributeValue) bool {
+	for _, group := range w {
+		matched := true
+		for _, c := range group {
+			if !c.Match(item) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// Expression compiles the filter to a DynamoDB condition, for the FilterExpression
+// of a query or scan. Values are typed by the entity's fields like [ParseAttribute]
+// does, and by the key kinds of the table and its GSIs; values of other attributes
+// are numbers if they look like one.
+func (w Where) Expression(e schema.Entity, t schema.Table) (expression.ConditionBuilder, error) {
+	kinds := keyKinds(t)
+	var or []expression.ConditionBuilder
+	for _, group := range w {
+		var and []expression.ConditionBuilder
+		for _, c := range group {
+			cb, err := c.expression(e, kinds)
+			if err != nil {
+				return expression.ConditionBuilder{}, err
+			}
+			and = append(and, cb)
+		}
+		or = append(or, combine(and, expression.And))
+	}
+	return combine(or, expression.Or), nil
+}
+
+func combine(conds []expression.ConditionBuilder, op func(l, r expression.ConditionBuilder, other ...expression.ConditionBuilder) expression.ConditionBuilder) expression.ConditionBuilder {
+	if len(conds) == 1 {
+		return conds[0]
+	}
+	return op(conds[0], conds[1], conds[2:]...)
+}
+
+// keyKinds returns the kinds of the table's key attributes, the attributes of its
+// GSI keys and its TTL attribute, by name.
+func keyKinds(t schema.Table) map[string]string {
+	kinds := make(map[string]string)
+	add := func(k *schema.KeyDef) {
+		if k != nil && k.Name != "" && k.Kind != "" {
+			kinds[k.Name] = k.Kind
+		}
+	}
+	add(&t.PartitionKey)
+	add(t.SortKey)
+	for i := range t.GSIs {
+		add(&t.GSIs[i].PartitionKey)
+		add(t.GSIs[i].SortKey)
+	}
+	if t.TimeToLiveKey != "" {
+		kinds[t.TimeToLiveKey] = "N"
+	}
+	return kinds
+}
+
+func (c Condition) expression(e schema.Entity, kinds map[string]string) (expression.ConditionBuilder, error) {
+	name := expression.Name(c.Path)
+	fieldType, isField := fieldTypeMap(e)[c.Path]
+	fieldType = strings.TrimPrefix(fieldType, "*")
+	kind := kinds[c.Path]
+	if isField && (isIntegerType(fieldType) || isUintType(fieldType) || isFloatType(fieldType)) {
+		kind = "N"
+	}
+
+	switch c.Op {
+	case "exists":
+		return expression.AttributeExists(name), nil
+	case "missing":
+		return expression.AttributeNotExists(name), nil
+	case "^=":
+		if kind == "N" {
+			return expression.ConditionBuilder{}, fmt.Errorf("%s is a number, ^= (begins with) only applies to strings and binary values", c.Path)
+		}
+		return expression.BeginsWith(name, c.Value), nil
+	case "~=":
+		return expression.Contains(name, c.Value), nil
+	}
+
+	var av types.AttributeValue
+	switch {
+	case isField:
+		var err error
+		if _, av, err = ParseAttribute(e, c.Path, c.Value); err != nil {
+			return expression.ConditionBuilder{}, err
+		}
+	case kind == "N":
+		if _, err := strconv.ParseFloat(c.Value, 64); err != nil {
+			return expression.ConditionBuilder{}, fmt.Errorf("%s: %q is not a number", c.Path, c.Value)
+		}
+		av = &types.AttributeValueMemberN{Value: c.Value}
+	case kind == "B":
+		b, err := base64.StdEncoding.DecodeString(c.Value)
+		if err != nil {
+			return expression.ConditionBuilder{}, fmt.Errorf("%s: %q is not base64", c.Path, c.Value)
+		}
+		av = &types.AttributeValueMemberB{Value: b}
+	case kind == "S":
+		av = &types.AttributeValueMemberS{Value: c.Value}
+	default:
+		if _, err := strconv.ParseFloat(c.Value, 64); err == nil {
+			av = &types.AttributeValueMemberN{Value: c.Value}
+		} else {
+			av = &types.AttributeValueMemberS{Value: c.Value}
+		}
+	}
+	value := expression.Value(av)
+
+	switch c.Op {
+	case "=":
+		return expression.Equal(name, value), nil
+	case "!=":
+		return expression.NotEqual(name, value), nil
+	case ">":
+		return expression.GreaterThan(name, value), nil
+	case ">=":
+		return expression.GreaterThanEqual(name, value), nil
+	case "<":
+		return expression.LessThan(name, value), nil
+	case "<=":
+		return expression.LessThanEqual(name, value), nil
+	}
+	return expression.ConditionBuilder{}, fmt.Errorf("unsupported operator %q", c.Op)
+}
+
+// Match reports whether the item matches the condition. Numbers compare
+// numerically and everything else as text. A missing attribute only matches
+// != and missing().
+func (c Condition) Match(item map[string]types.AttributeValue) bool {
+	av := lookupPath(item, c.Path)
+	switch c.Op {
+	case "exists":
+		return av != nil
+	case "missing":
+		return av == nil
+	}
+	if av == nil {
+		return c.Op == "!="
+	}
+	switch c.Op {
+	case "~=":
+		return contains(av, c.Value)
+	case "^=":
+		return strings.HasPrefix(cellValue(av), c.Value)
+	}
+
+	var cmp int
+	if n, ok := av.(*types.AttributeValueMemberN); ok {
+		a, errA := strconv.ParseFloat(n.Value, 64)
+		b, errB := strconv.ParseFloat(c.Value, 64)
+		if errA != nil || errB != nil {
+			return c.Op == "!="
+		}
+		switch {
+		case a < b:
+			cmp = -1
+		case a > b:
+			cmp = 1
+		}
+	} else {
+		cmp = strings.Compare(cellValue(av), c.Value)
+	}
+
+	switch c.Op {
+	case "=":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	}
+	return false
+}
+
+func lookupPath(item map[string]types.AttributeValue, path string) types.AttributeValue {
+	if av, ok := item[path]; ok {
+		return av
+	}
+	name, rest, ok := strings.Cut(path, ".")
+	if !ok {
+		return nil
+	}
+	m, isMap := item[name].(*types.AttributeValueMemberM)
+	if !isMap {
+		return nil
+	}
+	return lookupPath(m.Value, rest)
+}
+
+// contains reports whether a string contains s, or a set or list has an element s.
+func contains(av types.AttributeValue, s string) bool {
+	switch v := av.(type) {
+	case *types.AttributeValueMemberSS:
+		for _, e := range v.Value {
+			if e == s {
+				return true
+			}
+		}
+		return false
+	case *types.AttributeValueMemberNS:
+		for _, e := range v.Value {
+			if e == s {
+				return true
+			}
+		}
+		return false
+	case *types.AttributeValueMemberL:
+		for _, e := range v.Value {
+			if cellValue(e) == s {
+				return true
+			}
+		}
+		return false
+	}
+	return strings.Contains(cellValue(av), s)
+}
@@ -0,0 +1,127 @@
+package ddbcli
+
+import (
+	"testing"
+
+	"github.com/acksell/bezos/dynamodb/schema"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCondition(t *testing.T) {
+	tests := map[string]Condition{
+		"status=open":     {Path: "status", Op: "=", Value: "open"},
+		"total>=100":      {Path: "total", Op: ">=", Value: "100"},
+		"note!=a=b":       {Path: "note", Op: "!=", Value: "a=b"},
+		"meta.gift=true":  {Path: "meta.gift", Op: "=", Value: "true"},
+		"id^=o":           {Path: "id", Op: "^=", Value: "o"},
+		"tags~=rush":      {Path: "tags", Op: "~=", Value: "rush"},
+		"createdAt<2024-": {Path: "createdAt", Op: "<", Value: "2024-"},
+		"exists(note)":    {Path: "note", Op: "exists"},
+		"missing(a.b)":    {Path: "a.b", Op: "missing"},
+	}
+	for s, want := range tests {
+		got, err := ParseCondition(s)
+		require.NoError(t, err, s)
+		assert.Equal(t, want, got, s)
+	}
+
+	for _, s := range []string{"status", "=open", "", "exists()"} {
+		_, err := ParseCondition(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestWhere_Apply(t *testing.T) {
+	ids := func(args ...string) []string {
+		f, err := ParseWhere(args)
+		require.NoError(t, err)
+		var out []string
+		for _, item := range f.Apply(testItems) {
+			out = append(out, cellValue(item["id"]))
+		}
+		return out
+	}
+
+	assert.Equal(t, []string{"o1"}, ids("status=open"))
+	assert.Equal(t, []string{"o2"}, ids("total>20"), "numbers compare numerically")
+	assert.Equal(t, []string{"o1", "o2"}, ids("total>=12.5"))
+	assert.Equal(t, []string{"o1"}, ids("meta.gift=true"))
+	assert.Equal(t, []string{"o2"}, ids("tags~=rush"))
+	assert.Equal(t, []string{"o1", "o2"}, ids("note!=x"), "missing attributes only match !=")
+	assert.Empty(t, ids("note<x"))
+	assert.Equal(t, []string{"o2"}, ids("status^=ship", "total<=100"))
+	assert.Empty(t, ids("status=open", "total>20"))
+	assert.Equal(t, []string{"o2"}, ids("status=open", "and", "total>20", "or", "tags~=rush"))
+	assert.Equal(t, []string{"o1", "o2"}, ids("status=open", "or", "total>20"))
+	assert.Equal(t, []string{"o2"}, ids("exists(tags)"))
+	assert.Equal(t, []string{"o2"}, ids("missing(meta.gift)"))
+}
+
+func TestParseWhere_Invalid(t *testing.T) {
+	for _, words := range [][]string{nil, {"and", "status=open"}, {"status=open", "or"}, {"status=open", "and", "or", "id=o1"}} {
+		_, err := ParseWhere(words)
+		assert.Error(t, err, words)
+	}
+}
+
+func TestWhere_Expression(t *testing.T) {
+	w, err := ParseWhere([]string{"total>=12.5", "id^=o", "or", "missing(paid)", "note=7"})
+	require.NoError(t, err)
+	cond, err := w.Expression(orderEntity, orderTable)
+	require.NoError(t, err)
+	expr, err := expression.NewBuilder().WithFilter(cond).Build()
+	require.NoError(t, err)
+
+	assert.Equal(t, "((#0 >= :0) AND (begins_with (#1, :1))) OR ((attribute_not_exists (#2)) AND (#3 = :2))", *expr.Filter())
+	assert.Equal(t, map[string]string{"#0": "total", "#1": "id", "#2": "paid", "#3": "note"}, expr.Names())
+	assert.Equal(t, &types.AttributeValueMemberN{Value: "12.5"}, expr.Values()[":0"])
+	assert.Equal(t, &types.AttributeValueMemberS{Value: "o"}, expr.Values()[":1"])
+	assert.Equal(t, &types.AttributeValueMemberN{Value: "7"}, expr.Values()[":2"], "unknown attributes are numbers if they look like one")
+
+	w, err = ParseWhere([]string{"paid=maybe"})
+	require.NoError(t, err)
+	_, err = w.Expression(orderEntity, orderTable)
+	assert.Error(t, err, "values of known fields are typed")
+}
+
+// orderTable keys orders by strings, with a numeric sort key in its GSI.
+var orderTable = schema.Table{
+	Name:          "orders",
+	PartitionKey:  schema.KeyDef{Name: "pk", Kind: "S"},
+	SortKey:       &schema.KeyDef{Name: "sk", Kind: "S"},
+	TimeToLiveKey: "expiresAt",
+	GSIs: []schema.GSI{
+		{Name: "ByCustomer", PartitionKey: schema.KeyDef{Name: "gsi1pk", Kind: "S"}, SortKey: &schema.KeyDef{Name: "gsi1sk", Kind: "N"}},
+	},
+}
+
+func TestWhere_ExpressionTypesKeysByTheirKind(t *testing.T) {
+	w, err := ParseWhere([]string{"sk=2024", "gsi1pk=42", "gsi1sk>=7", "expiresAt<1714564800"})
+	require.NoError(t, err)
+	cond, err := w.Expression(orderEntity, orderTable)
+	require.NoError(t, err)
+	expr, err := expression.NewBuilder().WithFilter(cond).Build()
+	require.NoError(t, err)
+
+	assert.Equal(t, &types.AttributeValueMemberS{Value: "2024"}, expr.Values()[":0"], "string keys stay strings")
+	assert.Equal(t, &types.AttributeValueMemberS{Value: "42"}, expr.Values()[":1"], "string GSI keys stay strings")
+	assert.Equal(t, &types.AttributeValueMemberN{Value: "7"}, expr.Values()[":2"])
+	assert.Equal(t, &types.AttributeValueMemberN{Value: "1714564800"}, expr.Values()[":3"])
+
+	w, err = ParseWhere([]string{"gsi1sk=soon"})
+	require.NoError(t, err)
+	_, err = w.Expression(orderEntity, orderTable)
+	assert.ErrorContains(t, err, "not a number")
+}
+
+func TestWhere_ExpressionRejectsBeginsWithOnNumbers(t *testing.T) {
+	for _, word := range []string{"total^=1", "gsi1sk^=1"} {
+		w, err := ParseWhere([]string{word})
+		require.NoError(t, err)
+		_, err = w.Expression(orderEntity, orderTable)
+		assert.ErrorContains(t, err, "is a number", word)
+	}
+}
@@ -0,0 +1,72 @@
+package ddbcli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/acksell/bezos/dynamodb/schema"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// GSIMembership explains whether an item is present in one of its entity's GSIs.
+type GSIMembership struct {
+	GSI      string `json:"gsi"`
+	Included bool   `json:"included"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// SparseGSINote describes which items of an entity a sparse GSI contains,
+// or returns "" if every item of the entity is written to the GSI.
+//
+// For example: "only Order items where status = open are in ByStatus"
+func SparseGSINote(e schema.Entity, gsiName string) string {
+	for _, m := range e.GSIMappings {
+		if strings.EqualFold(m.GSI, gsiName) && m.Condition != nil {
+			return fmt.Sprintf("only %s items where %s are in %s", e.Type, m.Condition.Description, m.GSI)
+		}
+	}
+	return ""
+}
+
+// ExplainGSIMembership reports for each GSI of the entity whether the item is
+// in it. DynamoDB only indexes items that have the GSI's key attributes.
+func ExplainGSIMembership(e schema.Entity, t schema.Table, item map[string]types.AttributeValue) []GSIMembership {
+	var out []GSIMembership
+	for _, m := range e.GSIMappings {
+		gsi := findTableGSI(t, m.GSI)
+		if gsi == nil {
+			continue
+		}
+		res := GSIMembership{GSI: m.GSI, Included: hasAttr(item, gsi.PartitionKey.Name)}
+		if gsi.SortKey != nil && !hasAttr(item, gsi.SortKey.Name) {
+			res.Included = false
+		}
+		switch {
+		case res.Included:
+		case m.Condition != nil:
+			res.Reason = fmt.Sprintf("sparse index, only includes items where %s", m.Condition.Description)
+		default:
+			res.Reason = "item has no GSI key attributes"
+		}
+		out = append(out, res)
+	}
+	return out
+}
+
+func findTableGSI(t schema.Table, name string) *schema.GSI {
+	for i := range t.GSIs {
+		if strings.EqualFold(t.GSIs[i].Name, name) {
+			return &t.GSIs[i]
+		}
+	}
+	return nil
+}
+
+func hasAttr(item map[string]types.AttributeValue, name string) bool {
+	v, ok := item[name]
+	if !ok {
+		return false
+	}
+	_, null := v.(*types.AttributeValueMemberNULL)
+	return !null
+}
@@ -0,0 +1,39 @@
+package ddbcli
+
+import (
+	"testing"
+
+	"github.com/acksell/bezos/dynamodb/schema"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExplainGSIMembership(t *testing.T) {
+	tbl := schema.Table{
+		GSIs: []schema.GSI{
+			{Name: "ByStatus", PartitionKey: schema.KeyDef{Name: "gsi1pk", Kind: "S"}},
+			{Name: "ByEmail", PartitionKey: schema.KeyDef{Name: "gsi2pk", Kind: "S"}},
+		},
+	}
+	entity := schema.Entity{
+		Type: "Order",
+		GSIMappings: []schema.GSIMapping{
+			{GSI: "ByStatus", PartitionPattern: "STATUS#{status}", Condition: &schema.GSICondition{
+				Kind: "equals", Field: "status", Value: "open", Description: "status = open",
+			}},
+			{GSI: "ByEmail", PartitionPattern: "EMAIL#{email}"},
+		},
+	}
+	item := map[string]types.AttributeValue{
+		"gsi2pk": &types.AttributeValueMemberS{Value: "EMAIL#a@example.com"},
+	}
+
+	got := ExplainGSIMembership(entity, tbl, item)
+	assert.Equal(t, []GSIMembership{
+		{GSI: "ByStatus", Included: false, Reason: "sparse index, only includes items where status = open"},
+		{GSI: "ByEmail", Included: true},
+	}, got)
+
+	assert.Equal(t, "only Order items where status = open are in ByStatus", SparseGSINote(entity, "bystatus"))
+	assert.Empty(t, SparseGSINote(entity, "ByEmail"))
+}
@@ -0,0 +1,155 @@
+package ddbcli
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/acksell/bezos/dynamodb/ddbexport"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"gopkg.in/yaml.v3"
+)
+
+// OutputFormat is a format to print items in.
+type OutputFormat string
+
+const (
+	OutputTable        OutputFormat = "table"
+	OutputJSON         OutputFormat = "json"
+	OutputJSONL        OutputFormat = "jsonl" // one JSON object per line
+	OutputCSV          OutputFormat = "csv"   // a header row and a row per item
+	OutputYAML         OutputFormat = "yaml"
+	OutputDynamoDBJSON OutputFormat = "dynamodb-json" // typed attribute values, like the AWS CLI prints
+)
+
+// OutputFormats lists the supported output formats.
+var OutputFormats = []OutputFormat{OutputTable, OutputJSON, OutputJSONL, OutputCSV, OutputYAML, OutputDynamoDBJSON}
+
+// ParseOutputFormat parses the name of an output format.
+func ParseOutputFormat(s string) (OutputFormat, error) {
+	for _, f := range OutputFormats {
+		if strings.EqualFold(s, string(f)) {
+			return f, nil
+		}
+	}
+	names := make([]string, len(OutputFormats))
+	for i, f := range OutputFormats {
+		names[i] = string(f)
+	}
+	return "", fmt.Errorf("unknown output format %q (want %s)", s, strings.Join(names, ", "))
+}
+
+// WriteItems writes the items to w in the given format. Tables and CSV have the
+// given columns, or a column per attribute if there are none, see [Columns].
+// JSON, JSONL and YAML use the values of [ItemToJSON].
+func WriteItems(w io.Writer, items []map[string]types.AttributeValue, format OutputFormat, columns []string) error {
+	if len(columns) == 0 {
+		columns = Columns(items)
+	}
+	switch format {
+	case OutputTable:
+		return writeTable(w, items, columns)
+	case OutputCSV:
+		cw := csv.NewWriter(w)
+		cw.Write(columns)
+		row := make([]string, len(columns))
+		for _, item := range items {
+			for i, col := range columns {
+				row[i] = cellValue(lookupPath(item, col))
+			}
+			cw.Write(row)
+		}
+		cw.Flush()
+		return cw.Error()
+	case OutputJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(ItemsToJSON(items))
+	case OutputJSONL:
+		enc := json.NewEncoder(w)
+		for _, item := range items {
+			if err := enc.Encode(ItemToJSON(item)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case OutputDynamoDBJSON:
+		out := make([]map[string]any, len(items))
+		for i, item := range items {
+			attrs, err := ddbexport.JSONAttributes(item)
+			if err != nil {
+				return err
+			}
+			out[i] = attrs
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(map[string]any{"Items": out})
+	case OutputYAML:
+		if len(items) == 0 {
+			_, err := io.WriteString(w, "[]\n")
+			return err
+		}
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(ItemsToJSON(items)); err != nil {
+			return err
+		}
+		return enc.Close()
+	}
+	return fmt.Errorf("unknown output format %q", format)
+}
+
+// Columns returns the names of the attributes of the items, sorted.
+func Columns(items []map[string]types.AttributeValue) []string {
+	seen := make(map[string]bool)
+	var cols []string
+	for _, item := range items {
+		for name := range item {
+			if !seen[name] {
+				seen[name] = true
+				cols = append(cols, name)
+			}
+		}
+	}
+	sort.Strings(cols)
+	return cols
+}
+
+func writeTable(w io.Writer, items []map[string]types.AttributeValue, cols []string) error {
+	if len(items) == 0 {
+		_, err := io.WriteString(w, "(no items)\n")
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(cols, "\t"))
+	cells := make([]string, len(cols))
+	for _, item := range items {
+		for i, col := range cols {
+			cells[i] = strings.NewReplacer("\t", " ", "\n", " ").Replace(cellValue(lookupPath(item, col)))
+		}
+		fmt.Fprintln(tw, strings.Join(cells, "\t"))
+	}
+	return tw.Flush()
+}
+
+// cellValue formats an attribute for a table or CSV cell: strings as they are,
+// missing attributes empty and everything else as compact JSON.
+func cellValue(av types.AttributeValue) string {
+	if av == nil {
+		return ""
+	}
+	v := attributeValueToJSON(av)
+	if s, ok := v.(string); ok {
+		return s
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
@@ -0,0 +1,88 @@
+package ddbcli
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testItems = []map[string]types.AttributeValue{
+	{
+		"id":     &types.AttributeValueMemberS{Value: "o1"},
+		"status": &types.AttributeValueMemberS{Value: "open"},
+		"total":  &types.AttributeValueMemberN{Value: "12.5"},
+		"meta": &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+			"gift": &types.AttributeValueMemberBOOL{Value: true},
+		}},
+	},
+	{
+		"id":     &types.AttributeValueMemberS{Value: "o2"},
+		"status": &types.AttributeValueMemberS{Value: "shipped"},
+		"total":  &types.AttributeValueMemberN{Value: "100"},
+		"tags":   &types.AttributeValueMemberSS{Value: []string{"rush", "fragile"}},
+	},
+}
+
+func TestWriteItems_Table(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WriteItems(&buf, testItems, OutputTable, nil))
+	assert.Equal(t, `id  meta           status   tags                total
+o1  {"gift":true}  open                         12.5
+o2                 shipped  ["rush","fragile"]  100
+`, buf.String())
+
+	buf.Reset()
+	require.NoError(t, WriteItems(&buf, nil, OutputTable, nil))
+	assert.Equal(t, "(no items)\n", buf.String())
+}
+
+func TestWriteItems_CSV(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WriteItems(&buf, testItems, OutputCSV, []string{"id", "meta.gift", "tags"}))
+	assert.Equal(t, `id,meta.gift,tags
+o1,true,
+o2,,"[""rush"",""fragile""]"
+`, buf.String())
+}
+
+func TestWriteItems_JSONL(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WriteItems(&buf, testItems, OutputJSONL, nil))
+	assert.Equal(t, `{"id":"o1","meta":{"gift":true},"status":"open","total":12.5}
+{"id":"o2","status":"shipped","tags":["rush","fragile"],"total":100}
+`, buf.String())
+}
+
+func TestWriteItems_DynamoDBJSON(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WriteItems(&buf, testItems[1:], OutputDynamoDBJSON, nil))
+	assert.JSONEq(t, `{"Items":[{
+		"id": {"S": "o2"},
+		"status": {"S": "shipped"},
+		"total": {"N": "100"},
+		"tags": {"SS": ["rush", "fragile"]}
+	}]}`, buf.String())
+}
+
+func TestWriteItems_YAML(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WriteItems(&buf, testItems[:1], OutputYAML, nil))
+	assert.Equal(t, `- id: o1
+  meta:
+    gift: true
+  status: open
+  total: 12.5
+`, buf.String())
+}
+
+func TestParseOutputFormat(t *testing.T) {
+	f, err := ParseOutputFormat("JSON")
+	require.NoError(t, err)
+	assert.Equal(t, OutputJSON, f)
+
+	_, err = ParseOutputFormat("xml")
+	assert.ErrorContains(t, err, "want table, json, jsonl, csv, yaml, dynamodb-json")
+}
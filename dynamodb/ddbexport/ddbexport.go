@@ -0,0 +1,308 @@
+// Package ddbexport reads and writes table data in the formats of DynamoDB exports to
+// S3: DynamoDB JSON and Amazon Ion text, one item per line, usually gzipped.
+//
+// [Export] scans a table into a [Writer] and [Import] bulk-writes the items of a
+// [Reader] into a table. Both take a [ddbiface.ReadWriteClient], so they work against
+// AWS and against a local ddbstore alike, e.g. to load a production export into a local
+// database for debugging with ddb ui:
+//
+//	ddb export orders --out orders.json.gz
+//	ddb import orders orders.json.gz --db ./data
+//
+// [DataFiles] finds the data files of an export that was downloaded from S3, in its
+// data/ directory next to the manifest files.
+package ddbexport
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/acksell/bezos/dynamodb/ddbiface"
+	"github.com/acksell/bezos/dynamodb/ddbsdk"
+	"github.com/acksell/bezos/dynamodb/table"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Item is a DynamoDB item.
+type Item = map[string]types.AttributeValue
+
+// Format is the format of exported items.
+type Format string
+
+const (
+	// DynamoDBJSON is the DYNAMODB_JSON export format: a {"Item": {...}} object per
+	// line, with attribute values as in the DynamoDB API, e.g. {"N": "42"}.
+	DynamoDBJSON Format = "DYNAMODB_JSON"
+	// Ion is the ION export format: Amazon Ion text with a {Item: {...}} struct per
+	// line. Numbers are decimals and sets are lists annotated with $dynamodb_SS,
+	// $dynamodb_NS or $dynamodb_BS.
+	Ion Format = "ION"
+)
+
+// ParseFormat returns the format named s, case-insensitively: "dynamodb_json" (or
+// "dynamodb-json", "json") or "ion".
+func ParseFormat(s string) (Format, error) {
+	switch strings.ToLower(strings.ReplaceAll(s, "-", "_")) {
+	case "dynamodb_json", "json":
+		return DynamoDBJSON, nil
+	case "ion":
+		return Ion, nil
+	}
+	return "", fmt.Errorf("unknown export format %q, expected dynamodb_json or ion", s)
+}
+
+// FormatFromPath returns the format of a data file by its extension, e.g.
+// "data/abc.json.gz" or "orders.ion".
+func FormatFromPath(path string) (Format, bool) {
+	switch filepath.Ext(strings.TrimSuffix(path, ".gz")) {
+	case ".json":
+		return DynamoDBJSON, true
+	case ".ion":
+		return Ion, true
+	}
+	return "", false
+}
+
+// FileName is the name of an export file of table in format f, e.g. "orders.json.gz".
+func FileName(table string, f Format) string {
+	if f == Ion {
+		return table + ".ion.gz"
+	}
+	return table + ".json.gz"
+}
+
+// Writer writes items in an export format, gzipped.
+type Writer struct {
+	gz     *gzip.Writer
+	w      *bufio.Writer
+	format Format
+	n      int
+}
+
+// NewWriter returns a writer of items in format f to w. Close it to flush the items.
+func NewWriter(w io.Writer, f Format) (*Writer, error) {
+	if f != DynamoDBJSON && f != Ion {
+		return nil, fmt.Errorf("unknown export format %q", f)
+	}
+	gz := gzip.NewWriter(w)
+	ew := &Writer{gz: gz, w: bufio.NewWriter(gz), format: f}
+	if f == Ion {
+		if _, err := ew.w.WriteString(ionVersionMarker + "\n"); err != nil {
+			return nil, err
+		}
+	}
+	return ew, nil
+}
+
+// Write writes an item.
+func (w *Writer) Write(item Item) error {
+	var line []byte
+	var err error
+	if w.format == Ion {
+		line, err = marshalIonItem(item)
+	} else {
+		line, err = marshalJSONItem(item)
+	}
+	if err != nil {
+		return fmt.Errorf("encoding item %d: %w", w.n+1, err)
+	}
+	line = append(line, '\n')
+	if _, err := w.w.Write(line); err != nil {
+		return err
+	}
+	w.n++
+	return nil
+}
+
+// Count returns the number of items written.
+func (w *Writer) Count() int {
+	return w.n
+}
+
+// Close flushes the items and closes the gzip stream. It doesn't close the underlying writer.
+func (w *Writer) Close() error {
+	if err := w.w.Flush(); err != nil {
+		return err
+	}
+	return w.gz.Close()
+}
+
+// Reader reads the items of an export file.
+type Reader struct {
+	next func() (Item, error)
+	n    int
+}
+
+// NewReader returns a reader of the items in format f from r, which is gunzipped if
+// it starts with the gzip magic number.
+func NewReader(r io.Reader, f Format) (*Reader, error) {
+	br := bufio.NewReader(r)
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("opening gzip stream: %w", err)
+		}
+		br = bufio.NewReader(gz)
+	}
+	switch f {
+	case DynamoDBJSON:
+		return &Reader{next: newJSONItemDecoder(br)}, nil
+	case Ion:
+		return &Reader{next: newIonItemDecoder(br)}, nil
+	}
+	return nil, fmt.Errorf("unknown export format %q", f)
+}
+
+// Next returns the next item, or io.EOF after the last one.
+func (r *Reader) Next() (Item, error) {
+	item, err := r.next()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("item %d: %w", r.n+1, err)
+	}
+	r.n++
+	return item, nil
+}
+
+// Export scans table and writes its items to w. It returns the number of items written.
+func Export(ctx context.Context, client ddbiface.ReadWriteClient, table string, w *Writer) (int, error) {
+	input := &dynamodb.ScanInput{TableName: &table}
+	n := 0
+	for {
+		out, err := client.Scan(ctx, input)
+		if err != nil {
+			return n, fmt.Errorf("scanning %s: %w", table, err)
+		}
+		for _, item := range out.Items {
+			if err := w.Write(item); err != nil {
+				return n, err
+			}
+			n++
+		}
+		if len(out.LastEvaluatedKey) == 0 {
+			return n, nil
+		}
+		input.ExclusiveStartKey = out.LastEvaluatedKey
+	}
+}
+
+// importChunkSize is the number of items Import reads before writing them, which
+// bounds the memory it needs.
+const importChunkSize = 1000
+
+// Import writes the items of r to the table with a [ddbsdk.BulkWriter], overwriting
+// items with the same key. It returns the number of items written. The table's key
+// definitions are needed to tell the items apart; its entity type attribute is
+// written as it is in the items.
+func Import(ctx context.Context, client ddbiface.ReadWriteClient, def table.TableDefinition, r *Reader) (int, error) {
+	def.EntityTypeKey = ""
+	n := 0
+	for done := false; !done; {
+		w := ddbsdk.NewBulkWriter(client)
+		count := 0
+		for count < importChunkSize {
+			item, err := r.Next()
+			if errors.Is(err, io.EOF) {
+				done = true
+				break
+			}
+			if err != nil {
+				return n, err
+			}
+			key, err := itemKey(def.KeyDefinitions, item)
+			if err != nil {
+				return n, fmt.Errorf("item %d: %w", r.n, err)
+			}
+			w.AddAction(ddbsdk.NewUnsafePut(def, key, rawItem(item)))
+			count++
+		}
+		res, err := w.Exec(ctx)
+		n += count - len(res.Failed())
+		if err != nil {
+			return n, fmt.Errorf("writing to %s: %w", def.Name, err)
+		}
+	}
+	return n, nil
+}
+
+// rawItem is an imported item, written as it is.
+type rawItem Item
+
+func (i rawItem) IsValid() error { return nil }
+
+func (i rawItem) MarshalDynamoDBAttributeValue() (types.AttributeValue, error) {
+	return &types.AttributeValueMemberM{Value: i}, nil
+}
+
+// itemKey returns the primary key of item.
+func itemKey(def table.PrimaryKeyDefinition, item Item) (table.PrimaryKey, error) {
+	key := table.PrimaryKey{Definition: def}
+	var err error
+	if key.Values.PartitionKey, err = keyValue(def.PartitionKey, item); err != nil {
+		return table.PrimaryKey{}, err
+	}
+	if def.SortKey.Name != "" {
+		if key.Values.SortKey, err = keyValue(def.SortKey, item); err != nil {
+			return table.PrimaryKey{}, err
+		}
+	}
+	return key, nil
+}
+
+// keyValue returns the value of a key attribute, keeping numbers as written.
+func keyValue(def table.KeyDef, item Item) (any, error) {
+	switch av := item[def.Name].(type) {
+	case *types.AttributeValueMemberS:
+		if def.Kind == table.KeyKindS {
+			return av.Value, nil
+		}
+	case *types.AttributeValueMemberN:
+		if def.Kind == table.KeyKindN {
+			return attributevalue.Number(av.Value), nil
+		}
+	case *types.AttributeValueMemberB:
+		if def.Kind == table.KeyKindB {
+			return av.Value, nil
+		}
+	case nil:
+		return nil, fmt.Errorf("missing key attribute %q", def.Name)
+	}
+	return nil, fmt.Errorf("key attribute %q is not of type %s", def.Name, def.Kind)
+}
+
+// DataFiles returns the data files in dir and its subdirectories, sorted: the files
+// with a .json, .json.gz, .ion or .ion.gz extension, except the manifest files of
+// an export to S3.
+func DataFiles(dir string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || strings.HasPrefix(d.Name(), "manifest-") {
+			return nil
+		}
+		if _, ok := FormatFromPath(path); ok {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	return files, nil
+}
@@ -0,0 +1,280 @@
+package ddbexport_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/acksell/bezos/dynamodb/ddbexport"
+	"github.com/acksell/bezos/dynamodb/ddbstore"
+	"github.com/acksell/bezos/dynamodb/table"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+var testItem = ddbexport.Item{
+	"pk":      &types.AttributeValueMemberS{Value: "USER#1"},
+	"sk":      &types.AttributeValueMemberS{Value: "PROFILE"},
+	"name":    &types.AttributeValueMemberS{Value: "Ann \"the\" O'Brien\n"},
+	"age":     &types.AttributeValueMemberN{Value: "42"},
+	"score":   &types.AttributeValueMemberN{Value: "-1.5E-3"},
+	"photo":   &types.AttributeValueMemberB{Value: []byte{0, 1, 2, 255}},
+	"admin":   &types.AttributeValueMemberBOOL{Value: true},
+	"deleted": &types.AttributeValueMemberNULL{Value: true},
+	"tags":    &types.AttributeValueMemberSS{Value: []string{"a", "b"}},
+	"lucky":   &types.AttributeValueMemberNS{Value: []string{"7", "13.5"}},
+	"keys":    &types.AttributeValueMemberBS{Value: [][]byte{{1}, {2, 3}}},
+	"history": &types.AttributeValueMemberL{Value: []types.AttributeValue{
+		&types.AttributeValueMemberS{Value: "x"},
+		&types.AttributeValueMemberN{Value: "1"},
+	}},
+	"address": &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+		"street name": &types.AttributeValueMemberS{Value: "Main"},
+		"null":        &types.AttributeValueMemberBOOL{Value: false},
+	}},
+}
+
+func roundTrip(t *testing.T, f ddbexport.Format, items ...ddbexport.Item) []ddbexport.Item {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := ddbexport.NewWriter(&buf, f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, item := range items {
+		if err := w.Write(item); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return readAll(t, &buf, f)
+}
+
+func readAll(t *testing.T, r io.Reader, f ddbexport.Format) []ddbexport.Item {
+	t.Helper()
+	rd, err := ddbexport.NewReader(r, f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var items []ddbexport.Item
+	for {
+		item, err := rd.Next()
+		if errors.Is(err, io.EOF) {
+			return items
+		}
+		if err != nil {
+			t.Fatalf("Next() error = %v", err)
+		}
+		items = append(items, item)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, f := range []ddbexport.Format{ddbexport.DynamoDBJSON, ddbexport.Ion} {
+		t.Run(string(f), func(t *testing.T) {
+			got := roundTrip(t, f, testItem, testItem)
+			if len(got) != 2 {
+				t.Fatalf("read %d items, want 2", len(got))
+			}
+			want := testItem
+			if f == ddbexport.Ion {
+				// Ion decimals keep their value, not their spelling.
+				want = copyItem(testItem)
+				want["score"] = &types.AttributeValueMemberN{Value: "-1.5e-3"}
+			}
+			if !reflect.DeepEqual(got[0], want) {
+				t.Errorf("round trip changed the item:\ngot  %#v\nwant %#v", got[0], want)
+			}
+		})
+	}
+}
+
+func copyItem(item ddbexport.Item) ddbexport.Item {
+	c := ddbexport.Item{}
+	for k, v := range item {
+		c[k] = v
+	}
+	return c
+}
+
+func TestReader_AWSExports(t *testing.T) {
+	tests := []struct {
+		format ddbexport.Format
+		data   string
+	}{
+		{ddbexport.DynamoDBJSON, `{"Item":{"pk":{"S":"A"},"n":{"N":"1.50"},"set":{"SS":["x"]}}}
+{"Item":{"pk":{"S":"B"},"n":{"N":"2"},"set":{"SS":["y"]}}}
+`},
+		{ddbexport.Ion, `$ion_1_0 {Item:{pk:"A",n:1.50,set:$dynamodb_SS::["x"]}}
+// a comment
+$ion_1_0 {Item:{'pk':"B",n:2d0,set:$dynamodb_SS::[y]}}
+`},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			var gz bytes.Buffer
+			zw := gzip.NewWriter(&gz)
+			zw.Write([]byte(tt.data))
+			zw.Close()
+
+			items := readAll(t, &gz, tt.format)
+			if len(items) != 2 {
+				t.Fatalf("read %d items, want 2", len(items))
+			}
+			if pk := items[1]["pk"].(*types.AttributeValueMemberS).Value; pk != "B" {
+				t.Errorf("pk = %q, want B", pk)
+			}
+			if set := items[1]["set"].(*types.AttributeValueMemberSS).Value; !reflect.DeepEqual(set, []string{"y"}) {
+				t.Errorf("set = %v, want [y]", set)
+			}
+		})
+	}
+}
+
+func TestReader_Errors(t *testing.T) {
+	for _, data := range []string{
+		`{Item:{at:2024-01-01T00:00Z}}`,
+		`{Item:{n:nan}}`,
+		`{Item:{pk:"A"`,
+		`{Other:{}}`,
+	} {
+		r, err := ddbexport.NewReader(strings.NewReader(data), ddbexport.Ion)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := r.Next(); err == nil || errors.Is(err, io.EOF) {
+			t.Errorf("Next() on %s = %v, want an error", data, err)
+		}
+	}
+}
+
+var exportTable = table.TableDefinition{
+	Name: "users",
+	KeyDefinitions: table.PrimaryKeyDefinition{
+		PartitionKey: table.KeyDef{Name: "pk", Kind: table.KeyKindS},
+		SortKey:      table.KeyDef{Name: "sk", Kind: table.KeyKindS},
+	},
+}
+
+func TestExportImport(t *testing.T) {
+	ctx := context.Background()
+	src, err := ddbstore.New(ddbstore.StoreOptions{InMemory: true}, exportTable)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+	for i := range 60 {
+		item := copyItem(testItem)
+		item["sk"] = &types.AttributeValueMemberS{Value: strings.Repeat("x", i+1)}
+		if _, err := src.PutItem(ctx, &dynamodb.PutItemInput{TableName: &exportTable.Name, Item: item}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "data", ddbexport.FileName("users", ddbexport.Ion))
+	os.MkdirAll(filepath.Dir(path), 0755)
+	os.WriteFile(filepath.Join(dir, "manifest-summary.json"), []byte("{}"), 0644)
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, _ := ddbexport.NewWriter(f, ddbexport.Ion)
+	if n, err := ddbexport.Export(ctx, src, "users", w); err != nil || n != 60 {
+		t.Fatalf("Export() = %d, %v, want 60", n, err)
+	}
+	w.Close()
+	f.Close()
+
+	files, err := ddbexport.DataFiles(dir)
+	if err != nil || !reflect.DeepEqual(files, []string{path}) {
+		t.Fatalf("DataFiles() = %v, %v, want %s", files, err, path)
+	}
+
+	dst, err := ddbstore.New(ddbstore.StoreOptions{InMemory: true}, exportTable)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dst.Close()
+	f, _ = os.Open(path)
+	defer f.Close()
+	r, err := ddbexport.NewReader(f, ddbexport.Ion)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err := ddbexport.Import(ctx, dst, exportTable, r); err != nil || n != 60 {
+		t.Fatalf("Import() = %d, %v, want 60", n, err)
+	}
+	out, err := dst.Scan(ctx, &dynamodb.ScanInput{TableName: &exportTable.Name})
+	if err != nil || len(out.Items) != 60 {
+		t.Fatalf("Scan() = %d items, %v, want 60", len(out.Items), err)
+	}
+}
+
+func TestImport_WritesItemsAsTheyAre(t *testing.T) {
+	ctx := context.Background()
+	events := table.TableDefinition{
+		Name: "events",
+		KeyDefinitions: table.PrimaryKeyDefinition{
+			PartitionKey: table.KeyDef{Name: "pk", Kind: table.KeyKindS},
+			SortKey:      table.KeyDef{Name: "seq", Kind: table.KeyKindN},
+		},
+		EntityTypeKey: "type",
+	}
+	dst, err := ddbstore.New(ddbstore.StoreOptions{InMemory: true}, events)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dst.Close()
+
+	item := ddbexport.Item{
+		"pk":   &types.AttributeValueMemberS{Value: "STREAM#1"},
+		"seq":  &types.AttributeValueMemberN{Value: "12345678901234567890"},
+		"type": &types.AttributeValueMemberS{Value: "OrderPlaced"},
+	}
+	var buf bytes.Buffer
+	w, _ := ddbexport.NewWriter(&buf, ddbexport.DynamoDBJSON)
+	w.Write(item)
+	w.Close()
+	r, err := ddbexport.NewReader(&buf, ddbexport.DynamoDBJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err := ddbexport.Import(ctx, dst, events, r); err != nil || n != 1 {
+		t.Fatalf("Import() = %d, %v, want 1", n, err)
+	}
+	out, err := dst.Scan(ctx, &dynamodb.ScanInput{TableName: &events.Name})
+	if err != nil || len(out.Items) != 1 || !reflect.DeepEqual(out.Items[0], item) {
+		t.Fatalf("Scan() = %v, %v, want %v", out.Items, err, item)
+	}
+
+	buf.Reset()
+	w, _ = ddbexport.NewWriter(&buf, ddbexport.DynamoDBJSON)
+	w.Write(ddbexport.Item{"pk": &types.AttributeValueMemberS{Value: "STREAM#2"}})
+	w.Close()
+	r, _ = ddbexport.NewReader(&buf, ddbexport.DynamoDBJSON)
+	if _, err := ddbexport.Import(ctx, dst, events, r); err == nil || !strings.Contains(err.Error(), `missing key attribute "seq"`) {
+		t.Errorf("Import() error = %v, want a missing key error", err)
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	for s, want := range map[string]ddbexport.Format{"DYNAMODB_JSON": ddbexport.DynamoDBJSON, "dynamodb-json": ddbexport.DynamoDBJSON, "ion": ddbexport.Ion} {
+		if got, err := ddbexport.ParseFormat(s); err != nil || got != want {
+			t.Errorf("ParseFormat(%q) = %q, %v, want %q", s, got, err, want)
+		}
+	}
+	if f, ok := ddbexport.FormatFromPath("data/x.ion.gz"); !ok || f != ddbexport.Ion {
+		t.Errorf("FormatFromPath = %q, %v", f, ok)
+	}
+}
@@ -0,0 +1,758 @@
+package ddbexport
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// This file implements the subset of Amazon Ion text that DynamoDB exports use:
+// structs, lists, strings, symbols, integers, decimals, floats, booleans, nulls, blobs
+// and annotations. Timestamps and s-expressions are rejected, DynamoDB has no such types.
+
+const ionVersionMarker = "$ion_1_0"
+
+// Annotations of lists that hold DynamoDB sets.
+const (
+	ionStringSet = "$dynamodb_SS"
+	ionNumberSet = "$dynamodb_NS"
+	ionBinarySet = "$dynamodb_BS"
+)
+
+func marshalIonItem(item Item) ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteString("{Item:")
+	if err := writeIonStruct(&b, item); err != nil {
+		return nil, err
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+func writeIonStruct(b *bytes.Buffer, m Item) error {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	b.WriteByte('{')
+	for i, k := range keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		writeIonFieldName(b, k)
+		b.WriteByte(':')
+		if err := writeIonValue(b, m[k]); err != nil {
+			return fmt.Errorf("attribute %s: %w", k, err)
+		}
+	}
+	b.WriteByte('}')
+	return nil
+}
+
+func writeIonValue(b *bytes.Buffer, av types.AttributeValue) error {
+	switch v := av.(type) {
+	case *types.AttributeValueMemberS:
+		writeIonString(b, v.Value)
+	case *types.AttributeValueMemberN:
+		b.WriteString(ionDecimal(v.Value))
+	case *types.AttributeValueMemberB:
+		writeIonBlob(b, v.Value)
+	case *types.AttributeValueMemberBOOL:
+		b.WriteString(strconv.FormatBool(v.Value))
+	case *types.AttributeValueMemberNULL:
+		b.WriteString("null")
+	case *types.AttributeValueMemberSS:
+		b.WriteString(ionStringSet + "::[")
+		for i, s := range v.Value {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			writeIonString(b, s)
+		}
+		b.WriteByte(']')
+	case *types.AttributeValueMemberNS:
+		b.WriteString(ionNumberSet + "::[")
+		for i, n := range v.Value {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(ionDecimal(n))
+		}
+		b.WriteByte(']')
+	case *types.AttributeValueMemberBS:
+		b.WriteString(ionBinarySet + "::[")
+		for i, bs := range v.Value {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			writeIonBlob(b, bs)
+		}
+		b.WriteByte(']')
+	case *types.AttributeValueMemberL:
+		b.WriteByte('[')
+		for i, e := range v.Value {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			if err := writeIonValue(b, e); err != nil {
+				return err
+			}
+		}
+		b.WriteByte(']')
+	case *types.AttributeValueMemberM:
+		return writeIonStruct(b, v.Value)
+	default:
+		return fmt.Errorf("unsupported attribute value %T", av)
+	}
+	return nil
+}
+
+var ionIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_$]*$`)
+
+// writeIonFieldName writes name as an identifier, or as a quoted symbol if it isn't one.
+func writeIonFieldName(b *bytes.Buffer, name string) {
+	switch name {
+	case "null", "true", "false", "nan":
+	default:
+		if ionIdentifier.MatchString(name) {
+			b.WriteString(name)
+			return
+		}
+	}
+	writeIonQuoted(b, name, '\'')
+}
+
+func writeIonString(b *bytes.Buffer, s string) {
+	writeIonQuoted(b, s, '"')
+}
+
+func writeIonQuoted(b *bytes.Buffer, s string, quote byte) {
+	b.WriteByte(quote)
+	for _, r := range s {
+		switch {
+		case r == rune(quote) || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r < 0x20 || r == 0x7f:
+			fmt.Fprintf(b, `\x%02x`, r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte(quote)
+}
+
+func writeIonBlob(b *bytes.Buffer, data []byte) {
+	b.WriteString("{{")
+	b.WriteString(base64.StdEncoding.EncodeToString(data))
+	b.WriteString("}}")
+}
+
+// ionDecimal converts a DynamoDB number to an Ion decimal, e.g. "42" to "42." and
+// "1.5E+3" to "1.5d+3", so that it doesn't read back as an int or float.
+func ionDecimal(n string) string {
+	n = strings.TrimPrefix(n, "+")
+	if i := strings.IndexAny(n, "eE"); i >= 0 {
+		return n[:i] + "d" + n[i+1:]
+	}
+	if !strings.Contains(n, ".") {
+		return n + "."
+	}
+	return n
+}
+
+// dynamoNumber converts an Ion int, decimal or float to a DynamoDB number.
+func dynamoNumber(tok string) (string, error) {
+	tok = strings.ReplaceAll(tok, "_", "")
+	lower := strings.ToLower(tok)
+	if strings.HasPrefix(strings.TrimPrefix(lower, "-"), "0x") || strings.HasPrefix(strings.TrimPrefix(lower, "-"), "0b") {
+		i, ok := new(big.Int).SetString(lower, 0)
+		if !ok {
+			return "", fmt.Errorf("invalid integer %q", tok)
+		}
+		return i.String(), nil
+	}
+	if strings.ContainsAny(lower, "d") {
+		lower = strings.Replace(lower, "d", "e", 1)
+	}
+	mantissa, exp, hasExp := strings.Cut(lower, "e")
+	mantissa = strings.TrimSuffix(mantissa, ".")
+	if _, ok := new(big.Float).SetString(mantissa); !ok || mantissa == "" {
+		return "", fmt.Errorf("invalid number %q", tok)
+	}
+	if hasExp {
+		if _, err := strconv.Atoi(exp); err != nil {
+			return "", fmt.Errorf("invalid number %q", tok)
+		}
+		return mantissa + "e" + exp, nil
+	}
+	return mantissa, nil
+}
+
+// ionValue is a parsed Ion value.
+type ionValue struct {
+	kind        ionKind
+	annotations []string
+	text        string // string, symbol or number token
+	blob        []byte
+	boolean     bool
+	list        []ionValue
+	fields      []ionField
+}
+
+type ionField struct {
+	name  string
+	value ionValue
+}
+
+type ionKind int
+
+const (
+	ionNull ionKind = iota
+	ionBool
+	ionNumber
+	ionString
+	ionSymbol
+	ionBlob
+	ionList
+	ionStruct
+)
+
+// newIonItemDecoder returns a function decoding the next {Item: {...}} struct of r,
+// skipping version markers and symbol tables.
+func newIonItemDecoder(r *bufio.Reader) func() (Item, error) {
+	p := &ionParser{r: r}
+	return func() (Item, error) {
+		for {
+			if err := p.skipSpace(); err != nil {
+				return nil, err
+			}
+			v, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			if v.kind == ionSymbol && len(v.annotations) == 0 && v.text == ionVersionMarker {
+				continue
+			}
+			if v.kind == ionStruct && len(v.annotations) == 1 && v.annotations[0] == "$ion_symbol_table" {
+				continue
+			}
+			if v.kind != ionStruct {
+				return nil, fmt.Errorf("expected a struct, got %s", v.describe())
+			}
+			for _, f := range v.fields {
+				if f.name == "Item" {
+					av, err := f.value.attributeValue()
+					if err != nil {
+						return nil, err
+					}
+					m, ok := av.(*types.AttributeValueMemberM)
+					if !ok {
+						return nil, fmt.Errorf("Item is %s, not a struct", f.value.describe())
+					}
+					return m.Value, nil
+				}
+			}
+			return nil, fmt.Errorf("no Item field")
+		}
+	}
+}
+
+func (v ionValue) describe() string {
+	switch v.kind {
+	case ionNull:
+		return "null"
+	case ionBool:
+		return "a bool"
+	case ionNumber:
+		return "number " + v.text
+	case ionString:
+		return "a string"
+	case ionSymbol:
+		return "symbol " + v.text
+	case ionBlob:
+		return "a blob"
+	case ionList:
+		return "a list"
+	}
+	return "a struct"
+}
+
+// attributeValue converts v to the DynamoDB attribute value it exports.
+func (v ionValue) attributeValue() (types.AttributeValue, error) {
+	set := ""
+	if len(v.annotations) > 0 {
+		set = v.annotations[0]
+	}
+	switch v.kind {
+	case ionNull:
+		return &types.AttributeValueMemberNULL{Value: true}, nil
+	case ionBool:
+		return &types.AttributeValueMemberBOOL{Value: v.boolean}, nil
+	case ionNumber:
+		n, err := dynamoNumber(v.text)
+		if err != nil {
+			return nil, err
+		}
+		return &types.AttributeValueMemberN{Value: n}, nil
+	case ionString, ionSymbol:
+		return &types.AttributeValueMemberS{Value: v.text}, nil
+	case ionBlob:
+		return &types.AttributeValueMemberB{Value: v.blob}, nil
+	case ionStruct:
+		m := make(Item, len(v.fields))
+		for _, f := range v.fields {
+			av, err := f.value.attributeValue()
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", f.name, err)
+			}
+			m[f.name] = av
+		}
+		return &types.AttributeValueMemberM{Value: m}, nil
+	}
+	// A list, or a set.
+	elems := make([]types.AttributeValue, len(v.list))
+	for i, e := range v.list {
+		av, err := e.attributeValue()
+		if err != nil {
+			return nil, err
+		}
+		elems[i] = av
+	}
+	switch set {
+	case ionStringSet:
+		ss := make([]string, len(elems))
+		for i, e := range elems {
+			s, ok := e.(*types.AttributeValueMemberS)
+			if !ok {
+				return nil, fmt.Errorf("string set holds %T", e)
+			}
+			ss[i] = s.Value
+		}
+		return &types.AttributeValueMemberSS{Value: ss}, nil
+	case ionNumberSet:
+		ns := make([]string, len(elems))
+		for i, e := range elems {
+			n, ok := e.(*types.AttributeValueMemberN)
+			if !ok {
+				return nil, fmt.Errorf("number set holds %T", e)
+			}
+			ns[i] = n.Value
+		}
+		return &types.AttributeValueMemberNS{Value: ns}, nil
+	case ionBinarySet:
+		bs := make([][]byte, len(elems))
+		for i, e := range elems {
+			b, ok := e.(*types.AttributeValueMemberB)
+			if !ok {
+				return nil, fmt.Errorf("binary set holds %T", e)
+			}
+			bs[i] = b.Value
+		}
+		return &types.AttributeValueMemberBS{Value: bs}, nil
+	}
+	return &types.AttributeValueMemberL{Value: elems}, nil
+}
+
+type ionParser struct {
+	r *bufio.Reader
+}
+
+func (p *ionParser) peek() (byte, error) {
+	b, err := p.r.Peek(1)
+	if err != nil {
+		return 0, err
+	}
+	return b[0], nil
+}
+
+func (p *ionParser) peekString(n int) string {
+	b, _ := p.r.Peek(n)
+	return string(b)
+}
+
+func (p *ionParser) expect(c byte) error {
+	b, err := p.r.ReadByte()
+	if err != nil {
+		return unexpectedEOF(err)
+	}
+	if b != c {
+		return fmt.Errorf("expected %q, got %q", c, b)
+	}
+	return nil
+}
+
+func unexpectedEOF(err error) error {
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// skipSpace skips whitespace and comments. It returns io.EOF at the end of the input.
+func (p *ionParser) skipSpace() error {
+	for {
+		c, err := p.peek()
+		if err != nil {
+			return err
+		}
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v':
+			p.r.ReadByte()
+		case p.peekString(2) == "//":
+			if _, err := p.r.ReadString('\n'); err != nil {
+				return err
+			}
+		case p.peekString(2) == "/*":
+			p.r.Discard(2)
+			for p.peekString(2) != "*/" {
+				if _, err := p.r.ReadByte(); err != nil {
+					return unexpectedEOF(err)
+				}
+			}
+			p.r.Discard(2)
+		default:
+			return nil
+		}
+	}
+}
+
+// value parses a value with its annotations, at a non-space character.
+func (p *ionParser) value() (ionValue, error) {
+	var annotations []string
+	for {
+		c, err := p.peek()
+		if err != nil {
+			return ionValue{}, unexpectedEOF(err)
+		}
+		var v ionValue
+		switch {
+		case c == '{' && p.peekString(2) == "{{":
+			v, err = p.lob()
+		case c == '{':
+			v, err = p.structure()
+		case c == '[':
+			v, err = p.list()
+		case c == '(':
+			return ionValue{}, fmt.Errorf("s-expressions are not supported")
+		case c == '"' || p.peekString(3) == "'''":
+			var s string
+			s, err = p.str()
+			v = ionValue{kind: ionString, text: s}
+		case c == '\'':
+			var s string
+			s, err = p.quoted('\'')
+			v = ionValue{kind: ionSymbol, text: s}
+		case c == '-' || c == '+' || c >= '0' && c <= '9':
+			v, err = p.number()
+		case isIdentStart(c):
+			v, err = p.keyword()
+		default:
+			return ionValue{}, fmt.Errorf("unexpected %q", c)
+		}
+		if err != nil {
+			return ionValue{}, err
+		}
+		if v.kind == ionSymbol {
+			if err := p.skipSpace(); err != nil && !errors.Is(err, io.EOF) {
+				return ionValue{}, err
+			}
+			if p.peekString(2) == "::" {
+				p.r.Discard(2)
+				annotations = append(annotations, v.text)
+				if err := p.skipSpace(); err != nil {
+					return ionValue{}, unexpectedEOF(err)
+				}
+				continue
+			}
+		}
+		v.annotations = annotations
+		return v, nil
+	}
+}
+
+func isIdentStart(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_' || c == '$'
+}
+
+func isIdentPart(c byte) bool {
+	return isIdentStart(c) || c >= '0' && c <= '9'
+}
+
+func (p *ionParser) ident() string {
+	var b strings.Builder
+	for {
+		c, err := p.peek()
+		if err != nil || !isIdentPart(c) {
+			return b.String()
+		}
+		p.r.ReadByte()
+		b.WriteByte(c)
+	}
+}
+
+// keyword parses an identifier symbol, or true, false, nan and the (typed) nulls.
+func (p *ionParser) keyword() (ionValue, error) {
+	id := p.ident()
+	switch id {
+	case "true", "false":
+		return ionValue{kind: ionBool, boolean: id == "true"}, nil
+	case "nan":
+		return ionValue{}, fmt.Errorf("nan can't be stored in DynamoDB")
+	case "null":
+		if c, err := p.peek(); err == nil && c == '.' {
+			p.r.ReadByte()
+			p.ident()
+		}
+		return ionValue{kind: ionNull}, nil
+	}
+	return ionValue{kind: ionSymbol, text: id}, nil
+}
+
+// number parses an int, decimal or float token.
+func (p *ionParser) number() (ionValue, error) {
+	var b strings.Builder
+	for {
+		c, err := p.peek()
+		if err != nil || strings.IndexByte(" \t\n\r\f\v,]})/", c) >= 0 {
+			break
+		}
+		p.r.ReadByte()
+		b.WriteByte(c)
+	}
+	tok := b.String()
+	switch {
+	case tok == "+inf" || tok == "-inf":
+		return ionValue{}, fmt.Errorf("%s can't be stored in DynamoDB", tok)
+	case strings.ContainsAny(tok, "T:") || strings.Count(strings.TrimPrefix(tok, "-"), "-") > 0 && !strings.ContainsAny(tok, "eEdD"):
+		return ionValue{}, fmt.Errorf("timestamps are not supported: %s", tok)
+	}
+	return ionValue{kind: ionNumber, text: tok}, nil
+}
+
+// str parses a string, or adjacent long strings, which are concatenated.
+func (p *ionParser) str() (string, error) {
+	if p.peekString(3) != "'''" {
+		return p.quoted('"')
+	}
+	var b strings.Builder
+	for p.peekString(3) == "'''" {
+		p.r.Discard(3)
+		for p.peekString(3) != "'''" {
+			if err := p.char(&b); err != nil {
+				return "", err
+			}
+		}
+		p.r.Discard(3)
+		if err := p.skipSpace(); err != nil && !errors.Is(err, io.EOF) {
+			return "", err
+		}
+	}
+	return b.String(), nil
+}
+
+// quoted parses a string or symbol between quote characters.
+func (p *ionParser) quoted(quote byte) (string, error) {
+	if err := p.expect(quote); err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	for {
+		c, err := p.peek()
+		if err != nil {
+			return "", unexpectedEOF(err)
+		}
+		if c == quote {
+			p.r.ReadByte()
+			return b.String(), nil
+		}
+		if err := p.char(&b); err != nil {
+			return "", err
+		}
+	}
+}
+
+// char reads a character of a string, resolving escapes.
+func (p *ionParser) char(b *strings.Builder) error {
+	r, _, err := p.r.ReadRune()
+	if err != nil {
+		return unexpectedEOF(err)
+	}
+	if r != '\\' {
+		b.WriteRune(r)
+		return nil
+	}
+	e, err := p.r.ReadByte()
+	if err != nil {
+		return unexpectedEOF(err)
+	}
+	simple := map[byte]rune{'0': 0, 'a': '\a', 'b': '\b', 't': '\t', 'n': '\n', 'f': '\f', 'r': '\r', 'v': '\v',
+		'"': '"', '\'': '\'', '?': '?', '\\': '\\', '/': '/'}
+	if r, ok := simple[e]; ok {
+		b.WriteRune(r)
+		return nil
+	}
+	digits := map[byte]int{'x': 2, 'u': 4, 'U': 8}[e]
+	switch {
+	case digits > 0:
+		hex := make([]byte, digits)
+		if _, err := io.ReadFull(p.r, hex); err != nil {
+			return unexpectedEOF(err)
+		}
+		code, err := strconv.ParseUint(string(hex), 16, 32)
+		if err != nil || !utf8.ValidRune(rune(code)) {
+			return fmt.Errorf("invalid escape \\%c%s", e, hex)
+		}
+		b.WriteRune(rune(code))
+	case e == '\n': // line continuation
+	case e == '\r':
+		if c, err := p.peek(); err == nil && c == '\n' {
+			p.r.ReadByte()
+		}
+	default:
+		return fmt.Errorf("invalid escape \\%c", e)
+	}
+	return nil
+}
+
+// lob parses a blob, or a clob, which is read as its bytes.
+func (p *ionParser) lob() (ionValue, error) {
+	p.r.Discard(2)
+	if err := p.skipSpace(); err != nil {
+		return ionValue{}, unexpectedEOF(err)
+	}
+	if c, _ := p.peek(); c == '"' || p.peekString(3) == "'''" {
+		s, err := p.str()
+		if err != nil {
+			return ionValue{}, err
+		}
+		if err := p.skipSpace(); err != nil {
+			return ionValue{}, unexpectedEOF(err)
+		}
+		if p.peekString(2) != "}}" {
+			return ionValue{}, fmt.Errorf("unterminated clob")
+		}
+		p.r.Discard(2)
+		return ionValue{kind: ionBlob, blob: []byte(s)}, nil
+	}
+	var b strings.Builder
+	for p.peekString(2) != "}}" {
+		c, err := p.r.ReadByte()
+		if err != nil {
+			return ionValue{}, unexpectedEOF(err)
+		}
+		if c != ' ' && c != '\t' && c != '\n' && c != '\r' {
+			b.WriteByte(c)
+		}
+	}
+	p.r.Discard(2)
+	data, err := base64.StdEncoding.DecodeString(b.String())
+	if err != nil {
+		return ionValue{}, fmt.Errorf("invalid blob: %w", err)
+	}
+	return ionValue{kind: ionBlob, blob: data}, nil
+}
+
+func (p *ionParser) list() (ionValue, error) {
+	p.r.ReadByte()
+	v := ionValue{kind: ionList, list: []ionValue{}}
+	for {
+		if err := p.skipSpace(); err != nil {
+			return ionValue{}, unexpectedEOF(err)
+		}
+		if c, _ := p.peek(); c == ']' {
+			p.r.ReadByte()
+			return v, nil
+		}
+		e, err := p.value()
+		if err != nil {
+			return ionValue{}, err
+		}
+		v.list = append(v.list, e)
+		if err := p.separator(']'); err != nil {
+			return ionValue{}, err
+		}
+	}
+}
+
+func (p *ionParser) structure() (ionValue, error) {
+	p.r.ReadByte()
+	v := ionValue{kind: ionStruct}
+	for {
+		if err := p.skipSpace(); err != nil {
+			return ionValue{}, unexpectedEOF(err)
+		}
+		c, _ := p.peek()
+		if c == '}' {
+			p.r.ReadByte()
+			return v, nil
+		}
+		var name string
+		var err error
+		switch {
+		case c == '"' || p.peekString(3) == "'''":
+			name, err = p.str()
+		case c == '\'':
+			name, err = p.quoted('\'')
+		case isIdentStart(c):
+			name = p.ident()
+		default:
+			err = fmt.Errorf("unexpected %q in struct", c)
+		}
+		if err != nil {
+			return ionValue{}, err
+		}
+		if err := p.skipSpace(); err != nil {
+			return ionValue{}, unexpectedEOF(err)
+		}
+		if err := p.expect(':'); err != nil {
+			return ionValue{}, fmt.Errorf("field %s: %w", name, err)
+		}
+		if err := p.skipSpace(); err != nil {
+			return ionValue{}, unexpectedEOF(err)
+		}
+		fv, err := p.value()
+		if err != nil {
+			return ionValue{}, fmt.Errorf("field %s: %w", name, err)
+		}
+		v.fields = append(v.fields, ionField{name: name, value: fv})
+		if err := p.separator('}'); err != nil {
+			return ionValue{}, err
+		}
+	}
+}
+
+// separator consumes the comma after a container element, leaving the closing
+// character for the caller.
+func (p *ionParser) separator(end byte) error {
+	if err := p.skipSpace(); err != nil {
+		return unexpectedEOF(err)
+	}
+	c, _ := p.peek()
+	switch c {
+	case ',':
+		p.r.ReadByte()
+		return nil
+	case end:
+		return nil
+	}
+	return fmt.Errorf("expected ',' or %q, got %q", end, c)
+}
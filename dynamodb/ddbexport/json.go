@@ -0,0 +1,183 @@
+package ddbexport
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+type jsonLine struct {
+	Item map[string]json.RawMessage `json:"Item"`
+}
+
+func marshalJSONItem(item Item) ([]byte, error) {
+	m, err := JSONAttributes(item)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(map[string]any{"Item": m})
+}
+
+// JSONAttributes returns the attributes of item in DynamoDB JSON, as in the requests of
+// the DynamoDB API, e.g. {"id": {"S": "abc"}}.
+func JSONAttributes(item Item) (map[string]any, error) {
+	m := make(map[string]any, len(item))
+	for k, av := range item {
+		v, err := jsonValue(av)
+		if err != nil {
+			return nil, fmt.Errorf("attribute %s: %w", k, err)
+		}
+		m[k] = v
+	}
+	return m, nil
+}
+
+// jsonValue returns the DynamoDB JSON of av, e.g. {"N": "42"}.
+func jsonValue(av types.AttributeValue) (any, error) {
+	switch v := av.(type) {
+	case *types.AttributeValueMemberS:
+		return map[string]any{"S": v.Value}, nil
+	case *types.AttributeValueMemberN:
+		return map[string]any{"N": v.Value}, nil
+	case *types.AttributeValueMemberB:
+		return map[string]any{"B": base64.StdEncoding.EncodeToString(v.Value)}, nil
+	case *types.AttributeValueMemberBOOL:
+		return map[string]any{"BOOL": v.Value}, nil
+	case *types.AttributeValueMemberNULL:
+		return map[string]any{"NULL": true}, nil
+	case *types.AttributeValueMemberSS:
+		return map[string]any{"SS": v.Value}, nil
+	case *types.AttributeValueMemberNS:
+		return map[string]any{"NS": v.Value}, nil
+	case *types.AttributeValueMemberBS:
+		bs := make([]string, len(v.Value))
+		for i, b := range v.Value {
+			bs[i] = base64.StdEncoding.EncodeToString(b)
+		}
+		return map[string]any{"BS": bs}, nil
+	case *types.AttributeValueMemberL:
+		l := make([]any, len(v.Value))
+		for i, e := range v.Value {
+			ev, err := jsonValue(e)
+			if err != nil {
+				return nil, err
+			}
+			l[i] = ev
+		}
+		return map[string]any{"L": l}, nil
+	case *types.AttributeValueMemberM:
+		m, err := JSONAttributes(v.Value)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"M": m}, nil
+	}
+	return nil, fmt.Errorf("unsupported attribute value %T", av)
+}
+
+// newJSONItemDecoder returns a function decoding the next {"Item": {...}} object of r.
+func newJSONItemDecoder(r *bufio.Reader) func() (Item, error) {
+	dec := json.NewDecoder(r)
+	return func() (Item, error) {
+		var line jsonLine
+		if err := dec.Decode(&line); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil, io.EOF
+			}
+			return nil, err
+		}
+		if line.Item == nil {
+			return nil, fmt.Errorf(`no "Item" object`)
+		}
+		return parseJSONAttributes(line.Item)
+	}
+}
+
+func parseJSONAttributes(raw map[string]json.RawMessage) (Item, error) {
+	item := make(Item, len(raw))
+	for k, r := range raw {
+		av, err := parseJSONValue(r)
+		if err != nil {
+			return nil, fmt.Errorf("attribute %s: %w", k, err)
+		}
+		item[k] = av
+	}
+	return item, nil
+}
+
+// parseJSONValue parses an attribute value in DynamoDB JSON.
+func parseJSONValue(raw json.RawMessage) (types.AttributeValue, error) {
+	var typed map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &typed); err != nil {
+		return nil, err
+	}
+	if len(typed) != 1 {
+		return nil, fmt.Errorf("expected a single type descriptor, got %s", raw)
+	}
+	for typ, v := range typed {
+		switch typ {
+		case "S":
+			var s string
+			err := json.Unmarshal(v, &s)
+			return &types.AttributeValueMemberS{Value: s}, err
+		case "N":
+			var s string
+			err := json.Unmarshal(v, &s)
+			return &types.AttributeValueMemberN{Value: s}, err
+		case "B":
+			var b []byte // base64 in JSON
+			err := json.Unmarshal(v, &b)
+			return &types.AttributeValueMemberB{Value: b}, err
+		case "BOOL":
+			var b bool
+			err := json.Unmarshal(v, &b)
+			return &types.AttributeValueMemberBOOL{Value: b}, err
+		case "NULL":
+			return &types.AttributeValueMemberNULL{Value: true}, nil
+		case "SS":
+			var ss []string
+			err := json.Unmarshal(v, &ss)
+			return &types.AttributeValueMemberSS{Value: ss}, err
+		case "NS":
+			var ns []string
+			err := json.Unmarshal(v, &ns)
+			return &types.AttributeValueMemberNS{Value: ns}, err
+		case "BS":
+			var bs [][]byte
+			err := json.Unmarshal(v, &bs)
+			return &types.AttributeValueMemberBS{Value: bs}, err
+		case "L":
+			var raws []json.RawMessage
+			if err := json.Unmarshal(v, &raws); err != nil {
+				return nil, err
+			}
+			l := make([]types.AttributeValue, len(raws))
+			for i, r := range raws {
+				av, err := parseJSONValue(r)
+				if err != nil {
+					return nil, err
+				}
+				l[i] = av
+			}
+			return &types.AttributeValueMemberL{Value: l}, nil
+		case "M":
+			var raws map[string]json.RawMessage
+			if err := json.Unmarshal(v, &raws); err != nil {
+				return nil, err
+			}
+			m, err := parseJSONAttributes(raws)
+			if err != nil {
+				return nil, err
+			}
+			return &types.AttributeValueMemberM{Value: m}, nil
+		default:
+			return nil, fmt.Errorf("unknown type descriptor %q", typ)
+		}
+	}
+	panic("unreachable")
+}
@@ -0,0 +1,88 @@
+package ddbgen
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/acksell/bezos/dynamodb/schema"
+	"gopkg.in/yaml.v3"
+)
+
+// AllowlistFileName is the file in the schema/ directory that acknowledges breaking
+// changes, one [schema.Change.ID] per line.
+const AllowlistFileName = "breaking_changes_allowed.txt"
+
+// checkSchema compares the schema generated from indexes and streams with the committed one in
+// dir/schema, or the one at the git ref if set. It fails if there are breaking changes
+// that the allowlist doesn't acknowledge.
+func checkSchema(dir string, indexes []indexInfo, streams []streamInfo, ref string) error {
+	generated, err := generatedSchema(indexes, streams)
+	if err != nil {
+		return err
+	}
+
+	oldData, err := readCommittedSchema(dir, ref)
+	if errors.Is(err, os.ErrNotExist) {
+		fmt.Printf("ddb gen: no committed schema to compare against\n")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var committed schema.Schema
+	if err := yaml.Unmarshal(oldData, &committed); err != nil {
+		return fmt.Errorf("parsing committed schema: %w", err)
+	}
+
+	allow := make(schema.Allowlist)
+	if f, err := os.Open(filepath.Join(dir, "schema", AllowlistFileName)); err == nil {
+		allow, err = schema.ParseAllowlist(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("reading allowlist: %w", err)
+		}
+	}
+
+	changes := schema.Diff(committed, generated)
+	for _, c := range changes {
+		marker := " "
+		switch {
+		case c.Breaking && allow[c.ID()]:
+			marker = "~" // acknowledged
+		case c.Breaking:
+			marker = "!"
+		}
+		fmt.Printf("%s %s\n", marker, c)
+	}
+	if broken := allow.Unacknowledged(changes); len(broken) > 0 {
+		return fmt.Errorf("%d breaking schema change(s), add their IDs to schema/%s to acknowledge them", len(broken), AllowlistFileName)
+	}
+	fmt.Printf("ddb gen: schema check passed (%d change(s))\n", len(changes))
+	return nil
+}
+
+// readCommittedSchema reads schema_dynamodb.yaml from dir/schema, or from the given
+// git ref. Returns an error wrapping os.ErrNotExist if there is none.
+func readCommittedSchema(dir, ref string) ([]byte, error) {
+	path := filepath.Join(dir, "schema", schemaFileName)
+	if ref == "" {
+		return os.ReadFile(path)
+	}
+	cmd := exec.Command("git", "show", ref+":./"+filepath.ToSlash(filepath.Join("schema", schemaFileName)))
+	cmd.Dir = dir
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		msg := stderr.String()
+		if strings.Contains(msg, "does not exist") || strings.Contains(msg, "exists on disk, but not in") {
+			return nil, fmt.Errorf("%s at %s: %w", path, ref, os.ErrNotExist)
+		}
+		return nil, fmt.Errorf("git show %s: %v: %s", ref, err, strings.TrimSpace(msg))
+	}
+	return out, nil
+}
@@ -6,10 +6,12 @@ import (
 	"fmt"
 	"go/format"
 	"os"
+	"slices"
 	"strings"
 	"text/template"
 
 	"github.com/acksell/bezos/dynamodb/index/val"
+	"github.com/acksell/bezos/dynamodb/internal/naming"
 )
 
 // =============================================================================
@@ -182,6 +184,15 @@ func buildIndexData(idx indexInfo) (indexData, error) {
 		IsVersioned:  idx.IsVersioned,
 	}
 
+	for _, f := range idx.Fields {
+		goType := f.GoType
+		if goType == "" {
+			// Still usable in conditions, just without type checking of values.
+			goType = "any"
+		}
+		data.Fields = append(data.Fields, fieldRefData{Name: f.Name, Tag: f.Tag, GoType: goType})
+	}
+
 	if idx.SortKey != nil && !idx.SortKey.IsZero() {
 		skData, err := buildKeyData(*idx.SortKey, tagMap, true, idx.EntityType)
 		if err != nil {
@@ -191,6 +202,9 @@ func buildIndexData(idx indexInfo) (indexData, error) {
 		data.SortKey = &skData
 	}
 
+	data.KeyFields = mergeKeyFields(data.PartitionKey, data.SortKey)
+	data.Validation, data.Patterns, data.UsesUTF8 = validationCode(idx.EntityType, idx.Fields)
+
 	for _, gsi := range idx.GSIs {
 		gd, err := buildGSIData(gsi, tagMap, idx.EntityType)
 		if err != nil {
@@ -212,6 +226,7 @@ func buildGSIData(gsi gsiInfo, tagMap map[string]fieldInfo, entityType string) (
 		Name:         gsi.Name,
 		Index:        gsi.Index,
 		PartitionKey: pkData,
+		Sparse:       gsi.Include != nil,
 	}
 
 	if gsi.SKPattern != nil && !gsi.SKPattern.IsZero() {
@@ -222,6 +237,7 @@ func buildGSIData(gsi gsiInfo, tagMap map[string]fieldInfo, entityType string) (
 		data.HasSortKey = true
 		data.SortKey = &skData
 	}
+	data.KeyFields = mergeKeyFields(data.PartitionKey, data.SortKey)
 
 	return data, nil
 }
@@ -272,7 +288,7 @@ func buildKeyData(vd val.ValDef, tagMap map[string]fieldInfo, isSortKey bool, en
 			return keyData{}, fmt.Errorf("no struct field found with tag %q", vd.FromField)
 		}
 		pName := vd.FromField
-		pType := goParamType(field.Type)
+		pType := goParamType(field)
 		part := val.SpecPart{Value: vd.FromField}
 		paramResult, err := generateConversionExpr(part, pName, field.Type)
 		if err != nil {
@@ -283,10 +299,11 @@ func buildKeyData(vd val.ValDef, tagMap map[string]fieldInfo, isSortKey bool, en
 			return keyData{}, fmt.Errorf("field %q: %w", vd.FromField, err)
 		}
 		return keyData{
-			Params:           []paramData{{Name: pName, Type: pType, FieldType: field.Type}},
+			Params:           []paramData{{Name: pName, Path: vd.FromField, Type: pType, FieldType: field.Type}},
 			FormatExpr:       paramResult.Expr,
 			EntityFormatExpr: entityResult.Expr,
 			FieldRefNames:    []string{pName},
+			Fields:           keyFields(nil, field, vd.FromField),
 			UsesFmt:          paramResult.UsesFmt || entityResult.UsesFmt,
 			UsesStrconv:      paramResult.UsesStrconv || entityResult.UsesStrconv,
 			UsesTime:         pType == "time.Time" || paramResult.UsesTime || entityResult.UsesTime,
@@ -319,6 +336,7 @@ func buildKeyData(vd val.ValDef, tagMap map[string]fieldInfo, isSortKey bool, en
 	var params []paramData
 	var formatParts, entityFormatParts []string
 	var fieldRefNames []string
+	var fields []keyFieldData
 	usesStrconv, usesTime, usesFmt := false, false, false
 	literalPrefix := spec.LiteralPrefix()
 
@@ -333,12 +351,12 @@ func buildKeyData(vd val.ValDef, tagMap map[string]fieldInfo, isSortKey bool, en
 			return keyData{}, fmt.Errorf("no struct field found with tag %q", part.Value)
 		}
 		pName := part.ParamName()
-		pType := goParamType(field.Type)
+		pType := goParamType(field)
 		if pType == "time.Time" {
 			usesTime = true
 		}
 		params = append(params, paramData{
-			Name: pName, Type: pType, FieldType: field.Type,
+			Name: pName, Path: part.Value, Type: pType, FieldType: field.Type,
 			Formats: part.Formats, PrintfSpec: part.PrintfSpec,
 		})
 		if isSortKey {
@@ -352,6 +370,7 @@ func buildKeyData(vd val.ValDef, tagMap map[string]fieldInfo, isSortKey bool, en
 		}
 		formatParts = append(formatParts, paramResult.Expr)
 		fieldRefNames = append(fieldRefNames, pName)
+		fields = keyFields(fields, field, part.Value)
 		usesStrconv = usesStrconv || paramResult.UsesStrconv
 		usesTime = usesTime || paramResult.UsesTime
 		usesFmt = usesFmt || paramResult.UsesFmt
@@ -372,12 +391,43 @@ func buildKeyData(vd val.ValDef, tagMap map[string]fieldInfo, isSortKey bool, en
 		EntityFormatExpr: joinExprParts(entityFormatParts),
 		LiteralPrefix:    literalPrefix,
 		FieldRefNames:    fieldRefNames,
+		Fields:           fields,
 		UsesFmt:          usesFmt,
 		UsesStrconv:      usesStrconv,
 		UsesTime:         usesTime,
 	}, nil
 }
 
+// keyFields appends field to fields unless it is already there.
+// Fields with types that generated code can't name are left out, so key parsing
+// only sets fields it can convert to with val.As.
+func keyFields(fields []keyFieldData, field fieldInfo, path string) []keyFieldData {
+	if field.GoType == "" {
+		return fields
+	}
+	for _, f := range fields {
+		if f.FieldName == field.Name {
+			return fields
+		}
+	}
+	return append(fields, keyFieldData{FieldName: field.Name, Path: path, GoType: field.GoType})
+}
+
+// mergeKeyFields returns the fields of pk and sk without duplicates.
+func mergeKeyFields(pk keyData, sk *keyData) []keyFieldData {
+	var fields []keyFieldData
+	keys := []keyData{pk}
+	if sk != nil {
+		keys = append(keys, *sk)
+	}
+	for _, k := range keys {
+		for _, f := range k.Fields {
+			fields = keyFields(fields, fieldInfo{Name: f.FieldName, GoType: f.GoType}, f.Path)
+		}
+	}
+	return fields
+}
+
 func joinExprParts(parts []string) string {
 	if len(parts) == 0 {
 		return `""`
@@ -473,10 +523,30 @@ func needsStrconvImport(idx indexData) bool {
 	return false
 }
 
+func needsValImport(idx indexData) bool {
+	if !idx.HasEntity() {
+		return false
+	}
+	if len(idx.KeyFields) > 0 {
+		return true
+	}
+	for _, gsi := range idx.GSIs {
+		if len(gsi.KeyFields) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
 func needsTimeImport(idx indexData) bool {
 	if idx.PartitionKey.UsesTime {
 		return true
 	}
+	for _, f := range idx.Fields {
+		if strings.Contains(f.GoType, "time.Time") {
+			return true
+		}
+	}
 	if idx.SortKey != nil && idx.SortKey.UsesTime {
 		return true
 	}
@@ -491,11 +561,136 @@ func needsTimeImport(idx indexData) bool {
 	return false
 }
 
+// buildTableData returns the tables that get a polymorphic decoder: those with
+// an entity type attribute or with several entity types.
+func buildTableData(indexes []indexInfo) []tableData {
+	var tables []tableData
+	pos := make(map[string]int)
+	for _, idx := range indexes {
+		i, ok := pos[idx.TableName]
+		if !ok {
+			i = len(tables)
+			pos[idx.TableName] = i
+			tables = append(tables, tableData{
+				Name:          idx.TableName,
+				Ident:         naming.Exported(idx.TableName),
+				EntityTypeKey: idx.EntityTypeKey,
+				IndexVarName:  idx.VarName,
+			})
+		}
+		tables[i].Entities = append(tables[i].Entities, tableEntityData{EntityType: idx.EntityType, TypeName: idx.EntityTypeName})
+	}
+	return slices.DeleteFunc(tables, func(t tableData) bool {
+		return t.EntityTypeKey == "" && len(t.Entities) < 2
+	})
+}
+
+// pkParams returns the parameter list of a key function, e.g. "tenantID string".
+func pkParams(kd keyData) string {
+	var parts []string
+	for _, p := range kd.Params {
+		parts = append(parts, fmt.Sprintf("%s %s", p.Name, p.Type))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// maxKeySuffix sorts after any UTF-8 continuation of a key prefix.
+const maxKeySuffix = "\U0010FFFF"
+
+// buildCollectionData groups entities stored under the same partition key pattern of a
+// table into item collections. Members are ordered with single-item entities first.
+func buildCollectionData(indexes []indexInfo, data []indexData) []collectionData {
+	var collections []collectionData
+	pos := make(map[string]int)
+	for i, idx := range indexes {
+		if idx.SortKey == nil || idx.SortKey.IsZero() {
+			continue
+		}
+		d := data[i]
+		group := idx.TableName + "\x00" + valDefPattern(idx.PartitionKey) + "\x00" + pkParams(d.PartitionKey)
+		member := collectionMemberData{
+			EntityType: idx.EntityType,
+			TypeName:   idx.EntityTypeName,
+			Single:     d.SortKey.IsConstant,
+			SortKey:    *d.SortKey,
+			SKLow:      d.SortKey.LiteralPrefix,
+			SKHigh:     d.SortKey.LiteralPrefix,
+		}
+		if !member.Single {
+			member.SKHigh += maxKeySuffix
+		}
+		j, ok := pos[group]
+		if !ok {
+			j = len(collections)
+			pos[group] = j
+			collections = append(collections, collectionData{
+				TableName:        idx.TableName,
+				PartitionPattern: valDefPattern(idx.PartitionKey),
+				PartitionKey:     d.PartitionKey,
+				IndexVarName:     idx.VarName,
+				HasRange:         true,
+			})
+		}
+		c := &collections[j]
+		c.Members = append(c.Members, member)
+		if member.SKLow == "" || valDefKind(*idx.SortKey) != string(val.SpecKindS) {
+			c.HasRange = false
+		}
+	}
+
+	collections = slices.DeleteFunc(collections, func(c collectionData) bool { return len(c.Members) < 2 })
+	for i := range collections {
+		c := &collections[i]
+		slices.SortStableFunc(c.Members, func(a, b collectionMemberData) int {
+			switch {
+			case a.Single == b.Single:
+				return 0
+			case a.Single:
+				return -1
+			}
+			return 1
+		})
+		for j := range c.Members {
+			m := &c.Members[j]
+			m.Field = m.EntityType
+			if !m.Single {
+				m.Field = plural(m.EntityType)
+			}
+			m.Var = strings.ToLower(m.Field[:1]) + m.Field[1:]
+		}
+		c.Name = c.Members[0].EntityType + "Collection"
+	}
+	return collections
+}
+
+// plural returns the English plural of an entity type name, for collection fields.
+func plural(name string) string {
+	switch {
+	case strings.HasSuffix(name, "y") && !strings.HasSuffix(name, "ey") && !strings.HasSuffix(name, "ay"):
+		return name[:len(name)-1] + "ies"
+	case strings.HasSuffix(name, "s"), strings.HasSuffix(name, "x"), strings.HasSuffix(name, "ch"), strings.HasSuffix(name, "sh"):
+		return name + "es"
+	}
+	return name + "s"
+}
+
 // =============================================================================
 // Template functions and code generation
 // =============================================================================
 
 var tmplFuncs = template.FuncMap{
+	"maxKeySuffix": func() string { return maxKeySuffix },
+	"hasSparseGSI": func(idx indexData) bool {
+		for _, gsi := range idx.GSIs {
+			if gsi.Sparse {
+				return true
+			}
+		}
+		return false
+	},
+	"parseKeys": func(entity, parseExpr string, fields []keyFieldData) parseKeysData {
+		return parseKeysData{Entity: entity, Parse: parseExpr, Fields: fields}
+	},
 	"allParams": func(idx indexData) string {
 		var parts []string
 		for _, p := range idx.PartitionKey.Params {
@@ -520,6 +715,20 @@ var tmplFuncs = template.FuncMap{
 		}
 		return strings.Join(args, ", ")
 	},
+	"knownFields": func(idx indexData) string {
+		var entries []string
+		params := idx.PartitionKey.Params
+		if idx.SortKey != nil {
+			params = append(params[:len(params):len(params)], idx.SortKey.Params...)
+		}
+		for _, p := range params {
+			entries = append(entries, fmt.Sprintf("%q: %s", p.Path, p.Name))
+		}
+		if len(entries) == 0 {
+			return "nil"
+		}
+		return "map[string]any{" + strings.Join(entries, ", ") + "}"
+	},
 	"gsiAllParams": func(gsi gsiData) string {
 		var parts []string
 		for _, p := range gsi.PartitionKey.Params {
@@ -544,13 +753,7 @@ var tmplFuncs = template.FuncMap{
 		}
 		return strings.Join(args, ", ")
 	},
-	"pkParams": func(kd keyData) string {
-		var parts []string
-		for _, p := range kd.Params {
-			parts = append(parts, fmt.Sprintf("%s %s", p.Name, p.Type))
-		}
-		return strings.Join(parts, ", ")
-	},
+	"pkParams": pkParams,
 	"pkArgs": func(kd keyData) string {
 		var args []string
 		for _, p := range kd.Params {
@@ -605,7 +808,8 @@ var tmplFuncs = template.FuncMap{
 // generateCode transforms index info into Go source code using the index template.
 func generateCode(packageName string, indexes []indexInfo) ([]byte, error) {
 	var idxDataList []indexData
-	needsFmt, needsStrconv, needsTime := false, false, false
+	needsFmt, needsStrconv, needsTime, needsVal := false, false, false, false
+	needsErrors, needsRegexp, needsUTF8 := false, false, false
 
 	for _, idx := range indexes {
 		data, err := buildIndexData(idx)
@@ -622,8 +826,26 @@ func generateCode(packageName string, indexes []indexInfo) ([]byte, error) {
 		if needsTimeImport(data) {
 			needsTime = true
 		}
+		if needsValImport(data) {
+			needsVal = true
+		}
+		if data.Validation != "" {
+			needsErrors = true
+		}
+		if len(data.Patterns) > 0 {
+			needsRegexp = true
+		}
+		if data.UsesUTF8 {
+			needsUTF8 = true
+		}
 	}
 
+	tables := buildTableData(indexes)
+	if len(tables) > 0 {
+		needsFmt = true
+	}
+	collections := buildCollectionData(indexes, idxDataList)
+
 	imports := []string{
 		`"sync"`,
 		`"github.com/acksell/bezos/dynamodb/ddbsdk"`,
@@ -632,6 +854,9 @@ func generateCode(packageName string, indexes []indexInfo) ([]byte, error) {
 		`"github.com/acksell/bezos/dynamodb/table"`,
 		`"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"`,
 	}
+	if needsVal {
+		imports = append(imports, `"github.com/acksell/bezos/dynamodb/index/val"`)
+	}
 	if needsFmt {
 		imports = append([]string{`"fmt"`}, imports...)
 	}
@@ -641,12 +866,29 @@ func generateCode(packageName string, indexes []indexInfo) ([]byte, error) {
 	if needsTime {
 		imports = append([]string{`"time"`}, imports...)
 	}
+	if needsErrors {
+		imports = append([]string{`"errors"`}, imports...)
+	}
+	if needsRegexp {
+		imports = append([]string{`"regexp"`}, imports...)
+	}
+	if needsUTF8 {
+		imports = append([]string{`"unicode/utf8"`}, imports...)
+	}
+	if len(collections) > 0 {
+		imports = append([]string{`"context"`}, imports...)
+	}
+	if slices.ContainsFunc(collections, func(c collectionData) bool { return c.HasRange }) {
+		imports = append([]string{`"slices"`}, imports...)
+	}
 
 	tmplData := struct {
-		Package string
-		Imports []string
-		Indexes []indexData
-	}{packageName, imports, idxDataList}
+		Package     string
+		Imports     []string
+		Indexes     []indexData
+		Tables      []tableData
+		Collections []collectionData
+	}{packageName, imports, idxDataList, tables, collections}
 
 	tmpl, err := template.New("index.tmpl").Funcs(tmplFuncs).ParseFS(templates, "template/index.tmpl")
 	if err != nil {
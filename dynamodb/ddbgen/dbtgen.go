@@ -0,0 +1,76 @@
+package ddbgen
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/acksell/bezos/dynamodb/dbt"
+)
+
+// DBTDirName is the directory in schema/ that ddbgen writes dbt sources and models
+// to, see [GenerateOptions.DBT].
+const DBTDirName = "dbt"
+
+// generateDBTFiles writes the dbt sources and staging models of every table to dir.
+// Generated models of entities that no longer exist are removed.
+func generateDBTFiles(dir string, indexes []indexInfo, streams []streamInfo, dialect dbt.Dialect) error {
+	s, err := generatedSchema(indexes, streams)
+	if err != nil {
+		return err
+	}
+	files, err := dbt.Generate(s, dialect)
+	if err != nil {
+		return err
+	}
+	if err := removeGeneratedDBTFiles(filepath.Join(dir, "models")); err != nil {
+		return err
+	}
+	for _, f := range files {
+		path := filepath.Join(dir, filepath.FromSlash(f.Path))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return fmt.Errorf("creating dbt directory: %w", err)
+		}
+		if err := os.WriteFile(path, f.Content, 0644); err != nil {
+			return fmt.Errorf("writing %s: %w", path, err)
+		}
+	}
+	fmt.Printf("ddb gen: generated %s dbt models of %d tables in %s\n", dialect, len(s.Tables), dir)
+	return nil
+}
+
+// removeGeneratedDBTFiles removes the files in dir that start with the generated
+// header, leaving models that were added by hand.
+func removeGeneratedDBTFiles(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", dir, err)
+	}
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		path := filepath.Join(dir, e.Name())
+		if isGeneratedDBTFile(path) {
+			if err := os.Remove(path); err != nil {
+				return fmt.Errorf("removing %s: %w", path, err)
+			}
+		}
+	}
+	return nil
+}
+
+func isGeneratedDBTFile(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	return scanner.Scan() && strings.Contains(scanner.Text(), dbt.Header)
+}
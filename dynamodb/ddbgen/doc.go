@@ -24,7 +24,92 @@
 // It imports your package (populating the indices registry via side-effect)
 // and then calls [Generate] with the appropriate options.
 //
+// # Several entity types per table
+//
+// Entities registered on the same table must have key patterns that can't produce
+// the same primary key, otherwise generation fails. Set [table.TableDefinition.EntityTypeKey]
+// to store each item's entity type; tables with it, or with several entities, get a
+// generated Decode<Table>Items function that decodes a mixed query page into typed values.
+//
+// Entities sharing a partition key pattern form an item collection, e.g. a customer
+// profile and its orders. ddbgen generates a <Parent>Collection struct, named after the
+// entity with a constant sort key, and a Query<Parent>Collection builder whose sort key
+// range covers every member, so the whole collection is fetched with one query. Each
+// member's range can be narrowed, e.g. OrdersBeginsWith("2024"), and Exec returns the
+// populated struct.
+//
 // You can also run ddb gen from the CLI to regenerate all packages at once:
 //
 //	ddb gen
+//
+// # Validation
+//
+// Entity fields can declare rules in a validate struct tag, comma-separated:
+//
+//	type Order struct {
+//	    OrderID string    `dynamodbav:"orderID" validate:"required,maxlen=64"`
+//	    Amount  int       `dynamodbav:"amount" validate:"min=1,max=10000"`
+//	    Status  string    `dynamodbav:"status" validate:"enum=pending|paid|shipped"`
+//	    Email   string    `dynamodbav:"email" validate:"regex=^[^@]+@[^@]+$"`
+//	    Start   time.Time `dynamodbav:"start" validate:"required,ltfield=End"`
+//	    End     time.Time `dynamodbav:"end"`
+//	}
+//
+// The rules are required (non-zero, or non-nil for pointers; a time must not be zero),
+// min and max for numbers, len, minlen and maxlen for strings (in runes), slices and
+// maps, regex and enum (values separated by |), and eqfield, nefield, gtfield, gtefield,
+// ltfield and ltefield comparing with another field of the same type. regex takes the
+// rest of the tag and must be last. regex and enum accept empty strings, combine them
+// with required otherwise. Rules of pointer fields only apply when they are set.
+//
+// ddbgen generates an IsValidGenerated method returning a [ddbsdk.FieldError] for every
+// violated rule. It doesn't replace IsValid, which can call it and add its own checks:
+//
+//	func (o *Order) IsValid() error {
+//	    return o.IsValidGenerated()
+//	}
+//
+// Since gen/main.go imports your package, add the call after a first generation.
+// The rules are also written to schema/schema_dynamodb.yaml, so that ddb ui can check
+// items before saving them.
+//
+// # Breaking schema changes
+//
+// With [GenerateOptions.Check] (ddb gen --check) nothing is written. Instead the schema
+// is compared with the committed schema/schema_dynamodb.yaml, or the one at a git ref
+// with ddb gen --check --ref main, and generation fails on breaking changes such as a
+// changed key pattern, a removed GSI or a renamed dynamodbav tag. Once a break is
+// intended, add its ID as printed to schema/breaking_changes_allowed.txt.
+//
+// # Event streams
+//
+// Streams registered with [eventsource.Add] get an eventsource_gen.go with a sealed
+// <Aggregate>Event interface that only their event types implement, the list of
+// their stored event type names, and a Decode<Aggregate>Event function that decodes
+// an [eventsource.Record] into its typed event. The streams and the fields of their
+// events are written to the schema, where removing an event type is a breaking
+// change: stored events are never rewritten, so they must stay decodable.
+//
+// # Mutation report
+//
+// [AnalyzeMutations] (ddb gen --mutations ./...) type-checks your packages and lists
+// every function that calls the UnsafePut, SafePut, UnsafeUpdate or Delete builders of
+// an index, or the ddbsdk constructors of those actions, with the labels it passes to
+// [ddbsdk.WithCause], followed by the functions writing each entity. It answers which
+// commands can change an entity without reading every method of the service. At runtime,
+// [ddbsdk.WithAuditLog] records the same per item.
+//
+// # Infrastructure
+//
+// With [GenerateOptions.Infra] (ddb gen --infra terraform,cdk) every table is also
+// written to schema/infra/ as a CloudFormation template, Terraform resource or CDK
+// construct, see package [infra]. Key types, GSIs and the TTL attribute come from the
+// [table.TableDefinition]. To keep hand-maintained templates in line with the Go
+// definitions instead, run ddb schema verify-infra on them in CI.
+//
+// # dbt models
+//
+// With [GenerateOptions.DBT] (ddb gen --dbt snowflake) schema/dbt/models/ gets a dbt
+// source per table and a staging model per entity, reading DynamoDB exports to S3,
+// see package [dbt]. Models of removed entities are deleted on the next generation.
 package ddbgen
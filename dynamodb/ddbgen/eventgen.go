@@ -0,0 +1,83 @@
+package ddbgen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"reflect"
+	"text/template"
+
+	"github.com/acksell/bezos/dynamodb/ddbsdk/eventsource"
+	"github.com/acksell/bezos/dynamodb/index/val"
+	"github.com/acksell/bezos/dynamodb/internal/naming"
+)
+
+// eventsOutput is the file the event stream code is written to.
+const eventsOutput = "eventsource_gen.go"
+
+// streamToInfo converts a registered stream to streamInfo.
+func streamToInfo(s *eventsource.Stream) (streamInfo, error) {
+	info := streamInfo{
+		Name:      naming.Exported(s.Aggregate),
+		Aggregate: s.Aggregate,
+		Table:     s.Table,
+
+		PartitionKeyPattern: s.PartitionKeyPattern(),
+	}
+	names := s.EventTypes()
+	for i, e := range s.Events {
+		t := reflect.TypeOf(e)
+		for t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		fields, err := structFields(t)
+		if err != nil {
+			return streamInfo{}, fmt.Errorf("event %s: %w", t.Name(), err)
+		}
+		info.Events = append(info.Events, eventInfo{Type: names[i], GoType: t.Name(), Fields: fields})
+	}
+	return info, nil
+}
+
+// validateStreams checks that no entity sharing a table with a stream has keys that
+// could overwrite its event or snapshot items.
+func validateStreams(streams []streamInfo, indexes []indexInfo) error {
+	for _, s := range streams {
+		pk := val.Fmt(s.PartitionKeyPattern)
+		sortKeys := []val.ValDef{val.Fmt(eventsource.EventSortKeyPattern), val.Fmt(eventsource.SnapshotSortKey)}
+		for _, idx := range indexes {
+			if idx.TableName != s.Table.Name || !idx.PartitionKey.Overlaps(pk) {
+				continue
+			}
+			for _, sk := range sortKeys {
+				if idx.SortKey == nil || idx.SortKey.Overlaps(sk) {
+					return fmt.Errorf("table %q: key patterns of %s (%s) overlap the %s event stream, use distinct literal prefixes",
+						s.Table.Name, idx.EntityType, keyPatternString(idx), s.Aggregate)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// generateEventsCode renders the sealed event interfaces and decoders of the streams.
+func generateEventsCode(packageName string, streams []streamInfo) ([]byte, error) {
+	tmpl, err := template.ParseFS(templates, "template/eventsource.tmpl")
+	if err != nil {
+		return nil, fmt.Errorf("parsing template: %w", err)
+	}
+	data := struct {
+		Package string
+		Streams []streamInfo
+	}{packageName, streams}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("executing template: %w", err)
+	}
+	formatted, err := format.Source(buf.Bytes())
+	if err != nil {
+		return buf.Bytes(), fmt.Errorf("formatting generated code: %w\n%s", err, buf.String())
+	}
+	return formatted, nil
+}
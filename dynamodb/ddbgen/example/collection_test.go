@@ -0,0 +1,83 @@
+package example
+
+import (
+	"context"
+	"testing"
+
+	"github.com/acksell/bezos/dynamodb/ddbsdk"
+)
+
+func seedOrders(t *testing.T) *ddbsdk.Client {
+	t.Helper()
+	db := ddbsdk.NewMemoryClient(OrderTable)
+	w := db.NewBulkWriter()
+	w.AddAction(
+		CustomerIndex.UnsafePut(&Customer{TenantID: "t1", Name: "Ada", Email: "ada@example.com"}),
+		OrderIndex.UnsafePut(&Order{TenantID: "t1", OrderID: "2023-12", Amount: 5, Status: "paid"}),
+		OrderIndex.UnsafePut(&Order{TenantID: "t1", OrderID: "2024-01", Amount: 10, Status: "paid"}),
+		OrderIndex.UnsafePut(&Order{TenantID: "t1", OrderID: "2024-02", Amount: 20, Status: "pending"}),
+		OrderIndex.UnsafePut(&Order{TenantID: "t2", OrderID: "2024-01", Amount: 30, Status: "paid"}),
+	)
+	if _, err := w.Exec(context.Background()); err != nil {
+		t.Fatalf("seeding: %v", err)
+	}
+	return db
+}
+
+func orderIDs(orders []Order) []string {
+	var ids []string
+	for _, o := range orders {
+		ids = append(ids, o.OrderID)
+	}
+	return ids
+}
+
+func TestQueryCustomerCollection(t *testing.T) {
+	db := seedOrders(t)
+	ctx := context.Background()
+
+	c, err := QueryCustomerCollection("t1").Exec(ctx, db)
+	if err != nil {
+		t.Fatalf("Exec: %v", err)
+	}
+	if c.Customer == nil || c.Customer.Name != "Ada" {
+		t.Errorf("expected customer Ada, got %+v", c.Customer)
+	}
+	if got := orderIDs(c.Orders); len(got) != 3 {
+		t.Errorf("expected the 3 orders of t1, got %v", got)
+	}
+}
+
+func TestQueryCustomerCollection_MemberRanges(t *testing.T) {
+	db := seedOrders(t)
+	ctx := context.Background()
+
+	tests := map[string]struct {
+		query CustomerCollectionQuery
+		want  []string
+	}{
+		"begins with": {QueryCustomerCollection("t1").OrdersBeginsWith("2024"), []string{"2024-01", "2024-02"}},
+		"between":     {QueryCustomerCollection("t1").OrdersBetween("2023-12", "2024-01"), []string{"2023-12", "2024-01"}},
+		"empty range": {QueryCustomerCollection("t1").OrdersBeginsWith("2025"), nil},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			c, err := tt.query.Exec(ctx, db)
+			if err != nil {
+				t.Fatalf("Exec: %v", err)
+			}
+			if c.Customer == nil {
+				t.Error("expected the customer regardless of the orders' range")
+			}
+			got := orderIDs(c.Orders)
+			if len(got) != len(tt.want) {
+				t.Fatalf("expected orders %v, got %v", tt.want, got)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("expected orders %v, got %v", tt.want, got)
+				}
+			}
+		})
+	}
+}
@@ -22,14 +22,28 @@ func (u *User) VersionField() (string, any) {
 
 // Order represents an order entity stored in DynamoDB.
 type Order struct {
-	TenantID string `dynamodbav:"tenantID"`
-	OrderID  string `dynamodbav:"orderID"`
-	Amount   int    `dynamodbav:"amount"`
+	TenantID string `dynamodbav:"tenantID" validate:"required"`
+	OrderID  string `dynamodbav:"orderID" validate:"required,maxlen=64"`
+	Amount   int    `dynamodbav:"amount" validate:"min=1"`
+	Status   string `dynamodbav:"status" validate:"required,enum=pending|paid|shipped|cancelled"`
 }
 
-// IsValid implements ddbsdk.DynamoEntity.
+// IsValid implements ddbsdk.DynamoEntity, checking the validate tags with the
+// generated IsValidGenerated.
 func (o *Order) IsValid() error {
-	return nil
+	return o.IsValidGenerated()
+}
+
+// Customer is the tenant's customer profile, stored in the same partition as its orders.
+type Customer struct {
+	TenantID string `dynamodbav:"tenantID" validate:"required"`
+	Name     string `dynamodbav:"name" validate:"required,maxlen=100"`
+	Email    string `dynamodbav:"email" validate:"regex=^[^@\\s]+@[^@\\s]+$"`
+}
+
+// IsValid implements ddbsdk.DynamoEntity.
+func (c *Customer) IsValid() error {
+	return c.IsValidGenerated()
 }
 
 // Message represents a chat message in a project's conversation history.
@@ -37,10 +51,10 @@ type Message struct {
 	ChatID      string    `dynamodbav:"chatID"`
 	SequenceNum int64     `dynamodbav:"sequenceNum"`
 	Content     string    `dynamodbav:"content"`
-	CreatedAt   time.Time `dynamodbav:"createdAt"`
+	CreatedAt   time.Time `dynamodbav:"createdAt" validate:"required"`
 }
 
 // IsValid implements ddbsdk.DynamoEntity.
 func (m *Message) IsValid() error {
-	return nil
+	return m.IsValidGenerated()
 }
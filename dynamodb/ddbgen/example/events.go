@@ -0,0 +1,54 @@
+package example
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/acksell/bezos/dynamodb/ddbsdk/eventsource"
+)
+
+// CartStream stores shopping carts as events in the default events table.
+// ddbgen generates the CartEvent interface and DecodeCartEvent for it.
+var CartStream = eventsource.Add(eventsource.Stream{
+	Table:     eventsource.EventsTable,
+	Aggregate: "Cart",
+	Events:    []any{CartItemAdded{}, CartItemRemoved{}, CartCheckedOut{}},
+})
+
+type CartItemAdded struct {
+	SKU      string `dynamodbav:"sku"`
+	Quantity int    `dynamodbav:"quantity"`
+}
+
+type CartItemRemoved struct {
+	SKU string `dynamodbav:"sku"`
+}
+
+type CartCheckedOut struct {
+	OrderID string    `dynamodbav:"orderID"`
+	At      time.Time `dynamodbav:"at"`
+}
+
+// Cart is the state of a cart, rebuilt from its events by eventsource.Store.Load.
+type Cart struct {
+	Items      map[string]int `dynamodbav:"items"`
+	CheckedOut bool           `dynamodbav:"checkedOut"`
+}
+
+// Apply implements eventsource.Aggregate.
+func (c *Cart) Apply(event any) error {
+	switch e := event.(type) {
+	case CartItemAdded:
+		if c.Items == nil {
+			c.Items = make(map[string]int)
+		}
+		c.Items[e.SKU] += e.Quantity
+	case CartItemRemoved:
+		delete(c.Items, e.SKU)
+	case CartCheckedOut:
+		c.CheckedOut = true
+	default:
+		return fmt.Errorf("unexpected cart event %T", event)
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+// Code generated by ddbgen. DO NOT EDIT.
+
+package example
+
+import (
+	"fmt"
+
+	"github.com/acksell/bezos/dynamodb/ddbsdk/eventsource"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+)
+
+// =============================================================================
+// Cart Event Stream
+// =============================================================================
+
+// CartEvent is implemented by the event types of the Cart stream only.
+type CartEvent interface {
+	isCartEvent()
+}
+
+func (CartItemAdded) isCartEvent()   {}
+func (CartItemRemoved) isCartEvent() {}
+func (CartCheckedOut) isCartEvent()  {}
+
+// CartEventTypes are the stored type names of the Cart events.
+var CartEventTypes = []string{
+	"CartItemAdded",
+	"CartItemRemoved",
+	"CartCheckedOut",
+}
+
+// DecodeCartEvent decodes the event of a record of the Cart stream.
+func DecodeCartEvent(r eventsource.Record) (CartEvent, error) {
+	switch r.Type {
+	case "CartItemAdded":
+		var e CartItemAdded
+		if err := attributevalue.UnmarshalMap(r.Data, &e); err != nil {
+			return nil, fmt.Errorf("decoding Cart event %d: %w", r.Seq, err)
+		}
+		return e, nil
+	case "CartItemRemoved":
+		var e CartItemRemoved
+		if err := attributevalue.UnmarshalMap(r.Data, &e); err != nil {
+			return nil, fmt.Errorf("decoding Cart event %d: %w", r.Seq, err)
+		}
+		return e, nil
+	case "CartCheckedOut":
+		var e CartCheckedOut
+		if err := attributevalue.UnmarshalMap(r.Data, &e); err != nil {
+			return nil, fmt.Errorf("decoding Cart event %d: %w", r.Seq, err)
+		}
+		return e, nil
+	}
+	return nil, fmt.Errorf("unknown Cart event type %q", r.Type)
+}
@@ -64,6 +64,13 @@ func main() {
 		OrderIndex.QueryPartition("tenant-123").OrderIDBeginsWith("2024"),
 	).Descending()
 
+	// item collection - a customer and its orders in one query
+	customer, err := QueryCustomerCollection("tenant-123").OrdersBeginsWith("2024").Exec(ctx, db)
+	if err != nil {
+		panic(fmt.Sprintf("failed to query collection: %v", err))
+	}
+	fmt.Println(customer.Customer.Name, len(customer.Orders))
+
 	batch := db.NewBatch()
 	batch.AddAction(
 		UserIndex.UnsafePut(user),
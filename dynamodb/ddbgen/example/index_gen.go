@@ -3,15 +3,21 @@
 package example
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"github.com/acksell/bezos/dynamodb/ddbsdk"
 	"github.com/acksell/bezos/dynamodb/index"
 	"github.com/acksell/bezos/dynamodb/index/indices"
+	"github.com/acksell/bezos/dynamodb/index/val"
 	"github.com/acksell/bezos/dynamodb/table"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"regexp"
+	"slices"
 	"strconv"
 	"sync"
 	"time"
+	"unicode/utf8"
 )
 
 // =============================================================================
@@ -33,6 +39,47 @@ func (idx *OrderIndexUtil) Definition() *index.PrimaryIndex[Order] {
 // OrderIndex is the typed wrapper for Order operations.
 var OrderIndex OrderIndexUtil
 
+// OrderFields holds typed references to the attributes of Order,
+// for use in filters, conditions and update operations.
+var OrderFields = struct {
+	TenantID ddbsdk.Field[string]
+	OrderID  ddbsdk.Field[string]
+	Amount   ddbsdk.Field[int]
+	Status   ddbsdk.Field[string]
+}{
+	TenantID: ddbsdk.NewField[string]("tenantID"),
+	OrderID:  ddbsdk.NewField[string]("orderID"),
+	Amount:   ddbsdk.NewField[int]("amount"),
+	Status:   ddbsdk.NewField[string]("status"),
+}
+
+// IsValidGenerated checks the validate struct tags of Order's fields,
+// returning a *ddbsdk.FieldError for each violated rule. Call it from IsValid.
+func (e *Order) IsValidGenerated() error {
+	var errs []error
+	if e.TenantID == "" {
+		errs = append(errs, &ddbsdk.FieldError{Field: "tenantID", Rule: "required", Msg: "is required"})
+	}
+	if e.OrderID == "" {
+		errs = append(errs, &ddbsdk.FieldError{Field: "orderID", Rule: "required", Msg: "is required"})
+	}
+	if utf8.RuneCountInString(e.OrderID) > 64 {
+		errs = append(errs, &ddbsdk.FieldError{Field: "orderID", Rule: "maxlen", Msg: "must have length at most 64"})
+	}
+	if e.Amount < 1 {
+		errs = append(errs, &ddbsdk.FieldError{Field: "amount", Rule: "min", Msg: "must be at least 1"})
+	}
+	if e.Status == "" {
+		errs = append(errs, &ddbsdk.FieldError{Field: "status", Rule: "required", Msg: "is required"})
+	}
+	switch e.Status {
+	case "", "pending", "paid", "shipped", "cancelled":
+	default:
+		errs = append(errs, &ddbsdk.FieldError{Field: "status", Rule: "enum", Msg: "must be one of pending, paid, shipped, cancelled"})
+	}
+	return errors.Join(errs...)
+}
+
 // PrimaryKey creates a primary key from explicit parameters.
 func (idx *OrderIndexUtil) PrimaryKey(tenantID string, orderID string) table.PrimaryKey {
 	return table.PrimaryKey{
@@ -55,9 +102,62 @@ func (idx *OrderIndexUtil) PrimaryKeyFrom(e *Order) table.PrimaryKey {
 	}
 }
 
+// GSIKeysFrom creates the GSI keys of a Order entity.
+// Sparse GSIs whose inclusion rule excludes e are left out.
+func (idx *OrderIndexUtil) GSIKeysFrom(e *Order) []table.PrimaryKey {
+	keys := make([]table.PrimaryKey, 0, 1)
+	if idx.Definition().Secondary[0].Includes(e) {
+		keys = append(keys, table.PrimaryKey{
+			Definition: idx.Definition().Secondary[0].KeyDefinition(),
+			Values: table.PrimaryKeyValues{
+				PartitionKey: "TENANT#" + e.TenantID + "#STATUS#" + e.Status,
+				SortKey:      "ORDER#" + e.OrderID,
+			},
+		})
+	}
+	return keys
+}
+
+// ParsePrimaryKey decodes the primary key attributes of item into a new Order.
+// Only the fields encoded in the key are set.
+func (idx *OrderIndexUtil) ParsePrimaryKey(item ddbsdk.Item) (*Order, error) {
+	k, err := idx.Definition().ParseKeys(item)
+	if err != nil {
+		return nil, err
+	}
+	e := new(Order)
+	if e.TenantID, err = val.As[string](k, "tenantID"); err != nil {
+		return nil, err
+	}
+	if e.OrderID, err = val.As[string](k, "orderID"); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// ParseByStatusKey decodes the ByStatus GSI key attributes of item into a new Order,
+// e.g. for items of a query on the GSI. Only the fields encoded in the GSI key are set.
+func (idx *OrderIndexUtil) ParseByStatusKey(item ddbsdk.Item) (*Order, error) {
+	k, err := idx.Definition().Secondary[0].ParseKeys(item)
+	if err != nil {
+		return nil, err
+	}
+	e := new(Order)
+	if e.TenantID, err = val.As[string](k, "tenantID"); err != nil {
+		return nil, err
+	}
+	if e.Status, err = val.As[string](k, "status"); err != nil {
+		return nil, err
+	}
+	if e.OrderID, err = val.As[string](k, "orderID"); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
 // UnsafePut creates a Put operation without optimistic locking.
 func (idx *OrderIndexUtil) UnsafePut(e *Order) *ddbsdk.Put {
-	return ddbsdk.NewUnsafePut(idx.Definition().Table, idx.PrimaryKeyFrom(e), e)
+	return ddbsdk.NewUnsafePut(idx.Definition().Table, idx.PrimaryKeyFrom(e), e).WithGSIKeys(idx.GSIKeysFrom(e)...)
 }
 
 // Delete creates a Delete operation.
@@ -66,8 +166,10 @@ func (idx *OrderIndexUtil) Delete(tenantID string, orderID string) *ddbsdk.Delet
 }
 
 // UnsafeUpdate creates an Update operation without optimistic locking.
+// GSI keys derived from fields set by the update are recomputed, see [ddbsdk.UnsafeUpdate.WithDerivedKeys].
 func (idx *OrderIndexUtil) UnsafeUpdate(tenantID string, orderID string) *ddbsdk.UnsafeUpdate {
-	return ddbsdk.NewUnsafeUpdate(idx.Definition().Table, idx.PrimaryKey(tenantID, orderID))
+	return ddbsdk.NewUnsafeUpdate(idx.Definition().Table, idx.PrimaryKey(tenantID, orderID)).
+		WithDerivedKeys(idx.Definition().DerivedKeys(), map[string]any{"tenantID": tenantID, "orderID": orderID})
 }
 
 // EnsureExists creates a ConditionCheck that asserts an item with this primary key exists.
@@ -80,6 +182,17 @@ func (idx *OrderIndexUtil) EnsureExists(tenantID string, orderID string) *ddbsdk
 	)
 }
 
+// ByStatusKey creates a key for querying the ByStatus GSI.
+func (idx *OrderIndexUtil) ByStatusKey(tenantID string, status string, orderID string) table.PrimaryKey {
+	return table.PrimaryKey{
+		Definition: idx.Definition().Secondary[0].KeyDefinition(),
+		Values: table.PrimaryKeyValues{
+			PartitionKey: "TENANT#" + tenantID + "#STATUS#" + status,
+			SortKey:      "ORDER#" + orderID,
+		},
+	}
+}
+
 // -------------------------------------------------------------------------
 // Primary Index Query Builder
 // -------------------------------------------------------------------------
@@ -136,6 +249,206 @@ func (q OrderPrimaryQuery) OrderIDLessThanOrEqual(orderID string) ddbsdk.QueryDe
 	return q.qd.WithSKCondition(ddbsdk.LessThanOrEqual("ORDER#" + orderID))
 }
 
+// -------------------------------------------------------------------------
+// OrderIndexByStatus - Query-only GSI Wrapper
+// -------------------------------------------------------------------------
+
+// OrderIndexByStatusUtil provides query methods for the ByStatus GSI.
+type OrderIndexByStatusUtil struct {
+	primary *OrderIndexUtil
+}
+
+// OrderIndexByStatus is the query-only wrapper for the ByStatus GSI.
+var OrderIndexByStatus = OrderIndexByStatusUtil{primary: &OrderIndex}
+
+// OrderByStatusQuery is a query builder for the ByStatus GSI.
+type OrderByStatusQuery struct {
+	idx *OrderIndexByStatusUtil
+	qd  ddbsdk.QueryDef
+}
+
+// QueryDef returns the underlying QueryDef, implementing ddbsdk.QueryDefinition.
+func (q OrderByStatusQuery) QueryDef() ddbsdk.QueryDef { return q.qd }
+
+// QueryPartition creates a query for the given partition key on the ByStatus GSI.
+func (idx OrderIndexByStatusUtil) QueryPartition(tenantID string, status string) OrderByStatusQuery {
+	return OrderByStatusQuery{
+		idx: &idx,
+		qd:  ddbsdk.QueryPartition(idx.primary.Definition().Table, "TENANT#"+tenantID+"#STATUS#"+status).OnIndex("ByStatus"),
+	}
+}
+
+// OrderIDEquals adds a sort key equals condition and returns the final QueryDef.
+func (q OrderByStatusQuery) OrderIDEquals(orderID string) ddbsdk.QueryDef {
+	return q.qd.WithSKCondition(ddbsdk.Equals("ORDER#" + orderID))
+}
+
+// OrderIDBeginsWith adds a sort key begins_with condition and returns the final QueryDef.
+func (q OrderByStatusQuery) OrderIDBeginsWith(prefix string) ddbsdk.QueryDef {
+	return q.qd.WithSKCondition(ddbsdk.BeginsWith("ORDER#" + prefix))
+}
+
+// OrderIDBetween adds a sort key between condition and returns the final QueryDef.
+func (q OrderByStatusQuery) OrderIDBetween(orderIDStart string, orderIDEnd string) ddbsdk.QueryDef {
+	return q.qd.WithSKCondition(ddbsdk.Between("ORDER#"+orderIDStart, "ORDER#"+orderIDEnd))
+}
+
+// OrderIDGreaterThan adds a sort key > condition and returns the final QueryDef.
+func (q OrderByStatusQuery) OrderIDGreaterThan(orderID string) ddbsdk.QueryDef {
+	return q.qd.WithSKCondition(ddbsdk.GreaterThan("ORDER#" + orderID))
+}
+
+// OrderIDGreaterThanOrEqual adds a sort key >= condition and returns the final QueryDef.
+func (q OrderByStatusQuery) OrderIDGreaterThanOrEqual(orderID string) ddbsdk.QueryDef {
+	return q.qd.WithSKCondition(ddbsdk.GreaterThanOrEqual("ORDER#" + orderID))
+}
+
+// OrderIDLessThan adds a sort key < condition and returns the final QueryDef.
+func (q OrderByStatusQuery) OrderIDLessThan(orderID string) ddbsdk.QueryDef {
+	return q.qd.WithSKCondition(ddbsdk.LessThan("ORDER#" + orderID))
+}
+
+// OrderIDLessThanOrEqual adds a sort key <= condition and returns the final QueryDef.
+func (q OrderByStatusQuery) OrderIDLessThanOrEqual(orderID string) ddbsdk.QueryDef {
+	return q.qd.WithSKCondition(ddbsdk.LessThanOrEqual("ORDER#" + orderID))
+}
+
+// =============================================================================
+// Customer Index Wrapper
+// =============================================================================
+
+// CustomerIndexUtil wraps the PrimaryIndex with strongly-typed methods.
+type CustomerIndexUtil struct {
+	o  sync.Once
+	pi *index.PrimaryIndex[Customer]
+}
+
+// Definition returns the underlying PrimaryIndex, resolving it lazily on first call.
+func (idx *CustomerIndexUtil) Definition() *index.PrimaryIndex[Customer] {
+	idx.o.Do(func() { idx.pi = indices.Get[Customer]() })
+	return idx.pi
+}
+
+// CustomerIndex is the typed wrapper for Customer operations.
+var CustomerIndex CustomerIndexUtil
+
+// CustomerFields holds typed references to the attributes of Customer,
+// for use in filters, conditions and update operations.
+var CustomerFields = struct {
+	TenantID ddbsdk.Field[string]
+	Name     ddbsdk.Field[string]
+	Email    ddbsdk.Field[string]
+}{
+	TenantID: ddbsdk.NewField[string]("tenantID"),
+	Name:     ddbsdk.NewField[string]("name"),
+	Email:    ddbsdk.NewField[string]("email"),
+}
+
+var customerEmailPattern = regexp.MustCompile(`^[^@\s]+@[^@\s]+$`)
+
+// IsValidGenerated checks the validate struct tags of Customer's fields,
+// returning a *ddbsdk.FieldError for each violated rule. Call it from IsValid.
+func (e *Customer) IsValidGenerated() error {
+	var errs []error
+	if e.TenantID == "" {
+		errs = append(errs, &ddbsdk.FieldError{Field: "tenantID", Rule: "required", Msg: "is required"})
+	}
+	if e.Name == "" {
+		errs = append(errs, &ddbsdk.FieldError{Field: "name", Rule: "required", Msg: "is required"})
+	}
+	if utf8.RuneCountInString(e.Name) > 100 {
+		errs = append(errs, &ddbsdk.FieldError{Field: "name", Rule: "maxlen", Msg: "must have length at most 100"})
+	}
+	if e.Email != "" && !customerEmailPattern.MatchString(e.Email) {
+		errs = append(errs, &ddbsdk.FieldError{Field: "email", Rule: "regex", Msg: "must match ^[^@\\s]+@[^@\\s]+$"})
+	}
+	return errors.Join(errs...)
+}
+
+// PrimaryKey creates a primary key from explicit parameters.
+func (idx *CustomerIndexUtil) PrimaryKey(tenantID string) table.PrimaryKey {
+	return table.PrimaryKey{
+		Definition: idx.Definition().Table.KeyDefinitions,
+		Values: table.PrimaryKeyValues{
+			PartitionKey: "TENANT#" + tenantID,
+			SortKey:      "CUSTOMER",
+		},
+	}
+}
+
+// PrimaryKeyFrom creates the primary key from a Customer entity.
+func (idx *CustomerIndexUtil) PrimaryKeyFrom(e *Customer) table.PrimaryKey {
+	return table.PrimaryKey{
+		Definition: idx.Definition().Table.KeyDefinitions,
+		Values: table.PrimaryKeyValues{
+			PartitionKey: "TENANT#" + e.TenantID,
+			SortKey:      "CUSTOMER",
+		},
+	}
+}
+
+// ParsePrimaryKey decodes the primary key attributes of item into a new Customer.
+// Only the fields encoded in the key are set.
+func (idx *CustomerIndexUtil) ParsePrimaryKey(item ddbsdk.Item) (*Customer, error) {
+	k, err := idx.Definition().ParseKeys(item)
+	if err != nil {
+		return nil, err
+	}
+	e := new(Customer)
+	if e.TenantID, err = val.As[string](k, "tenantID"); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// UnsafePut creates a Put operation without optimistic locking.
+func (idx *CustomerIndexUtil) UnsafePut(e *Customer) *ddbsdk.Put {
+	return ddbsdk.NewUnsafePut(idx.Definition().Table, idx.PrimaryKeyFrom(e), e)
+}
+
+// Delete creates a Delete operation.
+func (idx *CustomerIndexUtil) Delete(tenantID string) *ddbsdk.Delete {
+	return ddbsdk.NewDelete(idx.Definition().Table, idx.PrimaryKey(tenantID))
+}
+
+// UnsafeUpdate creates an Update operation without optimistic locking.
+// GSI keys derived from fields set by the update are recomputed, see [ddbsdk.UnsafeUpdate.WithDerivedKeys].
+func (idx *CustomerIndexUtil) UnsafeUpdate(tenantID string) *ddbsdk.UnsafeUpdate {
+	return ddbsdk.NewUnsafeUpdate(idx.Definition().Table, idx.PrimaryKey(tenantID)).
+		WithDerivedKeys(idx.Definition().DerivedKeys(), map[string]any{"tenantID": tenantID})
+}
+
+// EnsureExists creates a ConditionCheck that asserts an item with this primary key exists.
+// Use this in transactions for referential integrity checks.
+func (idx *CustomerIndexUtil) EnsureExists(tenantID string) *ddbsdk.ConditionCheck {
+	return ddbsdk.NewConditionCheck(
+		idx.Definition().Table,
+		idx.PrimaryKey(tenantID),
+		expression.AttributeExists(expression.Name(idx.Definition().Table.KeyDefinitions.PartitionKey.Name)),
+	)
+}
+
+// -------------------------------------------------------------------------
+// Primary Index Query Builder
+// -------------------------------------------------------------------------
+
+// CustomerPrimaryQuery is a query builder for the primary index.
+type CustomerPrimaryQuery struct {
+	idx *CustomerIndexUtil
+	qd  ddbsdk.QueryDef
+}
+
+// Build returns the underlying QueryDef, implementing ddbsdk.QueryBuilder.
+func (q CustomerPrimaryQuery) Build() ddbsdk.QueryDef { return q.qd }
+
+// QueryPartition creates a query for the given partition key on the primary index.
+func (idx *CustomerIndexUtil) QueryPartition(tenantID string) CustomerPrimaryQuery {
+	return CustomerPrimaryQuery{
+		idx: idx,
+		qd:  ddbsdk.QueryPartition(idx.Definition().Table, "TENANT#"+tenantID),
+	}
+}
+
 // =============================================================================
 // Message Index Wrapper
 // =============================================================================
@@ -155,6 +468,30 @@ func (idx *MessageIndexUtil) Definition() *index.PrimaryIndex[Message] {
 // MessageIndex is the typed wrapper for Message operations.
 var MessageIndex MessageIndexUtil
 
+// MessageFields holds typed references to the attributes of Message,
+// for use in filters, conditions and update operations.
+var MessageFields = struct {
+	ChatID      ddbsdk.Field[string]
+	SequenceNum ddbsdk.Field[int64]
+	Content     ddbsdk.Field[string]
+	CreatedAt   ddbsdk.Field[time.Time]
+}{
+	ChatID:      ddbsdk.NewField[string]("chatID"),
+	SequenceNum: ddbsdk.NewField[int64]("sequenceNum"),
+	Content:     ddbsdk.NewField[string]("content"),
+	CreatedAt:   ddbsdk.NewField[time.Time]("createdAt"),
+}
+
+// IsValidGenerated checks the validate struct tags of Message's fields,
+// returning a *ddbsdk.FieldError for each violated rule. Call it from IsValid.
+func (e *Message) IsValidGenerated() error {
+	var errs []error
+	if e.CreatedAt.IsZero() {
+		errs = append(errs, &ddbsdk.FieldError{Field: "createdAt", Rule: "required", Msg: "is required"})
+	}
+	return errors.Join(errs...)
+}
+
 // PrimaryKey creates a primary key from explicit parameters.
 func (idx *MessageIndexUtil) PrimaryKey(chatID string, sequenceNum int64) table.PrimaryKey {
 	return table.PrimaryKey{
@@ -177,6 +514,23 @@ func (idx *MessageIndexUtil) PrimaryKeyFrom(e *Message) table.PrimaryKey {
 	}
 }
 
+// ParsePrimaryKey decodes the primary key attributes of item into a new Message.
+// Only the fields encoded in the key are set.
+func (idx *MessageIndexUtil) ParsePrimaryKey(item ddbsdk.Item) (*Message, error) {
+	k, err := idx.Definition().ParseKeys(item)
+	if err != nil {
+		return nil, err
+	}
+	e := new(Message)
+	if e.ChatID, err = val.As[string](k, "chatID"); err != nil {
+		return nil, err
+	}
+	if e.SequenceNum, err = val.As[int64](k, "sequenceNum"); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
 // UnsafePut creates a Put operation without optimistic locking.
 func (idx *MessageIndexUtil) UnsafePut(e *Message) *ddbsdk.Put {
 	return ddbsdk.NewUnsafePut(idx.Definition().Table, idx.PrimaryKeyFrom(e), e)
@@ -188,8 +542,10 @@ func (idx *MessageIndexUtil) Delete(chatID string, sequenceNum int64) *ddbsdk.De
 }
 
 // UnsafeUpdate creates an Update operation without optimistic locking.
+// GSI keys derived from fields set by the update are recomputed, see [ddbsdk.UnsafeUpdate.WithDerivedKeys].
 func (idx *MessageIndexUtil) UnsafeUpdate(chatID string, sequenceNum int64) *ddbsdk.UnsafeUpdate {
-	return ddbsdk.NewUnsafeUpdate(idx.Definition().Table, idx.PrimaryKey(chatID, sequenceNum))
+	return ddbsdk.NewUnsafeUpdate(idx.Definition().Table, idx.PrimaryKey(chatID, sequenceNum)).
+		WithDerivedKeys(idx.Definition().DerivedKeys(), map[string]any{"chatID": chatID, "sequenceNum": sequenceNum})
 }
 
 // EnsureExists creates a ConditionCheck that asserts an item with this primary key exists.
@@ -277,6 +633,18 @@ func (idx *EventIndexUtil) Definition() *index.PrimaryIndex[Event] {
 // EventIndex is the typed wrapper for Event operations.
 var EventIndex EventIndexUtil
 
+// EventFields holds typed references to the attributes of Event,
+// for use in filters, conditions and update operations.
+var EventFields = struct {
+	EventID   ddbsdk.Field[string]
+	Timestamp ddbsdk.Field[time.Time]
+	EventType ddbsdk.Field[string]
+}{
+	EventID:   ddbsdk.NewField[string]("eventID"),
+	Timestamp: ddbsdk.NewField[time.Time]("timestamp"),
+	EventType: ddbsdk.NewField[string]("eventType"),
+}
+
 // PrimaryKey creates a primary key from explicit parameters.
 func (idx *EventIndexUtil) PrimaryKey(eventID string, timestamp time.Time) table.PrimaryKey {
 	return table.PrimaryKey{
@@ -299,6 +667,23 @@ func (idx *EventIndexUtil) PrimaryKeyFrom(e *Event) table.PrimaryKey {
 	}
 }
 
+// ParsePrimaryKey decodes the primary key attributes of item into a new Event.
+// Only the fields encoded in the key are set.
+func (idx *EventIndexUtil) ParsePrimaryKey(item ddbsdk.Item) (*Event, error) {
+	k, err := idx.Definition().ParseKeys(item)
+	if err != nil {
+		return nil, err
+	}
+	e := new(Event)
+	if e.EventID, err = val.As[string](k, "eventID"); err != nil {
+		return nil, err
+	}
+	if e.Timestamp, err = val.As[time.Time](k, "timestamp"); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
 // UnsafePut creates a Put operation without optimistic locking.
 func (idx *EventIndexUtil) UnsafePut(e *Event) *ddbsdk.Put {
 	return ddbsdk.NewUnsafePut(idx.Definition().Table, idx.PrimaryKeyFrom(e), e)
@@ -310,8 +695,10 @@ func (idx *EventIndexUtil) Delete(eventID string, timestamp time.Time) *ddbsdk.D
 }
 
 // UnsafeUpdate creates an Update operation without optimistic locking.
+// GSI keys derived from fields set by the update are recomputed, see [ddbsdk.UnsafeUpdate.WithDerivedKeys].
 func (idx *EventIndexUtil) UnsafeUpdate(eventID string, timestamp time.Time) *ddbsdk.UnsafeUpdate {
-	return ddbsdk.NewUnsafeUpdate(idx.Definition().Table, idx.PrimaryKey(eventID, timestamp))
+	return ddbsdk.NewUnsafeUpdate(idx.Definition().Table, idx.PrimaryKey(eventID, timestamp)).
+		WithDerivedKeys(idx.Definition().DerivedKeys(), map[string]any{"eventID": eventID, "timestamp": timestamp})
 }
 
 // EnsureExists creates a ConditionCheck that asserts an item with this primary key exists.
@@ -399,6 +786,14 @@ func (idx *RandomEntityIndexUtil) Definition() *index.PrimaryIndex[RandomEntity]
 // RandomEntityIndex is the typed wrapper for RandomEntity operations.
 var RandomEntityIndex RandomEntityIndexUtil
 
+// RandomEntityFields holds typed references to the attributes of RandomEntity,
+// for use in filters, conditions and update operations.
+var RandomEntityFields = struct {
+	ID ddbsdk.Field[string]
+}{
+	ID: ddbsdk.NewField[string]("id"),
+}
+
 // PrimaryKey creates a primary key from explicit parameters.
 func (idx *RandomEntityIndexUtil) PrimaryKey() table.PrimaryKey {
 	return table.PrimaryKey{
@@ -421,7 +816,7 @@ func (idx *RandomEntityIndexUtil) PrimaryKeyFrom(e *RandomEntity) table.PrimaryK
 	}
 }
 
-// GSIKeysFrom creates all GSI keys from a RandomEntity entity.
+// GSIKeysFrom creates the GSI keys of a RandomEntity entity.
 func (idx *RandomEntityIndexUtil) GSIKeysFrom(e *RandomEntity) []table.PrimaryKey {
 	return []table.PrimaryKey{
 		{
@@ -434,6 +829,29 @@ func (idx *RandomEntityIndexUtil) GSIKeysFrom(e *RandomEntity) []table.PrimaryKe
 	}
 }
 
+// ParsePrimaryKey decodes the primary key attributes of item into a new RandomEntity.
+// Only the fields encoded in the key are set.
+func (idx *RandomEntityIndexUtil) ParsePrimaryKey(item ddbsdk.Item) (*RandomEntity, error) {
+	if _, err := idx.Definition().ParseKeys(item); err != nil {
+		return nil, err
+	}
+	return new(RandomEntity), nil
+}
+
+// ParseGSI1Key decodes the GSI1 GSI key attributes of item into a new RandomEntity,
+// e.g. for items of a query on the GSI. Only the fields encoded in the GSI key are set.
+func (idx *RandomEntityIndexUtil) ParseGSI1Key(item ddbsdk.Item) (*RandomEntity, error) {
+	k, err := idx.Definition().Secondary[0].ParseKeys(item)
+	if err != nil {
+		return nil, err
+	}
+	e := new(RandomEntity)
+	if e.ID, err = val.As[string](k, "id"); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
 // UnsafePut creates a Put operation without optimistic locking.
 func (idx *RandomEntityIndexUtil) UnsafePut(e *RandomEntity) *ddbsdk.Put {
 	return ddbsdk.NewUnsafePut(idx.Definition().Table, idx.PrimaryKeyFrom(e), e).WithGSIKeys(idx.GSIKeysFrom(e)...)
@@ -445,8 +863,10 @@ func (idx *RandomEntityIndexUtil) Delete() *ddbsdk.Delete {
 }
 
 // UnsafeUpdate creates an Update operation without optimistic locking.
+// GSI keys derived from fields set by the update are recomputed, see [ddbsdk.UnsafeUpdate.WithDerivedKeys].
 func (idx *RandomEntityIndexUtil) UnsafeUpdate() *ddbsdk.UnsafeUpdate {
-	return ddbsdk.NewUnsafeUpdate(idx.Definition().Table, idx.PrimaryKey())
+	return ddbsdk.NewUnsafeUpdate(idx.Definition().Table, idx.PrimaryKey()).
+		WithDerivedKeys(idx.Definition().DerivedKeys(), nil)
 }
 
 // EnsureExists creates a ConditionCheck that asserts an item with this primary key exists.
@@ -574,6 +994,20 @@ func (idx *UserIndexUtil) Definition() *index.PrimaryIndex[User] {
 // UserIndex is the typed wrapper for User operations.
 var UserIndex UserIndexUtil
 
+// UserFields holds typed references to the attributes of User,
+// for use in filters, conditions and update operations.
+var UserFields = struct {
+	UserID    ddbsdk.Field[string]
+	Email     ddbsdk.Field[string]
+	Name      ddbsdk.Field[string]
+	UpdatedAt ddbsdk.Field[time.Time]
+}{
+	UserID:    ddbsdk.NewField[string]("id"),
+	Email:     ddbsdk.NewField[string]("email"),
+	Name:      ddbsdk.NewField[string]("name"),
+	UpdatedAt: ddbsdk.NewField[time.Time]("updatedAt"),
+}
+
 // PrimaryKey creates a primary key from explicit parameters.
 func (idx *UserIndexUtil) PrimaryKey(id string) table.PrimaryKey {
 	return table.PrimaryKey{
@@ -596,7 +1030,7 @@ func (idx *UserIndexUtil) PrimaryKeyFrom(e *User) table.PrimaryKey {
 	}
 }
 
-// GSIKeysFrom creates all GSI keys from a User entity.
+// GSIKeysFrom creates the GSI keys of a User entity.
 func (idx *UserIndexUtil) GSIKeysFrom(e *User) []table.PrimaryKey {
 	return []table.PrimaryKey{
 		{
@@ -616,6 +1050,54 @@ func (idx *UserIndexUtil) GSIKeysFrom(e *User) []table.PrimaryKey {
 	}
 }
 
+// ParsePrimaryKey decodes the primary key attributes of item into a new User.
+// Only the fields encoded in the key are set.
+func (idx *UserIndexUtil) ParsePrimaryKey(item ddbsdk.Item) (*User, error) {
+	k, err := idx.Definition().ParseKeys(item)
+	if err != nil {
+		return nil, err
+	}
+	e := new(User)
+	if e.UserID, err = val.As[string](k, "id"); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// ParseGSI1Key decodes the GSI1 GSI key attributes of item into a new User,
+// e.g. for items of a query on the GSI. Only the fields encoded in the GSI key are set.
+func (idx *UserIndexUtil) ParseGSI1Key(item ddbsdk.Item) (*User, error) {
+	k, err := idx.Definition().Secondary[0].ParseKeys(item)
+	if err != nil {
+		return nil, err
+	}
+	e := new(User)
+	if e.Email, err = val.As[string](k, "email"); err != nil {
+		return nil, err
+	}
+	if e.UserID, err = val.As[string](k, "id"); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// ParseGSI2Key decodes the GSI2 GSI key attributes of item into a new User,
+// e.g. for items of a query on the GSI. Only the fields encoded in the GSI key are set.
+func (idx *UserIndexUtil) ParseGSI2Key(item ddbsdk.Item) (*User, error) {
+	k, err := idx.Definition().Secondary[1].ParseKeys(item)
+	if err != nil {
+		return nil, err
+	}
+	e := new(User)
+	if e.Name, err = val.As[string](k, "name"); err != nil {
+		return nil, err
+	}
+	if e.UserID, err = val.As[string](k, "id"); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
 // UnsafePut creates a Put operation without optimistic locking.
 func (idx *UserIndexUtil) UnsafePut(e *User) *ddbsdk.Put {
 	return ddbsdk.NewUnsafePut(idx.Definition().Table, idx.PrimaryKeyFrom(e), e).WithGSIKeys(idx.GSIKeysFrom(e)...)
@@ -634,8 +1116,10 @@ func (idx *UserIndexUtil) Delete(id string) *ddbsdk.Delete {
 }
 
 // UnsafeUpdate creates an Update operation without optimistic locking.
+// GSI keys derived from fields set by the update are recomputed, see [ddbsdk.UnsafeUpdate.WithDerivedKeys].
 func (idx *UserIndexUtil) UnsafeUpdate(id string) *ddbsdk.UnsafeUpdate {
-	return ddbsdk.NewUnsafeUpdate(idx.Definition().Table, idx.PrimaryKey(id))
+	return ddbsdk.NewUnsafeUpdate(idx.Definition().Table, idx.PrimaryKey(id)).
+		WithDerivedKeys(idx.Definition().DerivedKeys(), map[string]any{"id": id})
 }
 
 // EnsureExists creates a ConditionCheck that asserts an item with this primary key exists.
@@ -818,3 +1302,143 @@ func (q UserGSI2Query) IdLessThan(id string) ddbsdk.QueryDef {
 func (q UserGSI2Query) IdLessThanOrEqual(id string) ddbsdk.QueryDef {
 	return q.qd.WithSKCondition(ddbsdk.LessThanOrEqual("USER#" + id))
 }
+
+// =============================================================================
+// orders Table Decoder
+// =============================================================================
+
+// DecodeOrdersItem decodes an item of table "orders" into its entity type,
+// one of *Order, *Customer.
+// The type is read from the "type" attribute, or matched by key pattern if it's missing.
+func DecodeOrdersItem(item ddbsdk.Item) (any, error) {
+	switch et := ddbsdk.EntityTypeOf(OrderIndex.Definition().Table, item); et {
+	case "Order":
+		e, err := ddbsdk.DecodeEntity[Order](item)
+		if err != nil {
+			return nil, err
+		}
+		return e, nil
+	case "Customer":
+		e, err := ddbsdk.DecodeEntity[Customer](item)
+		if err != nil {
+			return nil, err
+		}
+		return e, nil
+	default:
+		return nil, fmt.Errorf("table orders: unknown entity type %q", et)
+	}
+}
+
+// DecodeOrdersItems decodes a page of items of table "orders", e.g. a query result
+// with several entity types in one partition. Use a type switch on the results.
+func DecodeOrdersItems(items []ddbsdk.Item) ([]any, error) {
+	out := make([]any, 0, len(items))
+	for _, item := range items {
+		e, err := DecodeOrdersItem(item)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	return out, nil
+}
+
+// =============================================================================
+// CustomerCollection Item Collection
+// =============================================================================
+
+// CustomerCollection holds the items of one "TENANT#{tenantID}" partition of table "orders".
+type CustomerCollection struct {
+	Customer *Customer
+	Orders   []Order
+}
+
+// CustomerCollectionQuery fetches a CustomerCollection with one query, see QueryCustomerCollection.
+type CustomerCollectionQuery struct {
+	qd ddbsdk.QueryDef
+	// customerLow and customerHigh bound the sort keys of the Customer items.
+	customerLow, customerHigh string
+	// ordersLow and ordersHigh bound the sort keys of the Order items.
+	ordersLow, ordersHigh string
+}
+
+// QueryCustomerCollection creates a query for all Customer, Order items of a partition.
+// The sort key range spans each member's sort keys, so other items in the partition aren't read.
+// Narrow a member's range with its Between and BeginsWith methods.
+// Run it with Exec, or decode the result pages with [CustomerCollection.Add].
+func QueryCustomerCollection(tenantID string) CustomerCollectionQuery {
+	return CustomerCollectionQuery{
+		qd:           ddbsdk.QueryPartition(OrderIndex.Definition().Table, "TENANT#"+tenantID),
+		customerLow:  "CUSTOMER",
+		customerHigh: "CUSTOMER",
+		ordersLow:    "ORDER#",
+		ordersHigh:   "ORDER#\U0010ffff",
+	}
+}
+
+// OrdersBetween limits the Order items to sort keys between start and end, inclusive.
+func (q CustomerCollectionQuery) OrdersBetween(orderIDStart string, orderIDEnd string) CustomerCollectionQuery {
+	q.ordersLow, q.ordersHigh = "ORDER#"+orderIDStart, "ORDER#"+orderIDEnd
+	return q
+}
+
+// OrdersBeginsWith limits the Order items to sort keys beginning with the prefix.
+func (q CustomerCollectionQuery) OrdersBeginsWith(prefix string) CustomerCollectionQuery {
+	q.ordersLow = "ORDER#" + prefix
+	q.ordersHigh = q.ordersLow + "\U0010ffff"
+	return q
+}
+
+// Build returns the underlying QueryDef, implementing ddbsdk.QueryBuilder.
+// The sort key condition spans the ranges of all members.
+func (q CustomerCollectionQuery) Build() ddbsdk.QueryDef {
+	low := min(q.customerLow, q.ordersLow)
+	high := max(q.customerHigh, q.ordersHigh)
+	return q.qd.WithSKCondition(ddbsdk.Between(low, high))
+}
+
+// Exec runs the query, reading all pages, and returns the decoded collection.
+// Items outside their member's sort key range are skipped.
+func (q CustomerCollectionQuery) Exec(ctx context.Context, db ddbsdk.Reader) (*CustomerCollection, error) {
+	res, err := db.NewQuery(q).QueryAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	items := res.Items
+	t := OrderIndex.Definition().Table
+	items = slices.DeleteFunc(items, func(item ddbsdk.Item) bool {
+		switch ddbsdk.EntityTypeOf(t, item) {
+		case "Order":
+			return !ddbsdk.SortKeyInRange(t, item, q.ordersLow, q.ordersHigh)
+		}
+		return false
+	})
+	c := new(CustomerCollection)
+	if err := c.Add(items); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Add decodes a page of query results into the collection.
+// Items of other entity types in the partition are skipped.
+func (c *CustomerCollection) Add(items []ddbsdk.Item) error {
+	t := OrderIndex.Definition().Table
+	for _, item := range items {
+		switch ddbsdk.EntityTypeOf(t, item) {
+		case "Customer":
+			e, err := ddbsdk.DecodeEntity[Customer](item)
+			if err != nil {
+				return err
+			}
+			c.Customer = e
+		case "Order":
+			e, err := ddbsdk.DecodeEntity[Order](item)
+			if err != nil {
+				return err
+			}
+			c.Orders = append(c.Orders, *e)
+		}
+	}
+	return nil
+}
@@ -60,18 +60,45 @@ func init() {
 	})
 }
 
+// OrderTable stores each tenant's customer profile and orders in one partition.
+// The "type" attribute tells them apart, see DecodeOrdersItems.
 var OrderTable = table.TableDefinition{
 	Name: "orders",
 	KeyDefinitions: table.PrimaryKeyDefinition{
 		PartitionKey: table.KeyDef{Name: "pk", Kind: table.KeyKindS},
 		SortKey:      table.KeyDef{Name: "sk", Kind: table.KeyKindS},
 	},
+	EntityTypeKey: "type",
+	GSIs: []table.GSIDefinition{
+		{
+			Name: "ByStatus",
+			KeyDefinitions: table.PrimaryKeyDefinition{
+				PartitionKey: table.KeyDef{Name: "gsi1pk", Kind: table.KeyKindS},
+				SortKey:      table.KeyDef{Name: "gsi1sk", Kind: table.KeyKindS},
+			},
+		},
+	},
 }
 
+// ByStatus is a sparse GSI, only pending orders are written to it.
 var _ = indices.Add(index.PrimaryIndex[Order]{
 	Table:        OrderTable,
 	PartitionKey: val.Fmt("TENANT#{tenantID}"),
 	SortKey:      val.Fmt("ORDER#{orderID}").Ptr(),
+	Secondary: []index.SecondaryIndex{
+		{
+			GSI:       OrderTable.GSIs[0],
+			Partition: val.Fmt("TENANT#{tenantID}#STATUS#{status}"),
+			Sort:      val.Fmt("ORDER#{orderID}").Ptr(),
+			Include:   index.WhenEquals("status", "pending"),
+		},
+	},
+})
+
+var _ = indices.Add(index.PrimaryIndex[Customer]{
+	Table:        OrderTable,
+	PartitionKey: val.Fmt("TENANT#{tenantID}"),
+	SortKey:      val.Fmt("CUSTOMER").Ptr(),
 })
 
 // MessageTable demonstrates using int64 fields in keys
@@ -81,6 +108,8 @@ var MessageTable = table.TableDefinition{
 		PartitionKey: table.KeyDef{Name: "pk", Kind: table.KeyKindS},
 		SortKey:      table.KeyDef{Name: "sk", Kind: table.KeyKindS},
 	},
+	// Messages written WithTTL expire.
+	TimeToLiveKey: "expiresAt",
 }
 
 // _ = indices.Add demonstrates int64 in sort key with warning
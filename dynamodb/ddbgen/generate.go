@@ -7,10 +7,13 @@ import (
 	"reflect"
 	"strings"
 
+	"github.com/acksell/bezos/dynamodb/dbt"
 	"github.com/acksell/bezos/dynamodb/ddbsdk"
+	"github.com/acksell/bezos/dynamodb/ddbsdk/eventsource"
 	"github.com/acksell/bezos/dynamodb/index"
 	"github.com/acksell/bezos/dynamodb/index/indices"
 	"github.com/acksell/bezos/dynamodb/index/val"
+	"github.com/acksell/bezos/dynamodb/infra"
 	"github.com/acksell/bezos/dynamodb/table"
 )
 
@@ -24,9 +27,25 @@ type GenerateOptions struct {
 	PackageName string
 	// NoSchema disables schema/ subdirectory generation.
 	NoSchema bool
+	// Check compares the generated schema with the committed schema/schema_dynamodb.yaml
+	// instead of writing any files, and fails on unacknowledged breaking changes.
+	// Also enabled by the DDBGEN_CHECK environment variable, which ddb gen --check sets.
+	Check bool
+	// CheckRef compares against the schema at this git ref instead of the one on disk.
+	// Defaults to the DDBGEN_CHECK_REF environment variable.
+	CheckRef string
+	// Infra lists the formats to write table definitions in, to schema/infra/.
+	// Defaults to the comma-separated DDBGEN_INFRA environment variable, which
+	// ddb gen --infra sets.
+	Infra []infra.Format
+	// DBT is the SQL dialect to write dbt sources and staging models in, to schema/dbt/.
+	// Defaults to the DDBGEN_DBT environment variable, which ddb gen --dbt sets.
+	// Nothing is written if it's empty.
+	DBT dbt.Dialect
 }
 
-// Generate produces generated code from all registered PrimaryIndex definitions.
+// Generate produces generated code from all registered PrimaryIndex definitions
+// and event streams.
 // Call this from a gen/main.go after importing the package that registers indexes.
 //
 // Example:
@@ -54,9 +73,32 @@ func Generate(opts GenerateOptions) error {
 	if opts.PackageName == "" {
 		opts.PackageName = "main"
 	}
+	if os.Getenv("DDBGEN_CHECK") != "" {
+		opts.Check = true
+	}
+	if opts.CheckRef == "" {
+		opts.CheckRef = os.Getenv("DDBGEN_CHECK_REF")
+	}
+	if len(opts.Infra) == 0 {
+		formats, err := parseInfraFormats(os.Getenv("DDBGEN_INFRA"))
+		if err != nil {
+			return err
+		}
+		opts.Infra = formats
+	}
+	if opts.DBT == "" {
+		if name := os.Getenv("DDBGEN_DBT"); name != "" {
+			d, err := dbt.ParseDialect(name)
+			if err != nil {
+				return err
+			}
+			opts.DBT = d
+		}
+	}
 
 	entries := indices.All()
-	if len(entries) == 0 {
+	streams := eventsource.Streams()
+	if len(entries) == 0 && len(streams) == 0 {
 		fmt.Fprintf(os.Stderr, "ddbgen: no registered indexes found\n")
 		fmt.Fprintf(os.Stderr, "\n")
 		fmt.Fprintf(os.Stderr, "Register indexes in your package using indices.Add:\n")
@@ -74,24 +116,63 @@ func Generate(opts GenerateOptions) error {
 		}
 		indexInfos = append(indexInfos, info)
 	}
+	streamInfos := make([]streamInfo, 0, len(streams))
+	for _, s := range streams {
+		info, err := streamToInfo(s)
+		if err != nil {
+			return fmt.Errorf("processing stream %s: %w", s.Aggregate, err)
+		}
+		streamInfos = append(streamInfos, info)
+	}
+
+	if err := validateTables(indexInfos); err != nil {
+		return err
+	}
+	if err := validateStreams(streamInfos, indexInfos); err != nil {
+		return err
+	}
 
 	// Generate code.
-	code, err := generateCode(opts.PackageName, indexInfos)
-	if err != nil {
-		return fmt.Errorf("generating code: %w", err)
+	var code, eventsCode []byte
+	var err error
+	if len(indexInfos) > 0 {
+		code, err = generateCode(opts.PackageName, indexInfos)
+		if err != nil {
+			return fmt.Errorf("generating code: %w", err)
+		}
+	}
+	if len(streamInfos) > 0 {
+		eventsCode, err = generateEventsCode(opts.PackageName, streamInfos)
+		if err != nil {
+			return fmt.Errorf("generating event stream code: %w", err)
+		}
 	}
 
-	// Write output.
-	outputPath := filepath.Join(opts.Dir, opts.Output)
-	absOutput, err := filepath.Abs(outputPath)
-	if err != nil {
-		return fmt.Errorf("resolving output path: %w", err)
+	if opts.Check {
+		return checkSchema(opts.Dir, indexInfos, streamInfos, opts.CheckRef)
 	}
 
-	if err := os.WriteFile(absOutput, code, 0644); err != nil {
-		return fmt.Errorf("writing output: %w", err)
+	// Write output.
+	if code != nil {
+		absOutput, err := filepath.Abs(filepath.Join(opts.Dir, opts.Output))
+		if err != nil {
+			return fmt.Errorf("resolving output path: %w", err)
+		}
+		if err := os.WriteFile(absOutput, code, 0644); err != nil {
+			return fmt.Errorf("writing output: %w", err)
+		}
+		fmt.Printf("ddbgen: generated %s (%d indexes)\n", absOutput, len(indexInfos))
+	}
+	if eventsCode != nil {
+		absOutput, err := filepath.Abs(filepath.Join(opts.Dir, eventsOutput))
+		if err != nil {
+			return fmt.Errorf("resolving output path: %w", err)
+		}
+		if err := os.WriteFile(absOutput, eventsCode, 0644); err != nil {
+			return fmt.Errorf("writing output: %w", err)
+		}
+		fmt.Printf("ddbgen: generated %s (%d event streams)\n", absOutput, len(streamInfos))
 	}
-	fmt.Printf("ddbgen: generated %s (%d indexes)\n", absOutput, len(indexInfos))
 
 	// Generate schema files.
 	if !opts.NoSchema {
@@ -103,9 +184,19 @@ func Generate(opts GenerateOptions) error {
 		if err := os.MkdirAll(schemaDir, 0755); err != nil {
 			return fmt.Errorf("creating schema directory: %w", err)
 		}
-		if err := generateSchemaFiles(schemaDir, indexInfos); err != nil {
+		if err := generateSchemaFiles(schemaDir, indexInfos, streamInfos); err != nil {
 			return fmt.Errorf("generating schema: %w", err)
 		}
+		if len(opts.Infra) > 0 {
+			if err := generateInfraFiles(filepath.Join(schemaDir, InfraDirName), indexInfos, streamInfos, opts.Infra); err != nil {
+				return fmt.Errorf("generating infra: %w", err)
+			}
+		}
+		if opts.DBT != "" {
+			if err := generateDBTFiles(filepath.Join(schemaDir, DBTDirName), indexInfos, streamInfos, opts.DBT); err != nil {
+				return fmt.Errorf("generating dbt models: %w", err)
+			}
+		}
 	}
 
 	return nil
@@ -160,28 +251,20 @@ func entryToindexInfo(entry indices.Entry) (indexInfo, error) {
 				PKPattern: sec.Partition,
 				SKDef:     sec.GSI.KeyDefinitions.SortKey.Name,
 				SKPattern: sec.Sort,
+				Include:   sec.Include,
 			})
 		}
 	}
 
-	// Extract entity struct fields with dynamodbav tags.
-	var fields []fieldInfo
-	if entityType.Kind() == reflect.Struct {
-		for i := 0; i < entityType.NumField(); i++ {
-			f := entityType.Field(i)
-			tag := f.Tag.Get("dynamodbav")
-			if tag == "" || tag == "-" {
-				continue
-			}
-			if commaIdx := strings.IndexByte(tag, ','); commaIdx != -1 {
-				tag = tag[:commaIdx]
-			}
-			fields = append(fields, fieldInfo{
-				Name: f.Name,
-				Tag:  tag,
-				Type: reflectTypeString(f.Type),
-			})
-		}
+	fields, err := structFields(entityType)
+	if err != nil {
+		return indexInfo{}, err
+	}
+	if err := resolveValidation(entityType, fields); err != nil {
+		return indexInfo{}, err
+	}
+	if err := checkInclusions(gsis, fields); err != nil {
+		return indexInfo{}, err
 	}
 
 	// Check if entity implements VersionedDynamoEntity.
@@ -197,14 +280,99 @@ func entryToindexInfo(entry indices.Entry) (indexInfo, error) {
 		TableName:    tbl.Name,
 		PKDefName:    tbl.KeyDefinitions.PartitionKey.Name,
 		SKDefName:    tbl.KeyDefinitions.SortKey.Name,
+		Table:        tbl,
 		PartitionKey: pk,
 		SortKey:      sk,
 		GSIs:         gsis,
 		IsVersioned:  isVersioned,
 		Fields:       fields,
+
+		EntityTypeName: entry.EntityTypeName(),
+		EntityTypeKey:  tbl.EntityTypeKey,
+		TimeToLiveKey:  tbl.TimeToLiveKey,
 	}, nil
 }
 
+// structFields returns the fields of struct type t that have a dynamodbav tag.
+func structFields(t reflect.Type) ([]fieldInfo, error) {
+	if t.Kind() != reflect.Struct {
+		return nil, nil
+	}
+	var fields []fieldInfo
+	seenTags := make(map[string]string)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("dynamodbav")
+		if tag == "" || tag == "-" {
+			continue
+		}
+		if commaIdx := strings.IndexByte(tag, ','); commaIdx != -1 {
+			tag = tag[:commaIdx]
+		}
+		if other, dup := seenTags[tag]; dup {
+			return nil, fmt.Errorf("fields %s and %s have the same dynamodbav tag %q", other, f.Name, tag)
+		}
+		seenTags[tag] = f.Name
+		rules, err := parseValidateTag(f.Tag.Get("validate"))
+		if err != nil {
+			return nil, fmt.Errorf("field %s: validate tag: %w", f.Name, err)
+		}
+		goType, ok := goTypeExpr(f.Type, t.PkgPath())
+		if !ok {
+			// Partial expressions like "*" or "map[string]" for types of other packages.
+			goType = ""
+		}
+		fields = append(fields, fieldInfo{
+			Name:   f.Name,
+			Tag:    tag,
+			Type:   reflectTypeString(f.Type),
+			GoType: goType,
+			Rules:  rules,
+		})
+	}
+	return fields, nil
+}
+
+// validateTables checks that entities sharing a table can be told apart: their primary
+// key patterns must not overlap, since their items could otherwise overwrite each other,
+// and their entity type names must be unique.
+func validateTables(infos []indexInfo) error {
+	for i, a := range infos {
+		for _, b := range infos[i+1:] {
+			if a.TableName != b.TableName {
+				continue
+			}
+			if a.EntityTypeName == b.EntityTypeName {
+				return fmt.Errorf("table %q: entities %s and %s have the same entity type %q", a.TableName, a.EntityType, b.EntityType, a.EntityTypeName)
+			}
+			if keysOverlap(a, b) {
+				return fmt.Errorf("table %q: key patterns of %s (%s) and %s (%s) overlap, use distinct literal prefixes",
+					a.TableName, a.EntityType, keyPatternString(a), b.EntityType, keyPatternString(b))
+			}
+		}
+	}
+	return nil
+}
+
+// keysOverlap reports whether a primary key could match the key patterns of both entities.
+func keysOverlap(a, b indexInfo) bool {
+	if !a.PartitionKey.Overlaps(b.PartitionKey) {
+		return false
+	}
+	if a.SortKey == nil || b.SortKey == nil {
+		return true
+	}
+	return a.SortKey.Overlaps(*b.SortKey)
+}
+
+func keyPatternString(info indexInfo) string {
+	s := valDefPattern(info.PartitionKey)
+	if info.SortKey != nil {
+		s += " / " + valDefPattern(*info.SortKey)
+	}
+	return s
+}
+
 // reflectTypeString returns a string representation of a reflect.Type.
 func reflectTypeString(t reflect.Type) string {
 	switch t.Kind() {
@@ -224,3 +392,41 @@ func reflectTypeString(t reflect.Type) string {
 		return t.String()
 	}
 }
+
+// goTypeExpr returns the type expression for t as written in package pkgPath.
+// Reports false for types that generated code can't name, such as types from
+// other packages (except time.Time) and anonymous structs.
+func goTypeExpr(t reflect.Type, pkgPath string) (string, bool) {
+	switch t.Kind() {
+	case reflect.Ptr:
+		elem, ok := goTypeExpr(t.Elem(), pkgPath)
+		return "*" + elem, ok
+	case reflect.Slice:
+		elem, ok := goTypeExpr(t.Elem(), pkgPath)
+		return "[]" + elem, ok
+	case reflect.Array:
+		elem, ok := goTypeExpr(t.Elem(), pkgPath)
+		return fmt.Sprintf("[%d]%s", t.Len(), elem), ok
+	case reflect.Map:
+		key, okKey := goTypeExpr(t.Key(), pkgPath)
+		elem, okElem := goTypeExpr(t.Elem(), pkgPath)
+		return "map[" + key + "]" + elem, okKey && okElem
+	}
+	switch {
+	case t.Name() == "":
+		if t.Kind() == reflect.Interface && t.NumMethod() == 0 {
+			return "any", true
+		}
+		return "", false
+	case strings.Contains(t.Name(), "["):
+		// instantiated generic type
+		return "", false
+	case t.PkgPath() == "":
+		return t.Name(), true
+	case t.PkgPath() == "time" && t.Name() == "Time":
+		return "time.Time", true
+	case t.PkgPath() == pkgPath:
+		return t.Name(), true
+	}
+	return "", false
+}
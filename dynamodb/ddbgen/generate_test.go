@@ -0,0 +1,152 @@
+package ddbgen
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/acksell/bezos/dynamodb/ddbgen/testdata/fieldtypes"
+	"github.com/acksell/bezos/dynamodb/index"
+	"github.com/acksell/bezos/dynamodb/index/indices"
+	"github.com/acksell/bezos/dynamodb/index/val"
+	"github.com/acksell/bezos/dynamodb/table"
+	"golang.org/x/tools/go/packages"
+)
+
+var linkTable = table.TableDefinition{
+	Name: "links",
+	KeyDefinitions: table.PrimaryKeyDefinition{
+		PartitionKey: table.KeyDef{Name: "pk", Kind: table.KeyKindS},
+		SortKey:      table.KeyDef{Name: "sk", Kind: table.KeyKindS},
+	},
+	GSIs: []table.GSIDefinition{
+		{
+			Name: "GSI1",
+			KeyDefinitions: table.PrimaryKeyDefinition{
+				PartitionKey: table.KeyDef{Name: "gsi1pk", Kind: table.KeyKindS},
+				SortKey:      table.KeyDef{Name: "gsi1sk", Kind: table.KeyKindS},
+			},
+		},
+	},
+}
+
+// generateFor generates the index code for the given indexes.
+func generateFor(t *testing.T, entries ...indices.Entry) string {
+	t.Helper()
+	var infos []indexInfo
+	for _, entry := range entries {
+		info, err := entryToindexInfo(entry)
+		if err != nil {
+			t.Fatalf("entryToindexInfo(%s): %v", entry.EntityType.Name(), err)
+		}
+		infos = append(infos, info)
+	}
+	src, err := generateCode("fieldtypes", infos)
+	if err != nil {
+		t.Fatalf("generateCode: %v", err)
+	}
+	return string(src)
+}
+
+// typeCheck compiles the generated code as part of the testdata/fieldtypes package.
+func typeCheck(t *testing.T, src string) {
+	t.Helper()
+	dir, err := filepath.Abs(filepath.Join("testdata", "fieldtypes"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkgs, err := packages.Load(&packages.Config{
+		Mode:    packages.NeedName | packages.NeedTypes | packages.NeedSyntax,
+		Dir:     dir,
+		Overlay: map[string][]byte{filepath.Join(dir, "index_gen.go"): []byte(src)},
+	}, ".")
+	if err != nil {
+		t.Fatalf("loading package: %v", err)
+	}
+	for _, pkg := range pkgs {
+		for _, err := range pkg.Errors {
+			t.Errorf("generated code: %v", err)
+		}
+	}
+	if t.Failed() {
+		t.Logf("generated code:\n%s", src)
+	}
+}
+
+func TestGenerate_UnnameableFieldTypes(t *testing.T) {
+	src := generateFor(t, indices.Entry{
+		EntityType: reflect.TypeFor[fieldtypes.Link](),
+		Index: index.PrimaryIndex[fieldtypes.Link]{
+			Table:        linkTable,
+			PartitionKey: val.Fmt("LINK#{id}"),
+			SortKey:      val.Fmt("LINK").Ptr(),
+		},
+	})
+
+	for _, want := range []string{
+		"Target ddbsdk.Field[any]",
+		"Mirrors ddbsdk.Field[any]",
+		"Meta ddbsdk.Field[any]",
+		"Tags ddbsdk.Field[[]string]",
+	} {
+		if !strings.Contains(strings.Join(strings.Fields(src), " "), want) {
+			t.Errorf("generated code lacks %q", want)
+		}
+	}
+	typeCheck(t, src)
+}
+
+func TestGenerate_UnnameableKeyFieldTypes(t *testing.T) {
+	src := generateFor(t, indices.Entry{
+		EntityType: reflect.TypeFor[fieldtypes.Hit](),
+		Index: index.PrimaryIndex[fieldtypes.Hit]{
+			Table:        linkTable,
+			PartitionKey: val.Fmt("HOST#{host}"),
+			SortKey:      val.Fmt("PATH#{path}").Ptr(),
+			Secondary: []index.SecondaryIndex{{
+				GSI:       linkTable.GSIs[0],
+				Partition: val.Fmt("PEER#{peer}"),
+				Sort:      val.Fmt("PATH#{path}").Ptr(),
+			}},
+		},
+	})
+
+	if !strings.Contains(src, "PrimaryKey(host any, path string)") {
+		t.Error("expected key parameters of unnameable types to be any")
+	}
+	if strings.Contains(src, "val.As[*") || strings.Contains(src, "val.As[netip") {
+		t.Error("expected no typed parsing of unnameable key fields")
+	}
+	if !strings.Contains(src, `val.As[string](k, "path")`) {
+		t.Error("expected typed parsing of nameable key fields")
+	}
+	typeCheck(t, src)
+}
+
+func TestGenerate_InclusionOutsideEnum(t *testing.T) {
+	ticketIndex := func(status string) indices.Entry {
+		return indices.Entry{
+			EntityType: reflect.TypeFor[fieldtypes.Ticket](),
+			Index: index.PrimaryIndex[fieldtypes.Ticket]{
+				Table:        linkTable,
+				PartitionKey: val.Fmt("TICKET#{id}"),
+				SortKey:      val.Fmt("TICKET").Ptr(),
+				Secondary: []index.SecondaryIndex{{
+					GSI:       linkTable.GSIs[0],
+					Partition: val.Fmt("STATUS#{status}"),
+					Sort:      val.Fmt("TICKET#{id}").Ptr(),
+					Include:   index.WhenEquals("status", status),
+				}},
+			},
+		}
+	}
+
+	if _, err := entryToindexInfo(ticketIndex("new")); err != nil {
+		t.Errorf("expected an enum value to be accepted, got %v", err)
+	}
+	_, err := entryToindexInfo(ticketIndex("open"))
+	if err == nil || !strings.Contains(err.Error(), "field Status only allows new, closed") {
+		t.Errorf("expected an error for a value outside the enum, got %v", err)
+	}
+}
@@ -0,0 +1,63 @@
+package ddbgen
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/acksell/bezos/dynamodb/infra"
+)
+
+// InfraDirName is the directory in schema/ that ddbgen writes infrastructure
+// definitions to, see [GenerateOptions.Infra].
+const InfraDirName = "infra"
+
+// parseInfraFormats parses a comma-separated list of infra formats, e.g. "terraform,cdk".
+func parseInfraFormats(s string) ([]infra.Format, error) {
+	var formats []infra.Format
+	for _, name := range strings.Split(s, ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		f, err := infra.ParseFormat(name)
+		if err != nil {
+			return nil, err
+		}
+		formats = append(formats, f)
+	}
+	return formats, nil
+}
+
+// generateInfraFiles writes the definition of every table in each format to dir.
+func generateInfraFiles(dir string, indexes []indexInfo, streams []streamInfo, formats []infra.Format) error {
+	s, err := generatedSchema(indexes, streams)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("creating infra directory: %w", err)
+	}
+	for _, t := range s.Tables {
+		for _, f := range formats {
+			out, err := infra.Generate(t, f)
+			if err != nil {
+				return fmt.Errorf("table %s: %w", t.Name, err)
+			}
+			path := filepath.Join(dir, infra.FileName(t, f))
+			if err := os.WriteFile(path, out, 0644); err != nil {
+				return fmt.Errorf("writing %s: %w", path, err)
+			}
+		}
+	}
+	fmt.Printf("ddb gen: generated %s definitions of %d tables in %s\n", formatNames(formats), len(s.Tables), dir)
+	return nil
+}
+
+func formatNames(formats []infra.Format) string {
+	names := make([]string, len(formats))
+	for i, f := range formats {
+		names[i] = string(f)
+	}
+	return strings.Join(names, ", ")
+}
@@ -1,6 +1,10 @@
 package ddbgen
 
-import "github.com/acksell/bezos/dynamodb/index/val"
+import (
+	"github.com/acksell/bezos/dynamodb/index"
+	"github.com/acksell/bezos/dynamodb/index/val"
+	"github.com/acksell/bezos/dynamodb/table"
+)
 
 // =============================================================================
 // Index info types (used by code generation)
@@ -9,16 +13,23 @@ import "github.com/acksell/bezos/dynamodb/index/val"
 // indexInfo holds the data extracted from a PrimaryIndex variable.
 // Used by code generation to build type-safe accessor functions.
 type indexInfo struct {
-	VarName      string
-	EntityType   string
-	TableName    string
-	PKDefName    string
-	SKDefName    string
+	VarName    string
+	EntityType string
+	TableName  string
+	PKDefName  string
+	SKDefName  string
+	// Table is the definition of the table the entity is stored in.
+	Table        table.TableDefinition
 	PartitionKey val.ValDef
 	SortKey      *val.ValDef
 	GSIs         []gsiInfo
 	IsVersioned  bool
 	Fields       []fieldInfo
+	// EntityTypeName is the value of the table's discriminator attribute for this entity.
+	EntityTypeName string
+	// EntityTypeKey is the table's discriminator attribute, empty if it has none.
+	EntityTypeKey string
+	TimeToLiveKey string
 }
 
 // gsiInfo holds GSI data extracted from a SecondaryIndex.
@@ -29,6 +40,7 @@ type gsiInfo struct {
 	PKPattern val.ValDef
 	SKDef     string
 	SKPattern *val.ValDef
+	Include   *index.Inclusion
 }
 
 // fieldInfo holds metadata about an entity struct field.
@@ -36,6 +48,35 @@ type fieldInfo struct {
 	Name string
 	Tag  string
 	Type string
+	// GoType is the field type as written in the entity's package,
+	// or empty if it can't be referenced from generated code.
+	GoType string
+	// Rules are the field's validate tag rules.
+	Rules []validationRule
+
+	kind        valueKind
+	pointer     bool
+	namedString bool
+}
+
+// streamInfo holds the data extracted from a registered eventsource.Stream.
+type streamInfo struct {
+	// Name is the Go identifier of the aggregate, which prefixes the generated names.
+	Name      string
+	Aggregate string
+	Table     table.TableDefinition
+	Events    []eventInfo
+	// PartitionKeyPattern is the val.Fmt pattern of the stream's partition keys.
+	PartitionKeyPattern string
+}
+
+// eventInfo holds metadata about an event type of a stream.
+type eventInfo struct {
+	// Type is the stored event type name.
+	Type string
+	// GoType is the name of the event struct.
+	GoType string
+	Fields []fieldInfo
 }
 
 // =============================================================================
@@ -52,6 +93,14 @@ type indexData struct {
 	HasSortKey   bool
 	GSIs         []gsiData
 	IsVersioned  bool
+	Fields       []fieldRefData
+	// KeyFields are the fields encoded in the primary key.
+	KeyFields []keyFieldData
+	// Validation is the body of the entity's IsValidGenerated method, empty if
+	// none of its fields have validate tags.
+	Validation string
+	Patterns   []patternData
+	UsesUTF8   bool
 }
 
 // HasEntity returns true if an entity type is associated with this index.
@@ -65,6 +114,7 @@ type keyData struct {
 	IsConstant       bool
 	LiteralPrefix    string
 	FieldRefNames    []string
+	Fields           []keyFieldData
 	UsesFmt          bool
 	UsesStrconv      bool
 	UsesTime         bool
@@ -73,12 +123,80 @@ type keyData struct {
 // paramData is one parameter in a key function signature.
 type paramData struct {
 	Name       string
+	Path       string // field path in the key pattern
 	Type       string
 	FieldType  string
 	Formats    []string
 	PrintfSpec string
 }
 
+// keyFieldData is an entity field encoded in a key, for parsing it back out.
+type keyFieldData struct {
+	FieldName string
+	Path      string
+	GoType    string
+}
+
+// parseKeysData is the input of the parseKeyFields template.
+type parseKeysData struct {
+	Entity string
+	Parse  string // expression returning (val.ParsedKey, error)
+	Fields []keyFieldData
+}
+
+// fieldRefData is one typed attribute reference of an entity.
+type fieldRefData struct {
+	Name   string
+	Tag    string
+	GoType string
+}
+
+// tableData is the template-ready data for the decoder of a table with several entity types.
+type tableData struct {
+	Name          string
+	Ident         string
+	EntityTypeKey string
+	// IndexVarName is the index wrapper of one of the table's entities, for its table definition.
+	IndexVarName string
+	Entities     []tableEntityData
+}
+
+// tableEntityData is one entity type stored in a table.
+type tableEntityData struct {
+	EntityType string
+	TypeName   string
+}
+
+// collectionData is the template-ready data for an item collection: entities of one
+// table that share a partition key pattern, so one query can fetch them together.
+type collectionData struct {
+	Name             string
+	TableName        string
+	PartitionPattern string
+	PartitionKey     keyData
+	// IndexVarName is the index wrapper of the first member, for the table definition.
+	IndexVarName string
+	Members      []collectionMemberData
+	// HasRange is true if every member has a string sort key with a literal prefix,
+	// so the query can be limited to the members' sort key ranges.
+	HasRange bool
+}
+
+// collectionMemberData is one entity type of an item collection.
+type collectionMemberData struct {
+	Field      string
+	EntityType string
+	TypeName   string
+	// Single is true if the member has a constant sort key, i.e. one item per partition.
+	Single bool
+	// Var is the prefix of the query's fields holding the member's sort key range.
+	Var string
+	// SortKey is the member's sort key, and SKLow and SKHigh its default range.
+	SortKey keyData
+	SKLow   string
+	SKHigh  string
+}
+
 // gsiData is the template-ready GSI data.
 type gsiData struct {
 	Name         string
@@ -86,4 +204,7 @@ type gsiData struct {
 	PartitionKey keyData
 	SortKey      *keyData
 	HasSortKey   bool
+	KeyFields    []keyFieldData
+	// Sparse is true if the GSI has an inclusion rule.
+	Sparse bool
 }
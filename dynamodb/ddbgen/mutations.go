@@ -0,0 +1,327 @@
+package ddbgen
+
+import (
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"io"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+const (
+	ddbsdkPkgPath = "github.com/acksell/bezos/dynamodb/ddbsdk"
+	indexPkgPath  = "github.com/acksell/bezos/dynamodb/index"
+)
+
+// MutationReport lists the functions of a program that write entities, found by
+// [AnalyzeMutations].
+type MutationReport struct {
+	Functions []FunctionMutations `json:"functions"`
+}
+
+// FunctionMutations are the writes made in a function, including its closures.
+type FunctionMutations struct {
+	// Func is the function, e.g. "orders.(*Service).PlaceOrder".
+	Func string `json:"func"`
+	Pos  string `json:"pos"`
+	// Causes are the constant labels the function passes to ddbsdk.WithCause.
+	Causes    []string   `json:"causes,omitempty"`
+	Mutations []Mutation `json:"mutations"`
+}
+
+// Mutation is a call that builds a write action.
+type Mutation struct {
+	// Entity is the Go type of the written entity, e.g. "orders.Order". It is empty
+	// for updates and deletes built with ddbsdk directly, see Table.
+	Entity string `json:"entity,omitempty"`
+	// Table is the table argument of a ddbsdk constructor, as written in the source.
+	Table string `json:"table,omitempty"`
+	// Op is put, update or delete.
+	Op   string `json:"op"`
+	Call string `json:"call"`
+	Pos  string `json:"pos"`
+}
+
+// Target returns the entity, or the table for writes of unknown entities.
+func (m Mutation) Target() string {
+	if m.Entity != "" {
+		return m.Entity
+	}
+	return "table " + m.Table
+}
+
+// generatedOps are the write builders of generated index utils.
+var generatedOps = map[string]string{
+	"UnsafePut":    "put",
+	"SafePut":      "put",
+	"UnsafeUpdate": "update",
+	"Delete":       "delete",
+}
+
+// sdkOps are the write constructors of ddbsdk.
+var sdkOps = map[string]string{
+	"NewUnsafePut":    "put",
+	"NewSafePut":      "put",
+	"NewUnsafeUpdate": "update",
+	"NewDelete":       "delete",
+}
+
+// AnalyzeMutations loads the packages matching patterns, relative to dir, and reports
+// the functions that call the UnsafePut, SafePut, UnsafeUpdate and Delete builders of
+// generated index utils, or the ddbsdk constructors of those actions. Generated files
+// are skipped. The report is static: calls through interfaces or reflection are not
+// found, and a function is reported even if the action it builds is never committed.
+func AnalyzeMutations(dir string, patterns ...string) (*MutationReport, error) {
+	if len(patterns) == 0 {
+		patterns = []string{"./..."}
+	}
+	cfg := &packages.Config{
+		Mode: packages.NeedName | packages.NeedFiles | packages.NeedSyntax | packages.NeedImports | packages.NeedDeps | packages.NeedTypes | packages.NeedTypesInfo,
+		Dir:  dir,
+	}
+	pkgs, err := packages.Load(cfg, patterns...)
+	if err != nil {
+		return nil, fmt.Errorf("loading packages: %w", err)
+	}
+	var errs []string
+	packages.Visit(pkgs, nil, func(p *packages.Package) {
+		for _, err := range p.Errors {
+			errs = append(errs, err.Error())
+		}
+	})
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("loading packages:\n  %s", strings.Join(errs, "\n  "))
+	}
+
+	report := &MutationReport{}
+	for _, pkg := range pkgs {
+		a := mutationAnalyzer{pkg: pkg, dir: dir}
+		for _, file := range pkg.Syntax {
+			if ast.IsGenerated(file) {
+				continue
+			}
+			for _, decl := range file.Decls {
+				fn, ok := decl.(*ast.FuncDecl)
+				if !ok || fn.Body == nil {
+					continue
+				}
+				if fm := a.function(fn); len(fm.Mutations) > 0 {
+					report.Functions = append(report.Functions, fm)
+				}
+			}
+		}
+	}
+	sort.Slice(report.Functions, func(i, j int) bool {
+		return report.Functions[i].Func < report.Functions[j].Func
+	})
+	return report, nil
+}
+
+type mutationAnalyzer struct {
+	pkg *packages.Package
+	dir string
+}
+
+// function collects the mutations and causes in the body of fn.
+func (a mutationAnalyzer) function(fn *ast.FuncDecl) FunctionMutations {
+	fm := FunctionMutations{Func: a.funcName(fn), Pos: a.pos(fn.Pos())}
+	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		callee := a.callee(call)
+		if callee == nil {
+			return true
+		}
+		if m, ok := a.mutation(call, callee); ok {
+			fm.Mutations = append(fm.Mutations, m)
+		}
+		if cause, ok := a.cause(call, callee); ok && !slices.Contains(fm.Causes, cause) {
+			fm.Causes = append(fm.Causes, cause)
+		}
+		return true
+	})
+	return fm
+}
+
+// callee returns the function or method a call statically calls, if any.
+func (a mutationAnalyzer) callee(call *ast.CallExpr) *types.Func {
+	fun := ast.Unparen(call.Fun)
+	if ix, ok := fun.(*ast.IndexExpr); ok { // explicit type arguments
+		fun = ix.X
+	}
+	var id *ast.Ident
+	switch f := fun.(type) {
+	case *ast.Ident:
+		id = f
+	case *ast.SelectorExpr:
+		id = f.Sel
+	default:
+		return nil
+	}
+	fn, _ := a.pkg.TypesInfo.Uses[id].(*types.Func)
+	return fn
+}
+
+func (a mutationAnalyzer) mutation(call *ast.CallExpr, callee *types.Func) (Mutation, bool) {
+	m := Mutation{Call: callee.Name(), Pos: a.pos(call.Pos())}
+	sig := callee.Type().(*types.Signature)
+	if recv := sig.Recv(); recv != nil {
+		op, ok := generatedOps[callee.Name()]
+		if !ok {
+			return Mutation{}, false
+		}
+		entity := indexUtilEntity(recv.Type())
+		if entity == nil {
+			return Mutation{}, false
+		}
+		m.Op, m.Entity = op, a.typeString(entity)
+		return m, true
+	}
+	if callee.Pkg() == nil || callee.Pkg().Path() != ddbsdkPkgPath {
+		return Mutation{}, false
+	}
+	op, ok := sdkOps[callee.Name()]
+	if !ok || len(call.Args) < 2 {
+		return Mutation{}, false
+	}
+	m.Op, m.Call = op, "ddbsdk."+callee.Name()
+	m.Table = types.ExprString(call.Args[0])
+	if op == "put" && len(call.Args) >= 3 {
+		m.Entity = a.typeString(a.pkg.TypesInfo.TypeOf(call.Args[len(call.Args)-1]))
+	}
+	return m, true
+}
+
+// indexUtilEntity returns the entity type of a generated index util, the type argument
+// of the index.PrimaryIndex its Definition method returns, or nil for other types.
+func indexUtilEntity(t types.Type) types.Type {
+	def, _, _ := types.LookupFieldOrMethod(t, true, nil, "Definition")
+	fn, ok := def.(*types.Func)
+	if !ok {
+		return nil
+	}
+	res := fn.Type().(*types.Signature).Results()
+	if res.Len() != 1 {
+		return nil
+	}
+	rt := res.At(0).Type()
+	if p, ok := rt.(*types.Pointer); ok {
+		rt = p.Elem()
+	}
+	named, ok := rt.(*types.Named)
+	if !ok || named.Obj().Pkg() == nil || named.Obj().Pkg().Path() != indexPkgPath ||
+		named.Obj().Name() != "PrimaryIndex" || named.TypeArgs().Len() != 1 {
+		return nil
+	}
+	return named.TypeArgs().At(0)
+}
+
+// cause returns the label of a ddbsdk.WithCause call with a constant argument.
+func (a mutationAnalyzer) cause(call *ast.CallExpr, callee *types.Func) (string, bool) {
+	if callee.Pkg() == nil || callee.Pkg().Path() != ddbsdkPkgPath || callee.Name() != "WithCause" || len(call.Args) != 1 {
+		return "", false
+	}
+	tv := a.pkg.TypesInfo.Types[call.Args[0]]
+	if tv.Value == nil || tv.Value.Kind() != constant.String {
+		return "", false
+	}
+	return constant.StringVal(tv.Value), true
+}
+
+func (a mutationAnalyzer) funcName(fn *ast.FuncDecl) string {
+	name := fn.Name.Name
+	if fn.Recv != nil && len(fn.Recv.List) == 1 {
+		recv := a.pkg.TypesInfo.TypeOf(fn.Recv.List[0].Type)
+		if p, ok := recv.(*types.Pointer); ok {
+			name = fmt.Sprintf("(*%s).%s", baseTypeName(p.Elem()), name)
+		} else {
+			name = fmt.Sprintf("%s.%s", baseTypeName(recv), name)
+		}
+	}
+	return a.pkg.Name + "." + name
+}
+
+func baseTypeName(t types.Type) string {
+	if named, ok := t.(*types.Named); ok {
+		return named.Obj().Name()
+	}
+	return types.TypeString(t, nil)
+}
+
+// typeString names t by package name, dereferencing pointers.
+func (a mutationAnalyzer) typeString(t types.Type) string {
+	if p, ok := t.(*types.Pointer); ok {
+		t = p.Elem()
+	}
+	return types.TypeString(t, func(p *types.Package) string { return p.Name() })
+}
+
+// pos returns the position relative to the analyzed directory where possible.
+func (a mutationAnalyzer) pos(p token.Pos) string {
+	position := a.pkg.Fset.Position(p)
+	file := position.Filename
+	if abs, err := filepath.Abs(a.dir); err == nil {
+		if rel, err := filepath.Rel(abs, file); err == nil && !strings.HasPrefix(rel, "..") {
+			file = rel
+		}
+	}
+	return fmt.Sprintf("%s:%d", file, position.Line)
+}
+
+// Entities inverts the report: for every written entity, or table of writes whose
+// entity is unknown, the functions that write it, sorted.
+func (r *MutationReport) Entities() map[string][]string {
+	byEntity := map[string][]string{}
+	for _, fm := range r.Functions {
+		for _, m := range fm.Mutations {
+			if !slices.Contains(byEntity[m.Target()], fm.Func) {
+				byEntity[m.Target()] = append(byEntity[m.Target()], fm.Func)
+			}
+		}
+	}
+	for _, funcs := range byEntity {
+		sort.Strings(funcs)
+	}
+	return byEntity
+}
+
+// WriteText writes the report for reading in a terminal: the writes of every
+// function, then the functions writing every entity.
+func (r *MutationReport) WriteText(w io.Writer) error {
+	var b strings.Builder
+	for _, fm := range r.Functions {
+		fmt.Fprintf(&b, "%s (%s)\n", fm.Func, fm.Pos)
+		if len(fm.Causes) > 0 {
+			fmt.Fprintf(&b, "  causes: %s\n", strings.Join(fm.Causes, ", "))
+		}
+		for _, m := range fm.Mutations {
+			fmt.Fprintf(&b, "  %-6s %s via %s (%s)\n", m.Op, m.Target(), m.Call, m.Pos)
+		}
+	}
+	byEntity := r.Entities()
+	entities := make([]string, 0, len(byEntity))
+	for e := range byEntity {
+		entities = append(entities, e)
+	}
+	sort.Strings(entities)
+	if len(entities) > 0 {
+		b.WriteString("\nwritten by:\n")
+	}
+	for _, e := range entities {
+		fmt.Fprintf(&b, "  %s\n", e)
+		for _, f := range byEntity[e] {
+			fmt.Fprintf(&b, "    %s\n", f)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,61 @@
+package ddbgen
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAnalyzeMutations(t *testing.T) {
+	report, err := AnalyzeMutations("testdata/shop", "./...")
+	if err != nil {
+		t.Fatalf("AnalyzeMutations: %v", err)
+	}
+
+	// The ddbsdk calls in the generated index_gen.go are not reported.
+	want := []FunctionMutations{
+		{
+			Func:   "orders.(*Service).Cancel",
+			Pos:    "orders/service.go:25",
+			Causes: []string{"OrderCancelled"},
+			Mutations: []Mutation{
+				{Entity: "orders.Order", Op: "update", Call: "UnsafeUpdate", Pos: "orders/service.go:28"},
+			},
+		},
+		{
+			Func:   "orders.(*Service).Place",
+			Pos:    "orders/service.go:18",
+			Causes: []string{"OrderPlaced"},
+			Mutations: []Mutation{
+				{Entity: "orders.Order", Op: "put", Call: "UnsafePut", Pos: "orders/service.go:20"},
+			},
+		},
+		{
+			Func: "orders.Archive",
+			Pos:  "orders/service.go:44",
+			Mutations: []Mutation{
+				{Entity: "orders.Archived", Table: "ArchiveTable", Op: "put", Call: "ddbsdk.NewUnsafePut", Pos: "orders/service.go:47"},
+				{Table: "OrderTable", Op: "delete", Call: "ddbsdk.NewDelete", Pos: "orders/service.go:48"},
+				{Entity: "orders.Order", Op: "delete", Call: "Delete", Pos: "orders/service.go:49"},
+			},
+		},
+		{
+			Func: "orders.Service.Touch",
+			Pos:  "orders/service.go:35",
+			Mutations: []Mutation{
+				{Entity: "orders.Order", Op: "put", Call: "SafePut", Pos: "orders/service.go:39"},
+			},
+		},
+	}
+	if !reflect.DeepEqual(report.Functions, want) {
+		t.Errorf("AnalyzeMutations:\ngot  %+v\nwant %+v", report.Functions, want)
+	}
+
+	wantEntities := map[string][]string{
+		"orders.Archived":  {"orders.Archive"},
+		"orders.Order":     {"orders.(*Service).Cancel", "orders.(*Service).Place", "orders.Archive", "orders.Service.Touch"},
+		"table OrderTable": {"orders.Archive"},
+	}
+	if got := report.Entities(); !reflect.DeepEqual(got, wantEntities) {
+		t.Errorf("Entities:\ngot  %v\nwant %v", got, wantEntities)
+	}
+}
@@ -37,12 +37,17 @@ func hasPadding(spec string) bool {
 	return strings.HasPrefix(spec, "%0") && len(spec) > 2
 }
 
-func goParamType(fieldType string) string {
-	if fieldType == "string" {
+// goParamType returns the type of the key parameter for a field. Fields of types
+// that generated code can't name are taken as any, since they are formatted with %v.
+func goParamType(field fieldInfo) string {
+	if field.Type == "string" {
 		return "string"
 	}
-	if isTimeType(fieldType) {
+	if isTimeType(field.Type) {
 		return "time.Time"
 	}
-	return fieldType
+	if field.GoType == "" {
+		return "any"
+	}
+	return field.GoType
 }
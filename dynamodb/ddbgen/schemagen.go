@@ -6,8 +6,13 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 
+	"github.com/acksell/bezos/dynamodb/ddbsdk/eventsource"
+	"github.com/acksell/bezos/dynamodb/index"
 	"github.com/acksell/bezos/dynamodb/index/val"
+	"github.com/acksell/bezos/dynamodb/schema"
+	"github.com/acksell/bezos/dynamodb/table"
 	"gopkg.in/yaml.v3"
 )
 
@@ -16,11 +21,14 @@ import (
 // =============================================================================
 
 type schemaTable struct {
-	Name         string         `yaml:"name"`
-	PartitionKey schemaKeyDef   `yaml:"partitionKey"`
-	SortKey      *schemaKeyDef  `yaml:"sortKey,omitempty"`
-	GSIs         []schemaGSI    `yaml:"gsis,omitempty"`
-	Entities     []schemaEntity `yaml:"entities,omitempty"`
+	Name          string         `yaml:"name"`
+	PartitionKey  schemaKeyDef   `yaml:"partitionKey"`
+	SortKey       *schemaKeyDef  `yaml:"sortKey,omitempty"`
+	EntityTypeKey string         `yaml:"entityTypeKey,omitempty"`
+	TimeToLiveKey string         `yaml:"timeToLiveKey,omitempty"`
+	GSIs          []schemaGSI    `yaml:"gsis,omitempty"`
+	Entities      []schemaEntity `yaml:"entities,omitempty"`
+	Streams       []schemaStream `yaml:"streams,omitempty"`
 }
 
 type schemaKeyDef struct {
@@ -36,6 +44,7 @@ type schemaGSI struct {
 
 type schemaEntity struct {
 	Type                string         `yaml:"type"`
+	EntityTypeValue     string         `yaml:"entityTypeValue,omitempty"`
 	PartitionKeyPattern string         `yaml:"partitionKeyPattern"`
 	SortKeyPattern      string         `yaml:"sortKeyPattern,omitempty"`
 	Fields              []schemaField  `yaml:"fields"`
@@ -43,16 +52,42 @@ type schemaEntity struct {
 	IsVersioned         bool           `yaml:"isVersioned,omitempty"`
 }
 
+type schemaStream struct {
+	Aggregate           string        `yaml:"aggregate"`
+	PartitionKeyPattern string        `yaml:"partitionKeyPattern"`
+	SortKeyPattern      string        `yaml:"sortKeyPattern"`
+	Events              []schemaEvent `yaml:"events"`
+}
+
+type schemaEvent struct {
+	Type   string        `yaml:"type"`
+	Fields []schemaField `yaml:"fields"`
+}
+
 type schemaField struct {
+	Name  string       `yaml:"name"`
+	Tag   string       `yaml:"tag"`
+	Type  string       `yaml:"type"`
+	Rules []schemaRule `yaml:"rules,omitempty"`
+}
+
+type schemaRule struct {
 	Name string `yaml:"name"`
-	Tag  string `yaml:"tag"`
-	Type string `yaml:"type"`
+	Arg  string `yaml:"arg,omitempty"`
 }
 
 type schemaGSIMap struct {
-	GSI              string `yaml:"gsi"`
-	PartitionPattern string `yaml:"partitionPattern"`
-	SortPattern      string `yaml:"sortPattern,omitempty"`
+	GSI              string              `yaml:"gsi"`
+	PartitionPattern string              `yaml:"partitionPattern"`
+	SortPattern      string              `yaml:"sortPattern,omitempty"`
+	Condition        *schemaGSICondition `yaml:"condition,omitempty"`
+}
+
+type schemaGSICondition struct {
+	Kind        string `yaml:"kind"`
+	Field       string `yaml:"field,omitempty"`
+	Value       string `yaml:"value,omitempty"`
+	Description string `yaml:"description"`
 }
 
 type schemaRoot struct {
@@ -93,11 +128,127 @@ func valDefKind(vd val.ValDef) string {
 	return "S" // Default for FromField
 }
 
+// keyKind returns the attribute type of a key: the one of its definition, which the
+// table is created with, or else the one its values are written as.
+func keyKind(def table.KeyDef, vd val.ValDef) string {
+	if def.Kind != "" {
+		return string(def.Kind)
+	}
+	return valDefKind(vd)
+}
+
+// schemaGSIs returns the GSIs of a table: the ones of its definition, then any
+// others that its entities are mapped to.
+func schemaGSIs(idxs []indexInfo) []schemaGSI {
+	mapped := make(map[string]gsiInfo)
+	var order []string
+	for _, idx := range idxs {
+		for _, gsi := range idx.GSIs {
+			if _, ok := mapped[gsi.Name]; !ok {
+				mapped[gsi.Name] = gsi
+				order = append(order, gsi.Name)
+			}
+		}
+	}
+
+	var gsis []schemaGSI
+	seen := make(map[string]bool)
+	for _, def := range idxs[0].Table.GSIs {
+		seen[def.Name] = true
+		keys, m := def.KeyDefinitions, mapped[def.Name]
+		g := schemaGSI{Name: def.Name, PartitionKey: schemaKeyDef{Name: keys.PartitionKey.Name, Kind: keyKind(keys.PartitionKey, m.PKPattern)}}
+		if keys.SortKey.Name != "" {
+			var sk val.ValDef
+			if m.SKPattern != nil {
+				sk = *m.SKPattern
+			}
+			g.SortKey = &schemaKeyDef{Name: keys.SortKey.Name, Kind: keyKind(keys.SortKey, sk)}
+		}
+		gsis = append(gsis, g)
+	}
+	for _, name := range order {
+		if seen[name] {
+			continue
+		}
+		gsi := mapped[name]
+		g := schemaGSI{Name: gsi.Name, PartitionKey: schemaKeyDef{Name: gsi.PKDef, Kind: valDefKind(gsi.PKPattern)}}
+		if gsi.SKPattern != nil && !gsi.SKPattern.IsZero() {
+			g.SortKey = &schemaKeyDef{Name: gsi.SKDef, Kind: valDefKind(*gsi.SKPattern)}
+		}
+		gsis = append(gsis, g)
+	}
+	return gsis
+}
+
 // =============================================================================
 // Schema generation
 // =============================================================================
 
-func generateSchemaFiles(schemaDir string, indexes []indexInfo) error {
+func generateSchemaFiles(schemaDir string, indexes []indexInfo, streams []streamInfo) error {
+	data, err := schemaYAML(indexes, streams)
+	if err != nil {
+		return err
+	}
+
+	yamlPath := filepath.Join(schemaDir, schemaFileName)
+	if err := os.WriteFile(yamlPath, data, 0644); err != nil {
+		return fmt.Errorf("writing schema file: %w", err)
+	}
+	fmt.Printf("ddb gen: generated %s (%d tables)\n", yamlPath, countTables(indexes, streams))
+
+	schemaGoCode := `// Code generated by ddbgen. DO NOT EDIT.
+
+package schema
+
+import (
+	_ "embed"
+
+	"github.com/acksell/bezos/dynamodb/schema"
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed schema_dynamodb.yaml
+var schemaYAML []byte
+
+// Schema contains the DynamoDB table and entity definitions for this package.
+// Pass this to ddbui.NewServer to enable schema-aware debugging UI.
+var Schema schema.Schema
+
+func init() {
+	if err := yaml.Unmarshal(schemaYAML, &Schema); err != nil {
+		panic("ddbgen: failed to parse embedded schema: " + err.Error())
+	}
+}
+`
+
+	schemaGoPath := filepath.Join(schemaDir, "schema_gen.go")
+	if err := os.WriteFile(schemaGoPath, []byte(schemaGoCode), 0644); err != nil {
+		return fmt.Errorf("writing schema go file: %w", err)
+	}
+	fmt.Printf("ddb gen: generated %s\n", schemaGoPath)
+
+	return nil
+}
+
+// schemaFileName is the name of the generated schema file in the schema/ directory.
+const schemaFileName = "schema_dynamodb.yaml"
+
+// generatedSchema returns the schema of the indexes and streams, as read from
+// schema_dynamodb.yaml.
+func generatedSchema(indexes []indexInfo, streams []streamInfo) (schema.Schema, error) {
+	var s schema.Schema
+	data, err := schemaYAML(indexes, streams)
+	if err != nil {
+		return s, err
+	}
+	if err := yaml.Unmarshal(data, &s); err != nil {
+		return s, fmt.Errorf("parsing generated schema: %w", err)
+	}
+	return s, nil
+}
+
+// schemaYAML renders the schema_dynamodb.yaml contents for the indexes and streams.
+func schemaYAML(indexes []indexInfo, streams []streamInfo) ([]byte, error) {
 	// Group indexes by table, preserving discovery order
 	tableIndexes := make(map[string][]indexInfo)
 	var tableOrder []string
@@ -117,39 +268,39 @@ func generateSchemaFiles(schemaDir string, indexes []indexInfo) error {
 		idxs := tableIndexes[tableName]
 		firstIdx := idxs[0]
 		tbl := schemaTable{
-			Name:         tableName,
-			PartitionKey: schemaKeyDef{Name: firstIdx.PKDefName, Kind: valDefKind(firstIdx.PartitionKey)},
+			Name:          tableName,
+			PartitionKey:  schemaKeyDef{Name: firstIdx.PKDefName, Kind: keyKind(firstIdx.Table.KeyDefinitions.PartitionKey, firstIdx.PartitionKey)},
+			EntityTypeKey: firstIdx.EntityTypeKey,
+			TimeToLiveKey: firstIdx.TimeToLiveKey,
 		}
 		if firstIdx.SortKey != nil && !firstIdx.SortKey.IsZero() {
-			tbl.SortKey = &schemaKeyDef{Name: firstIdx.SKDefName, Kind: valDefKind(*firstIdx.SortKey)}
-		}
-		for _, gsi := range firstIdx.GSIs {
-			g := schemaGSI{
-				Name:         gsi.Name,
-				PartitionKey: schemaKeyDef{Name: gsi.PKDef, Kind: valDefKind(gsi.PKPattern)},
-			}
-			if gsi.SKPattern != nil && !gsi.SKPattern.IsZero() {
-				g.SortKey = &schemaKeyDef{Name: gsi.SKDef, Kind: valDefKind(*gsi.SKPattern)}
-			}
-			tbl.GSIs = append(tbl.GSIs, g)
+			tbl.SortKey = &schemaKeyDef{Name: firstIdx.SKDefName, Kind: keyKind(firstIdx.Table.KeyDefinitions.SortKey, *firstIdx.SortKey)}
 		}
+		tbl.GSIs = schemaGSIs(idxs)
 		for _, idx := range idxs {
 			entity := schemaEntity{
 				Type:                idx.EntityType,
 				PartitionKeyPattern: valDefPattern(idx.PartitionKey),
 				IsVersioned:         idx.IsVersioned,
 			}
+			if idx.EntityTypeKey != "" {
+				entity.EntityTypeValue = idx.EntityTypeName
+			}
 			if idx.SortKey != nil && !idx.SortKey.IsZero() {
 				entity.SortKeyPattern = valDefPattern(*idx.SortKey)
 			}
-			for _, f := range idx.Fields {
-				entity.Fields = append(entity.Fields, schemaField{Name: f.Name, Tag: f.Tag, Type: f.Type})
-			}
+			entity.Fields = schemaFields(idx.Fields)
 			for _, gsi := range idx.GSIs {
 				mapping := schemaGSIMap{GSI: gsi.Name, PartitionPattern: valDefPattern(gsi.PKPattern)}
 				if gsi.SKPattern != nil && !gsi.SKPattern.IsZero() {
 					mapping.SortPattern = valDefPattern(*gsi.SKPattern)
 				}
+				if in := gsi.Include; in != nil {
+					mapping.Condition = &schemaGSICondition{Kind: string(in.Kind), Field: in.Field, Description: in.Description}
+					if in.Kind == index.IncludeWhenEquals {
+						mapping.Condition.Value = fmt.Sprint(in.Value)
+					}
+				}
 				entity.GSIMappings = append(entity.GSIMappings, mapping)
 			}
 			tbl.Entities = append(tbl.Entities, entity)
@@ -157,55 +308,71 @@ func generateSchemaFiles(schemaDir string, indexes []indexInfo) error {
 		tables = append(tables, tbl)
 	}
 
+	// Streams go to the table of their entities, or to a table of their own.
+	for _, st := range streams {
+		i := slices.IndexFunc(tables, func(t schemaTable) bool { return t.Name == st.Table.Name })
+		if i == -1 {
+			keys := st.Table.KeyDefinitions
+			tables = append(tables, schemaTable{
+				Name:          st.Table.Name,
+				PartitionKey:  schemaKeyDef{Name: keys.PartitionKey.Name, Kind: string(keys.PartitionKey.Kind)},
+				SortKey:       &schemaKeyDef{Name: keys.SortKey.Name, Kind: string(keys.SortKey.Kind)},
+				EntityTypeKey: st.Table.EntityTypeKey,
+				TimeToLiveKey: st.Table.TimeToLiveKey,
+			})
+			i = len(tables) - 1
+		}
+		stream := schemaStream{
+			Aggregate:           st.Aggregate,
+			PartitionKeyPattern: st.PartitionKeyPattern,
+			SortKeyPattern:      eventsource.EventSortKeyPattern,
+		}
+		for _, e := range st.Events {
+			stream.Events = append(stream.Events, schemaEvent{Type: e.Type, Fields: schemaFields(e.Fields)})
+		}
+		tables[i].Streams = append(tables[i].Streams, stream)
+	}
+
 	schema := schemaRoot{Tables: tables}
 
 	var buf bytes.Buffer
 	encoder := yaml.NewEncoder(&buf)
 	encoder.SetIndent(1)
 	if err := encoder.Encode(schema); err != nil {
-		return fmt.Errorf("marshaling schema: %w", err)
+		return nil, fmt.Errorf("marshaling schema: %w", err)
 	}
 	encoder.Close()
 
 	header := []byte("# Generated by ddbgen. DO NOT EDIT.\n\n")
-	data := append(header, buf.Bytes()...)
-
-	yamlPath := filepath.Join(schemaDir, "schema_dynamodb.yaml")
-	if err := os.WriteFile(yamlPath, data, 0644); err != nil {
-		return fmt.Errorf("writing schema file: %w", err)
-	}
-	fmt.Printf("ddb gen: generated %s (%d tables)\n", yamlPath, len(tables))
-
-	schemaGoCode := `// Code generated by ddbgen. DO NOT EDIT.
-
-package schema
-
-import (
-	_ "embed"
-
-	"github.com/acksell/bezos/dynamodb/schema"
-	"gopkg.in/yaml.v3"
-)
-
-//go:embed schema_dynamodb.yaml
-var schemaYAML []byte
-
-// Schema contains the DynamoDB table and entity definitions for this package.
-// Pass this to ddbui.NewServer to enable schema-aware debugging UI.
-var Schema schema.Schema
+	return append(header, buf.Bytes()...), nil
+}
 
-func init() {
-	if err := yaml.Unmarshal(schemaYAML, &Schema); err != nil {
-		panic("ddbgen: failed to parse embedded schema: " + err.Error())
+// schemaFields returns the schema of struct fields. Cross-field rules refer to the
+// other field by attribute name.
+func schemaFields(fields []fieldInfo) []schemaField {
+	var out []schemaField
+	for _, f := range fields {
+		field := schemaField{Name: f.Name, Tag: f.Tag, Type: f.Type}
+		for _, r := range f.Rules {
+			arg := r.Arg
+			if r.OtherTag != "" {
+				arg = r.OtherTag
+			}
+			field.Rules = append(field.Rules, schemaRule{Name: r.Name, Arg: arg})
+		}
+		out = append(out, field)
 	}
+	return out
 }
-`
 
-	schemaGoPath := filepath.Join(schemaDir, "schema_gen.go")
-	if err := os.WriteFile(schemaGoPath, []byte(schemaGoCode), 0644); err != nil {
-		return fmt.Errorf("writing schema go file: %w", err)
+// countTables returns the number of distinct tables of the indexes and streams.
+func countTables(indexes []indexInfo, streams []streamInfo) int {
+	seen := make(map[string]bool)
+	for _, idx := range indexes {
+		seen[idx.TableName] = true
 	}
-	fmt.Printf("ddb gen: generated %s\n", schemaGoPath)
-
-	return nil
+	for _, s := range streams {
+		seen[s.Table.Name] = true
+	}
+	return len(seen)
 }
@@ -0,0 +1,51 @@
+// Package fieldtypes has entities for the ddbgen tests, mostly with fields of
+// types that generated code can't name.
+package fieldtypes
+
+import (
+	"net/netip"
+	"net/url"
+	"time"
+)
+
+type Link struct {
+	ID      string                        `dynamodbav:"id"`
+	Target  *url.URL                      `dynamodbav:"target"`
+	Mirrors []*url.URL                    `dynamodbav:"mirrors"`
+	Meta    map[string]struct{ Rank int } `dynamodbav:"meta"`
+	Tags    []string                      `dynamodbav:"tags"`
+}
+
+func (l *Link) IsValid() error { return nil }
+
+// Hit has key fields of types that generated code can't name.
+type Hit struct {
+	Host *url.URL   `dynamodbav:"host"`
+	Path string     `dynamodbav:"path"`
+	Peer netip.Addr `dynamodbav:"peer"`
+}
+
+func (h *Hit) IsValid() error { return nil }
+
+// Ticket has a status limited to an enum.
+type Ticket struct {
+	ID     string `dynamodbav:"id"`
+	Status string `dynamodbav:"status" validate:"enum=new|closed"`
+}
+
+func (t *Ticket) IsValid() error { return nil }
+
+// Account has fields with validate rules.
+type Account struct {
+	ID        string    `dynamodbav:"id" validate:"required,maxlen=8"`
+	Email     string    `dynamodbav:"email" validate:"regex=^[^@,]+@[^@,]+$"`
+	Plan      string    `dynamodbav:"plan" validate:"enum=free|pro"`
+	Seats     int       `dynamodbav:"seats" validate:"min=1,max=10"`
+	MaxSeats  int       `dynamodbav:"maxSeats" validate:"gtefield=Seats"`
+	Nickname  *string   `dynamodbav:"nickname" validate:"required,minlen=2"`
+	Tags      []string  `dynamodbav:"tags" validate:"maxlen=2"`
+	CreatedAt time.Time `dynamodbav:"createdAt" validate:"required"`
+	ExpiresAt time.Time `dynamodbav:"expiresAt" validate:"gtfield=CreatedAt"`
+}
+
+func (a *Account) IsValid() error { return nil }
@@ -0,0 +1,22 @@
+// Package orders writes orders in every way AnalyzeMutations recognizes.
+package orders
+
+import "time"
+
+type Order struct {
+	TenantID  string    `dynamodbav:"tenantID"`
+	OrderID   string    `dynamodbav:"orderID"`
+	Status    string    `dynamodbav:"status"`
+	UpdatedAt time.Time `dynamodbav:"updatedAt"`
+}
+
+func (o *Order) IsValid() error { return nil }
+
+func (o *Order) VersionField() (string, any) { return "updatedAt", o.UpdatedAt }
+
+// Archived is an order moved out of the orders table, written without index utils.
+type Archived struct {
+	OrderID string `dynamodbav:"orderID"`
+}
+
+func (a *Archived) IsValid() error { return nil }
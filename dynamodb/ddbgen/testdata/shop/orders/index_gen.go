@@ -0,0 +1,176 @@
+// Code generated by ddbgen. DO NOT EDIT.
+
+package orders
+
+import (
+	"github.com/acksell/bezos/dynamodb/ddbsdk"
+	"github.com/acksell/bezos/dynamodb/index"
+	"github.com/acksell/bezos/dynamodb/index/indices"
+	"github.com/acksell/bezos/dynamodb/index/val"
+	"github.com/acksell/bezos/dynamodb/table"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"sync"
+	"time"
+)
+
+// =============================================================================
+// Order Index Wrapper
+// =============================================================================
+
+// OrderIndexUtil wraps the PrimaryIndex with strongly-typed methods.
+type OrderIndexUtil struct {
+	o  sync.Once
+	pi *index.PrimaryIndex[Order]
+}
+
+// Definition returns the underlying PrimaryIndex, resolving it lazily on first call.
+func (idx *OrderIndexUtil) Definition() *index.PrimaryIndex[Order] {
+	idx.o.Do(func() { idx.pi = indices.Get[Order]() })
+	return idx.pi
+}
+
+// OrderIndex is the typed wrapper for Order operations.
+var OrderIndex OrderIndexUtil
+
+// OrderFields holds typed references to the attributes of Order,
+// for use in filters, conditions and update operations.
+var OrderFields = struct {
+	TenantID  ddbsdk.Field[string]
+	OrderID   ddbsdk.Field[string]
+	Status    ddbsdk.Field[string]
+	UpdatedAt ddbsdk.Field[time.Time]
+}{
+	TenantID:  ddbsdk.NewField[string]("tenantID"),
+	OrderID:   ddbsdk.NewField[string]("orderID"),
+	Status:    ddbsdk.NewField[string]("status"),
+	UpdatedAt: ddbsdk.NewField[time.Time]("updatedAt"),
+}
+
+// PrimaryKey creates a primary key from explicit parameters.
+func (idx *OrderIndexUtil) PrimaryKey(tenantID string, orderID string) table.PrimaryKey {
+	return table.PrimaryKey{
+		Definition: idx.Definition().Table.KeyDefinitions,
+		Values: table.PrimaryKeyValues{
+			PartitionKey: "TENANT#" + tenantID,
+			SortKey:      "ORDER#" + orderID,
+		},
+	}
+}
+
+// PrimaryKeyFrom creates the primary key from a Order entity.
+func (idx *OrderIndexUtil) PrimaryKeyFrom(e *Order) table.PrimaryKey {
+	return table.PrimaryKey{
+		Definition: idx.Definition().Table.KeyDefinitions,
+		Values: table.PrimaryKeyValues{
+			PartitionKey: "TENANT#" + e.TenantID,
+			SortKey:      "ORDER#" + e.OrderID,
+		},
+	}
+}
+
+// ParsePrimaryKey decodes the primary key attributes of item into a new Order.
+// Only the fields encoded in the key are set.
+func (idx *OrderIndexUtil) ParsePrimaryKey(item ddbsdk.Item) (*Order, error) {
+	k, err := idx.Definition().ParseKeys(item)
+	if err != nil {
+		return nil, err
+	}
+	e := new(Order)
+	if e.TenantID, err = val.As[string](k, "tenantID"); err != nil {
+		return nil, err
+	}
+	if e.OrderID, err = val.As[string](k, "orderID"); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// UnsafePut creates a Put operation without optimistic locking.
+func (idx *OrderIndexUtil) UnsafePut(e *Order) *ddbsdk.Put {
+	return ddbsdk.NewUnsafePut(idx.Definition().Table, idx.PrimaryKeyFrom(e), e)
+}
+
+// SafePut creates a Put operation with optimistic locking.
+// If old is nil, acts as a conditional create (fails if item already exists).
+// If old is non-nil, fails unless the existing item's version matches old's version.
+func (idx *OrderIndexUtil) SafePut(old *Order, new *Order) *ddbsdk.PutWithCondition {
+	return ddbsdk.NewSafePut(idx.Definition().Table, idx.PrimaryKeyFrom(new), old, new)
+}
+
+// Delete creates a Delete operation.
+func (idx *OrderIndexUtil) Delete(tenantID string, orderID string) *ddbsdk.Delete {
+	return ddbsdk.NewDelete(idx.Definition().Table, idx.PrimaryKey(tenantID, orderID))
+}
+
+// UnsafeUpdate creates an Update operation without optimistic locking.
+// GSI keys derived from fields set by the update are recomputed, see [ddbsdk.UnsafeUpdate.WithDerivedKeys].
+func (idx *OrderIndexUtil) UnsafeUpdate(tenantID string, orderID string) *ddbsdk.UnsafeUpdate {
+	return ddbsdk.NewUnsafeUpdate(idx.Definition().Table, idx.PrimaryKey(tenantID, orderID)).
+		WithDerivedKeys(idx.Definition().DerivedKeys(), map[string]any{"tenantID": tenantID, "orderID": orderID})
+}
+
+// EnsureExists creates a ConditionCheck that asserts an item with this primary key exists.
+// Use this in transactions for referential integrity checks.
+func (idx *OrderIndexUtil) EnsureExists(tenantID string, orderID string) *ddbsdk.ConditionCheck {
+	return ddbsdk.NewConditionCheck(
+		idx.Definition().Table,
+		idx.PrimaryKey(tenantID, orderID),
+		expression.AttributeExists(expression.Name(idx.Definition().Table.KeyDefinitions.PartitionKey.Name)),
+	)
+}
+
+// -------------------------------------------------------------------------
+// Primary Index Query Builder
+// -------------------------------------------------------------------------
+
+// OrderPrimaryQuery is a query builder for the primary index.
+type OrderPrimaryQuery struct {
+	idx *OrderIndexUtil
+	qd  ddbsdk.QueryDef
+}
+
+// Build returns the underlying QueryDef, implementing ddbsdk.QueryBuilder.
+func (q OrderPrimaryQuery) Build() ddbsdk.QueryDef { return q.qd }
+
+// QueryPartition creates a query for the given partition key on the primary index.
+func (idx *OrderIndexUtil) QueryPartition(tenantID string) OrderPrimaryQuery {
+	return OrderPrimaryQuery{
+		idx: idx,
+		qd:  ddbsdk.QueryPartition(idx.Definition().Table, "TENANT#"+tenantID),
+	}
+}
+
+// OrderIDEquals adds a sort key equals condition and returns the final QueryDef.
+func (q OrderPrimaryQuery) OrderIDEquals(orderID string) ddbsdk.QueryDef {
+	return q.qd.WithSKCondition(ddbsdk.Equals("ORDER#" + orderID))
+}
+
+// OrderIDBeginsWith adds a sort key begins_with condition and returns the final QueryDef.
+func (q OrderPrimaryQuery) OrderIDBeginsWith(prefix string) ddbsdk.QueryDef {
+	return q.qd.WithSKCondition(ddbsdk.BeginsWith("ORDER#" + prefix))
+}
+
+// OrderIDBetween adds a sort key between condition and returns the final QueryDef.
+func (q OrderPrimaryQuery) OrderIDBetween(orderIDStart string, orderIDEnd string) ddbsdk.QueryDef {
+	return q.qd.WithSKCondition(ddbsdk.Between("ORDER#"+orderIDStart, "ORDER#"+orderIDEnd))
+}
+
+// OrderIDGreaterThan adds a sort key > condition and returns the final QueryDef.
+func (q OrderPrimaryQuery) OrderIDGreaterThan(orderID string) ddbsdk.QueryDef {
+	return q.qd.WithSKCondition(ddbsdk.GreaterThan("ORDER#" + orderID))
+}
+
+// OrderIDGreaterThanOrEqual adds a sort key >= condition and returns the final QueryDef.
+func (q OrderPrimaryQuery) OrderIDGreaterThanOrEqual(orderID string) ddbsdk.QueryDef {
+	return q.qd.WithSKCondition(ddbsdk.GreaterThanOrEqual("ORDER#" + orderID))
+}
+
+// OrderIDLessThan adds a sort key < condition and returns the final QueryDef.
+func (q OrderPrimaryQuery) OrderIDLessThan(orderID string) ddbsdk.QueryDef {
+	return q.qd.WithSKCondition(ddbsdk.LessThan("ORDER#" + orderID))
+}
+
+// OrderIDLessThanOrEqual adds a sort key <= condition and returns the final QueryDef.
+func (q OrderPrimaryQuery) OrderIDLessThanOrEqual(orderID string) ddbsdk.QueryDef {
+	return q.qd.WithSKCondition(ddbsdk.LessThanOrEqual("ORDER#" + orderID))
+}
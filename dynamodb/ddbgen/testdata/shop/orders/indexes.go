@@ -0,0 +1,33 @@
+package orders
+
+//go:generate go run ./gen
+
+import (
+	"github.com/acksell/bezos/dynamodb/index"
+	"github.com/acksell/bezos/dynamodb/index/indices"
+	"github.com/acksell/bezos/dynamodb/index/val"
+	"github.com/acksell/bezos/dynamodb/table"
+)
+
+var OrderTable = table.TableDefinition{
+	Name: "orders",
+	KeyDefinitions: table.PrimaryKeyDefinition{
+		PartitionKey: table.KeyDef{Name: "pk", Kind: table.KeyKindS},
+		SortKey:      table.KeyDef{Name: "sk", Kind: table.KeyKindS},
+	},
+}
+
+var ArchiveTable = table.TableDefinition{
+	Name: "archive",
+	KeyDefinitions: table.PrimaryKeyDefinition{
+		PartitionKey: table.KeyDef{Name: "pk", Kind: table.KeyKindS},
+	},
+}
+
+func init() {
+	indices.Add(index.PrimaryIndex[Order]{
+		Table:        OrderTable,
+		PartitionKey: val.Fmt("TENANT#{tenantID}"),
+		SortKey:      val.Fmt("ORDER#{orderID}").Ptr(),
+	})
+}
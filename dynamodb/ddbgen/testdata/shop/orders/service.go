@@ -0,0 +1,57 @@
+package orders
+
+import (
+	"context"
+	"time"
+
+	"github.com/acksell/bezos/dynamodb/ddbsdk"
+	"github.com/acksell/bezos/dynamodb/table"
+)
+
+const causeOrderCancelled = "OrderCancelled"
+
+type Service struct {
+	db *ddbsdk.Client
+}
+
+// Place writes a new order with a generated builder.
+func (s *Service) Place(ctx context.Context, o *Order) error {
+	tx := s.db.NewTx(ddbsdk.WithCause("OrderPlaced"))
+	tx.AddAction(OrderIndex.UnsafePut(o))
+	return tx.Commit(ctx)
+}
+
+// Cancel updates the order in a closure, with the cause a named constant.
+func (s *Service) Cancel(ctx context.Context, tenantID, orderID string) error {
+	tx := s.db.NewTx(ddbsdk.WithCause(causeOrderCancelled))
+	add := func() {
+		tx.AddAction(OrderIndex.UnsafeUpdate(tenantID, orderID).AddOp(ddbsdk.SetFieldOp("status", "cancelled")))
+	}
+	add()
+	return tx.Commit(ctx)
+}
+
+// Touch writes the order with optimistic locking, with a cause that isn't a constant.
+func (s Service) Touch(ctx context.Context, cause string, old *Order) error {
+	updated := *old
+	updated.UpdatedAt = time.Now()
+	tx := s.db.NewTx(ddbsdk.WithCause(cause))
+	tx.AddAction(OrderIndex.SafePut(old, &updated))
+	return tx.Commit(ctx)
+}
+
+// Archive moves an order to the archive with the ddbsdk constructors.
+func Archive(ctx context.Context, db *ddbsdk.Client, key, archiveKey table.PrimaryKey, o *Order) error {
+	tx := db.NewTx()
+	tx.AddAction(
+		ddbsdk.NewUnsafePut(ArchiveTable, archiveKey, &Archived{OrderID: o.OrderID}),
+		ddbsdk.NewDelete(OrderTable, key),
+		OrderIndex.Delete(o.TenantID, o.OrderID),
+	)
+	return tx.Commit(ctx)
+}
+
+// Lookup only reads, so it isn't reported.
+func Lookup(ctx context.Context, db *ddbsdk.Client, tenantID, orderID string) (ddbsdk.Item, error) {
+	return db.NewLookup().GetItem(ctx, ddbsdk.GetItemRequest{Table: OrderTable, Key: OrderIndex.PrimaryKey(tenantID, orderID)})
+}
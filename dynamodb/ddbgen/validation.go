@@ -0,0 +1,427 @@
+package ddbgen
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/acksell/bezos/dynamodb/index"
+)
+
+// =============================================================================
+// validate struct tags
+// =============================================================================
+
+// validationRule is one rule of a field's validate tag, e.g. "max=100".
+type validationRule struct {
+	Name string
+	Arg  string
+	// OtherField and OtherTag are the Go name and dynamodbav tag of the field
+	// compared by a cross-field rule.
+	OtherField string
+	OtherTag   string
+}
+
+// valueKind groups field types by the rules that apply to them.
+type valueKind int
+
+const (
+	kindOther valueKind = iota
+	kindString
+	kindInt
+	kindUint
+	kindFloat
+	kindBool
+	kindTime
+	kindCollection // slices, arrays and maps
+)
+
+// crossFieldRules compare a field with another field of the entity.
+var crossFieldRules = map[string]bool{
+	"eqfield": true, "nefield": true,
+	"gtfield": true, "gtefield": true,
+	"ltfield": true, "ltefield": true,
+}
+
+// ruleKinds lists the kinds each rule applies to, other than required and
+// the cross-field rules.
+var ruleKinds = map[string][]valueKind{
+	"min":    {kindInt, kindUint, kindFloat},
+	"max":    {kindInt, kindUint, kindFloat},
+	"len":    {kindString, kindCollection},
+	"minlen": {kindString, kindCollection},
+	"maxlen": {kindString, kindCollection},
+	"regex":  {kindString},
+	"enum":   {kindString, kindInt, kindUint},
+}
+
+// parseValidateTag splits a validate tag into its rules. A regex rule takes the
+// rest of the tag as its pattern, so that it can contain commas, and must be last.
+func parseValidateTag(tag string) ([]validationRule, error) {
+	var rules []validationRule
+	seen := make(map[string]bool)
+	for tag != "" {
+		var part string
+		if strings.HasPrefix(tag, "regex=") {
+			part, tag = tag, ""
+		} else {
+			part, tag, _ = strings.Cut(tag, ",")
+		}
+		name, arg, hasArg := strings.Cut(strings.TrimSpace(part), "=")
+		switch {
+		case name == "required":
+			if hasArg {
+				return nil, fmt.Errorf("rule required takes no argument")
+			}
+		case ruleKinds[name] != nil || crossFieldRules[name]:
+			if arg == "" {
+				return nil, fmt.Errorf("rule %s needs an argument, e.g. %s=...", name, name)
+			}
+		default:
+			return nil, fmt.Errorf("unknown rule %q", part)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate rule %s", name)
+		}
+		seen[name] = true
+		rules = append(rules, validationRule{Name: name, Arg: arg})
+	}
+	return rules, nil
+}
+
+// kindOf returns the valueKind of t, and whether t is a pointer to it.
+func kindOf(t reflect.Type) (valueKind, bool) {
+	ptr := t.Kind() == reflect.Ptr
+	if ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return kindString, ptr
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return kindInt, ptr
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return kindUint, ptr
+	case reflect.Float32, reflect.Float64:
+		return kindFloat, ptr
+	case reflect.Bool:
+		return kindBool, ptr
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return kindCollection, ptr
+	case reflect.Struct:
+		if t.PkgPath() == "time" && t.Name() == "Time" {
+			return kindTime, ptr
+		}
+	}
+	return kindOther, ptr
+}
+
+// resolveValidation checks the rules of the entity's fields against their types
+// and resolves the fields that cross-field rules refer to.
+func resolveValidation(entityType reflect.Type, fields []fieldInfo) error {
+	byName := make(map[string]*fieldInfo, len(fields))
+	for i := range fields {
+		byName[fields[i].Name] = &fields[i]
+	}
+	for i := range fields {
+		f := &fields[i]
+		sf, _ := entityType.FieldByName(f.Name)
+		f.kind, f.pointer = kindOf(sf.Type)
+		deref := sf.Type
+		if f.pointer {
+			deref = deref.Elem()
+		}
+		f.namedString = f.kind == kindString && deref.Name() != "string"
+
+		for j := range f.Rules {
+			if err := checkRule(f, &f.Rules[j], sf.Type, byName, entityType); err != nil {
+				return fmt.Errorf("field %s: %w", f.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+func checkRule(f *fieldInfo, r *validationRule, t reflect.Type, byName map[string]*fieldInfo, entityType reflect.Type) error {
+	if r.Name == "required" {
+		if f.kind == kindOther && !f.pointer {
+			return fmt.Errorf("rule required is not supported for %s", t)
+		}
+		return nil
+	}
+
+	if crossFieldRules[r.Name] {
+		other, ok := byName[r.Arg]
+		if !ok {
+			return fmt.Errorf("rule %s: %s has no field %s with a dynamodbav tag", r.Name, entityType.Name(), r.Arg)
+		}
+		if other.Name == f.Name {
+			return fmt.Errorf("rule %s compares the field with itself", r.Name)
+		}
+		otherField, _ := entityType.FieldByName(other.Name)
+		if otherField.Type != t {
+			return fmt.Errorf("rule %s: %s is a %s, not a %s", r.Name, other.Name, otherField.Type, t)
+		}
+		if f.pointer {
+			return fmt.Errorf("rule %s is not supported for pointers", r.Name)
+		}
+		ordered := f.kind == kindString || f.kind == kindInt || f.kind == kindUint || f.kind == kindFloat || f.kind == kindTime
+		equality := r.Name == "eqfield" || r.Name == "nefield"
+		if !ordered && !(equality && f.kind == kindBool) {
+			return fmt.Errorf("rule %s is not supported for %s", r.Name, t)
+		}
+		r.OtherField, r.OtherTag = other.Name, other.Tag
+		return nil
+	}
+
+	if !containsKind(ruleKinds[r.Name], f.kind) {
+		return fmt.Errorf("rule %s is not supported for %s", r.Name, t)
+	}
+	switch r.Name {
+	case "min", "max":
+		return checkNumber(r, f.kind)
+	case "len", "minlen", "maxlen":
+		if n, err := strconv.Atoi(r.Arg); err != nil || n < 0 {
+			return fmt.Errorf("rule %s: %q is not a length", r.Name, r.Arg)
+		}
+	case "regex":
+		if _, err := regexp.Compile(r.Arg); err != nil {
+			return fmt.Errorf("rule regex: %w", err)
+		}
+	case "enum":
+		for _, v := range strings.Split(r.Arg, "|") {
+			if v == "" {
+				return fmt.Errorf("rule enum has an empty value")
+			}
+			if f.kind != kindString {
+				if err := checkNumber(&validationRule{Name: r.Name, Arg: v}, f.kind); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// checkNumber checks that the argument of r is a constant of the field's kind.
+func checkNumber(r *validationRule, kind valueKind) error {
+	var err error
+	switch kind {
+	case kindInt:
+		_, err = strconv.ParseInt(r.Arg, 10, 64)
+	case kindUint:
+		_, err = strconv.ParseUint(r.Arg, 10, 64)
+	default:
+		_, err = strconv.ParseFloat(r.Arg, 64)
+	}
+	if err != nil {
+		return fmt.Errorf("rule %s: %q is not a number of the field's type", r.Name, r.Arg)
+	}
+	return nil
+}
+
+func containsKind(kinds []valueKind, k valueKind) bool {
+	for _, kind := range kinds {
+		if kind == k {
+			return true
+		}
+	}
+	return false
+}
+
+// =============================================================================
+// Code generation
+// =============================================================================
+
+// patternData is a precompiled regex rule of a field.
+type patternData struct {
+	Name string
+	Expr string
+}
+
+// validationCode generates the body of an entity's IsValidGenerated method, and the
+// regexp variables it uses. Violations are appended to errs.
+func validationCode(entity string, fields []fieldInfo) (string, []patternData, bool) {
+	var b strings.Builder
+	var patterns []patternData
+	usesUTF8 := false
+	for _, f := range fields {
+		if len(f.Rules) == 0 {
+			continue
+		}
+		v := "e." + f.Name
+		indent := "\t"
+		var checks []validationRule
+		for _, r := range f.Rules {
+			if r.Name == "required" && f.pointer {
+				writeCheck(&b, indent, v+" == nil", f.Tag, r.Name, "is required")
+				continue
+			}
+			checks = append(checks, r)
+		}
+		if len(checks) == 0 {
+			continue
+		}
+		if f.pointer {
+			fmt.Fprintf(&b, "\tif %s != nil {\n", v)
+			v = "*" + v
+			indent = "\t\t"
+		}
+		str := v
+		if f.namedString {
+			str = "string(" + v + ")"
+		}
+		for _, r := range checks {
+			switch r.Name {
+			case "required":
+				writeCheck(&b, indent, zeroCheck(f.kind, v), f.Tag, r.Name, "is required")
+			case "min":
+				writeCheck(&b, indent, v+" < "+r.Arg, f.Tag, r.Name, "must be at least "+r.Arg)
+			case "max":
+				writeCheck(&b, indent, v+" > "+r.Arg, f.Tag, r.Name, "must be at most "+r.Arg)
+			case "len", "minlen", "maxlen":
+				length := "len(" + v + ")"
+				if f.kind == kindString {
+					length = "utf8.RuneCountInString(" + str + ")"
+					usesUTF8 = true
+				}
+				op, msg := " != ", "must have length "
+				switch r.Name {
+				case "minlen":
+					op, msg = " < ", "must have length at least "
+				case "maxlen":
+					op, msg = " > ", "must have length at most "
+				}
+				writeCheck(&b, indent, length+op+r.Arg, f.Tag, r.Name, msg+r.Arg)
+			case "regex":
+				name := lowerFirst(entity) + f.Name + "Pattern"
+				expr := strconv.Quote(r.Arg)
+				if strconv.CanBackquote(r.Arg) {
+					expr = "`" + r.Arg + "`"
+				}
+				patterns = append(patterns, patternData{Name: name, Expr: expr})
+				writeCheck(&b, indent, fmt.Sprintf("%s != \"\" && !%s.MatchString(%s)", v, name, str), f.Tag, r.Name, "must match "+r.Arg)
+			case "enum":
+				values := strings.Split(r.Arg, "|")
+				cases := make([]string, len(values))
+				for i, val := range values {
+					cases[i] = val
+					if f.kind == kindString {
+						cases[i] = strconv.Quote(val)
+					}
+				}
+				if f.kind == kindString {
+					// Empty strings are left to the required rule.
+					cases = append([]string{`""`}, cases...)
+				}
+				fmt.Fprintf(&b, "%sswitch %s {\n%scase %s:\n%sdefault:\n", indent, v, indent, strings.Join(cases, ", "), indent)
+				writeAppend(&b, indent+"\t", f.Tag, r.Name, "must be one of "+strings.Join(values, ", "))
+				fmt.Fprintf(&b, "%s}\n", indent)
+			default:
+				cond, msg := crossFieldCheck(r, f.kind, v, "e."+r.OtherField)
+				writeCheck(&b, indent, cond, f.Tag, r.Name, msg+" "+r.OtherTag)
+			}
+		}
+		if f.pointer {
+			b.WriteString("\t}\n")
+		}
+	}
+	return b.String(), patterns, usesUTF8
+}
+
+func writeCheck(b *strings.Builder, indent, cond, tag, rule, msg string) {
+	fmt.Fprintf(b, "%sif %s {\n", indent, cond)
+	writeAppend(b, indent+"\t", tag, rule, msg)
+	fmt.Fprintf(b, "%s}\n", indent)
+}
+
+func writeAppend(b *strings.Builder, indent, tag, rule, msg string) {
+	fmt.Fprintf(b, "%serrs = append(errs, &ddbsdk.FieldError{Field: %q, Rule: %q, Msg: %q})\n", indent, tag, rule, msg)
+}
+
+// zeroCheck returns the condition under which a value of kind k fails the required rule.
+func zeroCheck(k valueKind, v string) string {
+	switch k {
+	case kindString:
+		return v + ` == ""`
+	case kindBool:
+		return "!" + v
+	case kindTime:
+		return v + ".IsZero()"
+	case kindCollection:
+		return "len(" + v + ") == 0"
+	default:
+		return v + " == 0"
+	}
+}
+
+// crossFieldCheck returns the condition under which v fails the cross-field rule r
+// comparing it with other, and the start of its message.
+func crossFieldCheck(r validationRule, k valueKind, v, other string) (string, string) {
+	if k == kindTime {
+		switch r.Name {
+		case "eqfield":
+			return "!" + v + ".Equal(" + other + ")", "must equal"
+		case "nefield":
+			return v + ".Equal(" + other + ")", "must differ from"
+		case "gtfield":
+			return "!" + v + ".After(" + other + ")", "must be after"
+		case "gtefield":
+			return v + ".Before(" + other + ")", "must not be before"
+		case "ltfield":
+			return "!" + v + ".Before(" + other + ")", "must be before"
+		default:
+			return v + ".After(" + other + ")", "must not be after"
+		}
+	}
+	switch r.Name {
+	case "eqfield":
+		return v + " != " + other, "must equal"
+	case "nefield":
+		return v + " == " + other, "must differ from"
+	case "gtfield":
+		return v + " <= " + other, "must be greater than"
+	case "gtefield":
+		return v + " < " + other, "must be at least"
+	case "ltfield":
+		return v + " >= " + other, "must be less than"
+	default:
+		return v + " > " + other, "must be at most"
+	}
+}
+
+func lowerFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	r := []rune(s)
+	r[0] = unicode.ToLower(r[0])
+	return string(r)
+}
+
+// checkInclusions checks that the values sparse GSIs include items by are allowed by
+// the enum rule of their field, since otherwise no valid entity is ever in the GSI.
+func checkInclusions(gsis []gsiInfo, fields []fieldInfo) error {
+	for _, gsi := range gsis {
+		in := gsi.Include
+		if in == nil || in.Kind != index.IncludeWhenEquals {
+			continue
+		}
+		for _, f := range fields {
+			if f.Tag != in.Field {
+				continue
+			}
+			for _, r := range f.Rules {
+				if r.Name == "enum" && !slices.Contains(strings.Split(r.Arg, "|"), fmt.Sprint(in.Value)) {
+					return fmt.Errorf("GSI %s only includes items where %s, but field %s only allows %s",
+						gsi.Name, in.Description, f.Name, strings.ReplaceAll(r.Arg, "|", ", "))
+				}
+			}
+		}
+	}
+	return nil
+}
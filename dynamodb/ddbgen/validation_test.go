@@ -0,0 +1,300 @@
+package ddbgen
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/acksell/bezos/dynamodb/ddbgen/testdata/fieldtypes"
+	"github.com/acksell/bezos/dynamodb/index"
+	"github.com/acksell/bezos/dynamodb/index/indices"
+	"github.com/acksell/bezos/dynamodb/index/val"
+)
+
+func TestParseValidateTag(t *testing.T) {
+	tests := []struct {
+		tag     string
+		want    []validationRule
+		wantErr string
+	}{
+		{tag: "", want: nil},
+		{tag: "required", want: []validationRule{{Name: "required"}}},
+		{tag: "required, min=1,max=10", want: []validationRule{{Name: "required"}, {Name: "min", Arg: "1"}, {Name: "max", Arg: "10"}}},
+		{tag: "enum=a|b", want: []validationRule{{Name: "enum", Arg: "a|b"}}},
+		{tag: "maxlen=3,regex=^a{1,2}$", want: []validationRule{{Name: "maxlen", Arg: "3"}, {Name: "regex", Arg: "^a{1,2}$"}}},
+		{tag: "eqfield=Other", want: []validationRule{{Name: "eqfield", Arg: "Other"}}},
+		{tag: "required=true", wantErr: "rule required takes no argument"},
+		{tag: "min", wantErr: "rule min needs an argument"},
+		{tag: "enum=", wantErr: "rule enum needs an argument"},
+		{tag: "min=1,min=2", wantErr: "duplicate rule min"},
+		{tag: "email", wantErr: `unknown rule "email"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tag, func(t *testing.T) {
+			got, err := parseValidateTag(tt.tag)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveValidation(t *testing.T) {
+	field := func(name string, typ reflect.Type, validate string) reflect.StructField {
+		tag := `dynamodbav:"` + strings.ToLower(name) + `"`
+		if validate != "" {
+			tag += ` validate:"` + validate + `"`
+		}
+		return reflect.StructField{Name: name, Type: typ, Tag: reflect.StructTag(tag)}
+	}
+	var (
+		str      = reflect.TypeFor[string]()
+		integer  = reflect.TypeFor[int]()
+		uinteger = reflect.TypeFor[uint]()
+		float    = reflect.TypeFor[float64]()
+		strPtr   = reflect.TypeFor[*string]()
+		ts       = reflect.TypeFor[time.Time]()
+		strs     = reflect.TypeFor[[]string]()
+	)
+
+	tests := []struct {
+		name    string
+		fields  []reflect.StructField
+		wantErr string
+	}{
+		{
+			name:   "valid rules",
+			fields: []reflect.StructField{field("Name", str, "required,maxlen=10,regex=^[a-z]+$"), field("Age", integer, "min=0,max=150"), field("Tags", strs, "minlen=1")},
+		},
+		{
+			name:    "bad regex",
+			fields:  []reflect.StructField{field("Name", str, "regex=^(a$")},
+			wantErr: "field Name: rule regex: error parsing regexp",
+		},
+		{
+			name:    "min on a string",
+			fields:  []reflect.StructField{field("Name", str, "min=1")},
+			wantErr: "field Name: rule min is not supported for string",
+		},
+		{
+			name:    "regex on an int",
+			fields:  []reflect.StructField{field("Age", integer, "regex=^1$")},
+			wantErr: "rule regex is not supported for int",
+		},
+		{
+			name:    "max not a number",
+			fields:  []reflect.StructField{field("Score", float, "max=high")},
+			wantErr: `rule max: "high" is not a number`,
+		},
+		{
+			name:    "negative length",
+			fields:  []reflect.StructField{field("Name", str, "maxlen=-1")},
+			wantErr: `rule maxlen: "-1" is not a length`,
+		},
+		{
+			name:    "enum with an empty value",
+			fields:  []reflect.StructField{field("Plan", str, "enum=free||pro")},
+			wantErr: "rule enum has an empty value",
+		},
+		{
+			name:    "enum of a uint with a negative value",
+			fields:  []reflect.StructField{field("Level", uinteger, "enum=1|-1")},
+			wantErr: `rule enum: "-1" is not a number`,
+		},
+		{
+			name:    "required on a struct",
+			fields:  []reflect.StructField{field("Inner", reflect.TypeFor[struct{ A int }](), "required")},
+			wantErr: "rule required is not supported",
+		},
+		{
+			name:   "cross-field rule on times",
+			fields: []reflect.StructField{field("Start", ts, ""), field("End", ts, "gtfield=Start")},
+		},
+		{
+			name:    "cross-field rule with a missing field",
+			fields:  []reflect.StructField{field("End", ts, "gtfield=Start")},
+			wantErr: "rule gtfield: Entity has no field Start with a dynamodbav tag",
+		},
+		{
+			name:    "cross-field rule with the field itself",
+			fields:  []reflect.StructField{field("End", ts, "gtfield=End")},
+			wantErr: "rule gtfield compares the field with itself",
+		},
+		{
+			name:    "cross-field rule with another type",
+			fields:  []reflect.StructField{field("Min", integer, ""), field("Max", float, "gtefield=Min")},
+			wantErr: "rule gtefield: Min is a int, not a float64",
+		},
+		{
+			name:    "cross-field rule on pointers",
+			fields:  []reflect.StructField{field("A", strPtr, ""), field("B", strPtr, "nefield=A")},
+			wantErr: "rule nefield is not supported for pointers",
+		},
+		{
+			name:    "ordering of slices",
+			fields:  []reflect.StructField{field("A", strs, ""), field("B", strs, "ltfield=A")},
+			wantErr: "rule ltfield is not supported for []string",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Named, so that errors can refer to the entity.
+			entityType := reflect.StructOf(tt.fields)
+			fields, err := structFields(entityType)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = resolveValidation(namedEntity{entityType}, fields)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+// namedEntity gives a struct type created with reflect.StructOf the name Entity.
+type namedEntity struct{ reflect.Type }
+
+func (namedEntity) Name() string { return "Entity" }
+
+func TestGenerate_IsValidGeneratedReportsFieldErrors(t *testing.T) {
+	src := generateFor(t, indices.Entry{
+		EntityType: reflect.TypeFor[fieldtypes.Account](),
+		Index: index.PrimaryIndex[fieldtypes.Account]{
+			Table:        linkTable,
+			PartitionKey: val.Fmt("ACCOUNT#{id}"),
+			SortKey:      val.Fmt("ACCOUNT").Ptr(),
+		},
+	})
+	typeCheck(t, src)
+	if t.Failed() {
+		return
+	}
+	if testing.Short() {
+		t.Skip("runs go test on the generated code")
+	}
+
+	// Run the generated method in the testdata package, printing the errors it returns.
+	dir, err := filepath.Abs(filepath.Join("testdata", "fieldtypes"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmp := t.TempDir()
+	files := map[string]string{
+		filepath.Join(dir, "index_gen.go"):       src,
+		filepath.Join(dir, "validation_test.go"): accountValidationTest,
+	}
+	overlay := map[string]map[string]string{"Replace": {}}
+	for path, content := range files {
+		name := filepath.Join(tmp, filepath.Base(path))
+		if err := os.WriteFile(name, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		overlay["Replace"][path] = name
+	}
+	overlayJSON, err := json.Marshal(overlay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	overlayFile := filepath.Join(tmp, "overlay.json")
+	if err := os.WriteFile(overlayFile, overlayJSON, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command("go", "test", "-overlay", overlayFile, "-run", "^TestAccountFieldErrors$", "-count=1", "-v", ".")
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("go test: %v\n%s", err, out)
+	}
+
+	got := make(map[string][]string)
+	for _, line := range strings.Split(string(out), "\n") {
+		line = strings.TrimSpace(line)
+		name, errs, ok := strings.Cut(line, ":")
+		if !ok || !strings.HasPrefix(name, "account ") {
+			continue
+		}
+		got[strings.TrimPrefix(name, "account ")] = strings.Fields(errs)
+	}
+	want := map[string][]string{
+		"valid": nil,
+		"empty": {"id/required", "seats/min", "nickname/required", "createdAt/required", "expiresAt/gtfield"},
+		"invalid": {
+			"id/maxlen", "email/regex", "plan/enum", "seats/max", "maxSeats/gtefield",
+			"nickname/minlen", "tags/maxlen", "expiresAt/gtfield",
+		},
+	}
+	for name, w := range want {
+		g, ok := got[name]
+		if !ok {
+			t.Errorf("no output for account %s:\n%s", name, out)
+			continue
+		}
+		if !slices.Equal(g, w) {
+			t.Errorf("account %s: got field errors %v, want %v", name, g, w)
+		}
+	}
+}
+
+// accountValidationTest prints the field errors of fieldtypes.Account values as
+// "account <name>: <field>/<rule>...".
+const accountValidationTest = `package fieldtypes
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/acksell/bezos/dynamodb/ddbsdk"
+)
+
+func TestAccountFieldErrors(t *testing.T) {
+	nick, short := "nick", "n"
+	now := time.Now()
+	accounts := []struct {
+		name    string
+		account Account
+	}{
+		{"valid", Account{ID: "a1", Email: "a@b.c", Plan: "pro", Seats: 2, MaxSeats: 2, Nickname: &nick, CreatedAt: now, ExpiresAt: now.Add(time.Hour)}},
+		{"empty", Account{}},
+		{"invalid", Account{ID: "too-long-id", Email: "nope", Plan: "gold", Seats: 11, MaxSeats: 3, Nickname: &short, Tags: []string{"a", "b", "c"}, CreatedAt: now, ExpiresAt: now}},
+	}
+	for _, a := range accounts {
+		var rules []string
+		if err := a.account.IsValidGenerated(); err != nil {
+			for _, e := range err.(interface{ Unwrap() []error }).Unwrap() {
+				var fe *ddbsdk.FieldError
+				if !errors.As(e, &fe) {
+					t.Fatalf("not a field error: %v", e)
+				}
+				rules = append(rules, fe.Field+"/"+fe.Rule)
+			}
+		}
+		fmt.Printf("account %s: %s\n", a.name, strings.Join(rules, " "))
+	}
+}
+`
@@ -0,0 +1,174 @@
+package ddbsdk
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/acksell/bezos/dynamodb/table"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// CauseAttr is the attribute that puts and updates of a transaction with [WithCause]
+// stamp the cause onto, so that every item shows the command or event that last wrote it.
+const CauseAttr = "ddbCause"
+
+// AuditTable stores an entry for every item written by a transaction with [WithAuditLog].
+// Create it along with your other tables.
+//
+// Entries are partitioned by the written item and sorted by time, see [QueryAuditLog].
+var AuditTable = table.TableDefinition{
+	Name: "bezos_audit",
+	KeyDefinitions: table.PrimaryKeyDefinition{
+		PartitionKey: table.KeyDef{Name: "itemKey", Kind: table.KeyKindS},
+		SortKey:      table.KeyDef{Name: "writeID", Kind: table.KeyKindS},
+	},
+}
+
+// AuditOp is the kind of write an [AuditEntry] records.
+type AuditOp string
+
+const (
+	AuditOpPut    AuditOp = "put"
+	AuditOpUpdate AuditOp = "update"
+	AuditOpDelete AuditOp = "delete"
+)
+
+// AuditEntry is an item of the [AuditTable].
+type AuditEntry struct {
+	// Item identifies the written item, see [AuditItemKey].
+	Item string `dynamodbav:"itemKey"`
+	// WriteID sorts the entries of an item by time.
+	WriteID string    `dynamodbav:"writeID"`
+	Time    time.Time `dynamodbav:"time"`
+	Table   string    `dynamodbav:"table"`
+	Op      AuditOp   `dynamodbav:"op"`
+	// Cause is the transaction's [WithCause] label, if any.
+	Cause string `dynamodbav:"cause,omitempty"`
+}
+
+func (e *AuditEntry) IsValid() error {
+	return nil
+}
+
+// AuditItemKey returns the partition key of the audit entries of an item,
+// e.g. "users#USER#1#PROFILE".
+func AuditItemKey(tableName string, key table.PrimaryKey) string {
+	s := fmt.Sprintf("%s#%v", tableName, key.Values.PartitionKey)
+	if key.Definition.SortKey.Name != "" {
+		s += fmt.Sprintf("#%v", key.Values.SortKey)
+	}
+	return s
+}
+
+// QueryAuditLog returns the audit entries of an item, oldest first.
+func QueryAuditLog(ctx context.Context, db IO, t table.TableDefinition, key table.PrimaryKey) ([]AuditEntry, error) {
+	res, err := db.NewQuery(QueryPartition(AuditTable, AuditItemKey(t.Name, key))).QueryAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("querying audit log: %w", err)
+	}
+	entries := make([]AuditEntry, 0, len(res.Items))
+	for _, item := range res.Items {
+		var e AuditEntry
+		if err := attributevalue.UnmarshalMap(item, &e); err != nil {
+			return nil, fmt.Errorf("decoding audit entry: %w", err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// causeName and causeValue are the placeholders of the cause in update expressions.
+// The expression builder numbers its placeholders, #0 and :0, so they don't collide.
+const (
+	causeName  = "#ddbCause"
+	causeValue = ":ddbCause"
+)
+
+// stampCause sets the CauseAttr of the item written by a put, or changed by an update,
+// in a built request. The actions themselves are left alone, so an action reused in
+// another transaction or written directly doesn't carry the cause along.
+func stampCause(twi *types.TransactWriteItem, cause string) {
+	switch {
+	case twi.Put != nil:
+		stampItem(twi.Put.Item, cause)
+	case twi.Update != nil:
+		u := twi.Update
+		u.UpdateExpression, u.ExpressionAttributeNames, u.ExpressionAttributeValues = stampUpdate(
+			u.UpdateExpression, u.ExpressionAttributeNames, u.ExpressionAttributeValues, cause)
+	}
+}
+
+// stampItem sets the cause on an item, which puts marshal anew for every request.
+func stampItem(item map[string]types.AttributeValue, cause string) {
+	item[CauseAttr] = &types.AttributeValueMemberS{Value: cause}
+}
+
+// stampUpdate adds setting the cause to the SET clause of an update expression.
+func stampUpdate(expr *string, names map[string]string, values map[string]types.AttributeValue, cause string) (*string, map[string]string, map[string]types.AttributeValue) {
+	if names == nil {
+		names = make(map[string]string)
+	}
+	if values == nil {
+		values = make(map[string]types.AttributeValue)
+	}
+	names[causeName] = CauseAttr
+	values[causeValue] = &types.AttributeValueMemberS{Value: cause}
+
+	set := causeName + " = " + causeValue
+	var clauses []string
+	if expr != nil && *expr != "" {
+		clauses = strings.Split(*expr, "\n")
+	}
+	found := false
+	for i, c := range clauses {
+		if rest, ok := strings.CutPrefix(c, "SET "); ok {
+			clauses[i] = "SET " + set + ", " + rest
+			found = true
+			break
+		}
+	}
+	if !found {
+		clauses = append([]string{"SET " + set}, clauses...)
+	}
+	out := strings.Join(clauses, "\n")
+	return &out, names, values
+}
+
+// auditActions returns the audit entries of the writes among actions. Condition
+// checks don't write, and the outbox records of the transaction are left out.
+func auditActions(actions map[actionKey]Action, cause string, now time.Time) []Action {
+	var entries []Action
+	for key, a := range actions {
+		var op AuditOp
+		switch a.(type) {
+		case PutItemAction:
+			op = AuditOpPut
+		case UpdateItemAction:
+			op = AuditOpUpdate
+		case DeleteItemAction:
+			op = AuditOpDelete
+		default:
+			continue
+		}
+		if key.tableName == OutboxTable.Name {
+			continue
+		}
+		e := &AuditEntry{
+			Item:    AuditItemKey(key.tableName, key.primaryKey),
+			WriteID: newSortableID(now),
+			Time:    now,
+			Table:   key.tableName,
+			Op:      op,
+			Cause:   cause,
+		}
+		entries = append(entries, NewUnsafePut(AuditTable, table.PrimaryKey{
+			Definition: AuditTable.KeyDefinitions,
+			Values:     table.PrimaryKeyValues{PartitionKey: e.Item, SortKey: e.WriteID},
+		}, e))
+	}
+	return entries
+}
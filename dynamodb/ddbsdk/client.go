@@ -6,9 +6,37 @@ import (
 	"github.com/acksell/bezos/dynamodb/table"
 )
 
-func NewClient(awsddb ddbiface.ReadWriteClient) *Client {
+// NewClient creates a client for the given DynamoDB API.
+// Every request is retried according to [DefaultRetryPolicy] unless configured otherwise with [WithRetryPolicy].
+// Turn off the retries of the AWS SDK client, see [RetryPolicy].
+func NewClient(awsddb ddbiface.ReadWriteClient, opts ...ClientOption) *Client {
+	o := clientOpts{
+		retryPolicy: DefaultRetryPolicy,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.observer != nil {
+		awsddb = &statsClient{ddb: awsddb}
+	}
 	return &Client{
-		awsddb: awsddb,
+		awsddb:   NewRetryClient(awsddb, o.retryPolicy),
+		observer: o.observer,
+	}
+}
+
+type ClientOption func(*clientOpts)
+
+type clientOpts struct {
+	retryPolicy RetryPolicy
+	observer    Observer
+}
+
+// WithRetryPolicy sets the retry policy for all operations of the client.
+// Override it per call with [ContextWithRetryPolicy].
+func WithRetryPolicy(p RetryPolicy) ClientOption {
+	return func(o *clientOpts) {
+		o.retryPolicy = p
 	}
 }
 
@@ -29,19 +57,34 @@ func NewDiskClient(path string, defs ...table.TableDefinition) *Client {
 }
 
 type Client struct {
-	awsddb ddbiface.ReadWriteClient
+	awsddb   ddbiface.ReadWriteClient
+	observer Observer
 }
 
 var _ IO = &Client{}
 
 // NewTx creates a new transaction. Add actions and commit the transaction.
 func (c *Client) NewTx(opts ...TxOption) Txer {
-	return NewTx(c.awsddb, opts...)
+	tx := NewTx(c.awsddb, opts...)
+	if c.observer != nil {
+		return &observedTxer{Txer: tx, observer: c.observer}
+	}
+	return tx
 }
 
 // NewBatch creates a new write-batch. Add actions and execute the batch writes.
 func (c *Client) NewBatch(opts ...BatchOption) Batcher {
-	return NewBatcher(c.awsddb, opts...)
+	b := NewBatcher(c.awsddb, opts...)
+	if c.observer != nil {
+		return &observedBatcher{Batcher: b, observer: c.observer}
+	}
+	return b
+}
+
+// NewBulkWriter creates a non-atomic writer for large sets of mixed actions.
+// See [NewBulkWriter] for how actions are chunked and what guarantees are given.
+func (c *Client) NewBulkWriter(opts ...BulkOption) BulkWriter {
+	return NewBulkWriter(c.awsddb, opts...)
 }
 
 // NewQuery creates a new querier.
@@ -50,7 +93,9 @@ func (c *Client) NewBatch(opts ...BatchOption) Batcher {
 //
 // See [QueryBuilder] for how to build queries.
 func (c *Client) NewQuery(qb QueryBuilder) *Querier {
-	return NewQuerier(c.awsddb, qb)
+	q := NewQuerier(c.awsddb, qb)
+	q.observer = c.observer
+	return q
 }
 
 // NewLookup creates a new getter for direct lookups by primary key.
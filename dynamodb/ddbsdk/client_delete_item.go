@@ -12,7 +12,9 @@ func (c *Client) DeleteItem(ctx context.Context, d DeleteItemAction) error {
 	if err != nil {
 		return fmt.Errorf("failed to convert delete to delete item: %w", err)
 	}
+	ctx, done := observe(ctx, c.observer, singleItemOp("DeleteItem", del.TableName, d))
 	_, err = c.awsddb.DeleteItem(ctx, del)
+	done(err, 0)
 	if err != nil {
 		return fmt.Errorf("failed to delete item: %w", err)
 	}
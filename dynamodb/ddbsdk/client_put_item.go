@@ -12,7 +12,9 @@ func (c *Client) PutItem(ctx context.Context, p PutItemAction) error {
 	if err != nil {
 		return fmt.Errorf("failed to convert put to put item: %w", err)
 	}
+	ctx, done := observe(ctx, c.observer, singleItemOp("PutItem", put.TableName, p))
 	_, err = c.awsddb.PutItem(ctx, put)
+	done(err, 0)
 	if err != nil {
 		return fmt.Errorf("failed to put item: %w", err)
 	}
@@ -5,6 +5,9 @@ import (
 	"testing"
 	"time"
 
+	"github.com/acksell/bezos/dynamodb/index"
+	"github.com/acksell/bezos/dynamodb/index/indices"
+	"github.com/acksell/bezos/dynamodb/index/val"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
 )
@@ -150,3 +153,51 @@ func TestClient_SafePut_OptimisticLocking(t *testing.T) {
 		t.Fatal("expected error when old version doesn't match current")
 	}
 }
+
+func TestClient_PutItem_EntityType(t *testing.T) {
+	indices.Clear()
+	defer indices.Clear()
+
+	tbl := clientTestTable
+	tbl.EntityTypeKey = "type"
+	db := NewMemoryClient(tbl)
+	ctx := context.Background()
+	pk := testKey("user#1", "profile")
+
+	if err := db.PutItem(ctx, NewUnsafePut(tbl, pk, &testEntity{PK: "user#1", SK: "profile", Name: "Alice"})); err != nil {
+		t.Fatalf("PutItem failed: %v", err)
+	}
+	item, err := db.NewLookup().GetItem(ctx, GetItemRequest{Table: tbl, Key: pk})
+	if err != nil {
+		t.Fatalf("GetItem failed: %v", err)
+	}
+	if got := stringAttr(item, "type"); got != "testEntity" {
+		t.Errorf("type = %q, want the Go type name", got)
+	}
+	if got := EntityTypeOf(tbl, item); got != "testEntity" {
+		t.Errorf("EntityTypeOf() = %q, want %q", got, "testEntity")
+	}
+	e, err := DecodeEntity[testEntity](item)
+	if err != nil || e.Name != "Alice" {
+		t.Errorf("DecodeEntity() = %+v, %v", e, err)
+	}
+
+	// A registered index can name the type, and items without the attribute are matched by key.
+	indices.Add(index.PrimaryIndex[testEntity]{
+		Table:        tbl,
+		PartitionKey: val.Fmt("user#{pk}"),
+		SortKey:      val.Fmt("profile").Ptr(),
+		EntityType:   "User",
+	})
+	_, built, err := NewUnsafePut(tbl, pk, &testEntity{PK: "user#1", SK: "profile"}).Build()
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	if got := stringAttr(built, "type"); got != "User" {
+		t.Errorf("type = %q, want %q", got, "User")
+	}
+	delete(item, "type")
+	if got := EntityTypeOf(tbl, item); got != "User" {
+		t.Errorf("EntityTypeOf() without attribute = %q, want %q", got, "User")
+	}
+}
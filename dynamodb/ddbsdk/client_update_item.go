@@ -12,7 +12,9 @@ func (c *Client) UpdateItem(ctx context.Context, u UpdateItemAction) error {
 	if err != nil {
 		return fmt.Errorf("failed to convert update to update item: %w", err)
 	}
+	ctx, done := observe(ctx, c.observer, singleItemOp("UpdateItem", update.TableName, u))
 	_, err = c.awsddb.UpdateItem(ctx, update)
+	done(err, 0)
 	if err != nil {
 		return fmt.Errorf("failed to update item: %w", err)
 	}
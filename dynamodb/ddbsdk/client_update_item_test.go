@@ -5,8 +5,12 @@ import (
 	"testing"
 	"time"
 
+	"github.com/acksell/bezos/dynamodb/index"
+	"github.com/acksell/bezos/dynamodb/index/val"
+	"github.com/acksell/bezos/dynamodb/table"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 )
 
 func TestClient_UpdateItem_Basic(t *testing.T) {
@@ -143,3 +147,160 @@ func TestClient_UpdateItem_RefreshTTL(t *testing.T) {
 		t.Error("expected TTL field to be set")
 	}
 }
+
+var derivedKeysTestIndex = index.PrimaryIndex[testEntity]{
+	Table:        clientTestTable,
+	PartitionKey: val.FromField("pk"),
+	SortKey:      val.FromField("sk").Ptr(),
+	Secondary: []index.SecondaryIndex{{
+		GSI:       clientTestTable.GSIs[0],
+		Partition: val.Fmt("EMAIL#{email}"),
+		Sort:      val.Fmt("{name}#{age:%03d}").Ptr(),
+	}},
+}
+
+func TestClient_UpdateItem_DerivedKeys(t *testing.T) {
+	db := NewMemoryClient(clientTestTable)
+	ctx := context.Background()
+	pk := testKey("user#1", "profile")
+	keys := derivedKeysTestIndex.DerivedKeys()
+
+	entity := &testEntity{PK: "user#1", SK: "profile", Name: "Alice", Email: "old@example.com", Age: 30}
+	if err := db.PutItem(ctx, NewUnsafePut(clientTestTable, pk, entity)); err != nil {
+		t.Fatalf("PutItem failed: %v", err)
+	}
+
+	update := NewUnsafeUpdate(clientTestTable, pk).
+		AddOp(SetFieldOp("email", "new@example.com")).
+		AddOp(SetFieldOp("name", "Bob")).
+		WithDerivedKeys(keys, map[string]any{"age": 30})
+	if err := db.UpdateItem(ctx, update); err != nil {
+		t.Fatalf("UpdateItem failed: %v", err)
+	}
+
+	item := getTestItem(t, db, pk)
+	if got := stringAttr(item, "gsi1pk"); got != "EMAIL#new@example.com" {
+		t.Errorf("gsi1pk = %q, want %q", got, "EMAIL#new@example.com")
+	}
+	if got := stringAttr(item, "gsi1sk"); got != "Bob#030" {
+		t.Errorf("gsi1sk = %q, want %q", got, "Bob#030")
+	}
+
+	// Removing a referenced field removes the derived key.
+	update = NewUnsafeUpdate(clientTestTable, pk).
+		AddOp(RemoveFieldOp("email")).
+		WithDerivedKeys(keys, nil)
+	if err := db.UpdateItem(ctx, update); err != nil {
+		t.Fatalf("UpdateItem failed: %v", err)
+	}
+	item = getTestItem(t, db, pk)
+	if _, ok := item["gsi1pk"]; ok {
+		t.Error("expected gsi1pk to be removed")
+	}
+	if got := stringAttr(item, "gsi1sk"); got != "Bob#030" {
+		t.Errorf("gsi1sk = %q, want it unchanged", got)
+	}
+}
+
+func TestClient_UpdateItem_DerivedKeys_Errors(t *testing.T) {
+	keys := derivedKeysTestIndex.DerivedKeys()
+	pk := testKey("user#1", "profile")
+
+	tests := []struct {
+		name    string
+		update  *UnsafeUpdate
+		wantErr string
+	}{
+		{
+			name:    "missing referenced field",
+			update:  NewUnsafeUpdate(clientTestTable, pk).AddOp(SetFieldOp("name", "Bob")).WithDerivedKeys(keys, nil),
+			wantErr: "does not set [age]",
+		},
+		{
+			name:    "primary key field",
+			update:  NewUnsafeUpdate(clientTestTable, pk).AddOp(SetFieldOp("sk", "other")).WithDerivedKeys(keys, nil),
+			wantErr: "primary key",
+		},
+		{
+			name: "non-set op on referenced field",
+			update: NewUnsafeUpdate(clientTestTable, pk).AddOp(AddNumberOp("age", 1)).
+				WithAccidentalIdempotency().WithDerivedKeys(keys, map[string]any{"name": "Bob"}),
+			wantErr: "use SetFieldOp",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.update.Build()
+			if err == nil || !contains(err.Error(), tt.wantErr) {
+				t.Errorf("Build() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestClient_UpdateItem_DerivedKeys_SparseGSI(t *testing.T) {
+	db := NewMemoryClient(clientTestTable)
+	ctx := context.Background()
+	pk := testKey("user#1", "profile")
+	sparse := index.PrimaryIndex[testEntity]{
+		Table:        clientTestTable,
+		PartitionKey: val.FromField("pk"),
+		SortKey:      val.FromField("sk").Ptr(),
+		Secondary: []index.SecondaryIndex{{
+			GSI:       clientTestTable.GSIs[0],
+			Partition: val.Fmt("EMAIL#{email}"),
+			Include:   index.WhenEquals("active", true),
+		}},
+	}
+	keys := sparse.DerivedKeys()
+
+	entity := &testEntity{PK: "user#1", SK: "profile", Email: "a@example.com"}
+	if err := db.PutItem(ctx, NewUnsafePut(clientTestTable, pk, entity)); err != nil {
+		t.Fatalf("PutItem failed: %v", err)
+	}
+
+	update := NewUnsafeUpdate(clientTestTable, pk).
+		AddOp(SetFieldOp("active", true)).
+		WithDerivedKeys(keys, map[string]any{"email": "a@example.com"})
+	if err := db.UpdateItem(ctx, update); err != nil {
+		t.Fatalf("UpdateItem failed: %v", err)
+	}
+	if got := stringAttr(getTestItem(t, db, pk), "gsi1pk"); got != "EMAIL#a@example.com" {
+		t.Errorf("gsi1pk = %q, want %q", got, "EMAIL#a@example.com")
+	}
+
+	// Deactivating drops the item from the GSI, without needing the key's fields.
+	update = NewUnsafeUpdate(clientTestTable, pk).
+		AddOp(SetFieldOp("active", false)).
+		WithDerivedKeys(keys, nil)
+	if err := db.UpdateItem(ctx, update); err != nil {
+		t.Fatalf("UpdateItem failed: %v", err)
+	}
+	if _, ok := getTestItem(t, db, pk)["gsi1pk"]; ok {
+		t.Error("expected gsi1pk to be removed")
+	}
+
+	// Changing a key field needs the rule's field to know whether to write the key.
+	_, err := NewUnsafeUpdate(clientTestTable, pk).
+		AddOp(SetFieldOp("email", "b@example.com")).
+		WithDerivedKeys(keys, nil).Build()
+	if err == nil || !contains(err.Error(), `does not set "active"`) {
+		t.Errorf("Build() error = %v, want missing rule field", err)
+	}
+}
+
+func getTestItem(t *testing.T, db *Client, pk table.PrimaryKey) Item {
+	t.Helper()
+	item, err := db.NewLookup().GetItem(context.Background(), GetItemRequest{Table: clientTestTable, Key: pk})
+	if err != nil {
+		t.Fatalf("GetItem failed: %v", err)
+	}
+	return item
+}
+
+func stringAttr(item Item, name string) string {
+	if s, ok := item[name].(*types.AttributeValueMemberS); ok {
+		return s.Value
+	}
+	return ""
+}
@@ -68,6 +68,16 @@ func (p *Put) Build() (expression2.Expression, map[string]types.AttributeValue,
 		}
 		entity[k] = v
 	}
+	if p.Table.EntityTypeKey != "" {
+		et := &types.AttributeValueMemberS{Value: entityTypeName(p.Entity)}
+		if val, exists := entity[p.Table.EntityTypeKey]; exists && !isAttrEqual(val, et) {
+			return expression2.Expression{}, nil, fmt.Errorf(
+				"entity type attribute %q already exists in entity with a different value, got %#v vs %q",
+				p.Table.EntityTypeKey, val, et.Value,
+			)
+		}
+		entity[p.Table.EntityTypeKey] = et
+	}
 	if p.ttlExpiry != nil {
 		entity[p.Table.TimeToLiveKey] = ttlDDB(*p.ttlExpiry)
 	}
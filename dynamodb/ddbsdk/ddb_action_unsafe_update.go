@@ -2,8 +2,10 @@ package ddbsdk
 
 import (
 	"fmt"
+	"slices"
 	"time"
 
+	"github.com/acksell/bezos/dynamodb/index"
 	"github.com/acksell/bezos/dynamodb/table"
 
 	expression2 "github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
@@ -89,7 +91,124 @@ func (u *UnsafeUpdate) WithAccidentalIdempotency() *UnsafeUpdate {
 	return u
 }
 
+// WithDerivedKeys keeps key attributes that are derived from entity fields consistent
+// with the update. Generated UnsafeUpdate methods call this with the index's keys.
+//
+// When a SetFieldOp changes a field that a GSI key is derived from, the key is recomputed
+// and set as well. This needs every field the key references, taken from the update's
+// SetFieldOps or from known, keyed by field path. When a RemoveFieldOp removes such a
+// field, the GSI key attribute is removed too. Build fails if a referenced field is
+// missing, if another kind of op changes it, or if the update changes a field of the
+// table's primary key.
+//
+// For a sparse GSI (see [index.Inclusion]) the key attributes are removed when the
+// updated fields no longer match its inclusion rule, which also has to be known to the update.
+func (u *UnsafeUpdate) WithDerivedKeys(keys []index.DerivedKey, known map[string]any) *UnsafeUpdate {
+	u.derivedKeys = keys
+	u.knownFields = known
+	return u
+}
+
+// valueSetter is implemented by update ops that set a field to a known value.
+type valueSetter interface {
+	setValue() any
+}
+
+// applyDerivedKeys adds the derived key attributes affected by the update's ops.
+func (u *UnsafeUpdate) applyDerivedKeys() error {
+	for _, k := range u.derivedKeys {
+		paths := k.ValDef.FieldPaths()
+		rulePaths := paths
+		if k.Include != nil && k.Include.Field != "" {
+			rulePaths = append(paths[:len(paths):len(paths)], k.Include.Field)
+		}
+		if !slices.ContainsFunc(rulePaths, func(p string) bool { _, ok := u.Fields[p]; return ok }) {
+			continue
+		}
+		if k.IsPrimary() {
+			return fmt.Errorf("update modifies a field of primary key attribute %q (%v), write a new item instead", k.KeyDef.Name, paths)
+		}
+		if k.Include != nil {
+			included, err := u.included(k)
+			if err != nil {
+				return err
+			}
+			if !included {
+				u.u = u.u.Remove(expression2.Name(k.KeyDef.Name))
+				continue
+			}
+		}
+		values := make(map[string]any, len(paths))
+		var missing []string
+		removed := false
+		for _, p := range paths {
+			v, set, err := u.fieldValue(k, p)
+			switch {
+			case err != nil:
+				return err
+			case !set:
+				missing = append(missing, p)
+			case v == nil:
+				removed = true
+			default:
+				values[p] = v
+			}
+		}
+		if removed {
+			u.u = u.u.Remove(expression2.Name(k.KeyDef.Name))
+			continue
+		}
+		if len(missing) > 0 {
+			return fmt.Errorf("key attribute %q of GSI %s is derived from %v, but the update does not set %v", k.KeyDef.Name, k.Index, paths, missing)
+		}
+		v, err := k.ValDef.Value(values)
+		if err != nil {
+			return fmt.Errorf("key attribute %q of GSI %s: %w", k.KeyDef.Name, k.Index, err)
+		}
+		if s, ok := v.(string); ok && k.KeyDef.Kind == table.KeyKindN {
+			v = &types.AttributeValueMemberN{Value: s}
+		}
+		u.u = u.u.Set(expression2.Name(k.KeyDef.Name), expression2.Value(v))
+	}
+	return nil
+}
+
+// fieldValue returns the value of the field at path after the update, from its op or the
+// known fields. A removed field has a nil value, set is false if the value is unknown.
+func (u *UnsafeUpdate) fieldValue(k index.DerivedKey, path string) (v any, set bool, err error) {
+	op, ok := u.Fields[path]
+	if !ok {
+		v, ok := u.knownFields[path]
+		return v, ok, nil
+	}
+	switch op := op.(type) {
+	case removeFieldOp:
+		return nil, true, nil
+	case valueSetter:
+		return op.setValue(), true, nil
+	}
+	return nil, false, fmt.Errorf("%T on field %q can't be used to derive key attribute %q of GSI %s, use SetFieldOp", op, path, k.KeyDef.Name, k.Index)
+}
+
+// included evaluates the inclusion rule of a sparse GSI key against the updated fields.
+func (u *UnsafeUpdate) included(k index.DerivedKey) (bool, error) {
+	if k.Include.Kind == index.IncludeWhenFunc {
+		return false, fmt.Errorf("GSI %s has a custom inclusion rule (%s) that a partial update can't evaluate, use a Put", k.Index, k.Include)
+	}
+	v, set, err := u.fieldValue(k, k.Include.Field)
+	if err != nil {
+		return false, err
+	}
+	if !set {
+		return false, fmt.Errorf("GSI %s includes items when %s, but the update does not set %q", k.Index, k.Include, k.Include.Field)
+	}
+	return k.Include.IncludesValue(v), nil
+}
+
 func (u *UnsafeUpdate) Build() (expression2.Expression, error) {
+	if err := u.applyDerivedKeys(); err != nil {
+		return expression2.Expression{}, err
+	}
 	if u.ttlExpiry != nil {
 		// todo inject time.Now dependency instead
 		u.u = u.u.Set(expression2.Name(u.Table.TimeToLiveKey), expression2.Value(ttlDDB(*u.ttlExpiry)))
@@ -41,6 +41,10 @@ func (o setFieldOp[T]) IsIdempotent() bool {
 	return true
 }
 
+func (o setFieldOp[T]) setValue() any {
+	return o.value
+}
+
 func (o setFieldOp[T]) Apply(expr expression.UpdateBuilder) expression.UpdateBuilder {
 	return expr.Set(expression.Name(o.field), expression.Value(o.value))
 }
@@ -3,6 +3,7 @@ package ddbsdk
 import (
 	"time"
 
+	"github.com/acksell/bezos/dynamodb/index"
 	"github.com/acksell/bezos/dynamodb/table"
 
 	expression2 "github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
@@ -81,6 +82,9 @@ type UnsafeUpdate struct {
 	ttlExpiry          *time.Time
 	allowNonIdempotent bool
 
+	derivedKeys []index.DerivedKey
+	knownFields map[string]any
+
 	u expression2.UpdateBuilder
 	c expression2.ConditionBuilder
 }
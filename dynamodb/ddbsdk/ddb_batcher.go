@@ -12,18 +12,16 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 )
 
+// NewBatcher creates a batcher for Put and Delete actions. Unprocessed items are
+// retried by the [RetryPolicy] of the client, or [DefaultRetryPolicy] for a raw client.
 func NewBatcher(ddb ddbiface.ReadWriteClient, opts ...BatchOption) *batcher {
 	b := &batcher{
-		awsddb:  ddb,
+		awsddb:  withRetries(ddb),
 		pending: make(map[string][]types.WriteRequest),
 	}
 	for _, opt := range opts {
 		opt(&b.opts)
 	}
-	// Default exponential backoff: 50ms base, 2x multiplier, 5s cap, full jitter
-	if b.opts.backoff == nil {
-		b.opts.backoff = DefaultBackoff
-	}
 	return b
 }
 
@@ -44,41 +42,47 @@ var _ Batcher = &batcher{}
 // Note: Batch operations do not support conditions, nor Update actions.
 func (b *batcher) AddAction(actions ...BatchAction) {
 	for _, a := range actions {
-		tableName := *a.TableName()
-
-		var req types.WriteRequest
-		var err error
-		switch act := a.(type) {
-		case *Put:
-			req, err = act.ToBatchWriteRequest()
-		case *Delete:
-			req, err = act.ToBatchWriteRequest()
-		default:
-			b.errs = append(b.errs, fmt.Errorf("unsupported action type: %T", a))
-			continue
-		}
+		req, err := batchWriteRequest(a)
 		if err != nil {
 			b.errs = append(b.errs, err)
 			continue
 		}
-
-		// Check for duplicate key
-		newKey := extractKey(req)
-		for _, existing := range b.pending[tableName] {
-			existingKey := extractKey(existing)
-			if keysEqual(newKey, existingKey) {
-				b.errs = append(b.errs, fmt.Errorf("duplicate action for table %s", tableName))
-				break
-			}
+		if err := b.addRequest(*a.TableName(), req); err != nil {
+			b.errs = append(b.errs, err)
 		}
+	}
+}
+
+// batchWriteRequest converts a Put or Delete to a WriteRequest for BatchWriteItem.
+func batchWriteRequest(a BatchAction) (types.WriteRequest, error) {
+	switch act := a.(type) {
+	case *Put:
+		return act.ToBatchWriteRequest()
+	case *Delete:
+		return act.ToBatchWriteRequest()
+	}
+	return types.WriteRequest{}, fmt.Errorf("unsupported action type: %T", a)
+}
 
-		b.pending[tableName] = append(b.pending[tableName], req)
+// addRequest adds a request to the pending requests of a table, unless it
+// writes the same key as one of them.
+func (b *batcher) addRequest(tableName string, req types.WriteRequest) error {
+	newKey := extractKey(req)
+	for _, existing := range b.pending[tableName] {
+		if keysEqual(newKey, extractKey(existing)) {
+			return fmt.Errorf("duplicate action for table %s", tableName)
+		}
 	}
+	b.pending[tableName] = append(b.pending[tableName], req)
+	return nil
 }
 
 // ExecChunk sends the next batch of items (up to 25 per table).
 // Returns [ExecResult] with any remaining pending items for the next chunk.
 // Most callers should use [ExecAll] instead.
+//
+// Unprocessed items of the chunk are retried according to the [RetryPolicy]. Items the
+// policy gave up on are put back into the pending items and reported as an error.
 func (b *batcher) ExecChunk(ctx context.Context) (ExecResult, error) {
 	if len(b.errs) > 0 {
 		return ExecResult{Unprocessed: b.pending, Retries: b.retries}, errors.Join(b.errs...)
@@ -86,6 +90,7 @@ func (b *batcher) ExecChunk(ctx context.Context) (ExecResult, error) {
 	if len(b.pending) == 0 {
 		return ExecResult{Retries: b.retries}, nil
 	}
+	ctx = b.opts.withPolicy(ctx, b.awsddb)
 
 	// Chunk to 25 items per table (DynamoDB batch limit)
 	toBatch := make(map[string][]types.WriteRequest)
@@ -118,29 +123,27 @@ func (b *batcher) ExecChunk(ctx context.Context) (ExecResult, error) {
 
 	b.retries++
 
-	return ExecResult{
+	result := ExecResult{
 		Unprocessed: b.pending,
 		Retries:     b.retries,
-	}, nil
+	}
+	if n := countRequests(res.UnprocessedItems); n > 0 {
+		return result, fmt.Errorf("batch write incomplete: %d items unprocessed after retries", n)
+	}
+	return result, nil
 }
 
-// ExecAll writes all pending items, chunking as needed and retrying until complete or limits exceeded.
-// At least one of [WithMaxRetries] or [WithTimeout] must be configured.
-// Uses exponential backoff by default (50ms, 100ms, 200ms, ...), override with [WithCustomBackoff].
+// ExecAll writes all pending items, chunking as needed. Unprocessed items are retried
+// according to the [RetryPolicy], see [ExecChunk], optionally limited by [WithTimeout].
 //
 // Example:
 //
-//	batch := client.NewBatch(
-//		ddbsdk.WithMaxRetries(5),
-//	    ddbsdk.WithTimeout(5*time.Second))
+//	batch := client.NewBatch(ddbsdk.WithTimeout(5 * time.Second))
 //	batch.AddAction(putUser, putOrder, deleteOldItem)
 //	if err := batch.ExecAll(ctx); err != nil {
 //	    return err
 //	}
 func (b *batcher) ExecAll(ctx context.Context) error {
-	if b.opts.maxRetries == 0 && b.opts.timeout == 0 {
-		return fmt.Errorf("ExecAll requires WithMaxRetries or WithTimeout to be configured")
-	}
 	if b.opts.timeout > 0 {
 		var cancel context.CancelFunc
 		ctx, cancel = context.WithTimeout(ctx, b.opts.timeout)
@@ -154,16 +157,6 @@ func (b *batcher) ExecAll(ctx context.Context) error {
 		if res.Done() {
 			return nil
 		}
-		if b.opts.maxRetries > 0 && res.Retries >= b.opts.maxRetries {
-			return fmt.Errorf("max retries (%d) exceeded: %d items unprocessed", b.opts.maxRetries, countRequests(b.pending))
-		}
-		if b.opts.backoff != nil {
-			select {
-			case <-ctx.Done():
-				return ctx.Err()
-			case <-time.After(b.opts.backoff(res.Retries)):
-			}
-		}
 	}
 }
 
@@ -243,29 +236,30 @@ type BatchOption func(*batchOpts)
 // BackoffFunc returns the duration to wait before retry attempt n.
 type BackoffFunc func(attempt int) time.Duration
 
-// WithMaxRetries sets the maximum number of retry attempts for [ExecAll].
+// WithMaxRetries overrides the MaxAttempts of the [RetryPolicy] for the batch's requests,
+// making up to n retries.
 func WithMaxRetries(n int) BatchOption {
 	return func(o *batchOpts) {
-		o.maxRetries = n
+		o.maxAttempts = n + 1
 	}
 }
 
-// WithTimeout sets a timeout for [ExecAndRetry].
+// WithTimeout sets a timeout for [ExecAll].
 func WithTimeout(d time.Duration) BatchOption {
 	return func(o *batchOpts) {
 		o.timeout = d
 	}
 }
 
-// WithBackoff sets a custom backoff function for [ExecAndRetry].
+// WithCustomBackoff overrides the Backoff of the [RetryPolicy] for the batch's requests.
 func WithCustomBackoff(fn BackoffFunc) BatchOption {
 	return func(o *batchOpts) {
 		o.backoff = fn
 	}
 }
 
-// WithExponentialBackoff sets exponential backoff for [ExecAndRetry].
-// See [ExponentialBackoff] for details.
+// WithExponentialBackoff overrides the Backoff of the [RetryPolicy] for the batch's
+// requests with an [ExponentialBackoff].
 func WithExponentialBackoff(base time.Duration, multiplier float64, cap time.Duration) BatchOption {
 	return WithCustomBackoff(ExponentialBackoff(base, multiplier, cap))
 }
@@ -292,7 +286,24 @@ func ExponentialBackoff(base time.Duration, multiplier float64, cap time.Duratio
 var DefaultBackoff = ExponentialBackoff(50*time.Millisecond, 2.0, 5*time.Second)
 
 type batchOpts struct {
-	maxRetries int
-	timeout    time.Duration
-	backoff    BackoffFunc
+	// maxAttempts and backoff override the retry policy of the client if set.
+	maxAttempts int
+	backoff     BackoffFunc
+	timeout     time.Duration
+}
+
+// withPolicy returns ctx with the retry policy of ddb, with the batch's overrides.
+func (o batchOpts) withPolicy(ctx context.Context, ddb ddbiface.ReadWriteClient) context.Context {
+	rc, ok := ddb.(*retryClient)
+	if !ok || (o.maxAttempts == 0 && o.backoff == nil) {
+		return ctx
+	}
+	p := rc.policyFor(ctx)
+	if o.maxAttempts > 0 {
+		p.MaxAttempts = o.maxAttempts
+	}
+	if o.backoff != nil {
+		p.Backoff = o.backoff
+	}
+	return ContextWithRetryPolicy(ctx, p)
 }
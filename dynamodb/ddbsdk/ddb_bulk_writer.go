@@ -0,0 +1,270 @@
+package ddbsdk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/acksell/bezos/dynamodb/ddbiface"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+const (
+	// maxBatchWriteItems is the DynamoDB limit of requests per BatchWriteItem call, across all tables.
+	maxBatchWriteItems = 25
+	// maxTransactWriteItems is the DynamoDB limit of actions per TransactWriteItems call.
+	maxTransactWriteItems = 100
+)
+
+// NewBulkWriter creates a writer for large, mixed sets of actions.
+//
+// Unconditional Put and Delete actions are grouped into BatchWriteItem chunks of 25,
+// everything else (conditional puts/deletes, updates, condition checks) into
+// TransactWriteItems chunks of up to 100. Chunks run concurrently, see [WithConcurrency].
+// Requests and unprocessed items are retried by the [RetryPolicy] of the client, or
+// [DefaultRetryPolicy] for a raw client.
+//
+// The bulk writer is NOT atomic. Each chunk succeeds or fails on its own, and chunks
+// are executed in no particular order, so a failed Exec can leave any subset of the
+// actions applied. Use it for migrations and backfills where every action is safe to
+// re-run, and inspect [BulkResult] for the per-action outcome. Use [Txer] when the
+// writes must succeed or fail together.
+func NewBulkWriter(ddb ddbiface.ReadWriteClient, opts ...BulkOption) BulkWriter {
+	w := &bulkWriter{
+		awsddb: withRetries(ddb),
+		keys:   make(map[actionKey]struct{}),
+		opts: bulkOpts{
+			concurrency: 4,
+		},
+	}
+	for _, opt := range opts {
+		opt(&w.opts)
+	}
+	if w.opts.concurrency < 1 {
+		w.opts.concurrency = 1
+	}
+	return w
+}
+
+type BulkWriter interface {
+	// AddAction appends actions to the bulk write.
+	// Each item may only be written once per bulk write.
+	AddAction(...Action)
+	// Exec writes all added actions and reports the outcome of each one.
+	// The returned error is non-nil if any action failed, see [BulkResult.Err].
+	Exec(context.Context) (BulkResult, error)
+}
+
+type bulkWriter struct {
+	awsddb ddbiface.ReadWriteClient
+	opts   bulkOpts
+
+	outcomes []BulkOutcome
+	keys     map[actionKey]struct{}
+}
+
+var _ BulkWriter = &bulkWriter{}
+
+func (w *bulkWriter) AddAction(actions ...Action) {
+	for _, action := range actions {
+		outcome := BulkOutcome{Action: action}
+		key := actionKey{tableName: *action.TableName(), primaryKey: action.PrimaryKey()}
+		if _, found := w.keys[key]; found {
+			outcome.Err = fmt.Errorf("an action already exists for table %s, primary key: %v", *action.TableName(), action.PrimaryKey())
+		} else {
+			w.keys[key] = struct{}{}
+		}
+		w.outcomes = append(w.outcomes, outcome)
+	}
+}
+
+// bulkChunk is a set of indices into bulkWriter.outcomes that are sent in one request.
+type bulkChunk struct {
+	batch   bool
+	indices []int
+}
+
+func (w *bulkWriter) Exec(ctx context.Context) (BulkResult, error) {
+	var chunks []bulkChunk
+	var batch, tx []int
+	for i, o := range w.outcomes {
+		if o.Err != nil {
+			continue
+		}
+		if isBatchWritable(o.Action) {
+			batch = append(batch, i)
+			if len(batch) == maxBatchWriteItems {
+				chunks = append(chunks, bulkChunk{batch: true, indices: batch})
+				batch = nil
+			}
+			continue
+		}
+		tx = append(tx, i)
+		if len(tx) == maxTransactWriteItems {
+			chunks = append(chunks, bulkChunk{indices: tx})
+			tx = nil
+		}
+	}
+	if len(batch) > 0 {
+		chunks = append(chunks, bulkChunk{batch: true, indices: batch})
+	}
+	if len(tx) > 0 {
+		chunks = append(chunks, bulkChunk{indices: tx})
+	}
+
+	sem := make(chan struct{}, w.opts.concurrency)
+	var wg sync.WaitGroup
+	for _, chunk := range chunks {
+		select {
+		case <-ctx.Done():
+			w.fail(chunk.indices, ctx.Err())
+			continue
+		case sem <- struct{}{}:
+		}
+		wg.Add(1)
+		go func(chunk bulkChunk) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if chunk.batch {
+				w.execBatch(ctx, chunk.indices)
+			} else {
+				w.execTx(ctx, chunk.indices)
+			}
+		}(chunk)
+	}
+	wg.Wait()
+
+	res := BulkResult{Outcomes: w.outcomes}
+	return res, res.Err()
+}
+
+// fail records err for all given actions. Chunks never share indices,
+// so concurrent calls write to disjoint outcomes.
+func (w *bulkWriter) fail(indices []int, err error) {
+	for _, i := range indices {
+		w.outcomes[i].Err = err
+	}
+}
+
+func (w *bulkWriter) execTx(ctx context.Context, indices []int) {
+	tx := NewTx(w.awsddb)
+	for _, i := range indices {
+		tx.AddAction(w.outcomes[i].Action)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		w.fail(indices, err)
+	}
+}
+
+// execBatch writes the actions with BatchWriteItem. Actions that can't be converted
+// to a request fail on their own; everything after that is an error from DynamoDB,
+// or unprocessed items the retry policy gave up on, which only fails the actions that
+// are still unprocessed.
+func (w *bulkWriter) execBatch(ctx context.Context, indices []int) {
+	b := NewBatcher(w.awsddb)
+	sent := make([]int, 0, len(indices))
+	for _, i := range indices {
+		a := w.outcomes[i].Action
+		req, err := batchWriteRequest(a.(BatchAction))
+		if err == nil {
+			err = b.addRequest(*a.TableName(), req)
+		}
+		if err != nil {
+			w.outcomes[i].Err = err
+			continue
+		}
+		sent = append(sent, i)
+	}
+	if res, err := b.ExecChunk(ctx); err != nil {
+		w.failUnprocessed(sent, res.Unprocessed, err)
+	}
+}
+
+// failUnprocessed records err for the actions that are still among the unprocessed requests.
+func (w *bulkWriter) failUnprocessed(indices []int, unprocessed map[string][]types.WriteRequest, err error) {
+	for _, i := range indices {
+		a := w.outcomes[i].Action
+		key := a.PrimaryKey().DDB()
+		for _, req := range unprocessed[*a.TableName()] {
+			if requestHasKey(req, key) {
+				w.outcomes[i].Err = err
+				break
+			}
+		}
+	}
+}
+
+// requestHasKey reports whether the write request targets the item with the given key.
+func requestHasKey(req types.WriteRequest, key map[string]types.AttributeValue) bool {
+	attrs := extractKey(req)
+	for k, v := range key {
+		if av, ok := attrs[k]; !ok || !attributeValuesEqual(av, v) {
+			return false
+		}
+	}
+	return true
+}
+
+// isBatchWritable reports whether the action can go into a BatchWriteItem request,
+// i.e. it is a Put or Delete without a condition.
+func isBatchWritable(a Action) bool {
+	switch act := a.(type) {
+	case *Put:
+		return !act.c.IsSet()
+	case *Delete:
+		return !act.c.IsSet()
+	}
+	return false
+}
+
+// BulkResult holds the outcome of every action passed to a [BulkWriter], in the order they were added.
+type BulkResult struct {
+	Outcomes []BulkOutcome
+}
+
+// BulkOutcome is the outcome of a single action. Err is nil if the action was written.
+//
+// Actions in a failed transaction chunk all share the same error, even if only
+// one of them caused the cancellation.
+type BulkOutcome struct {
+	Action Action
+	Err    error
+}
+
+// Failed returns the outcomes of all actions that were not written.
+func (r BulkResult) Failed() []BulkOutcome {
+	var failed []BulkOutcome
+	for _, o := range r.Outcomes {
+		if o.Err != nil {
+			failed = append(failed, o)
+		}
+	}
+	return failed
+}
+
+// Err returns nil if every action was written, otherwise an error summarizing the failures.
+func (r BulkResult) Err() error {
+	failed := r.Failed()
+	if len(failed) == 0 {
+		return nil
+	}
+	errs := make([]error, 0, len(failed))
+	for _, o := range failed {
+		errs = append(errs, fmt.Errorf("table %s, primary key %v: %w", *o.Action.TableName(), o.Action.PrimaryKey().Values, o.Err))
+	}
+	return fmt.Errorf("bulk write: %d of %d actions failed: %w", len(failed), len(r.Outcomes), errors.Join(errs...))
+}
+
+type BulkOption func(*bulkOpts)
+
+// WithConcurrency sets how many chunks a [BulkWriter] executes in parallel. Defaults to 4.
+func WithConcurrency(n int) BulkOption {
+	return func(o *bulkOpts) {
+		o.concurrency = n
+	}
+}
+
+type bulkOpts struct {
+	concurrency int
+}
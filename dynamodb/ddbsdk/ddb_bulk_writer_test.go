@@ -0,0 +1,179 @@
+package ddbsdk
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+)
+
+func TestBulkWriter_MixedActionsAcrossChunks(t *testing.T) {
+	db := NewMemoryClient(batchTestTable)
+	ctx := context.Background()
+
+	// Seed items to update and delete.
+	seed := db.NewBulkWriter()
+	for i := 0; i < 150; i++ {
+		e := &testEntity{PK: fmt.Sprintf("user#%d", i), SK: "profile", Name: "before"}
+		seed.AddAction(NewUnsafePut(batchTestTable, batchTestKey(e.PK, e.SK), e))
+	}
+	if _, err := seed.Exec(ctx); err != nil {
+		t.Fatalf("seed failed: %v", err)
+	}
+
+	w := db.NewBulkWriter(WithConcurrency(3))
+	// 150 updates -> two transaction chunks
+	for i := 0; i < 150; i++ {
+		w.AddAction(NewUnsafeUpdate(batchTestTable, batchTestKey(fmt.Sprintf("user#%d", i), "profile")).
+			AddOp(SetFieldOp("name", "after")))
+	}
+	// 60 unconditional puts -> three batch chunks
+	for i := 0; i < 60; i++ {
+		e := &testEntity{PK: fmt.Sprintf("new#%d", i), SK: "profile", Name: "new"}
+		w.AddAction(NewUnsafePut(batchTestTable, batchTestKey(e.PK, e.SK), e))
+	}
+	// a conditional delete goes into a transaction chunk
+	w.AddAction(NewDelete(batchTestTable, batchTestKey("gone", "profile")).
+		WithCondition(expression.AttributeNotExists(expression.Name("pk"))))
+
+	res, err := w.Exec(ctx)
+	if err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	if len(res.Outcomes) != 211 {
+		t.Fatalf("expected 211 outcomes, got %d", len(res.Outcomes))
+	}
+
+	getter := db.NewLookup()
+	item, err := getter.GetItem(ctx, GetItemRequest{Table: batchTestTable, Key: batchTestKey("user#149", "profile")})
+	if err != nil {
+		t.Fatalf("GetItem failed: %v", err)
+	}
+	var got testEntity
+	if err := attributevalue.UnmarshalMap(item, &got); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	if got.Name != "after" {
+		t.Errorf("expected updated name %q, got %q", "after", got.Name)
+	}
+	item, err = getter.GetItem(ctx, GetItemRequest{Table: batchTestTable, Key: batchTestKey("new#59", "profile")})
+	if err != nil {
+		t.Fatalf("GetItem failed: %v", err)
+	}
+	if item == nil {
+		t.Error("expected batch-written item to exist")
+	}
+}
+
+func TestBulkWriter_FailedChunkDoesNotAffectOthers(t *testing.T) {
+	db := NewMemoryClient(batchTestTable)
+	ctx := context.Background()
+
+	w := db.NewBulkWriter()
+	put := &testEntity{PK: "user#1", SK: "profile", Name: "Alice"}
+	w.AddAction(NewUnsafePut(batchTestTable, batchTestKey(put.PK, put.SK), put))
+	// Condition fails because the item does not exist.
+	w.AddAction(NewUnsafeUpdate(batchTestTable, batchTestKey("user#2", "profile")).
+		AddOp(SetFieldOp("name", "Bob")).
+		WithCondition(expression.AttributeExists(expression.Name("pk"))))
+
+	res, err := w.Exec(ctx)
+	if err == nil {
+		t.Fatal("expected error for failed condition")
+	}
+	failed := res.Failed()
+	if len(failed) != 1 {
+		t.Fatalf("expected 1 failed action, got %d", len(failed))
+	}
+	if failed[0].Action.PrimaryKey().Values.PartitionKey != "user#2" {
+		t.Errorf("expected update of user#2 to fail, got %v", failed[0].Action.PrimaryKey().Values)
+	}
+	if res.Outcomes[0].Err != nil {
+		t.Errorf("expected put to succeed, got %v", res.Outcomes[0].Err)
+	}
+
+	item, err := db.NewLookup().GetItem(ctx, GetItemRequest{Table: batchTestTable, Key: batchTestKey("user#1", "profile")})
+	if err != nil {
+		t.Fatalf("GetItem failed: %v", err)
+	}
+	if item == nil {
+		t.Error("expected put to be written despite failed update")
+	}
+}
+
+func TestBulkWriter_DuplicateKey_FailsOnlyDuplicate(t *testing.T) {
+	db := NewMemoryClient(batchTestTable)
+	ctx := context.Background()
+
+	w := db.NewBulkWriter()
+	e := &testEntity{PK: "user#1", SK: "profile", Name: "Alice"}
+	w.AddAction(NewUnsafePut(batchTestTable, batchTestKey(e.PK, e.SK), e))
+	w.AddAction(NewDelete(batchTestTable, batchTestKey(e.PK, e.SK)))
+
+	res, err := w.Exec(ctx)
+	if err == nil {
+		t.Fatal("expected error for duplicate key")
+	}
+	if res.Outcomes[0].Err != nil {
+		t.Errorf("expected first action to succeed, got %v", res.Outcomes[0].Err)
+	}
+	if res.Outcomes[1].Err == nil || !contains(res.Outcomes[1].Err.Error(), "already exists") {
+		t.Errorf("expected duplicate error on second action, got %v", res.Outcomes[1].Err)
+	}
+}
+
+func TestBulkWriter_Empty(t *testing.T) {
+	db := NewMemoryClient(batchTestTable)
+	res, err := db.NewBulkWriter().Exec(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(res.Outcomes) != 0 {
+		t.Errorf("expected no outcomes, got %d", len(res.Outcomes))
+	}
+}
+
+func TestBulkWriter_InvalidBatchAction_FailsOnlyThatAction(t *testing.T) {
+	db := NewMemoryClient(batchTestTable)
+	ctx := context.Background()
+
+	w := db.NewBulkWriter()
+	alice := &testEntity{PK: "user#1", SK: "profile", Name: "Alice"}
+	w.AddAction(NewUnsafePut(batchTestTable, batchTestKey(alice.PK, alice.SK), alice))
+	// Array map keys can't be marshalled, so the request for this put can't be built.
+	w.AddAction(NewUnsafePut(batchTestTable, batchTestKey("user#2", "profile"), &unmarshallableEntity{M: map[[2]int]int{{1, 2}: 1}}))
+	bob := &testEntity{PK: "user#3", SK: "profile", Name: "Bob"}
+	w.AddAction(NewUnsafePut(batchTestTable, batchTestKey(bob.PK, bob.SK), bob))
+
+	res, err := w.Exec(ctx)
+	if err == nil {
+		t.Fatal("expected error for the invalid put")
+	}
+	if res.Outcomes[1].Err == nil {
+		t.Error("expected the invalid put to fail")
+	}
+	for _, i := range []int{0, 2} {
+		if res.Outcomes[i].Err != nil {
+			t.Errorf("expected action %d to succeed, got %v", i, res.Outcomes[i].Err)
+		}
+	}
+
+	getter := db.NewLookup()
+	for _, e := range []*testEntity{alice, bob} {
+		item, err := getter.GetItem(ctx, GetItemRequest{Table: batchTestTable, Key: batchTestKey(e.PK, e.SK)})
+		if err != nil {
+			t.Fatalf("GetItem failed: %v", err)
+		}
+		if item == nil {
+			t.Errorf("expected %s to be written", e.PK)
+		}
+	}
+}
+
+type unmarshallableEntity struct {
+	M map[[2]int]int `dynamodbav:"m"`
+}
+
+func (e *unmarshallableEntity) IsValid() error { return nil }
@@ -2,6 +2,23 @@ package ddbsdk
 
 // We encourage you to implement IsValid for every entity struct.
 // We will call it before committing.
+//
+// ddbgen generates IsValidGenerated for entities with validate struct tags,
+// which IsValid can call and extend with its own checks.
 type DynamoEntity interface {
 	IsValid() error
 }
+
+// FieldError is a violated validation rule of an entity field.
+// Generated IsValidGenerated methods return them, joined with errors.Join.
+type FieldError struct {
+	// Field is the dynamodbav name of the field.
+	Field string
+	// Rule is the violated rule, e.g. "required" or "max".
+	Rule string
+	Msg  string
+}
+
+func (e *FieldError) Error() string {
+	return e.Field + ": " + e.Msg
+}
@@ -3,6 +3,9 @@ package ddbsdk
 import (
 	"context"
 	"fmt"
+	"math/big"
+	"slices"
+	"strings"
 
 	"github.com/acksell/bezos/dynamodb/ddbiface"
 	"github.com/acksell/bezos/dynamodb/table"
@@ -22,7 +25,7 @@ var _ Getter = &getter{}
 
 func NewGetter(ddb ddbiface.ReadWriteClient, opts ...GetOption) *getter {
 	g := &getter{
-		awsddb: ddb,
+		awsddb: withRetries(ddb),
 	}
 	for _, opt := range opts {
 		opt(&g.opts)
@@ -99,8 +102,16 @@ func (g *getter) GetItemsTx(ctx context.Context, items ...GetItemRequest) ([]Ite
 	return extractItemsFromResponses(res.Responses), nil
 }
 
-// Todo1 improve retrying API
-// Todo2: also improve the projection api: BatchGetItem applies projection per-table, so all items from the same table use the projection from the first item encountered for that table.
+// GetItemsBatch retrieves multiple items using BatchGetItem.
+//
+// The result has one entry per request, in request order, with nil for items that don't exist.
+//
+// BatchGetItem only accepts one projection per table, so requests for the same table
+// with different projections are sent in separate BatchGetItem calls.
+//
+// Unprocessed keys are re-requested according to the [RetryPolicy] of the client, or
+// [DefaultRetryPolicy] for a raw client. If keys are still unprocessed after that, an
+// error is returned.
 func (g *getter) GetItemsBatch(ctx context.Context, items ...GetItemRequest) ([]Item, error) {
 	if len(items) == 0 {
 		return nil, nil
@@ -110,54 +121,163 @@ func (g *getter) GetItemsBatch(ctx context.Context, items ...GetItemRequest) ([]
 		return nil, fmt.Errorf("batch get items limited to 100 items, got %d", len(items))
 	}
 
-	requestItems, err := g.buildBatchRequestItems(items)
+	groups, err := g.groupBatchRequests(items)
 	if err != nil {
 		return nil, err
 	}
 
-	var allItems []Item
+	results := make([]Item, len(items))
+	for len(groups) > 0 {
+		// Send at most one group per table per call.
+		requestItems := make(map[string]types.KeysAndAttributes)
+		sent := make(map[string]*batchGetGroup)
+		var rest []*batchGetGroup
+		for _, grp := range groups {
+			if _, taken := sent[grp.table.Name]; taken {
+				rest = append(rest, grp)
+				continue
+			}
+			sent[grp.table.Name] = grp
+			requestItems[grp.table.Name] = grp.keysAndAttrs
+		}
+		groups = rest
 
-	for len(requestItems) > 0 {
 		res, err := g.awsddb.BatchGetItem(ctx, &dynamodbv2.BatchGetItemInput{
 			RequestItems: requestItems,
 		})
 		if err != nil {
 			return nil, fmt.Errorf("batch get item failed: %w", err)
 		}
+		if n := countKeys(res.UnprocessedKeys); n > 0 {
+			return nil, fmt.Errorf("batch get item incomplete: %d keys unprocessed after retries", n)
+		}
 
-		for _, tableItems := range res.Responses {
-			allItems = append(allItems, tableItems...)
+		for tableName, tableItems := range res.Responses {
+			grp := sent[tableName]
+			if grp == nil {
+				continue
+			}
+			for _, item := range tableItems {
+				for _, i := range grp.requests[keySignature(item, grp.table.KeyDefinitions)] {
+					results[i] = grp.strip(item)
+				}
+			}
 		}
+	}
+
+	return results, nil
+}
 
-		requestItems = res.UnprocessedKeys
+// normalizeNumber formats a number attribute canonically, so "1.0" and "1" match.
+func normalizeNumber(n string) string {
+	f, ok := new(big.Float).SetPrec(256).SetString(n)
+	if !ok {
+		return n
 	}
+	return f.Text('g', -1)
+}
 
-	return allItems, nil
+func countKeys(m map[string]types.KeysAndAttributes) int {
+	var n int
+	for _, ka := range m {
+		n += len(ka.Keys)
+	}
+	return n
 }
 
-func (g *getter) buildBatchRequestItems(items []GetItemRequest) (map[string]types.KeysAndAttributes, error) {
-	requestItems := make(map[string]types.KeysAndAttributes)
+// batchGetGroup holds the requests for one table that share the same projection.
+type batchGetGroup struct {
+	table        table.TableDefinition
+	keysAndAttrs types.KeysAndAttributes
+	// requests maps key signatures to the indices of the requests for that key.
+	requests map[string][]int
+	// addedKeys are key attributes added to the projection to match items to
+	// requests, which are removed again from the returned items.
+	addedKeys []string
+}
 
-	for _, item := range items {
-		tableName := item.Table.Name
+// strip removes key attributes that were only projected to match items to requests.
+func (grp *batchGetGroup) strip(item Item) Item {
+	if len(grp.addedKeys) == 0 {
+		return item
+	}
+	out := make(Item, len(item))
+	for k, v := range item {
+		out[k] = v
+	}
+	for _, k := range grp.addedKeys {
+		delete(out, k)
+	}
+	return out
+}
 
-		keysAndAttrs, exists := requestItems[tableName]
+// groupBatchRequests groups requests by table and projection, in order of first appearance.
+// Duplicate keys within a group are only requested once.
+func (g *getter) groupBatchRequests(items []GetItemRequest) ([]*batchGetGroup, error) {
+	var groups []*batchGetGroup
+	byID := make(map[string]*batchGetGroup)
+
+	for i, item := range items {
+		id := item.Table.Name + "\x00" + strings.Join(item.Projection, "\x00")
+		grp, exists := byID[id]
 		if !exists {
-			keysAndAttrs = types.KeysAndAttributes{
-				ConsistentRead: ptr(!g.opts.eventuallyConsistent),
+			grp = &batchGetGroup{
+				table:    item.Table,
+				requests: make(map[string][]int),
+				keysAndAttrs: types.KeysAndAttributes{
+					ConsistentRead: ptr(!g.opts.eventuallyConsistent),
+				},
 			}
-
-			// For BatchGetItem, projection is per-table, use first item's projection
-			if err := applyProjectionToKeysAndAttributes(&keysAndAttrs, item.Projection); err != nil {
+			projection := item.Projection
+			if len(projection) > 0 {
+				for _, k := range keyAttributeNames(item.Table.KeyDefinitions) {
+					if !slices.Contains(projection, k) {
+						projection = append(slices.Clip(projection), k)
+						grp.addedKeys = append(grp.addedKeys, k)
+					}
+				}
+			}
+			if err := applyProjectionToKeysAndAttributes(&grp.keysAndAttrs, projection); err != nil {
 				return nil, fmt.Errorf("failed to apply projection: %w", err)
 			}
+			byID[id] = grp
+			groups = append(groups, grp)
 		}
 
-		keysAndAttrs.Keys = append(keysAndAttrs.Keys, item.Key.DDB())
-		requestItems[tableName] = keysAndAttrs
+		key := item.Key.DDB()
+		sig := keySignature(key, item.Table.KeyDefinitions)
+		if _, dup := grp.requests[sig]; !dup {
+			grp.keysAndAttrs.Keys = append(grp.keysAndAttrs.Keys, key)
+		}
+		grp.requests[sig] = append(grp.requests[sig], i)
 	}
 
-	return requestItems, nil
+	return groups, nil
+}
+
+func keyAttributeNames(def table.PrimaryKeyDefinition) []string {
+	names := []string{def.PartitionKey.Name}
+	if def.SortKey.Name != "" {
+		names = append(names, def.SortKey.Name)
+	}
+	return names
+}
+
+// keySignature returns a string identifying the primary key of an item.
+func keySignature(item Item, def table.PrimaryKeyDefinition) string {
+	var b strings.Builder
+	for _, name := range keyAttributeNames(def) {
+		switch v := item[name].(type) {
+		case *types.AttributeValueMemberS:
+			b.WriteString("S:" + v.Value)
+		case *types.AttributeValueMemberN:
+			b.WriteString("N:" + normalizeNumber(v.Value))
+		case *types.AttributeValueMemberB:
+			b.WriteString("B:" + string(v.Value))
+		}
+		b.WriteByte(0)
+	}
+	return b.String()
 }
 
 func applyProjectionToGetInput(input *dynamodbv2.GetItemInput, projection []string) error {
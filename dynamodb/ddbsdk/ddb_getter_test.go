@@ -291,9 +291,15 @@ func TestGetter_GetItemsBatch_PartiallyFound(t *testing.T) {
 		t.Fatalf("GetItemsBatch failed: %v", err)
 	}
 
-	// Should only return existing items
-	if len(results) != 2 {
-		t.Errorf("expected 2 items, got %d", len(results))
+	// Missing items are nil placeholders in request order
+	if len(results) != 3 {
+		t.Fatalf("expected 3 results, got %d", len(results))
+	}
+	if results[0] == nil || results[2] == nil {
+		t.Error("expected existing items at positions 0 and 2")
+	}
+	if results[1] != nil {
+		t.Errorf("expected nil for missing item, got %v", results[1])
 	}
 }
 
@@ -425,10 +431,69 @@ func TestGetter_GetItemsBatch_WithDifferentProjections(t *testing.T) {
 	}
 
 	if len(results) != 2 {
-		t.Errorf("expected 2 items, got %d", len(results))
+		t.Fatalf("expected 2 items, got %d", len(results))
+	}
+
+	// Each item gets its own projection, even though they share a table
+	if _, ok := results[0]["name"]; !ok {
+		t.Error("expected name in first item")
 	}
+	if _, ok := results[0]["email"]; ok {
+		t.Error("expected email to be excluded from first item")
+	}
+	if _, ok := results[1]["email"]; !ok {
+		t.Error("expected email in second item")
+	}
+	if _, ok := results[1]["name"]; ok {
+		t.Error("expected name to be excluded from second item")
+	}
+}
 
-	// Note: Projections in batch operations are per-item based on the request
+func TestGetter_GetItemsBatch_PreservesOrderAndStripsAddedKeys(t *testing.T) {
+	db := NewMemoryClient(getterTestTable)
+	ctx := context.Background()
+
+	for _, item := range []testEntity{
+		{PK: "user#1", SK: "profile", Name: "Alice", Age: 30},
+		{PK: "user#2", SK: "profile", Name: "Bob", Age: 25},
+		{PK: "user#3", SK: "profile", Name: "Charlie", Age: 35},
+	} {
+		put := NewUnsafePut(getterTestTable, getterTestKey(item.PK, item.SK), &item)
+		if err := db.PutItem(ctx, put); err != nil {
+			t.Fatalf("PutItem failed: %v", err)
+		}
+	}
+
+	getter := db.NewLookup()
+	results, err := getter.GetItemsBatch(ctx,
+		GetItemRequest{Table: getterTestTable, Key: getterTestKey("user#3", "profile")},
+		GetItemRequest{Table: getterTestTable, Key: getterTestKey("user#1", "profile"), Projection: []string{"name"}},
+		GetItemRequest{Table: getterTestTable, Key: getterTestKey("user#9", "profile")},
+		GetItemRequest{Table: getterTestTable, Key: getterTestKey("user#3", "profile")},
+	)
+	if err != nil {
+		t.Fatalf("GetItemsBatch failed: %v", err)
+	}
+	if len(results) != 4 {
+		t.Fatalf("expected 4 results, got %d", len(results))
+	}
+
+	var first testEntity
+	if err := attributevalue.UnmarshalMap(results[0], &first); err != nil {
+		t.Fatalf("failed to unmarshal: %v", err)
+	}
+	if first.Name != "Charlie" || first.Age != 35 {
+		t.Errorf("expected full Charlie item first, got %+v", first)
+	}
+	if len(results[1]) != 1 || results[1]["name"] == nil {
+		t.Errorf("expected only the projected name attribute, got %v", results[1])
+	}
+	if results[2] != nil {
+		t.Errorf("expected nil for missing item, got %v", results[2])
+	}
+	if results[3] == nil {
+		t.Error("expected duplicate request to be answered too")
+	}
 }
 
 func TestGetter_GetItemsTx_WithDifferentProjections(t *testing.T) {
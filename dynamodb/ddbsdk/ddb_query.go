@@ -88,7 +88,8 @@ func (qd QueryDef) WithSKCondition(sk SortKeyCondition) QueryDef {
 // Querier executes DynamoDB queries with configurable options.
 // Create with [Client.NewQuery] or [NewQuerier], then configure with method chaining.
 type Querier struct {
-	awsddb ddbiface.ReadWriteClient
+	awsddb   ddbiface.ReadWriteClient
+	observer Observer
 
 	queryDef QueryDef
 
@@ -199,6 +200,7 @@ func (q *Querier) Next(ctx context.Context) (*QueryResult, error) {
 		return nil, fmt.Errorf("failed to build query expression: %w", err)
 	}
 
+	ctx, done := observe(ctx, q.observer, q.operation())
 	res, err := q.awsddb.Query(ctx, &dynamodbv2.QueryInput{
 		TableName:                 &q.queryDef.Table.Name,
 		IndexName:                 q.queryDef.IndexName,
@@ -213,8 +215,10 @@ func (q *Querier) Next(ctx context.Context) (*QueryResult, error) {
 		ExclusiveStartKey:         q.lastCursor,
 	})
 	if err != nil {
+		done(err, 0)
 		return nil, fmt.Errorf("query failed: %w", err)
 	}
+	done(nil, len(res.Items))
 
 	q.lastCursor = res.LastEvaluatedKey
 	return &QueryResult{
@@ -223,6 +227,17 @@ func (q *Querier) Next(ctx context.Context) (*QueryResult, error) {
 	}, nil
 }
 
+// operation describes a query page for the client's [Observer].
+func (q *Querier) operation() Operation {
+	op := Operation{Name: "Query", Tables: []string{q.queryDef.Table.Name}}
+	if q.queryDef.IndexName != nil {
+		op.Index = *q.queryDef.IndexName
+	} else if et := entityTypeForKey(q.queryDef.Table.Name, q.queryDef.Partition, nil); et != "" {
+		op.EntityTypes = []string{et}
+	}
+	return op
+}
+
 func (q *Querier) QueryAll(ctx context.Context) (*QueryResult, error) {
 	var allItems []Item
 	for {
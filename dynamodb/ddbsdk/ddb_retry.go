@@ -0,0 +1,343 @@
+package ddbsdk
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/acksell/bezos/dynamodb/ddbiface"
+	dynamodbv2 "github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// RetryReason classifies why a request was retried.
+type RetryReason string
+
+const (
+	// RetryThrottled is used for throttling and exceeded provisioned throughput.
+	RetryThrottled RetryReason = "throttled"
+	// RetryTransactionConflict is used when a transaction was cancelled due to a
+	// conflicting transaction on one of its items.
+	RetryTransactionConflict RetryReason = "transaction_conflict"
+	// RetryServerError is used for 5xx responses.
+	RetryServerError RetryReason = "server_error"
+	// RetryUnprocessed is used when a batch request returned unprocessed items or keys.
+	RetryUnprocessed RetryReason = "unprocessed"
+)
+
+// RetryPolicy decides if and how often a failed DynamoDB request is retried.
+//
+// The policy is applied to every request a [Client] sends, and to the unprocessed items and
+// keys of batch requests, which [Batcher], [BulkWriter] and [Getter] leave to it. Set a policy
+// with [WithRetryPolicy] and override it for a single call with [ContextWithRetryPolicy].
+//
+// The AWS SDK retries throttling and server errors on its own. Turn its retryer off when
+// using a policy, or every attempt of the policy is retried again by the SDK:
+//
+//	awsClient := dynamodb.NewFromConfig(cfg, func(o *dynamodb.Options) {
+//		o.Retryer = aws.NopRetryer{}
+//	})
+//	client := ddbsdk.NewClient(awsClient)
+//
+// Writes that failed with a server error are not retried unless RetryWritesOnServerError
+// is set: a write may have been applied before the error, in which case a retried
+// conditional write fails its condition, and a non-idempotent update (see [UnsafeUpdate])
+// is applied twice.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first one.
+	// Values below 2 disable retries.
+	MaxAttempts int
+	// Backoff returns the wait before retry n, starting at 1. Defaults to [DefaultBackoff].
+	Backoff BackoffFunc
+	// Classify reports whether an error is retryable and why. Defaults to [ClassifyError].
+	Classify func(error) (RetryReason, bool)
+	// RetryWritesOnServerError retries PutItem, UpdateItem, DeleteItem, BatchWriteItem and
+	// TransactWriteItems requests that failed with a server error. Reads are always retried.
+	RetryWritesOnServerError bool
+	// OnRetry is called before waiting for each retry, e.g. to record metrics.
+	OnRetry func(RetryEvent)
+}
+
+// RetryEvent describes a single retry.
+type RetryEvent struct {
+	// Operation is the DynamoDB API name, e.g. "PutItem" or "TransactWriteItems".
+	Operation string
+	// Attempt is the attempt that failed, starting at 1.
+	Attempt int
+	Reason  RetryReason
+	// Err is the error of the failed attempt, nil for RetryUnprocessed.
+	Err   error
+	Delay time.Duration
+}
+
+// DefaultRetryPolicy makes up to 5 attempts with [DefaultBackoff].
+var DefaultRetryPolicy = RetryPolicy{MaxAttempts: 5, Backoff: DefaultBackoff}
+
+// NoRetries disables retries, e.g. for a single call with [ContextWithRetryPolicy].
+var NoRetries = RetryPolicy{MaxAttempts: 1}
+
+type retryPolicyKey struct{}
+
+// ContextWithRetryPolicy overrides the client's retry policy for all requests made with ctx.
+func ContextWithRetryPolicy(ctx context.Context, p RetryPolicy) context.Context {
+	return context.WithValue(ctx, retryPolicyKey{}, p)
+}
+
+// ClassifyError is the default error classification of [RetryPolicy].
+// Throttling, transaction conflicts and server errors are retryable,
+// everything else (including failed conditions) is not. Server errors of writes
+// are only retried with [RetryPolicy.RetryWritesOnServerError].
+func ClassifyError(err error) (RetryReason, bool) {
+	var (
+		throughput *types.ProvisionedThroughputExceededException
+		limit      *types.RequestLimitExceeded
+		conflict   *types.TransactionConflictException
+		inProgress *types.TransactionInProgressException
+		canceled   *types.TransactionCanceledException
+		internal   *types.InternalServerError
+		apiErr     interface{ ErrorCode() string }
+		httpErr    interface{ HTTPStatusCode() int }
+	)
+	switch {
+	case errors.As(err, &throughput), errors.As(err, &limit):
+		return RetryThrottled, true
+	case errors.As(err, &conflict), errors.As(err, &inProgress):
+		return RetryTransactionConflict, true
+	case errors.As(err, &canceled):
+		return classifyCancellation(canceled.CancellationReasons)
+	case errors.As(err, &internal):
+		return RetryServerError, true
+	case errors.As(err, &apiErr) && apiErr.ErrorCode() == "ThrottlingException":
+		return RetryThrottled, true
+	case errors.As(err, &httpErr) && httpErr.HTTPStatusCode() >= 500:
+		return RetryServerError, true
+	}
+	return "", false
+}
+
+// classifyCancellation retries a cancelled transaction only if every cancellation
+// reason is transient. A single failed condition makes the whole transaction final.
+func classifyCancellation(reasons []types.CancellationReason) (RetryReason, bool) {
+	var reason RetryReason
+	for _, r := range reasons {
+		if r.Code == nil {
+			continue
+		}
+		switch *r.Code {
+		case "None":
+		case "TransactionConflict":
+			if reason == "" {
+				reason = RetryTransactionConflict
+			}
+		case "ThrottlingError", "ProvisionedThroughputExceeded":
+			reason = RetryThrottled
+		default:
+			return "", false
+		}
+	}
+	return reason, reason != ""
+}
+
+// NewRetryClient wraps ddb so that every request is retried according to p.
+// [Client] does this for you, use it directly when constructing a [Txer] or [Querier]
+// from a raw client. [NewBatcher], [NewBulkWriter] and [NewGetter] wrap raw clients
+// with [DefaultRetryPolicy] themselves.
+//
+// Batch requests are re-sent with their unprocessed items or keys until none are left
+// or the attempts are exhausted, remaining ones are returned in the output as usual.
+func NewRetryClient(ddb ddbiface.ReadWriteClient, p RetryPolicy) ddbiface.ReadWriteClient {
+	return &retryClient{ddb: ddb, policy: p}
+}
+
+type retryClient struct {
+	ddb    ddbiface.ReadWriteClient
+	policy RetryPolicy
+}
+
+var _ ddbiface.ReadWriteClient = &retryClient{}
+
+// withRetries wraps ddb with [DefaultRetryPolicy] unless it retries already, for the
+// writers and readers that leave unprocessed items and keys to the retry policy.
+func withRetries(ddb ddbiface.ReadWriteClient) ddbiface.ReadWriteClient {
+	if _, ok := ddb.(*retryClient); ok {
+		return ddb
+	}
+	return NewRetryClient(ddb, DefaultRetryPolicy)
+}
+
+// writeOps are the operations that may have been applied when they fail with a server error.
+var writeOps = map[string]bool{
+	"PutItem":            true,
+	"UpdateItem":         true,
+	"DeleteItem":         true,
+	"BatchWriteItem":     true,
+	"TransactWriteItems": true,
+}
+
+// policyFor returns the effective policy for ctx with defaults filled in.
+func (c *retryClient) policyFor(ctx context.Context) RetryPolicy {
+	p := c.policy
+	if override, ok := ctx.Value(retryPolicyKey{}).(RetryPolicy); ok {
+		p = override
+	}
+	if p.Backoff == nil {
+		p.Backoff = DefaultBackoff
+	}
+	if p.Classify == nil {
+		p.Classify = ClassifyError
+	}
+	return p
+}
+
+// wait reports the retry and sleeps for its backoff. It returns false if ctx is done.
+func (p RetryPolicy) wait(ctx context.Context, ev RetryEvent) bool {
+	ev.Delay = p.Backoff(ev.Attempt)
+	if p.OnRetry != nil {
+		p.OnRetry(ev)
+	}
+	if s := statsFrom(ctx); s != nil {
+		s.addRetry()
+	}
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(ev.Delay):
+		return true
+	}
+}
+
+// withRetry calls fn until it succeeds, fails with a non-retryable error or runs out of attempts.
+// attempt is shared with the caller so batch operations can spend the remaining budget on unprocessed items.
+func withRetry[Out any](ctx context.Context, p RetryPolicy, op string, attempt *int, fn func() (Out, error)) (Out, error) {
+	for {
+		*attempt++
+		out, err := fn()
+		if err == nil {
+			return out, nil
+		}
+		reason, ok := p.Classify(err)
+		if reason == RetryServerError && writeOps[op] && !p.RetryWritesOnServerError {
+			ok = false
+		}
+		if !ok || *attempt >= p.MaxAttempts {
+			return out, err
+		}
+		if !p.wait(ctx, RetryEvent{Operation: op, Attempt: *attempt, Reason: reason, Err: err}) {
+			return out, err
+		}
+	}
+}
+
+func (c *retryClient) BatchGetItem(ctx context.Context, params *dynamodbv2.BatchGetItemInput, optFns ...func(*dynamodbv2.Options)) (*dynamodbv2.BatchGetItemOutput, error) {
+	p := c.policyFor(ctx)
+	var attempt int
+	input := *params
+	var res *dynamodbv2.BatchGetItemOutput
+	for {
+		out, err := withRetry(ctx, p, "BatchGetItem", &attempt, func() (*dynamodbv2.BatchGetItemOutput, error) {
+			return c.ddb.BatchGetItem(ctx, &input, optFns...)
+		})
+		if err != nil {
+			return out, err
+		}
+		if res == nil {
+			res = out
+			if res.Responses == nil {
+				res.Responses = make(map[string][]map[string]types.AttributeValue)
+			}
+		} else {
+			for table, items := range out.Responses {
+				res.Responses[table] = append(res.Responses[table], items...)
+			}
+			res.ConsumedCapacity = append(res.ConsumedCapacity, out.ConsumedCapacity...)
+			res.UnprocessedKeys = out.UnprocessedKeys
+		}
+		if len(out.UnprocessedKeys) == 0 || attempt >= p.MaxAttempts {
+			return res, nil
+		}
+		if !p.wait(ctx, RetryEvent{Operation: "BatchGetItem", Attempt: attempt, Reason: RetryUnprocessed}) {
+			return res, nil
+		}
+		input.RequestItems = out.UnprocessedKeys
+	}
+}
+
+func (c *retryClient) BatchWriteItem(ctx context.Context, params *dynamodbv2.BatchWriteItemInput, optFns ...func(*dynamodbv2.Options)) (*dynamodbv2.BatchWriteItemOutput, error) {
+	p := c.policyFor(ctx)
+	var attempt int
+	input := *params
+	var consumed []types.ConsumedCapacity
+	for {
+		out, err := withRetry(ctx, p, "BatchWriteItem", &attempt, func() (*dynamodbv2.BatchWriteItemOutput, error) {
+			return c.ddb.BatchWriteItem(ctx, &input, optFns...)
+		})
+		if err != nil {
+			return out, err
+		}
+		consumed = append(consumed, out.ConsumedCapacity...)
+		out.ConsumedCapacity = consumed
+		if len(out.UnprocessedItems) == 0 || attempt >= p.MaxAttempts {
+			return out, nil
+		}
+		if !p.wait(ctx, RetryEvent{Operation: "BatchWriteItem", Attempt: attempt, Reason: RetryUnprocessed}) {
+			return out, nil
+		}
+		input.RequestItems = out.UnprocessedItems
+	}
+}
+
+func (c *retryClient) DeleteItem(ctx context.Context, params *dynamodbv2.DeleteItemInput, optFns ...func(*dynamodbv2.Options)) (*dynamodbv2.DeleteItemOutput, error) {
+	var attempt int
+	return withRetry(ctx, c.policyFor(ctx), "DeleteItem", &attempt, func() (*dynamodbv2.DeleteItemOutput, error) {
+		return c.ddb.DeleteItem(ctx, params, optFns...)
+	})
+}
+
+func (c *retryClient) GetItem(ctx context.Context, params *dynamodbv2.GetItemInput, optFns ...func(*dynamodbv2.Options)) (*dynamodbv2.GetItemOutput, error) {
+	var attempt int
+	return withRetry(ctx, c.policyFor(ctx), "GetItem", &attempt, func() (*dynamodbv2.GetItemOutput, error) {
+		return c.ddb.GetItem(ctx, params, optFns...)
+	})
+}
+
+func (c *retryClient) PutItem(ctx context.Context, params *dynamodbv2.PutItemInput, optFns ...func(*dynamodbv2.Options)) (*dynamodbv2.PutItemOutput, error) {
+	var attempt int
+	return withRetry(ctx, c.policyFor(ctx), "PutItem", &attempt, func() (*dynamodbv2.PutItemOutput, error) {
+		return c.ddb.PutItem(ctx, params, optFns...)
+	})
+}
+
+func (c *retryClient) TransactGetItems(ctx context.Context, params *dynamodbv2.TransactGetItemsInput, optFns ...func(*dynamodbv2.Options)) (*dynamodbv2.TransactGetItemsOutput, error) {
+	var attempt int
+	return withRetry(ctx, c.policyFor(ctx), "TransactGetItems", &attempt, func() (*dynamodbv2.TransactGetItemsOutput, error) {
+		return c.ddb.TransactGetItems(ctx, params, optFns...)
+	})
+}
+
+func (c *retryClient) TransactWriteItems(ctx context.Context, params *dynamodbv2.TransactWriteItemsInput, optFns ...func(*dynamodbv2.Options)) (*dynamodbv2.TransactWriteItemsOutput, error) {
+	var attempt int
+	return withRetry(ctx, c.policyFor(ctx), "TransactWriteItems", &attempt, func() (*dynamodbv2.TransactWriteItemsOutput, error) {
+		return c.ddb.TransactWriteItems(ctx, params, optFns...)
+	})
+}
+
+func (c *retryClient) Query(ctx context.Context, params *dynamodbv2.QueryInput, optFns ...func(*dynamodbv2.Options)) (*dynamodbv2.QueryOutput, error) {
+	var attempt int
+	return withRetry(ctx, c.policyFor(ctx), "Query", &attempt, func() (*dynamodbv2.QueryOutput, error) {
+		return c.ddb.Query(ctx, params, optFns...)
+	})
+}
+
+func (c *retryClient) Scan(ctx context.Context, params *dynamodbv2.ScanInput, optFns ...func(*dynamodbv2.Options)) (*dynamodbv2.ScanOutput, error) {
+	var attempt int
+	return withRetry(ctx, c.policyFor(ctx), "Scan", &attempt, func() (*dynamodbv2.ScanOutput, error) {
+		return c.ddb.Scan(ctx, params, optFns...)
+	})
+}
+
+func (c *retryClient) UpdateItem(ctx context.Context, params *dynamodbv2.UpdateItemInput, optFns ...func(*dynamodbv2.Options)) (*dynamodbv2.UpdateItemOutput, error) {
+	var attempt int
+	return withRetry(ctx, c.policyFor(ctx), "UpdateItem", &attempt, func() (*dynamodbv2.UpdateItemOutput, error) {
+		return c.ddb.UpdateItem(ctx, params, optFns...)
+	})
+}
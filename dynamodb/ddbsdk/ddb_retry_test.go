@@ -0,0 +1,254 @@
+package ddbsdk
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/acksell/bezos/dynamodb/ddbiface"
+	"github.com/acksell/bezos/dynamodb/ddbstore"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	dynamodbv2 "github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// flakyClient fails the first n PutItem and GetItem calls with err and leaves the
+// first n BatchWriteItem and BatchGetItem requests unprocessed.
+type flakyClient struct {
+	ddbiface.ReadWriteClient
+	n   int
+	err error
+
+	putCalls      int
+	getCalls      int
+	batchCalls    int
+	batchGetCalls int
+}
+
+func (c *flakyClient) GetItem(ctx context.Context, params *dynamodbv2.GetItemInput, optFns ...func(*dynamodbv2.Options)) (*dynamodbv2.GetItemOutput, error) {
+	c.getCalls++
+	if c.getCalls <= c.n {
+		return nil, c.err
+	}
+	return c.ReadWriteClient.GetItem(ctx, params, optFns...)
+}
+
+func (c *flakyClient) PutItem(ctx context.Context, params *dynamodbv2.PutItemInput, optFns ...func(*dynamodbv2.Options)) (*dynamodbv2.PutItemOutput, error) {
+	c.putCalls++
+	if c.putCalls <= c.n {
+		return nil, c.err
+	}
+	return c.ReadWriteClient.PutItem(ctx, params, optFns...)
+}
+
+func (c *flakyClient) BatchWriteItem(ctx context.Context, params *dynamodbv2.BatchWriteItemInput, optFns ...func(*dynamodbv2.Options)) (*dynamodbv2.BatchWriteItemOutput, error) {
+	c.batchCalls++
+	if c.batchCalls <= c.n {
+		return &dynamodbv2.BatchWriteItemOutput{UnprocessedItems: params.RequestItems}, nil
+	}
+	return c.ReadWriteClient.BatchWriteItem(ctx, params, optFns...)
+}
+
+func (c *flakyClient) BatchGetItem(ctx context.Context, params *dynamodbv2.BatchGetItemInput, optFns ...func(*dynamodbv2.Options)) (*dynamodbv2.BatchGetItemOutput, error) {
+	c.batchGetCalls++
+	if c.batchGetCalls <= c.n {
+		return &dynamodbv2.BatchGetItemOutput{UnprocessedKeys: params.RequestItems}, nil
+	}
+	return c.ReadWriteClient.BatchGetItem(ctx, params, optFns...)
+}
+
+func newFlakyClient(t *testing.T, n int, err error) *flakyClient {
+	t.Helper()
+	store, storeErr := ddbstore.New(ddbstore.StoreOptions{InMemory: true}, batchTestTable)
+	if storeErr != nil {
+		t.Fatalf("failed to create store: %v", storeErr)
+	}
+	return &flakyClient{ReadWriteClient: store, n: n, err: err}
+}
+
+func noBackoff(int) time.Duration { return 0 }
+
+func TestRetry_ThrottledPutIsRetried(t *testing.T) {
+	flaky := newFlakyClient(t, 2, &types.ProvisionedThroughputExceededException{Message: aws.String("slow down")})
+	var events []RetryEvent
+	db := NewClient(flaky, WithRetryPolicy(RetryPolicy{
+		MaxAttempts: 3,
+		Backoff:     noBackoff,
+		OnRetry:     func(ev RetryEvent) { events = append(events, ev) },
+	}))
+
+	e := &testEntity{PK: "user#1", SK: "profile"}
+	if err := db.PutItem(context.Background(), NewUnsafePut(batchTestTable, batchTestKey(e.PK, e.SK), e)); err != nil {
+		t.Fatalf("PutItem failed: %v", err)
+	}
+	if flaky.putCalls != 3 {
+		t.Errorf("expected 3 calls, got %d", flaky.putCalls)
+	}
+	if len(events) != 2 || events[0].Reason != RetryThrottled || events[0].Operation != "PutItem" {
+		t.Errorf("unexpected retry events: %+v", events)
+	}
+}
+
+func TestRetry_GivesUpAfterMaxAttempts(t *testing.T) {
+	flaky := newFlakyClient(t, 5, &types.InternalServerError{Message: aws.String("boom")})
+	db := NewClient(flaky, WithRetryPolicy(RetryPolicy{MaxAttempts: 2, Backoff: noBackoff, RetryWritesOnServerError: true}))
+
+	e := &testEntity{PK: "user#1", SK: "profile"}
+	err := db.PutItem(context.Background(), NewUnsafePut(batchTestTable, batchTestKey(e.PK, e.SK), e))
+	var internal *types.InternalServerError
+	if !errors.As(err, &internal) {
+		t.Fatalf("expected InternalServerError, got %v", err)
+	}
+	if flaky.putCalls != 2 {
+		t.Errorf("expected 2 calls, got %d", flaky.putCalls)
+	}
+}
+
+func TestRetry_ServerErrorsOfWritesAreNotRetriedByDefault(t *testing.T) {
+	flaky := newFlakyClient(t, 1, &types.InternalServerError{Message: aws.String("boom")})
+	db := NewClient(flaky, WithRetryPolicy(RetryPolicy{MaxAttempts: 5, Backoff: noBackoff}))
+	ctx := context.Background()
+
+	e := &testEntity{PK: "user#1", SK: "profile"}
+	key := batchTestKey(e.PK, e.SK)
+	if err := db.PutItem(ctx, NewUnsafePut(batchTestTable, key, e)); err == nil {
+		t.Fatal("expected the server error of the write")
+	}
+	if flaky.putCalls != 1 {
+		t.Errorf("expected 1 put call, got %d", flaky.putCalls)
+	}
+
+	// Reads can't have been applied, so they are retried.
+	if _, err := db.NewLookup().GetItem(ctx, GetItemRequest{Table: batchTestTable, Key: key}); err != nil {
+		t.Fatalf("GetItem failed: %v", err)
+	}
+	if flaky.getCalls != 2 {
+		t.Errorf("expected 2 get calls, got %d", flaky.getCalls)
+	}
+}
+
+func TestRetry_NonRetryableErrorIsNotRetried(t *testing.T) {
+	flaky := newFlakyClient(t, 1, &types.ConditionalCheckFailedException{Message: aws.String("nope")})
+	db := NewClient(flaky, WithRetryPolicy(RetryPolicy{MaxAttempts: 5, Backoff: noBackoff}))
+
+	e := &testEntity{PK: "user#1", SK: "profile"}
+	if err := db.PutItem(context.Background(), NewUnsafePut(batchTestTable, batchTestKey(e.PK, e.SK), e)); err == nil {
+		t.Fatal("expected error")
+	}
+	if flaky.putCalls != 1 {
+		t.Errorf("expected 1 call, got %d", flaky.putCalls)
+	}
+}
+
+func TestRetry_PerCallOverride(t *testing.T) {
+	flaky := newFlakyClient(t, 1, &types.RequestLimitExceeded{Message: aws.String("limit")})
+	db := NewClient(flaky, WithRetryPolicy(RetryPolicy{MaxAttempts: 5, Backoff: noBackoff}))
+
+	ctx := ContextWithRetryPolicy(context.Background(), NoRetries)
+	e := &testEntity{PK: "user#1", SK: "profile"}
+	if err := db.PutItem(ctx, NewUnsafePut(batchTestTable, batchTestKey(e.PK, e.SK), e)); err == nil {
+		t.Fatal("expected error with retries disabled")
+	}
+	if flaky.putCalls != 1 {
+		t.Errorf("expected 1 call, got %d", flaky.putCalls)
+	}
+}
+
+func TestRetry_UnprocessedBatchItemsAreResent(t *testing.T) {
+	flaky := newFlakyClient(t, 2, nil)
+	var reasons []RetryReason
+	db := NewClient(flaky, WithRetryPolicy(RetryPolicy{
+		MaxAttempts: 5,
+		Backoff:     noBackoff,
+		OnRetry:     func(ev RetryEvent) { reasons = append(reasons, ev.Reason) },
+	}))
+
+	batch := db.NewBatch()
+	e := &testEntity{PK: "user#1", SK: "profile"}
+	batch.AddAction(NewUnsafePut(batchTestTable, batchTestKey(e.PK, e.SK), e))
+	if err := batch.ExecAll(context.Background()); err != nil {
+		t.Fatalf("ExecAll failed: %v", err)
+	}
+	if flaky.batchCalls != 3 {
+		t.Errorf("expected 3 calls, got %d", flaky.batchCalls)
+	}
+	if len(reasons) != 2 || reasons[0] != RetryUnprocessed {
+		t.Errorf("unexpected retry reasons: %v", reasons)
+	}
+}
+
+func TestRetry_BatchOptionsOverrideThePolicy(t *testing.T) {
+	flaky := newFlakyClient(t, 5, nil)
+	var reasons []RetryReason
+	db := NewClient(flaky, WithRetryPolicy(RetryPolicy{
+		MaxAttempts: 10,
+		Backoff:     noBackoff,
+		OnRetry:     func(ev RetryEvent) { reasons = append(reasons, ev.Reason) },
+	}))
+
+	// One policy retries the unprocessed items, the batch only lowers its attempts.
+	batch := db.NewBatch(WithMaxRetries(1))
+	e := &testEntity{PK: "user#1", SK: "profile"}
+	batch.AddAction(NewUnsafePut(batchTestTable, batchTestKey(e.PK, e.SK), e))
+	if err := batch.ExecAll(context.Background()); err == nil {
+		t.Fatal("expected ExecAll to give up on the unprocessed item")
+	}
+	if flaky.batchCalls != 2 {
+		t.Errorf("expected 2 calls, got %d", flaky.batchCalls)
+	}
+	if len(reasons) != 1 {
+		t.Errorf("expected the client's OnRetry to see 1 retry, got %v", reasons)
+	}
+}
+
+func TestGetItemsBatch_RawClientResendsUnprocessedKeys(t *testing.T) {
+	flaky := newFlakyClient(t, 2, nil)
+	ctx := context.Background()
+	e := &testEntity{PK: "user#1", SK: "profile", Name: "Alice"}
+	if err := NewClient(flaky.ReadWriteClient).PutItem(ctx, NewUnsafePut(batchTestTable, batchTestKey(e.PK, e.SK), e)); err != nil {
+		t.Fatalf("PutItem failed: %v", err)
+	}
+
+	// A getter on the raw client retries with DefaultRetryPolicy.
+	items, err := NewGetter(flaky).GetItemsBatch(ctx, GetItemRequest{Table: batchTestTable, Key: batchTestKey(e.PK, e.SK)})
+	if err != nil {
+		t.Fatalf("GetItemsBatch failed: %v", err)
+	}
+	if len(items) != 1 || items[0] == nil {
+		t.Fatalf("expected the item, got %v", items)
+	}
+	if flaky.batchGetCalls != 3 {
+		t.Errorf("expected 3 calls, got %d", flaky.batchGetCalls)
+	}
+}
+
+func TestGetItemsBatch_GivesUpOnUnprocessedKeys(t *testing.T) {
+	flaky := newFlakyClient(t, 5, nil)
+	ctx := ContextWithRetryPolicy(context.Background(), RetryPolicy{MaxAttempts: 3, Backoff: noBackoff})
+	_, err := NewGetter(flaky).GetItemsBatch(ctx, GetItemRequest{Table: batchTestTable, Key: batchTestKey("user#1", "profile")})
+	if err == nil {
+		t.Fatal("expected error for unprocessed keys")
+	}
+	if flaky.batchGetCalls != 3 {
+		t.Errorf("expected 3 calls, got %d", flaky.batchGetCalls)
+	}
+}
+
+func TestClassifyError_TransactionCancellation(t *testing.T) {
+	conflict := &types.TransactionCanceledException{CancellationReasons: []types.CancellationReason{
+		{Code: aws.String("None")},
+		{Code: aws.String("TransactionConflict")},
+	}}
+	if reason, ok := ClassifyError(conflict); !ok || reason != RetryTransactionConflict {
+		t.Errorf("expected transaction conflict to be retryable, got %q, %v", reason, ok)
+	}
+
+	condition := &types.TransactionCanceledException{CancellationReasons: []types.CancellationReason{
+		{Code: aws.String("ConditionalCheckFailed")},
+		{Code: aws.String("TransactionConflict")},
+	}}
+	if _, ok := ClassifyError(condition); ok {
+		t.Error("expected failed condition not to be retryable")
+	}
+}
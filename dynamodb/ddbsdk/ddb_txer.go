@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/acksell/bezos/dynamodb/ddbiface"
 	"github.com/acksell/bezos/dynamodb/table"
@@ -26,6 +27,13 @@ func NewTx(ddb ddbiface.ReadWriteClient, opts ...TxOption) Txer {
 	for _, opt := range opts {
 		opt(&tx.opts)
 	}
+	if len(tx.opts.outbox) > 0 {
+		actions, err := outboxActions(tx.opts.outbox, time.Now().UTC())
+		if err != nil {
+			tx.errs = append(tx.errs, err)
+		}
+		tx.AddAction(actions...)
+	}
 	return tx
 }
 
@@ -36,6 +44,7 @@ type txer struct {
 
 	actions map[actionKey]Action
 	errs    []error // errors from AddAction, checked in Commit
+	audited bool    // whether the audit entries were added
 }
 
 func (tx *txer) AddAction(actions ...Action) {
@@ -53,6 +62,10 @@ func (tx *txer) Commit(ctx context.Context) error {
 	if len(tx.errs) > 0 {
 		return errors.Join(tx.errs...)
 	}
+	if tx.opts.audit && !tx.audited {
+		tx.AddAction(auditActions(tx.actions, tx.opts.cause, time.Now().UTC())...)
+		tx.audited = true
+	}
 	switch len(tx.actions) {
 	case 0:
 		return nil
@@ -65,6 +78,9 @@ func (tx *txer) Commit(ctx context.Context) error {
 				if err != nil {
 					return fmt.Errorf("failed to convert put to put item: %w", err)
 				}
+				if tx.opts.cause != "" {
+					stampItem(put.Item, tx.opts.cause)
+				}
 				_, err = tx.awsddb.PutItem(ctx, put)
 				if err != nil {
 					return fmt.Errorf("failed to put item: %w", err)
@@ -74,6 +90,10 @@ func (tx *txer) Commit(ctx context.Context) error {
 				if err != nil {
 					return fmt.Errorf("failed to convert update to update item: %w", err)
 				}
+				if tx.opts.cause != "" {
+					update.UpdateExpression, update.ExpressionAttributeNames, update.ExpressionAttributeValues = stampUpdate(
+						update.UpdateExpression, update.ExpressionAttributeNames, update.ExpressionAttributeValues, tx.opts.cause)
+				}
 				_, err = tx.awsddb.UpdateItem(ctx, update)
 				if err != nil {
 					return fmt.Errorf("failed to update item: %w", err)
@@ -112,6 +132,9 @@ func (tx *txer) Commit(ctx context.Context) error {
 			if err != nil {
 				return fmt.Errorf("failed to convert action to transact write item: %w", err)
 			}
+			if tx.opts.cause != "" {
+				stampCause(&twi, tx.opts.cause)
+			}
 			txInputs = append(txInputs, twi)
 		}
 		params := &dynamodbv2.TransactWriteItemsInput{
@@ -134,6 +157,9 @@ type TxOption func(*txOpts) *txOpts
 
 type txOpts struct {
 	idempotencyToken string
+	outbox           []OutboxEvent
+	cause            string
+	audit            bool
 }
 
 // IdempotencyTokens last for 10 minutes according to AWS documentation.
@@ -146,3 +172,35 @@ func WithIdempotencyToken(token string) TxOption {
 		return opts
 	}
 }
+
+// WithOutbox writes outbox records of events to the [OutboxTable] in the transaction,
+// so that they are published if and only if the transaction commits.
+// The relay of package outbox publishes them.
+func WithOutbox(events ...OutboxEvent) TxOption {
+	return func(opts *txOpts) *txOpts {
+		opts.outbox = append(opts.outbox, events...)
+		return opts
+	}
+}
+
+// WithCause labels the transaction with the command or domain event that caused it,
+// e.g. "OrderPlaced". Its puts and updates stamp the label onto the [CauseAttr]
+// attribute of the items they write. Deleted items keep no trace of it, use
+// [WithAuditLog] to record those.
+func WithCause(cause string) TxOption {
+	return func(opts *txOpts) *txOpts {
+		opts.cause = cause
+		return opts
+	}
+}
+
+// WithAuditLog writes an [AuditEntry] to the [AuditTable] for every item the
+// transaction puts, updates or deletes, in the same transaction. Combined with
+// [WithCause] the audit log shows which commands changed an item over time.
+// Each entry is an extra action, and counts towards the 100 actions per transaction.
+func WithAuditLog() TxOption {
+	return func(opts *txOpts) *txOpts {
+		opts.audit = true
+		return opts
+	}
+}
@@ -639,3 +639,148 @@ func TestTransaction_GetItemsTx_TooManyItems(t *testing.T) {
 		t.Fatal("expected error for >100 items in transaction")
 	}
 }
+
+func TestTransaction_WithOutbox(t *testing.T) {
+	db := NewMemoryClient(txTestTable, OutboxTable)
+	ctx := context.Background()
+
+	entity := &testEntity{PK: "user#1", SK: "profile", Name: "Alice"}
+	pk := txTestKey(entity.PK, entity.SK)
+	tx := db.NewTx(WithOutbox(OutboxEvent{ID: "evt-1", Type: "UserCreated", Payload: map[string]string{"name": "Alice"}}))
+	tx.AddAction(NewUnsafePut(txTestTable, pk, entity).WithCondition(expression.AttributeNotExists(expression.Name("pk"))))
+	if err := tx.Commit(ctx); err != nil {
+		t.Fatalf("Transaction commit failed: %v", err)
+	}
+
+	item, err := db.NewLookup().GetItem(ctx, GetItemRequest{Table: OutboxTable, Key: OutboxKey("evt-1")})
+	if err != nil || item == nil {
+		t.Fatalf("expected outbox record, got %v, %v", item, err)
+	}
+	rec, err := DecodeOutboxRecord(item)
+	if err != nil {
+		t.Fatalf("DecodeOutboxRecord failed: %v", err)
+	}
+	var payload map[string]string
+	if err := rec.DecodePayload(&payload); err != nil {
+		t.Fatalf("DecodePayload failed: %v", err)
+	}
+	if rec.Type != "UserCreated" || payload["name"] != "Alice" || rec.CreatedAt.IsZero() {
+		t.Errorf("unexpected record %+v with payload %v", rec, payload)
+	}
+	if _, ok := item[OutboxPendingAttr]; !ok {
+		t.Errorf("expected record to be pending")
+	}
+
+	// A failing transaction writes no outbox record.
+	tx = db.NewTx(WithOutbox(OutboxEvent{ID: "evt-2", Type: "UserCreated"}))
+	tx.AddAction(NewUnsafePut(txTestTable, pk, entity).WithCondition(expression.AttributeNotExists(expression.Name("pk"))))
+	if err := tx.Commit(ctx); err == nil {
+		t.Fatal("expected transaction to fail due to condition")
+	}
+	item, err = db.NewLookup().GetItem(ctx, GetItemRequest{Table: OutboxTable, Key: OutboxKey("evt-2")})
+	if err != nil || item != nil {
+		t.Errorf("expected no outbox record, got %v, %v", item, err)
+	}
+}
+
+func TestTransaction_WithCauseAndAuditLog(t *testing.T) {
+	db := NewMemoryClient(txTestTable, AuditTable)
+	ctx := context.Background()
+
+	entity := &testEntity{PK: "user#1", SK: "profile", Name: "Alice"}
+	pk := txTestKey(entity.PK, entity.SK)
+	tx := db.NewTx(WithCause("UserRegistered"), WithAuditLog())
+	tx.AddAction(NewUnsafePut(txTestTable, pk, entity))
+	if err := tx.Commit(ctx); err != nil {
+		t.Fatalf("Transaction commit failed: %v", err)
+	}
+	tx = db.NewTx(WithCause("UserRenamed"), WithAuditLog())
+	tx.AddAction(NewUnsafeUpdate(txTestTable, pk).AddOp(SetFieldOp("name", "Bob")))
+	if err := tx.Commit(ctx); err != nil {
+		t.Fatalf("Transaction commit failed: %v", err)
+	}
+
+	item, err := db.NewLookup().GetItem(ctx, GetItemRequest{Table: txTestTable, Key: pk})
+	if err != nil || item == nil {
+		t.Fatalf("expected item, got %v, %v", item, err)
+	}
+	var cause string
+	if err := attributevalue.Unmarshal(item[CauseAttr], &cause); err != nil || cause != "UserRenamed" {
+		t.Errorf("expected cause UserRenamed, got %q, %v", cause, err)
+	}
+
+	tx = db.NewTx(WithCause("UserDeleted"), WithAuditLog())
+	tx.AddAction(NewDelete(txTestTable, pk))
+	if err := tx.Commit(ctx); err != nil {
+		t.Fatalf("Transaction commit failed: %v", err)
+	}
+
+	entries, err := QueryAuditLog(ctx, db, txTestTable, pk)
+	if err != nil {
+		t.Fatalf("QueryAuditLog failed: %v", err)
+	}
+	want := []struct {
+		op    AuditOp
+		cause string
+	}{{AuditOpPut, "UserRegistered"}, {AuditOpUpdate, "UserRenamed"}, {AuditOpDelete, "UserDeleted"}}
+	if len(entries) != len(want) {
+		t.Fatalf("expected %d audit entries, got %+v", len(want), entries)
+	}
+	for i, w := range want {
+		if entries[i].Op != w.op || entries[i].Cause != w.cause || entries[i].Table != txTestTable.Name {
+			t.Errorf("entry %d: expected %s by %s, got %+v", i, w.op, w.cause, entries[i])
+		}
+	}
+}
+
+func TestTransaction_WithCauseLeavesActionsUnchanged(t *testing.T) {
+	db := NewMemoryClient(txTestTable)
+	ctx := context.Background()
+	getCause := func(pk table.PrimaryKey) (string, bool) {
+		t.Helper()
+		item, err := db.NewLookup().GetItem(ctx, GetItemRequest{Table: txTestTable, Key: pk})
+		if err != nil || item == nil {
+			t.Fatalf("expected item, got %v, %v", item, err)
+		}
+		var cause string
+		av, ok := item[CauseAttr]
+		if ok {
+			if err := attributevalue.Unmarshal(av, &cause); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return cause, ok
+	}
+
+	first := &testEntity{PK: "user#1", SK: "profile", Name: "Alice"}
+	second := &testEntity{PK: "user#2", SK: "profile", Name: "Bob"}
+	put := NewUnsafePut(txTestTable, txTestKey(first.PK, first.SK), first)
+	tx := db.NewTx(WithCause("UserRegistered"))
+	tx.AddAction(put, NewUnsafePut(txTestTable, txTestKey(second.PK, second.SK), second))
+	if err := tx.Commit(ctx); err != nil {
+		t.Fatalf("Transaction commit failed: %v", err)
+	}
+	if cause, _ := getCause(put.PrimaryKey()); cause != "UserRegistered" {
+		t.Errorf("expected cause UserRegistered, got %q", cause)
+	}
+
+	// The same action in a transaction without a cause doesn't carry the earlier one.
+	tx = db.NewTx()
+	tx.AddAction(put)
+	if err := tx.Commit(ctx); err != nil {
+		t.Fatalf("Transaction commit failed: %v", err)
+	}
+	if cause, ok := getCause(put.PrimaryKey()); ok {
+		t.Errorf("expected no cause, got %q", cause)
+	}
+
+	// An update without SET clauses gets one for the cause.
+	tx = db.NewTx(WithCause("EmailCleared"))
+	tx.AddAction(NewUnsafeUpdate(txTestTable, put.PrimaryKey()).AddOp(RemoveFieldOp("email")))
+	if err := tx.Commit(ctx); err != nil {
+		t.Fatalf("Transaction commit failed: %v", err)
+	}
+	if cause, _ := getCause(put.PrimaryKey()); cause != "EmailCleared" {
+		t.Errorf("expected cause EmailCleared, got %q", cause)
+	}
+}
@@ -0,0 +1,137 @@
+// Package ddbotel provides an OpenTelemetry [ddbsdk.Observer].
+//
+// It creates a span per observed client operation and records a latency histogram:
+//
+//	client := ddbsdk.NewClient(awsClient, ddbsdk.WithObserver(ddbotel.NewObserver()))
+//
+// Spans and metrics carry the tables, index, entity types, item counts,
+// consumed capacity and retries of the operation.
+package ddbotel
+
+import (
+	"context"
+	"time"
+
+	"github.com/acksell/bezos/dynamodb/ddbsdk"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/acksell/bezos/dynamodb/ddbsdk/ddbotel"
+
+// Attribute keys set on spans. Table and index names follow the OpenTelemetry
+// semantic conventions for DynamoDB, the rest are specific to ddbsdk.
+const (
+	AttrTableNames       = attribute.Key("aws.dynamodb.table_names")
+	AttrIndexName        = attribute.Key("aws.dynamodb.index_name")
+	AttrConsumedCapacity = attribute.Key("aws.dynamodb.consumed_capacity")
+	AttrOperation        = attribute.Key("ddbsdk.operation")
+	AttrEntityTypes      = attribute.Key("ddbsdk.entity_types")
+	AttrItemCount        = attribute.Key("ddbsdk.item_count")
+	AttrReturnedCount    = attribute.Key("ddbsdk.returned_count")
+	AttrRetries          = attribute.Key("ddbsdk.retries")
+)
+
+// NewObserver creates an observer using the global tracer and meter providers,
+// unless configured otherwise with [WithTracerProvider] and [WithMeterProvider].
+func NewObserver(opts ...Option) ddbsdk.Observer {
+	o := options{
+		tracerProvider: otel.GetTracerProvider(),
+		meterProvider:  otel.GetMeterProvider(),
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	meter := o.meterProvider.Meter(instrumentationName)
+	duration, err := meter.Float64Histogram("ddbsdk.operation.duration",
+		metric.WithDescription("Duration of ddbsdk client operations, including retries."),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		otel.Handle(err)
+	}
+	return &observer{
+		tracer:   o.tracerProvider.Tracer(instrumentationName),
+		duration: duration,
+	}
+}
+
+type observer struct {
+	tracer   trace.Tracer
+	duration metric.Float64Histogram
+}
+
+func (o *observer) Observe(ctx context.Context, op ddbsdk.Operation) (context.Context, func(ddbsdk.OperationResult)) {
+	attrs := []attribute.KeyValue{
+		AttrOperation.String(op.Name),
+		AttrTableNames.StringSlice(op.Tables),
+	}
+	if op.Index != "" {
+		attrs = append(attrs, AttrIndexName.String(op.Index))
+	}
+	if len(op.EntityTypes) > 0 {
+		attrs = append(attrs, AttrEntityTypes.StringSlice(op.EntityTypes))
+	}
+	if op.ItemCount > 0 {
+		attrs = append(attrs, AttrItemCount.Int(op.ItemCount))
+	}
+
+	start := time.Now()
+	ctx, span := o.tracer.Start(ctx, "ddbsdk."+op.Name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
+	return ctx, func(res ddbsdk.OperationResult) {
+		span.SetAttributes(
+			AttrConsumedCapacity.Float64(res.ConsumedCapacity),
+			AttrRetries.Int(res.Retries),
+		)
+		if res.ItemCount > 0 {
+			span.SetAttributes(AttrReturnedCount.Int(res.ItemCount))
+		}
+		if res.Err != nil {
+			span.RecordError(res.Err)
+			span.SetStatus(codes.Error, res.Err.Error())
+		}
+		span.End()
+
+		if o.duration != nil {
+			// Only low-cardinality attributes on the metric.
+			metricAttrs := []attribute.KeyValue{
+				AttrOperation.String(op.Name),
+				AttrTableNames.StringSlice(op.Tables),
+				attribute.Bool("error", res.Err != nil),
+			}
+			if op.Index != "" {
+				metricAttrs = append(metricAttrs, AttrIndexName.String(op.Index))
+			}
+			o.duration.Record(ctx, time.Since(start).Seconds(), metric.WithAttributes(metricAttrs...))
+		}
+	}
+}
+
+type Option func(*options)
+
+type options struct {
+	tracerProvider trace.TracerProvider
+	meterProvider  metric.MeterProvider
+}
+
+// WithTracerProvider sets the tracer provider instead of the global one.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(o *options) {
+		o.tracerProvider = tp
+	}
+}
+
+// WithMeterProvider sets the meter provider instead of the global one.
+func WithMeterProvider(mp metric.MeterProvider) Option {
+	return func(o *options) {
+		o.meterProvider = mp
+	}
+}
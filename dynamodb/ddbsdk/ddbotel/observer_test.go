@@ -0,0 +1,95 @@
+package ddbotel
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/acksell/bezos/dynamodb/ddbsdk"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+type recordingProvider struct {
+	noop.TracerProvider
+	spans []*recordingSpan
+}
+
+func (p *recordingProvider) Tracer(string, ...trace.TracerOption) trace.Tracer {
+	return &recordingTracer{p: p}
+}
+
+type recordingTracer struct {
+	noop.Tracer
+	p *recordingProvider
+}
+
+func (t *recordingTracer) Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	cfg := trace.NewSpanStartConfig(opts...)
+	s := &recordingSpan{name: name, attrs: cfg.Attributes()}
+	t.p.spans = append(t.p.spans, s)
+	return ctx, s
+}
+
+type recordingSpan struct {
+	noop.Span
+	name   string
+	attrs  []attribute.KeyValue
+	status codes.Code
+	ended  bool
+}
+
+func (s *recordingSpan) SetAttributes(kv ...attribute.KeyValue) { s.attrs = append(s.attrs, kv...) }
+func (s *recordingSpan) SetStatus(c codes.Code, _ string)       { s.status = c }
+func (s *recordingSpan) End(...trace.SpanEndOption)             { s.ended = true }
+
+func (s *recordingSpan) attr(k attribute.Key) (attribute.Value, bool) {
+	for _, kv := range s.attrs {
+		if kv.Key == k {
+			return kv.Value, true
+		}
+	}
+	return attribute.Value{}, false
+}
+
+func TestObserver_CreatesSpans(t *testing.T) {
+	tp := &recordingProvider{}
+	o := NewObserver(WithTracerProvider(tp))
+
+	_, end := o.Observe(context.Background(), ddbsdk.Operation{
+		Name:        "Txer.Commit",
+		Tables:      []string{"users"},
+		EntityTypes: []string{"User"},
+		ItemCount:   2,
+	})
+	end(ddbsdk.OperationResult{ConsumedCapacity: 4, Retries: 1, Err: errors.New("boom")})
+
+	if len(tp.spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(tp.spans))
+	}
+	span := tp.spans[0]
+	if span.name != "ddbsdk.Txer.Commit" {
+		t.Errorf("unexpected span name %q", span.name)
+	}
+	if !span.ended {
+		t.Error("expected span to be ended")
+	}
+	if span.status != codes.Error {
+		t.Errorf("expected error status, got %v", span.status)
+	}
+	if v, ok := span.attr(AttrEntityTypes); !ok || v.AsStringSlice()[0] != "User" {
+		t.Errorf("expected entity types attribute, got %v", v)
+	}
+	if v, ok := span.attr(AttrItemCount); !ok || v.AsInt64() != 2 {
+		t.Errorf("expected item count 2, got %v", v)
+	}
+	if v, ok := span.attr(AttrConsumedCapacity); !ok || v.AsFloat64() != 4 {
+		t.Errorf("expected consumed capacity 4, got %v", v)
+	}
+	if v, ok := span.attr(AttrRetries); !ok || v.AsInt64() != 1 {
+		t.Errorf("expected 1 retry, got %v", v)
+	}
+}
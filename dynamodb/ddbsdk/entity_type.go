@@ -0,0 +1,65 @@
+package ddbsdk
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/acksell/bezos/dynamodb/index/indices"
+	"github.com/acksell/bezos/dynamodb/table"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// entityTypeName returns the discriminator value written for entity e:
+// the EntityType of its registered index, or else its Go type name.
+func entityTypeName(e any) string {
+	t := reflect.TypeOf(e)
+	if entry, ok := indices.Lookup(t); ok {
+		return entry.EntityTypeName()
+	}
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil {
+		return ""
+	}
+	return t.Name()
+}
+
+// EntityTypeOf returns the entity type of an item of table t. It reads the table's
+// EntityTypeKey attribute, and falls back to matching the item's primary key against
+// the key patterns of the registered indices, e.g. for items written before the table
+// had a discriminator. Returns "" if the type can't be determined.
+func EntityTypeOf(t table.TableDefinition, item Item) string {
+	if t.EntityTypeKey != "" {
+		if s, ok := item[t.EntityTypeKey].(*types.AttributeValueMemberS); ok {
+			return s.Value
+		}
+	}
+	key, err := t.ExtractPrimaryKey(item)
+	if err != nil {
+		return ""
+	}
+	if entry, ok := indices.MatchKey(t.Name, key.Values.PartitionKey, key.Values.SortKey); ok {
+		return entry.EntityTypeName()
+	}
+	return ""
+}
+
+// SortKeyInRange reports whether the string sort key of an item of table t is between
+// low and high, inclusive. Generated item collection queries use it to drop the items
+// of a member that fall outside the member's sort key range.
+func SortKeyInRange(t table.TableDefinition, item Item, low, high string) bool {
+	sk, ok := item[t.KeyDefinitions.SortKey.Name].(*types.AttributeValueMemberS)
+	return ok && low <= sk.Value && sk.Value <= high
+}
+
+// DecodeEntity unmarshals item into a new E.
+func DecodeEntity[E any](item Item) (*E, error) {
+	e := new(E)
+	if err := attributevalue.UnmarshalMap(item, e); err != nil {
+		return nil, fmt.Errorf("decoding %T: %w", *e, err)
+	}
+	return e, nil
+}
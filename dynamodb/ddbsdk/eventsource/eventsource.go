@@ -0,0 +1,195 @@
+// Package eventsource stores aggregates as append-only streams of domain events.
+//
+// Each aggregate instance has a stream of typed events in an event table, one item
+// per event under the sort key EVENT#<seq>, numbered from 1. Declare the events of an
+// aggregate type by registering a [Stream]:
+//
+//	var orderStream = eventsource.Add(eventsource.Stream{
+//	    Table:     eventsource.EventsTable,
+//	    Aggregate: "Order",
+//	    Events:    []any{OrderPlaced{}, OrderShipped{}},
+//	})
+//
+// A [Store] appends events with conditional puts in one transaction, so concurrent
+// writers can't write the same sequence number twice or leave a gap, and applies the
+// read model updates of its [Projection]s in the same transaction. [Store.Load]
+// rebuilds an [Aggregate] by applying its events in order, starting from its latest
+// [Store.Snapshot] if it has one:
+//
+//	o := &Order{}
+//	version, err := store.Load(ctx, orderStream, orderID, o)
+//	// check the command against o ...
+//	_, err = store.Append(ctx, orderStream, orderID, version, OrderShipped{At: now})
+//
+// ddbgen generates a sealed event interface and a decoder per stream, and adds the
+// streams and their events to the schema.
+package eventsource
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/acksell/bezos/dynamodb/table"
+)
+
+// EventsTable is the default event table. Streams of several aggregate types can share
+// it, their partition keys are prefixed with the aggregate type. Any table with string
+// partition and sort keys can be used instead.
+var EventsTable = table.TableDefinition{
+	Name: "bezos_events",
+	KeyDefinitions: table.PrimaryKeyDefinition{
+		PartitionKey: table.KeyDef{Name: "pk", Kind: table.KeyKindS},
+		SortKey:      table.KeyDef{Name: "sk", Kind: table.KeyKindS},
+	},
+}
+
+// Stream declares the event streams of an aggregate type.
+type Stream struct {
+	// Table stores the events. It needs string partition and sort keys.
+	Table table.TableDefinition
+	// Aggregate is the aggregate type, e.g. "Order". It prefixes the partition keys
+	// of its streams, so it must be unique within the table.
+	Aggregate string
+	// Events are zero values of the event structs, e.g. OrderPlaced{}.
+	// An event is stored with its [EventType].
+	Events []any
+
+	types map[string]reflect.Type
+}
+
+// Typer is implemented by events stored under another type name than their Go type
+// name, e.g. to keep reading old events after the Go type was renamed.
+type Typer interface {
+	EventType() string
+}
+
+// EventType returns the stored type name of an event: its EventType method if it
+// implements [Typer], or else its Go type name.
+func EventType(event any) string {
+	if t, ok := event.(Typer); ok {
+		return t.EventType()
+	}
+	t := reflect.TypeOf(event)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil {
+		return ""
+	}
+	return t.Name()
+}
+
+// EventTypes returns the stored type names of the stream's events, in declaration order.
+func (s *Stream) EventTypes() []string {
+	names := make([]string, len(s.Events))
+	for i, e := range s.Events {
+		names[i] = EventType(e)
+	}
+	return names
+}
+
+func (s *Stream) init() error {
+	if s.Aggregate == "" {
+		return fmt.Errorf("stream aggregate is required")
+	}
+	if strings.Contains(s.Aggregate, "#") {
+		return fmt.Errorf("stream %s: aggregate mustn't contain #", s.Aggregate)
+	}
+	keys := s.Table.KeyDefinitions
+	if s.Table.Name == "" || keys.PartitionKey.Kind != table.KeyKindS || keys.SortKey.Kind != table.KeyKindS {
+		return fmt.Errorf("stream %s: table %q needs string partition and sort keys", s.Aggregate, s.Table.Name)
+	}
+	if len(s.Events) == 0 {
+		return fmt.Errorf("stream %s: no events", s.Aggregate)
+	}
+	s.types = make(map[string]reflect.Type, len(s.Events))
+	for _, e := range s.Events {
+		t := reflect.TypeOf(e)
+		for t != nil && t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		if t == nil || t.Kind() != reflect.Struct {
+			return fmt.Errorf("stream %s: event %T is not a struct", s.Aggregate, e)
+		}
+		name := EventType(e)
+		if name == "" {
+			return fmt.Errorf("stream %s: event %T has no type name", s.Aggregate, e)
+		}
+		if _, dup := s.types[name]; dup {
+			return fmt.Errorf("stream %s: event type %q is declared twice", s.Aggregate, name)
+		}
+		s.types[name] = t
+	}
+	return nil
+}
+
+// checkEvent returns the stored type name of event, or an error if the stream
+// doesn't declare it.
+func (s *Stream) checkEvent(event any) (string, error) {
+	name := EventType(event)
+	t, ok := s.types[name]
+	if !ok {
+		return "", fmt.Errorf("stream %s has no event type %q", s.Aggregate, name)
+	}
+	et := reflect.TypeOf(event)
+	if et.Kind() == reflect.Pointer {
+		et = et.Elem()
+	}
+	if et != t {
+		return "", fmt.Errorf("stream %s: event type %q is %s, not %T", s.Aggregate, name, t, event)
+	}
+	return name, nil
+}
+
+var (
+	mu      sync.RWMutex
+	streams []*Stream
+)
+
+// Add registers a stream and returns it for use with a [Store].
+// Panics if the stream is invalid or its aggregate is already registered for the table.
+//
+// Example:
+//
+//	var orderStream = eventsource.Add(eventsource.Stream{...})
+func Add(s Stream) *Stream {
+	if err := s.init(); err != nil {
+		panic("eventsource: " + err.Error())
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	for _, other := range streams {
+		if other.Table.Name == s.Table.Name && other.Aggregate == s.Aggregate {
+			panic("eventsource: stream " + s.Aggregate + " is already registered for table " + s.Table.Name)
+		}
+	}
+	streams = append(streams, &s)
+	return &s
+}
+
+// Streams returns all registered streams, ordered by table and aggregate.
+func Streams() []*Stream {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	out := slices.Clone(streams)
+	slices.SortFunc(out, func(a, b *Stream) int {
+		if c := strings.Compare(a.Table.Name, b.Table.Name); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Aggregate, b.Aggregate)
+	})
+	return out
+}
+
+// Clear resets the registry. Useful for testing.
+func Clear() {
+	mu.Lock()
+	defer mu.Unlock()
+	streams = nil
+}
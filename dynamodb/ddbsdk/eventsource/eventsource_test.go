@@ -0,0 +1,324 @@
+package eventsource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/acksell/bezos/dynamodb/ddbsdk"
+	"github.com/acksell/bezos/dynamodb/table"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+)
+
+type AccountOpened struct {
+	Owner string `dynamodbav:"owner"`
+}
+
+type Deposited struct {
+	Amount int `dynamodbav:"amount"`
+}
+
+type Withdrawn struct {
+	Amount int `dynamodbav:"amount"`
+}
+
+func (Withdrawn) EventType() string { return "MoneyWithdrawn" }
+
+type Account struct {
+	Owner   string `dynamodbav:"owner"`
+	Balance int    `dynamodbav:"balance"`
+	// Applied counts the events applied since the aggregate was loaded or restored.
+	Applied int `dynamodbav:"-"`
+}
+
+func (a *Account) Apply(event any) error {
+	a.Applied++
+	switch e := event.(type) {
+	case AccountOpened:
+		a.Owner = e.Owner
+	case Deposited:
+		a.Balance += e.Amount
+	case Withdrawn:
+		a.Balance -= e.Amount
+	default:
+		return fmt.Errorf("unexpected event %T", event)
+	}
+	return nil
+}
+
+// balancesTable holds the Balance read model.
+var balancesTable = table.TableDefinition{
+	Name: "balances",
+	KeyDefinitions: table.PrimaryKeyDefinition{
+		PartitionKey: table.KeyDef{Name: "id", Kind: table.KeyKindS},
+	},
+}
+
+type Balance struct {
+	ID      string `dynamodbav:"id"`
+	Balance int    `dynamodbav:"balance"`
+	Version int64  `dynamodbav:"version"`
+}
+
+func (b *Balance) IsValid() error { return nil }
+
+var accountStream = &Stream{
+	Table:     EventsTable,
+	Aggregate: "Account",
+	Events:    []any{AccountOpened{}, Deposited{}, Withdrawn{}},
+}
+
+func init() {
+	if err := accountStream.init(); err != nil {
+		panic(err)
+	}
+}
+
+var testTime = time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+func newTestStore(t *testing.T, opts ...StoreOption) (*Store, *ddbsdk.Client) {
+	t.Helper()
+	db := ddbsdk.NewMemoryClient(EventsTable, balancesTable)
+	opts = append([]StoreOption{WithClock(func() time.Time { return testTime })}, opts...)
+	return New(db, opts...), db
+}
+
+func load(t *testing.T, s *Store, id string) (*Account, int64) {
+	t.Helper()
+	a := &Account{}
+	version, err := s.Load(context.Background(), accountStream, id, a)
+	if err != nil {
+		t.Fatalf("loading %s: %v", id, err)
+	}
+	return a, version
+}
+
+func TestAppendAndLoad(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newTestStore(t)
+
+	records, err := s.Append(ctx, accountStream, "a1", 0, AccountOpened{Owner: "ada"}, Deposited{Amount: 100})
+	if err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	if len(records) != 2 || records[0].Seq != 1 || records[1].Seq != 2 || records[1].Type != "Deposited" {
+		t.Fatalf("unexpected records %+v", records)
+	}
+	if _, err := s.Append(ctx, accountStream, "a1", 2, Withdrawn{Amount: 30}); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+
+	a, version := load(t, s, "a1")
+	if version != 3 || a.Owner != "ada" || a.Balance != 70 {
+		t.Errorf("loaded version %d, %+v", version, a)
+	}
+
+	events, err := s.Events(ctx, accountStream, "a1", 1)
+	if err != nil {
+		t.Fatalf("events: %v", err)
+	}
+	if len(events) != 2 || events[1].Type != "MoneyWithdrawn" || events[1].Event != (Withdrawn{Amount: 30}) || !events[1].OccurredAt.Equal(testTime) {
+		t.Errorf("unexpected events %+v", events)
+	}
+
+	if _, version := load(t, s, "unknown"); version != 0 {
+		t.Errorf("version of a new aggregate = %d, want 0", version)
+	}
+}
+
+func TestAppend_Conflict(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newTestStore(t)
+
+	if _, err := s.Append(ctx, accountStream, "a1", 0, AccountOpened{Owner: "ada"}); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	for _, tc := range []struct {
+		name     string
+		expected int64
+		events   []any
+	}{
+		{"stale version", 0, []any{AccountOpened{Owner: "bob"}}},
+		{"stale version, several events", 0, []any{AccountOpened{Owner: "bob"}, Deposited{Amount: 1}}},
+		{"version ahead of the stream", 2, []any{Deposited{Amount: 1}}},
+	} {
+		_, err := s.Append(ctx, accountStream, "a1", tc.expected, tc.events...)
+		if !errors.Is(err, ErrConflict) {
+			t.Errorf("%s: got %v, want ErrConflict", tc.name, err)
+		}
+	}
+
+	a, version := load(t, s, "a1")
+	if version != 1 || a.Owner != "ada" || a.Balance != 0 {
+		t.Errorf("conflicting appends were written: version %d, %+v", version, a)
+	}
+}
+
+func TestAppend_Invalid(t *testing.T) {
+	s, _ := newTestStore(t)
+	type Closed struct{}
+	_, err := s.Append(context.Background(), accountStream, "a1", 0, Closed{})
+	if err == nil || !strings.Contains(err.Error(), `no event type "Closed"`) {
+		t.Errorf("got %v, want an unknown event type error", err)
+	}
+}
+
+// balanceProjection keeps a Balance per account, with a version condition so that
+// it fails if it misses an event.
+var balanceProjection = ProjectionFunc(func(ctx context.Context, records []Record) ([]ddbsdk.Action, error) {
+	first := records[0]
+	b := &Balance{ID: first.AggregateID, Version: first.Seq - 1}
+	// A real projection would read the current balance, here the events carry it.
+	for _, r := range records {
+		switch e := r.Event.(type) {
+		case Deposited:
+			b.Balance += e.Amount
+		case Withdrawn:
+			b.Balance -= e.Amount
+		}
+		b.Version = r.Seq
+	}
+	key := table.PrimaryKey{Definition: balancesTable.KeyDefinitions, Values: table.PrimaryKeyValues{PartitionKey: b.ID}}
+	cond := expression.AttributeNotExists(expression.Name("id"))
+	if first.Seq > 1 {
+		cond = expression.Name("version").Equal(expression.Value(first.Seq - 1))
+	}
+	return []ddbsdk.Action{ddbsdk.NewUnsafePut(balancesTable, key, b).WithCondition(cond)}, nil
+})
+
+func TestAppend_Projection(t *testing.T) {
+	ctx := context.Background()
+	s, db := newTestStore(t, WithProjection(balanceProjection))
+
+	if _, err := s.Append(ctx, accountStream, "a1", 0, AccountOpened{Owner: "ada"}, Deposited{Amount: 5}); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	getBalance := func() Balance {
+		t.Helper()
+		item, err := db.NewLookup().GetItem(ctx, ddbsdk.GetItemRequest{
+			Table: balancesTable,
+			Key:   table.PrimaryKey{Definition: balancesTable.KeyDefinitions, Values: table.PrimaryKeyValues{PartitionKey: "a1"}},
+		})
+		if err != nil || item == nil {
+			t.Fatalf("getting balance: %v, %v", item, err)
+		}
+		var b Balance
+		if err := attributevalue.UnmarshalMap(item, &b); err != nil {
+			t.Fatalf("decoding balance: %v", err)
+		}
+		return b
+	}
+	if b := getBalance(); b.Balance != 5 || b.Version != 2 {
+		t.Errorf("balance after append = %+v", b)
+	}
+
+	// A failing projection rolls back the events.
+	failing := ProjectionFunc(func(ctx context.Context, records []Record) ([]ddbsdk.Action, error) {
+		key := table.PrimaryKey{Definition: balancesTable.KeyDefinitions, Values: table.PrimaryKeyValues{PartitionKey: "missing"}}
+		return []ddbsdk.Action{ddbsdk.NewConditionCheck(balancesTable, key, expression.AttributeExists(expression.Name("id")))}, nil
+	})
+	s2 := New(db, WithProjection(balanceProjection, failing))
+	if _, err := s2.Append(ctx, accountStream, "a1", 2, Deposited{Amount: 1}); err == nil {
+		t.Fatal("append with a failing projection succeeded")
+	}
+	if _, version := load(t, s, "a1"); version != 2 {
+		t.Errorf("version after a failed projection = %d, want 2", version)
+	}
+	if b := getBalance(); b.Balance != 5 || b.Version != 2 {
+		t.Errorf("balance after a failed projection = %+v", b)
+	}
+}
+
+func TestAppendTx(t *testing.T) {
+	ctx := context.Background()
+	s, db := newTestStore(t)
+
+	tx := db.NewTx()
+	if _, err := s.AppendTx(ctx, tx, accountStream, "a1", 0, AccountOpened{Owner: "ada"}); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	key := table.PrimaryKey{Definition: balancesTable.KeyDefinitions, Values: table.PrimaryKeyValues{PartitionKey: "a1"}}
+	tx.AddAction(ddbsdk.NewUnsafePut(balancesTable, key, &Balance{ID: "a1"}))
+	if err := tx.Commit(ctx); err != nil {
+		t.Fatalf("commit: %v", err)
+	}
+
+	tx = db.NewTx()
+	if _, err := s.AppendTx(ctx, tx, accountStream, "a1", 0, AccountOpened{Owner: "bob"}); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	if err := tx.Commit(ctx); !IsConflict(err) {
+		t.Errorf("commit of a stale append: got %v, want a conflict", err)
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newTestStore(t)
+
+	if _, err := s.Append(ctx, accountStream, "a1", 0, AccountOpened{Owner: "ada"}, Deposited{Amount: 10}, Deposited{Amount: 20}); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	a, version := load(t, s, "a1")
+	if err := s.Snapshot(ctx, accountStream, "a1", version, a); err != nil {
+		t.Fatalf("snapshot: %v", err)
+	}
+	if _, err := s.Append(ctx, accountStream, "a1", version, Withdrawn{Amount: 5}); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+
+	a, version = load(t, s, "a1")
+	if version != 4 || a.Owner != "ada" || a.Balance != 25 || a.Applied != 1 {
+		t.Errorf("loaded from snapshot: version %d, %+v", version, a)
+	}
+
+	// An older snapshot doesn't replace a newer one.
+	if err := s.Snapshot(ctx, accountStream, "a1", 4, a); err != nil {
+		t.Fatalf("snapshot: %v", err)
+	}
+	if err := s.Snapshot(ctx, accountStream, "a1", 1, &Account{Owner: "stale"}); err != nil {
+		t.Fatalf("stale snapshot: %v", err)
+	}
+	a, version = load(t, s, "a1")
+	if version != 4 || a.Owner != "ada" || a.Balance != 25 || a.Applied != 0 {
+		t.Errorf("loaded after a stale snapshot: version %d, %+v", version, a)
+	}
+}
+
+func TestAdd(t *testing.T) {
+	t.Cleanup(Clear)
+
+	s := Add(Stream{Table: EventsTable, Aggregate: "Account", Events: []any{AccountOpened{}, &Withdrawn{}}})
+	if got := s.EventTypes(); len(got) != 2 || got[1] != "MoneyWithdrawn" {
+		t.Errorf("EventTypes() = %v", got)
+	}
+	if got := Streams(); len(got) != 1 || got[0] != s {
+		t.Errorf("Streams() = %v", got)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		stream Stream
+		want   string
+	}{
+		{"duplicate", Stream{Table: EventsTable, Aggregate: "Account", Events: []any{Deposited{}}}, "already registered"},
+		{"number keys", Stream{Table: balancesTable, Aggregate: "Balance", Events: []any{Deposited{}}}, "string partition and sort keys"},
+		{"no events", Stream{Table: EventsTable, Aggregate: "Empty"}, "no events"},
+		{"not a struct", Stream{Table: EventsTable, Aggregate: "Bad", Events: []any{"x"}}, "not a struct"},
+		{"same type name", Stream{Table: EventsTable, Aggregate: "Twice", Events: []any{Deposited{}, &Deposited{}}}, "declared twice"},
+	} {
+		func() {
+			defer func() {
+				r := recover()
+				if r == nil || !strings.Contains(fmt.Sprint(r), tc.want) {
+					t.Errorf("%s: got panic %v, want %q", tc.name, r, tc.want)
+				}
+			}()
+			Add(tc.stream)
+		}()
+	}
+}
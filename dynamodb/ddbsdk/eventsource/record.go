@@ -0,0 +1,133 @@
+package eventsource
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/acksell/bezos/dynamodb/ddbsdk"
+	"github.com/acksell/bezos/dynamodb/table"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+const (
+	// EventSortKeyPattern is the sort key pattern of event items, in val.Fmt syntax.
+	EventSortKeyPattern = eventSortKeyPrefix + "{seq:%020d}"
+	// SnapshotSortKey is the sort key of snapshot items.
+	SnapshotSortKey = "SNAPSHOT"
+
+	eventSortKeyPrefix = "EVENT#"
+	// dataAttr holds the marshaled event or snapshot state.
+	dataAttr = "data"
+)
+
+// Record is a stored event.
+//
+// Its item has the partition key <Aggregate>#<AggregateID> and the sort key
+// EVENT#<Seq> with Seq zero-padded to 20 digits, so that events sort by sequence
+// number. Its attributes are aggregate, aggregateID, seq, eventType, occurredAt and
+// data, a map of the marshaled event.
+type Record struct {
+	Aggregate   string
+	AggregateID string
+	// Seq is the sequence number of the event in its stream, starting at 1.
+	Seq        int64
+	Type       string
+	OccurredAt time.Time
+	Data       ddbsdk.Item
+	// Event is the decoded event, a value of one of the stream's event types.
+	Event any
+}
+
+// PartitionKeyPattern returns the partition key pattern of the stream's items,
+// in val.Fmt syntax.
+func (s *Stream) PartitionKeyPattern() string {
+	return s.Aggregate + "#{aggregateID}"
+}
+
+func (s *Stream) partitionKey(id string) string {
+	return s.Aggregate + "#" + id
+}
+
+func (s *Stream) key(id, sortKey string) table.PrimaryKey {
+	return table.PrimaryKey{
+		Definition: s.Table.KeyDefinitions,
+		Values: table.PrimaryKeyValues{
+			PartitionKey: s.partitionKey(id),
+			SortKey:      sortKey,
+		},
+	}
+}
+
+func eventSortKey(seq int64) string {
+	return fmt.Sprintf("%s%020d", eventSortKeyPrefix, seq)
+}
+
+// item is an event or snapshot item. Data is written as the data map attribute.
+type item struct {
+	Aggregate   string      `dynamodbav:"aggregate"`
+	AggregateID string      `dynamodbav:"aggregateID"`
+	Seq         int64       `dynamodbav:"seq"`
+	Type        string      `dynamodbav:"eventType,omitempty"`
+	OccurredAt  time.Time   `dynamodbav:"occurredAt"`
+	Data        ddbsdk.Item `dynamodbav:"-"`
+}
+
+func (it *item) IsValid() error {
+	if it.Seq < 1 {
+		return fmt.Errorf("sequence number %d is less than 1", it.Seq)
+	}
+	return nil
+}
+
+func (it *item) MarshalDynamoDBAttributeValue() (types.AttributeValue, error) {
+	type attrs item // without the Marshaler method
+	av, err := attributevalue.Marshal((*attrs)(it))
+	if err != nil {
+		return nil, err
+	}
+	m := av.(*types.AttributeValueMemberM)
+	m.Value[dataAttr] = &types.AttributeValueMemberM{Value: it.Data}
+	return m, nil
+}
+
+func decodeItem(av ddbsdk.Item) (*item, error) {
+	it := &item{}
+	type attrs item
+	if err := attributevalue.UnmarshalMap(av, (*attrs)(it)); err != nil {
+		return nil, err
+	}
+	data, ok := av[dataAttr].(*types.AttributeValueMemberM)
+	if !ok {
+		return nil, fmt.Errorf("item has no %s map", dataAttr)
+	}
+	it.Data = data.Value
+	return it, nil
+}
+
+// decode returns the record of an event item, with the event decoded into its type.
+func (s *Stream) decode(av ddbsdk.Item) (Record, error) {
+	it, err := decodeItem(av)
+	if err != nil {
+		return Record{}, fmt.Errorf("decoding event of stream %s: %w", s.Aggregate, err)
+	}
+	r := Record{
+		Aggregate:   it.Aggregate,
+		AggregateID: it.AggregateID,
+		Seq:         it.Seq,
+		Type:        it.Type,
+		OccurredAt:  it.OccurredAt,
+		Data:        it.Data,
+	}
+	t, ok := s.types[r.Type]
+	if !ok {
+		return Record{}, fmt.Errorf("event %d of %s %s has unknown type %q", r.Seq, s.Aggregate, r.AggregateID, r.Type)
+	}
+	event := reflect.New(t)
+	if err := attributevalue.UnmarshalMap(r.Data, event.Interface()); err != nil {
+		return Record{}, fmt.Errorf("decoding event %d of %s %s: %w", r.Seq, s.Aggregate, r.AggregateID, err)
+	}
+	r.Event = event.Elem().Interface()
+	return r, nil
+}
@@ -0,0 +1,268 @@
+package eventsource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/acksell/bezos/dynamodb/ddbsdk"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// ErrConflict is returned by [Store.Append] when the stream isn't at the expected
+// version, because another writer appended to it since it was loaded.
+// Load the aggregate again and retry the command.
+var ErrConflict = errors.New("eventsource: stream was modified concurrently")
+
+// maxTxActions is the DynamoDB limit of actions per transaction.
+const maxTxActions = 100
+
+// Aggregate is rebuilt from its events by [Store.Load].
+type Aggregate interface {
+	// Apply changes the state of the aggregate by one event, a value of one of the
+	// stream's event types.
+	Apply(event any) error
+}
+
+// Projection updates read models from appended events. Its actions are committed in
+// the transaction that appends the events, so read models never miss an event or
+// see one that wasn't stored.
+type Projection interface {
+	// Project returns the actions that apply records, the events appended to one
+	// stream, to the read models. It can return no actions for events it ignores.
+	Project(ctx context.Context, records []Record) ([]ddbsdk.Action, error)
+}
+
+// ProjectionFunc adapts a function to a [Projection].
+type ProjectionFunc func(ctx context.Context, records []Record) ([]ddbsdk.Action, error)
+
+func (f ProjectionFunc) Project(ctx context.Context, records []Record) ([]ddbsdk.Action, error) {
+	return f(ctx, records)
+}
+
+// Store appends and loads the events of registered streams.
+type Store struct {
+	db   ddbsdk.IO
+	opts storeOpts
+}
+
+type StoreOption func(*storeOpts)
+
+type storeOpts struct {
+	projections []Projection
+	now         func() time.Time
+}
+
+// WithProjection adds projections that are applied with every append.
+func WithProjection(ps ...Projection) StoreOption {
+	return func(o *storeOpts) {
+		o.projections = append(o.projections, ps...)
+	}
+}
+
+// WithClock sets the source of the OccurredAt time of appended events.
+func WithClock(now func() time.Time) StoreOption {
+	return func(o *storeOpts) {
+		o.now = now
+	}
+}
+
+// New creates a store of the events in db.
+func New(db ddbsdk.IO, opts ...StoreOption) *Store {
+	o := storeOpts{now: time.Now}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &Store{db: db, opts: o}
+}
+
+// Append appends events to the stream of aggregate id and applies the store's
+// projections, in one transaction.
+//
+// expected is the version of the aggregate the events were decided on, i.e. the
+// sequence number of its last event as returned by [Store.Load], or 0 for a new
+// aggregate. The events get the following sequence numbers. If the stream has
+// moved on or doesn't have that many events, nothing is written and the error
+// is [ErrConflict].
+func (s *Store) Append(ctx context.Context, stream *Stream, id string, expected int64, events ...any) ([]Record, error) {
+	tx := s.db.NewTx()
+	records, err := s.AppendTx(ctx, tx, stream, id, expected, events...)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		if IsConflict(err) {
+			return nil, fmt.Errorf("%w: appending to %s %s at version %d: %w", ErrConflict, stream.Aggregate, id, expected, err)
+		}
+		return nil, fmt.Errorf("appending to %s %s: %w", stream.Aggregate, id, err)
+	}
+	return records, nil
+}
+
+// AppendTx adds the actions of [Store.Append] to tx, to commit the events together
+// with other writes. A conflict fails the commit of tx, see [IsConflict].
+func (s *Store) AppendTx(ctx context.Context, tx ddbsdk.Txer, stream *Stream, id string, expected int64, events ...any) ([]Record, error) {
+	if len(events) == 0 {
+		return nil, fmt.Errorf("appending to %s %s: no events", stream.Aggregate, id)
+	}
+	if expected < 0 {
+		return nil, fmt.Errorf("appending to %s %s: negative version %d", stream.Aggregate, id, expected)
+	}
+
+	pkName := stream.Table.KeyDefinitions.PartitionKey.Name
+	var actions []ddbsdk.Action
+	if expected > 0 {
+		// Appending after a missing event would leave a gap.
+		actions = append(actions, ddbsdk.NewConditionCheck(stream.Table, stream.key(id, eventSortKey(expected)),
+			expression.AttributeExists(expression.Name(pkName))))
+	}
+
+	now := s.opts.now().UTC()
+	records := make([]Record, len(events))
+	for i, event := range events {
+		eventType, err := stream.checkEvent(event)
+		if err != nil {
+			return nil, err
+		}
+		data, err := attributevalue.MarshalMap(event)
+		if err != nil {
+			return nil, fmt.Errorf("marshaling %s event: %w", eventType, err)
+		}
+		seq := expected + int64(i) + 1
+		records[i] = Record{
+			Aggregate:   stream.Aggregate,
+			AggregateID: id,
+			Seq:         seq,
+			Type:        eventType,
+			OccurredAt:  now,
+			Data:        data,
+			Event:       event,
+		}
+		it := &item{Aggregate: stream.Aggregate, AggregateID: id, Seq: seq, Type: eventType, OccurredAt: now, Data: data}
+		actions = append(actions, ddbsdk.NewUnsafePut(stream.Table, stream.key(id, eventSortKey(seq)), it).
+			WithCondition(expression.AttributeNotExists(expression.Name(pkName))))
+	}
+
+	for _, p := range s.opts.projections {
+		projected, err := p.Project(ctx, records)
+		if err != nil {
+			return nil, fmt.Errorf("projecting events of %s %s: %w", stream.Aggregate, id, err)
+		}
+		actions = append(actions, projected...)
+	}
+	if len(actions) > maxTxActions {
+		return nil, fmt.Errorf("appending to %s %s: %d actions exceed the transaction limit of %d", stream.Aggregate, id, len(actions), maxTxActions)
+	}
+	tx.AddAction(actions...)
+	return records, nil
+}
+
+// IsConflict reports whether err is [ErrConflict], or a failed condition of a commit,
+// e.g. of a transaction that [Store.AppendTx] added events to.
+func IsConflict(err error) bool {
+	var (
+		failed   *types.ConditionalCheckFailedException
+		canceled *types.TransactionCanceledException
+	)
+	switch {
+	case errors.Is(err, ErrConflict), errors.As(err, &failed):
+		return true
+	case errors.As(err, &canceled):
+		if len(canceled.CancellationReasons) == 0 {
+			return true
+		}
+		for _, r := range canceled.CancellationReasons {
+			if r.Code != nil && *r.Code == "ConditionalCheckFailed" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Events returns the events of aggregate id with sequence numbers greater than after,
+// in order.
+func (s *Store) Events(ctx context.Context, stream *Stream, id string, after int64) ([]Record, error) {
+	q := s.db.NewQuery(ddbsdk.QueryPartition(stream.Table, stream.partitionKey(id)).
+		WithSKCondition(ddbsdk.Between(eventSortKey(after+1), eventSortKey(1<<63-1)))).PageSize(100)
+
+	var records []Record
+	for {
+		res, err := q.Next(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("querying events of %s %s: %w", stream.Aggregate, id, err)
+		}
+		for _, av := range res.Items {
+			r, err := stream.decode(av)
+			if err != nil {
+				return nil, err
+			}
+			records = append(records, r)
+		}
+		if res.IsDone {
+			return records, nil
+		}
+	}
+}
+
+// Load rebuilds aggregate id into a, which must be a pointer. It unmarshals the
+// aggregate's latest snapshot into a, if any, and applies the events after it.
+// Returns the version of the aggregate, 0 if it has no events.
+func (s *Store) Load(ctx context.Context, stream *Stream, id string, a Aggregate) (int64, error) {
+	var version int64
+	snapshot, err := s.db.NewLookup().GetItem(ctx, ddbsdk.GetItemRequest{
+		Table: stream.Table,
+		Key:   stream.key(id, SnapshotSortKey),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("getting snapshot of %s %s: %w", stream.Aggregate, id, err)
+	}
+	if snapshot != nil {
+		it, err := decodeItem(snapshot)
+		if err != nil {
+			return 0, fmt.Errorf("decoding snapshot of %s %s: %w", stream.Aggregate, id, err)
+		}
+		if err := attributevalue.UnmarshalMap(it.Data, a); err != nil {
+			return 0, fmt.Errorf("decoding snapshot of %s %s: %w", stream.Aggregate, id, err)
+		}
+		version = it.Seq
+	}
+
+	records, err := s.Events(ctx, stream, id, version)
+	if err != nil {
+		return 0, err
+	}
+	for _, r := range records {
+		if r.Seq != version+1 {
+			return 0, fmt.Errorf("%s %s: event %d follows event %d", stream.Aggregate, id, r.Seq, version)
+		}
+		if err := a.Apply(r.Event); err != nil {
+			return 0, fmt.Errorf("applying event %d (%s) to %s %s: %w", r.Seq, r.Type, stream.Aggregate, id, err)
+		}
+		version = r.Seq
+	}
+	return version, nil
+}
+
+// Snapshot stores a, the state of aggregate id at version, so that [Store.Load] only
+// applies the events after it. An existing snapshot of a later version is kept.
+// The state is marshaled with attributevalue, like an entity.
+func (s *Store) Snapshot(ctx context.Context, stream *Stream, id string, version int64, a Aggregate) error {
+	data, err := attributevalue.MarshalMap(a)
+	if err != nil {
+		return fmt.Errorf("marshaling snapshot of %s %s: %w", stream.Aggregate, id, err)
+	}
+	it := &item{Aggregate: stream.Aggregate, AggregateID: id, Seq: version, OccurredAt: s.opts.now().UTC(), Data: data}
+	pkName := stream.Table.KeyDefinitions.PartitionKey.Name
+	put := ddbsdk.NewUnsafePut(stream.Table, stream.key(id, SnapshotSortKey), it).WithCondition(
+		expression.AttributeNotExists(expression.Name(pkName)).
+			Or(expression.Name("seq").LessThan(expression.Value(version))))
+	err = s.db.PutItem(ctx, put)
+	if err != nil && !IsConflict(err) {
+		return fmt.Errorf("putting snapshot of %s %s: %w", stream.Aggregate, id, err)
+	}
+	return nil
+}
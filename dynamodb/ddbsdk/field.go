@@ -0,0 +1,109 @@
+package ddbsdk
+
+import (
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+)
+
+// Field is a typed reference to an entity attribute.
+//
+// ddbgen generates one per tagged struct field, so attribute names come from the
+// dynamodbav tags and values are checked against the field type at compile time:
+//
+//	db.NewQuery(q).Filter(UserFields.Email.Eq("a@example.com"))
+//	put.WithCondition(OrderFields.Amount.Gt(100))
+//	update.AddOp(UserFields.Name.Set("Alice"))
+type Field[T any] struct {
+	name string
+}
+
+// NewField creates a reference to the attribute with the given name.
+// Generated code calls this, prefer the generated references.
+func NewField[T any](name string) Field[T] {
+	return Field[T]{name: name}
+}
+
+// Name returns the attribute name.
+func (f Field[T]) Name() string {
+	return f.name
+}
+
+// NameBuilder returns the attribute as an expression operand, for conditions not covered by Field.
+func (f Field[T]) NameBuilder() expression.NameBuilder {
+	return expression.Name(f.name)
+}
+
+// Eq builds the condition "attribute = v".
+func (f Field[T]) Eq(v T) expression.ConditionBuilder {
+	return expression.Equal(f.NameBuilder(), expression.Value(v))
+}
+
+// Ne builds the condition "attribute <> v".
+func (f Field[T]) Ne(v T) expression.ConditionBuilder {
+	return expression.NotEqual(f.NameBuilder(), expression.Value(v))
+}
+
+// Gt builds the condition "attribute > v".
+func (f Field[T]) Gt(v T) expression.ConditionBuilder {
+	return expression.GreaterThan(f.NameBuilder(), expression.Value(v))
+}
+
+// Ge builds the condition "attribute >= v".
+func (f Field[T]) Ge(v T) expression.ConditionBuilder {
+	return expression.GreaterThanEqual(f.NameBuilder(), expression.Value(v))
+}
+
+// Lt builds the condition "attribute < v".
+func (f Field[T]) Lt(v T) expression.ConditionBuilder {
+	return expression.LessThan(f.NameBuilder(), expression.Value(v))
+}
+
+// Le builds the condition "attribute <= v".
+func (f Field[T]) Le(v T) expression.ConditionBuilder {
+	return expression.LessThanEqual(f.NameBuilder(), expression.Value(v))
+}
+
+// Between builds the condition "attribute BETWEEN lower AND upper".
+func (f Field[T]) Between(lower, upper T) expression.ConditionBuilder {
+	return expression.Between(f.NameBuilder(), expression.Value(lower), expression.Value(upper))
+}
+
+// In builds the condition "attribute IN (v...)". At least one value is required.
+func (f Field[T]) In(v T, more ...T) expression.ConditionBuilder {
+	others := make([]expression.OperandBuilder, 0, len(more))
+	for _, m := range more {
+		others = append(others, expression.Value(m))
+	}
+	return expression.In(f.NameBuilder(), expression.Value(v), others...)
+}
+
+// Exists builds the condition "attribute_exists(attribute)".
+func (f Field[T]) Exists() expression.ConditionBuilder {
+	return expression.AttributeExists(f.NameBuilder())
+}
+
+// NotExists builds the condition "attribute_not_exists(attribute)".
+func (f Field[T]) NotExists() expression.ConditionBuilder {
+	return expression.AttributeNotExists(f.NameBuilder())
+}
+
+// BeginsWith builds the condition "begins_with(attribute, prefix)".
+// Only meaningful for string and binary attributes.
+func (f Field[T]) BeginsWith(prefix string) expression.ConditionBuilder {
+	return expression.BeginsWith(f.NameBuilder(), prefix)
+}
+
+// Contains builds the condition "contains(attribute, v)", for substrings of strings
+// and elements of sets and lists.
+func (f Field[T]) Contains(v any) expression.ConditionBuilder {
+	return expression.Contains(f.NameBuilder(), v)
+}
+
+// Set returns an update operation setting the attribute to v.
+func (f Field[T]) Set(v T) UpdateOp {
+	return SetFieldOp(f.name, v)
+}
+
+// Remove returns an update operation removing the attribute.
+func (f Field[T]) Remove() UpdateOp {
+	return RemoveFieldOp(f.name)
+}
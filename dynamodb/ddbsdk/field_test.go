@@ -0,0 +1,82 @@
+package ddbsdk
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+)
+
+var testEntityFields = struct {
+	Name Field[string]
+	Age  Field[int]
+}{
+	Name: NewField[string]("name"),
+	Age:  NewField[int]("age"),
+}
+
+func TestField_QueryFilter(t *testing.T) {
+	db := NewMemoryClient(queryTestTable)
+	ctx := context.Background()
+
+	items := []testEntity{
+		{PK: "user#1", SK: "profile#1", Name: "Alice", Age: 30},
+		{PK: "user#1", SK: "profile#2", Name: "Bob", Age: 25},
+		{PK: "user#1", SK: "profile#3", Name: "Charlie", Age: 35},
+	}
+	for _, item := range items {
+		if err := db.PutItem(ctx, NewUnsafePut(queryTestTable, queryTestKey(item.PK, item.SK), &item)); err != nil {
+			t.Fatalf("PutItem failed: %v", err)
+		}
+	}
+
+	result, err := db.NewQuery(QueryPartition(queryTestTable, "user#1")).
+		Filter(testEntityFields.Age.Ge(30).And(testEntityFields.Name.In("Alice", "Bob"))).
+		QueryAll(ctx)
+	if err != nil {
+		t.Fatalf("QueryAll failed: %v", err)
+	}
+	if len(result.Items) != 1 {
+		t.Fatalf("expected 1 item, got %d", len(result.Items))
+	}
+	var got testEntity
+	if err := attributevalue.UnmarshalMap(result.Items[0], &got); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	if got.Name != "Alice" {
+		t.Errorf("expected Alice, got %q", got.Name)
+	}
+}
+
+func TestField_ConditionAndUpdate(t *testing.T) {
+	db := NewMemoryClient(queryTestTable)
+	ctx := context.Background()
+	key := queryTestKey("user#1", "profile")
+
+	e := &testEntity{PK: "user#1", SK: "profile", Name: "Alice", Age: 30}
+	if err := db.PutItem(ctx, NewUnsafePut(queryTestTable, key, e).WithCondition(testEntityFields.Name.NotExists())); err != nil {
+		t.Fatalf("PutItem failed: %v", err)
+	}
+	if err := db.PutItem(ctx, NewUnsafePut(queryTestTable, key, e).WithCondition(testEntityFields.Name.NotExists())); err == nil {
+		t.Fatal("expected condition to fail for existing item")
+	}
+
+	update := NewUnsafeUpdate(queryTestTable, key).
+		AddOp(testEntityFields.Name.Set("Alicia")).
+		WithCondition(testEntityFields.Age.Eq(30))
+	if err := db.UpdateItem(ctx, update); err != nil {
+		t.Fatalf("UpdateItem failed: %v", err)
+	}
+
+	item, err := db.NewLookup().GetItem(ctx, GetItemRequest{Table: queryTestTable, Key: key})
+	if err != nil {
+		t.Fatalf("GetItem failed: %v", err)
+	}
+	var got testEntity
+	if err := attributevalue.UnmarshalMap(item, &got); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	if got.Name != "Alicia" {
+		t.Errorf("expected name %q, got %q", "Alicia", got.Name)
+	}
+}
@@ -14,6 +14,7 @@ type IO interface {
 type Writer interface {
 	NewTx(...TxOption) Txer
 	NewBatch(...BatchOption) Batcher
+	NewBulkWriter(...BulkOption) BulkWriter
 
 	PutItem(context.Context, PutItemAction) error
 	UpdateItem(context.Context, UpdateItemAction) error
@@ -59,6 +60,9 @@ type Getter interface {
 	// some of the items and the old state of the other items.
 	// If you need better isolation guarantees, use GetItemsTx.
 	//
+	// Results are returned in request order, with nil for items that don't exist.
+	// Each item can have its own projection.
+	//
 	// Maximum 100 items per batch (DynamoDB limit).
 	GetItemsBatch(context.Context, ...GetItemRequest) ([]Item, error)
 }
@@ -0,0 +1,146 @@
+package migrate
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/acksell/bezos/dynamodb/ddbexport"
+	"github.com/acksell/bezos/dynamodb/ddbiface"
+	"github.com/acksell/bezos/dynamodb/ddbsdk"
+)
+
+// RunCommand runs a ddb migrate command with the registered migrations:
+//
+//	up        apply pending migrations
+//	status    show the progress of every migration
+//	dry-run   report what pending migrations would change, without writing
+//
+// It is meant for the main package that ddb migrate runs, which imports the
+// packages registering migrations and connects to DynamoDB:
+//
+//	func main() {
+//	    // The runner retries with ddbsdk.DefaultRetryPolicy, so turn off the SDK retries.
+//	    client := dynamodb.NewFromConfig(cfg, func(o *dynamodb.Options) { o.Retryer = aws.NopRetryer{} })
+//	    if err := migrate.RunCommand(context.Background(), client, os.Args[1:], os.Stdout); err != nil {
+//	        fmt.Fprintln(os.Stderr, err)
+//	        os.Exit(1)
+//	    }
+//	}
+func RunCommand(ctx context.Context, ddb ddbiface.ReadWriteClient, args []string, w io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing command, expected up, status or dry-run")
+	}
+	cmd := args[0]
+
+	fs := flag.NewFlagSet("migrate "+cmd, flag.ContinueOnError)
+	fs.SetOutput(w)
+	segments := fs.Int("segments", 4, "parallel scan segments of new migrations")
+	pageSize := fs.Int("page-size", 0, "items per scanned page, 0 lets DynamoDB decide")
+	tableName := fs.String("table", MigrationsTable.Name, "table recording migration progress")
+	samples := fs.Int("samples", 5, "changed items to show per migration in a dry run")
+	asJSON := fs.Bool("json", false, "output JSON")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	migrationsTable := MigrationsTable
+	migrationsTable.Name = *tableName
+	r := NewRunner(ddb,
+		WithMigrationsTable(migrationsTable),
+		WithSegments(*segments),
+		WithPageSize(int32(*pageSize)),
+		WithSamples(*samples),
+		WithLog(w),
+	)
+	if *asJSON {
+		r.opts.log = io.Discard
+	}
+
+	switch cmd {
+	case "status":
+		statuses, err := r.Status(ctx)
+		if err != nil {
+			return err
+		}
+		if *asJSON {
+			return writeJSON(w, statuses)
+		}
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tSTATE\tSCANNED\tCHANGED\tSEGMENTS\tDESCRIPTION")
+		for _, s := range statuses {
+			segs := "-"
+			if s.Segments > 0 {
+				segs = fmt.Sprintf("%d/%d", s.SegmentsDone, s.Segments)
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%s\t%s\n", s.ID, s.State, s.Scanned, s.Changed, segs, s.Description)
+			if s.Error != "" {
+				fmt.Fprintf(tw, "\terror: %s\t\t\t\t\n", s.Error)
+			}
+		}
+		return tw.Flush()
+	case "up", "dry-run":
+		var reports []Report
+		var err error
+		if cmd == "up" {
+			reports, err = r.Up(ctx)
+		} else {
+			reports, err = r.DryRun(ctx)
+		}
+		if *asJSON {
+			if jsonErr := writeJSON(w, reports); jsonErr != nil {
+				return jsonErr
+			}
+		} else {
+			printReports(w, reports, cmd == "dry-run")
+		}
+		return err
+	default:
+		return fmt.Errorf("unknown command %q, expected up, status or dry-run", cmd)
+	}
+}
+
+func printReports(w io.Writer, reports []Report, dry bool) {
+	if len(reports) == 0 {
+		fmt.Fprintln(w, "no migrations registered")
+	}
+	for _, rep := range reports {
+		if rep.Skipped {
+			fmt.Fprintf(w, "%s: already applied\n", rep.ID)
+			continue
+		}
+		verb := "changed"
+		if dry {
+			verb = "would change"
+		}
+		fmt.Fprintf(w, "%s: scanned %d, matched %d, %s %d (%d actions)\n", rep.ID, rep.Scanned, rep.Matched, verb, rep.Changed, rep.Actions)
+		for _, s := range rep.Samples {
+			fmt.Fprintf(w, "  - %s\n", itemJSON(s.Before))
+			for _, after := range s.After {
+				fmt.Fprintf(w, "    + %s\n", itemJSON(after))
+			}
+			if s.Deleted {
+				fmt.Fprintf(w, "    (deleted)\n")
+			}
+		}
+	}
+}
+
+// itemJSON formats item in DynamoDB JSON on one line.
+func itemJSON(item ddbsdk.Item) string {
+	attrs, err := ddbexport.JSONAttributes(item)
+	if err != nil {
+		return fmt.Sprintf("(%v)", err)
+	}
+	b, _ := json.Marshal(attrs)
+	return string(b)
+}
+
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
@@ -0,0 +1,366 @@
+package migrate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/acksell/bezos/dynamodb/ddbsdk"
+	"github.com/acksell/bezos/dynamodb/ddbstore"
+	"github.com/acksell/bezos/dynamodb/index"
+	"github.com/acksell/bezos/dynamodb/index/val"
+	"github.com/acksell/bezos/dynamodb/table"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+var ordersTable = table.TableDefinition{
+	Name: "orders",
+	KeyDefinitions: table.PrimaryKeyDefinition{
+		PartitionKey: table.KeyDef{Name: "pk", Kind: table.KeyKindS},
+		SortKey:      table.KeyDef{Name: "sk", Kind: table.KeyKindS},
+	},
+	EntityTypeKey: "type",
+}
+
+type Order struct {
+	TenantID string `dynamodbav:"tenantID"`
+	OrderID  string `dynamodbav:"orderID"`
+}
+
+func (o *Order) IsValid() error { return nil }
+
+var orderIndex = &index.PrimaryIndex[Order]{
+	Table:        ordersTable,
+	PartitionKey: val.Fmt("TENANT#{tenantID}"),
+	SortKey:      val.Fmt("O#{orderID}").Ptr(),
+}
+
+const numOrders = 30
+
+// newTestStore returns a store with numOrders orders under the old ORDER# sort key
+// prefix, and a customer item.
+func newTestStore(t *testing.T) *ddbstore.Store {
+	t.Helper()
+	store, err := ddbstore.New(ddbstore.StoreOptions{InMemory: true}, ordersTable, MigrationsTable)
+	if err != nil {
+		t.Fatalf("creating store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	put := func(item ddbsdk.Item) {
+		if _, err := store.PutItem(context.Background(), &dynamodb.PutItemInput{TableName: &ordersTable.Name, Item: item}); err != nil {
+			t.Fatalf("seeding: %v", err)
+		}
+	}
+	for i := 0; i < numOrders; i++ {
+		tenant := fmt.Sprintf("t%d", i%7)
+		orderID := fmt.Sprintf("%03d", i)
+		put(ddbsdk.Item{
+			"pk":       stringAttr("TENANT#" + tenant),
+			"sk":       stringAttr("ORDER#" + orderID),
+			"type":     stringAttr("Order"),
+			"tenantID": stringAttr(tenant),
+			"orderID":  stringAttr(orderID),
+		})
+	}
+	put(ddbsdk.Item{
+		"pk":   stringAttr("TENANT#t0"),
+		"sk":   stringAttr("CUSTOMER"),
+		"type": stringAttr("Customer"),
+	})
+	return store
+}
+
+// shortPrefix moves orders from ORDER#{orderID} to O#{orderID}. It fails on the
+// items of the fail func, if set.
+func shortPrefix(calls *atomic.Int64, fail func(o *Order) bool) Migration {
+	return ForEntity(orderIndex, "0001_short_prefix", "shorten order sort keys",
+		func(ctx context.Context, o *Order, item ddbsdk.Item) ([]ddbsdk.Action, error) {
+			if calls != nil {
+				calls.Add(1)
+			}
+			if fail != nil && fail(o) {
+				return nil, errors.New("boom")
+			}
+			key := table.PrimaryKey{
+				Definition: ordersTable.KeyDefinitions,
+				Values:     table.PrimaryKeyValues{PartitionKey: "TENANT#" + o.TenantID, SortKey: "O#" + o.OrderID},
+			}
+			return Rekey(ordersTable, item, ddbsdk.NewUnsafePut(ordersTable, key, o))
+		},
+	).WithMatch(MatchKey(ordersTable, val.Fmt("TENANT#{tenantID}"), val.Fmt("ORDER#{orderID}").Ptr()))
+}
+
+// sortKeys counts the items of the orders table by sort key prefix.
+func sortKeys(t *testing.T, store *ddbstore.Store) map[string]int {
+	t.Helper()
+	out, err := store.Scan(context.Background(), &dynamodb.ScanInput{TableName: &ordersTable.Name})
+	if err != nil {
+		t.Fatalf("scanning: %v", err)
+	}
+	counts := make(map[string]int)
+	for _, item := range out.Items {
+		sk := item["sk"].(*types.AttributeValueMemberS).Value
+		prefix, _, _ := strings.Cut(sk, "#")
+		counts[prefix]++
+	}
+	return counts
+}
+
+func TestRunner_Up(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t)
+	r := NewRunner(store, WithMigrations(shortPrefix(nil, nil)), WithPageSize(4))
+
+	reports, err := r.Up(ctx)
+	if err != nil {
+		t.Fatalf("Up() error = %v", err)
+	}
+	if len(reports) != 1 {
+		t.Fatalf("expected 1 report, got %d", len(reports))
+	}
+	rep := reports[0]
+	if rep.Scanned != numOrders+1 || rep.Matched != numOrders || rep.Changed != numOrders || rep.Actions != 2*numOrders {
+		t.Errorf("unexpected report %+v", rep)
+	}
+	if got := sortKeys(t, store); got["O"] != numOrders || got["ORDER"] != 0 || got["CUSTOMER"] != 1 {
+		t.Errorf("unexpected items after migration: %v", got)
+	}
+
+	statuses, err := r.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status() error = %v", err)
+	}
+	st := statuses[0]
+	if st.State != StateDone || st.Changed != numOrders || st.SegmentsDone != st.Segments || st.FinishedAt == nil {
+		t.Errorf("unexpected status %+v", st)
+	}
+
+	// A done migration is not applied again.
+	reports, err = r.Up(ctx)
+	if err != nil {
+		t.Fatalf("second Up() error = %v", err)
+	}
+	if !reports[0].Skipped {
+		t.Errorf("expected migration to be skipped, got %+v", reports[0])
+	}
+}
+
+func TestRunner_Up_Resume(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t)
+
+	var failing atomic.Bool
+	failing.Store(true)
+	var calls atomic.Int64
+	m := shortPrefix(&calls, func(o *Order) bool { return failing.Load() && o.OrderID == "020" })
+	r := NewRunner(store, WithMigrations(m), WithSegments(1), WithPageSize(5))
+
+	if _, err := r.Up(ctx); err == nil {
+		t.Fatal("expected Up() to fail")
+	}
+	statuses, err := r.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status() error = %v", err)
+	}
+	if st := statuses[0]; st.State != StateFailed || !strings.Contains(st.Error, "boom") {
+		t.Errorf("unexpected status %+v", st)
+	}
+	migrated := sortKeys(t, store)["O"]
+	if migrated == 0 || migrated == numOrders {
+		t.Errorf("expected a partial migration, %d of %d orders migrated", migrated, numOrders)
+	}
+
+	failing.Store(false)
+	firstRun := calls.Load()
+	reports, err := r.Up(ctx)
+	if err != nil {
+		t.Fatalf("resumed Up() error = %v", err)
+	}
+	if got := sortKeys(t, store); got["O"] != numOrders || got["ORDER"] != 0 {
+		t.Errorf("unexpected items after resume: %v", got)
+	}
+	// The resumed run starts at the failed page rather than at the beginning.
+	if resumed := calls.Load() - firstRun; resumed >= numOrders {
+		t.Errorf("resumed run transformed %d items, expected it to skip checkpointed pages", resumed)
+	}
+	if reports[0].Changed != int64(numOrders-migrated) {
+		t.Errorf("resumed run changed %d items, want %d", reports[0].Changed, numOrders-migrated)
+	}
+}
+
+func TestRunner_DryRun(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t)
+	r := NewRunner(store, WithMigrations(shortPrefix(nil, nil)), WithSamples(3))
+
+	reports, err := r.DryRun(ctx)
+	if err != nil {
+		t.Fatalf("DryRun() error = %v", err)
+	}
+	rep := reports[0]
+	if rep.Changed != numOrders {
+		t.Errorf("Changed = %d, want %d", rep.Changed, numOrders)
+	}
+	if len(rep.Samples) != 3 {
+		t.Fatalf("expected 3 samples, got %d", len(rep.Samples))
+	}
+	for _, s := range rep.Samples {
+		if !s.Deleted || len(s.After) != 1 {
+			t.Fatalf("unexpected sample %+v", s)
+		}
+		before := s.Before["orderID"].(*types.AttributeValueMemberS).Value
+		after := s.After[0]["sk"].(*types.AttributeValueMemberS).Value
+		if after != "O#"+before {
+			t.Errorf("sample sort key = %q, want %q", after, "O#"+before)
+		}
+	}
+
+	if got := sortKeys(t, store); got["ORDER"] != numOrders || got["O"] != 0 {
+		t.Errorf("dry run changed the table: %v", got)
+	}
+	statuses, err := r.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status() error = %v", err)
+	}
+	if statuses[0].State != StatePending {
+		t.Errorf("dry run recorded state %q", statuses[0].State)
+	}
+}
+
+func TestRunner_DryRunWritesToOtherTables(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t)
+	archiveName := "archive"
+	_, err := store.CreateTable(ctx, &dynamodb.CreateTableInput{
+		TableName:            &archiveName,
+		KeySchema:            []types.KeySchemaElement{{AttributeName: ptr("id"), KeyType: types.KeyTypeHash}},
+		AttributeDefinitions: []types.AttributeDefinition{{AttributeName: ptr("id"), AttributeType: types.ScalarAttributeTypeS}},
+	})
+	if err != nil {
+		t.Fatalf("creating archive table: %v", err)
+	}
+	archive := table.TableDefinition{
+		Name:           archiveName,
+		KeyDefinitions: table.PrimaryKeyDefinition{PartitionKey: table.KeyDef{Name: "id", Kind: table.KeyKindS}},
+	}
+
+	// The archive table isn't scanned by any migration, so the dry run has to describe it.
+	copyOrders := ForEntity(orderIndex, "0001_archive", "copy orders to the archive",
+		func(ctx context.Context, o *Order, item ddbsdk.Item) ([]ddbsdk.Action, error) {
+			key := table.PrimaryKey{
+				Definition: archive.KeyDefinitions,
+				Values:     table.PrimaryKeyValues{PartitionKey: o.TenantID + "/" + o.OrderID},
+			}
+			return []ddbsdk.Action{ddbsdk.NewUnsafePut(archive, key, o)}, nil
+		},
+	)
+	r := NewRunner(store, WithMigrations(copyOrders), WithSamples(1))
+	reports, err := r.DryRun(ctx)
+	if err != nil {
+		t.Fatalf("DryRun() error = %v", err)
+	}
+	if len(reports[0].Samples) != 1 {
+		t.Fatalf("expected 1 sample, got %+v", reports[0])
+	}
+	s := reports[0].Samples[0]
+	if s.Deleted || len(s.After) != 1 {
+		t.Fatalf("unexpected sample %+v", s)
+	}
+	if id := s.After[0]["id"].(*types.AttributeValueMemberS).Value; !strings.Contains(id, "/") {
+		t.Errorf("unexpected archived item id %q", id)
+	}
+
+	// Samples are reported in DynamoDB JSON.
+	b, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), `"id":{"S":`) {
+		t.Errorf("expected DynamoDB JSON, got %s", b)
+	}
+
+	out, err := store.Scan(ctx, &dynamodb.ScanInput{TableName: &archiveName})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out.Items) != 0 {
+		t.Errorf("dry run wrote %d items to the archive", len(out.Items))
+	}
+}
+
+func TestRekey(t *testing.T) {
+	item := ddbsdk.Item{"pk": stringAttr("TENANT#t1"), "sk": stringAttr("O#001")}
+	o := &Order{TenantID: "t1", OrderID: "001"}
+	key, err := ordersTable.ExtractPrimaryKey(item)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actions, err := Rekey(ordersTable, item, ddbsdk.NewUnsafePut(ordersTable, key, o))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(actions) != 1 {
+		t.Errorf("expected only the put for an unchanged key, got %d actions", len(actions))
+	}
+}
+
+func TestRegister(t *testing.T) {
+	defer Clear()
+	Register(Migration{ID: "0002_b", Table: ordersTable, Transform: noop})
+	Register(Migration{ID: "0001_a", Table: ordersTable, Transform: noop})
+
+	ms := Registered()
+	if len(ms) != 2 || ms[0].ID != "0001_a" || ms[1].ID != "0002_b" {
+		t.Errorf("Registered() not in ID order: %v", ms)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected Register to panic on a duplicate ID")
+		}
+	}()
+	Register(Migration{ID: "0001_a", Table: ordersTable, Transform: noop})
+}
+
+func noop(context.Context, ddbsdk.Item) ([]ddbsdk.Action, error) { return nil, nil }
+
+func stringAttr(s string) types.AttributeValue {
+	return &types.AttributeValueMemberS{Value: s}
+}
+
+func TestRunCommand(t *testing.T) {
+	defer Clear()
+	Register(shortPrefix(nil, nil))
+	ctx := context.Background()
+	store := newTestStore(t)
+
+	run := func(args ...string) string {
+		t.Helper()
+		var out strings.Builder
+		if err := RunCommand(ctx, store, args, &out); err != nil {
+			t.Fatalf("RunCommand(%v) error = %v\n%s", args, err, out.String())
+		}
+		return out.String()
+	}
+
+	if out := run("dry-run", "--samples", "1"); !strings.Contains(out, "would change 30") || !strings.Contains(out, "(deleted)") {
+		t.Errorf("unexpected dry-run output:\n%s", out)
+	}
+	if out := run("up"); !strings.Contains(out, "0001_short_prefix: scanned 31, matched 30, changed 30") {
+		t.Errorf("unexpected up output:\n%s", out)
+	}
+	if out := run("status"); !strings.Contains(out, "done") {
+		t.Errorf("unexpected status output:\n%s", out)
+	}
+	if err := RunCommand(ctx, store, []string{"down"}, io.Discard); err == nil {
+		t.Error("expected an error for an unknown command")
+	}
+}
@@ -0,0 +1,178 @@
+// Package migrate runs data migrations: ordered, resumable rewrites of the items of a table.
+//
+// A migration is a per-item transform that returns the actions to apply to an item,
+// e.g. a put of the item with a new key format and a delete of the old item.
+// Register migrations in the package that owns the entities, here orderIndex is the
+// index returned by indices.Add:
+//
+//	var _ = migrate.Register(migrate.ForEntity(orderIndex, "0001_short_order_prefix",
+//	    "move orders from ORDER#{orderID} to O#{orderID}",
+//	    func(ctx context.Context, o *Order, item ddbsdk.Item) ([]ddbsdk.Action, error) {
+//	        return migrate.Rekey(orderIndex.Table, item, OrderIndex.UnsafePut(o))
+//	    },
+//	).WithMatch(migrate.MatchKey(orderIndex.Table, val.Fmt("TENANT#{tenantID}"), val.Fmt("ORDER#{orderID}").Ptr())))
+//
+// A [Runner] applies the registered migrations in ID order. Each migration scans its
+// table in parallel segments and records its progress in the [MigrationsTable], so
+// an interrupted migration resumes from the last written page and a finished one is
+// never applied twice. Transforms must still be idempotent: a page that was written but
+// not yet checkpointed is transformed again on resume.
+//
+// [RunCommand] implements the up, status and dry-run commands of ddb migrate.
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/acksell/bezos/dynamodb/ddbsdk"
+	"github.com/acksell/bezos/dynamodb/index"
+	"github.com/acksell/bezos/dynamodb/index/val"
+	"github.com/acksell/bezos/dynamodb/table"
+)
+
+// Transform migrates one item. It returns the actions to apply, or none if the item
+// needs no change, e.g. because it was already migrated.
+type Transform func(ctx context.Context, item ddbsdk.Item) ([]ddbsdk.Action, error)
+
+// Migration is a data migration of the items of one table.
+type Migration struct {
+	// ID identifies the migration in the migrations table. Migrations run in ID order,
+	// so prefix it with a sequence number, e.g. "0003_backfill_status".
+	ID          string
+	Description string
+	// Table is the scanned table.
+	Table table.TableDefinition
+	// Match selects the items to transform. Nil matches every item.
+	Match func(item ddbsdk.Item) bool
+	// Transform is called for every matching item.
+	Transform Transform
+}
+
+// WithMatch returns a copy of the migration that only transforms items matching fn.
+func (m Migration) WithMatch(fn func(item ddbsdk.Item) bool) Migration {
+	m.Match = fn
+	return m
+}
+
+func (m Migration) matches(item ddbsdk.Item) bool {
+	return m.Match == nil || m.Match(item)
+}
+
+func (m Migration) validate() error {
+	if m.ID == "" {
+		return fmt.Errorf("migration ID is required")
+	}
+	if m.Table.Name == "" {
+		return fmt.Errorf("migration %s: table is required", m.ID)
+	}
+	if m.Transform == nil {
+		return fmt.Errorf("migration %s: transform is required", m.ID)
+	}
+	return nil
+}
+
+// ForEntity declares a migration of the items of entity type E in the table of idx.
+// Items are matched with [ddbsdk.EntityTypeOf], and decoded into E before fn is called
+// with the entity and the item it was decoded from.
+//
+// EntityTypeOf falls back to the registered key patterns for tables without an
+// entity type attribute, which no longer match items written with a changed key
+// pattern. Use [Migration.WithMatch] and [MatchKey] with the old pattern for those.
+func ForEntity[E any](idx *index.PrimaryIndex[E], id, description string, fn func(ctx context.Context, e *E, item ddbsdk.Item) ([]ddbsdk.Action, error)) Migration {
+	entityType := idx.EntityTypeName()
+	return Migration{
+		ID:          id,
+		Description: description,
+		Table:       idx.Table,
+		Match: func(item ddbsdk.Item) bool {
+			return ddbsdk.EntityTypeOf(idx.Table, item) == entityType
+		},
+		Transform: func(ctx context.Context, item ddbsdk.Item) ([]ddbsdk.Action, error) {
+			e, err := ddbsdk.DecodeEntity[E](item)
+			if err != nil {
+				return nil, err
+			}
+			return fn(ctx, e, item)
+		},
+	}
+}
+
+// MatchKey matches items whose primary key matches the given key patterns.
+// A nil sort key pattern only checks the partition key.
+func MatchKey(t table.TableDefinition, pk val.ValDef, sk *val.ValDef) func(item ddbsdk.Item) bool {
+	return func(item ddbsdk.Item) bool {
+		key, err := t.ExtractPrimaryKey(item)
+		if err != nil || !pk.Matches(key.Values.PartitionKey) {
+			return false
+		}
+		return sk == nil || sk.Matches(key.Values.SortKey)
+	}
+}
+
+// Rekey moves item to the key written by put. It returns put, followed by a delete
+// of the item if put writes to another key.
+func Rekey(t table.TableDefinition, item ddbsdk.Item, put ddbsdk.Action) ([]ddbsdk.Action, error) {
+	old, err := t.ExtractPrimaryKey(item)
+	if err != nil {
+		return nil, err
+	}
+	if reflect.DeepEqual(old.Values, put.PrimaryKey().Values) {
+		return []ddbsdk.Action{put}, nil
+	}
+	return []ddbsdk.Action{put, ddbsdk.NewDelete(t, old)}, nil
+}
+
+var (
+	mu       sync.RWMutex
+	registry = make(map[string]Migration)
+)
+
+// Register adds a migration to the ones run by a [Runner] by default.
+// Panics if the migration is invalid or its ID is already registered.
+//
+// Example:
+//
+//	var _ = migrate.Register(migrate.Migration{...})
+func Register(m Migration) Migration {
+	if err := m.validate(); err != nil {
+		panic("migrate: " + err.Error())
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if _, exists := registry[m.ID]; exists {
+		panic("migrate: migration " + m.ID + " is already registered")
+	}
+	registry[m.ID] = m
+	return m
+}
+
+// Registered returns all registered migrations in ID order.
+func Registered() []Migration {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	ms := make([]Migration, 0, len(registry))
+	for _, m := range registry {
+		ms = append(ms, m)
+	}
+	sortByID(ms)
+	return ms
+}
+
+// Clear resets the registry. Useful for testing.
+func Clear() {
+	mu.Lock()
+	defer mu.Unlock()
+	registry = make(map[string]Migration)
+}
+
+func sortByID(ms []Migration) {
+	slices.SortFunc(ms, func(a, b Migration) int { return strings.Compare(a.ID, b.ID) })
+}
@@ -0,0 +1,489 @@
+package migrate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/acksell/bezos/dynamodb/ddbexport"
+	"github.com/acksell/bezos/dynamodb/ddbiface"
+	"github.com/acksell/bezos/dynamodb/ddbsdk"
+	"github.com/acksell/bezos/dynamodb/ddbstore"
+	"github.com/acksell/bezos/dynamodb/table"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// NewRunner creates a runner for the registered migrations.
+// Every request is retried according to [ddbsdk.DefaultRetryPolicy].
+func NewRunner(ddb ddbiface.ReadWriteClient, opts ...Option) *Runner {
+	r := &Runner{
+		ddb: ddbsdk.NewRetryClient(ddb, ddbsdk.DefaultRetryPolicy),
+		raw: ddb,
+		opts: runnerOpts{
+			table:    MigrationsTable,
+			segments: 4,
+			samples:  5,
+			log:      io.Discard,
+		},
+	}
+	for _, opt := range opts {
+		opt(&r.opts)
+	}
+	if r.opts.segments < 1 {
+		r.opts.segments = 1
+	}
+	return r
+}
+
+type Option func(*runnerOpts)
+
+type runnerOpts struct {
+	migrations []Migration
+	table      table.TableDefinition
+	segments   int
+	pageSize   int32
+	samples    int
+	log        io.Writer
+}
+
+// WithMigrations runs the given migrations instead of the registered ones.
+func WithMigrations(ms ...Migration) Option {
+	return func(o *runnerOpts) {
+		o.migrations = ms
+	}
+}
+
+// WithMigrationsTable records progress in t instead of [MigrationsTable].
+func WithMigrationsTable(t table.TableDefinition) Option {
+	return func(o *runnerOpts) {
+		o.table = t
+	}
+}
+
+// WithSegments sets the number of parallel scan segments of a new migration. Defaults to 4.
+// A started migration keeps the segment count it was started with.
+func WithSegments(n int) Option {
+	return func(o *runnerOpts) {
+		o.segments = n
+	}
+}
+
+// WithPageSize limits the items per scanned page, and thus the work lost when a
+// migration is interrupted. By default DynamoDB returns up to 1 MB per page.
+func WithPageSize(n int32) Option {
+	return func(o *runnerOpts) {
+		o.pageSize = n
+	}
+}
+
+// WithSamples sets the number of changed items a dry run reports per migration. Defaults to 5.
+func WithSamples(n int) Option {
+	return func(o *runnerOpts) {
+		o.samples = n
+	}
+}
+
+// WithLog writes progress messages to w.
+func WithLog(w io.Writer) Option {
+	return func(o *runnerOpts) {
+		o.log = w
+	}
+}
+
+// Runner applies migrations, see the package documentation.
+type Runner struct {
+	ddb ddbiface.ReadWriteClient
+	// raw is the client without retries, which dry runs use to describe tables.
+	raw  ddbiface.ReadWriteClient
+	opts runnerOpts
+}
+
+// Report is the outcome of running one migration.
+type Report struct {
+	ID string `json:"id"`
+	// Skipped is true if the migration was applied before.
+	Skipped bool `json:"skipped,omitempty"`
+	// Scanned, Matched and Changed count the items of this run: scanned, matched by the
+	// migration, and with at least one action.
+	Scanned int64 `json:"scanned"`
+	Matched int64 `json:"matched"`
+	Changed int64 `json:"changed"`
+	// Actions is the number of actions written.
+	Actions int64 `json:"actions"`
+	// Samples are the first changed items of a dry run.
+	Samples []Sample `json:"samples,omitempty"`
+}
+
+// Sample is a changed item of a dry run, before and after the migration.
+type Sample struct {
+	Before ddbsdk.Item
+	// After holds the items at the keys written for the item, as stored after the actions.
+	After []ddbsdk.Item
+	// Deleted is true if the item's own key no longer exists after the actions.
+	Deleted bool
+}
+
+// MarshalJSON encodes the items in DynamoDB JSON, as ddb export writes them.
+func (s Sample) MarshalJSON() ([]byte, error) {
+	before, err := ddbexport.JSONAttributes(s.Before)
+	if err != nil {
+		return nil, err
+	}
+	after := make([]map[string]any, 0, len(s.After))
+	for _, item := range s.After {
+		attrs, err := ddbexport.JSONAttributes(item)
+		if err != nil {
+			return nil, err
+		}
+		after = append(after, attrs)
+	}
+	return json.Marshal(struct {
+		Before  map[string]any   `json:"before"`
+		After   []map[string]any `json:"after,omitempty"`
+		Deleted bool             `json:"deleted,omitempty"`
+	}{before, after, s.Deleted})
+}
+
+func (r *Runner) migrations() []Migration {
+	if r.opts.migrations != nil {
+		ms := append([]Migration(nil), r.opts.migrations...)
+		sortByID(ms)
+		return ms
+	}
+	return Registered()
+}
+
+// Up applies all migrations that are not done yet, in ID order, resuming interrupted
+// ones from their checkpoints. It stops at the first failing migration.
+func (r *Runner) Up(ctx context.Context) ([]Report, error) {
+	var reports []Report
+	for _, m := range r.migrations() {
+		if err := m.validate(); err != nil {
+			return reports, err
+		}
+		rec, found, err := r.getRecord(ctx, m.ID)
+		if err != nil {
+			return reports, err
+		}
+		if found && rec.State == StateDone {
+			reports = append(reports, Report{ID: m.ID, Skipped: true})
+			continue
+		}
+		if !found {
+			rec = record{ID: m.ID, Description: m.Description, Segments: r.opts.segments, StartedAt: time.Now().UTC()}
+			fmt.Fprintf(r.opts.log, "migrate: starting %s\n", m.ID)
+		} else {
+			fmt.Fprintf(r.opts.log, "migrate: resuming %s\n", m.ID)
+		}
+		rec.State, rec.Error = StateRunning, ""
+		if err := r.putRecord(ctx, rec); err != nil {
+			return reports, err
+		}
+
+		cps, err := r.checkpoints(ctx, rec)
+		if err != nil {
+			return reports, err
+		}
+		report, err := r.apply(ctx, m, cps, r.ddb, nil)
+		reports = append(reports, report)
+		if err != nil {
+			rec.State, rec.Error = StateFailed, err.Error()
+			// The checkpoints are what matters for resuming, the record is informational.
+			if putErr := r.putRecord(context.WithoutCancel(ctx), rec); putErr != nil {
+				err = errors.Join(err, putErr)
+			}
+			return reports, fmt.Errorf("migration %s: %w", m.ID, err)
+		}
+		rec.State, rec.FinishedAt = StateDone, ptr(time.Now().UTC())
+		if err := r.putRecord(ctx, rec); err != nil {
+			return reports, err
+		}
+		fmt.Fprintf(r.opts.log, "migrate: %s done, %d of %d items changed\n", m.ID, report.Changed, report.Scanned)
+	}
+	return reports, nil
+}
+
+// DryRun runs the migrations that are not done yet without changing any data. Items
+// are read from the table, and the actions are applied to a copy of each changed item
+// in an in-memory ddbstore, so the report shows what the items would look like.
+//
+// Each migration starts from the beginning of the table, and doesn't see the changes
+// of the migrations before it. Actions that read other items, e.g. conditions on
+// another key, are evaluated against the in-memory store. The store has the tables of
+// all migrations, and the tables that actions write to as the client describes them.
+func (r *Runner) DryRun(ctx context.Context) ([]Report, error) {
+	var reports []Report
+	for _, m := range r.migrations() {
+		if err := m.validate(); err != nil {
+			return reports, err
+		}
+		rec, found, err := r.getRecord(ctx, m.ID)
+		if err != nil {
+			return reports, err
+		}
+		if found && rec.State == StateDone {
+			reports = append(reports, Report{ID: m.ID, Skipped: true})
+			continue
+		}
+
+		tables, err := r.dryRunStore()
+		if err != nil {
+			return reports, err
+		}
+		cps := make([]checkpoint, r.opts.segments)
+		for i := range cps {
+			cps[i].ID = checkpointID(m.ID, i)
+		}
+		report, err := r.apply(ctx, m, cps, tables.store, tables)
+		tables.store.Close()
+		reports = append(reports, report)
+		if err != nil {
+			return reports, fmt.Errorf("migration %s: %w", m.ID, err)
+		}
+	}
+	return reports, nil
+}
+
+// apply runs migration m over all segments in parallel, writing its actions to dst.
+// Unless it is a dry run, with the tables of the dry run store in dry, the checkpoints
+// are saved after every page.
+func (r *Runner) apply(ctx context.Context, m Migration, cps []checkpoint, dst ddbiface.ReadWriteClient, dry *dryRunTables) (Report, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	run := &migrationRun{r: r, m: m, dst: dst, dry: dry, report: Report{ID: m.ID}}
+	var wg sync.WaitGroup
+	errs := make([]error, len(cps))
+	for i := range cps {
+		if cps[i].Done {
+			continue
+		}
+		wg.Add(1)
+		go func(segment int) {
+			defer wg.Done()
+			if err := run.segment(ctx, segment, len(cps), &cps[segment]); err != nil {
+				errs[segment] = fmt.Errorf("segment %d: %w", segment, err)
+				cancel()
+			}
+		}(i)
+	}
+	wg.Wait()
+	return run.report, errors.Join(errs...)
+}
+
+// migrationRun is one run of a migration, shared by its segments.
+type migrationRun struct {
+	r   *Runner
+	m   Migration
+	dst ddbiface.ReadWriteClient
+	dry *dryRunTables
+
+	mu     sync.Mutex
+	report Report
+}
+
+func (run *migrationRun) segment(ctx context.Context, segment, total int, cp *checkpoint) error {
+	m := run.m
+	for {
+		in := &dynamodb.ScanInput{
+			TableName:         &m.Table.Name,
+			Segment:           ptr(int32(segment)),
+			TotalSegments:     ptr(int32(total)),
+			ExclusiveStartKey: cp.LastKey,
+		}
+		if run.r.opts.pageSize > 0 {
+			in.Limit = &run.r.opts.pageSize
+		}
+		out, err := run.r.ddb.Scan(ctx, in)
+		if err != nil {
+			return fmt.Errorf("scanning %s: %w", m.Table.Name, err)
+		}
+
+		page := Report{Scanned: int64(len(out.Items))}
+		var changed []pageChange
+		bw := ddbsdk.NewBulkWriter(run.dst)
+		for _, item := range out.Items {
+			if !m.matches(item) {
+				continue
+			}
+			page.Matched++
+			actions, err := m.Transform(ctx, item)
+			if err != nil {
+				key, _ := m.Table.ExtractPrimaryKey(item)
+				return fmt.Errorf("transforming item %v: %w", key.Values, err)
+			}
+			if len(actions) == 0 {
+				continue
+			}
+			page.Changed++
+			page.Actions += int64(len(actions))
+			if run.dry != nil {
+				// Apply the actions to a copy of the item, as they would be in the table.
+				if _, err := run.dst.PutItem(ctx, &dynamodb.PutItemInput{TableName: &m.Table.Name, Item: item}); err != nil {
+					return fmt.Errorf("copying item to dry run store: %w", err)
+				}
+			}
+			if run.dry != nil {
+				if err := run.dry.create(ctx, actions); err != nil {
+					return err
+				}
+			}
+			changed = append(changed, pageChange{item: item, actions: actions})
+			bw.AddAction(actions...)
+		}
+		if _, err := bw.Exec(ctx); err != nil {
+			return err
+		}
+
+		var samples []Sample
+		if run.dry != nil {
+			if samples, err = run.samples(ctx, changed); err != nil {
+				return err
+			}
+		}
+		run.add(page, samples)
+
+		cp.LastKey = out.LastEvaluatedKey
+		cp.Done = len(out.LastEvaluatedKey) == 0
+		cp.Scanned += page.Scanned
+		cp.Changed += page.Changed
+		if run.dry == nil {
+			if err := run.r.putCheckpoint(ctx, *cp); err != nil {
+				return err
+			}
+		}
+		if cp.Done {
+			return nil
+		}
+	}
+}
+
+// dryRunStore returns an in-memory store with the tables of the runner's migrations.
+func (r *Runner) dryRunStore() (*dryRunTables, error) {
+	var defs []table.TableDefinition
+	created := make(map[string]bool)
+	for _, m := range r.migrations() {
+		if !created[m.Table.Name] {
+			created[m.Table.Name] = true
+			defs = append(defs, m.Table)
+		}
+	}
+	store, err := ddbstore.New(ddbstore.StoreOptions{InMemory: true}, defs...)
+	if err != nil {
+		return nil, fmt.Errorf("creating dry run store: %w", err)
+	}
+	return &dryRunTables{src: r.raw, store: store, created: created}, nil
+}
+
+// dryRunTables creates the tables that actions write to in the dry run store, with
+// the keys and GSIs of the tables of src.
+type dryRunTables struct {
+	src   ddbiface.ReadWriteClient
+	store *ddbstore.Store
+
+	mu      sync.Mutex
+	created map[string]bool
+}
+
+func (t *dryRunTables) create(ctx context.Context, actions []ddbsdk.Action) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, a := range actions {
+		name := *a.TableName()
+		if t.created[name] {
+			continue
+		}
+		describer, ok := t.src.(interface {
+			DescribeTable(context.Context, *dynamodb.DescribeTableInput, ...func(*dynamodb.Options)) (*dynamodb.DescribeTableOutput, error)
+		})
+		if !ok {
+			return fmt.Errorf("actions write to table %s, which no migration scans and a %T can't describe", name, t.src)
+		}
+		out, err := describer.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: &name})
+		if err != nil {
+			return fmt.Errorf("describing table %s for the dry run: %w", name, err)
+		}
+		in := &dynamodb.CreateTableInput{
+			TableName:            &name,
+			KeySchema:            out.Table.KeySchema,
+			AttributeDefinitions: out.Table.AttributeDefinitions,
+		}
+		for _, gsi := range out.Table.GlobalSecondaryIndexes {
+			in.GlobalSecondaryIndexes = append(in.GlobalSecondaryIndexes, types.GlobalSecondaryIndex{
+				IndexName:  gsi.IndexName,
+				KeySchema:  gsi.KeySchema,
+				Projection: gsi.Projection,
+			})
+		}
+		if _, err := t.store.CreateTable(ctx, in); err != nil {
+			return fmt.Errorf("creating table %s in the dry run store: %w", name, err)
+		}
+		t.created[name] = true
+	}
+	return nil
+}
+
+// pageChange is a changed item of a scanned page and its actions.
+type pageChange struct {
+	item    ddbsdk.Item
+	actions []ddbsdk.Action
+}
+
+// samples reads back the changed items of a page from the dry run store, up to the
+// number of samples still missing from the report.
+func (run *migrationRun) samples(ctx context.Context, changed []pageChange) ([]Sample, error) {
+	run.mu.Lock()
+	missing := run.r.opts.samples - len(run.report.Samples)
+	run.mu.Unlock()
+
+	var samples []Sample
+	for _, c := range changed {
+		if len(samples) >= missing {
+			break
+		}
+		s := Sample{Before: c.item}
+		key, err := run.m.Table.ExtractPrimaryKey(c.item)
+		if err != nil {
+			return nil, err
+		}
+		if s.Deleted, err = run.missing(ctx, run.m.Table.Name, key); err != nil {
+			return nil, err
+		}
+		for _, a := range c.actions {
+			res, err := run.dst.GetItem(ctx, &dynamodb.GetItemInput{TableName: a.TableName(), Key: a.PrimaryKey().DDB()})
+			if err != nil {
+				return nil, fmt.Errorf("reading dry run result: %w", err)
+			}
+			if len(res.Item) > 0 {
+				s.After = append(s.After, res.Item)
+			}
+		}
+		samples = append(samples, s)
+	}
+	return samples, nil
+}
+
+func (run *migrationRun) missing(ctx context.Context, tableName string, key table.PrimaryKey) (bool, error) {
+	res, err := run.dst.GetItem(ctx, &dynamodb.GetItemInput{TableName: &tableName, Key: key.DDB()})
+	if err != nil {
+		return false, fmt.Errorf("reading dry run result: %w", err)
+	}
+	return len(res.Item) == 0, nil
+}
+
+func (run *migrationRun) add(page Report, samples []Sample) {
+	run.mu.Lock()
+	defer run.mu.Unlock()
+	run.report.Scanned += page.Scanned
+	run.report.Matched += page.Matched
+	run.report.Changed += page.Changed
+	run.report.Actions += page.Actions
+	if n := run.r.opts.samples - len(run.report.Samples); n > 0 {
+		run.report.Samples = append(run.report.Samples, samples[:min(n, len(samples))]...)
+	}
+}
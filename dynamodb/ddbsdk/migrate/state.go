@@ -0,0 +1,189 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/acksell/bezos/dynamodb/table"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// MigrationsTable is the default table recording applied migrations and their progress.
+// Create it along with your other tables, or pass another one to [WithMigrationsTable].
+// It only needs a string partition key.
+var MigrationsTable = table.TableDefinition{
+	Name: "bezos_migrations",
+	KeyDefinitions: table.PrimaryKeyDefinition{
+		PartitionKey: table.KeyDef{Name: "id", Kind: table.KeyKindS},
+	},
+}
+
+// State is the progress of a migration.
+type State string
+
+const (
+	StatePending State = "pending"
+	StateRunning State = "running"
+	StateFailed  State = "failed"
+	StateDone    State = "done"
+)
+
+// Status is the recorded progress of a migration, see [Runner.Status].
+type Status struct {
+	ID          string `json:"id"`
+	Description string `json:"description,omitempty"`
+	State       State  `json:"state"`
+	// Scanned and Changed count the items over all runs of the migration.
+	Scanned      int64      `json:"scanned"`
+	Changed      int64      `json:"changed"`
+	Segments     int        `json:"segments,omitempty"`
+	SegmentsDone int        `json:"segmentsDone,omitempty"`
+	StartedAt    *time.Time `json:"startedAt,omitempty"`
+	FinishedAt   *time.Time `json:"finishedAt,omitempty"`
+	Error        string     `json:"error,omitempty"`
+}
+
+// record is the item of a migration in the migrations table.
+type record struct {
+	ID          string     `dynamodbav:"id"`
+	Description string     `dynamodbav:"description,omitempty"`
+	State       State      `dynamodbav:"state"`
+	Segments    int        `dynamodbav:"segments"`
+	StartedAt   time.Time  `dynamodbav:"startedAt"`
+	FinishedAt  *time.Time `dynamodbav:"finishedAt,omitempty"`
+	Error       string     `dynamodbav:"error,omitempty"`
+}
+
+// checkpoint is the progress of one scan segment of a migration,
+// stored next to its record.
+type checkpoint struct {
+	ID string `dynamodbav:"id"`
+	// LastKey is the LastEvaluatedKey of the last written page.
+	// Stored as a map attribute, the attributevalue decoder can't decode it.
+	LastKey map[string]types.AttributeValue `dynamodbav:"-"`
+	Done    bool                            `dynamodbav:"done"`
+	Scanned int64                           `dynamodbav:"scanned"`
+	Changed int64                           `dynamodbav:"changed"`
+}
+
+const lastKeyAttr = "lastKey"
+
+func checkpointID(migrationID string, segment int) string {
+	return migrationID + "#segment#" + strconv.Itoa(segment)
+}
+
+func (r *Runner) getItem(ctx context.Context, id string) (map[string]types.AttributeValue, error) {
+	res, err := r.ddb.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      &r.opts.table.Name,
+		Key:            map[string]types.AttributeValue{r.opts.table.KeyDefinitions.PartitionKey.Name: &types.AttributeValueMemberS{Value: id}},
+		ConsistentRead: ptr(true),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reading %s from %s: %w", id, r.opts.table.Name, err)
+	}
+	return res.Item, nil
+}
+
+func (r *Runner) putItem(ctx context.Context, item map[string]types.AttributeValue) error {
+	if _, err := r.ddb.PutItem(ctx, &dynamodb.PutItemInput{TableName: &r.opts.table.Name, Item: item}); err != nil {
+		return fmt.Errorf("writing to %s: %w", r.opts.table.Name, err)
+	}
+	return nil
+}
+
+func (r *Runner) getRecord(ctx context.Context, id string) (record, bool, error) {
+	var rec record
+	item, err := r.getItem(ctx, id)
+	if err != nil || len(item) == 0 {
+		return rec, false, err
+	}
+	if err := attributevalue.UnmarshalMap(item, &rec); err != nil {
+		return rec, false, fmt.Errorf("decoding migration %s: %w", id, err)
+	}
+	return rec, true, nil
+}
+
+func (r *Runner) putRecord(ctx context.Context, rec record) error {
+	item, err := attributevalue.MarshalMap(rec)
+	if err != nil {
+		return err
+	}
+	return r.putItem(ctx, item)
+}
+
+func (r *Runner) getCheckpoint(ctx context.Context, id string) (checkpoint, error) {
+	cp := checkpoint{ID: id}
+	item, err := r.getItem(ctx, id)
+	if err != nil || len(item) == 0 {
+		return cp, err
+	}
+	if err := attributevalue.UnmarshalMap(item, &cp); err != nil {
+		return cp, fmt.Errorf("decoding checkpoint %s: %w", id, err)
+	}
+	if m, ok := item[lastKeyAttr].(*types.AttributeValueMemberM); ok {
+		cp.LastKey = m.Value
+	}
+	return cp, nil
+}
+
+func (r *Runner) putCheckpoint(ctx context.Context, cp checkpoint) error {
+	item, err := attributevalue.MarshalMap(cp)
+	if err != nil {
+		return err
+	}
+	if len(cp.LastKey) > 0 {
+		item[lastKeyAttr] = &types.AttributeValueMemberM{Value: cp.LastKey}
+	}
+	return r.putItem(ctx, item)
+}
+
+// checkpoints reads the checkpoints of all segments of a migration.
+func (r *Runner) checkpoints(ctx context.Context, rec record) ([]checkpoint, error) {
+	cps := make([]checkpoint, rec.Segments)
+	for i := range cps {
+		cp, err := r.getCheckpoint(ctx, checkpointID(rec.ID, i))
+		if err != nil {
+			return nil, err
+		}
+		cps[i] = cp
+	}
+	return cps, nil
+}
+
+// Status returns the recorded progress of the migrations, in ID order.
+func (r *Runner) Status(ctx context.Context) ([]Status, error) {
+	var statuses []Status
+	for _, m := range r.migrations() {
+		st := Status{ID: m.ID, Description: m.Description, State: StatePending}
+		rec, found, err := r.getRecord(ctx, m.ID)
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			cps, err := r.checkpoints(ctx, rec)
+			if err != nil {
+				return nil, err
+			}
+			st.State = rec.State
+			st.Segments = rec.Segments
+			st.StartedAt = &rec.StartedAt
+			st.FinishedAt = rec.FinishedAt
+			st.Error = rec.Error
+			for _, cp := range cps {
+				st.Scanned += cp.Scanned
+				st.Changed += cp.Changed
+				if cp.Done {
+					st.SegmentsDone++
+				}
+			}
+		}
+		statuses = append(statuses, st)
+	}
+	return statuses, nil
+}
+
+func ptr[T any](v T) *T { return &v }
@@ -0,0 +1,336 @@
+package ddbsdk
+
+import (
+	"context"
+	"reflect"
+	"slices"
+	"sync"
+
+	"github.com/acksell/bezos/dynamodb/ddbiface"
+	"github.com/acksell/bezos/dynamodb/index/indices"
+
+	dynamodbv2 "github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Observer receives the start and end of client operations, e.g. to create trace spans
+// and record metrics. See the ddbotel package for an OpenTelemetry implementation.
+//
+// Observed operations are PutItem, UpdateItem, DeleteItem, Query (one per page),
+// Txer.Commit and Batcher.ExecAll.
+type Observer interface {
+	// Observe is called when an operation starts. The returned context is used
+	// for the requests of the operation, and the returned func is called once when it ends.
+	Observe(ctx context.Context, op Operation) (context.Context, func(OperationResult))
+}
+
+// Operation describes an observed client operation.
+type Operation struct {
+	// Name is the operation, e.g. "PutItem", "Query" or "Txer.Commit".
+	Name string
+	// Tables are the tables the operation touches, sorted.
+	Tables []string
+	// Index is the GSI name for queries on an index.
+	Index string
+	// EntityTypes are the names of the entity types involved, sorted, as far as they
+	// can be determined from the entity or from key patterns in the indices registry.
+	EntityTypes []string
+	// ItemCount is the number of actions in a write operation.
+	ItemCount int
+}
+
+// OperationResult describes how an observed operation ended.
+type OperationResult struct {
+	Err error
+	// ItemCount is the number of items returned by a read operation.
+	ItemCount int
+	// ConsumedCapacity is the total capacity units consumed by all requests of the operation.
+	ConsumedCapacity float64
+	// Retries is the number of retries made by the client's [RetryPolicy].
+	Retries int
+}
+
+// WithObserver sets an observer for the client's operations.
+// With an observer set, requests ask DynamoDB to return the consumed capacity.
+func WithObserver(o Observer) ClientOption {
+	return func(opts *clientOpts) {
+		opts.observer = o
+	}
+}
+
+// opStats collects request statistics of an observed operation.
+// It is shared through the context, batch writes may update it concurrently.
+type opStats struct {
+	mu       sync.Mutex
+	retries  int
+	capacity float64
+}
+
+type opStatsKey struct{}
+
+func statsFrom(ctx context.Context) *opStats {
+	s, _ := ctx.Value(opStatsKey{}).(*opStats)
+	return s
+}
+
+func (s *opStats) addRetry() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.retries++
+}
+
+func (s *opStats) addCapacity(cc ...types.ConsumedCapacity) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, c := range cc {
+		if c.CapacityUnits != nil {
+			s.capacity += *c.CapacityUnits
+		}
+	}
+}
+
+// observe starts op on o. done must be called with the operation's error and returned item count.
+func observe(ctx context.Context, o Observer, op Operation) (context.Context, func(err error, items int)) {
+	if o == nil {
+		return ctx, func(error, int) {}
+	}
+	stats := &opStats{}
+	ctx = context.WithValue(ctx, opStatsKey{}, stats)
+	ctx, end := o.Observe(ctx, op)
+	return ctx, func(err error, items int) {
+		stats.mu.Lock()
+		defer stats.mu.Unlock()
+		end(OperationResult{
+			Err:              err,
+			ItemCount:        items,
+			ConsumedCapacity: stats.capacity,
+			Retries:          stats.retries,
+		})
+	}
+}
+
+// describeActions builds the Operation for a set of write actions.
+func describeActions(name string, actions []Action) Operation {
+	op := Operation{Name: name, ItemCount: len(actions)}
+	for _, a := range actions {
+		op.Tables = appendUnique(op.Tables, *a.TableName())
+		if et := entityTypeOf(a); et != "" {
+			op.EntityTypes = appendUnique(op.EntityTypes, et)
+		}
+	}
+	slices.Sort(op.Tables)
+	slices.Sort(op.EntityTypes)
+	return op
+}
+
+func appendUnique(s []string, v string) []string {
+	if slices.Contains(s, v) {
+		return s
+	}
+	return append(s, v)
+}
+
+// entityTypeOf returns the registered entity type name of the action's item, or "" if unknown.
+func entityTypeOf(a any) string {
+	var entity DynamoEntity
+	switch act := a.(type) {
+	case *Put:
+		entity = act.Entity
+	case *PutWithCondition:
+		entity = act.put.Entity
+	}
+	if entity != nil {
+		if entry, ok := indices.Lookup(reflect.TypeOf(entity)); ok {
+			return entry.EntityType.Name()
+		}
+		return ""
+	}
+	if act, ok := a.(Action); ok {
+		key := act.PrimaryKey()
+		return entityTypeForKey(*act.TableName(), key.Values.PartitionKey, key.Values.SortKey)
+	}
+	return ""
+}
+
+// singleItemOp builds the Operation for a single-item write.
+func singleItemOp(name string, tableName *string, action any) Operation {
+	op := Operation{Name: name, ItemCount: 1}
+	if tableName != nil {
+		op.Tables = []string{*tableName}
+	}
+	if et := entityTypeOf(action); et != "" {
+		op.EntityTypes = []string{et}
+	}
+	return op
+}
+
+func entityTypeForKey(tableName string, pk, sk any) string {
+	if entry, ok := indices.MatchKey(tableName, pk, sk); ok {
+		return entry.EntityType.Name()
+	}
+	return ""
+}
+
+type observedTxer struct {
+	Txer
+	observer Observer
+	actions  []Action
+}
+
+func (tx *observedTxer) AddAction(actions ...Action) {
+	tx.actions = append(tx.actions, actions...)
+	tx.Txer.AddAction(actions...)
+}
+
+func (tx *observedTxer) Commit(ctx context.Context) error {
+	ctx, done := observe(ctx, tx.observer, describeActions("Txer.Commit", tx.actions))
+	err := tx.Txer.Commit(ctx)
+	done(err, 0)
+	return err
+}
+
+type observedBatcher struct {
+	Batcher
+	observer Observer
+	actions  []Action
+}
+
+func (b *observedBatcher) AddAction(actions ...BatchAction) {
+	for _, a := range actions {
+		b.actions = append(b.actions, a)
+	}
+	b.Batcher.AddAction(actions...)
+}
+
+func (b *observedBatcher) ExecAll(ctx context.Context) error {
+	ctx, done := observe(ctx, b.observer, describeActions("Batcher.ExecAll", b.actions))
+	err := b.Batcher.ExecAll(ctx)
+	done(err, 0)
+	return err
+}
+
+// statsClient asks for consumed capacity on every request and adds it to the operation's stats.
+type statsClient struct {
+	ddb ddbiface.ReadWriteClient
+}
+
+var _ ddbiface.ReadWriteClient = &statsClient{}
+
+func capacityMode(ctx context.Context, mode types.ReturnConsumedCapacity) types.ReturnConsumedCapacity {
+	if mode == "" && statsFrom(ctx) != nil {
+		return types.ReturnConsumedCapacityTotal
+	}
+	return mode
+}
+
+func recordCapacity(ctx context.Context, cc ...types.ConsumedCapacity) {
+	if s := statsFrom(ctx); s != nil {
+		s.addCapacity(cc...)
+	}
+}
+
+func recordSingleCapacity(ctx context.Context, cc *types.ConsumedCapacity) {
+	if cc != nil {
+		recordCapacity(ctx, *cc)
+	}
+}
+
+func (c *statsClient) BatchGetItem(ctx context.Context, params *dynamodbv2.BatchGetItemInput, optFns ...func(*dynamodbv2.Options)) (*dynamodbv2.BatchGetItemOutput, error) {
+	in := *params
+	in.ReturnConsumedCapacity = capacityMode(ctx, in.ReturnConsumedCapacity)
+	out, err := c.ddb.BatchGetItem(ctx, &in, optFns...)
+	if err == nil {
+		recordCapacity(ctx, out.ConsumedCapacity...)
+	}
+	return out, err
+}
+
+func (c *statsClient) BatchWriteItem(ctx context.Context, params *dynamodbv2.BatchWriteItemInput, optFns ...func(*dynamodbv2.Options)) (*dynamodbv2.BatchWriteItemOutput, error) {
+	in := *params
+	in.ReturnConsumedCapacity = capacityMode(ctx, in.ReturnConsumedCapacity)
+	out, err := c.ddb.BatchWriteItem(ctx, &in, optFns...)
+	if err == nil {
+		recordCapacity(ctx, out.ConsumedCapacity...)
+	}
+	return out, err
+}
+
+func (c *statsClient) DeleteItem(ctx context.Context, params *dynamodbv2.DeleteItemInput, optFns ...func(*dynamodbv2.Options)) (*dynamodbv2.DeleteItemOutput, error) {
+	in := *params
+	in.ReturnConsumedCapacity = capacityMode(ctx, in.ReturnConsumedCapacity)
+	out, err := c.ddb.DeleteItem(ctx, &in, optFns...)
+	if err == nil {
+		recordSingleCapacity(ctx, out.ConsumedCapacity)
+	}
+	return out, err
+}
+
+func (c *statsClient) GetItem(ctx context.Context, params *dynamodbv2.GetItemInput, optFns ...func(*dynamodbv2.Options)) (*dynamodbv2.GetItemOutput, error) {
+	in := *params
+	in.ReturnConsumedCapacity = capacityMode(ctx, in.ReturnConsumedCapacity)
+	out, err := c.ddb.GetItem(ctx, &in, optFns...)
+	if err == nil {
+		recordSingleCapacity(ctx, out.ConsumedCapacity)
+	}
+	return out, err
+}
+
+func (c *statsClient) PutItem(ctx context.Context, params *dynamodbv2.PutItemInput, optFns ...func(*dynamodbv2.Options)) (*dynamodbv2.PutItemOutput, error) {
+	in := *params
+	in.ReturnConsumedCapacity = capacityMode(ctx, in.ReturnConsumedCapacity)
+	out, err := c.ddb.PutItem(ctx, &in, optFns...)
+	if err == nil {
+		recordSingleCapacity(ctx, out.ConsumedCapacity)
+	}
+	return out, err
+}
+
+func (c *statsClient) TransactGetItems(ctx context.Context, params *dynamodbv2.TransactGetItemsInput, optFns ...func(*dynamodbv2.Options)) (*dynamodbv2.TransactGetItemsOutput, error) {
+	in := *params
+	in.ReturnConsumedCapacity = capacityMode(ctx, in.ReturnConsumedCapacity)
+	out, err := c.ddb.TransactGetItems(ctx, &in, optFns...)
+	if err == nil {
+		recordCapacity(ctx, out.ConsumedCapacity...)
+	}
+	return out, err
+}
+
+func (c *statsClient) TransactWriteItems(ctx context.Context, params *dynamodbv2.TransactWriteItemsInput, optFns ...func(*dynamodbv2.Options)) (*dynamodbv2.TransactWriteItemsOutput, error) {
+	in := *params
+	in.ReturnConsumedCapacity = capacityMode(ctx, in.ReturnConsumedCapacity)
+	out, err := c.ddb.TransactWriteItems(ctx, &in, optFns...)
+	if err == nil {
+		recordCapacity(ctx, out.ConsumedCapacity...)
+	}
+	return out, err
+}
+
+func (c *statsClient) Query(ctx context.Context, params *dynamodbv2.QueryInput, optFns ...func(*dynamodbv2.Options)) (*dynamodbv2.QueryOutput, error) {
+	in := *params
+	in.ReturnConsumedCapacity = capacityMode(ctx, in.ReturnConsumedCapacity)
+	out, err := c.ddb.Query(ctx, &in, optFns...)
+	if err == nil {
+		recordSingleCapacity(ctx, out.ConsumedCapacity)
+	}
+	return out, err
+}
+
+func (c *statsClient) Scan(ctx context.Context, params *dynamodbv2.ScanInput, optFns ...func(*dynamodbv2.Options)) (*dynamodbv2.ScanOutput, error) {
+	in := *params
+	in.ReturnConsumedCapacity = capacityMode(ctx, in.ReturnConsumedCapacity)
+	out, err := c.ddb.Scan(ctx, &in, optFns...)
+	if err == nil {
+		recordSingleCapacity(ctx, out.ConsumedCapacity)
+	}
+	return out, err
+}
+
+func (c *statsClient) UpdateItem(ctx context.Context, params *dynamodbv2.UpdateItemInput, optFns ...func(*dynamodbv2.Options)) (*dynamodbv2.UpdateItemOutput, error) {
+	in := *params
+	in.ReturnConsumedCapacity = capacityMode(ctx, in.ReturnConsumedCapacity)
+	out, err := c.ddb.UpdateItem(ctx, &in, optFns...)
+	if err == nil {
+		recordSingleCapacity(ctx, out.ConsumedCapacity)
+	}
+	return out, err
+}
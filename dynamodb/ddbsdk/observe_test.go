@@ -0,0 +1,89 @@
+package ddbsdk
+
+import (
+	"context"
+	"testing"
+
+	"github.com/acksell/bezos/dynamodb/index"
+	"github.com/acksell/bezos/dynamodb/index/indices"
+	"github.com/acksell/bezos/dynamodb/index/val"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+type recordingObserver struct {
+	ops     []Operation
+	results []OperationResult
+}
+
+func (o *recordingObserver) Observe(ctx context.Context, op Operation) (context.Context, func(OperationResult)) {
+	o.ops = append(o.ops, op)
+	return ctx, func(res OperationResult) {
+		o.results = append(o.results, res)
+	}
+}
+
+func TestObserver_ReportsOperations(t *testing.T) {
+	indices.Clear()
+	defer indices.Clear()
+	indices.Add(index.PrimaryIndex[testEntity]{
+		Table:        batchTestTable,
+		PartitionKey: val.Fmt("user#{pk}"),
+		SortKey:      val.FromField("sk").Ptr(),
+	})
+
+	flaky := newFlakyClient(t, 1, &types.ProvisionedThroughputExceededException{Message: aws.String("slow down")})
+	obs := &recordingObserver{}
+	db := NewClient(flaky,
+		WithObserver(obs),
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 3, Backoff: noBackoff}),
+	)
+	ctx := context.Background()
+
+	e := &testEntity{PK: "user#1", SK: "profile"}
+	if err := db.PutItem(ctx, NewUnsafePut(batchTestTable, batchTestKey(e.PK, e.SK), e)); err != nil {
+		t.Fatalf("PutItem failed: %v", err)
+	}
+
+	tx := db.NewTx()
+	tx.AddAction(
+		NewUnsafeUpdate(batchTestTable, batchTestKey("user#1", "profile")).AddOp(SetFieldOp("name", "Alice")),
+		NewDelete(batchTestTable, batchTestKey("other#2", "profile")),
+	)
+	if err := tx.Commit(ctx); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+
+	if _, err := db.NewQuery(QueryPartition(batchTestTable, "user#1")).QueryAll(ctx); err != nil {
+		t.Fatalf("QueryAll failed: %v", err)
+	}
+
+	if len(obs.ops) != 3 {
+		t.Fatalf("expected 3 operations, got %d: %+v", len(obs.ops), obs.ops)
+	}
+
+	put := obs.ops[0]
+	if put.Name != "PutItem" || len(put.EntityTypes) != 1 || put.EntityTypes[0] != "testEntity" {
+		t.Errorf("unexpected put operation: %+v", put)
+	}
+	if obs.results[0].Retries != 1 {
+		t.Errorf("expected 1 retry for put, got %d", obs.results[0].Retries)
+	}
+
+	commit := obs.ops[1]
+	if commit.Name != "Txer.Commit" || commit.ItemCount != 2 {
+		t.Errorf("unexpected commit operation: %+v", commit)
+	}
+	// Only the update matches the registered key pattern.
+	if len(commit.EntityTypes) != 1 || commit.EntityTypes[0] != "testEntity" {
+		t.Errorf("expected entity type from key pattern, got %v", commit.EntityTypes)
+	}
+
+	query := obs.ops[2]
+	if query.Name != "Query" || query.Tables[0] != batchTestTable.Name {
+		t.Errorf("unexpected query operation: %+v", query)
+	}
+	if obs.results[2].ItemCount != 1 {
+		t.Errorf("expected query to return 1 item, got %d", obs.results[2].ItemCount)
+	}
+}
@@ -0,0 +1,155 @@
+package ddbsdk
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/acksell/bezos/dynamodb/table"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	expression2 "github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// OutboxTable stores the outbox records written by [WithOutbox], until the relay of
+// package outbox has published them. Create it along with your other tables.
+//
+// Pending records are in the sparse PendingGSI, ordered by the time they were written.
+var OutboxTable = table.TableDefinition{
+	Name: "bezos_outbox",
+	KeyDefinitions: table.PrimaryKeyDefinition{
+		PartitionKey: table.KeyDef{Name: "id", Kind: table.KeyKindS},
+	},
+	TimeToLiveKey: "expiresAt",
+	GSIs: []table.GSIDefinition{{
+		Name: OutboxPendingGSI,
+		KeyDefinitions: table.PrimaryKeyDefinition{
+			PartitionKey: table.KeyDef{Name: OutboxPendingAttr, Kind: table.KeyKindS},
+			SortKey:      table.KeyDef{Name: OutboxOrderAttr, Kind: table.KeyKindS},
+		},
+	}},
+}
+
+const (
+	// OutboxPendingGSI is the GSI of the [OutboxTable] holding the pending records.
+	OutboxPendingGSI = "PendingGSI"
+	// OutboxPendingAttr is set to [OutboxPending] on records that weren't published yet.
+	OutboxPendingAttr = "outboxPending"
+	OutboxPending     = "PENDING"
+	// OutboxOrderAttr orders the pending records by the time they were written.
+	OutboxOrderAttr = "outboxOrder"
+)
+
+// OutboxEvent is a domain event that is published after the transaction that
+// wrote it commits, see [WithOutbox].
+type OutboxEvent struct {
+	// ID identifies the event. A generated, time-ordered ID is used if empty.
+	// Events can be published more than once, consumers can drop duplicates by ID.
+	ID string
+	// Type names the event, e.g. "OrderPlaced".
+	Type string
+	// Payload is the event data, marshaled with attributevalue.
+	Payload any
+}
+
+// OutboxRecord is an outbox item as stored in the [OutboxTable].
+type OutboxRecord struct {
+	ID        string    `dynamodbav:"id"`
+	Type      string    `dynamodbav:"eventType"`
+	CreatedAt time.Time `dynamodbav:"createdAt"`
+	// Attempts counts the failed publish attempts.
+	Attempts  int    `dynamodbav:"attempts"`
+	LastError string `dynamodbav:"lastError,omitempty"`
+	// Payload is the marshaled OutboxEvent.Payload.
+	Payload types.AttributeValue `dynamodbav:"-"`
+}
+
+const outboxPayloadAttr = "payload"
+
+// DecodeOutboxRecord decodes an item of the [OutboxTable].
+func DecodeOutboxRecord(item Item) (OutboxRecord, error) {
+	var r OutboxRecord
+	if err := attributevalue.UnmarshalMap(item, &r); err != nil {
+		return OutboxRecord{}, fmt.Errorf("decoding outbox record: %w", err)
+	}
+	r.Payload = item[outboxPayloadAttr]
+	return r, nil
+}
+
+// DecodePayload unmarshals the record's payload into v.
+func (r OutboxRecord) DecodePayload(v any) error {
+	if r.Payload == nil {
+		return nil
+	}
+	return attributevalue.Unmarshal(r.Payload, v)
+}
+
+// OutboxKey returns the primary key of the outbox record with the given ID.
+func OutboxKey(id string) table.PrimaryKey {
+	return table.PrimaryKey{
+		Definition: OutboxTable.KeyDefinitions,
+		Values:     table.PrimaryKeyValues{PartitionKey: id},
+	}
+}
+
+// outboxItem is the entity written for an OutboxEvent.
+type outboxItem struct {
+	OutboxRecord
+	Pending string `dynamodbav:"outboxPending"`
+	Order   string `dynamodbav:"outboxOrder"`
+}
+
+func (o *outboxItem) IsValid() error {
+	if o.Type == "" {
+		return fmt.Errorf("outbox event %s has no type", o.ID)
+	}
+	return nil
+}
+
+func (o *outboxItem) MarshalDynamoDBAttributeValue() (types.AttributeValue, error) {
+	type attrs outboxItem // without the Marshaler method
+	av, err := attributevalue.Marshal((*attrs)(o))
+	if err != nil {
+		return nil, err
+	}
+	if o.Payload != nil {
+		av.(*types.AttributeValueMemberM).Value[outboxPayloadAttr] = o.Payload
+	}
+	return av, nil
+}
+
+// outboxActions returns the puts of the outbox records of events. A put fails if a
+// record with the same ID exists, so an event is never published twice because it
+// was written twice.
+func outboxActions(events []OutboxEvent, now time.Time) ([]Action, error) {
+	actions := make([]Action, 0, len(events))
+	for _, e := range events {
+		id := e.ID
+		if id == "" {
+			id = newSortableID(now)
+		}
+		payload, err := attributevalue.Marshal(e.Payload)
+		if err != nil {
+			return nil, fmt.Errorf("marshaling payload of outbox event %s: %w", e.Type, err)
+		}
+		item := &outboxItem{
+			OutboxRecord: OutboxRecord{ID: id, Type: e.Type, CreatedAt: now, Payload: payload},
+			Pending:      OutboxPending,
+			Order:        fmt.Sprintf("%020d#%s", now.UnixNano(), id),
+		}
+		actions = append(actions, NewUnsafePut(OutboxTable, OutboxKey(id), item).
+			WithCondition(expression2.AttributeNotExists(expression2.Name("id"))))
+	}
+	return actions, nil
+}
+
+// newSortableID returns a random ID that sorts by creation time.
+func newSortableID(now time.Time) string {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err)
+	}
+	return fmt.Sprintf("%020d-%s", now.UnixNano(), hex.EncodeToString(b[:]))
+}
@@ -0,0 +1,53 @@
+package outbox
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/acksell/bezos/dynamodb/ddbsdk"
+)
+
+// MemoryPublisher is a [Publisher] that keeps the published records in memory,
+// for tests. The zero value is ready to use.
+type MemoryPublisher struct {
+	mu      sync.Mutex
+	records []ddbsdk.OutboxRecord
+	err     error
+}
+
+func (p *MemoryPublisher) Publish(ctx context.Context, r ddbsdk.OutboxRecord) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err != nil {
+		return p.err
+	}
+	p.records = append(p.records, r)
+	return nil
+}
+
+// Records returns the published records in publish order.
+func (p *MemoryPublisher) Records() []ddbsdk.OutboxRecord {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return slices.Clone(p.records)
+}
+
+// Types returns the types of the published records in publish order.
+func (p *MemoryPublisher) Types() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	types := make([]string, len(p.records))
+	for i, r := range p.records {
+		types[i] = r.Type
+	}
+	return types
+}
+
+// Fail makes Publish return err until it is called with nil, e.g. to test how a
+// broker outage is handled.
+func (p *MemoryPublisher) Fail(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.err = err
+}
@@ -0,0 +1,231 @@
+// Package outbox publishes the domain events written with [ddbsdk.WithOutbox].
+//
+// Writing an event in the transaction that changes the entities guarantees that it
+// is emitted whenever the change commits, and never when it doesn't:
+//
+//	tx := db.NewTx(ddbsdk.WithOutbox(ddbsdk.OutboxEvent{Type: "OrderPlaced", Payload: placed}))
+//	tx.AddAction(OrderIndex.UnsafePut(order))
+//	err := tx.Commit(ctx)
+//
+// A [Relay] polls the pending records of the [ddbsdk.OutboxTable], hands them to a
+// [Publisher] in the order they were written, and deletes each record once it was
+// published. Delivery is at least once: a relay that crashes between publishing and
+// deleting publishes the record again, so consumers should drop duplicates by ID.
+// [MemoryPublisher] collects the published records for tests.
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/acksell/bezos/dynamodb/ddbsdk"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Publisher delivers outbox records, e.g. to SNS, SQS or EventBridge.
+type Publisher interface {
+	// Publish delivers a record. A returned error leaves the record pending, to be
+	// retried by the next drain.
+	Publish(ctx context.Context, r ddbsdk.OutboxRecord) error
+}
+
+// PublisherFunc adapts a function to a [Publisher].
+type PublisherFunc func(ctx context.Context, r ddbsdk.OutboxRecord) error
+
+func (f PublisherFunc) Publish(ctx context.Context, r ddbsdk.OutboxRecord) error {
+	return f(ctx, r)
+}
+
+// Relay moves pending outbox records to a [Publisher].
+type Relay struct {
+	db   ddbsdk.IO
+	pub  Publisher
+	opts relayOpts
+}
+
+type RelayOption func(*relayOpts)
+
+type relayOpts struct {
+	batchSize     int
+	pollInterval  time.Duration
+	keepDelivered time.Duration
+	maxAttempts   int
+	onError       func(error)
+}
+
+// WithBatchSize sets the number of pending records read per query, 25 by default.
+func WithBatchSize(n int) RelayOption {
+	return func(o *relayOpts) {
+		o.batchSize = n
+	}
+}
+
+// WithPollInterval sets how long [Relay.Run] waits when the outbox is empty or a
+// drain failed, 1 second by default.
+func WithPollInterval(d time.Duration) RelayOption {
+	return func(o *relayOpts) {
+		o.pollInterval = d
+	}
+}
+
+// WithKeepDelivered marks published records as delivered instead of deleting them,
+// and lets them expire with the table's TTL after d, e.g. to inspect them with ddb ui.
+func WithKeepDelivered(d time.Duration) RelayOption {
+	return func(o *relayOpts) {
+		o.keepDelivered = d
+	}
+}
+
+// WithMaxAttempts parks records that failed to publish n times, so that they don't
+// block the outbox. By default records are retried until they are published.
+func WithMaxAttempts(n int) RelayOption {
+	return func(o *relayOpts) {
+		o.maxAttempts = n
+	}
+}
+
+// WithErrorHandler sets the function that failed drains of [Relay.Run] and parked
+// records are reported to.
+func WithErrorHandler(fn func(error)) RelayOption {
+	return func(o *relayOpts) {
+		o.onError = fn
+	}
+}
+
+// NewRelay creates a relay of the records in the [ddbsdk.OutboxTable] of db to pub.
+func NewRelay(db ddbsdk.IO, pub Publisher, opts ...RelayOption) *Relay {
+	o := relayOpts{
+		batchSize:    25,
+		pollInterval: time.Second,
+		onError:      func(error) {},
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &Relay{db: db, pub: pub, opts: o}
+}
+
+// Run drains the outbox until ctx is done, polling it for new records.
+// It returns ctx.Err().
+func (r *Relay) Run(ctx context.Context) error {
+	for {
+		n, err := r.Drain(ctx)
+		if err != nil && ctx.Err() == nil {
+			r.opts.onError(err)
+		}
+		if n > 0 && err == nil {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(r.opts.pollInterval):
+		}
+	}
+}
+
+// Drain publishes the pending records in the order they were written, until the
+// outbox is empty. It stops at the first record that fails to publish, so that later
+// records aren't published before it, and counts the failure on the record. With
+// [WithMaxAttempts], a record that failed too often is parked instead, and the drain
+// goes on. Returns the number of published records.
+func (r *Relay) Drain(ctx context.Context) (int, error) {
+	// GSIs only support eventually consistent reads: a record that was just written
+	// may be picked up by the next drain.
+	q := r.db.NewQuery(ddbsdk.QueryPartition(ddbsdk.OutboxTable, ddbsdk.OutboxPending).
+		OnIndex(ddbsdk.OutboxPendingGSI)).EventuallyConsistent().PageSize(r.opts.batchSize)
+	published := 0
+	for {
+		res, err := q.Next(ctx)
+		if err != nil {
+			return published, fmt.Errorf("querying pending outbox records: %w", err)
+		}
+		for _, item := range res.Items {
+			rec, err := ddbsdk.DecodeOutboxRecord(item)
+			if err != nil {
+				return published, err
+			}
+			if err := r.pub.Publish(ctx, rec); err != nil {
+				err = fmt.Errorf("publishing outbox record %s (%s): %w", rec.ID, rec.Type, err)
+				parked, recordErr := r.recordFailure(ctx, rec, err)
+				if recordErr != nil || !parked {
+					return published, errors.Join(err, recordErr)
+				}
+				r.opts.onError(err)
+				continue
+			}
+			if err := r.delivered(ctx, rec); err != nil {
+				return published, err
+			}
+			published++
+		}
+		if res.IsDone {
+			return published, nil
+		}
+	}
+}
+
+// delivered deletes a published record, or marks it as delivered. A record that's
+// already gone, e.g. because a concurrent relay delivered it too, is ignored.
+func (r *Relay) delivered(ctx context.Context, rec ddbsdk.OutboxRecord) error {
+	exists := expression.AttributeExists(expression.Name("id"))
+	var err error
+	if r.opts.keepDelivered > 0 {
+		now := time.Now().UTC()
+		err = r.db.UpdateItem(ctx, ddbsdk.NewUnsafeUpdate(ddbsdk.OutboxTable, ddbsdk.OutboxKey(rec.ID)).
+			AddOp(ddbsdk.RemoveFieldOp(ddbsdk.OutboxPendingAttr)).
+			AddOp(ddbsdk.RemoveFieldOp(ddbsdk.OutboxOrderAttr)).
+			AddOp(ddbsdk.SetFieldOp("deliveredAt", now)).
+			RefreshTTL(now.Add(r.opts.keepDelivered)).
+			WithCondition(exists))
+	} else {
+		err = r.db.DeleteItem(ctx, ddbsdk.NewDelete(ddbsdk.OutboxTable, ddbsdk.OutboxKey(rec.ID)).WithCondition(exists))
+	}
+	var failed *types.ConditionalCheckFailedException
+	if err != nil && !errors.As(err, &failed) {
+		return fmt.Errorf("completing outbox record %s: %w", rec.ID, err)
+	}
+	return nil
+}
+
+// recordFailure counts a failed publish attempt on the record, and parks the record
+// if it reached the maximum number of attempts: it leaves the pending GSI and stays
+// in the table with failedAt set, see [Relay.Requeue].
+func (r *Relay) recordFailure(ctx context.Context, rec ddbsdk.OutboxRecord, cause error) (parked bool, err error) {
+	attempts := rec.Attempts + 1
+	update := ddbsdk.NewUnsafeUpdate(ddbsdk.OutboxTable, ddbsdk.OutboxKey(rec.ID)).
+		AddOp(ddbsdk.SetFieldOp("attempts", attempts)).
+		AddOp(ddbsdk.SetFieldOp("lastError", cause.Error())).
+		WithCondition(expression.Name("attempts").Equal(expression.Value(rec.Attempts)))
+	parked = r.opts.maxAttempts > 0 && attempts >= r.opts.maxAttempts
+	if parked {
+		update.AddOp(ddbsdk.RemoveFieldOp(ddbsdk.OutboxPendingAttr)).
+			AddOp(ddbsdk.RemoveFieldOp(ddbsdk.OutboxOrderAttr)).
+			AddOp(ddbsdk.SetFieldOp("failedAt", time.Now().UTC()))
+	}
+	err = r.db.UpdateItem(ctx, update)
+	var failed *types.ConditionalCheckFailedException
+	if err != nil && !errors.As(err, &failed) {
+		return false, fmt.Errorf("recording failure of outbox record %s: %w", rec.ID, err)
+	}
+	return parked, nil
+}
+
+// Requeue makes a parked record pending again, after the records that are pending
+// now, and resets its attempts.
+func (r *Relay) Requeue(ctx context.Context, id string) error {
+	now := time.Now().UTC()
+	err := r.db.UpdateItem(ctx, ddbsdk.NewUnsafeUpdate(ddbsdk.OutboxTable, ddbsdk.OutboxKey(id)).
+		AddOp(ddbsdk.SetFieldOp(ddbsdk.OutboxPendingAttr, ddbsdk.OutboxPending)).
+		AddOp(ddbsdk.SetFieldOp(ddbsdk.OutboxOrderAttr, fmt.Sprintf("%020d#%s", now.UnixNano(), id))).
+		AddOp(ddbsdk.SetFieldOp("attempts", 0)).
+		AddOp(ddbsdk.RemoveFieldOp("failedAt")).
+		WithCondition(expression.AttributeExists(expression.Name("failedAt"))))
+	if err != nil {
+		return fmt.Errorf("requeueing outbox record %s: %w", id, err)
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/acksell/bezos/dynamodb/ddbsdk"
+)
+
+func newTestDB(t *testing.T, events ...string) *ddbsdk.Client {
+	t.Helper()
+	db := ddbsdk.NewMemoryClient(ddbsdk.OutboxTable)
+	for _, e := range events {
+		if err := db.NewTx(ddbsdk.WithOutbox(ddbsdk.OutboxEvent{Type: e, Payload: e})).Commit(context.Background()); err != nil {
+			t.Fatalf("writing %s: %v", e, err)
+		}
+		// Records written within the same nanosecond have no defined order.
+		time.Sleep(time.Microsecond)
+	}
+	return db
+}
+
+func getRecord(t *testing.T, db ddbsdk.IO, id string) ddbsdk.Item {
+	t.Helper()
+	item, err := db.NewLookup().GetItem(context.Background(), ddbsdk.GetItemRequest{Table: ddbsdk.OutboxTable, Key: ddbsdk.OutboxKey(id)})
+	if err != nil {
+		t.Fatalf("getting %s: %v", id, err)
+	}
+	return item
+}
+
+func TestDrain(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t, "A", "B", "C")
+	pub := &MemoryPublisher{}
+	relay := NewRelay(db, pub, WithBatchSize(2))
+
+	n, err := relay.Drain(ctx)
+	if err != nil || n != 3 {
+		t.Fatalf("Drain() = %d, %v, want 3", n, err)
+	}
+	if got := pub.Types(); !reflect.DeepEqual(got, []string{"A", "B", "C"}) {
+		t.Errorf("published %v, want A, B, C in order", got)
+	}
+	var payload string
+	if err := pub.Records()[1].DecodePayload(&payload); err != nil || payload != "B" {
+		t.Errorf("payload = %q, %v", payload, err)
+	}
+	for _, r := range pub.Records() {
+		if item := getRecord(t, db, r.ID); item != nil {
+			t.Errorf("record %s wasn't deleted", r.ID)
+		}
+	}
+
+	// Delivered records are not published again.
+	if n, err := relay.Drain(ctx); err != nil || n != 0 {
+		t.Errorf("second Drain() = %d, %v, want 0", n, err)
+	}
+}
+
+func TestDrain_KeepDelivered(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t, "A")
+	pub := &MemoryPublisher{}
+
+	if _, err := NewRelay(db, pub, WithKeepDelivered(time.Hour)).Drain(ctx); err != nil {
+		t.Fatalf("Drain: %v", err)
+	}
+	item := getRecord(t, db, pub.Records()[0].ID)
+	if item == nil {
+		t.Fatal("delivered record was deleted")
+	}
+	for _, attr := range []string{"deliveredAt", ddbsdk.OutboxTable.TimeToLiveKey} {
+		if _, ok := item[attr]; !ok {
+			t.Errorf("delivered record has no %s", attr)
+		}
+	}
+	if _, ok := item[ddbsdk.OutboxPendingAttr]; ok {
+		t.Error("delivered record is still pending")
+	}
+	if n, err := NewRelay(db, pub).Drain(ctx); err != nil || n != 0 {
+		t.Errorf("second Drain() = %d, %v, want 0", n, err)
+	}
+}
+
+func TestDrain_PublishFails(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t, "A", "B")
+	pub := &MemoryPublisher{}
+	relay := NewRelay(db, pub)
+
+	pub.Fail(errors.New("broker down"))
+	n, err := relay.Drain(ctx)
+	if err == nil || n != 0 {
+		t.Fatalf("Drain() = %d, %v, want an error", n, err)
+	}
+	if len(pub.Records()) != 0 {
+		t.Errorf("published %v", pub.Types())
+	}
+
+	pub.Fail(nil)
+	n, err = relay.Drain(ctx)
+	if err != nil || n != 2 {
+		t.Fatalf("Drain() after recovery = %d, %v, want 2", n, err)
+	}
+	if got := pub.Types(); !reflect.DeepEqual(got, []string{"A", "B"}) {
+		t.Errorf("published %v, want A, B", got)
+	}
+	if got := pub.Records()[0].Attempts; got != 1 {
+		t.Errorf("attempts of A = %d, want 1", got)
+	}
+}
+
+func TestDrain_MaxAttempts(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t, "Poison", "B")
+	var published []string
+	var reported []error
+	var poisonID string
+	pub := PublisherFunc(func(ctx context.Context, r ddbsdk.OutboxRecord) error {
+		if r.Type == "Poison" && r.Attempts < 5 {
+			poisonID = r.ID
+			return fmt.Errorf("can't publish %s", r.Type)
+		}
+		published = append(published, r.Type)
+		return nil
+	})
+	relay := NewRelay(db, pub, WithMaxAttempts(2), WithErrorHandler(func(err error) { reported = append(reported, err) }))
+
+	if _, err := relay.Drain(ctx); err == nil {
+		t.Fatal("first Drain() succeeded, want the poison record to block")
+	}
+	n, err := relay.Drain(ctx)
+	if err != nil || n != 1 || !reflect.DeepEqual(published, []string{"B"}) {
+		t.Fatalf("second Drain() = %d, %v, published %v, want B after parking the poison record", n, err, published)
+	}
+	if len(reported) != 1 {
+		t.Errorf("reported %v, want the parked record", reported)
+	}
+
+	// The parked record stays until it is requeued.
+	items, err := db.NewQuery(ddbsdk.QueryPartition(ddbsdk.OutboxTable, ddbsdk.OutboxPending).OnIndex(ddbsdk.OutboxPendingGSI)).QueryAll(ctx)
+	if err != nil || len(items.Items) != 0 {
+		t.Fatalf("pending records = %v, %v, want none", items, err)
+	}
+	if err := relay.Requeue(ctx, poisonID); err != nil {
+		t.Fatalf("Requeue: %v", err)
+	}
+	if _, err := relay.Drain(ctx); err == nil {
+		t.Fatal("Drain() after requeue succeeded, want the poison record to fail again")
+	}
+	if item := getRecord(t, db, poisonID); item == nil {
+		t.Fatal("requeued record was deleted")
+	}
+}
+
+func TestRun(t *testing.T) {
+	db := newTestDB(t, "A")
+	pub := &MemoryPublisher{}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- NewRelay(db, pub, WithPollInterval(time.Millisecond)).Run(ctx) }()
+
+	if err := db.NewTx(ddbsdk.WithOutbox(ddbsdk.OutboxEvent{Type: "B"})).Commit(context.Background()); err != nil {
+		t.Fatalf("writing B: %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for len(pub.Records()) < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("Run() = %v, want context.Canceled", err)
+	}
+	if got := pub.Types(); !reflect.DeepEqual(got, []string{"A", "B"}) {
+		t.Errorf("published %v, want A, B", got)
+	}
+}
@@ -5,7 +5,9 @@ It is not used for queries. For queries, use keyconditionast, filterexpressionas
 package ast
 
 import (
+	"bytes"
 	"fmt"
+	"math/big"
 
 	"github.com/acksell/bezos/dynamodb/ddbstore/astutil"
 )
@@ -52,28 +54,85 @@ func (left *Operand) Equal(right *Operand) bool {
 	if left.Type != right.Type {
 		panic("cannot compare different types")
 	}
-	switch left.Type {
-	// all DynamoDB lists & sets are encoded as strings
-	case LIST, NUMBER_SET, STRING_SET, BINARY_SET:
-		av1 := astutil.ToSlice[string](left.Value)
-		av2 := astutil.ToSlice[string](left.Value)
+	return equalValues(left.Type, left.Value, right.Value)
+}
+
+// equalValues compares two values of type t. Lists are compared in order,
+// sets regardless of order and maps by their keys and values.
+func equalValues(t AttributeType, a, b any) bool {
+	switch t {
+	case LIST:
+		av1 := astutil.CastTo[[]AttributeValue](a)
+		av2 := astutil.CastTo[[]AttributeValue](b)
 		if len(av1) != len(av2) {
 			return false
 		}
 		for i := range av1 {
-			if av1[i] != av2[i] {
+			if !equalAttributeValues(av1[i], av2[i]) {
 				return false
 			}
 		}
 		return true
-	case NUMBER, STRING, BINARY, BOOL, NULL:
-		fmt.Printf("left: %T%+v right: %T%+v (%T) eq: %v\n", left, left, right, right, right.Value, left.Value == right.Value)
-		return left.Value == right.Value
 	case MAP:
-		panic("cannot compare two maps")
+		av1 := astutil.CastTo[map[string]AttributeValue](a)
+		av2 := astutil.CastTo[map[string]AttributeValue](b)
+		if len(av1) != len(av2) {
+			return false
+		}
+		for k, v1 := range av1 {
+			v2, ok := av2[k]
+			if !ok || !equalAttributeValues(v1, v2) {
+				return false
+			}
+		}
+		return true
+	case STRING_SET, NUMBER_SET:
+		return equalSets(astutil.CastTo[[]string](a), astutil.CastTo[[]string](b), func(s string) string {
+			if t == NUMBER_SET {
+				return normalizeNumber(s)
+			}
+			return s
+		})
+	case BINARY_SET:
+		return equalSets(astutil.CastTo[[][]byte](a), astutil.CastTo[[][]byte](b), func(b []byte) string { return string(b) })
+	case NUMBER:
+		return normalizeNumber(astutil.String(a)) == normalizeNumber(astutil.String(b))
+	case BINARY:
+		return bytes.Equal(astutil.CastTo[[]byte](a), astutil.CastTo[[]byte](b))
+	case STRING, BOOL, NULL:
+		return a == b
 	default:
-		panic(fmt.Sprintf("unsupported attribute type %s", left.Type))
+		panic(fmt.Sprintf("unsupported attribute type %s", t))
+	}
+}
+
+func equalAttributeValues(a, b AttributeValue) bool {
+	return a.Type == b.Type && equalValues(a.Type, a.Value, b.Value)
+}
+
+func equalSets[T any](a, b []T, key func(T) string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	seen := make(map[string]bool, len(a))
+	for _, v := range a {
+		seen[key(v)] = true
+	}
+	for _, v := range b {
+		if !seen[key(v)] {
+			return false
+		}
+	}
+	return true
+}
+
+// normalizeNumber formats a number canonically, so "1.0" and "1" are equal.
+func normalizeNumber(n string) string {
+	f, ok := new(big.Float).SetPrec(256).SetString(n)
+	if !ok {
+		return n
 	}
+	return f.Text('g', -1)
 }
 
 func (left *Operand) LessThan(right *Operand) bool {
@@ -200,7 +259,7 @@ func (c *ContainsExpr) Eval(input Input, doc map[string]AttributeValue) bool {
 	}
 	for _, item := range c.Container {
 		itemVal, itemOk := item.GetValue(input, doc)
-		if itemOk && itemVal == val {
+		if itemOk && itemVal.Type == val.Type && itemVal.Equal(val) {
 			return true
 		}
 	}
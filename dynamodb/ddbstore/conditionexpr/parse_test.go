@@ -34,6 +34,22 @@ func TestEvalCondition(t *testing.T) {
 			expected:  false,
 			shouldErr: false, // attribute_exists returns false for missing attributes, not an error
 		},
+		{
+			name: "in list",
+			cond: expression.Name("id").In(expression.Value("1"), expression.Value("123")),
+			doc: map[string]types.AttributeValue{
+				"id": &types.AttributeValueMemberS{Value: "123"},
+			},
+			expected: true,
+		},
+		{
+			name: "not in list",
+			cond: expression.Name("id").In(expression.Value("1"), expression.Value("2")),
+			doc: map[string]types.AttributeValue{
+				"id": &types.AttributeValueMemberS{Value: "123"},
+			},
+			expected: false,
+		},
 		{
 			name: "nested path",
 			cond: expression.Name("nested.path").AttributeExists().And(expression.Name("nested.path").Equal(expression.Value("123"))),
@@ -93,6 +109,73 @@ func TestEvalCondition(t *testing.T) {
 			},
 			expected: true,
 		},
+		{
+			name: "list in list",
+			cond: expression.Name("tags").In(expression.Value([]string{"a"}), expression.Value([]string{"a", "b"})),
+			doc: map[string]types.AttributeValue{
+				"tags": &types.AttributeValueMemberL{Value: []types.AttributeValue{
+					&types.AttributeValueMemberS{Value: "a"},
+					&types.AttributeValueMemberS{Value: "b"},
+				}},
+			},
+			expected: true,
+		},
+		{
+			name: "list equality compares both sides",
+			cond: expression.Name("tags").Equal(expression.Value([]string{"b", "a"})),
+			doc: map[string]types.AttributeValue{
+				"tags": &types.AttributeValueMemberL{Value: []types.AttributeValue{
+					&types.AttributeValueMemberS{Value: "a"},
+					&types.AttributeValueMemberS{Value: "b"},
+				}},
+			},
+			expected: false,
+		},
+		{
+			name: "set equality ignores order",
+			cond: expression.Name("tags").Equal(expression.Value(&types.AttributeValueMemberNS{Value: []string{"2", "1.0"}})),
+			doc: map[string]types.AttributeValue{
+				"tags": &types.AttributeValueMemberNS{Value: []string{"1", "2"}},
+			},
+			expected: true,
+		},
+		{
+			name: "set inequality",
+			cond: expression.Name("tags").NotEqual(expression.Value(&types.AttributeValueMemberSS{Value: []string{"a", "c"}})),
+			doc: map[string]types.AttributeValue{
+				"tags": &types.AttributeValueMemberSS{Value: []string{"a", "b"}},
+			},
+			expected: true,
+		},
+		{
+			name: "map equality",
+			cond: expression.Name("address").Equal(expression.Value(map[string]any{"city": "Berlin", "zip": 10115})),
+			doc: map[string]types.AttributeValue{
+				"address": &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+					"city": &types.AttributeValueMemberS{Value: "Berlin"},
+					"zip":  &types.AttributeValueMemberN{Value: "10115"},
+				}},
+			},
+			expected: true,
+		},
+		{
+			name: "map in list of maps",
+			cond: expression.Name("address").In(expression.Value(map[string]any{"city": "Paris"})),
+			doc: map[string]types.AttributeValue{
+				"address": &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+					"city": &types.AttributeValueMemberS{Value: "Berlin"},
+				}},
+			},
+			expected: false,
+		},
+		{
+			name: "binary equality",
+			cond: expression.Name("data").Equal(expression.Value([]byte("abc"))),
+			doc: map[string]types.AttributeValue{
+				"data": &types.AttributeValueMemberB{Value: []byte("abc")},
+			},
+			expected: true,
+		},
 	}
 
 	for _, tc := range testCases {
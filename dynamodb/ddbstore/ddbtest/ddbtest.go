@@ -0,0 +1,390 @@
+// Package ddbtest asserts the contents of a [ddbstore.Store] against golden files.
+//
+// [Dump] renders the items of the store's tables and GSIs in a canonical form: sorted
+// by key, one attribute per line. [AssertGolden] compares the dump with a golden file,
+// so a test of a service's DynamoDB side effects is a few lines, and its review a diff:
+//
+//	func TestPlaceOrder(t *testing.T) {
+//		store, _ := ddbstore.New(ddbstore.StoreOptions{InMemory: true}, OrdersTable)
+//		svc := NewService(ddbsdk.NewClient(store))
+//		svc.PlaceOrder(ctx, "tenant-1", cart)
+//		ddbtest.AssertGolden(t, store, "testdata/place_order.golden", ddbtest.IgnoreTimestamps(), ddbtest.IgnoreUUIDs())
+//	}
+//
+// Run the tests with -ddbtest.update to write the golden files, in packages that use
+// ddbtest. An -update flag of the package's own, or DDBTEST_UPDATE=1, works as well:
+//
+//	go test ./orders -run TestPlaceOrder -ddbtest.update
+//
+// Rules mask volatile values, like creation times and generated IDs, with a
+// placeholder, so the dump is the same on every run.
+package ddbtest
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/acksell/bezos/dynamodb/ddbstore"
+	"github.com/acksell/bezos/dynamodb/table"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Option configures a dump.
+type Option func(*options)
+
+type options struct {
+	// selected are the selected tables and GSIs, all if empty.
+	selected []string
+	// ignored are the paths of attributes whose values are masked.
+	ignored  map[string]bool
+	patterns []pattern
+}
+
+// pattern masks the matches of re in string values with placeholder.
+type pattern struct {
+	re          *regexp.Regexp
+	placeholder string
+}
+
+// Tables selects the tables and GSIs to dump, by table name or as "table/GSI".
+// A table name selects the table without its GSIs. By default, every table and GSI is dumped.
+func Tables(names ...string) Option {
+	return func(o *options) {
+		o.selected = append(o.selected, names...)
+	}
+}
+
+// IgnoreAttributes masks the values of the attributes with "<ignored>". Nested attributes
+// of maps are separated by dots, e.g. "audit.updatedAt".
+func IgnoreAttributes(paths ...string) Option {
+	return func(o *options) {
+		for _, p := range paths {
+			o.ignored[p] = true
+		}
+	}
+}
+
+// IgnorePattern masks the matches of re in string values, keys included, with placeholder.
+func IgnorePattern(re *regexp.Regexp, placeholder string) Option {
+	return func(o *options) {
+		o.patterns = append(o.patterns, pattern{re: re, placeholder: placeholder})
+	}
+}
+
+var (
+	timestampRe = regexp.MustCompile(`\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:\d{2})`)
+	uuidRe      = regexp.MustCompile(`(?i)[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}`)
+)
+
+// IgnoreTimestamps masks RFC 3339 timestamps in string values with <timestamp>,
+// e.g. time.Time fields and sort keys like "ORDER#2024-05-01T12:00:00Z".
+func IgnoreTimestamps() Option {
+	return IgnorePattern(timestampRe, "<timestamp>")
+}
+
+// IgnoreUUIDs masks UUIDs in string values with <uuid>.
+func IgnoreUUIDs() Option {
+	return IgnorePattern(uuidRe, "<uuid>")
+}
+
+// Dump renders the items of the store's tables and GSIs. Tables list their items with
+// their attributes, GSIs list the GSI keys of their items with the table keys.
+func Dump(ctx context.Context, store *ddbstore.Store, opts ...Option) (string, error) {
+	o := options{ignored: make(map[string]bool)}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	sections, err := o.sections(store.TableDefinitions())
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	for i, s := range sections {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		items, err := scan(ctx, store, s)
+		if err != nil {
+			return "", err
+		}
+		o.writeSection(&b, s, items)
+	}
+	return b.String(), nil
+}
+
+// section is a table or GSI of a dump.
+type section struct {
+	table table.TableDefinition
+	// gsi is the GSI, nil for the table.
+	gsi *table.GSIDefinition
+}
+
+func (s section) name() string {
+	if s.gsi != nil {
+		return s.table.Name + "/" + s.gsi.Name
+	}
+	return s.table.Name
+}
+
+// sections returns the selected tables and GSIs in the order of defs.
+func (o *options) sections(defs []table.TableDefinition) ([]section, error) {
+	var all []section
+	for _, def := range defs {
+		all = append(all, section{table: def})
+		for i := range def.GSIs {
+			all = append(all, section{table: def, gsi: &def.GSIs[i]})
+		}
+	}
+	if len(o.selected) == 0 {
+		return all, nil
+	}
+	selected := make(map[string]bool, len(o.selected))
+	for _, name := range o.selected {
+		selected[name] = true
+	}
+	var sections []section
+	for _, s := range all {
+		if selected[s.name()] {
+			sections = append(sections, s)
+			delete(selected, s.name())
+		}
+	}
+	for _, name := range o.selected {
+		if selected[name] {
+			return nil, fmt.Errorf("store has no table or GSI %q", name)
+		}
+	}
+	return sections, nil
+}
+
+func scan(ctx context.Context, store *ddbstore.Store, s section) ([]map[string]types.AttributeValue, error) {
+	input := &dynamodb.ScanInput{TableName: &s.table.Name}
+	if s.gsi != nil {
+		input.IndexName = &s.gsi.Name
+	}
+	var items []map[string]types.AttributeValue
+	for {
+		out, err := store.Scan(ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("scanning %s: %w", s.name(), err)
+		}
+		items = append(items, out.Items...)
+		if len(out.LastEvaluatedKey) == 0 {
+			return items, nil
+		}
+		input.ExclusiveStartKey = out.LastEvaluatedKey
+	}
+}
+
+// renderedItem is an item with its volatile values masked.
+type renderedItem struct {
+	// key are the masked key values, in the order they are sorted by.
+	key  []types.AttributeValue
+	text string
+}
+
+func (o *options) writeSection(b *strings.Builder, s section, items []map[string]types.AttributeValue) {
+	keyDefs := []table.KeyDef{s.table.KeyDefinitions.PartitionKey, s.table.KeyDefinitions.SortKey}
+	if s.gsi != nil {
+		keyDefs = append([]table.KeyDef{s.gsi.KeyDefinitions.PartitionKey, s.gsi.KeyDefinitions.SortKey}, keyDefs...)
+	}
+	isKey := make(map[string]bool)
+	for _, kd := range keyDefs {
+		isKey[kd.Name] = kd.Name != ""
+	}
+
+	rendered := make([]renderedItem, len(items))
+	for i, item := range items {
+		var r renderedItem
+		var keys []string
+		for j, kd := range keyDefs {
+			if kd.Name == "" {
+				continue
+			}
+			v := o.mask(kd.Name, item[kd.Name])
+			r.key = append(r.key, v)
+			if j == 2 && s.gsi != nil {
+				keys = append(keys, "->")
+			}
+			keys = append(keys, kd.Name+"="+formatKey(v))
+		}
+		r.text = "  " + strings.Join(keys, " ") + "\n"
+		if s.gsi == nil {
+			r.text += o.formatAttributes(item, isKey)
+		}
+		rendered[i] = r
+	}
+	sort.SliceStable(rendered, func(i, j int) bool {
+		if c := compareKeys(rendered[i].key, rendered[j].key); c != 0 {
+			return c < 0
+		}
+		return rendered[i].text < rendered[j].text
+	})
+
+	kind := "table"
+	if s.gsi != nil {
+		kind = "gsi"
+	}
+	fmt.Fprintf(b, "%s %s (%s)\n", kind, s.name(), pluralItems(len(items)))
+	for _, r := range rendered {
+		b.WriteString("\n")
+		b.WriteString(r.text)
+	}
+}
+
+func pluralItems(n int) string {
+	if n == 1 {
+		return "1 item"
+	}
+	return fmt.Sprintf("%d items", n)
+}
+
+// formatAttributes formats the attributes of item except its keys, sorted by name.
+func (o *options) formatAttributes(item map[string]types.AttributeValue, isKey map[string]bool) string {
+	names := make([]string, 0, len(item))
+	for name := range item {
+		if !isKey[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&b, "    %s: %s\n", name, FormatValue(o.mask(name, item[name])))
+	}
+	return b.String()
+}
+
+// ignoredValue replaces the values of ignored attributes.
+var ignoredValue = &types.AttributeValueMemberS{Value: "<ignored>"}
+
+// mask returns av, the value of the attribute at path, with its volatile values masked.
+func (o *options) mask(path string, av types.AttributeValue) types.AttributeValue {
+	if o.ignored[path] {
+		return ignoredValue
+	}
+	switch v := av.(type) {
+	case *types.AttributeValueMemberS:
+		return &types.AttributeValueMemberS{Value: o.maskString(v.Value)}
+	case *types.AttributeValueMemberSS:
+		ss := make([]string, len(v.Value))
+		for i, s := range v.Value {
+			ss[i] = o.maskString(s)
+		}
+		return &types.AttributeValueMemberSS{Value: ss}
+	case *types.AttributeValueMemberL:
+		l := make([]types.AttributeValue, len(v.Value))
+		for i, e := range v.Value {
+			l[i] = o.mask(path+"["+strconv.Itoa(i)+"]", e)
+		}
+		return &types.AttributeValueMemberL{Value: l}
+	case *types.AttributeValueMemberM:
+		m := make(map[string]types.AttributeValue, len(v.Value))
+		for k, e := range v.Value {
+			m[k] = o.mask(path+"."+k, e)
+		}
+		return &types.AttributeValueMemberM{Value: m}
+	}
+	return av
+}
+
+func (o *options) maskString(s string) string {
+	for _, p := range o.patterns {
+		s = p.re.ReplaceAllLiteralString(s, p.placeholder)
+	}
+	return s
+}
+
+// compareKeys compares key values, numbers by value and others by their formatting.
+func compareKeys(a, b []types.AttributeValue) int {
+	for i := range min(len(a), len(b)) {
+		if c := compareKey(a[i], b[i]); c != 0 {
+			return c
+		}
+	}
+	return len(a) - len(b)
+}
+
+func compareKey(a, b types.AttributeValue) int {
+	an, aok := a.(*types.AttributeValueMemberN)
+	bn, bok := b.(*types.AttributeValueMemberN)
+	if aok && bok {
+		af, _, err1 := big.ParseFloat(an.Value, 10, 128, big.ToNearestEven)
+		bf, _, err2 := big.ParseFloat(bn.Value, 10, 128, big.ToNearestEven)
+		if err1 == nil && err2 == nil {
+			return af.Cmp(bf)
+		}
+	}
+	return strings.Compare(formatKey(a), formatKey(b))
+}
+
+// formatKey formats a key value, S and N unquoted.
+func formatKey(av types.AttributeValue) string {
+	switch v := av.(type) {
+	case *types.AttributeValueMemberS:
+		return v.Value
+	case *types.AttributeValueMemberN:
+		return v.Value
+	}
+	return FormatValue(av)
+}
+
+// FormatValue formats av on one line, e.g. "abc" for a string, [1, 2] for a list of
+// numbers and <<"a", "b">> for a string set.
+func FormatValue(av types.AttributeValue) string {
+	switch v := av.(type) {
+	case nil:
+		return "<none>"
+	case *types.AttributeValueMemberS:
+		return strconv.Quote(v.Value)
+	case *types.AttributeValueMemberN:
+		return v.Value
+	case *types.AttributeValueMemberB:
+		return "b64:" + base64.StdEncoding.EncodeToString(v.Value)
+	case *types.AttributeValueMemberBOOL:
+		return strconv.FormatBool(v.Value)
+	case *types.AttributeValueMemberNULL:
+		return "null"
+	case *types.AttributeValueMemberSS:
+		return formatSet(v.Value, strconv.Quote)
+	case *types.AttributeValueMemberNS:
+		return formatSet(v.Value, func(s string) string { return s })
+	case *types.AttributeValueMemberBS:
+		return formatSet(v.Value, func(b []byte) string { return "b64:" + base64.StdEncoding.EncodeToString(b) })
+	case *types.AttributeValueMemberL:
+		elems := make([]string, len(v.Value))
+		for i, e := range v.Value {
+			elems[i] = FormatValue(e)
+		}
+		return "[" + strings.Join(elems, ", ") + "]"
+	case *types.AttributeValueMemberM:
+		keys := make([]string, 0, len(v.Value))
+		for k := range v.Value {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		elems := make([]string, len(keys))
+		for i, k := range keys {
+			elems[i] = k + ": " + FormatValue(v.Value[k])
+		}
+		return "{" + strings.Join(elems, ", ") + "}"
+	}
+	return fmt.Sprintf("%v", av)
+}
+
+func formatSet[T any](values []T, format func(T) string) string {
+	elems := make([]string, len(values))
+	for i, v := range values {
+		elems[i] = format(v)
+	}
+	sort.Strings(elems)
+	return "<<" + strings.Join(elems, ", ") + ">>"
+}
@@ -0,0 +1,117 @@
+package ddbtest_test
+
+import (
+	"context"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/acksell/bezos/dynamodb/ddbstore"
+	"github.com/acksell/bezos/dynamodb/ddbstore/ddbtest"
+	"github.com/acksell/bezos/dynamodb/table"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/require"
+)
+
+var ordersTable = table.TableDefinition{
+	Name: "orders",
+	KeyDefinitions: table.PrimaryKeyDefinition{
+		PartitionKey: table.KeyDef{Name: "pk", Kind: table.KeyKindS},
+		SortKey:      table.KeyDef{Name: "sk", Kind: table.KeyKindN},
+	},
+	GSIs: []table.GSIDefinition{{
+		Name: "ByStatus",
+		KeyDefinitions: table.PrimaryKeyDefinition{
+			PartitionKey: table.KeyDef{Name: "status", Kind: table.KeyKindS},
+		},
+	}},
+}
+
+var usersTable = table.TableDefinition{
+	Name: "users",
+	KeyDefinitions: table.PrimaryKeyDefinition{
+		PartitionKey: table.KeyDef{Name: "id", Kind: table.KeyKindS},
+	},
+}
+
+func s(v string) types.AttributeValue { return &types.AttributeValueMemberS{Value: v} }
+func n(v string) types.AttributeValue { return &types.AttributeValueMemberN{Value: v} }
+
+func newStore(t *testing.T) *ddbstore.Store {
+	t.Helper()
+	store, err := ddbstore.New(ddbstore.StoreOptions{InMemory: true}, ordersTable, usersTable)
+	require.NoError(t, err)
+	t.Cleanup(func() { store.Close() })
+
+	now := time.Now().UTC().Format(time.RFC3339Nano)
+	items := []struct {
+		table string
+		item  map[string]types.AttributeValue
+	}{
+		{"orders", map[string]types.AttributeValue{"pk": s("TENANT#1"), "sk": n("10"), "status": s("open"), "id": s("9b2e4c1a-6f0d-4a57-9c1e-2f8d3b7a6e10"), "createdAt": s(now)}},
+		{"orders", map[string]types.AttributeValue{"pk": s("TENANT#1"), "sk": n("9"), "total": n("12.50"), "tags": &types.AttributeValueMemberSS{Value: []string{"gift", "express"}}}},
+		{"orders", map[string]types.AttributeValue{"pk": s("TENANT#1"), "sk": n("2"), "status": s("shipped"), "audit": &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+			"by": s("alice"), "at": n("1714564800"),
+		}}}},
+		{"users", map[string]types.AttributeValue{"id": s("USER#" + "0d6f2b8e-3c4a-4e9b-8f71-5a2c9d1e7b34"), "active": &types.AttributeValueMemberBOOL{Value: true}}},
+	}
+	for _, it := range items {
+		_, err := store.PutItem(context.Background(), &dynamodb.PutItemInput{TableName: &it.table, Item: it.item})
+		require.NoError(t, err)
+	}
+	return store
+}
+
+func TestAssertGolden(t *testing.T) {
+	store := newStore(t)
+	ddbtest.AssertGolden(t, store, "testdata/store.golden",
+		ddbtest.IgnoreTimestamps(), ddbtest.IgnoreUUIDs(), ddbtest.IgnoreAttributes("audit.at"))
+}
+
+// The -update flag golden file tests often define must not collide with ddbtest's.
+var _ = flag.Bool("update", false, "update golden files")
+
+func TestAssertGolden_Update(t *testing.T) {
+	setFlag := func(name string) func(t *testing.T) {
+		return func(t *testing.T) {
+			require.NoError(t, flag.Set(name, "true"))
+			t.Cleanup(func() { flag.Set(name, "false") })
+		}
+	}
+	for name, enable := range map[string]func(t *testing.T){
+		"ddbtest flag": setFlag("ddbtest.update"),
+		"package flag": setFlag("update"),
+		"env":          func(t *testing.T) { t.Setenv(ddbtest.UpdateEnv, "1") },
+	} {
+		t.Run(name, func(t *testing.T) {
+			store := newStore(t)
+			path := filepath.Join(t.TempDir(), "store.golden")
+			t.Run("update", func(t *testing.T) {
+				enable(t)
+				ddbtest.AssertGolden(t, store, path)
+			})
+			_, err := os.Stat(path)
+			require.NoError(t, err)
+			ddbtest.AssertGolden(t, store, path)
+		})
+	}
+}
+
+func TestDump_Tables(t *testing.T) {
+	store := newStore(t)
+	got, err := ddbtest.Dump(context.Background(), store, ddbtest.Tables("orders/ByStatus"), ddbtest.IgnoreUUIDs())
+	require.NoError(t, err)
+	require.Equal(t, `gsi orders/ByStatus (2 items)
+
+  status=open -> pk=TENANT#1 sk=10
+
+  status=shipped -> pk=TENANT#1 sk=2
+`, got)
+
+	_, err = ddbtest.Dump(context.Background(), store, ddbtest.Tables("orders/ByDate"))
+	require.ErrorContains(t, err, `no table or GSI "orders/ByDate"`)
+}
@@ -0,0 +1,79 @@
+package ddbtest
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/acksell/bezos/dynamodb/ddbstore"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// update is namespaced so that it doesn't collide with an -update flag of the test
+// package, which updateGolden honors too.
+var update = flag.Bool("ddbtest.update", false, "write the golden files of ddbtest.AssertGolden")
+
+// UpdateEnv is the environment variable that, set to true, makes [AssertGolden] write
+// the golden files.
+const UpdateEnv = "DDBTEST_UPDATE"
+
+// updateGolden reports whether the golden files should be written: with the
+// -ddbtest.update flag, an -update flag the test package defines, or [UpdateEnv].
+func updateGolden() bool {
+	if *update {
+		return true
+	}
+	if f := flag.Lookup("update"); f != nil {
+		if g, ok := f.Value.(flag.Getter); ok {
+			if b, ok := g.Get().(bool); ok && b {
+				return true
+			}
+		}
+	}
+	b, _ := strconv.ParseBool(os.Getenv(UpdateEnv))
+	return b
+}
+
+// AssertGolden dumps the store, see [Dump], and fails t if the dump differs from the
+// golden file at path. With the -ddbtest.update flag, it writes the dump to the file
+// instead, see [UpdateEnv] for the other ways to enable it.
+func AssertGolden(t testing.TB, store *ddbstore.Store, path string, opts ...Option) {
+	t.Helper()
+	got, err := Dump(context.Background(), store, opts...)
+	if err != nil {
+		t.Fatalf("ddbtest: %v", err)
+	}
+	if updateGolden() {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("ddbtest: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatalf("ddbtest: %v", err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("ddbtest: golden file %s does not exist, run the test with -ddbtest.update to write it", path)
+	}
+	if err != nil {
+		t.Fatalf("ddbtest: %v", err)
+	}
+	if got == string(want) {
+		return
+	}
+	diff, _ := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(want)),
+		B:        difflib.SplitLines(got),
+		FromFile: path,
+		ToFile:   "store",
+		Context:  3,
+	})
+	t.Errorf("store contents differ from %s, run the test with -ddbtest.update to accept them:\n%s", path, diff)
+}
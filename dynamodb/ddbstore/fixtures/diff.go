@@ -0,0 +1,207 @@
+package fixtures
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/acksell/bezos/dynamodb/ddbiface"
+	"github.com/acksell/bezos/dynamodb/ddbstore/ddbtest"
+	"github.com/acksell/bezos/dynamodb/table"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// AssertTables fails t with the differences between the tables of want and their
+// contents in db, see [Diff].
+func AssertTables(t testing.TB, db ddbiface.ReadWriteClient, want *Set) {
+	t.Helper()
+	diff, err := Diff(context.Background(), db, want)
+	if err != nil {
+		t.Fatalf("fixtures: %v", err)
+	}
+	if diff != "" {
+		t.Errorf("table contents differ from the fixtures:\n%s", diff)
+	}
+}
+
+// Diff compares the tables of the fixtures in want with their contents in db. It returns
+// the differences, one per line, or "" if the tables match.
+//
+// Items are matched by primary key. Only the key attributes, the entity type attribute
+// and the fields set in the fixture file are compared, and fields set to {{any}} only
+// need to exist. Items of the tables that are not in want are reported as unexpected.
+func Diff(ctx context.Context, db ddbiface.ReadWriteClient, want *Set) (string, error) {
+	byTable := make(map[string][]*Fixture)
+	tables := make(map[string]table.TableDefinition)
+	for _, f := range want.fixtures {
+		byTable[f.Table.Name] = append(byTable[f.Table.Name], f)
+		tables[f.Table.Name] = f.Table
+	}
+	names := make([]string, 0, len(tables))
+	for name := range tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		items, err := scanTable(ctx, db, name)
+		if err != nil {
+			return "", err
+		}
+		t := tables[name]
+		got := make(map[string]map[string]types.AttributeValue, len(items))
+		for _, item := range items {
+			got[itemKey(t, item)] = item
+		}
+		for _, f := range byTable[name] {
+			key := itemKey(t, f.Item)
+			item, ok := got[key]
+			if !ok {
+				fmt.Fprintf(&b, "%s: missing %s (%s)\n", name, f.Ref(), key)
+				continue
+			}
+			delete(got, key)
+			diffItem(&b, f, key, item)
+		}
+		unexpected := make([]string, 0, len(got))
+		for key := range got {
+			unexpected = append(unexpected, key)
+		}
+		sort.Strings(unexpected)
+		for _, key := range unexpected {
+			fmt.Fprintf(&b, "%s: unexpected item %s\n", name, key)
+		}
+	}
+	return b.String(), nil
+}
+
+func diffItem(b *strings.Builder, f *Fixture, key string, got map[string]types.AttributeValue) {
+	var lines []string
+	for _, attr := range f.compareAttributes() {
+		g, ok := got[attr]
+		switch {
+		case slices.Contains(f.anyFields, attr):
+			if !ok {
+				lines = append(lines, fmt.Sprintf("    %s: missing, want any value", attr))
+			}
+		case !ok && f.Item[attr] == nil:
+		case !ok:
+			lines = append(lines, fmt.Sprintf("    %s: missing, want %s", attr, ddbtest.FormatValue(f.Item[attr])))
+		case f.Item[attr] == nil:
+			lines = append(lines, fmt.Sprintf("    %s: got %s, want none", attr, ddbtest.FormatValue(g)))
+		case !valuesEqual(g, f.Item[attr]):
+			lines = append(lines, fmt.Sprintf("    %s: got %s, want %s", attr, ddbtest.FormatValue(g), ddbtest.FormatValue(f.Item[attr])))
+		}
+	}
+	if len(lines) == 0 {
+		return
+	}
+	fmt.Fprintf(b, "%s: %s (%s):\n%s\n", f.Table.Name, f.Ref(), key, strings.Join(lines, "\n"))
+}
+
+// compareAttributes are the attributes of the fixture's item that [Diff] compares.
+func (f *Fixture) compareAttributes() []string {
+	attrs := []string{f.Table.KeyDefinitions.PartitionKey.Name}
+	if f.Table.KeyDefinitions.SortKey.Name != "" {
+		attrs = append(attrs, f.Table.KeyDefinitions.SortKey.Name)
+	}
+	if f.Table.EntityTypeKey != "" {
+		attrs = append(attrs, f.Table.EntityTypeKey)
+	}
+	for _, name := range f.Fields {
+		if !slices.Contains(attrs, name) {
+			attrs = append(attrs, name)
+		}
+	}
+	return attrs
+}
+
+func scanTable(ctx context.Context, db ddbiface.ReadWriteClient, name string) ([]map[string]types.AttributeValue, error) {
+	input := &dynamodb.ScanInput{TableName: &name}
+	var items []map[string]types.AttributeValue
+	for {
+		out, err := db.Scan(ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("scanning %s: %w", name, err)
+		}
+		items = append(items, out.Items...)
+		if len(out.LastEvaluatedKey) == 0 {
+			return items, nil
+		}
+		input.ExclusiveStartKey = out.LastEvaluatedKey
+	}
+}
+
+// itemKey formats the primary key of item, e.g. "pk=USER#1 sk=PROFILE".
+func itemKey(t table.TableDefinition, item map[string]types.AttributeValue) string {
+	var parts []string
+	for _, kd := range []table.KeyDef{t.KeyDefinitions.PartitionKey, t.KeyDefinitions.SortKey} {
+		if kd.Name != "" {
+			parts = append(parts, kd.Name+"="+formatKeyValue(item[kd.Name]))
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+func formatKeyValue(av types.AttributeValue) string {
+	switch v := av.(type) {
+	case *types.AttributeValueMemberS:
+		return v.Value
+	case *types.AttributeValueMemberN:
+		return v.Value
+	}
+	return ddbtest.FormatValue(av)
+}
+
+// valuesEqual reports whether a and b are equal, comparing numbers by value and sets
+// regardless of order.
+func valuesEqual(a, b types.AttributeValue) bool {
+	switch av := a.(type) {
+	case *types.AttributeValueMemberN:
+		bv, ok := b.(*types.AttributeValueMemberN)
+		return ok && numbersEqual(av.Value, bv.Value)
+	case *types.AttributeValueMemberL:
+		bv, ok := b.(*types.AttributeValueMemberL)
+		if !ok || len(av.Value) != len(bv.Value) {
+			return false
+		}
+		for i := range av.Value {
+			if !valuesEqual(av.Value[i], bv.Value[i]) {
+				return false
+			}
+		}
+		return true
+	case *types.AttributeValueMemberM:
+		bv, ok := b.(*types.AttributeValueMemberM)
+		if !ok || len(av.Value) != len(bv.Value) {
+			return false
+		}
+		for k, e := range av.Value {
+			if be, ok := bv.Value[k]; !ok || !valuesEqual(e, be) {
+				return false
+			}
+		}
+		return true
+	}
+	// FormatValue sorts the elements of sets.
+	return ddbtest.FormatValue(a) == ddbtest.FormatValue(b)
+}
+
+func numbersEqual(a, b string) bool {
+	if a == b {
+		return true
+	}
+	ra, ok := new(big.Rat).SetString(a)
+	if !ok {
+		return false
+	}
+	rb, ok := new(big.Rat).SetString(b)
+	return ok && ra.Cmp(rb) == 0
+}
@@ -0,0 +1,416 @@
+// Package fixtures seeds tables with entities described in YAML or JSON files, and
+// checks table contents against them.
+//
+// A fixture file maps entity type names to named fixtures, each a map of fields by
+// their dynamodbav names:
+//
+//	User:
+//	  alice:
+//	    id: "{{uuid}}"
+//	    email: alice@example.com
+//	    createdAt: "{{now}}"
+//	Order:
+//	  first:
+//	    tenantID: "{{ref User.alice.id}}"
+//	    orderID: o-1
+//	    expiresAt: "{{now+24h}}"
+//
+// Entity types are resolved through the [indices] registry, by their entity type or
+// Go type name, so the fields are decoded into the registered Go type and the key
+// attributes are computed from the declared key patterns, GSI keys included.
+//
+// String values can hold templates:
+//
+//	{{now}}, {{now+1h}}, {{now-7d}}  the load time, shifted by a duration
+//	{{uuid}}                         a random UUID
+//	{{ref Type.name.field}}          a field of another fixture
+//	{{any}}                          any value, see [Diff]
+//
+// A value that is a single template gets its type, e.g. a time.Time for {{now}}.
+// Templates within a longer string are formatted into it.
+//
+// Load a file or a directory of files and insert the entities with one batch:
+//
+//	set, err := fixtures.Load("testdata/fixtures")
+//	err = set.Insert(ctx, client)
+//	alice, _ := fixtures.Entity[User](set, "alice")
+package fixtures
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/acksell/bezos/dynamodb/ddbsdk"
+	"github.com/acksell/bezos/dynamodb/index/indices"
+	"github.com/acksell/bezos/dynamodb/table"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"gopkg.in/yaml.v3"
+)
+
+// Fixture is an entity of a fixture file.
+type Fixture struct {
+	// Type is the entity type name, Name the name of the fixture within its type.
+	Type string
+	Name string
+	// Table is the table the entity is stored in.
+	Table table.TableDefinition
+	// Entity is a pointer to the decoded entity of the registered Go type.
+	Entity any
+	// Item is the item written for the entity, with its key attributes.
+	Item ddbsdk.Item
+	// Fields are the names of the fields set in the fixture file, sorted.
+	Fields []string
+
+	put *ddbsdk.Put
+	// anyFields are the fields set to {{any}}.
+	anyFields []string
+}
+
+// Ref returns the reference of the fixture, e.g. "User.alice".
+func (f *Fixture) Ref() string {
+	return f.Type + "." + f.Name
+}
+
+// Set is a set of fixtures.
+type Set struct {
+	fixtures []*Fixture
+	byRef    map[string]*Fixture
+}
+
+// Fixtures returns the fixtures sorted by type and name.
+func (s *Set) Fixtures() []*Fixture {
+	return s.fixtures
+}
+
+// Get returns the fixture with the reference ref, e.g. "User.alice".
+func (s *Set) Get(ref string) (*Fixture, bool) {
+	f, ok := s.byRef[ref]
+	return f, ok
+}
+
+// Entity returns the entity of the fixture named name of entity type E.
+func Entity[E any](s *Set, name string) (*E, bool) {
+	for _, f := range s.fixtures {
+		if e, ok := f.Entity.(*E); ok && f.Name == name {
+			return e, true
+		}
+	}
+	return nil, false
+}
+
+// insertRetries bounds the retries of the batch writes of [Set.Insert].
+const insertRetries = 10
+
+// Insert writes the entities to db with a single batch, overwriting items with the same keys.
+func (s *Set) Insert(ctx context.Context, db ddbsdk.Writer) error {
+	batch := db.NewBatch(ddbsdk.WithMaxRetries(insertRetries))
+	for _, f := range s.fixtures {
+		batch.AddAction(f.put)
+	}
+	if err := batch.ExecAll(ctx); err != nil {
+		return fmt.Errorf("inserting fixtures: %w", err)
+	}
+	return nil
+}
+
+// Option configures the loading of fixtures.
+type Option func(*options)
+
+type options struct {
+	now func() time.Time
+}
+
+// WithNow sets the time of {{now}} templates, to make fixtures deterministic.
+func WithNow(now time.Time) Option {
+	return func(o *options) {
+		o.now = func() time.Time { return now }
+	}
+}
+
+// Load loads the fixture file at path, or every .yaml, .yml and .json file in the
+// directory at path.
+func Load(path string, opts ...Option) (*Set, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	files := []string{path}
+	if info.IsDir() {
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+		files = files[:0]
+		for _, e := range entries {
+			switch filepath.Ext(e.Name()) {
+			case ".yaml", ".yml", ".json":
+				if !e.IsDir() {
+					files = append(files, filepath.Join(path, e.Name()))
+				}
+			}
+		}
+	}
+	l := newLoader(opts)
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		if err := l.add(data); err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+	}
+	return l.build()
+}
+
+// Parse parses fixtures in YAML or JSON.
+func Parse(data []byte, opts ...Option) (*Set, error) {
+	l := newLoader(opts)
+	if err := l.add(data); err != nil {
+		return nil, err
+	}
+	return l.build()
+}
+
+// rawFixture is a fixture as written in its file.
+type rawFixture struct {
+	entry  indices.Entry
+	typ    string
+	name   string
+	fields map[string]any
+}
+
+func (r *rawFixture) ref() string {
+	return r.typ + "." + r.name
+}
+
+type loader struct {
+	now time.Time
+	raw map[string]*rawFixture
+	// evaluated holds the fields with their templates evaluated, by fixture reference.
+	evaluated map[string]map[string]any
+	// evaluating holds the fixtures being evaluated, to detect reference cycles.
+	evaluating map[string]bool
+}
+
+func newLoader(opts []Option) *loader {
+	o := options{now: time.Now}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &loader{
+		now:        o.now().UTC(),
+		raw:        make(map[string]*rawFixture),
+		evaluated:  make(map[string]map[string]any),
+		evaluating: make(map[string]bool),
+	}
+}
+
+// add parses a fixture file.
+func (l *loader) add(data []byte) error {
+	var doc map[string]map[string]map[string]any
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	for typeName, fixtures := range doc {
+		entry, err := lookupType(typeName)
+		if err != nil {
+			return err
+		}
+		for name, fields := range fixtures {
+			r := &rawFixture{entry: entry, typ: entry.EntityTypeName(), name: name, fields: fields}
+			if _, dup := l.raw[r.ref()]; dup {
+				return fmt.Errorf("fixture %s is defined twice", r.ref())
+			}
+			if r.fields == nil {
+				r.fields = map[string]any{}
+			}
+			l.raw[r.ref()] = r
+		}
+	}
+	return nil
+}
+
+// lookupType returns the registered entity type with the entity type or Go type name.
+func lookupType(name string) (indices.Entry, error) {
+	var known []string
+	for _, e := range indices.All() {
+		if e.EntityTypeName() == name || e.EntityType.Name() == name {
+			return e, nil
+		}
+		known = append(known, e.EntityTypeName())
+	}
+	sort.Strings(known)
+	return indices.Entry{}, fmt.Errorf("unknown entity type %q, registered types are %s", name, strings.Join(known, ", "))
+}
+
+// entityIndex is implemented by *index.PrimaryIndex[E] for any E.
+type entityIndex interface {
+	TableDefinition() table.TableDefinition
+	KeysOf(e any) (table.PrimaryKey, []table.PrimaryKey, error)
+}
+
+func (l *loader) build() (*Set, error) {
+	refs := make([]string, 0, len(l.raw))
+	for ref := range l.raw {
+		refs = append(refs, ref)
+	}
+	sort.Strings(refs)
+
+	s := &Set{byRef: make(map[string]*Fixture, len(refs))}
+	for _, ref := range refs {
+		f, err := l.fixture(l.raw[ref])
+		if err != nil {
+			return nil, fmt.Errorf("fixture %s: %w", ref, err)
+		}
+		s.fixtures = append(s.fixtures, f)
+		s.byRef[ref] = f
+	}
+	return s, nil
+}
+
+// fixture decodes the entity of r and computes its item.
+func (l *loader) fixture(r *rawFixture) (*Fixture, error) {
+	fields, err := l.fields(r.ref())
+	if err != nil {
+		return nil, err
+	}
+	f := &Fixture{Type: r.typ, Name: r.name}
+	known := fieldNames(r.entry.EntityType)
+	values := make(map[string]any, len(fields))
+	for name, v := range fields {
+		if !known[name] {
+			return nil, fmt.Errorf("%s has no field %q", r.entry.EntityType, name)
+		}
+		f.Fields = append(f.Fields, name)
+		if _, ok := v.(anyValue); ok {
+			f.anyFields = append(f.anyFields, name)
+			continue
+		}
+		values[name] = v
+	}
+	sort.Strings(f.Fields)
+
+	av, err := attributevalue.MarshalMap(values)
+	if err != nil {
+		return nil, err
+	}
+	e := reflect.New(r.entry.EntityType)
+	if err := attributevalue.UnmarshalMap(av, e.Interface()); err != nil {
+		return nil, fmt.Errorf("decoding %s: %w", r.entry.EntityType, err)
+	}
+	f.Entity = e.Interface()
+
+	idx, ok := r.entry.Index.(entityIndex)
+	if !ok {
+		return nil, fmt.Errorf("index of %s can't compute keys", r.entry.EntityType)
+	}
+	entity, ok := f.Entity.(ddbsdk.DynamoEntity)
+	if !ok {
+		return nil, fmt.Errorf("*%s does not implement ddbsdk.DynamoEntity", r.entry.EntityType)
+	}
+	pk, gsiKeys, err := idx.KeysOf(f.Entity)
+	if err != nil {
+		return nil, err
+	}
+	f.Table = idx.TableDefinition()
+	f.put = ddbsdk.NewUnsafePut(f.Table, pk, entity).WithGSIKeys(gsiKeys...)
+	if _, f.Item, err = f.put.Build(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// fieldNames returns the dynamodbav names of the fields of struct type t.
+func fieldNames(t reflect.Type) map[string]bool {
+	names := make(map[string]bool)
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag, _, _ := strings.Cut(sf.Tag.Get("dynamodbav"), ",")
+		ft := sf.Type
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		switch {
+		case tag == "-" || !sf.IsExported():
+		case sf.Anonymous && tag == "" && ft.Kind() == reflect.Struct:
+			for name := range fieldNames(ft) {
+				names[name] = true
+			}
+		case tag != "":
+			names[tag] = true
+		default:
+			names[sf.Name] = true
+		}
+	}
+	return names
+}
+
+// fields returns the fields of the fixture with their templates evaluated.
+func (l *loader) fields(ref string) (map[string]any, error) {
+	if fields, ok := l.evaluated[ref]; ok {
+		return fields, nil
+	}
+	r, ok := l.raw[ref]
+	if !ok {
+		return nil, fmt.Errorf("no fixture %s", ref)
+	}
+	if l.evaluating[ref] {
+		return nil, fmt.Errorf("reference cycle through %s", ref)
+	}
+	l.evaluating[ref] = true
+	defer delete(l.evaluating, ref)
+
+	fields := make(map[string]any, len(r.fields))
+	names := make([]string, 0, len(r.fields))
+	for name := range r.fields {
+		names = append(names, name)
+	}
+	slices.Sort(names) // evaluate in a fixed order, so {{uuid}}s don't depend on map order
+	for _, name := range names {
+		v, err := l.eval(r.fields[name])
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", name, err)
+		}
+		fields[name] = v
+	}
+	l.evaluated[ref] = fields
+	return fields, nil
+}
+
+// eval evaluates the templates in v and its elements.
+func (l *loader) eval(v any) (any, error) {
+	switch v := v.(type) {
+	case string:
+		return l.evalString(v)
+	case map[string]any:
+		m := make(map[string]any, len(v))
+		for k, e := range v {
+			ev, err := l.eval(e)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", k, err)
+			}
+			m[k] = ev
+		}
+		return m, nil
+	case []any:
+		list := make([]any, len(v))
+		for i, e := range v {
+			ev, err := l.eval(e)
+			if err != nil {
+				return nil, fmt.Errorf("[%d]: %w", i, err)
+			}
+			list[i] = ev
+		}
+		return list, nil
+	}
+	return v, nil
+}
@@ -0,0 +1,201 @@
+package fixtures_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/acksell/bezos/dynamodb/ddbsdk"
+	"github.com/acksell/bezos/dynamodb/ddbstore"
+	"github.com/acksell/bezos/dynamodb/ddbstore/fixtures"
+	"github.com/acksell/bezos/dynamodb/index"
+	"github.com/acksell/bezos/dynamodb/index/indices"
+	"github.com/acksell/bezos/dynamodb/index/val"
+	"github.com/acksell/bezos/dynamodb/table"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/require"
+)
+
+type user struct {
+	ID        string    `dynamodbav:"id"`
+	Email     string    `dynamodbav:"email"`
+	CreatedAt time.Time `dynamodbav:"createdAt"`
+}
+
+func (u *user) IsValid() error { return nil }
+
+type order struct {
+	UserID    string    `dynamodbav:"userID"`
+	OrderID   int       `dynamodbav:"orderID"`
+	Status    string    `dynamodbav:"status"`
+	Ref       string    `dynamodbav:"ref"`
+	ExpiresAt time.Time `dynamodbav:"expiresAt"`
+}
+
+func (o *order) IsValid() error { return nil }
+
+var fixtureTable = table.TableDefinition{
+	Name: "app",
+	KeyDefinitions: table.PrimaryKeyDefinition{
+		PartitionKey: table.KeyDef{Name: "pk", Kind: table.KeyKindS},
+		SortKey:      table.KeyDef{Name: "sk", Kind: table.KeyKindS},
+	},
+	EntityTypeKey: "type",
+	GSIs: []table.GSIDefinition{{
+		Name: "ByEmail",
+		KeyDefinitions: table.PrimaryKeyDefinition{
+			PartitionKey: table.KeyDef{Name: "gsi1pk", Kind: table.KeyKindS},
+		},
+	}},
+}
+
+func init() {
+	indices.Add(index.PrimaryIndex[user]{
+		Table:        fixtureTable,
+		PartitionKey: val.Fmt("USER#{id}"),
+		SortKey:      val.Fmt("PROFILE").Ptr(),
+		Secondary: []index.SecondaryIndex{{
+			GSI:       fixtureTable.GSIs[0],
+			Partition: val.Fmt("EMAIL#{email}"),
+		}},
+		EntityType: "User",
+	})
+	indices.Add(index.PrimaryIndex[order]{
+		Table:        fixtureTable,
+		PartitionKey: val.Fmt("USER#{userID}"),
+		SortKey:      val.Fmt("ORDER#{orderID:%05d}").Ptr(),
+		EntityType:   "Order",
+	})
+}
+
+const seed = `
+User:
+  alice:
+    id: "{{uuid}}"
+    email: alice@example.com
+    createdAt: "{{now}}"
+Order:
+  first:
+    userID: "{{ref User.alice.id}}"
+    orderID: 1
+    status: open
+    ref: "order-{{ref User.alice.email}}"
+    expiresAt: "{{now+1d}}"
+`
+
+func newStore(t *testing.T) *ddbstore.Store {
+	t.Helper()
+	store, err := ddbstore.New(ddbstore.StoreOptions{InMemory: true}, fixtureTable)
+	require.NoError(t, err)
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestParse(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	set, err := fixtures.Parse([]byte(seed), fixtures.WithNow(now))
+	require.NoError(t, err)
+	require.Len(t, set.Fixtures(), 2)
+
+	alice, ok := fixtures.Entity[user](set, "alice")
+	require.True(t, ok)
+	require.Len(t, alice.ID, 36)
+	require.Equal(t, now, alice.CreatedAt)
+
+	first, ok := set.Get("Order.first")
+	require.True(t, ok)
+	o := first.Entity.(*order)
+	require.Equal(t, alice.ID, o.UserID)
+	require.Equal(t, "order-alice@example.com", o.Ref)
+	require.Equal(t, now.Add(24*time.Hour), o.ExpiresAt)
+	require.Equal(t, []string{"expiresAt", "orderID", "ref", "status", "userID"}, first.Fields)
+	require.Equal(t, &types.AttributeValueMemberS{Value: "ORDER#00001"}, first.Item["sk"])
+	require.Equal(t, &types.AttributeValueMemberS{Value: "Order"}, first.Item["type"])
+
+	u, _ := set.Get("User.alice")
+	require.Equal(t, &types.AttributeValueMemberS{Value: "EMAIL#alice@example.com"}, u.Item["gsi1pk"])
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := map[string]string{
+		"unknown type":     "Nobody: {x: {id: a}}",
+		"unknown field":    "User: {alice: {nmae: a}}",
+		"unknown template": "User: {alice: {id: '{{today}}'}}",
+		"missing fixture":  "Order: {o: {userID: '{{ref User.bob.id}}'}}",
+		"reference cycle":  "User: {a: {id: '{{ref User.b.id}}'}, b: {id: '{{ref User.a.id}}'}}",
+		"bad offset":       "User: {alice: {createdAt: '{{now+1y}}'}}",
+	}
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := fixtures.Parse([]byte(data))
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestInsertAndDiff(t *testing.T) {
+	ctx := context.Background()
+	store := newStore(t)
+	set, err := fixtures.Parse([]byte(seed))
+	require.NoError(t, err)
+	require.NoError(t, set.Insert(ctx, ddbsdk.NewClient(store)))
+
+	alice, _ := fixtures.Entity[user](set, "alice")
+	want, err := fixtures.Parse([]byte(`
+User:
+  alice:
+    id: `+alice.ID+`
+    email: alice@example.com
+    createdAt: "{{any}}"
+Order:
+  first:
+    userID: `+alice.ID+`
+    orderID: 1
+    status: open
+`), fixtures.WithNow(time.Now()))
+	require.NoError(t, err)
+	fixtures.AssertTables(t, store, want)
+
+	// Change the order, and add an item the fixtures don't expect.
+	_, err = store.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:                 &fixtureTable.Name,
+		Key:                       map[string]types.AttributeValue{"pk": &types.AttributeValueMemberS{Value: "USER#" + alice.ID}, "sk": &types.AttributeValueMemberS{Value: "ORDER#00001"}},
+		UpdateExpression:          ptr("SET #s = :s"),
+		ExpressionAttributeNames:  map[string]string{"#s": "status"},
+		ExpressionAttributeValues: map[string]types.AttributeValue{":s": &types.AttributeValueMemberS{Value: "shipped"}},
+	})
+	require.NoError(t, err)
+	_, err = store.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: &fixtureTable.Name,
+		Item:      map[string]types.AttributeValue{"pk": &types.AttributeValueMemberS{Value: "USER#x"}, "sk": &types.AttributeValueMemberS{Value: "PROFILE"}},
+	})
+	require.NoError(t, err)
+
+	diff, err := fixtures.Diff(ctx, store, want)
+	require.NoError(t, err)
+	require.Equal(t, "app: Order.first (pk=USER#"+alice.ID+" sk=ORDER#00001):\n"+
+		`    status: got "shipped", want "open"`+"\n"+
+		"app: unexpected item pk=USER#x sk=PROFILE\n", diff)
+}
+
+func TestLoad_Directory(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "users.json"), []byte(`{"User": {"bob": {"id": "b", "email": "bob@example.com"}}}`), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "orders.yaml"), []byte("Order:\n  o1:\n    userID: '{{ref User.bob.id}}'\n    orderID: 7\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "README.md"), []byte("not a fixture"), 0o644))
+
+	set, err := fixtures.Load(dir)
+	require.NoError(t, err)
+	require.Len(t, set.Fixtures(), 2)
+	f, ok := set.Get("Order.o1")
+	require.True(t, ok)
+	require.Equal(t, &types.AttributeValueMemberS{Value: "USER#b"}, f.Item["pk"])
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,129 @@
+package fixtures
+
+import (
+	"crypto/rand"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var templateRe = regexp.MustCompile(`\{\{\s*(.*?)\s*\}\}`)
+
+// anyValue is the value of {{any}}, which matches any value in [Diff].
+type anyValue struct{}
+
+// evalString evaluates the templates of s. A string that is a single template
+// evaluates to the template's value, otherwise the values are formatted into s.
+func (l *loader) evalString(s string) (any, error) {
+	matches := templateRe.FindAllStringSubmatchIndex(s, -1)
+	if len(matches) == 0 {
+		return s, nil
+	}
+	if len(matches) == 1 && matches[0][0] == 0 && matches[0][1] == len(s) {
+		return l.evalTemplate(s[matches[0][2]:matches[0][3]])
+	}
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		b.WriteString(s[last:m[0]])
+		v, err := l.evalTemplate(s[m[2]:m[3]])
+		if err != nil {
+			return nil, err
+		}
+		switch v := v.(type) {
+		case anyValue:
+			return nil, fmt.Errorf("{{any}} can't be part of a string")
+		case time.Time:
+			b.WriteString(v.Format(time.RFC3339Nano))
+		default:
+			fmt.Fprint(&b, v)
+		}
+		last = m[1]
+	}
+	b.WriteString(s[last:])
+	return b.String(), nil
+}
+
+func (l *loader) evalTemplate(expr string) (any, error) {
+	switch {
+	case expr == "uuid":
+		return newUUID(), nil
+	case expr == "any":
+		return anyValue{}, nil
+	case strings.HasPrefix(expr, "now"):
+		d, err := parseOffset(strings.TrimSpace(strings.TrimPrefix(expr, "now")))
+		if err != nil {
+			return nil, fmt.Errorf("{{%s}}: %w", expr, err)
+		}
+		return l.now.Add(d), nil
+	case strings.HasPrefix(expr, "ref "):
+		return l.ref(strings.TrimSpace(strings.TrimPrefix(expr, "ref ")))
+	}
+	return nil, fmt.Errorf("unknown template {{%s}}, expected now, uuid, ref or any", expr)
+}
+
+// parseOffset parses the offset of a now template, e.g. "+1h30m" or "-7d".
+func parseOffset(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	if s[0] != '+' && s[0] != '-' {
+		return 0, fmt.Errorf("expected an offset like +1h or -7d")
+	}
+	if days, ok := strings.CutSuffix(s[1:], "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("invalid number of days %q", days)
+		}
+		d := time.Duration(n) * 24 * time.Hour
+		if s[0] == '-' {
+			d = -d
+		}
+		return d, nil
+	}
+	return time.ParseDuration(s)
+}
+
+// ref returns the value of a field of another fixture, e.g. "User.alice.id".
+// Nested fields are separated by dots, e.g. "User.alice.address.city".
+func (l *loader) ref(ref string) (any, error) {
+	parts := strings.Split(ref, ".")
+	if len(parts) < 3 {
+		return nil, fmt.Errorf("invalid reference %q, expected Type.name.field", ref)
+	}
+	entry, err := lookupType(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("reference %q: %w", ref, err)
+	}
+	fields, err := l.fields(entry.EntityTypeName() + "." + parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("reference %q: %w", ref, err)
+	}
+	var v any = fields
+	for _, name := range parts[2:] {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("reference %q: no field %s", ref, name)
+		}
+		if v, ok = m[name]; !ok {
+			return nil, fmt.Errorf("reference %q: no field %s", ref, name)
+		}
+	}
+	if _, ok := v.(anyValue); ok {
+		return nil, fmt.Errorf("reference %q: can't refer to {{any}}", ref)
+	}
+	return v, nil
+}
+
+// newUUID returns a random (version 4) UUID.
+func newUUID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err)
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
@@ -2,6 +2,7 @@ package ddbstore
 
 import (
 	"fmt"
+	"sort"
 	"sync"
 
 	"github.com/acksell/bezos/dynamodb/ddbiface"
@@ -72,6 +73,18 @@ func New(opts StoreOptions, defs ...table.TableDefinition) (*Store, error) {
 	}, nil
 }
 
+// TableDefinitions returns the definitions of the store's tables, sorted by name.
+func (s *Store) TableDefinitions() []table.TableDefinition {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	defs := make([]table.TableDefinition, 0, len(s.tables))
+	for _, t := range s.tables {
+		defs = append(defs, t.definition)
+	}
+	sort.Slice(defs, func(i, j int) bool { return defs[i].Name < defs[j].Name })
+	return defs
+}
+
 // Close closes the BadgerDB database.
 func (s *Store) Close() error {
 	return s.db.Close()
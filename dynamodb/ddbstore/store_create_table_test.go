@@ -211,3 +211,33 @@ func TestStore_CreateTable(t *testing.T) {
 		assert.Contains(t, err.Error(), "not found in AttributeDefinitions")
 	})
 }
+
+func TestStore_TableDefinitions(t *testing.T) {
+	store := newTestStore(t, singleTableDesign, numericSortKeyTable)
+	ctx := context.Background()
+
+	_, err := store.CreateTable(ctx, &dynamodb.CreateTableInput{
+		TableName: aws.String("a-created-table"),
+		KeySchema: []types.KeySchemaElement{
+			{AttributeName: aws.String("id"), KeyType: types.KeyTypeHash},
+		},
+		AttributeDefinitions: []types.AttributeDefinition{
+			{AttributeName: aws.String("id"), AttributeType: types.ScalarAttributeTypeS},
+		},
+	})
+	require.NoError(t, err)
+
+	defs := store.TableDefinitions()
+	var names []string
+	for _, def := range defs {
+		names = append(names, def.Name)
+	}
+	assert.Equal(t, []string{"a-created-table", "numeric-sk-table", "test-table"}, names, "sorted by name")
+
+	assert.Equal(t, "id", defs[0].KeyDefinitions.PartitionKey.Name)
+	assert.Empty(t, defs[0].KeyDefinitions.SortKey.Name)
+	assert.Equal(t, numericSortKeyTable.KeyDefinitions, defs[1].KeyDefinitions)
+	assert.Equal(t, singleTableDesign.KeyDefinitions, defs[2].KeyDefinitions)
+	require.Len(t, defs[2].GSIs, 1)
+	assert.Equal(t, "gsi1", defs[2].GSIs[0].Name)
+}
@@ -89,8 +89,8 @@ func (s *Store) Query(ctx context.Context, params *dynamodb.QueryInput, optFns .
 			}
 		} else {
 			it.Seek(startKey)
-			// Skip the start key if it's an exclusive start
-			if params.ExclusiveStartKey != nil && it.Valid() {
+			// Skip the start key if it's an exclusive start, and still exists
+			if params.ExclusiveStartKey != nil && it.Valid() && bytes.Equal(it.Item().Key(), startKey) {
 				it.Next()
 			}
 		}
@@ -4,9 +4,11 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"hash/fnv"
 
 	"github.com/acksell/bezos/dynamodb/ddbstore/conditionexpr"
 	"github.com/acksell/bezos/dynamodb/ddbstore/projectionexpr"
+	"github.com/acksell/bezos/dynamodb/table"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/dgraph-io/badger/v4"
@@ -23,6 +25,18 @@ func (s *Store) Scan(ctx context.Context, params *dynamodb.ScanInput, optFns ...
 		return nil, err
 	}
 
+	if (params.Segment == nil) != (params.TotalSegments == nil) {
+		return nil, fmt.Errorf("Segment and TotalSegments must be set together")
+	}
+	if params.TotalSegments != nil {
+		if *params.TotalSegments < 1 || *params.TotalSegments > maxTotalSegments {
+			return nil, fmt.Errorf("TotalSegments must be between 1 and %d", maxTotalSegments)
+		}
+		if *params.Segment < 0 || *params.Segment >= *params.TotalSegments {
+			return nil, fmt.Errorf("Segment must be between 0 and TotalSegments-1")
+		}
+	}
+
 	var items []map[string]types.AttributeValue
 	var lastKey map[string]types.AttributeValue
 
@@ -51,8 +65,9 @@ func (s *Store) Scan(ctx context.Context, params *dynamodb.ScanInput, optFns ...
 				return fmt.Errorf("encode start key: %w", err)
 			}
 			it.Seek(startKey)
-			if it.Valid() {
-				it.Next() // Skip the start key (exclusive)
+			// Skip the start key (exclusive). It may have been deleted since the last page.
+			if it.Valid() && bytes.Equal(it.Item().Key(), startKey) {
+				it.Next()
 			}
 		} else {
 			it.Seek(prefix)
@@ -72,6 +87,17 @@ func (s *Store) Scan(ctx context.Context, params *dynamodb.ScanInput, optFns ...
 				return err
 			}
 
+			if params.TotalSegments != nil {
+				segment, err := scanSegment(badgerEncoder.keyDefs, item, *params.TotalSegments)
+				if err != nil {
+					return err
+				}
+				if segment != *params.Segment {
+					it.Next()
+					continue
+				}
+			}
+
 			// Apply filter expression if present
 			if params.FilterExpression != nil {
 				input := conditionexpr.EvalInput{
@@ -119,3 +145,22 @@ func (s *Store) Scan(ctx context.Context, params *dynamodb.ScanInput, optFns ...
 		LastEvaluatedKey: lastKey,
 	}, nil
 }
+
+// maxTotalSegments is the DynamoDB limit of segments in a parallel scan.
+const maxTotalSegments = 1000000
+
+// scanSegment returns the segment of a parallel scan that item belongs to.
+// Like in DynamoDB, all items of a partition are in the same segment.
+func scanSegment(keyDefs table.PrimaryKeyDefinition, item map[string]types.AttributeValue, totalSegments int32) (int32, error) {
+	pk, err := keyDefs.ExtractPrimaryKey(item)
+	if err != nil {
+		return 0, fmt.Errorf("extract key: %w", err)
+	}
+	b, err := encodeKeyValue(pk.Values.PartitionKey, keyDefs.PartitionKey.Kind)
+	if err != nil {
+		return 0, fmt.Errorf("encode partition key: %w", err)
+	}
+	h := fnv.New32a()
+	h.Write(b)
+	return int32(h.Sum32() % uint32(totalSegments)), nil
+}
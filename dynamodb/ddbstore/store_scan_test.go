@@ -61,6 +61,51 @@ func TestStore_Scan(t *testing.T) {
 		assert.Len(t, result.Items, 3) // 0, 2, 4
 	})
 
+	t.Run("scan with filter on list and map values", func(t *testing.T) {
+		store := newTestStore(t, singleTableDesign)
+		ctx := context.Background()
+
+		tags := [][]string{{"a"}, {"a", "b"}, {"b", "a"}}
+		for i, tt := range tags {
+			list := make([]types.AttributeValue, 0, len(tt))
+			for _, tag := range tt {
+				list = append(list, &types.AttributeValueMemberS{Value: tag})
+			}
+			_, err := store.PutItem(ctx, &dynamodb.PutItemInput{
+				TableName: &singleTableDesign.Name,
+				Item: map[string]types.AttributeValue{
+					"pk":   &types.AttributeValueMemberS{Value: fmt.Sprintf("pk#%d", i)},
+					"sk":   &types.AttributeValueMemberS{Value: "sk"},
+					"tags": &types.AttributeValueMemberL{Value: list},
+					"meta": &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+						"n": &types.AttributeValueMemberN{Value: fmt.Sprint(i)},
+					}},
+				},
+			})
+			require.NoError(t, err)
+		}
+
+		result, err := store.Scan(ctx, &dynamodb.ScanInput{
+			TableName:        &singleTableDesign.Name,
+			FilterExpression: ptrStr("tags IN (:list) OR meta = :meta"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":list": &types.AttributeValueMemberL{Value: []types.AttributeValue{
+					&types.AttributeValueMemberS{Value: "a"},
+					&types.AttributeValueMemberS{Value: "b"},
+				}},
+				":meta": &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+					"n": &types.AttributeValueMemberN{Value: "0"},
+				}},
+			},
+		})
+		require.NoError(t, err)
+		var pks []string
+		for _, item := range result.Items {
+			pks = append(pks, item["pk"].(*types.AttributeValueMemberS).Value)
+		}
+		assert.ElementsMatch(t, []string{"pk#0", "pk#1"}, pks)
+	})
+
 	t.Run("scan with limit and pagination", func(t *testing.T) {
 		store := newTestStore(t, singleTableDesign)
 		ctx := context.Background()
@@ -104,6 +149,95 @@ func TestStore_Scan(t *testing.T) {
 	})
 }
 
+func TestStore_Scan_DeletedStartKey(t *testing.T) {
+	store := newTestStore(t, singleTableDesign)
+	ctx := context.Background()
+
+	for i := 0; i < 6; i++ {
+		_, err := store.PutItem(ctx, &dynamodb.PutItemInput{
+			TableName: &singleTableDesign.Name,
+			Item: map[string]types.AttributeValue{
+				"pk": &types.AttributeValueMemberS{Value: "pk"},
+				"sk": &types.AttributeValueMemberS{Value: fmt.Sprintf("sk#%d", i)},
+			},
+		})
+		require.NoError(t, err)
+	}
+
+	limit := int32(3)
+	page1, err := store.Scan(ctx, &dynamodb.ScanInput{TableName: &singleTableDesign.Name, Limit: &limit})
+	require.NoError(t, err)
+
+	// Deleting the last evaluated item must not skip the item after it.
+	_, err = store.DeleteItem(ctx, &dynamodb.DeleteItemInput{TableName: &singleTableDesign.Name, Key: page1.LastEvaluatedKey})
+	require.NoError(t, err)
+
+	page2, err := store.Scan(ctx, &dynamodb.ScanInput{
+		TableName:         &singleTableDesign.Name,
+		Limit:             &limit,
+		ExclusiveStartKey: page1.LastEvaluatedKey,
+	})
+	require.NoError(t, err)
+	require.Len(t, page2.Items, 3)
+	assert.Equal(t, &types.AttributeValueMemberS{Value: "sk#3"}, page2.Items[0]["sk"])
+}
+func TestStore_Scan_ParallelSegments(t *testing.T) {
+	store := newTestStore(t, singleTableDesign)
+	ctx := context.Background()
+
+	for i := 0; i < 20; i++ {
+		for _, sk := range []string{"a", "b"} {
+			_, err := store.PutItem(ctx, &dynamodb.PutItemInput{
+				TableName: &singleTableDesign.Name,
+				Item: map[string]types.AttributeValue{
+					"pk": &types.AttributeValueMemberS{Value: fmt.Sprintf("pk#%d", i)},
+					"sk": &types.AttributeValueMemberS{Value: sk},
+				},
+			})
+			require.NoError(t, err)
+		}
+	}
+
+	total := int32(3)
+	segmentOf := make(map[string]int32)
+	count := 0
+	for segment := int32(0); segment < total; segment++ {
+		result, err := store.Scan(ctx, &dynamodb.ScanInput{
+			TableName:     &singleTableDesign.Name,
+			Segment:       &segment,
+			TotalSegments: &total,
+		})
+		require.NoError(t, err)
+		for _, item := range result.Items {
+			pk := item["pk"].(*types.AttributeValueMemberS).Value
+			if seg, ok := segmentOf[pk]; ok {
+				assert.Equal(t, seg, segment, "partition %s split across segments", pk)
+			}
+			segmentOf[pk] = segment
+			count++
+		}
+	}
+	assert.Equal(t, 40, count, "segments should cover every item exactly once")
+
+	t.Run("segment out of range", func(t *testing.T) {
+		segment := total
+		_, err := store.Scan(ctx, &dynamodb.ScanInput{
+			TableName:     &singleTableDesign.Name,
+			Segment:       &segment,
+			TotalSegments: &total,
+		})
+		assert.Error(t, err)
+	})
+
+	t.Run("segment without total", func(t *testing.T) {
+		segment := int32(0)
+		_, err := store.Scan(ctx, &dynamodb.ScanInput{
+			TableName: &singleTableDesign.Name,
+			Segment:   &segment,
+		})
+		assert.Error(t, err)
+	})
+}
 func TestStore_Scan_ProjectionExpression(t *testing.T) {
 	store := newTestStore(t, singleTableDesign)
 	ctx := context.Background()
@@ -714,13 +714,24 @@ func decodeLastKey(encoded string) (map[string]types.AttributeValue, error) {
 	return convertJSONToItem(data), nil
 }
 
-// DetectEntityType attempts to match an item's key patterns to known entities.
+// DetectEntityType returns the entity type of an item. It reads the table's entity type
+// attribute if it has one, and otherwise matches the item's key patterns to known entities.
 func (h *APIHandler) DetectEntityType(tableName string, item map[string]any) string {
 	t, ok := h.schema.Tables[tableName]
 	if !ok {
 		return ""
 	}
 
+	if t.EntityTypeKey != "" {
+		if v, ok := item[t.EntityTypeKey].(string); ok {
+			for _, entity := range t.EnrichedEntities {
+				if entity.EntityTypeValue == v || (entity.EntityTypeValue == "" && entity.Type == v) {
+					return entity.Type
+				}
+			}
+		}
+	}
+
 	pkName := t.PartitionKey.Name
 	skName := ""
 	if t.SortKey != nil {
@@ -216,7 +216,8 @@ func enrichEntity(entity schema.Entity) EnrichedEntity {
 // toTableDefinition converts a schema.Table to a runtime TableDefinition.
 func toTableDefinition(t *schema.Table) table.TableDefinition {
 	def := table.TableDefinition{
-		Name: t.Name,
+		Name:          t.Name,
+		EntityTypeKey: t.EntityTypeKey,
 		KeyDefinitions: table.PrimaryKeyDefinition{
 			PartitionKey: table.KeyDef{
 				Name: t.PartitionKey.Name,
@@ -190,6 +190,117 @@
         return null;
     }
 
+    // Field validation, mirroring the IsValidGenerated methods that ddbgen generates
+    // from validate struct tags. Missing attributes of non-pointer fields count as
+    // the Go zero value, like when the item is decoded into the entity struct.
+    const ZERO_TIME = Date.parse('0001-01-01T00:00:00Z');
+
+    function fieldKind(type, value) {
+        const t = (type || '').replace(/^\*/, '');
+        if (t === 'string') return 'string';
+        if (t === 'bool') return 'bool';
+        if (t === 'time.Time') return 'time';
+        if (/^(u?int|float)\d*$/.test(t)) return 'number';
+        if (t.startsWith('[') || t.startsWith('map[')) return 'collection';
+        // Named types: go by the JSON value.
+        if (Array.isArray(value) || (value && typeof value === 'object')) return 'collection';
+        return typeof value === 'number' ? 'number' : typeof value === 'boolean' ? 'bool' : 'string';
+    }
+
+    function zeroValue(kind) {
+        return { string: '', number: 0, bool: false, time: '', collection: [] }[kind];
+    }
+
+    function valueLength(value) {
+        if (typeof value === 'string') return [...value].length;
+        if (Array.isArray(value)) return value.length;
+        return value ? Object.keys(value).length : 0;
+    }
+
+    function isZero(value, kind) {
+        switch (kind) {
+            case 'time': return !value || Date.parse(value) <= ZERO_TIME;
+            case 'collection': return valueLength(value) === 0;
+            case 'number': return Number(value) === 0;
+            default: return value === zeroValue(kind);
+        }
+    }
+
+    function compareValues(a, b, kind) {
+        if (kind === 'time') { a = Date.parse(a || 0); b = Date.parse(b || 0); }
+        if (kind === 'number') { a = Number(a); b = Number(b); }
+        return a < b ? -1 : a > b ? 1 : 0;
+    }
+
+    // [check of the comparison result, message, message for times]
+    const crossFieldChecks = {
+        eqfield: [c => c === 0, 'must equal', 'must equal'],
+        nefield: [c => c !== 0, 'must differ from', 'must differ from'],
+        gtfield: [c => c > 0, 'must be greater than', 'must be after'],
+        gtefield: [c => c >= 0, 'must be at least', 'must not be before'],
+        ltfield: [c => c < 0, 'must be less than', 'must be before'],
+        ltefield: [c => c <= 0, 'must be at most', 'must not be after'],
+    };
+
+    // Returns the message of the violated rule, or null.
+    function checkRule(rule, value, kind, item, fieldsByTag) {
+        const arg = rule.arg;
+        switch (rule.name) {
+            case 'required': return isZero(value, kind) ? 'is required' : null;
+            case 'min': return Number(value) < Number(arg) ? `must be at least ${arg}` : null;
+            case 'max': return Number(value) > Number(arg) ? `must be at most ${arg}` : null;
+            case 'len': return valueLength(value) !== Number(arg) ? `must have length ${arg}` : null;
+            case 'minlen': return valueLength(value) < Number(arg) ? `must have length at least ${arg}` : null;
+            case 'maxlen': return valueLength(value) > Number(arg) ? `must have length at most ${arg}` : null;
+            case 'regex':
+                try {
+                    return value !== '' && !new RegExp(arg).test(value) ? `must match ${arg}` : null;
+                } catch {
+                    return null; // Go regexp syntax the browser doesn't support
+                }
+            case 'enum': {
+                if (value === '') return null;
+                const values = arg.split('|');
+                const ok = kind === 'number' ? values.map(Number).includes(Number(value)) : values.includes(value);
+                return ok ? null : `must be one of ${values.join(', ')}`;
+            }
+        }
+        const cross = crossFieldChecks[rule.name];
+        if (!cross) return null;
+        const other = fieldsByTag[arg];
+        const otherValue = item[arg] ?? zeroValue(fieldKind(other?.type, item[arg]));
+        const msg = kind === 'time' ? cross[2] : cross[1];
+        return cross[0](compareValues(value, otherValue, kind)) ? null : `${msg} ${arg}`;
+    }
+
+    // Check an item against the validation rules of its entity. Returns the violations.
+    function validateItem(item, entity) {
+        const fields = entity.fields || [];
+        const fieldsByTag = Object.fromEntries(fields.map(f => [f.tag, f]));
+        const errors = [];
+        for (const field of fields) {
+            if (!field.rules || field.rules.length === 0) continue;
+            let value = item[field.tag];
+            const kind = fieldKind(field.type, value);
+            const isPointer = (field.type || '').startsWith('*');
+            if (value === undefined || value === null) {
+                if (isPointer) {
+                    if (field.rules.some(r => r.name === 'required')) {
+                        errors.push(`${field.tag}: is required`);
+                    }
+                    continue;
+                }
+                value = zeroValue(kind);
+            }
+            for (const rule of field.rules) {
+                if (isPointer && rule.name === 'required') continue; // only requires the attribute
+                const msg = checkRule(rule, value, kind, item, fieldsByTag);
+                if (msg) errors.push(`${field.tag}: ${msg}`);
+            }
+        }
+        return errors;
+    }
+
     // API Client
     const api = {
         async get(path) {
@@ -503,6 +614,7 @@
                         <div class="schema-row" style="margin-left: 1rem;">
                             <span class="schema-label">${m.gsi}:</span>
                             <span class="schema-value">${highlightPatternVars(m.partitionPattern)}${m.sortPattern ? ' / ' + highlightPatternVars(m.sortPattern) : ''}</span>
+                            ${m.condition ? `<span class="schema-value" title="sparse index">(only when ${escapeHtml(m.condition.description)})</span>` : ''}
                         </div>
                         `).join('')}
                     </div>` : ''}
@@ -626,6 +738,12 @@
             html += `</div>`;
         }
         
+        // Sparse GSIs only contain the items matching their condition
+        const selectedMapping = selectedIndex && gsiMappings.find(m => m.gsi === selectedIndex);
+        if (selectedMapping && selectedMapping.condition) {
+            html += `<p class="info-text">Sparse index: only ${entityType} items where <code>${escapeHtml(selectedMapping.condition.description)}</code> are in ${selectedIndex}</p>`;
+        }
+        
         // Collect PK variables using backend-parsed info
         const pkVars = getVariablesFromParsed(pkParsed);
         // Collect SK variables (excluding those already in PK by name)
@@ -1494,6 +1612,16 @@
             return;
         }
 
+        const schema = state.currentSchema;
+        const entityType = detectEntityType(item, schema);
+        const entity = schema?.entities?.find(e => e.type === entityType);
+        if (entity) {
+            const errors = validateItem(item, entity);
+            if (errors.length > 0 && !confirm(`${entityType} is not valid:\n\n${errors.join('\n')}\n\nSave anyway?`)) {
+                return;
+            }
+        }
+
         if (!confirmAWSWrite('save an item')) return;
 
         try {
@@ -0,0 +1,140 @@
+package index
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+
+	"github.com/acksell/bezos/dynamodb/index/val"
+	"github.com/acksell/bezos/dynamodb/table"
+)
+
+// DerivedKey is a key attribute whose value is computed from entity fields,
+// e.g. a GSI partition key "EMAIL#{email}" that depends on the email field.
+type DerivedKey struct {
+	KeyValDef
+	// Index is the GSI the key belongs to, or "" for the table's primary key.
+	Index string
+	// Include is the inclusion rule of a sparse GSI, nil if every entity is included.
+	Include *Inclusion
+}
+
+// IsPrimary reports whether the key is part of the table's primary key.
+// Fields of a primary key can't change without moving the item.
+func (k DerivedKey) IsPrimary() bool {
+	return k.Index == ""
+}
+
+// DependsOn reports whether the key value is derived from the field at path.
+func (k DerivedKey) DependsOn(path string) bool {
+	return slices.Contains(k.ValDef.FieldPaths(), path)
+}
+
+// DerivedKeys returns all key attributes of the table and its GSIs, with the
+// fields their values are derived from.
+func (pi *PrimaryIndex[E]) DerivedKeys() []DerivedKey {
+	keys := []DerivedKey{{
+		KeyValDef: KeyValDef{KeyDef: pi.Table.KeyDefinitions.PartitionKey, ValDef: pi.PartitionKey},
+	}}
+	if pi.SortKey != nil {
+		keys = append(keys, DerivedKey{
+			KeyValDef: KeyValDef{KeyDef: pi.Table.KeyDefinitions.SortKey, ValDef: *pi.SortKey},
+		})
+	}
+	for _, gsi := range pi.Secondary {
+		keys = append(keys, DerivedKey{
+			KeyValDef: KeyValDef{KeyDef: gsi.GSI.KeyDefinitions.PartitionKey, ValDef: gsi.Partition},
+			Index:     gsi.Name(),
+			Include:   gsi.Include,
+		})
+		if gsi.Sort != nil {
+			keys = append(keys, DerivedKey{
+				KeyValDef: KeyValDef{KeyDef: gsi.GSI.KeyDefinitions.SortKey, ValDef: *gsi.Sort},
+				Index:     gsi.Name(),
+				Include:   gsi.Include,
+			})
+		}
+	}
+	return keys
+}
+
+// KeysOf computes the primary key and the GSI keys of entity e from the key patterns,
+// like the generated PrimaryKeyFrom and GSIKeysFrom do. e must be an E or a *E.
+// Sparse GSIs whose inclusion rule excludes e are left out.
+func (pi *PrimaryIndex[E]) KeysOf(e any) (table.PrimaryKey, []table.PrimaryKey, error) {
+	v := reflect.ValueOf(e)
+	for v.Kind() == reflect.Pointer && !v.IsNil() {
+		v = v.Elem()
+	}
+	if want := reflect.TypeOf((*E)(nil)).Elem(); !v.IsValid() || v.Type() != want {
+		return table.PrimaryKey{}, nil, fmt.Errorf("got %T, want a %s", e, want)
+	}
+	pk, err := keyOf(v, pi.Table.KeyDefinitions, pi.PartitionKey, pi.SortKey)
+	if err != nil {
+		return table.PrimaryKey{}, nil, err
+	}
+	var gsiKeys []table.PrimaryKey
+	for _, gsi := range pi.Secondary {
+		if !gsi.Includes(v.Interface()) {
+			continue
+		}
+		k, err := keyOf(v, gsi.KeyDefinition(), gsi.Partition, gsi.Sort)
+		if err != nil {
+			return table.PrimaryKey{}, nil, fmt.Errorf("GSI %s: %w", gsi.Name(), err)
+		}
+		gsiKeys = append(gsiKeys, k)
+	}
+	return pk, gsiKeys, nil
+}
+
+func keyOf(e reflect.Value, def table.PrimaryKeyDefinition, pk val.ValDef, sk *val.ValDef) (table.PrimaryKey, error) {
+	key := table.PrimaryKey{Definition: def}
+	var err error
+	if key.Values.PartitionKey, err = keyValue(e, def.PartitionKey, pk); err != nil {
+		return table.PrimaryKey{}, err
+	}
+	if sk != nil && def.SortKey.Name != "" {
+		if key.Values.SortKey, err = keyValue(e, def.SortKey, *sk); err != nil {
+			return table.PrimaryKey{}, err
+		}
+	}
+	return key, nil
+}
+
+// keyValue computes the value of key attribute kd of entity e, typed to match the attribute's kind.
+func keyValue(e reflect.Value, kd table.KeyDef, vd val.ValDef) (any, error) {
+	values := make(map[string]any)
+	for _, path := range vd.FieldPaths() {
+		f, ok := fieldByTag(e, path)
+		if !ok {
+			return nil, fmt.Errorf("key attribute %q: %s has no field %q", kd.Name, e.Type(), path)
+		}
+		values[path] = f.Interface()
+	}
+	if vd.FromField != "" && kd.Kind == table.KeyKindN {
+		return values[vd.FromField], nil
+	}
+	v, err := vd.Value(values)
+	if err != nil {
+		return nil, fmt.Errorf("key attribute %q: %w", kd.Name, err)
+	}
+	s, ok := v.(string)
+	if !ok {
+		return v, nil
+	}
+	switch kd.Kind {
+	case table.KeyKindN:
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return i, nil
+		}
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, fmt.Errorf("key attribute %q: %q is not a number", kd.Name, s)
+		}
+		return f, nil
+	case table.KeyKindB:
+		return []byte(s), nil
+	}
+	return s, nil
+}
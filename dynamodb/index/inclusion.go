@@ -0,0 +1,178 @@
+package index
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// InclusionKind is the kind of rule of an [Inclusion].
+type InclusionKind string
+
+const (
+	// IncludeWhenPresent includes entities whose field is set to a non-zero value.
+	IncludeWhenPresent InclusionKind = "present"
+	// IncludeWhenEquals includes entities whose field equals a value.
+	IncludeWhenEquals InclusionKind = "equals"
+	// IncludeWhenFunc includes entities for which a Go func returns true.
+	IncludeWhenFunc InclusionKind = "custom"
+)
+
+// Inclusion decides which entities are written to a sparse GSI.
+// Entities that are not included get no GSI key attributes, so DynamoDB leaves them out of the index.
+//
+// Example, only open orders in the ByStatus index:
+//
+//	index.SecondaryIndex{
+//	    GSI:       OrdersTable.GSIs[0],
+//	    Partition: val.Fmt("STATUS#{status}"),
+//	    Include:   index.WhenEquals("status", "open"),
+//	}
+type Inclusion struct {
+	Kind InclusionKind
+	// Field is the attribute (dynamodbav tag) the rule checks, unused for IncludeWhenFunc.
+	Field string
+	// Value is the value the field must equal for IncludeWhenEquals.
+	Value any
+	// Description explains the rule, e.g. for the schema and the UI.
+	Description string
+
+	fn func(e any) bool
+}
+
+// WhenPresent includes entities whose field is set, i.e. not the zero value.
+func WhenPresent(field string) *Inclusion {
+	return &Inclusion{
+		Kind:        IncludeWhenPresent,
+		Field:       field,
+		Description: fmt.Sprintf("%s is set", field),
+	}
+}
+
+// WhenEquals includes entities whose field equals value.
+func WhenEquals(field string, value any) *Inclusion {
+	return &Inclusion{
+		Kind:        IncludeWhenEquals,
+		Field:       field,
+		Value:       value,
+		Description: fmt.Sprintf("%s = %v", field, value),
+	}
+}
+
+// When includes entities for which fn returns true. The description is recorded in the
+// schema, since the rule itself can't be. Partial updates can't evaluate the rule,
+// so keys of a GSI with a When rule can only be written by Puts.
+func When[E any](description string, fn func(e *E) bool) *Inclusion {
+	return &Inclusion{
+		Kind:        IncludeWhenFunc,
+		Description: description,
+		fn: func(e any) bool {
+			switch v := e.(type) {
+			case *E:
+				return v != nil && fn(v)
+			case E:
+				return fn(&v)
+			}
+			return false
+		},
+	}
+}
+
+// String returns the description of the rule.
+func (in *Inclusion) String() string {
+	return in.Description
+}
+
+// Includes reports whether entity e belongs in the index.
+func (in *Inclusion) Includes(e any) bool {
+	if in.Kind == IncludeWhenFunc {
+		return in.fn != nil && in.fn(e)
+	}
+	v, ok := fieldByTag(reflect.ValueOf(e), in.Field)
+	if !ok {
+		return false
+	}
+	return in.IncludesValue(v.Interface())
+}
+
+// IncludesValue reports whether an entity with v as the rule's field value belongs in the index.
+// It panics for IncludeWhenFunc rules, which need the whole entity.
+func (in *Inclusion) IncludesValue(v any) bool {
+	switch in.Kind {
+	case IncludeWhenPresent:
+		rv := reflect.ValueOf(v)
+		return rv.IsValid() && !rv.IsZero()
+	case IncludeWhenEquals:
+		return valuesEqual(v, in.Value)
+	}
+	panic(fmt.Sprintf("inclusion rule %q needs the entity", in.Kind))
+}
+
+// Validate checks that the rule is complete.
+func (in *Inclusion) Validate() error {
+	switch in.Kind {
+	case IncludeWhenPresent, IncludeWhenEquals:
+		if in.Field == "" {
+			return fmt.Errorf("inclusion rule %q requires a field", in.Kind)
+		}
+	case IncludeWhenFunc:
+		if in.fn == nil {
+			return fmt.Errorf("inclusion rule %q requires a func, use index.When", in.Kind)
+		}
+	default:
+		return fmt.Errorf("unknown inclusion rule %q", in.Kind)
+	}
+	return nil
+}
+
+func valuesEqual(v, want any) bool {
+	rv, rw := reflect.ValueOf(v), reflect.ValueOf(want)
+	if !rv.IsValid() || !rw.IsValid() {
+		return !rv.IsValid() && !rw.IsValid()
+	}
+	if rw.Type() != rv.Type() && rw.Type().ConvertibleTo(rv.Type()) && (rw.Kind() == rv.Kind() || isNumber(rw) && isNumber(rv)) {
+		rw = rw.Convert(rv.Type())
+		if !reflect.ValueOf(want).Equal(rw.Convert(reflect.TypeOf(want))) {
+			return false // lossy conversion, e.g. 300 to uint8
+		}
+	}
+	if rv.Type() != rw.Type() || !rv.Comparable() {
+		return false
+	}
+	return rv.Equal(rw)
+}
+
+func isNumber(v reflect.Value) bool {
+	return v.CanInt() || v.CanUint() || v.CanFloat()
+}
+
+// fieldByTag returns the struct field with the given dynamodbav tag (or name, if untagged).
+// Dotted paths descend into nested structs.
+func fieldByTag(v reflect.Value, path string) (reflect.Value, bool) {
+	for _, name := range strings.Split(path, ".") {
+		for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		if v.Kind() != reflect.Struct {
+			return reflect.Value{}, false
+		}
+		found := false
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			tag, _, _ := strings.Cut(f.Tag.Get("dynamodbav"), ",")
+			if tag == name || (tag == "" && f.Name == name) {
+				v = v.Field(i)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return reflect.Value{}, false
+		}
+	}
+	return v, true
+}
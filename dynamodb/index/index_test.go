@@ -5,6 +5,7 @@ import (
 
 	"github.com/acksell/bezos/dynamodb/index/val"
 	"github.com/acksell/bezos/dynamodb/table"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 )
 
 type TestEntity struct {
@@ -115,6 +116,20 @@ func TestSecondaryIndex_Validate(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "invalid inclusion rule",
+			gsi: SecondaryIndex{
+				GSI: table.GSIDefinition{
+					Name: "ByEmail",
+					KeyDefinitions: table.PrimaryKeyDefinition{
+						PartitionKey: table.KeyDef{Name: "gsi1pk", Kind: table.KeyKindS},
+					},
+				},
+				Partition: val.Fmt("EMAIL#{email}"),
+				Include:   WhenPresent(""),
+			},
+			wantErr: true,
+		},
 		{
 			name:    "missing name",
 			gsi:     SecondaryIndex{},
@@ -166,3 +181,142 @@ func TestSecondaryIndex_KeyDefinition(t *testing.T) {
 		t.Errorf("SortKey.Kind = %q, want %q", keyDef.SortKey.Kind, table.KeyKindN)
 	}
 }
+
+func TestPrimaryIndex_ParseKeys(t *testing.T) {
+	idx := PrimaryIndex[TestEntity]{
+		Table: table.TableDefinition{
+			Name: "TestTable",
+			KeyDefinitions: table.PrimaryKeyDefinition{
+				PartitionKey: table.KeyDef{Name: "pk", Kind: table.KeyKindS},
+				SortKey:      table.KeyDef{Name: "sk", Kind: table.KeyKindS},
+			},
+		},
+		PartitionKey: val.Fmt("USER#{id}"),
+		SortKey:      val.Fmt("PROFILE").Ptr(),
+		Secondary: []SecondaryIndex{{
+			GSI: table.GSIDefinition{
+				Name: "ByEmail",
+				KeyDefinitions: table.PrimaryKeyDefinition{
+					PartitionKey: table.KeyDef{Name: "gsi1pk", Kind: table.KeyKindS},
+					SortKey:      table.KeyDef{Name: "gsi1sk", Kind: table.KeyKindS},
+				},
+			},
+			Partition: val.Fmt("EMAIL#{email}"),
+			Sort:      val.Fmt("USER#{id}").Ptr(),
+		}},
+	}
+	item := map[string]types.AttributeValue{
+		"pk":     &types.AttributeValueMemberS{Value: "USER#123"},
+		"sk":     &types.AttributeValueMemberS{Value: "PROFILE"},
+		"gsi1pk": &types.AttributeValueMemberS{Value: "EMAIL#a@example.com"},
+		"gsi1sk": &types.AttributeValueMemberS{Value: "USER#123"},
+	}
+
+	k, err := idx.ParseKeys(item)
+	if err != nil {
+		t.Fatalf("ParseKeys() error = %v", err)
+	}
+	if id, _ := k.String("id"); id != "123" {
+		t.Errorf("id = %q, want %q", id, "123")
+	}
+
+	k, err = idx.Secondary[0].ParseKeys(item)
+	if err != nil {
+		t.Fatalf("SecondaryIndex.ParseKeys() error = %v", err)
+	}
+	if email, _ := k.String("email"); email != "a@example.com" {
+		t.Errorf("email = %q, want %q", email, "a@example.com")
+	}
+
+	item["sk"] = &types.AttributeValueMemberS{Value: "OTHER"}
+	if _, err := idx.ParseKeys(item); err == nil {
+		t.Error("expected error for mismatching sort key")
+	}
+}
+
+func TestPrimaryIndex_KeysOf(t *testing.T) {
+	idx := PrimaryIndex[TestEntity]{
+		Table: table.TableDefinition{
+			Name: "TestTable",
+			KeyDefinitions: table.PrimaryKeyDefinition{
+				PartitionKey: table.KeyDef{Name: "pk", Kind: table.KeyKindS},
+				SortKey:      table.KeyDef{Name: "sk", Kind: table.KeyKindS},
+			},
+		},
+		PartitionKey: val.Fmt("USER#{id}"),
+		SortKey:      val.Fmt("PROFILE").Ptr(),
+		Secondary: []SecondaryIndex{{
+			GSI: table.GSIDefinition{
+				Name: "ByEmail",
+				KeyDefinitions: table.PrimaryKeyDefinition{
+					PartitionKey: table.KeyDef{Name: "gsi1pk", Kind: table.KeyKindS},
+				},
+			},
+			Partition: val.FromField("email"),
+			Include:   WhenPresent("email"),
+		}},
+	}
+
+	pk, gsiKeys, err := idx.KeysOf(&TestEntity{ID: "123", Email: "a@example.com"})
+	if err != nil {
+		t.Fatalf("KeysOf() error = %v", err)
+	}
+	if pk.Values.PartitionKey != "USER#123" || pk.Values.SortKey != "PROFILE" {
+		t.Errorf("primary key = %+v, want USER#123/PROFILE", pk.Values)
+	}
+	if len(gsiKeys) != 1 || gsiKeys[0].Values.PartitionKey != "a@example.com" {
+		t.Errorf("GSI keys = %+v, want one with a@example.com", gsiKeys)
+	}
+
+	if _, gsiKeys, _ := idx.KeysOf(TestEntity{ID: "123"}); len(gsiKeys) != 0 {
+		t.Errorf("GSI keys = %+v, want none for an entity without email", gsiKeys)
+	}
+	if _, _, err := idx.KeysOf(&struct{ ID string }{}); err == nil {
+		t.Error("expected error for a different entity type")
+	}
+}
+
+func TestInclusion_Includes(t *testing.T) {
+	type profile struct {
+		Status string `dynamodbav:"status"`
+	}
+	type entity struct {
+		ID      string  `dynamodbav:"id"`
+		Email   string  `dynamodbav:"email"`
+		Score   int64   `dynamodbav:"score"`
+		Profile profile `dynamodbav:"profile"`
+	}
+	e := &entity{ID: "1", Score: 5, Profile: profile{Status: "open"}}
+
+	tests := []struct {
+		name string
+		rule *Inclusion
+		want bool
+	}{
+		{"present, zero value", WhenPresent("email"), false},
+		{"present, set", WhenPresent("id"), true},
+		{"present, unknown field", WhenPresent("nope"), false},
+		{"equals nested", WhenEquals("profile.status", "open"), true},
+		{"equals mismatch", WhenEquals("profile.status", "closed"), false},
+		{"equals converts numbers", WhenEquals("score", 5), true},
+		{"custom", When("score above 3", func(e *entity) bool { return e.Score > 3 }), true},
+		{"custom, other entity type", When("always", func(e *TestEntity) bool { return true }), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.rule.Validate(); err != nil {
+				t.Fatalf("Validate() error = %v", err)
+			}
+			if got := tt.rule.Includes(e); got != tt.want {
+				t.Errorf("Includes() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if got := WhenEquals("status", "open").String(); got != "status = open" {
+		t.Errorf("String() = %q, want %q", got, "status = open")
+	}
+	if err := (&Inclusion{Kind: IncludeWhenFunc}).Validate(); err == nil {
+		t.Error("expected error for custom rule without func")
+	}
+}
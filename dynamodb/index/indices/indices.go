@@ -77,6 +77,58 @@ func Get[E any]() *index.PrimaryIndex[E] {
 	return entry.Index.(*index.PrimaryIndex[E])
 }
 
+// Lookup returns the entry registered for the entity type t, if any.
+// Pointer types are resolved to their element type.
+func Lookup(t reflect.Type) (Entry, bool) {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	mu.RLock()
+	defer mu.RUnlock()
+	entry, ok := registry[t]
+	return entry, ok
+}
+
+// entityTypeNamer is implemented by *index.PrimaryIndex[E] for any E.
+type entityTypeNamer interface {
+	EntityTypeName() string
+}
+
+// EntityTypeName returns the entity type written to the table's discriminator attribute,
+// see [table.TableDefinition.EntityTypeKey].
+func (e Entry) EntityTypeName() string {
+	if n, ok := e.Index.(entityTypeNamer); ok {
+		return n.EntityTypeName()
+	}
+	return e.EntityType.Name()
+}
+
+// keyMatcher is implemented by *index.PrimaryIndex[E] for any E.
+type keyMatcher interface {
+	TableName() string
+	MatchesKey(pk, sk any) bool
+}
+
+// MatchKey returns the entry whose key patterns match the primary key values on the given table.
+// It only reports a match if exactly one registered entity matches.
+func MatchKey(tableName string, pk, sk any) (Entry, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	var match Entry
+	var n int
+	for _, t := range order {
+		entry := registry[t]
+		idx, ok := entry.Index.(keyMatcher)
+		if !ok || idx.TableName() != tableName || !idx.MatchesKey(pk, sk) {
+			continue
+		}
+		match = entry
+		n++
+	}
+	return match, n == 1
+}
+
 // All returns all registered entries in registration order.
 // Used by code generation tools.
 func All() []Entry {
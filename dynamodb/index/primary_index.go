@@ -2,9 +2,11 @@ package index
 
 import (
 	"fmt"
+	"reflect"
 
 	"github.com/acksell/bezos/dynamodb/index/val"
 	"github.com/acksell/bezos/dynamodb/table"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 )
 
 // PrimaryIndex represents the main table index with its key formats and associated GSIs.
@@ -26,6 +28,10 @@ type PrimaryIndex[E any] struct {
 	SortKey *val.ValDef
 	// Secondary are the Global Secondary Indexes associated with this table
 	Secondary []SecondaryIndex
+	// EntityType is the value written to the table's EntityTypeKey attribute.
+	// Defaults to the Go type name of E. Set it to keep stored items readable
+	// after renaming the Go type.
+	EntityType string
 }
 
 // TableName returns the table name.
@@ -33,6 +39,56 @@ func (pi *PrimaryIndex[E]) TableName() string {
 	return pi.Table.Name
 }
 
+// TableDefinition returns the table definition.
+func (pi *PrimaryIndex[E]) TableDefinition() table.TableDefinition {
+	return pi.Table
+}
+
+// EntityTypeName returns the entity type stored in the table's discriminator attribute.
+func (pi *PrimaryIndex[E]) EntityTypeName() string {
+	if pi.EntityType != "" {
+		return pi.EntityType
+	}
+	return reflect.TypeOf((*E)(nil)).Elem().Name()
+}
+
+// MatchesKey reports whether a primary key with the given values could belong to entity E.
+// A nil sort key value only checks the partition key.
+func (pi *PrimaryIndex[E]) MatchesKey(pk, sk any) bool {
+	if !pi.PartitionKey.Matches(pk) {
+		return false
+	}
+	if sk == nil || pi.SortKey == nil {
+		return true
+	}
+	return pi.SortKey.Matches(sk)
+}
+
+// ParseKeys extracts the field values encoded in the primary key attributes of item.
+func (pi *PrimaryIndex[E]) ParseKeys(item map[string]types.AttributeValue) (val.ParsedKey, error) {
+	return parseKeys(pi.Table.KeyDefinitions, pi.PartitionKey, pi.SortKey, item)
+}
+
+// parseKeys parses the key attributes defined by keyDef from item with the given value definitions.
+func parseKeys(keyDef table.PrimaryKeyDefinition, pk val.ValDef, sk *val.ValDef, item map[string]types.AttributeValue) (val.ParsedKey, error) {
+	key, err := keyDef.ExtractPrimaryKey(item)
+	if err != nil {
+		return val.ParsedKey{}, err
+	}
+	parsed, err := pk.Parse(key.Values.PartitionKey)
+	if err != nil {
+		return val.ParsedKey{}, fmt.Errorf("partition key %q: %w", keyDef.PartitionKey.Name, err)
+	}
+	if sk == nil || keyDef.SortKey.Name == "" {
+		return parsed, nil
+	}
+	skParsed, err := sk.Parse(key.Values.SortKey)
+	if err != nil {
+		return val.ParsedKey{}, fmt.Errorf("sort key %q: %w", keyDef.SortKey.Name, err)
+	}
+	return parsed.Merge(skParsed)
+}
+
 // Validate checks that the PrimaryIndex is properly configured.
 func (pi *PrimaryIndex[E]) Validate() error {
 	if pi.Table.Name == "" {
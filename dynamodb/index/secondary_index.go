@@ -5,6 +5,7 @@ import (
 
 	"github.com/acksell/bezos/dynamodb/index/val"
 	"github.com/acksell/bezos/dynamodb/table"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 )
 
 // SecondaryIndex represents a Global Secondary Index (GSI) definition with
@@ -24,6 +25,9 @@ type SecondaryIndex struct {
 	Partition val.ValDef
 	// Sort defines how to derive the GSI sort key value (optional)
 	Sort *val.ValDef
+	// Include makes the GSI sparse: only entities matching the rule get the GSI key
+	// attributes. Nil includes every entity.
+	Include *Inclusion
 }
 
 // Name returns the GSI name.
@@ -31,11 +35,22 @@ func (si SecondaryIndex) Name() string {
 	return si.GSI.Name
 }
 
+// Includes reports whether entity e belongs in this GSI, see [Inclusion].
+func (si SecondaryIndex) Includes(e any) bool {
+	return si.Include == nil || si.Include.Includes(e)
+}
+
 // KeyDefinition returns the key definition for this GSI.
 func (si SecondaryIndex) KeyDefinition() table.PrimaryKeyDefinition {
 	return si.GSI.KeyDefinitions
 }
 
+// ParseKeys extracts the field values encoded in the GSI key attributes of item,
+// e.g. of an item returned by a query on the GSI.
+func (si SecondaryIndex) ParseKeys(item map[string]types.AttributeValue) (val.ParsedKey, error) {
+	return parseKeys(si.GSI.KeyDefinitions, si.Partition, si.Sort, item)
+}
+
 // Validate checks that the SecondaryIndex is properly configured.
 func (si SecondaryIndex) Validate() error {
 	if si.GSI.Name == "" {
@@ -47,5 +62,10 @@ func (si SecondaryIndex) Validate() error {
 	if !si.Partition.HasValueSource() {
 		return fmt.Errorf("partition key value source (Fmt, FromField, or Const) is required for GSI %q", si.GSI.Name)
 	}
+	if si.Include != nil {
+		if err := si.Include.Validate(); err != nil {
+			return fmt.Errorf("GSI %q: %w", si.GSI.Name, err)
+		}
+	}
 	return nil
 }
@@ -2,8 +2,11 @@ package val
 
 import (
 	"fmt"
+	"reflect"
 	"regexp"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // SpecKind indicates the DynamoDB attribute type for a key spec.
@@ -264,3 +267,87 @@ func (p SpecPart) ParamName() string {
 	parts := p.FieldPath()
 	return parts[len(parts)-1]
 }
+
+// Format builds the key from field values keyed by field path, the same way
+// generated key functions do. Every referenced field must be present in values.
+func (s FmtSpec) Format(values map[string]any) (string, error) {
+	var b strings.Builder
+	for _, p := range s.Parts {
+		if p.IsLiteral {
+			b.WriteString(p.Value)
+			continue
+		}
+		v, ok := values[p.Value]
+		if !ok {
+			return "", fmt.Errorf("no value for field %q", p.Value)
+		}
+		str, err := p.FormatValue(v)
+		if err != nil {
+			return "", fmt.Errorf("field %q: %w", p.Value, err)
+		}
+		b.WriteString(str)
+	}
+	return b.String(), nil
+}
+
+// FormatValue formats a single field value according to the part's annotations.
+// Times require a time format and floats a format or printf spec.
+func (p SpecPart) FormatValue(v any) (string, error) {
+	if t, ok := v.(time.Time); ok {
+		return p.formatTime(t)
+	}
+	rv := reflect.ValueOf(v)
+	if !rv.IsValid() {
+		return "", fmt.Errorf("nil value")
+	}
+	switch rv.Kind() {
+	case reflect.String:
+		if p.PrintfSpec != "" {
+			return fmt.Sprintf(p.PrintfSpec, v), nil
+		}
+		return rv.String(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if p.PrintfSpec != "" {
+			return fmt.Sprintf(p.PrintfSpec, v), nil
+		}
+		return strconv.FormatInt(rv.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if p.PrintfSpec != "" {
+			return fmt.Sprintf(p.PrintfSpec, v), nil
+		}
+		return strconv.FormatUint(rv.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		spec := p.PrintfSpec
+		if spec == "" {
+			spec = p.Format()
+		}
+		if spec == "" {
+			return "", fmt.Errorf("float value requires explicit format")
+		}
+		return fmt.Sprintf(spec, v), nil
+	}
+	return fmt.Sprintf("%v", v), nil
+}
+
+func (p SpecPart) formatTime(t time.Time) (string, error) {
+	if p.HasModifier("utc") {
+		t = t.UTC()
+	}
+	var n int64
+	switch format := p.Format(); format {
+	case "", "utc":
+		return "", fmt.Errorf("time.Time value requires explicit format")
+	case "unix":
+		n = t.Unix()
+	case "unixmilli":
+		n = t.UnixMilli()
+	case "unixnano":
+		n = t.UnixNano()
+	default:
+		return t.Format(timeLayout(format)), nil
+	}
+	if p.PrintfSpec != "" {
+		return fmt.Sprintf(p.PrintfSpec, n), nil
+	}
+	return strconv.FormatInt(n, 10), nil
+}
@@ -2,6 +2,8 @@ package val
 
 import (
 	"encoding/base64"
+	"fmt"
+	"strings"
 
 	"golang.org/x/exp/constraints"
 )
@@ -81,3 +83,88 @@ func (c *ConstValue) GetKind() SpecKind {
 func (c *ConstValue) GetValue() any {
 	return c.Value
 }
+
+// Matches reports whether value could have been produced by this ValDef.
+// Constants must match exactly, formats must start with their literal prefix.
+// Field copies and numeric formats match any value.
+func (v ValDef) Matches(value any) bool {
+	switch {
+	case v.Const != nil:
+		return fmt.Sprint(v.Const.Value) == fmt.Sprint(value)
+	case v.Format != nil && v.Format.Kind == SpecKindS:
+		s, ok := value.(string)
+		if !ok {
+			return false
+		}
+		if v.Format.IsConstant() {
+			return s == v.Format.Raw
+		}
+		return strings.HasPrefix(s, v.Format.LiteralPrefix())
+	}
+	return true
+}
+
+// Parse extracts the field values from a key value produced by this ValDef.
+// Constants yield no fields but must match, field copies yield the value under the field's path.
+// Binary values are parsed as strings.
+func (v ValDef) Parse(value any) (ParsedKey, error) {
+	str := keyString(value)
+	switch {
+	case v.Const != nil:
+		if want := keyString(v.Const.Value); str != want {
+			return ParsedKey{}, fmt.Errorf("key %q does not match constant %q", str, want)
+		}
+		return ParsedKey{}, nil
+	case v.FromField != "":
+		part := SpecPart{Value: v.FromField}
+		return ParsedKey{
+			paths: []string{v.FromField},
+			raw:   map[string]string{v.FromField: str},
+			parts: map[string]SpecPart{v.FromField: part},
+		}, nil
+	case v.Format != nil:
+		return v.Format.Parse(str)
+	}
+	return ParsedKey{}, fmt.Errorf("ValDef has no value source")
+}
+
+func keyString(value any) string {
+	if b, ok := value.([]byte); ok {
+		return string(b)
+	}
+	return fmt.Sprint(value)
+}
+
+// FieldPaths returns the paths of the fields the value is derived from.
+func (v ValDef) FieldPaths() []string {
+	switch {
+	case v.Format != nil:
+		return v.Format.FieldPaths()
+	case v.FromField != "":
+		return []string{v.FromField}
+	}
+	return nil
+}
+
+// Value computes the key value from field values keyed by field path.
+// Constants are returned as is, field copies and formats are formatted to strings
+// like the generated key functions do.
+func (v ValDef) Value(values map[string]any) (any, error) {
+	switch {
+	case v.Const != nil:
+		return v.Const.Value, nil
+	case v.FromField != "":
+		fv, ok := values[v.FromField]
+		if !ok {
+			return nil, fmt.Errorf("no value for field %q", v.FromField)
+		}
+		s, err := SpecPart{Value: v.FromField}.FormatValue(fv)
+		if err != nil {
+			return nil, fmt.Errorf("field %q: %w", v.FromField, err)
+		}
+		return s, nil
+	case v.Format != nil:
+		return v.Format.Format(values)
+	}
+	return nil, fmt.Errorf("ValDef has no value source")
+}
@@ -0,0 +1,85 @@
+package val
+
+// Overlaps reports whether some key value could be produced by both v and o,
+// e.g. "USER#{id}" and "USER#{email}" overlap while "USER#{id}" and "ORDER#{id}" don't.
+// Field references are assumed to hold any string, so printf formats that only
+// produce digits are still treated as overlapping with letters.
+func (v ValDef) Overlaps(o ValDef) bool {
+	if !v.HasValueSource() || !o.HasValueSource() {
+		return false
+	}
+	if k1, k2 := v.kind(), o.kind(); k1 != "" && k2 != "" && k1 != k2 {
+		return false
+	}
+	return globsIntersect(v.glob(), o.glob())
+}
+
+// kind returns the attribute type of the value, or "" if it depends on the field.
+func (v ValDef) kind() SpecKind {
+	switch {
+	case v.Const != nil:
+		return v.Const.Kind
+	case v.Format != nil:
+		return v.Format.Kind
+	}
+	return ""
+}
+
+// globToken is a literal rune, or any string if wildcard is set.
+type globToken struct {
+	r        rune
+	wildcard bool
+}
+
+// glob returns the pattern of the values v produces.
+func (v ValDef) glob() []globToken {
+	var g []globToken
+	literal := func(s string) {
+		for _, r := range s {
+			g = append(g, globToken{r: r})
+		}
+	}
+	switch {
+	case v.Const != nil:
+		literal(keyString(v.Const.Value))
+	case v.FromField != "":
+		g = append(g, globToken{wildcard: true})
+	case v.Format != nil:
+		for _, p := range v.Format.Parts {
+			if p.IsLiteral {
+				literal(p.Value)
+			} else {
+				g = append(g, globToken{wildcard: true})
+			}
+		}
+	}
+	return g
+}
+
+// globsIntersect reports whether some string matches both patterns.
+func globsIntersect(a, b []globToken) bool {
+	memo := make(map[[2]int]bool)
+	var match func(i, j int) bool
+	match = func(i, j int) bool {
+		if i == len(a) && j == len(b) {
+			return true
+		}
+		key := [2]int{i, j}
+		if res, ok := memo[key]; ok {
+			return res
+		}
+		res := false
+		switch {
+		case i < len(a) && a[i].wildcard:
+			// The wildcard matches nothing more, or absorbs b's next token.
+			res = match(i+1, j) || (j < len(b) && match(i, j+1))
+		case j < len(b) && b[j].wildcard:
+			res = match(i, j+1) || (i < len(a) && match(i+1, j))
+		case i < len(a) && j < len(b):
+			res = a[i].r == b[j].r && match(i+1, j+1)
+		}
+		memo[key] = res
+		return res
+	}
+	return match(0, 0)
+}
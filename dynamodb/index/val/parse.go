@@ -0,0 +1,369 @@
+package val
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParsedKey holds the field values extracted from a key by [FmtSpec.Parse].
+//
+// Values are kept as the raw key segments and converted on access, using the
+// format annotations of the field reference they were extracted from:
+//
+//	k, err := val.Fmt("MSG#{chatID}#{ts:unixnano:%020d}").Format.Parse(sk)
+//	chatID, err := k.String("chatID")
+//	ts, err := k.Time("ts")
+type ParsedKey struct {
+	paths []string
+	raw   map[string]string
+	parts map[string]SpecPart
+}
+
+// Paths returns the field paths found in the key, in pattern order.
+func (k ParsedKey) Paths() []string {
+	return k.paths
+}
+
+// Raw returns the key segment of the field at path.
+func (k ParsedKey) Raw(path string) (string, bool) {
+	s, ok := k.raw[path]
+	return s, ok
+}
+
+func (k ParsedKey) segment(path string) (string, SpecPart, error) {
+	s, ok := k.raw[path]
+	if !ok {
+		return "", SpecPart{}, fmt.Errorf("key has no field %q", path)
+	}
+	return s, k.parts[path], nil
+}
+
+// String returns the field at path as a string.
+// Space padding from a printf width is trimmed.
+func (k ParsedKey) String(path string) (string, error) {
+	s, p, err := k.segment(path)
+	if err != nil {
+		return "", err
+	}
+	if p.PrintfSpec != "" {
+		s = strings.TrimSpace(s)
+	}
+	return s, nil
+}
+
+// Int returns the field at path as a signed integer. Zero and space padding is accepted,
+// and the printf verb selects the base (%x, %o, %b).
+func (k ParsedKey) Int(path string) (int64, error) {
+	s, p, err := k.segment(path)
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.ParseInt(strings.TrimSpace(s), printfBase(p.PrintfSpec), 64)
+	if err != nil {
+		return 0, fmt.Errorf("field %q: %w", path, err)
+	}
+	return n, nil
+}
+
+// Uint returns the field at path as an unsigned integer, see [ParsedKey.Int].
+func (k ParsedKey) Uint(path string) (uint64, error) {
+	s, p, err := k.segment(path)
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.ParseUint(strings.TrimSpace(s), printfBase(p.PrintfSpec), 64)
+	if err != nil {
+		return 0, fmt.Errorf("field %q: %w", path, err)
+	}
+	return n, nil
+}
+
+// Float returns the field at path as a float.
+func (k ParsedKey) Float(path string) (float64, error) {
+	s, _, err := k.segment(path)
+	if err != nil {
+		return 0, err
+	}
+	f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil {
+		return 0, fmt.Errorf("field %q: %w", path, err)
+	}
+	return f, nil
+}
+
+// Time returns the field at path as a time, parsed with the field's time format.
+// Unix timestamps carry no location and are returned in UTC, as are all
+// times of fields with the utc modifier.
+func (k ParsedKey) Time(path string) (time.Time, error) {
+	s, p, err := k.segment(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	var t time.Time
+	switch format := p.Format(); format {
+	case "", "utc":
+		return time.Time{}, fmt.Errorf("field %q has no time format", path)
+	case "unix", "unixmilli", "unixnano":
+		n, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("field %q: %w", path, err)
+		}
+		switch format {
+		case "unix":
+			t = time.Unix(n, 0)
+		case "unixmilli":
+			t = time.UnixMilli(n)
+		default:
+			t = time.Unix(0, n)
+		}
+		return t.UTC(), nil
+	default:
+		t, err = time.Parse(timeLayout(format), s)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("field %q: %w", path, err)
+		}
+	}
+	if p.HasModifier("utc") {
+		t = t.UTC()
+	}
+	return t, nil
+}
+
+// Values returns the raw segments of all fields. Dotted paths are expanded into
+// nested maps, so "{user.id}" yields {"user": {"id": "..."}}.
+func (k ParsedKey) Values() map[string]any {
+	out := make(map[string]any)
+	for _, path := range k.paths {
+		m := out
+		parts := strings.Split(path, ".")
+		for _, p := range parts[:len(parts)-1] {
+			next, ok := m[p].(map[string]any)
+			if !ok {
+				next = make(map[string]any)
+				m[p] = next
+			}
+			m = next
+		}
+		m[parts[len(parts)-1]] = k.raw[path]
+	}
+	return out
+}
+
+// Merge combines the fields of two parsed keys, e.g. of a partition and a sort key.
+// A field present in both must have the same raw value in both.
+func (k ParsedKey) Merge(other ParsedKey) (ParsedKey, error) {
+	out := ParsedKey{
+		paths: append([]string{}, k.paths...),
+		raw:   make(map[string]string, len(k.raw)+len(other.raw)),
+		parts: make(map[string]SpecPart, len(k.parts)+len(other.parts)),
+	}
+	for path, s := range k.raw {
+		out.raw[path] = s
+		out.parts[path] = k.parts[path]
+	}
+	for _, path := range other.paths {
+		if s, ok := out.raw[path]; ok {
+			if s != other.raw[path] {
+				return ParsedKey{}, fmt.Errorf("field %q has conflicting values %q and %q", path, s, other.raw[path])
+			}
+			continue
+		}
+		out.paths = append(out.paths, path)
+		out.raw[path] = other.raw[path]
+		out.parts[path] = other.parts[path]
+	}
+	return out, nil
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// As returns the field at path converted to T. T may be a string, bool, integer,
+// float or time.Time type, or a named type with one of those underlying types.
+func As[T any](k ParsedKey, path string) (T, error) {
+	var zero T
+	v := reflect.ValueOf(&zero).Elem()
+	if v.Type() == timeType {
+		t, err := k.Time(path)
+		if err != nil {
+			return zero, err
+		}
+		v.Set(reflect.ValueOf(t))
+		return zero, nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		s, err := k.String(path)
+		if err != nil {
+			return zero, err
+		}
+		v.SetString(s)
+	case reflect.Bool:
+		s, err := k.String(path)
+		if err != nil {
+			return zero, err
+		}
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return zero, fmt.Errorf("field %q: %w", path, err)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := k.Int(path)
+		if err != nil {
+			return zero, err
+		}
+		if v.OverflowInt(n) {
+			return zero, fmt.Errorf("field %q: value %d overflows %s", path, n, v.Type())
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := k.Uint(path)
+		if err != nil {
+			return zero, err
+		}
+		if v.OverflowUint(n) {
+			return zero, fmt.Errorf("field %q: value %d overflows %s", path, n, v.Type())
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := k.Float(path)
+		if err != nil {
+			return zero, err
+		}
+		v.SetFloat(f)
+	default:
+		return zero, fmt.Errorf("field %q: unsupported type %s", path, v.Type())
+	}
+	return zero, nil
+}
+
+// Parse extracts the field values from a key that was formatted with this spec.
+//
+// Field references are delimited by the literals between them. Two references
+// without a literal in between can only be separated if the first one has a fixed
+// width (a printf width such as %08d, or utc:rfc3339fixed), otherwise Parse
+// returns an error regardless of the key. If a delimiter also occurs inside a
+// value, the earliest split that lets the rest of the key match is used.
+func (s FmtSpec) Parse(key string) (ParsedKey, error) {
+	for i := 0; i+1 < len(s.Parts); i++ {
+		cur, next := s.Parts[i], s.Parts[i+1]
+		if cur.IsLiteral || next.IsLiteral {
+			continue
+		}
+		if _, fixed := fixedWidth(cur); !fixed {
+			return ParsedKey{}, fmt.Errorf("pattern %q is ambiguous: {%s} and {%s} are not separated by a literal", s.Raw, cur.Value, next.Value)
+		}
+	}
+
+	k := ParsedKey{raw: make(map[string]string), parts: make(map[string]SpecPart)}
+	if !s.match(key, 0, k.raw) {
+		return ParsedKey{}, fmt.Errorf("key %q does not match pattern %q", key, s.Raw)
+	}
+	for _, p := range s.Parts {
+		if p.IsLiteral {
+			continue
+		}
+		if _, seen := k.parts[p.Value]; !seen {
+			k.paths = append(k.paths, p.Value)
+		}
+		k.parts[p.Value] = p
+	}
+	return k, nil
+}
+
+// match matches key against s.Parts[i:], backtracking over delimiter occurrences.
+// Captured segments are written to raw.
+func (s FmtSpec) match(key string, i int, raw map[string]string) bool {
+	if i == len(s.Parts) {
+		return key == ""
+	}
+	p := s.Parts[i]
+	if p.IsLiteral {
+		rest, ok := strings.CutPrefix(key, p.Value)
+		return ok && s.match(rest, i+1, raw)
+	}
+	capture := func(n int) bool {
+		seg := key[:n]
+		if prev, ok := raw[p.Value]; ok && prev != seg {
+			// the same field referenced twice must have the same value
+			return false
+		}
+		_, had := raw[p.Value]
+		raw[p.Value] = seg
+		if s.match(key[n:], i+1, raw) {
+			return true
+		}
+		if !had {
+			delete(raw, p.Value)
+		}
+		return false
+	}
+	if i+1 == len(s.Parts) {
+		return capture(len(key))
+	}
+	next := s.Parts[i+1]
+	if !next.IsLiteral {
+		// Parse rejects adjacent references unless the first has a fixed width.
+		w, _ := fixedWidth(p)
+		return len(key) >= w && capture(w)
+	}
+	for n := 0; n <= len(key); n++ {
+		idx := strings.Index(key[n:], next.Value)
+		if idx < 0 {
+			return false
+		}
+		n += idx
+		if capture(n) {
+			return true
+		}
+	}
+	return false
+}
+
+// printfWidthRegex matches the width of a printf spec like "%020d" or "%-8s".
+var printfWidthRegex = regexp.MustCompile(`^%[-+ #0]*([1-9][0-9]*)`)
+
+// fixedWidth returns the length of every value formatted with p, if it is fixed.
+// A printf width is only a minimum, values that exceed it can't be split from
+// a directly following field.
+func fixedWidth(p SpecPart) (int, bool) {
+	if m := printfWidthRegex.FindStringSubmatch(p.PrintfSpec); m != nil {
+		w, _ := strconv.Atoi(m[1])
+		return w, true
+	}
+	if p.Format() == "rfc3339fixed" && p.HasModifier("utc") {
+		return len("2006-01-02T15:04:05.000000000Z"), true
+	}
+	return 0, false
+}
+
+func printfBase(spec string) int {
+	if spec == "" {
+		return 10
+	}
+	switch spec[len(spec)-1] {
+	case 'x', 'X':
+		return 16
+	case 'o':
+		return 8
+	case 'b':
+		return 2
+	}
+	return 10
+}
+
+func timeLayout(format string) string {
+	switch format {
+	case "rfc3339":
+		return time.RFC3339
+	case "rfc3339fixed":
+		return "2006-01-02T15:04:05.000000000Z07:00"
+	case "rfc3339nano":
+		return time.RFC3339Nano
+	}
+	return format
+}
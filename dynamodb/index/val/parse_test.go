@@ -0,0 +1,224 @@
+package val_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/acksell/bezos/dynamodb/index/val"
+)
+
+func TestFmtSpec_Parse(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		key     string
+		want    map[string]string
+		wantErr string
+	}{
+		{"single field", "USER#{id}", "USER#123", map[string]string{"id": "123"}, ""},
+		{"multiple fields", "ORDER#{tenant}#{id}", "ORDER#acme#42", map[string]string{"tenant": "acme", "id": "42"}, ""},
+		{"trailing literal", "{a}#{b}#END", "x#y#END", map[string]string{"a": "x", "b": "y"}, ""},
+		{"nested path", "USER#{user.id}", "USER#u1", map[string]string{"user.id": "u1"}, ""},
+		{"delimiter in last value", "A#{a}#{b}", "A#x#y#z", map[string]string{"a": "x", "b": "y#z"}, ""},
+		{"backtracks on delimiter", "{a}#{b}#END", "x#y#z#END", map[string]string{"a": "x", "b": "y#z"}, ""},
+		{"adjacent fixed width", "{seq:%04d}{id}", "0042abc", map[string]string{"seq": "0042", "id": "abc"}, ""},
+		{"same field twice", "{id}#{id}", "a#a", map[string]string{"id": "a"}, ""},
+		{"constant", "PROFILE", "PROFILE", map[string]string{}, ""},
+		{"prefix mismatch", "USER#{id}", "ORDER#123", nil, "does not match"},
+		{"constant mismatch", "PROFILE", "OTHER", nil, "does not match"},
+		{"same field different values", "{id}#{id}", "a#b", nil, "does not match"},
+		{"adjacent ambiguous", "{a}{b}", "xy", nil, "ambiguous"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k, err := val.Fmt(tt.pattern).Format.Parse(tt.key)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Parse(%q) error = %v, want containing %q", tt.key, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", tt.key, err)
+			}
+			if len(k.Paths()) != len(tt.want) {
+				t.Errorf("Paths() = %v, want %d paths", k.Paths(), len(tt.want))
+			}
+			for path, want := range tt.want {
+				if got, ok := k.Raw(path); !ok || got != want {
+					t.Errorf("Raw(%q) = %q, %v, want %q", path, got, ok, want)
+				}
+			}
+		})
+	}
+}
+
+func TestParsedKey_TypedAccessors(t *testing.T) {
+	ts := time.Date(2024, 3, 1, 12, 30, 0, 123456789, time.UTC)
+
+	k, err := val.Fmt("MSG#{chat}#{seq:%08d}#{ts:utc:unixnano:%020d}").Format.Parse(
+		"MSG#c1#00000042#" + padded(ts.UnixNano()))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if seq, err := k.Int("seq"); err != nil || seq != 42 {
+		t.Errorf("Int(seq) = %d, %v, want 42", seq, err)
+	}
+	if got, err := k.Time("ts"); err != nil || !got.Equal(ts) {
+		t.Errorf("Time(ts) = %v, %v, want %v", got, err, ts)
+	}
+	if _, err := k.Int("missing"); err == nil {
+		t.Error("expected error for missing field")
+	}
+
+	type chatID string
+	if got, err := val.As[chatID](k, "chat"); err != nil || got != "c1" {
+		t.Errorf("As[chatID](chat) = %q, %v, want c1", got, err)
+	}
+	if got, err := val.As[uint16](k, "seq"); err != nil || got != 42 {
+		t.Errorf("As[uint16](seq) = %d, %v, want 42", got, err)
+	}
+	if _, err := val.As[int8](k, "ts"); err == nil {
+		t.Error("expected overflow error for int8")
+	}
+}
+
+func TestParsedKey_TimeFormats(t *testing.T) {
+	ts := time.Date(2024, 3, 1, 12, 30, 5, 0, time.UTC)
+	tests := []struct {
+		pattern string
+		key     string
+	}{
+		{"{ts:unix}", "1709296205"},
+		{"{ts:unixmilli}", "1709296205000"},
+		{"{ts:rfc3339}", "2024-03-01T12:30:05Z"},
+		{"{ts:utc:rfc3339fixed}", "2024-03-01T12:30:05.000000000Z"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			k, err := val.Fmt(tt.pattern).Format.Parse(tt.key)
+			if err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+			got, err := val.As[time.Time](k, "ts")
+			if err != nil {
+				t.Fatalf("As[time.Time] failed: %v", err)
+			}
+			if !got.Equal(ts) {
+				t.Errorf("got %v, want %v", got, ts)
+			}
+		})
+	}
+
+	// custom layouts can't contain ':', which separates modifiers
+	k, err := val.Fmt("DAY#{day:2006-01-02}").Format.Parse("DAY#2024-03-01")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if got, err := k.Time("day"); err != nil || !got.Equal(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Time(day) = %v, %v", got, err)
+	}
+}
+
+func TestParsedKey_ValuesAndMerge(t *testing.T) {
+	pk, err := val.Fmt("TENANT#{org.id}").Parse("TENANT#acme")
+	if err != nil {
+		t.Fatalf("Parse pk failed: %v", err)
+	}
+	sk, err := val.FromField("id").Parse("42")
+	if err != nil {
+		t.Fatalf("Parse sk failed: %v", err)
+	}
+	k, err := pk.Merge(sk)
+	if err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+	values := k.Values()
+	org, ok := values["org"].(map[string]any)
+	if !ok || org["id"] != "acme" {
+		t.Errorf("Values()[org] = %v, want map with id=acme", values["org"])
+	}
+	if values["id"] != "42" {
+		t.Errorf("Values()[id] = %v, want 42", values["id"])
+	}
+
+	other, _ := val.FromField("org.id").Parse("other")
+	if _, err := pk.Merge(other); err == nil {
+		t.Error("expected conflict error when merging different values")
+	}
+
+	if _, err := val.String("PROFILE").Parse("OTHER"); err == nil {
+		t.Error("expected error for constant mismatch")
+	}
+}
+
+func padded(n int64) string {
+	return fmt.Sprintf("%020d", n)
+}
+
+func TestFmtSpec_Format_RoundTrip(t *testing.T) {
+	ts := time.Date(2024, 3, 1, 12, 30, 5, 0, time.UTC)
+	tests := []struct {
+		pattern string
+		values  map[string]any
+		want    string
+	}{
+		{"ORDER#{tenant}#{id}", map[string]any{"tenant": "acme", "id": "42"}, "ORDER#acme#42"},
+		{"SEQ#{n:%08d}", map[string]any{"n": int64(42)}, "SEQ#00000042"},
+		{"{n}", map[string]any{"n": uint8(7)}, "7"},
+		{"P#{price:%.2f}", map[string]any{"price": 9.5}, "P#9.50"},
+		{"TS#{ts:utc:unixnano:%020d}", map[string]any{"ts": ts}, "TS#" + padded(ts.UnixNano())},
+		{"DAY#{ts:utc:rfc3339}", map[string]any{"ts": ts}, "DAY#2024-03-01T12:30:05Z"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			spec := val.Fmt(tt.pattern).Format
+			got, err := spec.Format(tt.values)
+			if err != nil {
+				t.Fatalf("Format() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Format() = %q, want %q", got, tt.want)
+			}
+			if _, err := spec.Parse(got); err != nil {
+				t.Errorf("Parse(Format()) error = %v", err)
+			}
+		})
+	}
+
+	if _, err := val.Fmt("USER#{id}").Format.Format(nil); err == nil {
+		t.Error("expected error for missing field")
+	}
+	if _, err := val.Fmt("{ts}").Format.Format(map[string]any{"ts": ts}); err == nil {
+		t.Error("expected error for time without format")
+	}
+}
+
+func TestValDef_Overlaps(t *testing.T) {
+	tests := []struct {
+		a, b val.ValDef
+		want bool
+	}{
+		{val.Fmt("USER#{id}"), val.Fmt("USER#{email}"), true},
+		{val.Fmt("USER#{id}"), val.Fmt("ORDER#{id}"), false},
+		{val.Fmt("CUSTOMER"), val.Fmt("ORDER#{id}"), false},
+		{val.Fmt("ORDER#{id}"), val.Fmt("ORDER#{tenant}#{id}"), true},
+		{val.Fmt("{a}#ITEM"), val.Fmt("{b}#PROFILE"), false},
+		{val.Fmt("{a}#ITEM"), val.Fmt("X#{b}"), true},
+		{val.FromField("id"), val.Fmt("USER#{id}"), true},
+		{val.String("PROFILE"), val.Fmt("PROFILE"), true},
+		{val.String("PROFILE"), val.Fmt("PROFILES"), false},
+		{val.Number(1), val.Fmt("1"), false},
+	}
+	for i, tt := range tests {
+		if got := tt.a.Overlaps(tt.b); got != tt.want {
+			t.Errorf("case %d: a.Overlaps(b) = %v, want %v", i, got, tt.want)
+		}
+		if got := tt.b.Overlaps(tt.a); got != tt.want {
+			t.Errorf("case %d: b.Overlaps(a) = %v, want %v", i, got, tt.want)
+		}
+	}
+}
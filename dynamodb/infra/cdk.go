@@ -0,0 +1,61 @@
+package infra
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/acksell/bezos/dynamodb/internal/naming"
+	"github.com/acksell/bezos/dynamodb/schema"
+)
+
+var cdkAttributeTypes = map[string]string{
+	"S": "dynamodb.AttributeType.STRING",
+	"N": "dynamodb.AttributeType.NUMBER",
+	"B": "dynamodb.AttributeType.BINARY",
+}
+
+// cdk renders a construct wrapping the table, for aws-cdk-lib v2. Apps can set
+// other props, e.g. encryption or streams, but not the name, keys and TTL attribute,
+// which are applied after the props.
+func cdk(t schema.Table) []byte {
+	class := naming.Exported(t.Name) + "Table"
+	var b strings.Builder
+	b.WriteString("// Code generated by ddbgen. DO NOT EDIT.\n\n")
+	b.WriteString("import { aws_dynamodb as dynamodb } from 'aws-cdk-lib';\n")
+	b.WriteString("import { Construct } from 'constructs';\n\n")
+	fmt.Fprintf(&b, "// %s defines the DynamoDB table %s.\n", class, t.Name)
+	fmt.Fprintf(&b, "export class %s extends Construct {\n", class)
+	b.WriteString("  public readonly table: dynamodb.Table;\n\n")
+	b.WriteString("  constructor(scope: Construct, id: string, props: Omit<dynamodb.TableProps, " +
+		"'tableName' | 'partitionKey' | 'sortKey' | 'timeToLiveAttribute'> = {}) {\n")
+	b.WriteString("    super(scope, id);\n")
+	b.WriteString("    this.table = new dynamodb.Table(this, 'Table', {\n")
+	b.WriteString("      billingMode: dynamodb.BillingMode.PAY_PER_REQUEST,\n")
+	b.WriteString("      ...props,\n")
+	fmt.Fprintf(&b, "      tableName: '%s',\n", t.Name)
+	fmt.Fprintf(&b, "      partitionKey: %s,\n", cdkKey(t.PartitionKey))
+	if t.SortKey != nil {
+		fmt.Fprintf(&b, "      sortKey: %s,\n", cdkKey(*t.SortKey))
+	}
+	if t.TimeToLiveKey != "" {
+		fmt.Fprintf(&b, "      timeToLiveAttribute: '%s',\n", t.TimeToLiveKey)
+	}
+	b.WriteString("    });\n")
+	for _, g := range t.GSIs {
+		b.WriteString("    this.table.addGlobalSecondaryIndex({\n")
+		fmt.Fprintf(&b, "      indexName: '%s',\n", g.Name)
+		fmt.Fprintf(&b, "      partitionKey: %s,\n", cdkKey(g.PartitionKey))
+		if g.SortKey != nil {
+			fmt.Fprintf(&b, "      sortKey: %s,\n", cdkKey(*g.SortKey))
+		}
+		b.WriteString("      projectionType: dynamodb.ProjectionType.ALL,\n")
+		b.WriteString("    });\n")
+	}
+	b.WriteString("  }\n")
+	b.WriteString("}\n")
+	return []byte(b.String())
+}
+
+func cdkKey(k schema.KeyDef) string {
+	return fmt.Sprintf("{ name: '%s', type: %s }", k.Name, cdkAttributeTypes[k.Kind])
+}
@@ -0,0 +1,132 @@
+package infra
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/acksell/bezos/dynamodb/internal/naming"
+	"github.com/acksell/bezos/dynamodb/schema"
+	"gopkg.in/yaml.v3"
+)
+
+const cfnTableType = "AWS::DynamoDB::Table"
+
+// The cfn types are the subset of a CloudFormation template that defines a table,
+// with the fields in the order they are written.
+type cfnTemplate struct {
+	AWSTemplateFormatVersion string                 `yaml:"AWSTemplateFormatVersion" json:"AWSTemplateFormatVersion"`
+	Description              string                 `yaml:"Description" json:"Description"`
+	Resources                map[string]cfnResource `yaml:"Resources" json:"Resources"`
+	Outputs                  map[string]cfnOutput   `yaml:"Outputs,omitempty" json:"Outputs,omitempty"`
+}
+
+type cfnResource struct {
+	Type                string        `yaml:"Type" json:"Type"`
+	DeletionPolicy      string        `yaml:"DeletionPolicy,omitempty" json:"DeletionPolicy,omitempty"`
+	UpdateReplacePolicy string        `yaml:"UpdateReplacePolicy,omitempty" json:"UpdateReplacePolicy,omitempty"`
+	Properties          cfnProperties `yaml:"Properties" json:"Properties"`
+}
+
+type cfnProperties struct {
+	TableName               string       `yaml:"TableName" json:"TableName"`
+	BillingMode             string       `yaml:"BillingMode" json:"BillingMode"`
+	AttributeDefinitions    []cfnAttrDef `yaml:"AttributeDefinitions" json:"AttributeDefinitions"`
+	KeySchema               []cfnKey     `yaml:"KeySchema" json:"KeySchema"`
+	GlobalSecondaryIndexes  []cfnGSI     `yaml:"GlobalSecondaryIndexes,omitempty" json:"GlobalSecondaryIndexes,omitempty"`
+	TimeToLiveSpecification *cfnTTLSpec  `yaml:"TimeToLiveSpecification,omitempty" json:"TimeToLiveSpecification,omitempty"`
+}
+
+type cfnAttrDef struct {
+	AttributeName string `yaml:"AttributeName" json:"AttributeName"`
+	AttributeType string `yaml:"AttributeType" json:"AttributeType"`
+}
+
+type cfnKey struct {
+	AttributeName string `yaml:"AttributeName" json:"AttributeName"`
+	KeyType       string `yaml:"KeyType" json:"KeyType"`
+}
+
+type cfnGSI struct {
+	IndexName  string        `yaml:"IndexName" json:"IndexName"`
+	KeySchema  []cfnKey      `yaml:"KeySchema" json:"KeySchema"`
+	Projection cfnProjection `yaml:"Projection" json:"Projection"`
+}
+
+type cfnProjection struct {
+	ProjectionType string `yaml:"ProjectionType" json:"ProjectionType"`
+}
+
+type cfnTTLSpec struct {
+	AttributeName string `yaml:"AttributeName" json:"AttributeName"`
+	Enabled       bool   `yaml:"Enabled" json:"Enabled"`
+}
+
+type cfnOutput struct {
+	Value map[string]string `yaml:"Value" json:"Value"`
+}
+
+func cloudFormation(t schema.Table, asJSON bool) ([]byte, error) {
+	props := cfnProperties{
+		TableName:   t.Name,
+		BillingMode: "PAY_PER_REQUEST",
+		KeySchema:   cfnKeySchema(t.PartitionKey, t.SortKey),
+	}
+	for _, a := range attributes(t) {
+		props.AttributeDefinitions = append(props.AttributeDefinitions, cfnAttrDef{AttributeName: a.Name, AttributeType: a.Kind})
+	}
+	for _, g := range t.GSIs {
+		props.GlobalSecondaryIndexes = append(props.GlobalSecondaryIndexes, cfnGSI{
+			IndexName:  g.Name,
+			KeySchema:  cfnKeySchema(g.PartitionKey, g.SortKey),
+			Projection: cfnProjection{ProjectionType: "ALL"},
+		})
+	}
+	if t.TimeToLiveKey != "" {
+		props.TimeToLiveSpecification = &cfnTTLSpec{AttributeName: t.TimeToLiveKey, Enabled: true}
+	}
+
+	id := naming.Exported(t.Name) + "Table"
+	tmpl := cfnTemplate{
+		AWSTemplateFormatVersion: "2010-09-09",
+		Description:              fmt.Sprintf("DynamoDB table %s. Generated by ddbgen, verify changes with ddb schema verify-infra.", t.Name),
+		Resources: map[string]cfnResource{
+			id: {
+				Type:                cfnTableType,
+				DeletionPolicy:      "Retain",
+				UpdateReplacePolicy: "Retain",
+				Properties:          props,
+			},
+		},
+		Outputs: map[string]cfnOutput{
+			id + "Arn": {Value: map[string]string{"Fn::GetAtt": id + ".Arn"}},
+		},
+	}
+
+	if asJSON {
+		out, err := json.MarshalIndent(tmpl, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(out, '\n'), nil
+	}
+	var buf bytes.Buffer
+	buf.WriteString("# Code generated by ddbgen. DO NOT EDIT.\n")
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(tmpl); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func cfnKeySchema(pk schema.KeyDef, sk *schema.KeyDef) []cfnKey {
+	keys := []cfnKey{{AttributeName: pk.Name, KeyType: "HASH"}}
+	if sk != nil {
+		keys = append(keys, cfnKey{AttributeName: sk.Name, KeyType: "RANGE"})
+	}
+	return keys
+}
@@ -0,0 +1,332 @@
+package infra
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/acksell/bezos/dynamodb/schema"
+)
+
+// A minimal reader of Terraform's HCL: enough to find aws_dynamodb_table resources
+// and their literal attributes. Expressions other than single strings, numbers and
+// bools, e.g. var.table_name, read as unknown.
+
+type hclTokenKind int
+
+const (
+	hclEOF hclTokenKind = iota
+	hclNewline
+	hclIdent
+	hclString
+	hclNumber
+	hclPunct
+)
+
+type hclToken struct {
+	kind  hclTokenKind
+	text  string
+	line  int
+	known bool // strings without interpolation
+}
+
+type hclLexer struct {
+	src  string
+	pos  int
+	line int
+}
+
+func (l *hclLexer) errorf(format string, args ...any) error {
+	return fmt.Errorf("line %d: %s", l.line, fmt.Sprintf(format, args...))
+}
+
+func (l *hclLexer) next() (hclToken, error) {
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case c == ' ' || c == '\t' || c == '\r':
+			l.pos++
+		case c == '#' || strings.HasPrefix(l.src[l.pos:], "//"):
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' {
+				l.pos++
+			}
+		case strings.HasPrefix(l.src[l.pos:], "/*"):
+			end := strings.Index(l.src[l.pos+2:], "*/")
+			if end < 0 {
+				return hclToken{}, l.errorf("unterminated comment")
+			}
+			l.line += strings.Count(l.src[l.pos:l.pos+2+end], "\n")
+			l.pos += end + 4
+		case c == '\n':
+			l.pos++
+			l.line++
+			return hclToken{kind: hclNewline, line: l.line - 1}, nil
+		case c == '"':
+			return l.string()
+		case strings.HasPrefix(l.src[l.pos:], "<<"):
+			return l.heredoc()
+		case isIdentStart(c):
+			start := l.pos
+			for l.pos < len(l.src) && (isIdentStart(l.src[l.pos]) || isDigit(l.src[l.pos]) || l.src[l.pos] == '-') {
+				l.pos++
+			}
+			return hclToken{kind: hclIdent, text: l.src[start:l.pos], line: l.line}, nil
+		case isDigit(c):
+			start := l.pos
+			for l.pos < len(l.src) && (isDigit(l.src[l.pos]) || l.src[l.pos] == '.') {
+				l.pos++
+			}
+			return hclToken{kind: hclNumber, text: l.src[start:l.pos], line: l.line}, nil
+		default:
+			l.pos++
+			return hclToken{kind: hclPunct, text: string(c), line: l.line}, nil
+		}
+	}
+	return hclToken{kind: hclEOF, line: l.line}, nil
+}
+
+func (l *hclLexer) string() (hclToken, error) {
+	start := l.pos
+	l.pos++ // opening quote
+	for l.pos < len(l.src) {
+		switch l.src[l.pos] {
+		case '\\':
+			l.pos += 2
+		case '\n':
+			return hclToken{}, l.errorf("unterminated string")
+		case '"':
+			l.pos++
+			raw := l.src[start:l.pos]
+			tok := hclToken{kind: hclString, line: l.line, known: !strings.Contains(raw, "${")}
+			s, err := strconv.Unquote(raw)
+			if err != nil {
+				s, tok.known = raw, false
+			}
+			tok.text = s
+			return tok, nil
+		default:
+			l.pos++
+		}
+	}
+	return hclToken{}, l.errorf("unterminated string")
+}
+
+// heredoc reads a <<EOF or <<-EOF string, which is never a key or table name.
+func (l *hclLexer) heredoc() (hclToken, error) {
+	line := l.line
+	header, rest, ok := strings.Cut(l.src[l.pos:], "\n")
+	marker := strings.TrimSpace(strings.TrimLeft(header, "<-"))
+	if !ok || marker == "" {
+		return hclToken{}, l.errorf("invalid heredoc")
+	}
+	l.pos += len(header) + 1
+	l.line++
+	for rest != "" {
+		text, after, _ := strings.Cut(rest, "\n")
+		l.pos += len(text) + 1
+		l.line++
+		if strings.TrimSpace(text) == marker {
+			l.pos--
+			l.line--
+			return hclToken{kind: hclString, line: line}, nil
+		}
+		rest = after
+	}
+	return hclToken{}, l.errorf("unterminated heredoc %s", marker)
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
+
+// hclBlock is a block such as resource "aws_dynamodb_table" "orders" { ... }.
+type hclBlock struct {
+	typ    string
+	labels []string
+	attrs  map[string]hclValue
+	blocks []*hclBlock
+}
+
+// hclValue is the value of an attribute; known is false for expressions.
+type hclValue struct {
+	text  string
+	known bool
+}
+
+func (b *hclBlock) str(name string) string {
+	if v := b.attrs[name]; v.known {
+		return v.text
+	}
+	return ""
+}
+
+func (b *hclBlock) children(typ string) []*hclBlock {
+	var out []*hclBlock
+	for _, c := range b.blocks {
+		if c.typ == typ {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+type hclParser struct {
+	lex *hclLexer
+	tok hclToken
+}
+
+func (p *hclParser) advance() error {
+	tok, err := p.lex.next()
+	p.tok = tok
+	return err
+}
+
+func parseHCL(src string) (*hclBlock, error) {
+	p := &hclParser{lex: &hclLexer{src: src, line: 1}}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	return p.body(&hclBlock{}, false)
+}
+
+// body parses attributes and blocks into b, up to the closing brace if nested.
+func (p *hclParser) body(b *hclBlock, nested bool) (*hclBlock, error) {
+	b.attrs = make(map[string]hclValue)
+	for {
+		switch {
+		case p.tok.kind == hclNewline:
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			continue
+		case p.tok.kind == hclEOF:
+			if nested {
+				return nil, p.lex.errorf("missing } of %s block", b.typ)
+			}
+			return b, nil
+		case p.tok.kind == hclPunct && p.tok.text == "}" && nested:
+			return b, p.advance()
+		case p.tok.kind != hclIdent:
+			return nil, fmt.Errorf("line %d: unexpected %q", p.tok.line, p.tok.text)
+		}
+
+		name := p.tok.text
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		if p.tok.kind == hclPunct && p.tok.text == "=" {
+			v, err := p.expression()
+			if err != nil {
+				return nil, err
+			}
+			b.attrs[name] = v
+			continue
+		}
+
+		child := &hclBlock{typ: name}
+		for p.tok.kind == hclString || p.tok.kind == hclIdent {
+			child.labels = append(child.labels, p.tok.text)
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+		}
+		if p.tok.kind != hclPunct || p.tok.text != "{" {
+			return nil, fmt.Errorf("line %d: expected { after %s", p.tok.line, name)
+		}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		if _, err := p.body(child, true); err != nil {
+			return nil, err
+		}
+		b.blocks = append(b.blocks, child)
+	}
+}
+
+// expression reads the tokens of an attribute value, up to the end of the line or
+// the closing brace of a one-line block.
+func (p *hclParser) expression() (hclValue, error) {
+	var toks []hclToken
+	depth := 0
+	for {
+		if err := p.advance(); err != nil {
+			return hclValue{}, err
+		}
+		t := p.tok
+		if t.kind == hclEOF || (depth == 0 && (t.kind == hclNewline || (t.kind == hclPunct && t.text == "}"))) {
+			break
+		}
+		if t.kind == hclPunct {
+			switch t.text {
+			case "(", "[", "{":
+				depth++
+			case ")", "]", "}":
+				depth--
+			}
+		}
+		toks = append(toks, t)
+	}
+	if len(toks) != 1 {
+		return hclValue{}, nil
+	}
+	switch t := toks[0]; t.kind {
+	case hclString:
+		return hclValue{text: t.text, known: t.known}, nil
+	case hclNumber:
+		return hclValue{text: t.text, known: true}, nil
+	case hclIdent:
+		if t.text == "true" || t.text == "false" {
+			return hclValue{text: t.text, known: true}, nil
+		}
+	}
+	return hclValue{}, nil
+}
+
+func parseTerraform(data []byte) ([]TemplateTable, error) {
+	root, err := parseHCL(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("parsing Terraform: %w", err)
+	}
+	var tables []TemplateTable
+	for _, res := range root.children("resource") {
+		if len(res.labels) != 2 || res.labels[0] != tfTableType {
+			continue
+		}
+		tables = append(tables, TemplateTable{Resource: tfTableType + "." + res.labels[1], Table: tfTable(res)})
+	}
+	return tables, nil
+}
+
+func tfTable(res *hclBlock) schema.Table {
+	kinds := make(map[string]string)
+	for _, a := range res.children("attribute") {
+		kinds[a.str("name")] = a.str("type")
+	}
+	key := func(name string) *schema.KeyDef {
+		if name == "" {
+			return nil
+		}
+		return &schema.KeyDef{Name: name, Kind: kinds[name]}
+	}
+
+	t := schema.Table{Name: res.str("name"), SortKey: key(res.str("range_key"))}
+	if pk := key(res.str("hash_key")); pk != nil {
+		t.PartitionKey = *pk
+	}
+	for _, g := range res.children("global_secondary_index") {
+		gsi := schema.GSI{Name: g.str("name"), SortKey: key(g.str("range_key"))}
+		if pk := key(g.str("hash_key")); pk != nil {
+			gsi.PartitionKey = *pk
+		}
+		t.GSIs = append(t.GSIs, gsi)
+	}
+	for _, ttl := range res.children("ttl") {
+		// enabled defaults to false.
+		if ttl.str("enabled") == "true" {
+			t.TimeToLiveKey = ttl.str("attribute_name")
+		}
+	}
+	return t
+}
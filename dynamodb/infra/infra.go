@@ -0,0 +1,103 @@
+// Package infra generates infrastructure definitions of DynamoDB tables from their
+// schema, and checks existing templates against it.
+//
+// [Generate] renders a table as a CloudFormation template (YAML or JSON), a Terraform
+// aws_dynamodb_table resource or a CDK construct in TypeScript. ddbgen writes them
+// next to schema_dynamodb.yaml with ddb gen --infra.
+//
+// [ParseTemplate] reads the tables of a CloudFormation or Terraform template and
+// [Verify] reports where they disagree with the Go definitions, e.g. in CI with
+// ddb schema verify-infra. Check CDK apps by verifying the templates cdk synth writes.
+package infra
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/acksell/bezos/dynamodb/schema"
+)
+
+// Format is an infrastructure definition format.
+type Format string
+
+const (
+	CloudFormationYAML Format = "cloudformation"
+	CloudFormationJSON Format = "cloudformation-json"
+	Terraform          Format = "terraform"
+	CDK                Format = "cdk"
+)
+
+// Formats lists the formats [Generate] supports.
+func Formats() []Format {
+	return []Format{CloudFormationYAML, CloudFormationJSON, Terraform, CDK}
+}
+
+// ParseFormat returns the format named s.
+func ParseFormat(s string) (Format, error) {
+	for _, f := range Formats() {
+		if string(f) == s {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("unknown infra format %q, expected one of %s", s, formatList())
+}
+
+func formatList() string {
+	names := make([]string, 0, len(Formats()))
+	for _, f := range Formats() {
+		names = append(names, string(f))
+	}
+	return strings.Join(names, ", ")
+}
+
+// Generate renders the definition of table t in format f. Tables are billed per
+// request and their GSIs project all attributes, since entities are decoded from
+// GSI query results.
+func Generate(t schema.Table, f Format) ([]byte, error) {
+	switch f {
+	case CloudFormationYAML, CloudFormationJSON:
+		return cloudFormation(t, f == CloudFormationJSON)
+	case Terraform:
+		return terraform(t), nil
+	case CDK:
+		return cdk(t), nil
+	default:
+		return nil, fmt.Errorf("unknown infra format %q, expected one of %s", f, formatList())
+	}
+}
+
+// FileName is the name of the file holding table t in format f,
+// e.g. "orders.cfn.yaml" or "orders.tf".
+func FileName(t schema.Table, f Format) string {
+	name := t.Name
+	switch f {
+	case CloudFormationJSON:
+		return name + ".cfn.json"
+	case Terraform:
+		return name + ".tf"
+	case CDK:
+		return name + ".cdk.ts"
+	default:
+		return name + ".cfn.yaml"
+	}
+}
+
+// attributes returns the key attributes of the table and its GSIs, without duplicates.
+func attributes(t schema.Table) []schema.KeyDef {
+	var attrs []schema.KeyDef
+	seen := make(map[string]bool)
+	add := func(k *schema.KeyDef) {
+		if k == nil || seen[k.Name] {
+			return
+		}
+		seen[k.Name] = true
+		attrs = append(attrs, *k)
+	}
+	add(&t.PartitionKey)
+	add(t.SortKey)
+	for _, g := range t.GSIs {
+		add(&g.PartitionKey)
+		add(g.SortKey)
+	}
+	return attrs
+}
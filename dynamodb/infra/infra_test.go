@@ -0,0 +1,208 @@
+package infra_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/acksell/bezos/dynamodb/infra"
+	"github.com/acksell/bezos/dynamodb/schema"
+)
+
+var ordersTable = schema.Table{
+	Name:          "orders",
+	PartitionKey:  schema.KeyDef{Name: "pk", Kind: "S"},
+	SortKey:       &schema.KeyDef{Name: "sk", Kind: "S"},
+	TimeToLiveKey: "expiresAt",
+	GSIs: []schema.GSI{
+		{Name: "ByStatus", PartitionKey: schema.KeyDef{Name: "gsi1pk", Kind: "S"}, SortKey: &schema.KeyDef{Name: "gsi1sk", Kind: "N"}},
+		{Name: "ByCustomer", PartitionKey: schema.KeyDef{Name: "gsi2pk", Kind: "S"}},
+	},
+}
+
+func TestGenerate_RoundTrip(t *testing.T) {
+	for _, f := range []infra.Format{infra.CloudFormationYAML, infra.CloudFormationJSON, infra.Terraform} {
+		t.Run(string(f), func(t *testing.T) {
+			out, err := infra.Generate(ordersTable, f)
+			if err != nil {
+				t.Fatalf("Generate() error = %v", err)
+			}
+			tables, err := infra.ParseTemplate(out)
+			if err != nil {
+				t.Fatalf("ParseTemplate() error = %v\n%s", err, out)
+			}
+			if len(tables) != 1 {
+				t.Fatalf("expected 1 table, got %d\n%s", len(tables), out)
+			}
+			if ms := infra.Verify([]schema.Table{ordersTable}, tables, true); len(ms) > 0 {
+				t.Errorf("generated template doesn't verify: %v\n%s", ms, out)
+			}
+		})
+	}
+}
+
+func TestGenerate_CDK(t *testing.T) {
+	out, err := infra.Generate(ordersTable, infra.CDK)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"export class OrdersTable extends Construct",
+		"sortKey: { name: 'sk', type: dynamodb.AttributeType.STRING }",
+		"timeToLiveAttribute: 'expiresAt'",
+		"indexName: 'ByStatus'",
+		"sortKey: { name: 'gsi1sk', type: dynamodb.AttributeType.NUMBER }",
+	} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("CDK construct is missing %q:\n%s", want, out)
+		}
+	}
+	// Props can't change the name, keys or TTL attribute.
+	src := string(out)
+	if !strings.Contains(src, "Omit<dynamodb.TableProps, 'tableName' | 'partitionKey' | 'sortKey' | 'timeToLiveAttribute'>") {
+		t.Errorf("expected props without the name, keys and TTL attribute:\n%s", out)
+	}
+	if spread := strings.Index(src, "...props"); spread < 0 || spread > strings.Index(src, "tableName: 'orders'") {
+		t.Errorf("expected props to be spread before the name and keys:\n%s", out)
+	}
+}
+
+const handwrittenTerraform = `
+variable "env" {}
+
+# Orders, see orders.go.
+resource "aws_dynamodb_table" "orders" {
+  name      = "orders"
+  hash_key  = "pk"
+  range_key = "sk"
+  billing_mode = var.env == "prod" ? "PROVISIONED" : "PAY_PER_REQUEST"
+  tags = {
+    Team = "checkout"
+  }
+
+  attribute {
+    name = "pk"
+    type = "S"
+  }
+  attribute {
+    name = "sk"
+    type = "N"
+  }
+  attribute {
+    name = "gsi1pk"
+    type = "S"
+  }
+
+  global_secondary_index {
+    name            = "ByStatus"
+    hash_key        = "gsi1pk"
+    projection_type = "ALL"
+  }
+  global_secondary_index {
+    name            = "Legacy"
+    hash_key        = "gsi1pk"
+    projection_type = "KEYS_ONLY"
+  }
+
+  ttl { enabled = false }
+}
+
+resource "aws_dynamodb_table" "events" {
+  name     = "events-${var.env}"
+  hash_key = "pk"
+  policy   = <<EOF
+{"Version": "2012-10-17"}
+EOF
+}
+`
+
+func TestVerify_Terraform(t *testing.T) {
+	tables, err := infra.ParseTemplate([]byte(handwrittenTerraform))
+	if err != nil {
+		t.Fatalf("ParseTemplate() error = %v", err)
+	}
+	if len(tables) != 2 || tables[1].Resource != "aws_dynamodb_table.events" || tables[1].Table.Name != "" {
+		t.Fatalf("unexpected tables %+v", tables)
+	}
+
+	got := mismatchStrings(infra.Verify([]schema.Table{ordersTable, {Name: "events"}}, tables, true))
+	want := []string{
+		"orders/sortKey: Go defines sk (S), template has sk (N)",
+		"orders/ttl: Go defines expiresAt, template has none",
+		"orders/gsi:ByStatus/sortKey: Go defines gsi1sk (N), template has none",
+		"orders/gsi:ByCustomer: Go defines the GSI, template has none",
+		"orders/gsi:Legacy: Go defines none, template has the GSI",
+		"events: Go defines the table, template has none",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Verify() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestVerify_OnlyDeclaredTables(t *testing.T) {
+	tables, err := infra.ParseTemplate([]byte(handwrittenTerraform))
+	if err != nil {
+		t.Fatalf("ParseTemplate() error = %v", err)
+	}
+
+	// A template of one stack doesn't declare the tables of the others.
+	for _, m := range infra.Verify([]schema.Table{ordersTable, {Name: "events"}}, tables, false) {
+		if m.Table != "orders" {
+			t.Errorf("unexpected mismatch %s", m)
+		}
+	}
+	if ms := infra.Verify([]schema.Table{{Name: "events"}}, tables, false); len(ms) > 0 {
+		t.Errorf("expected no mismatches for a table no template declares, got %q", mismatchStrings(ms))
+	}
+}
+
+const handwrittenCloudFormation = `
+Parameters:
+  Env:
+    Type: String
+Resources:
+  Orders:
+    Type: AWS::DynamoDB::Table
+    Properties:
+      TableName: orders
+      BillingMode: PAY_PER_REQUEST
+      AttributeDefinitions:
+        - {AttributeName: pk, AttributeType: S}
+        - {AttributeName: sk, AttributeType: S}
+      KeySchema:
+        - {AttributeName: pk, KeyType: HASH}
+        - {AttributeName: sk, KeyType: RANGE}
+      TimeToLiveSpecification:
+        AttributeName: expiresAt
+        Enabled: true
+  Events:
+    Type: AWS::DynamoDB::Table
+    Properties:
+      TableName: !Sub events-${Env}
+      KeySchema:
+        - {AttributeName: pk, KeyType: HASH}
+  Queue:
+    Type: AWS::SQS::Queue
+`
+
+func TestVerify_CloudFormation(t *testing.T) {
+	tables, err := infra.ParseTemplate([]byte(handwrittenCloudFormation))
+	if err != nil {
+		t.Fatalf("ParseTemplate() error = %v", err)
+	}
+	if len(tables) != 2 || tables[0].Resource != "Events" || tables[0].Table.Name != "" {
+		t.Fatalf("unexpected tables %+v", tables)
+	}
+
+	got := mismatchStrings(infra.Verify([]schema.Table{ordersTable}, tables, true))
+	if len(got) != 2 || !strings.HasPrefix(got[0], "orders/gsi:ByStatus:") || !strings.HasPrefix(got[1], "orders/gsi:ByCustomer:") {
+		t.Errorf("unexpected mismatches %q", got)
+	}
+}
+
+func mismatchStrings(ms []infra.Mismatch) []string {
+	out := make([]string, len(ms))
+	for i, m := range ms {
+		out[i] = m.String()
+	}
+	return out
+}
@@ -0,0 +1,82 @@
+package infra
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/acksell/bezos/dynamodb/schema"
+)
+
+const tfTableType = "aws_dynamodb_table"
+
+func terraform(t schema.Table) []byte {
+	var b strings.Builder
+	b.WriteString("# Code generated by ddbgen. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&b, "resource %q %q {\n", tfTableType, tfLabel(t.Name))
+
+	attrs := [][2]string{
+		{"name", strconv.Quote(t.Name)},
+		{"billing_mode", `"PAY_PER_REQUEST"`},
+		{"hash_key", strconv.Quote(t.PartitionKey.Name)},
+	}
+	if t.SortKey != nil {
+		attrs = append(attrs, [2]string{"range_key", strconv.Quote(t.SortKey.Name)})
+	}
+	writeTFAttrs(&b, "  ", attrs)
+
+	for _, a := range attributes(t) {
+		b.WriteString("\n  attribute {\n")
+		writeTFAttrs(&b, "    ", [][2]string{{"name", strconv.Quote(a.Name)}, {"type", strconv.Quote(a.Kind)}})
+		b.WriteString("  }\n")
+	}
+	for _, g := range t.GSIs {
+		attrs := [][2]string{
+			{"name", strconv.Quote(g.Name)},
+			{"hash_key", strconv.Quote(g.PartitionKey.Name)},
+		}
+		if g.SortKey != nil {
+			attrs = append(attrs, [2]string{"range_key", strconv.Quote(g.SortKey.Name)})
+		}
+		attrs = append(attrs, [2]string{"projection_type", `"ALL"`})
+		b.WriteString("\n  global_secondary_index {\n")
+		writeTFAttrs(&b, "    ", attrs)
+		b.WriteString("  }\n")
+	}
+	if t.TimeToLiveKey != "" {
+		b.WriteString("\n  ttl {\n")
+		writeTFAttrs(&b, "    ", [][2]string{{"attribute_name", strconv.Quote(t.TimeToLiveKey)}, {"enabled", "true"}})
+		b.WriteString("  }\n")
+	}
+	b.WriteString("}\n")
+	return []byte(b.String())
+}
+
+// writeTFAttrs writes attributes with their equals signs aligned, like terraform fmt.
+func writeTFAttrs(b *strings.Builder, indent string, attrs [][2]string) {
+	width := 0
+	for _, a := range attrs {
+		width = max(width, len(a[0]))
+	}
+	for _, a := range attrs {
+		fmt.Fprintf(b, "%s%-*s = %s\n", indent, width, a[0], a[1])
+	}
+}
+
+// tfLabel turns a table name into a resource name, e.g. "single-table" into "single_table".
+func tfLabel(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) || r == '_' {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte('_')
+		}
+	}
+	label := b.String()
+	if label == "" || unicode.IsDigit(rune(label[0])) {
+		label = "table_" + label
+	}
+	return label
+}
@@ -0,0 +1,261 @@
+package infra
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/acksell/bezos/dynamodb/schema"
+	"gopkg.in/yaml.v3"
+)
+
+// TemplateTable is a table defined in an infrastructure template.
+type TemplateTable struct {
+	// Resource is the logical ID or Terraform resource name of the table.
+	Resource string
+	// Table holds the keys, GSIs and TTL attribute of the table. Its Name is empty
+	// if the template computes it, e.g. with !Sub or a Terraform variable.
+	Table schema.Table
+}
+
+// ParseTemplate reads the DynamoDB tables of a CloudFormation template, in YAML or
+// JSON, or of Terraform aws_dynamodb_table resources.
+func ParseTemplate(data []byte) ([]TemplateTable, error) {
+	if isTerraform(data) {
+		return parseTerraform(data)
+	}
+	return parseCloudFormation(data)
+}
+
+func isTerraform(data []byte) bool {
+	trimmed := bytes.TrimSpace(data)
+	return len(trimmed) > 0 && trimmed[0] != '{' && bytes.Contains(data, []byte(`"`+tfTableType+`"`))
+}
+
+// Mismatch is a difference between a table's Go definition and its template.
+type Mismatch struct {
+	Table string `json:"table"`
+	// Path is the part of the table that differs, e.g. "sortKey" or "gsi:ByStatus",
+	// or empty if the table is missing from the template.
+	Path string `json:"path,omitempty"`
+	Want string `json:"want"`
+	Got  string `json:"got"`
+}
+
+func (m Mismatch) String() string {
+	where := m.Table
+	if m.Path != "" {
+		where += "/" + m.Path
+	}
+	return fmt.Sprintf("%s: Go defines %s, template has %s", where, m.Want, m.Got)
+}
+
+// Verify compares the Go definitions of tables with the tables of templates, matched
+// by name. Tables of the templates that aren't defined in Go are ignored, tables
+// defined in Go must have the same keys, GSIs and TTL attribute in the templates.
+//
+// Templates often only declare some of the tables, e.g. one stack each, so tables
+// defined in Go that no template declares are only reported if requireAll is set.
+func Verify(defined []schema.Table, templates []TemplateTable, requireAll bool) []Mismatch {
+	byName := make(map[string]schema.Table)
+	for _, tt := range templates {
+		if tt.Table.Name != "" {
+			byName[tt.Table.Name] = tt.Table
+		}
+	}
+
+	var ms []Mismatch
+	for _, want := range defined {
+		got, ok := byName[want.Name]
+		if !ok {
+			if requireAll {
+				ms = append(ms, Mismatch{Table: want.Name, Want: "the table", Got: "none"})
+			}
+			continue
+		}
+		add := func(path, w, g string) {
+			if w != g {
+				ms = append(ms, Mismatch{Table: want.Name, Path: path, Want: w, Got: g})
+			}
+		}
+		add("partitionKey", keyString(&want.PartitionKey), keyString(&got.PartitionKey))
+		add("sortKey", keyString(want.SortKey), keyString(got.SortKey))
+		add("ttl", orNone(want.TimeToLiveKey), orNone(got.TimeToLiveKey))
+
+		gotGSIs := make(map[string]schema.GSI)
+		for _, g := range got.GSIs {
+			gotGSIs[g.Name] = g
+		}
+		for _, w := range want.GSIs {
+			path := "gsi:" + w.Name
+			g, ok := gotGSIs[w.Name]
+			if !ok {
+				add(path, "the GSI", "none")
+				continue
+			}
+			delete(gotGSIs, w.Name)
+			add(path+"/partitionKey", keyString(&w.PartitionKey), keyString(&g.PartitionKey))
+			add(path+"/sortKey", keyString(w.SortKey), keyString(g.SortKey))
+		}
+		// GSIs that Go doesn't know about don't get keys written by the SDK.
+		for _, name := range sortedKeys(gotGSIs) {
+			add("gsi:"+name, "none", "the GSI")
+		}
+	}
+	return ms
+}
+
+func keyString(k *schema.KeyDef) string {
+	if k == nil || k.Name == "" {
+		return "none"
+	}
+	kind := k.Kind
+	if kind == "" {
+		kind = "no attribute definition"
+	}
+	return k.Name + " (" + kind + ")"
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "none"
+	}
+	return s
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// =============================================================================
+// CloudFormation
+// =============================================================================
+
+func parseCloudFormation(data []byte) ([]TemplateTable, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parsing CloudFormation template: %w", err)
+	}
+	root, _ := nodeValue(&doc).(map[string]any)
+	resources, _ := root["Resources"].(map[string]any)
+	if resources == nil {
+		return nil, fmt.Errorf("parsing CloudFormation template: no Resources")
+	}
+
+	var tables []TemplateTable
+	for _, id := range sortedKeys(resources) {
+		res, _ := resources[id].(map[string]any)
+		typ, _ := res["Type"].(string)
+		if typ != cfnTableType && typ != "AWS::DynamoDB::GlobalTable" {
+			continue
+		}
+		props, _ := res["Properties"].(map[string]any)
+		tables = append(tables, TemplateTable{Resource: id, Table: cfnTable(props)})
+	}
+	return tables, nil
+}
+
+func cfnTable(props map[string]any) schema.Table {
+	kinds := make(map[string]string)
+	for _, a := range list(props["AttributeDefinitions"]) {
+		name, _ := a["AttributeName"].(string)
+		kind, _ := a["AttributeType"].(string)
+		kinds[name] = kind
+	}
+
+	var t schema.Table
+	t.Name, _ = props["TableName"].(string)
+	pk, sk := cfnKeys(props["KeySchema"], kinds)
+	if pk != nil {
+		t.PartitionKey = *pk
+	}
+	t.SortKey = sk
+	for _, g := range list(props["GlobalSecondaryIndexes"]) {
+		gsi := schema.GSI{}
+		gsi.Name, _ = g["IndexName"].(string)
+		pk, sk := cfnKeys(g["KeySchema"], kinds)
+		if pk != nil {
+			gsi.PartitionKey = *pk
+		}
+		gsi.SortKey = sk
+		t.GSIs = append(t.GSIs, gsi)
+	}
+	if ttl, ok := props["TimeToLiveSpecification"].(map[string]any); ok && ttl["Enabled"] == true {
+		t.TimeToLiveKey, _ = ttl["AttributeName"].(string)
+	}
+	return t
+}
+
+func cfnKeys(keySchema any, kinds map[string]string) (pk, sk *schema.KeyDef) {
+	for _, k := range list(keySchema) {
+		name, _ := k["AttributeName"].(string)
+		def := &schema.KeyDef{Name: name, Kind: kinds[name]}
+		switch k["KeyType"] {
+		case "HASH":
+			pk = def
+		case "RANGE":
+			sk = def
+		}
+	}
+	return pk, sk
+}
+
+// list returns the maps of a list value, skipping anything else.
+func list(v any) []map[string]any {
+	items, _ := v.([]any)
+	var out []map[string]any
+	for _, item := range items {
+		if m, ok := item.(map[string]any); ok {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+// nodeValue converts a YAML node to maps, lists, strings and bools. Values with
+// CloudFormation tags such as !Ref or !Sub become nil, as they are computed on
+// deployment.
+func nodeValue(n *yaml.Node) any {
+	switch n.Kind {
+	case yaml.DocumentNode:
+		if len(n.Content) == 0 {
+			return nil
+		}
+		return nodeValue(n.Content[0])
+	case yaml.AliasNode:
+		return nodeValue(n.Alias)
+	case yaml.MappingNode:
+		m := make(map[string]any, len(n.Content)/2)
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			m[n.Content[i].Value] = nodeValue(n.Content[i+1])
+		}
+		return m
+	case yaml.SequenceNode:
+		if isIntrinsic(n) {
+			return nil
+		}
+		items := make([]any, len(n.Content))
+		for i, c := range n.Content {
+			items[i] = nodeValue(c)
+		}
+		return items
+	default:
+		if isIntrinsic(n) {
+			return nil
+		}
+		if n.Tag == "!!bool" {
+			return n.Value == "true"
+		}
+		return n.Value
+	}
+}
+
+func isIntrinsic(n *yaml.Node) bool {
+	return strings.HasPrefix(n.Tag, "!") && !strings.HasPrefix(n.Tag, "!!")
+}
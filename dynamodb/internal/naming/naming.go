@@ -0,0 +1,31 @@
+// Package naming derives identifiers from DynamoDB names, shared by the code and
+// infrastructure generators.
+package naming
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Exported turns a name like "single-table" into an exported CamelCase identifier,
+// "SingleTable". Names that would start with a digit, or are empty, get a "Table" prefix.
+func Exported(name string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	ident := b.String()
+	if ident == "" || unicode.IsDigit(rune(ident[0])) {
+		ident = "Table" + ident
+	}
+	return ident
+}
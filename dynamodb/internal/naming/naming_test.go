@@ -0,0 +1,18 @@
+package naming
+
+import "testing"
+
+func TestExported(t *testing.T) {
+	tests := map[string]string{
+		"single-table":  "SingleTable",
+		"orders":        "Orders",
+		"my_app.events": "MyAppEvents",
+		"2024-archive":  "Table2024Archive",
+		"":              "Table",
+	}
+	for name, want := range tests {
+		if got := Exported(name); got != want {
+			t.Errorf("Exported(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,679 @@
+package scaffold
+
+import (
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/acksell/bezos/dynamodb/index"
+	"github.com/acksell/bezos/dynamodb/schema"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// keyDelimiter separates the segments of composite keys, e.g. "TENANT#t1#ORDER#o1".
+const keyDelimiter = "#"
+
+// labelPattern matches the key segments that are literal when every item of a
+// cluster has them, e.g. ORDER or GSI1. Other segments become field references.
+var labelPattern = regexp.MustCompile(`^[A-Z][A-Z_]*[0-9]*$`)
+
+// typeAttributes are attributes that commonly store the entity type, used to name
+// the entities.
+var typeAttributes = []string{"type", "entityType", "entity_type", "_type", "__typename"}
+
+var fieldRefPattern = regexp.MustCompile(`\{([^}:]+)`)
+
+type item = map[string]types.AttributeValue
+
+// Infer clusters the sampled items of table t into entities. Items whose keys have
+// the same number of segments and start with the same label are one entity. Without
+// items it returns a placeholder entity whose fields are the key attributes.
+func Infer(t schema.Table, items []map[string]types.AttributeValue) Table {
+	out := Table{Def: t, Sampled: len(items)}
+	groups := make(map[string][]item)
+	var order []string
+	for _, it := range items {
+		key, ok := clusterKey(t, it)
+		if !ok {
+			continue
+		}
+		if _, seen := groups[key]; !seen {
+			order = append(order, key)
+		}
+		groups[key] = append(groups[key], it)
+	}
+
+	names := make(map[string]bool)
+	for _, key := range order {
+		e := inferEntity(t, groups[key])
+		e.Name = uniqueName(e.Name, names)
+		out.Entities = append(out.Entities, e)
+	}
+	if len(out.Entities) == 0 {
+		out.Entities = []Entity{placeholder(t)}
+	}
+	return out
+}
+
+func clusterKey(t schema.Table, it item) (string, bool) {
+	pk, ok := keyString(it[t.PartitionKey.Name])
+	if !ok {
+		return "", false
+	}
+	key := shape(pk, t.PartitionKey.Kind)
+	if t.SortKey != nil {
+		sk, ok := keyString(it[t.SortKey.Name])
+		if !ok {
+			return "", false
+		}
+		key += " " + shape(sk, t.SortKey.Kind)
+	}
+	return key, true
+}
+
+// shape returns the number of segments of a key and its first segment if it's a label.
+func shape(key, kind string) string {
+	if kind != "S" {
+		return "*"
+	}
+	segs := strings.Split(key, keyDelimiter)
+	first := "*"
+	if labelPattern.MatchString(segs[0]) {
+		first = segs[0]
+	}
+	return fmt.Sprintf("%s/%d", first, len(segs))
+}
+
+// keyString returns the value of a key attribute as a string, binary values in base64.
+func keyString(av types.AttributeValue) (string, bool) {
+	switch v := av.(type) {
+	case *types.AttributeValueMemberS:
+		return v.Value, true
+	case *types.AttributeValueMemberN:
+		return v.Value, true
+	case *types.AttributeValueMemberB:
+		return base64.StdEncoding.EncodeToString(v.Value), true
+	}
+	return "", false
+}
+
+// inferrer infers the entity of a cluster of items.
+type inferrer struct {
+	t     schema.Table
+	items []item
+	// attrs are the attributes of the items other than keys and the TTL attribute.
+	attrs []string
+	// refs are the fields the key patterns reference, in order.
+	refs []string
+	// extra holds the fields that aren't attributes of the items but parts of their
+	// keys, with their value in each item.
+	extra       map[string]Field
+	extraValues map[string][]string
+}
+
+func inferEntity(t schema.Table, items []item) Entity {
+	in := &inferrer{t: t, items: items, extra: make(map[string]Field), extraValues: make(map[string][]string)}
+	keys := keyAttributes(t)
+	seen := make(map[string]bool)
+	for _, it := range items {
+		for name := range it {
+			if !keys[name] && name != t.TimeToLiveKey && !seen[name] {
+				seen[name] = true
+				in.attrs = append(in.attrs, name)
+			}
+		}
+	}
+	sort.Strings(in.attrs)
+
+	all := make([]int, len(items))
+	for i := range all {
+		all[i] = i
+	}
+	e := Entity{Items: len(items), Example: example(t, items[0])}
+	e.PartitionKey = in.pattern(t.PartitionKey, all)
+	if t.SortKey != nil {
+		e.SortKey = in.pattern(*t.SortKey, all)
+	}
+
+	for _, g := range t.GSIs {
+		// Items are only in a GSI if they have all of its key attributes.
+		var members []int
+		for i, it := range items {
+			_, hasPK := keyString(it[g.PartitionKey.Name])
+			hasSK := true
+			if g.SortKey != nil {
+				_, hasSK = keyString(it[g.SortKey.Name])
+			}
+			if hasPK && hasSK {
+				members = append(members, i)
+			}
+		}
+		if len(members) == 0 {
+			continue
+		}
+		m := GSIMapping{GSI: g.Name, PartitionKey: in.pattern(g.PartitionKey, members)}
+		if g.SortKey != nil {
+			m.SortKey = in.pattern(*g.SortKey, members)
+		}
+		if len(members) < len(items) {
+			if m.Include = in.inclusion(m, members); m.Include == nil {
+				e.Notes = append(e.Notes, fmt.Sprintf("only %d of %d sampled items are in %s, set Include to the rule", len(members), len(items), g.Name))
+			}
+		}
+		e.GSIs = append(e.GSIs, m)
+	}
+
+	e.Name = in.name(e)
+	e.Fields = in.fields()
+	return e
+}
+
+func keyAttributes(t schema.Table) map[string]bool {
+	keys := map[string]bool{t.PartitionKey.Name: true}
+	if t.SortKey != nil {
+		keys[t.SortKey.Name] = true
+	}
+	for _, g := range t.GSIs {
+		keys[g.PartitionKey.Name] = true
+		if g.SortKey != nil {
+			keys[g.SortKey.Name] = true
+		}
+	}
+	return keys
+}
+
+func example(t schema.Table, it item) string {
+	pk, _ := keyString(it[t.PartitionKey.Name])
+	s := fmt.Sprintf("%s %q", t.PartitionKey.Name, pk)
+	if t.SortKey != nil {
+		sk, _ := keyString(it[t.SortKey.Name])
+		s += fmt.Sprintf(", %s %q", t.SortKey.Name, sk)
+	}
+	return s
+}
+
+// pattern infers the pattern of key k from its values in the items idx. Segments
+// that are the same label in every item are literal, the others reference the
+// attribute holding the same value, or else a new field.
+func (in *inferrer) pattern(k schema.KeyDef, idx []int) string {
+	values := make([]string, len(idx))
+	for j, i := range idx {
+		values[j], _ = keyString(in.items[i][k.Name])
+	}
+	if k.Kind != "S" {
+		return "{" + in.reference(values, idx, k.Name, k.Kind) + "}"
+	}
+
+	segs := make([][]string, len(values))
+	n := -1
+	for j, v := range values {
+		segs[j] = strings.Split(v, keyDelimiter)
+		if n == -1 {
+			n = len(segs[j])
+		} else if n != len(segs[j]) {
+			n = 0
+		}
+	}
+	if n == 0 {
+		// Only happens for GSIs, whose keys don't decide the clusters.
+		return "{" + in.reference(values, idx, k.Name, k.Kind) + "}"
+	}
+
+	parts := make([]string, n)
+	prev := ""
+	for p := range parts {
+		col := make([]string, len(segs))
+		for j := range segs {
+			col[j] = segs[j][p]
+		}
+		if lit, ok := literal(col); ok {
+			parts[p], prev = lit, lit
+			continue
+		}
+		fallback := k.Name
+		switch {
+		case prev != "":
+			fallback = tagName(prev) + "ID"
+		case n > 1:
+			fallback = k.Name + strconv.Itoa(p+1)
+		}
+		parts[p] = "{" + in.reference(col, idx, fallback, k.Kind) + "}"
+		prev = ""
+	}
+	return strings.Join(parts, keyDelimiter)
+}
+
+// literal returns the label all values are equal to.
+func literal(values []string) (string, bool) {
+	for _, v := range values[1:] {
+		if v != values[0] {
+			return "", false
+		}
+	}
+	return values[0], labelPattern.MatchString(values[0])
+}
+
+// reference returns the field reference for a key segment with the values of the
+// items idx: a field with the same value in every item, or else a new field.
+func (in *inferrer) reference(values []string, idx []int, fallback, kind string) string {
+	pad := ""
+	if kind == "S" {
+		pad = padding(values)
+	}
+	if tag, numeric := in.match(values, idx); tag != "" {
+		if !numeric {
+			pad = ""
+		}
+		return in.ref(tag) + pad
+	}
+
+	typ := "string"
+	switch {
+	case kind == "B":
+		typ = "[]byte"
+	case allInts(values):
+		typ = "int64"
+	case kind == "N":
+		typ = "float64"
+	}
+	if typ != "int64" {
+		pad = ""
+	}
+	tag := fallback
+	for i := 2; in.taken(tag); i++ {
+		tag = fallback + strconv.Itoa(i)
+	}
+	in.extra[tag] = Field{Tag: tag, Type: typ}
+	all := make([]string, len(in.items))
+	for j, i := range idx {
+		all[i] = values[j]
+	}
+	in.extraValues[tag] = all
+	return in.ref(tag) + pad
+}
+
+func (in *inferrer) ref(tag string) string {
+	for _, r := range in.refs {
+		if r == tag {
+			return tag
+		}
+	}
+	in.refs = append(in.refs, tag)
+	return tag
+}
+
+func (in *inferrer) taken(tag string) bool {
+	if _, ok := in.extra[tag]; ok {
+		return true
+	}
+	i := sort.SearchStrings(in.attrs, tag)
+	return i < len(in.attrs) && in.attrs[i] == tag
+}
+
+// match returns the field whose value equals values in each of the items idx, and
+// whether it's a number compared to zero-padded values.
+func (in *inferrer) match(values []string, idx []int) (tag string, numeric bool) {
+	for _, r := range in.refs {
+		if vs, ok := in.extraValues[r]; ok && matchExtra(vs, values, idx) {
+			return r, false
+		}
+	}
+	for _, name := range in.attrs {
+		ok, num := true, false
+		for j, i := range idx {
+			switch av := in.items[i][name].(type) {
+			case *types.AttributeValueMemberS:
+				ok = av.Value == values[j]
+			case *types.AttributeValueMemberN:
+				if av.Value != values[j] {
+					a, errA := strconv.ParseInt(av.Value, 10, 64)
+					b, errB := strconv.ParseInt(values[j], 10, 64)
+					ok = errA == nil && errB == nil && a == b
+					num = true
+				}
+			default:
+				ok = false
+			}
+			if !ok {
+				break
+			}
+		}
+		if ok {
+			return name, num
+		}
+	}
+	return "", false
+}
+
+func matchExtra(extra, values []string, idx []int) bool {
+	for j, i := range idx {
+		if extra[i] != values[j] {
+			return false
+		}
+	}
+	return true
+}
+
+// padding returns the printf verb of zero-padded integer values, e.g. "%05d" for
+// "00042", or "" if they aren't.
+func padding(values []string) string {
+	if !allInts(values) {
+		return ""
+	}
+	width, padded := len(values[0]), false
+	for _, v := range values {
+		if len(v) != width || strings.HasPrefix(v, "-") {
+			return ""
+		}
+		padded = padded || (v[0] == '0' && len(v) > 1)
+	}
+	if !padded {
+		return ""
+	}
+	return fmt.Sprintf(":%%0%dd", width)
+}
+
+func allInts(values []string) bool {
+	for _, v := range values {
+		if _, err := strconv.ParseInt(v, 10, 64); err != nil {
+			return false
+		}
+	}
+	return len(values) > 0
+}
+
+// inclusion infers the rule of a sparse GSI from its members among the items: a
+// field only they have, preferably one of the GSI keys, or a value only they have.
+func (in *inferrer) inclusion(m GSIMapping, members []int) *index.Inclusion {
+	isMember := make([]bool, len(in.items))
+	for _, i := range members {
+		isMember[i] = true
+	}
+
+	var candidates []string
+	for _, p := range []string{m.PartitionKey, m.SortKey} {
+		for _, match := range fieldRefPattern.FindAllStringSubmatch(p, -1) {
+			candidates = append(candidates, match[1])
+		}
+	}
+	candidates = append(candidates, in.attrs...)
+	for _, tag := range candidates {
+		ok := true
+		for i := range in.items {
+			if in.present(tag, i) != isMember[i] {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return index.WhenPresent(tag)
+		}
+	}
+
+	for _, tag := range candidates {
+		value, ok := "", true
+		for i, it := range in.items {
+			v, scalar := scalarString(it[tag])
+			if isMember[i] {
+				if !scalar || (value != "" && v != value) {
+					ok = false
+					break
+				}
+				value = v
+			}
+		}
+		if !ok {
+			continue
+		}
+		for i, it := range in.items {
+			if v, _ := scalarString(it[tag]); !isMember[i] && v == value {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return index.WhenEquals(tag, typedValue(value, in.attrType(tag)))
+		}
+	}
+	return nil
+}
+
+// present reports whether a field of item i is set, i.e. not the zero value.
+func (in *inferrer) present(tag string, i int) bool {
+	if vs, ok := in.extraValues[tag]; ok {
+		return vs[i] != ""
+	}
+	switch av := in.items[i][tag].(type) {
+	case nil, *types.AttributeValueMemberNULL:
+		return false
+	case *types.AttributeValueMemberS:
+		return av.Value != ""
+	case *types.AttributeValueMemberN:
+		f, err := strconv.ParseFloat(av.Value, 64)
+		return err != nil || f != 0
+	case *types.AttributeValueMemberBOOL:
+		return av.Value
+	case *types.AttributeValueMemberB:
+		return len(av.Value) > 0
+	case *types.AttributeValueMemberSS:
+		return len(av.Value) > 0
+	case *types.AttributeValueMemberNS:
+		return len(av.Value) > 0
+	case *types.AttributeValueMemberL:
+		return len(av.Value) > 0
+	case *types.AttributeValueMemberM:
+		return len(av.Value) > 0
+	}
+	return true
+}
+
+func scalarString(av types.AttributeValue) (string, bool) {
+	switch v := av.(type) {
+	case *types.AttributeValueMemberS:
+		return v.Value, true
+	case *types.AttributeValueMemberN:
+		return v.Value, true
+	case *types.AttributeValueMemberBOOL:
+		return strconv.FormatBool(v.Value), true
+	}
+	return "", false
+}
+
+func typedValue(s, typ string) any {
+	switch typ {
+	case "int64":
+		n, _ := strconv.ParseInt(s, 10, 64)
+		return n
+	case "float64":
+		f, _ := strconv.ParseFloat(s, 64)
+		return f
+	case "bool":
+		return s == "true"
+	}
+	return s
+}
+
+// name names the entity after its type attribute, or else the label of its keys.
+func (in *inferrer) name(e Entity) string {
+	for _, attr := range typeAttributes {
+		if v, ok := in.constant(attr); ok && goName(v) != "" {
+			return goName(v)
+		}
+	}
+	for _, p := range []string{e.SortKey, e.PartitionKey} {
+		if l := label(p); l != "" {
+			return goName(l)
+		}
+	}
+	return tableIdent(in.t.Name) + "Item"
+}
+
+// constant returns the string value an attribute has in every item.
+func (in *inferrer) constant(attr string) (string, bool) {
+	value := ""
+	for i, it := range in.items {
+		s, ok := it[attr].(*types.AttributeValueMemberS)
+		if !ok || (i > 0 && s.Value != value) {
+			return "", false
+		}
+		value = s.Value
+	}
+	return value, true
+}
+
+// label returns the literal segment of a pattern that names what it identifies:
+// the one before its last field reference, or else its last literal segment.
+func label(pattern string) string {
+	segs := strings.Split(pattern, keyDelimiter)
+	lastLit, beforeRef := "", ""
+	for i, s := range segs {
+		if strings.HasPrefix(s, "{") {
+			if i > 0 && segs[i-1] != "" && !strings.HasPrefix(segs[i-1], "{") {
+				beforeRef = segs[i-1]
+			}
+		} else if s != "" {
+			lastLit = s
+		}
+	}
+	if beforeRef != "" {
+		return beforeRef
+	}
+	return lastLit
+}
+
+// fields returns the fields of the entity, the ones its keys reference first.
+func (in *inferrer) fields() []Field {
+	var fields []Field
+	names := make(map[string]bool)
+	add := func(f Field) {
+		f.Name = uniqueName(goName(f.Tag), names)
+		fields = append(fields, f)
+	}
+	for _, tag := range in.refs {
+		if f, ok := in.extra[tag]; ok {
+			add(f)
+		} else {
+			add(Field{Tag: tag, Type: in.attrType(tag)})
+		}
+	}
+	for _, tag := range in.attrs {
+		if !contains(in.refs, tag) {
+			add(Field{Tag: tag, Type: in.attrType(tag)})
+		}
+	}
+	return fields
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// attrType returns the Go type of an attribute, "any" if the items disagree.
+func (in *inferrer) attrType(tag string) string {
+	typ := ""
+	for _, it := range in.items {
+		t := goType(it[tag])
+		switch {
+		case t == "":
+		case typ == "":
+			typ = t
+		case typ != t:
+			if numeric(typ) && numeric(t) {
+				typ = strings.Replace(typ, "int64", "float64", 1)
+			} else {
+				return "any"
+			}
+		}
+	}
+	if typ == "" {
+		return "any"
+	}
+	return typ
+}
+
+func numeric(typ string) bool {
+	return strings.TrimPrefix(typ, "[]") == "int64" || strings.TrimPrefix(typ, "[]") == "float64"
+}
+
+func goType(av types.AttributeValue) string {
+	switch v := av.(type) {
+	case *types.AttributeValueMemberS:
+		return "string"
+	case *types.AttributeValueMemberN:
+		if allInts([]string{v.Value}) {
+			return "int64"
+		}
+		return "float64"
+	case *types.AttributeValueMemberBOOL:
+		return "bool"
+	case *types.AttributeValueMemberB:
+		return "[]byte"
+	case *types.AttributeValueMemberSS:
+		return "[]string"
+	case *types.AttributeValueMemberNS:
+		if allInts(v.Value) {
+			return "[]int64"
+		}
+		return "[]float64"
+	case *types.AttributeValueMemberBS:
+		return "[][]byte"
+	case *types.AttributeValueMemberL:
+		return "[]any"
+	case *types.AttributeValueMemberM:
+		return "map[string]any"
+	}
+	return ""
+}
+
+// placeholder returns an entity whose fields are the key attributes of table t.
+func placeholder(t schema.Table) Entity {
+	e := Entity{
+		Name:  tableIdent(t.Name) + "Item",
+		Notes: []string{"no items were sampled, replace the fields and key patterns with the entity's"},
+	}
+	names := make(map[string]bool)
+	ref := func(k schema.KeyDef) string {
+		if !contains(fieldTags(e.Fields), k.Name) {
+			e.Fields = append(e.Fields, Field{Name: uniqueName(goName(k.Name), names), Tag: k.Name, Type: kindType(k.Kind)})
+		}
+		return "{" + k.Name + "}"
+	}
+	e.PartitionKey = ref(t.PartitionKey)
+	if t.SortKey != nil {
+		e.SortKey = ref(*t.SortKey)
+	}
+	for _, g := range t.GSIs {
+		m := GSIMapping{GSI: g.Name, PartitionKey: ref(g.PartitionKey)}
+		if g.SortKey != nil {
+			m.SortKey = ref(*g.SortKey)
+		}
+		e.GSIs = append(e.GSIs, m)
+	}
+	return e
+}
+
+func fieldTags(fields []Field) []string {
+	tags := make([]string, len(fields))
+	for i, f := range fields {
+		tags[i] = f.Tag
+	}
+	return tags
+}
+
+func kindType(kind string) string {
+	switch kind {
+	case "N":
+		return "int64"
+	case "B":
+		return "[]byte"
+	}
+	return "string"
+}
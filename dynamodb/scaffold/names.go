@@ -0,0 +1,94 @@
+package scaffold
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// initialisms are the words Go names spell in upper case.
+var initialisms = map[string]bool{
+	"api": true, "arn": true, "db": true, "gsi": true, "html": true, "http": true,
+	"id": true, "ip": true, "json": true, "lsi": true, "pk": true, "sk": true,
+	"sql": true, "ttl": true, "uri": true, "url": true, "uuid": true, "xml": true,
+}
+
+// words splits a name into words at separators, case changes and digits, e.g.
+// "created_at" into created and at, and "gsi1PK" into gsi, 1 and PK.
+func words(s string) []string {
+	var ws []string
+	var cur []rune
+	rs := []rune(s)
+	for i, r := range rs {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			if len(cur) > 0 {
+				ws, cur = append(ws, string(cur)), nil
+			}
+			continue
+		}
+		if len(cur) > 0 {
+			prev := rs[i-1]
+			split := unicode.IsDigit(r) != unicode.IsDigit(prev) ||
+				(unicode.IsUpper(r) && unicode.IsLower(prev)) ||
+				(unicode.IsUpper(r) && unicode.IsUpper(prev) && i+1 < len(rs) && unicode.IsLower(rs[i+1]))
+			if split {
+				ws, cur = append(ws, string(cur)), nil
+			}
+		}
+		cur = append(cur, r)
+	}
+	if len(cur) > 0 {
+		ws = append(ws, string(cur))
+	}
+	return ws
+}
+
+// goName returns the exported Go name of an attribute or label, e.g. "TenantID" for
+// "tenantID" and "OrderItem" for "ORDER_ITEM".
+func goName(s string) string {
+	var b strings.Builder
+	for _, w := range words(s) {
+		lower := strings.ToLower(w)
+		if initialisms[lower] {
+			b.WriteString(strings.ToUpper(w))
+			continue
+		}
+		rs := []rune(lower)
+		rs[0] = unicode.ToUpper(rs[0])
+		b.WriteString(string(rs))
+	}
+	name := b.String()
+	if name != "" && unicode.IsDigit(rune(name[0])) {
+		name = "F" + name
+	}
+	return name
+}
+
+// tagName returns the attribute name for a label, e.g. "orderItem" for "ORDER_ITEM".
+func tagName(label string) string {
+	ws := words(label)
+	if len(ws) == 0 {
+		return ""
+	}
+	return strings.ToLower(ws[0]) + goName(strings.Join(ws[1:], "_"))
+}
+
+// tableIdent returns the Go name of a table, e.g. "Orders" for "orders".
+func tableIdent(name string) string {
+	ident := strings.TrimSuffix(goName(name), "Table")
+	if ident == "" {
+		ident = "Default"
+	}
+	return ident
+}
+
+// uniqueName returns name, or name with the lowest number from 2 not in used,
+// and adds it to used.
+func uniqueName(name string, used map[string]bool) string {
+	unique := name
+	for i := 2; used[unique]; i++ {
+		unique = name + strconv.Itoa(i)
+	}
+	used[unique] = true
+	return unique
+}
@@ -0,0 +1,60 @@
+// Package scaffold writes Go definitions for existing DynamoDB tables, to move them
+// onto bezos without writing everything by hand.
+//
+// [Infer] turns a table and a sample of its items into placeholder entities: items
+// are clustered by the literal prefixes of their keys, e.g. "TENANT#…" and "ORDER#…",
+// and each cluster becomes a struct with the sampled attributes as fields and key
+// patterns such as "TENANT#{tenantID}". [Source] renders the tables as Go source with
+// a table.TableDefinition per table and an indices.Add registration per entity.
+//
+// The result is a starting point: entity names, field types and patterns are guesses
+// to review before running ddb gen. ddb init-from-aws and ddb init-from-cfn use it.
+package scaffold
+
+import (
+	"github.com/acksell/bezos/dynamodb/index"
+	"github.com/acksell/bezos/dynamodb/schema"
+)
+
+// Table is a table with the entities inferred from its items.
+type Table struct {
+	Def      schema.Table
+	Entities []Entity
+	// Sampled is the number of items the entities were inferred from.
+	Sampled int
+}
+
+// Entity is a placeholder entity of a table.
+type Entity struct {
+	// Name is the Go type name of the entity.
+	Name string
+	// PartitionKey and SortKey are the key patterns, in the syntax of val.Fmt.
+	// The patterns of number and binary keys are a single field reference.
+	PartitionKey string
+	SortKey      string
+	Fields       []Field
+	GSIs         []GSIMapping
+	// Items is the number of sampled items of the entity, and Example the keys
+	// of one of them.
+	Items   int
+	Example string
+	// Notes are things to check by hand, rendered as TODO comments.
+	Notes []string
+}
+
+// Field is a field of an entity struct.
+type Field struct {
+	// Name is the Go field name, Tag the dynamodbav tag and Type the Go type.
+	Name string
+	Tag  string
+	Type string
+}
+
+// GSIMapping is the key patterns of an entity in a GSI of its table.
+type GSIMapping struct {
+	GSI          string
+	PartitionKey string
+	SortKey      string
+	// Include is set for sparse GSIs that only some sampled items are in.
+	Include *index.Inclusion
+}
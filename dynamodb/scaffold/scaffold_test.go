@@ -0,0 +1,244 @@
+package scaffold_test
+
+import (
+	"go/ast"
+	"go/types"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/acksell/bezos/dynamodb/index"
+	"github.com/acksell/bezos/dynamodb/scaffold"
+	"github.com/acksell/bezos/dynamodb/schema"
+	ddbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"golang.org/x/tools/go/packages"
+)
+
+var ordersTable = schema.Table{
+	Name:          "orders",
+	PartitionKey:  schema.KeyDef{Name: "pk", Kind: "S"},
+	SortKey:       &schema.KeyDef{Name: "sk", Kind: "S"},
+	TimeToLiveKey: "expiresAt",
+	GSIs: []schema.GSI{{
+		Name:         "ByStatus",
+		PartitionKey: schema.KeyDef{Name: "gsi1pk", Kind: "S"},
+		SortKey:      &schema.KeyDef{Name: "gsi1sk", Kind: "S"},
+	}},
+}
+
+func s(v string) ddbtypes.AttributeValue { return &ddbtypes.AttributeValueMemberS{Value: v} }
+func n(v string) ddbtypes.AttributeValue { return &ddbtypes.AttributeValueMemberN{Value: v} }
+
+func orderItem(tenant, order, status string, amount string) map[string]ddbtypes.AttributeValue {
+	item := map[string]ddbtypes.AttributeValue{
+		"pk":        s("TENANT#" + tenant),
+		"sk":        s("ORDER#" + order),
+		"orderID":   s(order),
+		"status":    s(status),
+		"amount":    n(amount),
+		"expiresAt": n("1700000000"),
+	}
+	if status == "open" {
+		item["gsi1pk"] = s("TENANT#" + tenant + "#STATUS#open")
+		item["gsi1sk"] = s("ORDER#" + order)
+	}
+	return item
+}
+
+func TestInfer(t *testing.T) {
+	items := []map[string]ddbtypes.AttributeValue{
+		orderItem("t1", "o1", "open", "10"),
+		{"pk": s("TENANT#t1"), "sk": s("CUSTOMER"), "name": s("Ada")},
+		orderItem("t2", "o2", "paid", "12.5"),
+		orderItem("t2", "o3", "open", "7"),
+		{"pk": s("TENANT#t2"), "sk": s("CUSTOMER"), "name": s("Bob")},
+		{"pk": s("TENANT#t1"), "sk": s("MSG#00042"), "seq": n("42")},
+		{"pk": s("TENANT#t1"), "sk": s("MSG#00107"), "seq": n("107")},
+	}
+
+	got := scaffold.Infer(ordersTable, items)
+	if got.Sampled != len(items) {
+		t.Errorf("Sampled = %d, want %d", got.Sampled, len(items))
+	}
+	want := []scaffold.Entity{
+		{
+			Name:         "Order",
+			PartitionKey: "TENANT#{tenantID}",
+			SortKey:      "ORDER#{orderID}",
+			Fields: []scaffold.Field{
+				{Name: "TenantID", Tag: "tenantID", Type: "string"},
+				{Name: "OrderID", Tag: "orderID", Type: "string"},
+				{Name: "Status", Tag: "status", Type: "string"},
+				{Name: "Amount", Tag: "amount", Type: "float64"},
+			},
+			GSIs: []scaffold.GSIMapping{{
+				GSI:          "ByStatus",
+				PartitionKey: "TENANT#{tenantID}#STATUS#{status}",
+				SortKey:      "ORDER#{orderID}",
+				Include:      index.WhenEquals("status", "open"),
+			}},
+			Items:   3,
+			Example: `pk "TENANT#t1", sk "ORDER#o1"`,
+		},
+		{
+			Name:         "Customer",
+			PartitionKey: "TENANT#{tenantID}",
+			SortKey:      "CUSTOMER",
+			Fields: []scaffold.Field{
+				{Name: "TenantID", Tag: "tenantID", Type: "string"},
+				{Name: "Name", Tag: "name", Type: "string"},
+			},
+			Items:   2,
+			Example: `pk "TENANT#t1", sk "CUSTOMER"`,
+		},
+		{
+			Name:         "Msg",
+			PartitionKey: "TENANT#{tenantID}",
+			SortKey:      "MSG#{seq:%05d}",
+			Fields: []scaffold.Field{
+				{Name: "TenantID", Tag: "tenantID", Type: "string"},
+				{Name: "Seq", Tag: "seq", Type: "int64"},
+			},
+			Items:   2,
+			Example: `pk "TENANT#t1", sk "MSG#00042"`,
+		},
+	}
+	if len(got.Entities) != len(want) {
+		t.Fatalf("got %d entities, want %d: %+v", len(got.Entities), len(want), got.Entities)
+	}
+	for i := range want {
+		if !reflect.DeepEqual(got.Entities[i], want[i]) {
+			t.Errorf("entity %d:\n got %+v\nwant %+v", i, got.Entities[i], want[i])
+		}
+	}
+}
+
+func TestInfer_TypeAttributeAndSimpleKey(t *testing.T) {
+	tbl := schema.Table{Name: "users", PartitionKey: schema.KeyDef{Name: "id", Kind: "S"}}
+	items := []map[string]ddbtypes.AttributeValue{
+		{"id": s("u-1"), "type": s("user_account"), "email": s("a@example.com")},
+		{"id": s("u-2"), "type": s("user_account"), "email": s("b@example.com")},
+	}
+
+	got := scaffold.Infer(tbl, items).Entities
+	if len(got) != 1 {
+		t.Fatalf("got %d entities, want 1", len(got))
+	}
+	e := got[0]
+	if e.Name != "UserAccount" || e.PartitionKey != "{id}" {
+		t.Errorf("got name %q and partition key %q, want UserAccount and {id}", e.Name, e.PartitionKey)
+	}
+	wantFields := []scaffold.Field{
+		{Name: "ID", Tag: "id", Type: "string"},
+		{Name: "Email", Tag: "email", Type: "string"},
+		{Name: "Type", Tag: "type", Type: "string"},
+	}
+	if !reflect.DeepEqual(e.Fields, wantFields) {
+		t.Errorf("fields = %+v, want %+v", e.Fields, wantFields)
+	}
+}
+
+func TestInfer_NoItems(t *testing.T) {
+	got := scaffold.Infer(ordersTable, nil).Entities
+	if len(got) != 1 {
+		t.Fatalf("got %d entities, want a placeholder", len(got))
+	}
+	e := got[0]
+	if e.Name != "OrdersItem" || e.PartitionKey != "{pk}" || e.SortKey != "{sk}" || len(e.Fields) != 4 || len(e.Notes) != 1 {
+		t.Errorf("unexpected placeholder %+v", e)
+	}
+}
+
+func TestSource(t *testing.T) {
+	items := []map[string]ddbtypes.AttributeValue{
+		orderItem("t1", "o1", "open", "10"),
+		orderItem("t2", "o2", "paid", "12"),
+	}
+	src, err := scaffold.Source("orders", "ddb init-from-aws", []scaffold.Table{scaffold.Infer(ordersTable, items)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg := typeCheck(t, src)
+	for _, want := range []string{
+		"//go:generate ddb gen",
+		`TimeToLiveKey: "expiresAt",`,
+		"type Order struct {",
+		"\tAmount   int64  `dynamodbav:\"amount\"`",
+	} {
+		if !strings.Contains(string(src), want) {
+			t.Errorf("source is missing %q:\n%s", want, src)
+		}
+	}
+
+	got := indexFields(t, pkg)
+	want := map[string]string{
+		"Table":        "OrdersTable",
+		"PartitionKey": `val.Fmt("TENANT#{tenantID}")`,
+		"SortKey":      `val.Fmt("ORDER#{orderID}").Ptr()`,
+		"GSI":          "OrdersTable.GSIs[0]",
+		"Partition":    `val.Fmt("TENANT#{tenantID}#STATUS#{status}")`,
+		"Sort":         `val.Fmt("ORDER#{orderID}").Ptr()`,
+		"Include":      `index.WhenEquals("status", "open")`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("index fields =\n%v\nwant\n%v", got, want)
+	}
+}
+
+// typeCheck compiles the scaffolded source as the testdata/orders package.
+func typeCheck(t *testing.T, src []byte) *packages.Package {
+	t.Helper()
+	dir, err := filepath.Abs(filepath.Join("testdata", "orders"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkgs, err := packages.Load(&packages.Config{
+		Mode:    packages.NeedName | packages.NeedTypes | packages.NeedSyntax | packages.NeedTypesInfo,
+		Dir:     dir,
+		Overlay: map[string][]byte{filepath.Join(dir, "orders.go"): src},
+	}, ".")
+	if err != nil {
+		t.Fatalf("loading package: %v", err)
+	}
+	if len(pkgs) != 1 {
+		t.Fatalf("expected 1 package, got %d", len(pkgs))
+	}
+	for _, err := range pkgs[0].Errors {
+		t.Errorf("scaffolded source: %v", err)
+	}
+	if t.Failed() {
+		t.FailNow()
+	}
+	return pkgs[0]
+}
+
+// indexFields returns the fields of the index.PrimaryIndex literals and their
+// secondary indexes in the package, as source.
+func indexFields(t *testing.T, pkg *packages.Package) map[string]string {
+	t.Helper()
+	fields := make(map[string]string)
+	for _, f := range pkg.Syntax {
+		ast.Inspect(f, func(n ast.Node) bool {
+			lit, ok := n.(*ast.CompositeLit)
+			if !ok {
+				return true
+			}
+			named, ok := pkg.TypesInfo.TypeOf(lit).(*types.Named)
+			if !ok || named.Obj().Pkg() == nil || named.Obj().Pkg().Path() != "github.com/acksell/bezos/dynamodb/index" {
+				return true
+			}
+			if name := named.Obj().Name(); name != "PrimaryIndex" && name != "SecondaryIndex" {
+				return true
+			}
+			for _, elt := range lit.Elts {
+				kv := elt.(*ast.KeyValueExpr)
+				if _, nested := kv.Value.(*ast.CompositeLit); !nested {
+					fields[kv.Key.(*ast.Ident).Name] = types.ExprString(kv.Value)
+				}
+			}
+			return true
+		})
+	}
+	return fields
+}
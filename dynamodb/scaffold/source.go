@@ -0,0 +1,151 @@
+package scaffold
+
+import (
+	"fmt"
+	"go/format"
+	"strconv"
+	"strings"
+
+	"github.com/acksell/bezos/dynamodb/index"
+	"github.com/acksell/bezos/dynamodb/schema"
+)
+
+// Source renders the tables as a Go file of package pkg. origin names what
+// scaffolded the file, e.g. "ddb init-from-aws", for its header comment.
+func Source(pkg, origin string, tables []Table) ([]byte, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "// Scaffolded by %s. Review the entities, their fields and key patterns,\n", origin)
+	b.WriteString("// then run go generate to generate their key constructors.\n\n")
+	fmt.Fprintf(&b, "package %s\n\n//go:generate ddb gen\n\n", pkg)
+	b.WriteString(`import (
+	"github.com/acksell/bezos/dynamodb/index"
+	"github.com/acksell/bezos/dynamodb/index/indices"
+	"github.com/acksell/bezos/dynamodb/index/val"
+	"github.com/acksell/bezos/dynamodb/table"
+)
+`)
+
+	// The entities of all tables share the package.
+	names := make(map[string]bool)
+	for _, t := range tables {
+		writeTable(&b, t.Def)
+		for _, e := range t.Entities {
+			e.Name = uniqueName(e.Name, names)
+			writeEntity(&b, t, e)
+		}
+	}
+
+	src, err := format.Source([]byte(b.String()))
+	if err != nil {
+		return nil, fmt.Errorf("formatting scaffolded source: %w", err)
+	}
+	return src, nil
+}
+
+func tableVar(name string) string {
+	return tableIdent(name) + "Table"
+}
+
+func writeTable(b *strings.Builder, t schema.Table) {
+	fmt.Fprintf(b, "\n// %s is the %q table.\n", tableVar(t.Name), t.Name)
+	fmt.Fprintf(b, "var %s = table.TableDefinition{\n", tableVar(t.Name))
+	fmt.Fprintf(b, "Name: %q,\n", t.Name)
+	writeKeyDefinitions(b, t.PartitionKey, t.SortKey)
+	if t.TimeToLiveKey != "" {
+		fmt.Fprintf(b, "TimeToLiveKey: %q,\n", t.TimeToLiveKey)
+	}
+	if len(t.GSIs) > 0 {
+		b.WriteString("GSIs: []table.GSIDefinition{\n")
+		for _, g := range t.GSIs {
+			fmt.Fprintf(b, "{\nName: %q,\n", g.Name)
+			writeKeyDefinitions(b, g.PartitionKey, g.SortKey)
+			b.WriteString("},\n")
+		}
+		b.WriteString("},\n")
+	}
+	b.WriteString("}\n")
+}
+
+func writeKeyDefinitions(b *strings.Builder, pk schema.KeyDef, sk *schema.KeyDef) {
+	b.WriteString("KeyDefinitions: table.PrimaryKeyDefinition{\n")
+	fmt.Fprintf(b, "PartitionKey: %s,\n", keyDef(pk))
+	if sk != nil {
+		fmt.Fprintf(b, "SortKey: %s,\n", keyDef(*sk))
+	}
+	b.WriteString("},\n")
+}
+
+func keyDef(k schema.KeyDef) string {
+	return fmt.Sprintf("table.KeyDef{Name: %q, Kind: table.KeyKind%s}", k.Name, k.Kind)
+}
+
+func writeEntity(b *strings.Builder, t Table, e Entity) {
+	tv := tableVar(t.Def.Name)
+	if e.Items > 0 {
+		fmt.Fprintf(b, "\n// %s has the keys of %d of %d sampled items, e.g. %s.\n", e.Name, e.Items, t.Sampled, e.Example)
+	} else {
+		fmt.Fprintf(b, "\n// %s is a placeholder for the items of %s.\n", e.Name, tv)
+	}
+	for _, n := range e.Notes {
+		fmt.Fprintf(b, "// TODO: %s.\n", n)
+	}
+	fmt.Fprintf(b, "type %s struct {\n", e.Name)
+	for _, f := range e.Fields {
+		fmt.Fprintf(b, "%s %s `dynamodbav:%q`\n", f.Name, f.Type, f.Tag)
+	}
+	b.WriteString("}\n\n")
+	fmt.Fprintf(b, "func (e *%s) IsValid() error {\n// TODO: check the invariants of the entity.\nreturn nil\n}\n\n", e.Name)
+
+	fmt.Fprintf(b, "var _ = indices.Add(index.PrimaryIndex[%s]{\n", e.Name)
+	fmt.Fprintf(b, "Table: %s,\n", tv)
+	fmt.Fprintf(b, "PartitionKey: %s,\n", keyValue(e.PartitionKey, t.Def.PartitionKey.Kind))
+	if t.Def.SortKey != nil {
+		fmt.Fprintf(b, "SortKey: %s.Ptr(),\n", keyValue(e.SortKey, t.Def.SortKey.Kind))
+	}
+	if len(e.GSIs) > 0 {
+		b.WriteString("Secondary: []index.SecondaryIndex{\n")
+		for _, m := range e.GSIs {
+			i, g := gsi(t.Def, m.GSI)
+			fmt.Fprintf(b, "{\nGSI: %s.GSIs[%d],\n", tv, i)
+			fmt.Fprintf(b, "Partition: %s,\n", keyValue(m.PartitionKey, g.PartitionKey.Kind))
+			if g.SortKey != nil {
+				fmt.Fprintf(b, "Sort: %s.Ptr(),\n", keyValue(m.SortKey, g.SortKey.Kind))
+			}
+			if m.Include != nil {
+				fmt.Fprintf(b, "Include: %s,\n", inclusion(m.Include))
+			}
+			b.WriteString("},\n")
+		}
+		b.WriteString("},\n")
+	}
+	b.WriteString("})\n")
+}
+
+func gsi(t schema.Table, name string) (int, schema.GSI) {
+	for i, g := range t.GSIs {
+		if g.Name == name {
+			return i, g
+		}
+	}
+	panic("scaffold: table " + t.Name + " has no GSI " + name)
+}
+
+// keyValue returns the val expression of a key pattern. Number and binary keys are
+// copied from a field.
+func keyValue(pattern, kind string) string {
+	if kind != "S" {
+		return fmt.Sprintf("val.FromField(%q)", strings.Trim(pattern, "{}"))
+	}
+	return fmt.Sprintf("val.Fmt(%q)", pattern)
+}
+
+func inclusion(in *index.Inclusion) string {
+	if in.Kind == index.IncludeWhenPresent {
+		return fmt.Sprintf("index.WhenPresent(%q)", in.Field)
+	}
+	value := fmt.Sprint(in.Value)
+	if s, ok := in.Value.(string); ok {
+		value = strconv.Quote(s)
+	}
+	return fmt.Sprintf("index.WhenEquals(%q, %s)", in.Field, value)
+}
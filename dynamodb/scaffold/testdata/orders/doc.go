@@ -0,0 +1,2 @@
+// Package orders is where the scaffold tests type-check scaffolded source.
+package orders
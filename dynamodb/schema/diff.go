@@ -0,0 +1,395 @@
+package schema
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// ChangeKind classifies a difference between two schemas.
+type ChangeKind string
+
+const (
+	TableAdded           ChangeKind = "table-added"
+	TableRemoved         ChangeKind = "table-removed"
+	KeyChanged           ChangeKind = "key-changed"
+	EntityTypeKeyChanged ChangeKind = "entity-type-key-changed"
+	TimeToLiveChanged    ChangeKind = "ttl-changed"
+	GSIAdded             ChangeKind = "gsi-added"
+	GSIRemoved           ChangeKind = "gsi-removed"
+	GSIKeyChanged        ChangeKind = "gsi-key-changed"
+	EntityAdded          ChangeKind = "entity-added"
+	EntityRemoved        ChangeKind = "entity-removed"
+	EntityTypeChanged    ChangeKind = "entity-type-changed"
+	KeyPatternChanged    ChangeKind = "key-pattern-changed"
+	GSIMappingAdded      ChangeKind = "gsi-mapping-added"
+	GSIMappingRemoved    ChangeKind = "gsi-mapping-removed"
+	GSIMappingChanged    ChangeKind = "gsi-mapping-changed"
+	FieldAdded           ChangeKind = "field-added"
+	FieldRemoved         ChangeKind = "field-removed"
+	FieldTagRenamed      ChangeKind = "field-tag-renamed"
+	FieldTypeChanged     ChangeKind = "field-type-changed"
+	FieldRulesChanged    ChangeKind = "field-rules-changed"
+	VersioningAdded      ChangeKind = "versioning-added"
+	VersioningRemoved    ChangeKind = "versioning-removed"
+	StreamAdded          ChangeKind = "stream-added"
+	StreamRemoved        ChangeKind = "stream-removed"
+	EventTypeAdded       ChangeKind = "event-type-added"
+	EventTypeRemoved     ChangeKind = "event-type-removed"
+)
+
+// Change is one difference between two schemas.
+type Change struct {
+	Kind ChangeKind `json:"kind"`
+	// Path locates the change, e.g. "orders/Order/sortKey" or "orders/gsi:ByStatus".
+	Path string `json:"path"`
+	Old  string `json:"old,omitempty"`
+	New  string `json:"new,omitempty"`
+	// Breaking is true if items written with the old schema can't be read or found
+	// with the new one, or the table itself must be recreated.
+	Breaking bool `json:"breaking"`
+}
+
+// ID identifies the change in an allowlist, e.g. "key-pattern-changed orders/Order/sortKey".
+func (c Change) ID() string {
+	return string(c.Kind) + " " + c.Path
+}
+
+func (c Change) String() string {
+	s := c.ID()
+	if c.Old != "" || c.New != "" {
+		s += fmt.Sprintf(": %q -> %q", c.Old, c.New)
+	}
+	return s
+}
+
+// Diff compares two schemas and returns their differences, breaking or not.
+// Tables, GSIs and entities are matched by name, fields by Go field name, so a
+// field that keeps its name but gets another dynamodbav tag is reported as renamed.
+func Diff(old, new Schema) []Change {
+	var d differ
+	oldTables, newTables := tablesByName(old), tablesByName(new)
+	for _, name := range sortedKeys(oldTables) {
+		ot := oldTables[name]
+		nt, ok := newTables[name]
+		if !ok {
+			d.add(TableRemoved, name, name, "", true)
+			continue
+		}
+		d.table(ot, nt)
+	}
+	for _, name := range sortedKeys(newTables) {
+		if _, ok := oldTables[name]; !ok {
+			d.add(TableAdded, name, "", name, false)
+		}
+	}
+	return d.changes
+}
+
+// Breaking returns the breaking changes.
+func Breaking(changes []Change) []Change {
+	var out []Change
+	for _, c := range changes {
+		if c.Breaking {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+type differ struct {
+	changes []Change
+}
+
+func (d *differ) add(kind ChangeKind, path, old, new string, breaking bool) {
+	d.changes = append(d.changes, Change{Kind: kind, Path: path, Old: old, New: new, Breaking: breaking})
+}
+
+func (d *differ) table(ot, nt Table) {
+	path := ot.Name
+	if k1, k2 := keyString(&ot.PartitionKey), keyString(&nt.PartitionKey); k1 != k2 {
+		d.add(KeyChanged, path+"/partitionKey", k1, k2, true)
+	}
+	if k1, k2 := keyString(ot.SortKey), keyString(nt.SortKey); k1 != k2 {
+		d.add(KeyChanged, path+"/sortKey", k1, k2, true)
+	}
+	if ot.EntityTypeKey != nt.EntityTypeKey {
+		// Adding a discriminator is fine, items without it are matched by key.
+		d.add(EntityTypeKeyChanged, path, ot.EntityTypeKey, nt.EntityTypeKey, ot.EntityTypeKey != "")
+	}
+	if ot.TimeToLiveKey != nt.TimeToLiveKey {
+		// The table stays, only its TTL setting has to be updated.
+		d.add(TimeToLiveChanged, path, ot.TimeToLiveKey, nt.TimeToLiveKey, false)
+	}
+
+	oldGSIs, newGSIs := gsisByName(ot.GSIs), gsisByName(nt.GSIs)
+	for _, name := range sortedKeys(oldGSIs) {
+		og := oldGSIs[name]
+		gpath := path + "/gsi:" + name
+		ng, ok := newGSIs[name]
+		if !ok {
+			d.add(GSIRemoved, gpath, name, "", true)
+			continue
+		}
+		if k1, k2 := keyString(&og.PartitionKey), keyString(&ng.PartitionKey); k1 != k2 {
+			d.add(GSIKeyChanged, gpath+"/partitionKey", k1, k2, true)
+		}
+		if k1, k2 := keyString(og.SortKey), keyString(ng.SortKey); k1 != k2 {
+			d.add(GSIKeyChanged, gpath+"/sortKey", k1, k2, true)
+		}
+	}
+	for _, name := range sortedKeys(newGSIs) {
+		if _, ok := oldGSIs[name]; !ok {
+			d.add(GSIAdded, path+"/gsi:"+name, "", name, false)
+		}
+	}
+
+	oldEntities, newEntities := entitiesByType(ot.Entities), entitiesByType(nt.Entities)
+	for _, name := range sortedKeys(oldEntities) {
+		ne, ok := newEntities[name]
+		if !ok {
+			d.add(EntityRemoved, path+"/"+name, name, "", true)
+			continue
+		}
+		d.entity(path+"/"+name, oldEntities[name], ne)
+	}
+	for _, name := range sortedKeys(newEntities) {
+		if _, ok := oldEntities[name]; !ok {
+			d.add(EntityAdded, path+"/"+name, "", name, false)
+		}
+	}
+
+	oldStreams, newStreams := streamsByAggregate(ot.Streams), streamsByAggregate(nt.Streams)
+	for _, name := range sortedKeys(oldStreams) {
+		spath := path + "/stream:" + name
+		ns, ok := newStreams[name]
+		if !ok {
+			d.add(StreamRemoved, spath, name, "", true)
+			continue
+		}
+		d.stream(spath, oldStreams[name], ns)
+	}
+	for _, name := range sortedKeys(newStreams) {
+		if _, ok := oldStreams[name]; !ok {
+			d.add(StreamAdded, path+"/stream:"+name, "", name, false)
+		}
+	}
+}
+
+// stream compares the events of a stream. Stored events are never rewritten, so every
+// event type that was ever appended must stay decodable.
+func (d *differ) stream(path string, os, ns Stream) {
+	if os.PartitionKeyPattern != ns.PartitionKeyPattern {
+		d.add(KeyPatternChanged, path+"/partitionKey", os.PartitionKeyPattern, ns.PartitionKeyPattern, true)
+	}
+	if os.SortKeyPattern != ns.SortKeyPattern {
+		d.add(KeyPatternChanged, path+"/sortKey", os.SortKeyPattern, ns.SortKeyPattern, true)
+	}
+	oldEvents, newEvents := eventsByType(os.Events), eventsByType(ns.Events)
+	for _, name := range sortedKeys(oldEvents) {
+		ne, ok := newEvents[name]
+		if !ok {
+			d.add(EventTypeRemoved, path+"/"+name, name, "", true)
+			continue
+		}
+		d.fields(path+"/"+name, oldEvents[name].Fields, ne.Fields)
+	}
+	for _, name := range sortedKeys(newEvents) {
+		if _, ok := oldEvents[name]; !ok {
+			d.add(EventTypeAdded, path+"/"+name, "", name, false)
+		}
+	}
+}
+
+func (d *differ) entity(path string, oe, ne Entity) {
+	if oe.PartitionKeyPattern != ne.PartitionKeyPattern {
+		d.add(KeyPatternChanged, path+"/partitionKey", oe.PartitionKeyPattern, ne.PartitionKeyPattern, true)
+	}
+	if oe.SortKeyPattern != ne.SortKeyPattern {
+		d.add(KeyPatternChanged, path+"/sortKey", oe.SortKeyPattern, ne.SortKeyPattern, true)
+	}
+	if oe.EntityTypeValue != ne.EntityTypeValue {
+		d.add(EntityTypeChanged, path, oe.EntityTypeValue, ne.EntityTypeValue, oe.EntityTypeValue != "")
+	}
+	switch {
+	case oe.IsVersioned && !ne.IsVersioned:
+		d.add(VersioningRemoved, path, "", "", true)
+	case !oe.IsVersioned && ne.IsVersioned:
+		d.add(VersioningAdded, path, "", "", false)
+	}
+
+	oldMappings, newMappings := mappingsByGSI(oe.GSIMappings), mappingsByGSI(ne.GSIMappings)
+	for _, name := range sortedKeys(oldMappings) {
+		om := oldMappings[name]
+		mpath := path + "/gsi:" + name
+		nm, ok := newMappings[name]
+		if !ok {
+			d.add(GSIMappingRemoved, mpath, mappingString(om), "", true)
+			continue
+		}
+		if m1, m2 := mappingString(om), mappingString(nm); m1 != m2 {
+			d.add(GSIMappingChanged, mpath, m1, m2, true)
+		}
+	}
+	for _, name := range sortedKeys(newMappings) {
+		if _, ok := oldMappings[name]; !ok {
+			// Existing items only get the new GSI keys when they are written again.
+			d.add(GSIMappingAdded, path+"/gsi:"+name, "", mappingString(newMappings[name]), false)
+		}
+	}
+
+	d.fields(path, oe.Fields, ne.Fields)
+}
+
+func (d *differ) fields(path string, old, new []Field) {
+	oldFields, newFields := fieldsByName(old), fieldsByName(new)
+	for _, name := range sortedKeys(oldFields) {
+		of := oldFields[name]
+		fpath := path + "/" + name
+		nf, ok := newFields[name]
+		if !ok {
+			d.add(FieldRemoved, fpath, of.Tag, "", false)
+			continue
+		}
+		if of.Tag != nf.Tag {
+			d.add(FieldTagRenamed, fpath, of.Tag, nf.Tag, true)
+		}
+		if of.Type != nf.Type {
+			d.add(FieldTypeChanged, fpath, of.Type, nf.Type, true)
+		}
+		if oldRules, newRules := rulesString(of.Rules), rulesString(nf.Rules); oldRules != newRules {
+			// Rules are checked on write, existing items stay readable.
+			d.add(FieldRulesChanged, fpath, oldRules, newRules, false)
+		}
+	}
+	for _, name := range sortedKeys(newFields) {
+		if _, ok := oldFields[name]; !ok {
+			d.add(FieldAdded, path+"/"+name, "", newFields[name].Tag, false)
+		}
+	}
+}
+
+// Allowlist holds the IDs of acknowledged breaking changes, see [Change.ID].
+type Allowlist map[string]bool
+
+// ParseAllowlist reads an allowlist with one change ID per line.
+// Blank lines and lines starting with # are ignored.
+func ParseAllowlist(r io.Reader) (Allowlist, error) {
+	a := make(Allowlist)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		a[line] = true
+	}
+	return a, scanner.Err()
+}
+
+// Unacknowledged returns the breaking changes that are not in the allowlist.
+func (a Allowlist) Unacknowledged(changes []Change) []Change {
+	var out []Change
+	for _, c := range Breaking(changes) {
+		if !a[c.ID()] {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+func keyString(k *KeyDef) string {
+	if k == nil {
+		return ""
+	}
+	return k.Name + " (" + k.Kind + ")"
+}
+
+func mappingString(m GSIMapping) string {
+	s := m.PartitionPattern
+	if m.SortPattern != "" {
+		s += " / " + m.SortPattern
+	}
+	if m.Condition != nil {
+		s += " when " + m.Condition.Description
+	}
+	return s
+}
+
+func tablesByName(s Schema) map[string]Table {
+	m := make(map[string]Table, len(s.Tables))
+	for _, t := range s.Tables {
+		m[t.Name] = t
+	}
+	return m
+}
+
+func gsisByName(gsis []GSI) map[string]GSI {
+	m := make(map[string]GSI, len(gsis))
+	for _, g := range gsis {
+		m[g.Name] = g
+	}
+	return m
+}
+
+func entitiesByType(entities []Entity) map[string]Entity {
+	m := make(map[string]Entity, len(entities))
+	for _, e := range entities {
+		m[e.Type] = e
+	}
+	return m
+}
+
+func streamsByAggregate(streams []Stream) map[string]Stream {
+	m := make(map[string]Stream, len(streams))
+	for _, s := range streams {
+		m[s.Aggregate] = s
+	}
+	return m
+}
+
+func eventsByType(events []Event) map[string]Event {
+	m := make(map[string]Event, len(events))
+	for _, e := range events {
+		m[e.Type] = e
+	}
+	return m
+}
+
+func mappingsByGSI(mappings []GSIMapping) map[string]GSIMapping {
+	m := make(map[string]GSIMapping, len(mappings))
+	for _, g := range mappings {
+		m[g.GSI] = g
+	}
+	return m
+}
+
+func rulesString(rules []Rule) string {
+	parts := make([]string, len(rules))
+	for i, r := range rules {
+		parts[i] = r.Name
+		if r.Arg != "" {
+			parts[i] += "=" + r.Arg
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+func fieldsByName(fields []Field) map[string]Field {
+	m := make(map[string]Field, len(fields))
+	for _, f := range fields {
+		m[f.Name] = f
+	}
+	return m
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -0,0 +1,170 @@
+package schema_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/acksell/bezos/dynamodb/schema"
+)
+
+func baseSchema() schema.Schema {
+	return schema.Schema{Tables: []schema.Table{{
+		Name:         "orders",
+		PartitionKey: schema.KeyDef{Name: "pk", Kind: "S"},
+		SortKey:      &schema.KeyDef{Name: "sk", Kind: "S"},
+		GSIs: []schema.GSI{{
+			Name:         "ByStatus",
+			PartitionKey: schema.KeyDef{Name: "gsi1pk", Kind: "S"},
+		}},
+		Entities: []schema.Entity{{
+			Type:                "Order",
+			PartitionKeyPattern: "TENANT#{tenantID}",
+			SortKeyPattern:      "ORDER#{orderID}",
+			IsVersioned:         true,
+			Fields: []schema.Field{
+				{Name: "TenantID", Tag: "tenantID", Type: "string"},
+				{Name: "OrderID", Tag: "orderID", Type: "string"},
+				{Name: "Total", Tag: "total", Type: "int"},
+			},
+			GSIMappings: []schema.GSIMapping{{GSI: "ByStatus", PartitionPattern: "STATUS#{status}"}},
+		}},
+		Streams: []schema.Stream{{
+			Aggregate:           "Cart",
+			PartitionKeyPattern: "Cart#{aggregateID}",
+			SortKeyPattern:      "EVENT#{seq:%020d}",
+			Events: []schema.Event{
+				{Type: "ItemAdded", Fields: []schema.Field{{Name: "SKU", Tag: "sku", Type: "string"}}},
+				{Type: "CheckedOut", Fields: []schema.Field{}},
+			},
+		}},
+	}}}
+}
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(s *schema.Schema)
+		want   []string // change IDs
+		broken int
+	}{
+		{
+			name:   "no changes",
+			modify: func(s *schema.Schema) {},
+		},
+		{
+			name:   "key pattern changed",
+			modify: func(s *schema.Schema) { s.Tables[0].Entities[0].SortKeyPattern = "O#{orderID}" },
+			want:   []string{"key-pattern-changed orders/Order/sortKey"},
+			broken: 1,
+		},
+		{
+			name:   "key kind changed",
+			modify: func(s *schema.Schema) { s.Tables[0].SortKey.Kind = "N" },
+			want:   []string{"key-changed orders/sortKey"},
+			broken: 1,
+		},
+		{
+			name: "gsi removed",
+			modify: func(s *schema.Schema) {
+				s.Tables[0].GSIs = nil
+				s.Tables[0].Entities[0].GSIMappings = nil
+			},
+			want:   []string{"gsi-removed orders/gsi:ByStatus", "gsi-mapping-removed orders/Order/gsi:ByStatus"},
+			broken: 2,
+		},
+		{
+			name: "tag renamed and type changed",
+			modify: func(s *schema.Schema) {
+				s.Tables[0].Entities[0].Fields[2] = schema.Field{Name: "Total", Tag: "amount", Type: "float64"}
+			},
+			want:   []string{"field-tag-renamed orders/Order/Total", "field-type-changed orders/Order/Total"},
+			broken: 2,
+		},
+		{
+			name:   "ttl added",
+			modify: func(s *schema.Schema) { s.Tables[0].TimeToLiveKey = "expiresAt" },
+			want:   []string{"ttl-changed orders"},
+		},
+		{
+			name: "rules changed",
+			modify: func(s *schema.Schema) {
+				s.Tables[0].Entities[0].Fields[2].Rules = []schema.Rule{{Name: "min", Arg: "1"}}
+			},
+			want: []string{"field-rules-changed orders/Order/Total"},
+		},
+		{
+			name:   "versioning removed",
+			modify: func(s *schema.Schema) { s.Tables[0].Entities[0].IsVersioned = false },
+			want:   []string{"versioning-removed orders/Order"},
+			broken: 1,
+		},
+		{
+			name: "event types changed",
+			modify: func(s *schema.Schema) {
+				st := &s.Tables[0].Streams[0]
+				st.Events[0].Fields[0].Type = "int"
+				st.Events[1] = schema.Event{Type: "Abandoned"}
+			},
+			want: []string{
+				"event-type-removed orders/stream:Cart/CheckedOut",
+				"field-type-changed orders/stream:Cart/ItemAdded/SKU",
+				"event-type-added orders/stream:Cart/Abandoned",
+			},
+			broken: 2,
+		},
+		{
+			name:   "stream removed",
+			modify: func(s *schema.Schema) { s.Tables[0].Streams = nil },
+			want:   []string{"stream-removed orders/stream:Cart"},
+			broken: 1,
+		},
+		{
+			name: "additive changes",
+			modify: func(s *schema.Schema) {
+				e := &s.Tables[0].Entities[0]
+				e.Fields = append(e.Fields, schema.Field{Name: "Note", Tag: "note", Type: "string"})
+				s.Tables[0].EntityTypeKey = "type"
+				s.Tables = append(s.Tables, schema.Table{Name: "users", PartitionKey: schema.KeyDef{Name: "pk", Kind: "S"}})
+			},
+			want: []string{"entity-type-key-changed orders", "field-added orders/Order/Note", "table-added users"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := baseSchema()
+			tt.modify(&next)
+			changes := schema.Diff(baseSchema(), next)
+
+			var got []string
+			for _, c := range changes {
+				got = append(got, c.ID())
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("Diff() = %q, want %q", got, tt.want)
+			}
+			if n := len(schema.Breaking(changes)); n != tt.broken {
+				t.Errorf("Breaking() = %d changes, want %d", n, tt.broken)
+			}
+		})
+	}
+}
+
+func TestAllowlist_Unacknowledged(t *testing.T) {
+	next := baseSchema()
+	next.Tables[0].Entities[0].SortKeyPattern = "O#{orderID}"
+	next.Tables[0].Entities[0].IsVersioned = false
+	changes := schema.Diff(baseSchema(), next)
+
+	allow, err := schema.ParseAllowlist(strings.NewReader(`
+# moving to short prefixes, see the migration in #123
+key-pattern-changed orders/Order/sortKey
+`))
+	if err != nil {
+		t.Fatalf("ParseAllowlist() error = %v", err)
+	}
+
+	broken := allow.Unacknowledged(changes)
+	if len(broken) != 1 || broken[0].ID() != "versioning-removed orders/Order" {
+		t.Errorf("Unacknowledged() = %v, want only versioning-removed", broken)
+	}
+}
@@ -1,8 +1,8 @@
 // Package schema defines the data types for DynamoDB table and entity schemas.
 // These types are used by ddbui for introspecting entities, indices and tables.
 //
-// Could also be used for detecting breaking changes / schema management,
-// although we'd probably want to use a more conventional schema format then.
+// [Diff] compares two versions of a schema to detect breaking changes, e.g. in CI
+// with ddb schema diff or ddb gen --check.
 package schema
 
 // Schema is the root type containing all table definitions.
@@ -13,11 +13,14 @@ type Schema struct {
 
 // Table describes a DynamoDB table structure with its entities.
 type Table struct {
-	Name         string   `yaml:"name" json:"name"`
-	PartitionKey KeyDef   `yaml:"partitionKey" json:"partitionKey"`
-	SortKey      *KeyDef  `yaml:"sortKey,omitempty" json:"sortKey,omitempty"`
-	GSIs         []GSI    `yaml:"gsis,omitempty" json:"gsis,omitempty"`
-	Entities     []Entity `yaml:"entities,omitempty" json:"entities,omitempty"`
+	Name          string   `yaml:"name" json:"name"`
+	PartitionKey  KeyDef   `yaml:"partitionKey" json:"partitionKey"`
+	SortKey       *KeyDef  `yaml:"sortKey,omitempty" json:"sortKey,omitempty"`
+	EntityTypeKey string   `yaml:"entityTypeKey,omitempty" json:"entityTypeKey,omitempty"` // attribute storing each item's entity type
+	TimeToLiveKey string   `yaml:"timeToLiveKey,omitempty" json:"timeToLiveKey,omitempty"` // TTL attribute, empty if TTL is disabled
+	GSIs          []GSI    `yaml:"gsis,omitempty" json:"gsis,omitempty"`
+	Entities      []Entity `yaml:"entities,omitempty" json:"entities,omitempty"`
+	Streams       []Stream `yaml:"streams,omitempty" json:"streams,omitempty"`
 }
 
 // KeyDef describes a key attribute definition.
@@ -36,6 +39,7 @@ type GSI struct {
 // Entity describes an entity type stored in a table.
 type Entity struct {
 	Type                string       `yaml:"type" json:"type"`
+	EntityTypeValue     string       `yaml:"entityTypeValue,omitempty" json:"entityTypeValue,omitempty"` // value of the table's EntityTypeKey
 	PartitionKeyPattern string       `yaml:"partitionKeyPattern" json:"partitionKeyPattern"`
 	SortKeyPattern      string       `yaml:"sortKeyPattern,omitempty" json:"sortKeyPattern,omitempty"`
 	Fields              []Field      `yaml:"fields" json:"fields"`
@@ -48,11 +52,45 @@ type Field struct {
 	Name string `yaml:"name" json:"name"`
 	Tag  string `yaml:"tag" json:"tag"`
 	Type string `yaml:"type" json:"type"`
+	// Rules are the validation rules of the field's validate struct tag.
+	Rules []Rule `yaml:"rules,omitempty" json:"rules,omitempty"`
+}
+
+// Stream describes the event streams of an aggregate type stored in a table,
+// see the eventsource package.
+type Stream struct {
+	Aggregate           string  `yaml:"aggregate" json:"aggregate"`
+	PartitionKeyPattern string  `yaml:"partitionKeyPattern" json:"partitionKeyPattern"`
+	SortKeyPattern      string  `yaml:"sortKeyPattern" json:"sortKeyPattern"`
+	Events              []Event `yaml:"events" json:"events"`
+}
+
+// Event describes an event type of a stream. Type is its stored type name.
+type Event struct {
+	Type   string  `yaml:"type" json:"type"`
+	Fields []Field `yaml:"fields" json:"fields"`
+}
+
+// Rule is a validation rule of a field, e.g. {Name: "max", Arg: "100"}.
+// The argument of a cross-field rule such as ltfield is the other field's tag.
+type Rule struct {
+	Name string `yaml:"name" json:"name"`
+	Arg  string `yaml:"arg,omitempty" json:"arg,omitempty"`
 }
 
 // GSIMapping describes how an entity maps to a GSI.
 type GSIMapping struct {
-	GSI              string `yaml:"gsi" json:"gsi"`
-	PartitionPattern string `yaml:"partitionPattern" json:"partitionPattern"`
-	SortPattern      string `yaml:"sortPattern,omitempty" json:"sortPattern,omitempty"`
+	GSI              string        `yaml:"gsi" json:"gsi"`
+	PartitionPattern string        `yaml:"partitionPattern" json:"partitionPattern"`
+	SortPattern      string        `yaml:"sortPattern,omitempty" json:"sortPattern,omitempty"`
+	Condition        *GSICondition `yaml:"condition,omitempty" json:"condition,omitempty"`
+}
+
+// GSICondition describes when an entity is written to a sparse GSI.
+// Entities that don't match have no GSI key attributes and are absent from the index.
+type GSICondition struct {
+	Kind        string `yaml:"kind" json:"kind"`                       // "present", "equals" or "custom"
+	Field       string `yaml:"field,omitempty" json:"field,omitempty"` // attribute checked by present/equals
+	Value       string `yaml:"value,omitempty" json:"value,omitempty"` // required value for equals
+	Description string `yaml:"description" json:"description"`
 }
@@ -10,7 +10,11 @@ type TableDefinition struct {
 	Name           string
 	KeyDefinitions PrimaryKeyDefinition
 	TimeToLiveKey  string
-	GSIs           []GSIDefinition
+	// EntityTypeKey is the attribute that stores each item's entity type, e.g. "type".
+	// Puts write it automatically, so mixed query results can be decoded into their
+	// Go types. Empty disables the discriminator.
+	EntityTypeKey string
+	GSIs          []GSIDefinition
 }
 
 // GSIDefinition represents a Global Secondary Index definition.